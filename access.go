@@ -0,0 +1,87 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// AccessViolation is a read through a Module outside the key prefixes it declared.
+type AccessViolation struct {
+	Module string
+	// Path is the value read, e.g. "server.port".
+	Path string
+	// Allowed are the prefixes the module declared.
+	Allowed []string
+}
+
+func (v *AccessViolation) Error() string {
+	allowed := "none"
+	if len(v.Allowed) > 0 {
+		allowed = strings.Join(v.Allowed, ", ")
+	}
+	return fmt.Sprintf("module %s read %q outside its declared prefixes (%s)", v.Module, v.Path, allowed)
+}
+
+// Module is a view of a Config for the code of one module, which may only read below the key
+// prefixes it declared. It keeps config ownership explicit in large codebases: a read outside the
+// prefixes is passed to Config.OnAccessViolation, or panics when that is nil.
+type Module struct {
+	c        *Config
+	name     string
+	prefixes []slowjson.Path
+	allowed  []string
+}
+
+// Module returns the view of c for the module name reading the prefixes, e.g. "billing" or
+// "db.billing". A prefix covers itself and every path below it, "" covers the whole config.
+// It panics when a prefix is not a valid path, prefixes are declared once at startup.
+func (c *Config) Module(name string, prefixes ...string) *Module {
+	m := &Module{c: c, name: name, allowed: prefixes}
+	for _, prefix := range prefixes {
+		p, err := slowjson.ParsePath(prefix)
+		if err != nil {
+			panic(fmt.Sprintf("tracedconfig: module %s: invalid prefix %q: %v", name, prefix, err))
+		}
+		m.prefixes = append(m.prefixes, p)
+	}
+	return m
+}
+
+// Get is Config.Get for a path below the prefixes of the module.
+func (m *Module) Get(path string, opts ...slowjson.GetOption) *slowjson.Node {
+	n := m.c.Get(path, opts...)
+	switch p, err := slowjson.ParsePath(path); {
+	case err == nil:
+		m.check(p)
+	case n != nil:
+		m.check(n.Path())
+	}
+	return n
+}
+
+// Decode is Config.Decode reading only below the prefixes of the module. Values that are decoded
+// into v are checked, so a struct having fields for the keys of the module only may decode the root.
+func (m *Module) Decode(v interface{}) error {
+	d := NewDecoder(m.c.Root())
+	d.read = func(n *slowjson.Node) {
+		m.check(n.Path())
+		m.c.markUsed(n)
+	}
+	return d.Decode(v)
+}
+
+// check reports a read of p outside the prefixes.
+func (m *Module) check(p slowjson.Path) {
+	for _, prefix := range m.prefixes {
+		if p.HasPrefix(prefix) {
+			return
+		}
+	}
+	v := &AccessViolation{Module: m.name, Path: p.String(), Allowed: m.allowed}
+	if m.c.OnAccessViolation == nil {
+		panic(v)
+	}
+	m.c.OnAccessViolation(v)
+}
@@ -0,0 +1,69 @@
+package tracedconfig
+
+import (
+	"context"
+	"testing"
+)
+
+func TestModule(t *testing.T) {
+	c := NewConfig(Bytes("app.json", []byte(`{"billing": {"rate": 2, "currency": "EUR"}, "db": {"billing": {"dsn": "x"}, "users": {"dsn": "y"}}, "server": {"port": 80}}`)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var violations []string
+	c.OnAccessViolation = func(v *AccessViolation) { violations = append(violations, v.Error()) }
+	m := c.Module("billing", "billing", "db.billing")
+
+	if n := m.Get("billing.rate"); n == nil || n.Value != "2" {
+		t.Errorf("Get(billing.rate) = %v", n)
+	}
+	m.Get("db.billing.dsn")
+	var cfg struct {
+		Billing struct{ Rate int }
+		DB      struct {
+			Billing map[string]string
+		}
+	}
+	if err := m.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Billing.Rate != 2 || cfg.DB.Billing["dsn"] != "x" {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("declared reads reported %v", violations)
+	}
+
+	m.Get("server.port")
+	m.Get("billingx")
+	var all struct{ DB interface{} }
+	if err := m.Decode(&all); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`module billing read "server.port" outside its declared prefixes (billing, db.billing)`,
+		`module billing read "billingx" outside its declared prefixes (billing, db.billing)`,
+		`module billing read "db" outside its declared prefixes (billing, db.billing)`,
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("violations = %q, want %q", violations, want)
+	}
+	for i := range want {
+		if violations[i] != want[i] {
+			t.Errorf("violations[%d] = %q, want %q", i, violations[i], want[i])
+		}
+	}
+}
+
+func TestModule_Panics(t *testing.T) {
+	c := NewConfig()
+	m := c.Module("web")
+	defer func() {
+		v, ok := recover().(*AccessViolation)
+		if !ok || v.Path != "server" || v.Error() != `module web read "server" outside its declared prefixes (none)` {
+			t.Errorf("recover() = %v", v)
+		}
+	}()
+	m.Get("server")
+	t.Error("Get outside the prefixes did not panic")
+}
@@ -0,0 +1,368 @@
+package blockconf
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Options configures Parse.
+type Options struct {
+	// Lines makes line breaks end directives, as in a Caddyfile, instead of semicolons, as in nginx.
+	// Braces then open and close blocks only as words of their own, so placeholders such as {$PORT} are
+	// part of the words, a block may have no name, e.g. Caddy's global options, and `backquoted` words
+	// are kept as written.
+	Lines bool
+}
+
+// Parse parses an nginx style config, where semicolons end directives, into an object of its
+// directives. file is recorded as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	return Options{}.Parse(data, file)
+}
+
+// Parse parses a config into an object of its directives.
+func (o Options) Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{opts: o, src: string(data), file: file, line: 1, col: 1}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root, err := p.block(nil)
+	if err != nil {
+		return nil, err
+	}
+	slowjson.SetParents(root)
+	return root, nil
+}
+
+type parser struct {
+	opts Options
+	src  string
+	file string
+
+	off, line, col int
+	depth          int
+}
+
+type mark struct {
+	off, line, col int
+}
+
+// Token kinds besides the punctuation they stand for.
+const (
+	tokenEOF  = 0
+	tokenWord = 'w'
+)
+
+type token struct {
+	kind       byte
+	text       string
+	start, end mark
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) span(typ slowjson.NodeType, value string, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// brace reports whether the brace at the offset is a token, which it is in nginx style anywhere but in
+// ${var}, and with Lines only as a word of its own.
+func (p *parser) brace() bool {
+	if !p.opts.Lines {
+		return true
+	}
+	after := p.off + 1
+	return after == len(p.src) || isSpace(p.src[after])
+}
+
+// token reads the next token, skipping spaces and comments.
+func (p *parser) token() (token, error) {
+	for !p.eof() {
+		c := p.peek()
+		if c == '\n' && p.opts.Lines {
+			break
+		}
+		if isSpace(c) {
+			p.next()
+			continue
+		}
+		if c == '#' {
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+			continue
+		}
+		break
+	}
+	start := p.mark()
+	if p.eof() {
+		return token{kind: tokenEOF, start: start, end: start}, nil
+	}
+	c := p.peek()
+	switch {
+	case c == '\n' || c == ';' && !p.opts.Lines || (c == '{' || c == '}') && p.brace():
+		p.next()
+		return token{kind: c, text: string(c), start: start, end: p.mark()}, nil
+	case c == '"' || c == '\'' || c == '`' && p.opts.Lines:
+		return p.quoted(c)
+	}
+	var b strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		if isSpace(c) || !p.opts.Lines && (c == ';' || c == '}' || c == '{' && !strings.HasSuffix(b.String(), "$")) {
+			break
+		}
+		if c == '\\' && !p.opts.Lines && p.off+1 < len(p.src) {
+			p.next()
+			b.WriteString(unescape(p.peek(), false))
+			p.next()
+			continue
+		}
+		if c == '{' && !p.opts.Lines {
+			// ${var}, up to the closing brace
+			for p.peek() != '}' {
+				if p.eof() || isSpace(p.peek()) {
+					return token{}, p.errorf(start, "unterminated variable, expected }")
+				}
+				b.WriteByte(p.peek())
+				p.next()
+			}
+		}
+		b.WriteByte(p.peek())
+		p.next()
+	}
+	return token{kind: tokenWord, text: b.String(), start: start, end: p.mark()}, nil
+}
+
+// unescape returns the character c escaped with a backslash, which nginx turns into a tab, line break
+// or carriage return for t, n and r, into the character itself for quotes and backslashes and keeps
+// as written otherwise. In Caddy's quoted strings only quotes are escaped.
+func unescape(c byte, caddy bool) string {
+	switch {
+	case c == '"':
+		return `"`
+	case caddy:
+		return `\` + string(c)
+	case c == '\'' || c == '\\':
+		return string(c)
+	case c == 't':
+		return "\t"
+	case c == 'n':
+		return "\n"
+	case c == 'r':
+		return "\r"
+	}
+	return `\` + string(c)
+}
+
+// quoted reads a word in the quotes q.
+func (p *parser) quoted(q byte) (token, error) {
+	start := p.mark()
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return token{}, p.errorf(start, "unterminated string")
+		}
+		c := p.peek()
+		if c == q {
+			p.next()
+			break
+		}
+		if c == '\\' && q != '`' && p.off+1 < len(p.src) {
+			p.next()
+			b.WriteString(unescape(p.peek(), p.opts.Lines))
+			p.next()
+			continue
+		}
+		b.WriteByte(c)
+		p.next()
+	}
+	if !p.eof() && !isSpace(p.peek()) && (p.opts.Lines || p.peek() != ';' && p.peek() != '{' && p.peek() != '}') {
+		return token{}, p.errorf(p.mark(), "expected a space after the quoted string")
+	}
+	return token{kind: tokenWord, text: b.String(), start: start, end: p.mark()}, nil
+}
+
+// block parses directives up to the brace closing open, or to the end of the file when open is nil, into
+// an object.
+func (p *parser) block(open *token) (*slowjson.Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > slowjson.MaxDepth {
+		return nil, p.errorf(p.mark(), "exceeded max depth %d", slowjson.MaxDepth)
+	}
+	start := p.mark()
+	if open != nil {
+		start = open.start
+	}
+	obj := p.span(slowjson.NodeObject, "", start, start)
+	lists := map[string]*slowjson.Node{}
+	for {
+		var words []token
+		tok, err := p.token()
+		for ; err == nil && tok.kind == tokenWord; tok, err = p.token() {
+			words = append(words, tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case '\n':
+			if len(words) == 0 {
+				continue
+			}
+		case ';':
+			if len(words) == 0 {
+				return nil, p.errorf(tok.start, "unexpected ;")
+			}
+		case '{':
+			if len(words) == 0 && !p.opts.Lines {
+				return nil, p.errorf(tok.start, "expected a directive name before {")
+			}
+		case '}', tokenEOF:
+			if len(words) > 0 && (tok.kind == '}' || !p.opts.Lines) {
+				return nil, p.errorf(words[len(words)-1].end, "expected %s after the directive %s", p.terminator(), words[0].text)
+			}
+			if len(words) > 0 {
+				break
+			}
+			switch {
+			case tok.kind == tokenEOF && open != nil:
+				return nil, p.errorf(open.start, "unterminated block, expected }")
+			case tok.kind == '}' && open == nil:
+				return nil, p.errorf(tok.start, "unexpected }")
+			}
+			obj.EndLine, obj.EndCol, obj.EndOffset = tok.end.line, tok.end.col, tok.end.off
+			return obj, nil
+		}
+		occ, err := p.directive(words, tok)
+		if err != nil {
+			return nil, err
+		}
+		name, nameAt := "", tok
+		if len(words) > 0 {
+			name, nameAt = words[0].text, words[0]
+		}
+		list := lists[name]
+		if list == nil {
+			key := p.span(slowjson.NodeString, name, nameAt.start, nameAt.end)
+			list = p.span(slowjson.NodeArray, "", nameAt.start, nameAt.start)
+			key.Children = []*slowjson.Node{list}
+			obj.Children = append(obj.Children, key)
+			lists[name] = list
+		}
+		list.Children = append(list.Children, occ)
+		list.EndLine, list.EndCol, list.EndOffset = occ.EndLine, occ.EndCol, occ.EndOffset
+	}
+}
+
+// terminator names what ends a directive.
+func (p *parser) terminator() string {
+	if p.opts.Lines {
+		return "a line break"
+	}
+	return ";"
+}
+
+// directive returns the object of the directive of words ended by tok, parsing its block when tok opens
+// one.
+func (p *parser) directive(words []token, tok token) (*slowjson.Node, error) {
+	start := tok.start
+	if len(words) > 0 {
+		start = words[0].start
+	}
+	occ := p.span(slowjson.NodeObject, "", start, tok.end)
+	if tok.kind != ';' && len(words) > 0 {
+		occ.EndLine, occ.EndCol, occ.EndOffset = words[len(words)-1].end.line, words[len(words)-1].end.col, words[len(words)-1].end.off
+	}
+	argsAt := start
+	if len(words) > 0 {
+		argsAt = words[0].end
+	}
+	args := p.span(slowjson.NodeArray, "", argsAt, argsAt)
+	if len(words) > 1 {
+		args.StartLine, args.StartCol, args.StartOffset = words[1].start.line, words[1].start.col, words[1].start.off
+		args.EndLine, args.EndCol, args.EndOffset = words[len(words)-1].end.line, words[len(words)-1].end.col, words[len(words)-1].end.off
+		for _, w := range words[1:] {
+			args.Children = append(args.Children, p.span(slowjson.NodeString, w.text, w.start, w.end))
+		}
+	}
+	argsKey := *args
+	argsKey.Type, argsKey.Value, argsKey.Children = slowjson.NodeString, "args", []*slowjson.Node{args}
+	occ.Children = []*slowjson.Node{&argsKey}
+	if tok.kind != '{' {
+		return occ, nil
+	}
+	block, err := p.block(&tok)
+	if err != nil {
+		return nil, err
+	}
+	blockKey := *block
+	blockKey.Type, blockKey.Value, blockKey.Children = slowjson.NodeString, "block", []*slowjson.Node{block}
+	occ.Children = append(occ.Children, &blockKey)
+	occ.EndLine, occ.EndCol, occ.EndOffset = block.EndLine, block.EndCol, block.EndOffset
+	return occ, nil
+}
+
+// Quote returns s as an argument of an nginx style config, as is when it reads back unchanged and in
+// double quotes otherwise.
+func Quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n;{}#\"'\\") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,148 @@
+package blockconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := `# main config
+worker_processes auto;
+http {
+    server {
+        listen 80;
+        listen 443 ssl; # tls
+        location /api{proxy_pass http://api; }
+        add_header X-Upstream "${upstream_addr} \"up\"";
+        return 301 https://$host$request_uri;
+    }
+    server { listen 8080; }
+}
+`
+	n, err := Parse([]byte(input), "nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"worker_processes":[{"args":["auto"]}],"http":[{"args":[],"block":{"server":[{"args":[],"block":{` +
+		`"listen":[{"args":["80"]},{"args":["443","ssl"]}],` +
+		`"location":[{"args":["/api"],"block":{"proxy_pass":[{"args":["http://api"]}]}}],` +
+		`"add_header":[{"args":["X-Upstream","${upstream_addr} \"up\""]}],` +
+		`"return":[{"args":["301","https://$host$request_uri"]}]}},` +
+		`{"args":[],"block":{"listen":[{"args":["8080"]}]}}]}}]}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	ssl := n.Get("http[0].block.server[0].block.listen[1].args[1]")
+	if ssl.Location() != "nginx.conf:6:20" || ssl.EndCol != 23 {
+		t.Errorf("ssl at %s to col %d", ssl.Location(), ssl.EndCol)
+	}
+	listen := n.Get("http[0].block.server[0].block.listen")
+	if listen.Location() != "nginx.conf:5:9" || listen.EndLine != 6 || listen.Parent.Location() != "nginx.conf:5:9" {
+		t.Errorf("listen at %s to line %d", listen.Location(), listen.EndLine)
+	}
+	if occ := listen.Children[1]; occ.EndCol != 24 {
+		t.Errorf("listen[1] ends at col %d", occ.EndCol)
+	}
+	server := n.Get("http[0].block.server[0]")
+	if server.Location() != "nginx.conf:4:5" || server.EndLine != 10 {
+		t.Errorf("server at %s to line %d", server.Location(), server.EndLine)
+	}
+	if block := server.Get("block"); block.Location() != "nginx.conf:4:12" || block.EndCol != 6 {
+		t.Errorf("server block at %s to col %d", block.Location(), block.EndCol)
+	}
+	header := n.Get("http[0].block.server[0].block.add_header[0].args[1]")
+	if header.Location() != "nginx.conf:8:31" || input[header.StartOffset:header.EndOffset] != `"${upstream_addr} \"up\""` {
+		t.Errorf("header at %s", header.Location())
+	}
+}
+
+func TestParse_Lines(t *testing.T) {
+	input := `{
+	email admin@example.com
+}
+
+example.com, www.example.com {
+	reverse_proxy localhost:{$PORT} {
+		header_up Host {host}
+	}
+	respond "ok; done" 200
+	header X-Raw ` + "`a \"b\"`" + `
+}
+`
+	n, err := Options{Lines: true}.Parse([]byte(input), "Caddyfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"":[{"args":[],"block":{"email":[{"args":["admin@example.com"]}]}}],` +
+		`"example.com,":[{"args":["www.example.com"],"block":{` +
+		`"reverse_proxy":[{"args":["localhost:{$PORT}"],"block":{"header_up":[{"args":["Host","{host}"]}]}}],` +
+		`"respond":[{"args":["ok; done","200"]}],"header":[{"args":["X-Raw","a \"b\""]}]}}]}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	if email := n.Lookup(slowjson.Path{}.Key("").Index(0).Key("block").Key("email").Index(0)); email.Location() != "Caddyfile:2:2" || email.EndCol != 25 {
+		t.Errorf("email at %s to col %d", email.Location(), email.EndCol)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		lines bool
+		input string
+		want  string
+	}{
+		{false, "a 1", `expected ; after the directive a at f:1:4`},
+		{false, "a {\n b 1\n}", `expected ; after the directive b at f:2:5`},
+		{false, "a {\n b;", `unterminated block, expected } at f:1:3`},
+		{false, "}", `unexpected } at f:1:1`},
+		{false, ";", `unexpected ; at f:1:1`},
+		{false, "{ a; }", `expected a directive name before { at f:1:1`},
+		{false, `a "b`, `unterminated string at f:1:3`},
+		{false, `a "b"c;`, `expected a space after the quoted string at f:1:6`},
+		{false, "a ${b c;", `unterminated variable, expected } at f:1:3`},
+		{true, "a {\n b }\n}", `expected a line break after the directive b at f:2:3`},
+		{true, "a {\n", `unterminated block, expected } at f:1:3`},
+	}
+	for _, tt := range tests {
+		_, err := Options{Lines: tt.lines}.Parse([]byte(tt.input), "f")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %s", tt.input, err, tt.want)
+		}
+	}
+	deep := strings.Repeat("a {", slowjson.MaxDepth+1)
+	if _, err := Parse([]byte(deep), ""); err == nil || !strings.Contains(err.Error(), "exceeded max depth") {
+		t.Errorf("Parse(deep) error = %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for s, want := range map[string]string{
+		"80":            "80",
+		"$host":         "$host",
+		"a b":           `"a b"`,
+		`say "hi"`:      `"say \"hi\""`,
+		"a;":            `"a;"`,
+		"":              `""`,
+		"tab\there":     `"tab\there"`,
+		`back\slash{x}`: `"back\\slash{x}"`,
+	} {
+		got := Quote(s)
+		if got != want {
+			t.Errorf("Quote(%q) = %s, want %s", s, got, want)
+		}
+		if n, err := Parse([]byte("a "+got+";"), ""); err != nil || n.Get("a[0].args[0]").Value != s {
+			t.Errorf("Parse(Quote(%q)) = %v", s, err)
+		}
+	}
+}
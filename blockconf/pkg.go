@@ -0,0 +1,22 @@
+// Package blockconf parses brace-block configs such as nginx.conf and Caddyfiles into slowjson nodes
+// with line and column positions, so web server configs can be linted, diffed and explained like JSON.
+//
+// A config is a list of directives, each a name followed by arguments and, optionally, a block of
+// nested directives in braces:
+//
+//	server {
+//	    listen 443 ssl;
+//	    location /api { proxy_pass http://api; }
+//	}
+//
+// Directives may repeat, so a block becomes an object with a list per directive name holding every
+// occurrence in order, and an occurrence an object of its arguments, "args", and of its nested
+// directives, "block", when it has a block. The config above is
+//
+//	{"server": [{"args": [], "block": {
+//	    "listen": [{"args": ["443", "ssl"]}],
+//	    "location": [{"args": ["/api"], "block": {"proxy_pass": [{"args": ["http://api"]}]}}]}}]}
+//
+// so the port is at server[0].block.listen[0].args[0]. Arguments are strings with quotes and escapes
+// removed, and their nodes cover the arguments as written.
+package blockconf
@@ -0,0 +1,33 @@
+package blockconf
+
+import (
+	"context"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FileSource is a config source reading a brace-block config file.
+type FileSource struct {
+	Path    string
+	Options Options
+}
+
+// File creates a source reading the nginx style config at path.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Options.Parse(b, s.Path)
+}
@@ -0,0 +1,212 @@
+package tracedconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// CacheOptions configures Cached.
+type CacheOptions struct {
+	// Dir holds the cache files, one per source.
+	Dir string
+	// TTL is how long a cached payload is served without loading the source, e.g. to start quickly
+	// or spare a rate limited store. Every Load goes to the source when zero.
+	TTL time.Duration
+	// MaxAge limits how old a cached payload may be to be served when the source fails, no limit when zero.
+	MaxAge time.Duration
+	// OnWriteError is called when a loaded payload cannot be written to the cache. The load still
+	// succeeds, and provenance records the failure as the metadata "cache".
+	OnWriteError func(error)
+}
+
+// Cached wraps a remote source so every successful load is written to a cache file, and the cached
+// payload is served while it is fresher than TTL or when the source fails, so a process can start
+// offline with the last known config. Served payloads are recorded in provenance as the metadata
+// "cache", and as a fallback in Config.Health when the source failed.
+// Cache files hold the payload with its SHA-256 hash and fetch time, corrupt files are ignored.
+func Cached(src Source, opts CacheOptions) Source {
+	sum := sha256.Sum256([]byte(src.Name()))
+	path := filepath.Join(opts.Dir, hex.EncodeToString(sum[:8])+".json")
+	return &cachedSource{Source: src, opts: opts, path: path, now: time.Now}
+}
+
+type cachedSource struct {
+	Source
+	opts CacheOptions
+	path string
+	now  func() time.Time
+
+	mu       sync.Mutex
+	served   *cacheEntry // the served cache entry, nil when the source was loaded
+	cause    error
+	writeErr error // why the loaded payload was not cached
+}
+
+// cacheEntry is the content of a cache file.
+type cacheEntry struct {
+	Name    string    `json:"name"`
+	File    string    `json:"file"`
+	Fetched time.Time `json:"fetched"`
+	Hash    string    `json:"hash"`
+	Payload string    `json:"payload"`
+	// Meta is the metadata of the source at fetch time.
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+func payloadHash(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (s *cachedSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	entry, entryErr := s.read()
+	if entryErr == nil && s.opts.TTL > 0 && s.now().Sub(entry.Fetched) < s.opts.TTL {
+		return s.serve(entry, nil)
+	}
+	n, err := s.Source.Load(ctx)
+	if err != nil {
+		if entryErr != nil {
+			return nil, err
+		}
+		if s.opts.MaxAge > 0 && s.now().Sub(entry.Fetched) > s.opts.MaxAge {
+			return nil, fmt.Errorf("%w, cache from %s is too old", err, entry.Fetched.Format(time.RFC3339))
+		}
+		return s.serve(entry, err)
+	}
+	// a source that loads is not failed by its cache, the failure is only reported
+	writeErr := s.write(n)
+	if writeErr != nil {
+		writeErr = fmt.Errorf("write cache: %w", writeErr)
+		if s.opts.OnWriteError != nil {
+			s.opts.OnWriteError(writeErr)
+		}
+	}
+	s.mu.Lock()
+	s.served, s.cause, s.writeErr = nil, nil, writeErr
+	s.mu.Unlock()
+	return n, nil
+}
+
+// serve parses a cache entry, cause is the error of the source when it failed.
+func (s *cachedSource) serve(entry cacheEntry, cause error) (*slowjson.Node, error) {
+	p := slowjson.NewParser(entry.Payload)
+	p.File = entry.File
+	n, err := p.Parse()
+	if err != nil {
+		if cause != nil {
+			return nil, cause
+		}
+		return nil, fmt.Errorf("cache %s: %w", s.path, err)
+	}
+	s.mu.Lock()
+	s.served, s.cause, s.writeErr = &entry, cause, nil
+	s.mu.Unlock()
+	return n, nil
+}
+
+func (s *cachedSource) read() (cacheEntry, error) {
+	var entry cacheEntry
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return entry, err
+	}
+	if entry.Name != s.Name() || entry.Hash != payloadHash(entry.Payload) {
+		return entry, fmt.Errorf("cache %s is corrupt", s.path)
+	}
+	return entry, nil
+}
+
+// write stores n in the cache file. Trees parsed from JSON keep their text, and so their positions,
+// other trees, e.g. of YAML files, are written as indented JSON.
+func (s *cachedSource) write(n *slowjson.Node) error {
+	entry := cacheEntry{Name: s.Name(), File: n.File, Fetched: s.now().UTC()}
+	if ms, ok := s.Source.(MetadataSource); ok {
+		entry.Meta = ms.Metadata()
+	}
+	if text, ok := printedJSON(n); ok {
+		entry.Payload = text
+	} else {
+		b, err := slowjson.MarshalOptions{Indent: "  "}.Marshal(n)
+		if err != nil {
+			return err
+		}
+		entry.Payload, entry.File = string(b), s.path
+	}
+	entry.Hash = payloadHash(entry.Payload)
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.opts.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// printedJSON returns the text n was parsed from when it is JSON holding n. The parsers of other
+// formats keep their source too, printing it gives their syntax.
+func printedJSON(n *slowjson.Node) (string, bool) {
+	if n.Source == "" {
+		return "", false
+	}
+	text := slowjson.Print(n)
+	p, err := slowjson.NewParser(text).Parse()
+	if err != nil || len(slowjson.DiffOptions{}.Diff(n, p)) > 0 {
+		return "", false
+	}
+	return text, true
+}
+
+func (s *cachedSource) Fallback() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cause == nil {
+		return "", nil
+	}
+	return "cache " + s.path, s.cause
+}
+
+func (s *cachedSource) Metadata() map[string]string {
+	s.mu.Lock()
+	served, writeErr := s.served, s.writeErr
+	s.mu.Unlock()
+	if served == nil {
+		var meta map[string]string
+		if ms, ok := s.Source.(MetadataSource); ok {
+			meta = ms.Metadata()
+		}
+		if writeErr == nil {
+			return meta
+		}
+		out := map[string]string{"cache": "not cached: " + writeErr.Error()}
+		for k, v := range meta {
+			out[k] = v
+		}
+		return out
+	}
+	meta := map[string]string{"cache": "served from cache fetched " + served.Fetched.Format(time.RFC3339)}
+	for k, v := range served.Meta {
+		meta[k] = v
+	}
+	return meta
+}
+
+func (s *cachedSource) Changed(ctx context.Context) (bool, error) {
+	return SourceChanged(ctx, s.Source)
+}
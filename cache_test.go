@@ -0,0 +1,99 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	dir := t.TempDir()
+	remote := &flakySource{data: `{"port": 80}`}
+	c := NewConfig(Cached(remote, CacheOptions{Dir: dir}))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new process starts offline with the cached payload
+	remote.fail = errors.New("connection refused")
+	offline := NewConfig(Cached(remote, CacheOptions{Dir: dir}))
+	if err := offline.Load(context.Background()); err != nil {
+		t.Fatalf("Load() offline error = %v", err)
+	}
+	o, _ := offline.Origin("port")
+	if !strings.HasPrefix(o.String(), "remote:1:10 (remote, cache=served from cache fetched ") {
+		t.Errorf("Origin() = %s", o)
+	}
+	if h := offline.Health()[0]; h.Status != StatusDegraded || !strings.HasPrefix(h.Fallback, "cache ") {
+		t.Errorf("Health() = %+v", h)
+	}
+
+	// too old to be served
+	old := Cached(remote, CacheOptions{Dir: dir, MaxAge: time.Hour}).(*cachedSource)
+	old.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if err := NewConfig(old).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "is too old") {
+		t.Errorf("Load() with an old cache error = %v", err)
+	}
+
+	// a corrupt cache is ignored
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("cache dir has %d files", len(entries))
+	}
+	path := dir + "/" + entries[0].Name()
+	b, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte(strings.Replace(string(b), `\"port\": 80`, `\"port\": 81`, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewConfig(Cached(remote, CacheOptions{Dir: dir})).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Load() with a corrupt cache error = %v", err)
+	}
+}
+
+func TestCached_WriteError(t *testing.T) {
+	// the cache dir cannot be created below a file
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var reported error
+	c := NewConfig(Cached(&flakySource{data: `{"port": 80}`}, CacheOptions{
+		Dir:          filepath.Join(file, "cache"),
+		OnWriteError: func(err error) { reported = err },
+	}))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if reported == nil || !strings.HasPrefix(reported.Error(), "write cache: ") {
+		t.Errorf("OnWriteError got %v", reported)
+	}
+	if o, _ := c.Origin("port"); !strings.HasPrefix(o.Meta["cache"], "not cached: write cache: ") {
+		t.Errorf("Origin() = %s", o)
+	}
+	if h := c.Health()[0]; h.Status != StatusHealthy {
+		t.Errorf("Health() = %+v", h)
+	}
+}
+
+func TestCached_TTL(t *testing.T) {
+	remote := &flakySource{data: `{"port": 80}`}
+	c := NewConfig(Cached(remote, CacheOptions{Dir: t.TempDir(), TTL: time.Hour}))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// within the TTL the source is not loaded, so its failure does not matter
+	remote.fail = errors.New("rate limited")
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Health()[0]; h.Status != StatusHealthy {
+		t.Errorf("Health() = %+v", h)
+	}
+	if o, _ := c.Origin("port"); !strings.HasPrefix(o.Meta["cache"], "served from cache") {
+		t.Errorf("Origin() = %s", o)
+	}
+}
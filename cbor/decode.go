@@ -0,0 +1,355 @@
+package cbor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+const (
+	majorUint = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+const (
+	aiIndefinite = 31
+	breakByte    = 0xff
+)
+
+const (
+	tagDateTime     = 0
+	tagEpoch        = 1
+	tagPositiveBig  = 2
+	tagNegativeBig  = 3
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+)
+
+// Decode decodes a single CBOR data item into a node tree.
+// Byte strings become base64 strings, epoch timestamps become RFC3339 strings
+// and unknown tags are dropped in favor of their content.
+func Decode(data []byte) (*slowjson.Node, error) {
+	d := &decoder{data: data}
+	n, err := d.decodeValue(0)
+	if err != nil {
+		return n, err
+	}
+	slowjson.SetParents(n)
+	if d.pos != len(d.data) {
+		return n, fmt.Errorf("unexpected trailing data at offset %d", d.pos)
+	}
+	return n, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) decodeValue(depth int) (*slowjson.Node, error) {
+	if depth > slowjson.MaxDepth {
+		return nil, fmt.Errorf("exceeded max depth %d at offset %d", slowjson.MaxDepth, d.pos)
+	}
+	n := &slowjson.Node{StartOffset: d.pos}
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	major, ai := b>>5, b&0x1f
+	if ai == aiIndefinite {
+		switch major {
+		case majorBytes, majorText:
+			return d.decodeChunks(n, major)
+		case majorArray:
+			return d.decodeArray(n, -1, depth)
+		case majorMap:
+			return d.decodeMap(n, -1, depth)
+		default:
+			return n, fmt.Errorf("unexpected indefinite length for major type %d at offset %d", major, n.StartOffset)
+		}
+	}
+	if major == majorSimple {
+		return d.decodeSimple(n, ai)
+	}
+	arg, err := d.readArgument(ai)
+	if err != nil {
+		return n, err
+	}
+	switch major {
+	case majorUint:
+		d.setNumber(n, strconv.FormatUint(arg, 10))
+	case majorNegInt:
+		d.setNumber(n, negative(arg))
+	case majorBytes, majorText:
+		raw, err := d.readBytes(arg)
+		if err != nil {
+			return n, err
+		}
+		n.Type, n.Value = slowjson.NodeString, stringValue(major, raw)
+		n.EndOffset = d.pos
+	case majorArray:
+		return d.decodeArray(n, int64(arg), depth)
+	case majorMap:
+		return d.decodeMap(n, int64(arg), depth)
+	case majorTag:
+		return d.decodeTag(n, arg, depth)
+	}
+	return n, nil
+}
+
+func (d *decoder) decodeSimple(n *slowjson.Node, ai byte) (*slowjson.Node, error) {
+	switch ai {
+	case simpleFalse:
+		n.Type, n.Value = slowjson.NodeBoolean, "false"
+	case simpleTrue:
+		n.Type, n.Value = slowjson.NodeBoolean, "true"
+	case simpleNull, simpleUndefined:
+		n.Type, n.Value = slowjson.NodeNull, "null"
+	case 25:
+		v, err := d.readUint(2)
+		if err != nil {
+			return n, err
+		}
+		if err := d.setFloat(n, float64(halfToFloat(uint16(v))), 32); err != nil {
+			return n, err
+		}
+	case 26:
+		v, err := d.readUint(4)
+		if err != nil {
+			return n, err
+		}
+		if err := d.setFloat(n, float64(math.Float32frombits(uint32(v))), 32); err != nil {
+			return n, err
+		}
+	case 27:
+		v, err := d.readUint(8)
+		if err != nil {
+			return n, err
+		}
+		if err := d.setFloat(n, math.Float64frombits(v), 64); err != nil {
+			return n, err
+		}
+	default:
+		return n, fmt.Errorf("unsupported simple value %d at offset %d", ai, n.StartOffset)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeTag(n *slowjson.Node, tag uint64, depth int) (*slowjson.Node, error) {
+	inner, err := d.decodeValue(depth + 1)
+	if err != nil {
+		return n, err
+	}
+	// The tag is part of the item, so the span starts at the tag.
+	inner.StartOffset = n.StartOffset
+	switch tag {
+	case tagDateTime:
+		if inner.Type != slowjson.NodeString {
+			return inner, fmt.Errorf("date/time tag expects a text string at offset %d", n.StartOffset)
+		}
+	case tagEpoch:
+		if inner.Type != slowjson.NodeNumber {
+			return inner, fmt.Errorf("epoch tag expects a number at offset %d", n.StartOffset)
+		}
+		f, err := strconv.ParseFloat(inner.Value, 64)
+		if err != nil {
+			return inner, fmt.Errorf("invalid epoch %q at offset %d: %w", inner.Value, n.StartOffset, err)
+		}
+		sec, frac := math.Modf(f)
+		inner.Type = slowjson.NodeString
+		inner.Value = time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+	case tagPositiveBig, tagNegativeBig:
+		raw, err := base64.StdEncoding.DecodeString(inner.Value)
+		if inner.Type != slowjson.NodeString || err != nil {
+			return inner, fmt.Errorf("bignum tag expects a byte string at offset %d", n.StartOffset)
+		}
+		v := new(big.Int).SetBytes(raw)
+		if tag == tagNegativeBig {
+			v.Neg(v).Sub(v, big.NewInt(1))
+		}
+		inner.Type, inner.Value = slowjson.NodeNumber, v.String()
+	}
+	return inner, nil
+}
+
+func (d *decoder) decodeMap(n *slowjson.Node, size int64, depth int) (*slowjson.Node, error) {
+	n.Type = slowjson.NodeObject
+	n.Children = []*slowjson.Node{}
+	for i := int64(0); size < 0 || i < size; i++ {
+		if size < 0 && d.atBreak() {
+			break
+		}
+		key, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		if key.Type != slowjson.NodeString && key.Type != slowjson.NodeNumber {
+			return n, fmt.Errorf("map key must be a string or integer at offset %d", key.StartOffset)
+		}
+		key.Type = slowjson.NodeString
+		value, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		key.Children = []*slowjson.Node{value}
+		n.Children = append(n.Children, key)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeArray(n *slowjson.Node, size int64, depth int) (*slowjson.Node, error) {
+	n.Type = slowjson.NodeArray
+	n.Children = []*slowjson.Node{}
+	for i := int64(0); size < 0 || i < size; i++ {
+		if size < 0 && d.atBreak() {
+			break
+		}
+		child, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		n.Children = append(n.Children, child)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+// decodeChunks decodes an indefinite length string made of definite length chunks.
+func (d *decoder) decodeChunks(n *slowjson.Node, major byte) (*slowjson.Node, error) {
+	var sb strings.Builder
+	for !d.atBreak() {
+		b, err := d.readByte()
+		if err != nil {
+			return n, err
+		}
+		if b>>5 != major || b&0x1f == aiIndefinite {
+			return n, fmt.Errorf("invalid chunk in indefinite length string at offset %d", d.pos-1)
+		}
+		size, err := d.readArgument(b & 0x1f)
+		if err != nil {
+			return n, err
+		}
+		raw, err := d.readBytes(size)
+		if err != nil {
+			return n, err
+		}
+		sb.Write(raw)
+	}
+	n.Type, n.Value = slowjson.NodeString, stringValue(major, []byte(sb.String()))
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+// atBreak consumes the break marker of an indefinite length item if it is next.
+func (d *decoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == breakByte {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *decoder) readArgument(ai byte) (uint64, error) {
+	switch {
+	case ai < 24:
+		return uint64(ai), nil
+	case ai <= 27:
+		return d.readUint(1 << (ai - 24))
+	default:
+		return 0, fmt.Errorf("invalid additional info %d at offset %d", ai, d.pos-1)
+	}
+}
+
+func (d *decoder) setNumber(n *slowjson.Node, v string) {
+	n.Type, n.Value = slowjson.NodeNumber, v
+	n.EndOffset = d.pos
+}
+
+// setFloat sets n to the float f of the given bit size, NaN and infinities have no JSON number and are rejected.
+func (d *decoder) setFloat(n *slowjson.Node, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return &slowjson.ParseError{Pos: slowjson.Position{Offset: n.StartOffset}, Msg: fmt.Sprintf("non-finite float %v", f)}
+	}
+	d.setNumber(n, strconv.FormatFloat(f, 'g', -1, bitSize))
+	return nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("unexpected end of input at offset %d", d.pos)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readBytes(size uint64) ([]byte, error) {
+	if size > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("unexpected end of input at offset %d, need %d bytes", d.pos, size)
+	}
+	b := d.data[d.pos : d.pos+int(size)]
+	d.pos += int(size)
+	return b, nil
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.readBytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func stringValue(major byte, raw []byte) string {
+	if major == majorBytes {
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+	return string(raw)
+}
+
+// negative returns the decimal form of -1-arg without overflowing int64.
+func negative(arg uint64) string {
+	if arg == math.MaxUint64 {
+		return "-18446744073709551616"
+	}
+	return "-" + strconv.FormatUint(arg+1, 10)
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		f := float32(frac) / 1024 / (1 << 14)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | frac<<13)
+	default:
+		return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+	}
+}
@@ -0,0 +1,96 @@
+package cbor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDecode(t *testing.T) {
+	// {"port": 8080, "neg": -10, "list": [_ 1, 2], "t": 1(1700000000)}
+	data := []byte{0xa4,
+		0x64, 'p', 'o', 'r', 't', 0x19, 0x1f, 0x90,
+		0x63, 'n', 'e', 'g', 0x29,
+		0x64, 'l', 'i', 's', 't', 0x9f, 0x01, 0x02, 0xff,
+		0x61, 't', 0xc1, 0x1a, 0x65, 0x53, 0xf1, 0x00,
+	}
+	n, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if n.Type != slowjson.NodeObject || len(n.Children) != 4 {
+		t.Fatalf("Decode() got type = %v, len = %v", n.Type, len(n.Children))
+	}
+	port := n.Children[0].Children[0]
+	if port.Value != "8080" || port.StartOffset != 6 || port.EndOffset != 9 {
+		t.Errorf("Decode() got port %q [%d, %d)", port.Value, port.StartOffset, port.EndOffset)
+	}
+	if got := n.Children[1].Children[0].Value; got != "-10" {
+		t.Errorf("Decode() got neg = %v", got)
+	}
+	list := n.Children[2].Children[0]
+	if list.Type != slowjson.NodeArray || len(list.Children) != 2 || list.EndOffset != 23 {
+		t.Errorf("Decode() got list len = %d end = %d", len(list.Children), list.EndOffset)
+	}
+	ts := n.Children[3].Children[0]
+	if ts.Value != "2023-11-14T22:13:20Z" || ts.StartOffset != 25 {
+		t.Errorf("Decode() got t %q at %d", ts.Value, ts.StartOffset)
+	}
+}
+
+func TestDecode_Scalars(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		wantType slowjson.NodeType
+		wantVal  string
+	}{
+		{"half float", []byte{0xf9, 0x3c, 0x00}, slowjson.NodeNumber, "1"},
+		{"float64", []byte{0xfb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, slowjson.NodeNumber, "1.5"},
+		{"undefined", []byte{0xf7}, slowjson.NodeNull, "null"},
+		{"bytes", []byte{0x42, 0x01, 0x02}, slowjson.NodeString, "AQI="},
+		{"indefinite text", []byte{0x7f, 0x61, 'a', 0x62, 'b', 'c', 0xff}, slowjson.NodeString, "abc"},
+		{"bignum", []byte{0xc2, 0x49, 0x01, 0, 0, 0, 0, 0, 0, 0, 0}, slowjson.NodeNumber, "18446744073709551616"},
+		{"min negative", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, slowjson.NodeNumber, "-18446744073709551616"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Decode(tt.input)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if n.Type != tt.wantType || n.Value != tt.wantVal {
+				t.Errorf("Decode() got %v %q, want %v %q", n.Type, n.Value, tt.wantType, tt.wantVal)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []byte
+		wantError string
+	}{
+		{"empty", []byte{}, "unexpected end of input at offset 0"},
+		{"truncated text", []byte{0x65, 'a'}, "unexpected end of input at offset 1"},
+		{"invalid key", []byte{0xa1, 0x80, 0x01}, "map key must be a string or integer at offset 1"},
+		{"bad chunk", []byte{0x7f, 0x41, 0x00, 0xff}, "invalid chunk in indefinite length string at offset 1"},
+		{"trailing", []byte{0x01, 0x02}, "unexpected trailing data at offset 1"},
+		{"half NaN", []byte{0x81, 0xf9, 0x7e, 0x00}, "non-finite float NaN at offset 1"},
+		{"single infinity", []byte{0xfa, 0x7f, 0x80, 0x00, 0x00}, "non-finite float +Inf at offset 0"},
+		{"double negative infinity", []byte{0xa1, 0x61, 'a', 0xfb, 0xff, 0xf0, 0, 0, 0, 0, 0, 0}, "non-finite float -Inf at offset 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.input)
+			if err == nil {
+				t.Fatal("Decode() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Decode() error = %v, want error containing %v", err, tt.wantError)
+			}
+		})
+	}
+}
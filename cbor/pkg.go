@@ -0,0 +1,7 @@
+// Package cbor decodes CBOR (RFC 8949) payloads into slowjson nodes that record the byte offsets of
+// every data item, since binary input has no lines to point at.
+//
+// Tags are resolved to JSON values: date/time strings stay strings, epoch times become RFC3339 strings
+// and bignums become numbers, other tags give way to their content. Byte strings become base64 strings,
+// undefined becomes null, and indefinite-length strings, arrays and maps are accepted.
+package cbor
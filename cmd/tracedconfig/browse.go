@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runBrowse merges config files, later ones overriding earlier ones, and explores the result
+// interactively with commands read from stdin, see browseHelp.
+func runBrowse(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig browse file...")
+		fmt.Fprint(stderr, browseHelp)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	var sources []tracedconfig.Source
+	for _, file := range fs.Args() {
+		sources = append(sources, tracedconfig.File(file))
+	}
+	c := tracedconfig.NewConfig(sources...)
+	if err := c.Load(context.Background()); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig browse: %v\n", err)
+		return 1
+	}
+	b := &browser{config: c, cur: c.Root(), out: stdout}
+	sc := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprintf(stdout, "%s> ", b.name(b.cur))
+		if !sc.Scan() {
+			fmt.Fprintln(stdout)
+			return 0
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if cmd == "quit" || cmd == "q" {
+			return 0
+		}
+		if err := b.run(cmd, strings.TrimSpace(arg)); err != nil {
+			fmt.Fprintf(stdout, "error: %v\n", err)
+		}
+	}
+}
+
+const browseHelp = `commands:
+  ls [path]        list the children of a value with their types and values
+  tree [depth]     show the values below the current one, 3 levels by default
+  cd [path|..]     move to a value, the root without a path
+  show [path]      print a value with its type, span and the sources that set it
+  def [path]       jump to the definition, printing the source lines that set the value
+  find text        list the paths whose key or value contains text
+  help             print this help
+  quit             exit
+paths are relative to the current value, or absolute when starting with $, e.g. $.servers[0].port
+`
+
+// browser is the state of a browse session.
+type browser struct {
+	config *tracedconfig.Config
+	cur    *slowjson.Node
+	out    io.Writer
+}
+
+func (b *browser) run(cmd, arg string) error {
+	switch cmd {
+	case "":
+		return nil
+	case "help", "?":
+		fmt.Fprint(b.out, browseHelp)
+		return nil
+	case "ls":
+		n, err := b.resolve(arg)
+		if err != nil {
+			return err
+		}
+		b.tree(n, 0, 1)
+		return nil
+	case "tree":
+		depth := 3
+		if arg != "" {
+			d, err := strconv.Atoi(arg)
+			if err != nil || d < 1 {
+				return fmt.Errorf("invalid depth %q", arg)
+			}
+			depth = d
+		}
+		b.tree(b.cur, 0, depth)
+		return nil
+	case "cd":
+		n, err := b.resolve(arg)
+		if err != nil {
+			return err
+		}
+		b.cur = n
+		return nil
+	case "show":
+		n, err := b.resolve(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b.out, "type: %s\nspan: %s\n", typeOf(n), n.Location())
+		explain, err := b.config.Explain(n.Path().String())
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(b.out, explain)
+		return nil
+	case "def":
+		n, err := b.resolve(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(b.out, n.Location())
+		if n.Source != "" {
+			fmt.Fprint(b.out, n.DebugContext(2, 2))
+		}
+		return nil
+	case "find":
+		if arg == "" {
+			return fmt.Errorf("find needs the text to search for")
+		}
+		found := 0
+		b.find(b.config.Root(), arg, &found)
+		if found == 0 {
+			fmt.Fprintf(b.out, "no matches for %q\n", arg)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, try help", cmd)
+	}
+}
+
+// resolve finds the value at path, relative to the current value unless it starts with $.
+func (b *browser) resolve(path string) (*slowjson.Node, error) {
+	switch {
+	case path == "":
+		return b.cur, nil
+	case path == "..":
+		for p := b.cur.Parent; p != nil; p = p.Parent {
+			if !p.IsKey() {
+				return p, nil
+			}
+		}
+		return b.config.Root(), nil
+	}
+	base := b.cur
+	if strings.HasPrefix(path, "$") {
+		base = b.config.Root()
+		path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+		if path == "" {
+			return base, nil
+		}
+	}
+	p, err := slowjson.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	n := base.Lookup(p)
+	if n == nil {
+		return nil, fmt.Errorf("%s is not set", path)
+	}
+	return n, nil
+}
+
+// name returns the absolute path of n for the prompt and listings.
+func (b *browser) name(n *slowjson.Node) string {
+	if p := n.Path().String(); p != "" {
+		return "$." + p
+	}
+	return "$"
+}
+
+// tree prints the children of n down to depth levels.
+func (b *browser) tree(n *slowjson.Node, level, depth int) {
+	if level == depth {
+		return
+	}
+	indent := strings.Repeat("  ", level)
+	switch n.Type {
+	case slowjson.NodeObject:
+		for _, key := range n.Children {
+			v := key.Children[0]
+			fmt.Fprintf(b.out, "%s%s: %s  (%s)\n", indent, key.Value, summary(v), v.Location())
+			b.tree(v, level+1, depth)
+		}
+	case slowjson.NodeArray:
+		for i, v := range n.Children {
+			fmt.Fprintf(b.out, "%s[%d]: %s  (%s)\n", indent, i, summary(v), v.Location())
+			b.tree(v, level+1, depth)
+		}
+	default:
+		if level == 0 {
+			fmt.Fprintf(b.out, "%s  (%s)\n", summary(n), n.Location())
+		}
+	}
+}
+
+// find prints the paths below n whose key or scalar value contains text.
+func (b *browser) find(n *slowjson.Node, text string, found *int) {
+	for _, c := range n.Children {
+		v := c
+		match := false
+		if c.IsKey() {
+			v = c.Children[0]
+			match = strings.Contains(c.Value, text)
+		}
+		if v.Type != slowjson.NodeObject && v.Type != slowjson.NodeArray && strings.Contains(v.Value, text) {
+			match = true
+		}
+		if match {
+			*found++
+			fmt.Fprintf(b.out, "%s = %s  (%s)\n", b.name(v), summary(v), v.Location())
+		}
+		b.find(v, text, found)
+	}
+}
+
+// summary shows scalars as JSON and containers by their size.
+func summary(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeObject:
+		return fmt.Sprintf("{%d keys}", len(n.Children))
+	case slowjson.NodeArray:
+		return fmt.Sprintf("[%d items]", len(n.Children))
+	}
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		return n.Value
+	}
+	if s := string(b); len(s) <= 60 {
+		return s
+	}
+	return string(b[:57]) + "..."
+}
+
+// typeOf names the JSON type of n.
+func typeOf(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeObject:
+		return "object"
+	case slowjson.NodeArray:
+		return "array"
+	case slowjson.NodeString:
+		return "string"
+	case slowjson.NodeNumber:
+		return "number"
+	case slowjson.NodeBoolean:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
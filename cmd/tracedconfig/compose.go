@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/at15/tracedconfig/compose"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// composeFiles are the default compose files in the order docker compose looks for them, each with
+// the override file merged after it.
+var composeFiles = [][2]string{
+	{"compose.yaml", "compose.override.yaml"},
+	{"compose.yml", "compose.override.yml"},
+	{"docker-compose.yaml", "docker-compose.override.yaml"},
+	{"docker-compose.yml", "docker-compose.override.yml"},
+}
+
+// runCompose loads a compose project like docker compose, see compose.Load, to debug why a container
+// gets a variable. Without a service it prints the merged model, with one the environment of its
+// container and where every variable is set, and with variable names how each got its value: the
+// compose files and env_files setting it and the .env entries interpolated into it. It exits with 1
+// when a variable is not set.
+func runCompose(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compose", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts compose.Options
+	fs.Var((*stringList)(&opts.Files), "f", "compose `file`, repeatable, default compose.yaml and compose.override.yaml")
+	fs.StringVar(&opts.EnvFile, "env-file", "", "`file` with the variables for interpolation, default .env next to the first compose file")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig compose [-f file]... [-env-file file] [service [variable...]]")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(opts.Files) == 0 {
+		for _, pair := range composeFiles {
+			if _, err := os.Stat(pair[0]); err != nil {
+				continue
+			}
+			opts.Files = append(opts.Files, pair[0])
+			if _, err := os.Stat(pair[1]); err == nil {
+				opts.Files = append(opts.Files, pair[1])
+			}
+			break
+		}
+		if len(opts.Files) == 0 {
+			fmt.Fprintln(stderr, "tracedconfig compose: no compose.yaml or docker-compose.yml in the current directory, use -f")
+			return 2
+		}
+	}
+	p, err := compose.Load(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig compose: %v\n", err)
+		return 2
+	}
+	if len(rest) == 0 {
+		warn(stderr, p)
+		b, err := slowjson.MarshalOptions{Indent: "  "}.Marshal(p.Root)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig compose: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "%s\n", b)
+		return 0
+	}
+	env, err := p.Environment(rest[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig compose: %v\n", err)
+		return 2
+	}
+	warn(stderr, p)
+	if len(rest) == 1 {
+		tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+		for _, key := range env.Root.Children {
+			o, _ := env.Origin(slowjson.Path{}.Key(key.Value).String())
+			fmt.Fprintf(tw, "%s=%s\t%s\n", key.Value, key.Children[0].Value, o)
+		}
+		tw.Flush()
+		return 0
+	}
+	code := 0
+	for _, name := range rest[1:] {
+		explain, err := env.Explain(slowjson.Path{}.Key(name).String())
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig compose: %v in the environment of %s\n", err, rest[0])
+			code = 1
+			continue
+		}
+		fmt.Fprint(stdout, explain)
+		history, _ := env.History(slowjson.Path{}.Key(name).String())
+		for i := len(history) - 1; i >= 0; i-- {
+			for _, s := range p.Substitutions {
+				if s.Node == history[i].Node {
+					fmt.Fprintf(stdout, "  %s in %s expands %s\n", s.Text, history[i].Node.Location(), substitutionSource(s))
+				}
+			}
+		}
+	}
+	return code
+}
+
+// substitutionSource describes where the variable of s is set.
+func substitutionSource(s compose.Substitution) string {
+	switch {
+	case s.From == nil:
+		return s.Name + ", which is not set"
+	case s.From.File == compose.Environ:
+		return fmt.Sprintf("%s=%q from the environment", s.Name, s.From.Value)
+	default:
+		return fmt.Sprintf("%s=%q from %s", s.Name, s.From.Value, s.From.Location())
+	}
+}
+
+// warn prints the warnings of loading p, e.g. about unset variables.
+func warn(stderr io.Writer, p *compose.Project) {
+	for _, d := range p.Diagnostics {
+		fmt.Fprintf(stderr, "tracedconfig compose: %s\n", strings.TrimSpace(d.String()))
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/lint"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// defaultConfigName is the CLI config file read from the working directory when -config is not given.
+const defaultConfigName = ".tracedconfig.json"
+
+// cliConfig is the CLI config file, each command reads its own section. Command flags take precedence over it.
+//
+//	{
+//	  "lint": {
+//	    "rules": {"TC1002": "off", "similar-key": "error"},
+//	    "failOn": "warning",
+//	    "schema": "app.schema.json",
+//	    "baseline": "lint-baseline.json"
+//	  },
+//	  "normalize": {
+//	    "sortKeys": true,
+//	    "arrays": "auto",
+//	    "profiles": {"k8s": {"indent": 4, "arrays": "expand"}}
+//	  }
+//	}
+type cliConfig struct {
+	Lint      lintConfig      `json:"lint"`
+	Normalize normalizeConfig `json:"normalize"`
+}
+
+type lintConfig struct {
+	// Rules are rule settings keyed by rule name or code, see lint.Runner.Configure.
+	Rules map[string]string `json:"rules"`
+	// FailOn is the lowest severity that fails the run: "error", the default, "warning", "info" or "never".
+	FailOn string `json:"failOn"`
+	// Schema and Baseline are files relative to the config file.
+	Schema   string `json:"schema"`
+	Baseline string `json:"baseline"`
+}
+
+// normalizeConfig is the default style of normalize, Profiles are named styles selected with -profile.
+type normalizeConfig struct {
+	styleConfig
+	Profiles map[string]styleConfig `json:"profiles"`
+}
+
+type styleConfig struct {
+	// Indent is the number of spaces per level, 2 by default, Tabs indents with tabs instead.
+	Indent int  `json:"indent"`
+	Tabs   bool `json:"tabs"`
+	// SortKeys, NormalizeNumbers, Arrays and MaxWidth are those of slowjson.Style.
+	SortKeys         bool   `json:"sortKeys"`
+	NormalizeNumbers bool   `json:"normalizeNumbers"`
+	Arrays           string `json:"arrays"`
+	MaxWidth         int    `json:"maxWidth"`
+}
+
+// style returns the slowjson.Style of c.
+func (c styleConfig) style() slowjson.Style {
+	s := slowjson.Style{SortKeys: c.SortKeys, NormalizeNumbers: c.NormalizeNumbers, Arrays: c.Arrays, MaxWidth: c.MaxWidth}
+	switch {
+	case c.Tabs:
+		s.Indent = "\t"
+	case c.Indent > 0:
+		s.Indent = strings.Repeat(" ", c.Indent)
+	}
+	return s
+}
+
+// validArrays reports whether s is an array layout of slowjson.Style, empty being the default.
+func validArrays(s string) bool {
+	switch s {
+	case "", slowjson.ArraysAuto, slowjson.ArraysExpand, slowjson.ArraysCollapse:
+		return true
+	}
+	return false
+}
+
+// loadConfig reads the config file at path, or the default file when path is empty.
+// A missing default file is an empty config.
+func loadConfig(path string) (*cliConfig, error) {
+	c := &cliConfig{}
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigName
+	}
+	root, err := slowjson.ParseFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tracedconfig.Decode(root, c); err != nil {
+		return nil, err
+	}
+	if n := root.Get("lint.failOn"); n != nil {
+		if _, err := parseFailOn(c.Lint.FailOn); err != nil {
+			return nil, n.Errorf("%w", err)
+		}
+	}
+	arrays := []*slowjson.Node{root.Get("normalize.arrays")}
+	if profiles := root.Get("normalize.profiles"); profiles != nil {
+		for _, k := range profiles.Children {
+			arrays = append(arrays, k.Children[0].Get("arrays"))
+		}
+	}
+	for _, n := range arrays {
+		if n != nil && !validArrays(n.Value) {
+			return nil, n.Errorf("invalid arrays %q, expected auto, expand or collapse", n.Value)
+		}
+	}
+	dir := filepath.Dir(path)
+	for _, p := range []*string{&c.Lint.Schema, &c.Lint.Baseline} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return c, nil
+}
+
+// failNever is a fail-on threshold no finding reaches.
+const failNever diag.Severity = -1
+
+// parseFailOn parses a fail-on threshold, empty is "error".
+func parseFailOn(s string) (diag.Severity, error) {
+	switch s {
+	case "":
+		return diag.SeverityError, nil
+	case "never":
+		return failNever, nil
+	}
+	sev, err := lint.ParseSeverity(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fail-on %q, expected error, warning, info or never", s)
+	}
+	return sev, nil
+}
+
+// fails reports whether any diagnostic is at least as severe as the threshold.
+func fails(diags []diag.Diagnostic, threshold diag.Severity) bool {
+	for _, d := range diags {
+		if d.Severity <= threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/merge"
+)
+
+// runConflicts merges the files as layers, in order, in a strict merge and reports every key two
+// layers set to different values without the later one listing it in its @override list, see
+// merge.Options.Strict. Findings show both definitions. It exits with 1 when there is a conflict.
+func runConflicts(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("conflicts", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text or github")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig conflicts [-format text|github] layer...")
+		fmt.Fprintln(stderr, "layers are merged in order, later ones overriding earlier ones")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || *format != "text" && *format != "github" {
+		fs.Usage()
+		return 2
+	}
+	var layers []merge.Layer
+	for _, file := range rest {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig conflicts: %v\n", err)
+			return 2
+		}
+		layers = append(layers, merge.Layer{Name: file, Root: root})
+	}
+	res, err := merge.Options{Strict: true}.Merge(layers...)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig conflicts: %v\n", err)
+		return 2
+	}
+	diags := res.Diagnostics
+	if *format == "github" {
+		if err := diag.WriteGitHub(stdout, diags); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig conflicts: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprint(stdout, d.Render(1, 1))
+		}
+	}
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
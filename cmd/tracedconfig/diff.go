@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runDiff prints the structural changes between two config files, as one line per change or as a
+// Markdown or HTML report for pull requests and change tickets. It exits with 1 when they differ, like diff.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text, markdown or html")
+	ignoreOrder := fs.Bool("ignore-order", false, "do not report objects whose keys only differ in order")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig diff [-format text|markdown|html] [-ignore-order] old new")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || *format != "text" && *format != "markdown" && *format != "html" {
+		fs.Usage()
+		return 2
+	}
+	var trees [2]*slowjson.Node
+	for i, file := range fs.Args() {
+		n, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig diff: %v\n", err)
+			return 2
+		}
+		trees[i] = n
+	}
+	changes := slowjson.DiffOptions{IgnoreOrder: *ignoreOrder}.Diff(trees[0], trees[1])
+	switch *format {
+	case "markdown":
+		fmt.Fprint(stdout, slowjson.MarkdownDiff(changes))
+	case "html":
+		fmt.Fprint(stdout, slowjson.HTMLDiff(changes))
+	default:
+		for _, c := range changes {
+			fmt.Fprintln(stdout, c)
+		}
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
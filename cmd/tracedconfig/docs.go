@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/schema"
+)
+
+// runDocs prints a Markdown reference of a config struct read from Go source.
+func runDocs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeName := fs.String("type", "", "name of the config struct `type`")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig docs -type Config [package dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *typeName == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	s, err := tracedconfig.GenerateSchemaFromSource(dir, *typeName)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig docs: %v\n", err)
+		return 1
+	}
+	if err := schema.Markdown(stdout, s); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig docs: %v\n", err)
+		return 1
+	}
+	return 0
+}
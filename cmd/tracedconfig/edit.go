@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/blockconf"
+	"github.com/at15/tracedconfig/properties"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/table"
+	"github.com/at15/tracedconfig/tfvars"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// edit replaces the bytes [start, end) of a file with text. Commands edit config files by replacing the
+// text of nodes, so formatting and comments elsewhere are kept.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// sortEdits orders edits by position and drops edits within an earlier edit: repeated edits of the same
+// span, e.g. of values a YAML alias copied, and edits of values inside a replaced object or array.
+func sortEdits(edits []edit) []edit {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	var out []edit
+	for _, e := range edits {
+		if last := len(out) - 1; last >= 0 && (e.start < out[last].end || e.start == out[last].start && e.end == out[last].end) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// applyEdits returns src with the sorted, non-overlapping edits applied, offsets are relative to base.
+func applyEdits(src string, base int, edits []edit) string {
+	var b strings.Builder
+	at := 0
+	for _, e := range edits {
+		b.WriteString(src[at : e.start-base])
+		b.WriteString(e.text)
+		at = e.end - base
+	}
+	b.WriteString(src[at:])
+	return b.String()
+}
+
+// writeDiff writes the sorted edits of the file name as a unified diff without context lines.
+func writeDiff(w io.Writer, name, src string, edits []edit) {
+	if len(edits) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", name, name)
+	lineStart := func(off int) int { return strings.LastIndexByte(src[:off], '\n') + 1 }
+	lineEnd := func(off int) int {
+		if i := strings.IndexByte(src[off:], '\n'); i >= 0 {
+			return off + i
+		}
+		return len(src)
+	}
+	shift := 0
+	for i := 0; i < len(edits); {
+		// edits touching the same lines form one hunk
+		from, to := lineStart(edits[i].start), lineEnd(edits[i].end)
+		j := i + 1
+		for j < len(edits) && edits[j].start <= to {
+			to = lineEnd(edits[j].end)
+			j++
+		}
+		old := src[from:to]
+		changed := applyEdits(old, from, edits[i:j])
+		oldLines, newLines := strings.Split(old, "\n"), strings.Split(changed, "\n")
+		line := strings.Count(src[:from], "\n") + 1
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", line, len(oldLines), line+shift, len(newLines))
+		for _, l := range oldLines {
+			fmt.Fprintf(w, "-%s\n", l)
+		}
+		for _, l := range newLines {
+			fmt.Fprintf(w, "+%s\n", l)
+		}
+		shift += len(newLines) - len(oldLines)
+		i = j
+	}
+}
+
+// render formats n as a value of a config file of format, on a single line.
+func render(n *slowjson.Node, format string) (string, error) {
+	switch format {
+	case formatYAML:
+		if n.Type == slowjson.NodeString && !strings.ContainsAny(n.Value, "\n\r") {
+			// write the string plain when YAML reads it back as the same string
+			if v, err := yaml.Parse([]byte("v: "+n.Value), ""); err == nil {
+				if s := v.Get("v"); s != nil && s.Type == slowjson.NodeString && s.Value == n.Value {
+					return n.Value, nil
+				}
+			}
+		}
+	case formatTOML:
+		return renderTOML(n)
+	case formatTFVars:
+		if n.Type == slowjson.NodeString {
+			return tfvars.Quote(n.Value), nil
+		}
+	case formatINI:
+		if n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray || strings.ContainsAny(n.Value, "\n\r") {
+			return "", fmt.Errorf("INI values are single lines")
+		}
+		return n.Value, nil
+	case formatBlock:
+		if n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray {
+			return "", fmt.Errorf("directive arguments are single words")
+		}
+		return blockconf.Quote(n.Value), nil
+	case formatProperties:
+		if n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray {
+			return "", fmt.Errorf(".properties values are strings")
+		}
+		return properties.Escape(n.Value), nil
+	case formatCSV, formatTSV:
+		if n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray {
+			return "", fmt.Errorf("table cells are strings")
+		}
+		if format == formatTSV {
+			return table.Quote(n.Value, '\t'), nil
+		}
+		return table.Quote(n.Value, ','), nil
+	}
+	// JSON is valid YAML flow style
+	b, err := slowjson.Marshal(n)
+	return string(b), err
+}
+
+func renderTOML(n *slowjson.Node) (string, error) {
+	switch n.Type {
+	case slowjson.NodeNull:
+		return "", fmt.Errorf("TOML has no null")
+	case slowjson.NodeObject:
+		parts := make([]string, len(n.Children))
+		for i, k := range n.Children {
+			key, err := slowjson.Marshal(k)
+			if err != nil {
+				return "", err
+			}
+			if isTOMLBareKey(k.Value) {
+				key = []byte(k.Value)
+			}
+			v, err := renderTOML(k.Children[0])
+			if err != nil {
+				return "", err
+			}
+			parts[i] = string(key) + " = " + v
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case slowjson.NodeArray:
+		parts := make([]string, len(n.Children))
+		for i, item := range n.Children {
+			v, err := renderTOML(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = v
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	// JSON strings, numbers and booleans are valid TOML
+	b, err := slowjson.Marshal(n)
+	return string(b), err
+}
+
+func isTOMLBareKey(s string) bool {
+	for _, c := range s {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/schema"
+)
+
+// runEditor writes the schema of a config struct to a file and prints the editor settings that use it.
+func runEditor(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("editor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeName := fs.String("type", "", "name of the config struct `type`")
+	out := fs.String("o", "", "write the schema to `file` (default <type>.schema.json)")
+	url := fs.String("url", "", "URL editors load the schema from (default ./<file>)")
+	files := fs.String("files", "*.json,*.yaml,*.yml", "comma separated `globs` of the config files")
+	modeline := fs.Bool("modeline", false, "print the yaml-language-server comment instead of VS Code settings")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig editor -type Config [-o file] [-url url] [-files globs] [package dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *typeName == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if *out == "" {
+		*out = strings.ToLower(*typeName) + ".schema.json"
+	}
+	if *url == "" {
+		*url = "./" + filepath.ToSlash(filepath.Base(*out))
+	}
+	s, err := tracedconfig.GenerateSchemaFromSource(dir, *typeName)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig editor: %v\n", err)
+		return 1
+	}
+	s.ID = *url
+	b, err := s.MarshalIndent()
+	if err == nil {
+		err = os.WriteFile(*out, b, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig editor: %v\n", err)
+		return 1
+	}
+	if *modeline {
+		fmt.Fprintln(stdout, schema.YAMLModeline(*url))
+		return 0
+	}
+	settings, err := schema.VSCodeSettings(schema.Association{URL: *url, Files: strings.Split(*files, ",")})
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig editor: %v\n", err)
+		return 1
+	}
+	stdout.Write(settings)
+	return 0
+}
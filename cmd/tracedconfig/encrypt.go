@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/at15/tracedconfig/sealed"
+)
+
+// stdin is read by commands taking input on standard input, tests replace it.
+var stdin io.Reader = os.Stdin
+
+// runEncrypt prints ENC[...] values for plaintexts given as arguments or, one per line, on stdin.
+func runEncrypt(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key-file", "", "`file` holding the base64 encoded AES key")
+	genKey := fs.Bool("genkey", false, "print a new random key and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig encrypt -key-file key [value...]")
+		fmt.Fprintln(stderr, "       tracedconfig encrypt -genkey")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *genKey {
+		key, err := sealed.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig encrypt: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, key)
+		return 0
+	}
+	if *keyFile == "" {
+		fs.Usage()
+		return 2
+	}
+	c, err := loadCipher(*keyFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig encrypt: %v\n", err)
+		return 1
+	}
+	values := fs.Args()
+	if len(values) == 0 {
+		sc := bufio.NewScanner(stdin)
+		for sc.Scan() {
+			values = append(values, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig encrypt: %v\n", err)
+			return 1
+		}
+	}
+	for _, v := range values {
+		enc, err := sealed.Encrypt(c, v)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig encrypt: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, enc)
+	}
+	return 0
+}
+
+func loadCipher(keyFile string) (sealed.Cipher, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sealed.ParseKey(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return sealed.NewAESCipher(key)
+}
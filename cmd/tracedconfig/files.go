@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/blockconf"
+	"github.com/at15/tracedconfig/ini"
+	"github.com/at15/tracedconfig/properties"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/table"
+	"github.com/at15/tracedconfig/tfvars"
+	"github.com/at15/tracedconfig/toml"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// Config file formats, by extension.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+	// formatTFVars is the HCL syntax of Terraform variable files, .tfvars.json files are JSON.
+	formatTFVars = "tfvars"
+	// formatINI is INI files, systemd units are read with repeated keys as lists, see unitExts.
+	formatINI = "ini"
+	// formatBlock is nginx style brace-block configs, Caddyfiles are read with line breaks ending
+	// directives.
+	formatBlock = "block"
+	// formatProperties is Java .properties files.
+	formatProperties = "properties"
+	// formatCSV and formatTSV are tables, rows of cells separated by commas or tabs.
+	formatCSV = "csv"
+	formatTSV = "tsv"
+)
+
+// isCaddyfile reports whether path is a Caddyfile, e.g. Caddyfile or site.caddyfile.
+func isCaddyfile(path string) bool {
+	return filepath.Base(path) == "Caddyfile" || strings.ToLower(filepath.Ext(path)) == ".caddyfile"
+}
+
+// unitExts are the extensions of systemd units.
+var unitExts = map[string]bool{".service": true, ".socket": true, ".timer": true, ".mount": true, ".path": true, ".target": true}
+
+// fileFormat returns the format of a config file by its extension, JSON for anything unknown.
+func fileFormat(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if unitExts[ext] {
+		return formatINI
+	}
+	if isCaddyfile(path) {
+		return formatBlock
+	}
+	switch ext {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	case ".tfvars":
+		return formatTFVars
+	case ".ini":
+		return formatINI
+	case ".conf":
+		return formatBlock
+	case ".properties":
+		return formatProperties
+	case ".csv":
+		return formatCSV
+	case ".tsv", ".tab":
+		return formatTSV
+	default:
+		return formatJSON
+	}
+}
+
+// parseFile parses a JSON, YAML, TOML, Terraform variable, INI, brace-block, .properties or CSV config
+// file by its extension.
+func parseFile(path string) (*slowjson.Node, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseData(path, b)
+}
+
+// parseData parses the content of the config file path, see parseFile.
+func parseData(path string, b []byte) (*slowjson.Node, error) {
+	switch fileFormat(path) {
+	case formatYAML:
+		return yaml.Parse(b, path)
+	case formatTOML:
+		return toml.Parse(b, path)
+	case formatTFVars:
+		return tfvars.Parse(b, path)
+	case formatINI:
+		return ini.Options{Repeated: unitExts[strings.ToLower(filepath.Ext(path))]}.Parse(b, path)
+	case formatBlock:
+		return blockconf.Options{Lines: isCaddyfile(path)}.Parse(b, path)
+	case formatProperties:
+		return properties.Parse(b, path)
+	case formatCSV:
+		return table.Parse(b, path)
+	case formatTSV:
+		return table.Options{Comma: '\t'}.Parse(b, path)
+	default:
+		p := slowjson.NewParser(string(b))
+		p.File = path
+		return p.Parse()
+	}
+}
+
+// parsedExts are the extensions of the config files parseFile reads, picked up in directories.
+var parsedExts = map[string]bool{".json": true, ".jsonc": true, ".yaml": true, ".yml": true, ".toml": true, ".tfvars": true, ".ini": true, ".properties": true}
+
+// expandFiles returns the config files of targets in order. A file is kept as is, a directory stands for
+// the config files in it and "dir/..." for those below it, skipping hidden directories.
+func expandFiles(targets []string) ([]string, error) {
+	var files []string
+	for _, target := range targets {
+		recursive := target == "..." || strings.HasSuffix(target, "/...")
+		dir := target
+		if recursive {
+			if dir = strings.TrimSuffix(strings.TrimSuffix(target, "..."), "/"); dir == "" {
+				dir = "."
+			}
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, target)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && (!recursive || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if parsedExts[strings.ToLower(filepath.Ext(path))] {
+				found = append(found, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// parseInterspersed parses flags given before, between or after the arguments, e.g.
+// "locate $.a file.json -format json", and returns the arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
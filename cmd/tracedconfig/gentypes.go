@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/at15/tracedconfig/schema"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runGenTypes prints Go struct definitions inferred from example config files, to start using typed
+// config for an existing one. With several examples the keys present in all of them are required.
+func runGenTypes(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gen-types", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pkg := fs.String("package", "config", "`name` of the generated package")
+	typeName := fs.String("type", "Config", "`name` of the root struct type")
+	out := fs.String("o", "", "write the source to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig gen-types [-package config] [-type Config] [-o file] example.json...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	var examples []*slowjson.Node
+	for _, file := range fs.Args() {
+		n, err := slowjson.ParseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig gen-types: %v\n", err)
+			return 1
+		}
+		if n.Type != slowjson.NodeObject {
+			fmt.Fprintf(stderr, "tracedconfig gen-types: %s: expected an object at the top level\n", file)
+			return 1
+		}
+		examples = append(examples, n)
+	}
+	src, err := schema.GoTypes(schema.Infer(examples...), *pkg, *typeName)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig gen-types: %v\n", err)
+		return 1
+	}
+	if *out == "" {
+		stdout.Write(src)
+		return 0
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig gen-types: %v\n", err)
+		return 1
+	}
+	return 0
+}
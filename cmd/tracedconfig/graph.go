@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/at15/tracedconfig/lint"
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// graphKey is a key of the reference graph in the json format.
+type graphKey struct {
+	Path string `json:"path"`
+	// File is the layer setting the key, empty when no layer does.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	Col  int    `json:"col,omitempty"`
+}
+
+// graphEdge is a reference from the value of a key to another key in the json format.
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Ref  string `json:"ref"`
+}
+
+type graph struct {
+	Keys  []graphKey  `json:"keys"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// runGraph prints the graph of ${path} references between keys after merging the files as layers, to
+// visualize and debug chains of interpolations. Keys are grouped by the file setting them, keys no file
+// sets are drawn dashed. The dot format is for Graphviz, e.g. "tracedconfig graph *.yaml | dot -Tsvg",
+// the json format lists the keys with their positions and the edges.
+func runGraph(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "dot", "output `format`: dot or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig graph [-format dot|json] layer...")
+		fmt.Fprintln(stderr, "layers are merged in order, later ones overriding earlier ones")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || *format != "dot" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+	var layers []merge.Layer
+	for _, file := range rest {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig graph: %v\n", err)
+			return 2
+		}
+		layers = append(layers, merge.Layer{Name: file, Root: root})
+	}
+	res, err := merge.Merge(layers...)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig graph: %v\n", err)
+		return 2
+	}
+
+	g := graph{Keys: []graphKey{}, Edges: []graphEdge{}}
+	seen := map[string]bool{}
+	addKey := func(p slowjson.Path) {
+		path := p.String()
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		k := graphKey{Path: path}
+		if n := res.Root.Lookup(p); n != nil {
+			k.File, k.Line, k.Col = n.File, n.StartLine, n.StartCol
+		}
+		g.Keys = append(g.Keys, k)
+	}
+	for _, r := range lint.References(res.Root) {
+		if r.Path == nil {
+			continue
+		}
+		from := r.Node.Path()
+		addKey(from)
+		addKey(r.Path)
+		g.Edges = append(g.Edges, graphEdge{From: from.String(), To: r.Path.String(), Ref: r.Text})
+	}
+
+	if *format == "json" {
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig graph: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "%s\n", b)
+		return 0
+	}
+	writeDOT(stdout, g, rest)
+	return 0
+}
+
+// writeDOT writes g in the Graphviz format, a cluster per file in the order of files.
+func writeDOT(w io.Writer, g graph, files []string) {
+	q := strconv.Quote
+	fmt.Fprintln(w, "digraph config {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for i, file := range files {
+		var keys []string
+		for _, k := range g.Keys {
+			if k.File == file {
+				keys = append(keys, k.Path)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  subgraph %s {\n    label=%s;\n", q("cluster_"+strconv.Itoa(i)), q(file))
+		for _, k := range keys {
+			fmt.Fprintf(w, "    %s;\n", q(k))
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	for _, k := range g.Keys {
+		if k.File == "" {
+			fmt.Fprintf(w, "  %s [style=dashed, color=red];\n", q(k.Path))
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(w, "  %s -> %s [label=%s];\n", q(e.From), q(e.To), q(e.Ref))
+	}
+	fmt.Fprintln(w, "}")
+}
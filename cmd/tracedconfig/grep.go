@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+var nodeTypes = map[string]slowjson.NodeType{
+	"object":  slowjson.NodeObject,
+	"array":   slowjson.NodeArray,
+	"string":  slowjson.NodeString,
+	"number":  slowjson.NodeNumber,
+	"boolean": slowjson.NodeBoolean,
+	"null":    slowjson.NodeNull,
+}
+
+// runGrep searches config files structurally: the pattern is matched against keys and scalar values
+// rather than lines, so formatting, quoting and comments do not matter. Each hit is printed as
+// file:line:col: path = value, objects and arrays matched by their key shown by their size. It exits
+// with 1 when nothing matched and 2 on errors, like grep.
+func runGrep(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keys := fs.Bool("keys", false, "match keys only")
+	values := fs.Bool("values", false, "match values only")
+	fixed := fs.Bool("F", false, "match the pattern as a fixed string instead of a regular expression")
+	ignoreCase := fs.Bool("i", false, "ignore case")
+	typ := fs.String("type", "", "only report values of `type`: object, array, string, number, boolean or null")
+	pathPattern := fs.String("path", "", "only report values whose path matches `pattern`, e.g. servers[*].timeout, * matches within a key and ** across keys")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig grep [-keys|-values] [-F] [-i] [-type type] [-path pattern] pattern file|dir|dir/...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	wantType, okType := nodeTypes[*typ]
+	if len(rest) < 2 || *keys && *values || *typ != "" && !okType {
+		fs.Usage()
+		return 2
+	}
+	expr := rest[0]
+	if *fixed {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if *ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig grep: %v\n", err)
+		return 2
+	}
+	var pathRe *regexp.Regexp
+	if *pathPattern != "" {
+		pathRe = globPath(*pathPattern)
+	}
+	files, err := expandFiles(rest[1:])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig grep: %v\n", err)
+		return 2
+	}
+
+	code := 1
+	for _, file := range files {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig grep: %v\n", err)
+			return 2
+		}
+		walkValues(root, nil, func(path slowjson.Path, n *slowjson.Node) {
+			keyHit, valueHit := false, false
+			if last := len(path) - 1; !*values && last >= 0 && !path[last].IsIndex {
+				keyHit = re.MatchString(path[last].Key)
+			}
+			if !*keys && n.Type != slowjson.NodeObject && n.Type != slowjson.NodeArray {
+				valueHit = re.MatchString(n.Value)
+			}
+			if !keyHit && !valueHit || *typ != "" && n.Type != wantType || pathRe != nil && !pathRe.MatchString(path.String()) {
+				return
+			}
+			code = 0
+			fmt.Fprintf(stdout, "%s:%d:%d: %s = %s\n", file, n.StartLine, n.StartCol, path, summary(n))
+		})
+	}
+	return code
+}
+
+// walkValues calls fn for n and every value below it with its path.
+func walkValues(n *slowjson.Node, path slowjson.Path, fn func(slowjson.Path, *slowjson.Node)) {
+	fn(path, n)
+	switch n.Type {
+	case slowjson.NodeObject:
+		for _, k := range n.Children {
+			walkValues(k.Children[0], path.Key(k.Value), fn)
+		}
+	case slowjson.NodeArray:
+		for i, item := range n.Children {
+			walkValues(item, path.Index(i), fn)
+		}
+	}
+}
+
+// globPath compiles a path pattern such as servers[*].timeout or **.port. * matches within a key or
+// index, ** matches any part of a path.
+func globPath(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "$"), ".")
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**."):
+			b.WriteString(`(.*\.)?`)
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString(`[^.\[\]]*`)
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/at15/tracedconfig/helm"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runHelm computes the values of a Helm chart like helm install does, see helm.Values, and explains
+// where the given paths were set, e.g. which -f file or --set expression set image.tag. Without paths it
+// prints the merged values. It exits with 1 when a path is not set.
+func runHelm(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("helm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var v helm.Values
+	fs.Var((*stringList)(&v.Files), "f", "values `file` overriding the chart defaults, repeatable")
+	fs.Var((*stringList)(&v.Files), "values", "same as -f")
+	fs.Var((*stringList)(&v.Set), "set", "`name=value` pairs applied after the files, repeatable")
+	fs.Var((*stringList)(&v.SetString), "set-string", "`name=value` pairs with string values applied after -set, repeatable")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig helm [-f file]... [-set name=value]... [-set-string name=value]... chart-dir [path...]")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 {
+		fs.Usage()
+		return 2
+	}
+	v.Chart = rest[0]
+	c := v.Config()
+	if err := c.Load(context.Background()); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig helm: %v\n", err)
+		return 2
+	}
+	if len(rest) == 1 {
+		b, err := slowjson.MarshalOptions{Indent: "  "}.Marshal(c.Root())
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig helm: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "%s\n", b)
+		return 0
+	}
+	code := 0
+	for _, path := range rest[1:] {
+		explain, err := c.Explain(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig helm: %v\n", err)
+			code = 1
+			continue
+		}
+		fmt.Fprint(stdout, explain)
+	}
+	return code
+}
+
+// stringList is a flag that can be given several times, collecting the values in order.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// configExts are the file extensions hook -staged picks up.
+var configExts = []string{".json", ".jsonc"}
+
+// runHook lints the files of a commit for pre-commit hooks and frameworks. With -staged it asks git for
+// the staged config files and lints their staged content, otherwise it lints the files given, e.g. by
+// the pre-commit framework. Findings are printed one per line followed by a summary, it exits with 1
+// when any finding reaches the fail-on severity. It takes the lint flags and CLI config file.
+func runHook(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	lf := addLintFlags(fs)
+	staged := fs.Bool("staged", false, "lint the staged "+strings.Join(configExts, " and ")+" files")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig hook [lint flags] -staged | file...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *staged == (fs.NArg() > 0) {
+		fs.Usage()
+		return 2
+	}
+	ls, code := lf.setup("hook", true, stderr)
+	if code != 0 {
+		return code
+	}
+	files := fs.Args()
+	read := os.ReadFile
+	if *staged {
+		var err error
+		if files, err = stagedFiles(); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig hook: %v\n", err)
+			return 1
+		}
+		read = readStaged
+	}
+	findings, failed := 0, 0
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig hook: %v\n", err)
+			code = 1
+			continue
+		}
+		diags, _ := ls.lint(file, data)
+		for _, d := range diags {
+			fmt.Fprintln(stdout, d)
+		}
+		findings += len(diags)
+		if fails(diags, ls.threshold) {
+			failed++
+			code = 1
+		}
+	}
+	if findings > 0 {
+		fmt.Fprintf(stderr, "tracedconfig hook: %d findings in %d files, %d files fail\n", findings, len(files), failed)
+	}
+	return code
+}
+
+// stagedFiles lists the added, copied, modified and renamed config files in the index, relative to the working directory.
+func stagedFiles() ([]string, error) {
+	out, err := git("diff", "--cached", "--name-only", "--relative", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		ext := filepath.Ext(name)
+		for _, e := range configExts {
+			if name != "" && ext == e {
+				files = append(files, name)
+			}
+		}
+	}
+	return files, nil
+}
+
+// readStaged reads the staged content of file, which may differ from the working tree.
+func readStaged(file string) ([]byte, error) {
+	return git("show", ":./"+filepath.ToSlash(file))
+}
+
+func git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/schema"
+)
+
+// runInit writes a commented starter config for a config struct read from Go source.
+func runInit(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeName := fs.String("type", "", "name of the config struct `type`")
+	format := fs.String("format", "", "output format: json, yaml or toml (default from -o extension, else json)")
+	out := fs.String("o", "", "write to `file` instead of stdout")
+	force := fs.Bool("force", false, "overwrite an existing output file")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig init -type Config [-format yaml] [-o file] [package dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *typeName == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	if *format == "" {
+		*format = schema.FormatJSON
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".yaml", ".yml":
+			*format = schema.FormatYAML
+		case ".toml":
+			*format = schema.FormatTOML
+		}
+	}
+	s, err := tracedconfig.GenerateSchemaFromSource(dir, *typeName)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig init: %v\n", err)
+		return 1
+	}
+	var buf bytes.Buffer
+	if err := schema.Skeleton(&buf, s, *format); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig init: %v\n", err)
+		return 1
+	}
+	if *out == "" {
+		stdout.Write(buf.Bytes())
+		return 0
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(*out, flags, 0o644)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig init: %v\n", err)
+		return 1
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig init: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/k8s"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// runK8s validates Kubernetes manifests against the schemas of their kinds in an OpenAPI spec, see
+// k8s.Spec.Validate, as a preflight before applying them. Every document of a YAML stream is a manifest.
+// Findings are printed with source snippets, it exits with 1 when any is an error.
+func runK8s(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("k8s", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	specFlag := fs.String("spec", "", "OpenAPI spec `file or URL`, e.g. saved with kubectl get --raw /openapi/v2")
+	format := fs.String("format", "text", "output `format`: text or github")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig k8s -spec file|url [-format text|github] manifest...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || *specFlag == "" || *format != "text" && *format != "github" {
+		fs.Usage()
+		return 2
+	}
+	data, err := readSpec(*specFlag)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig k8s: %v\n", err)
+		return 2
+	}
+	spec, err := k8s.ParseSpec(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig k8s: %s: %v\n", *specFlag, err)
+		return 2
+	}
+	files, err := expandFiles(rest)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig k8s: %v\n", err)
+		return 2
+	}
+	var diags []diag.Diagnostic
+	for _, file := range files {
+		docs, err := parseManifests(file)
+		var perr *slowjson.ParseError
+		if err != nil && !errors.As(err, &perr) {
+			fmt.Fprintf(stderr, "tracedconfig k8s: %v\n", err)
+			return 2
+		}
+		diags = append(diags, diag.FromError(err)...)
+		diags = append(diags, spec.Validate(docs...)...)
+	}
+	if *format == "github" {
+		if err := diag.WriteGitHub(stdout, diags); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig k8s: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprint(stdout, d.Render(1, 1))
+		}
+	}
+	if fails(diags, diag.SeverityError) {
+		return 1
+	}
+	return 0
+}
+
+// readSpec reads the OpenAPI spec from a file or an http(s) URL.
+func readSpec(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+	resp, err := http.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", location, resp.Status)
+	}
+	return body, nil
+}
+
+// parseManifests parses the documents of a YAML stream, or a JSON manifest.
+func parseManifests(file string) ([]*slowjson.Node, error) {
+	if fileFormat(file) == formatYAML {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.ParseStream(b, file)
+	}
+	root, err := parseFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return []*slowjson.Node{root}, nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/lint"
+	"github.com/at15/tracedconfig/schema"
+)
+
+// runLint runs the built-in lint rules over config files and prints the findings with source context.
+// Syntax errors are reported as findings too. It exits with 1 when any finding reaches the -fail-on
+// severity, error by default. With -baseline only findings not recorded in the baseline file are reported,
+// -update-baseline records them. -format junit prints a JUnit XML report for CI test views instead,
+// -format github GitHub Actions annotations that show inline on pull requests. -fix applies the fixes
+// findings suggest, e.g. "true" to true where a boolean is expected, replacing only the text of the values.
+// Defaults for the flags come from the "lint" section of the CLI config file.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	lf := addLintFlags(fs)
+	updateBaseline := fs.Bool("update-baseline", false, "record the current findings in the -baseline file instead of reporting them")
+	format := fs.String("format", "text", "output `format`: text, junit or github")
+	fix := fs.Bool("fix", false, "apply the fixes of the findings to the files and report the remaining findings")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig lint [-config file] [-schema file] [-rules rule=setting,...] [-fail-on severity] [-format text|junit|github] [-baseline file [-update-baseline]] [-fix] file...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ls, code := lf.setup("lint", !*updateBaseline, stderr)
+	if code != 0 {
+		return code
+	}
+	if fs.NArg() == 0 || *updateBaseline && *lf.baseline == "" || !validFormat(*format) {
+		fs.Usage()
+		return 2
+	}
+	var recorded []diag.Diagnostic
+	var reports []diag.FileReport
+	// fixed files are written together once all are linted, see writeKeepingMode
+	var writes []fileWrite
+	var fixedCounts []int
+	for _, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig lint: %v\n", err)
+			code = 1
+			continue
+		}
+		diags, parsed := ls.lint(file, data)
+		if *fix && parsed {
+			if fixed, n := applyFixes(file, data, diags); n > 0 {
+				writes = append(writes, fileWrite{path: file, data: fixed})
+				fixedCounts = append(fixedCounts, n)
+				diags, parsed = ls.lint(file, fixed)
+			}
+		}
+		if *updateBaseline && parsed {
+			recorded = append(recorded, diags...)
+			continue
+		}
+		reports = append(reports, diag.FileReport{File: file, Diagnostics: diags})
+		if fails(diags, ls.threshold) {
+			code = 1
+		}
+	}
+	if err := writeKeepingMode(writes...); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig lint: %v\n", err)
+		return 1
+	}
+	for i, w := range writes {
+		fmt.Fprintf(stderr, "tracedconfig lint: fixed %d findings in %s\n", fixedCounts[i], w.path)
+	}
+	if err := writeReport(stdout, *format, reports, ls.threshold); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig lint: %v\n", err)
+		return 1
+	}
+	if *updateBaseline {
+		if err := lint.NewBaseline(recorded).Write(*lf.baseline); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig lint: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "recorded %d findings in %s\n", len(recorded), *lf.baseline)
+	}
+	return code
+}
+
+// lintFlags are the flags shared by the commands that lint, their defaults come from the CLI config file.
+type lintFlags struct {
+	schema   *string
+	rules    *string
+	failOn   *string
+	baseline *string
+	config   *string
+}
+
+func addLintFlags(fs *flag.FlagSet) *lintFlags {
+	return &lintFlags{
+		schema:   fs.String("schema", "", "JSON Schema `file` enabling the unknown and similar key rules"),
+		rules:    fs.String("rules", "", "comma separated rule=setting, rule is a name or code like TC1001, setting is off, on, error, warning or info"),
+		failOn:   fs.String("fail-on", "", "lowest `severity` that fails the run: error, warning, info or never (default error)"),
+		baseline: fs.String("baseline", "", "baseline `file`, only findings not recorded in it are reported"),
+		config:   fs.String("config", "", "CLI config `file`, default "+defaultConfigName+" when it exists"),
+	}
+}
+
+// lintSetup is the configured runner of a lint run.
+type lintSetup struct {
+	runner    *lint.Runner
+	threshold diag.Severity
+	// known is the loaded baseline, nil without one.
+	known *lint.Baseline
+}
+
+// setup fills unset flags from the CLI config file and builds the runner, loading the baseline when
+// loadBaseline is set. Errors are printed for cmd and returned as a non-zero exit code.
+func (f *lintFlags) setup(cmd string, loadBaseline bool, stderr io.Writer) (*lintSetup, int) {
+	cfg, err := loadConfig(*f.config)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+		return nil, 2
+	}
+	if *f.schema == "" {
+		*f.schema = cfg.Lint.Schema
+	}
+	if *f.baseline == "" {
+		*f.baseline = cfg.Lint.Baseline
+	}
+	if *f.failOn == "" {
+		*f.failOn = cfg.Lint.FailOn
+	}
+	ls := &lintSetup{}
+	if ls.threshold, err = parseFailOn(*f.failOn); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+		return nil, 2
+	}
+	var s *schema.Schema
+	if *f.schema != "" {
+		b, err := os.ReadFile(*f.schema)
+		if err == nil {
+			s = &schema.Schema{}
+			err = json.Unmarshal(b, s)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+			return nil, 1
+		}
+	}
+	ls.runner = lint.NewRunner(lint.Builtin(s)...)
+	if err := ls.runner.Configure(cfg.Lint.Rules); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+		return nil, 2
+	}
+	if *f.rules != "" {
+		settings := map[string]string{}
+		for _, kv := range strings.Split(*f.rules, ",") {
+			name, setting, ok := strings.Cut(kv, "=")
+			if !ok {
+				fmt.Fprintf(stderr, "tracedconfig %s: invalid rule setting %q, expected rule=setting\n", cmd, kv)
+				return nil, 2
+			}
+			settings[strings.TrimSpace(name)] = strings.TrimSpace(setting)
+		}
+		if err := ls.runner.Configure(settings); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+			return nil, 2
+		}
+	}
+	if *f.baseline != "" && loadBaseline {
+		if ls.known, err = lint.LoadBaseline(*f.baseline); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig %s: %v\n", cmd, err)
+			return nil, 1
+		}
+	}
+	return ls, 0
+}
+
+// lint parses data as the content of file and returns its findings without those in the baseline.
+// A syntax error is the only finding, parsed is false then.
+func (ls *lintSetup) lint(file string, data []byte) (diags []diag.Diagnostic, parsed bool) {
+	root, err := parseData(file, data)
+	if err != nil {
+		return diag.FromError(err), false
+	}
+	diags = ls.runner.Run(root)
+	if ls.known != nil {
+		diags = ls.known.Filter(diags)
+	}
+	return diags, true
+}
+
+// applyFixes returns data of file with the fixes of diags applied and how many were applied. Fixes are
+// not applied when the result would not parse.
+func applyFixes(file string, data []byte, diags []diag.Diagnostic) ([]byte, int) {
+	var edits []edit
+	for _, d := range diags {
+		if d.Fix == nil {
+			continue
+		}
+		text, err := render(d.Fix.Value, fileFormat(file))
+		if err != nil {
+			continue
+		}
+		edits = append(edits, edit{start: d.Span.StartOffset, end: d.Span.EndOffset, text: text})
+	}
+	edits = sortEdits(edits)
+	for i := 1; i < len(edits); i++ {
+		if edits[i].start < edits[i-1].end {
+			// of overlapping fixes the first one wins
+			edits = append(edits[:i], edits[i+1:]...)
+			i--
+		}
+	}
+	if len(edits) == 0 {
+		return data, 0
+	}
+	out := []byte(applyEdits(string(data), 0, edits))
+	if _, err := parseData(file, out); err != nil {
+		return data, 0
+	}
+	return out, len(edits)
+}
+
+// validFormat reports whether writeReport supports the format.
+func validFormat(format string) bool {
+	return format == "text" || format == "junit" || format == "github"
+}
+
+// writeReport prints the findings in format, text renders them with source context.
+func writeReport(w io.Writer, format string, reports []diag.FileReport, threshold diag.Severity) error {
+	switch format {
+	case "junit":
+		return diag.WriteJUnit(w, "tracedconfig lint", reports, threshold)
+	case "github":
+		for _, r := range reports {
+			if err := diag.WriteGitHub(w, r.Diagnostics); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, r := range reports {
+			for _, d := range r.Diagnostics {
+				fmt.Fprint(w, d.Render(1, 1))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// location is the JSON output of locate.
+type location struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	EndLine int    `json:"endLine"`
+	EndCol  int    `json:"endCol"`
+}
+
+// runLocate prints where the value at a path is set in each file, so scripts and editor plugins can
+// jump to it. The text format is file:line:col, the json format one object per line with the end of
+// the value as well. Columns count characters from 1, endCol is past the value. It exits with 1 when
+// a file does not set the path, like grep.
+func runLocate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("locate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig locate [-format text|json] path file...")
+		fmt.Fprintln(stderr, "path is e.g. $.server.port or servers[0].host")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) < 2 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+	path, err := slowjson.ParsePath(rest[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig locate: %v\n", err)
+		return 2
+	}
+	code := 0
+	enc := json.NewEncoder(stdout)
+	for _, file := range rest[1:] {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig locate: %v\n", err)
+			return 2
+		}
+		n := root.Lookup(path)
+		if n == nil {
+			fmt.Fprintf(stderr, "tracedconfig locate: %s does not set %s\n", file, path)
+			code = 1
+			continue
+		}
+		if *format == "text" {
+			fmt.Fprintf(stdout, "%s:%d:%d\n", file, n.StartLine, n.StartCol)
+			continue
+		}
+		enc.Encode(location{File: file, Line: n.StartLine, Col: n.StartCol, EndLine: n.EndLine, EndCol: n.EndCol})
+	}
+	return code
+}
@@ -0,0 +1,76 @@
+// Command tracedconfig works with config files and the Go structs they decode into.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"browse":    {"explore merged config files interactively with provenance", runBrowse},
+	"compose":   {"show a docker compose project and why a container gets a variable", runCompose},
+	"conflicts": {"report keys layers set to different values without an @override", runConflicts},
+	"diff":      {"show the structural changes between two config files", runDiff},
+	"docs":      {"print a Markdown reference of a config struct", runDocs},
+	"editor":    {"write the schema and editor settings for config completion", runEditor},
+	"encrypt":   {"encrypt values as ENC[...] for config files", runEncrypt},
+	"gen-types": {"generate Go config structs from example config files", runGenTypes},
+	"graph":     {"print the graph of ${path} references between keys and files as DOT or JSON", runGraph},
+	"grep":      {"search keys and values of config files structurally", runGrep},
+	"helm":      {"explain which values file or --set expression set each value of a Helm chart", runHelm},
+	"hook":      {"lint the staged config files from a pre-commit hook", runHook},
+	"init":      {"write a commented starter config for a config struct", runInit},
+	"k8s":       {"validate Kubernetes manifests against the schemas of an OpenAPI spec", runK8s},
+	"lint":      {"check config files with the built-in lint rules", runLint},
+	"locate":    {"print where a path is set in config files for editors and scripts", runLocate},
+	"matrix":    {"compare the configs of environments key by key and flag unexpected differences", runMatrix},
+	"normalize": {"rewrite JSON config files in the canonical style of the project", runNormalize},
+	"query":     {"print the values matching a JSONPath expression across merged layers with their origin", runQuery},
+	"repeats":   {"report values repeated across config files that belong in a shared key", runRepeats},
+	"refs":      {"check that ${path} references and YAML aliases resolve after merging layers", runRefs},
+	"rekey":     {"re-encrypt ENC[...] values of config files with a new key", runRekey},
+	"rename":    {"rename or move a key in config files, keeping their formatting", runRename},
+	"rollback":  {"restore an earlier config of a running process via its debug endpoint", runRollback},
+	"schema":    {"print the JSON Schema of a config struct", runSchema},
+	"set":       {"change the values matching a path in config files, keeping their formatting", runSet},
+	"sign":      {"sign config files so loading can verify them", runSign},
+	"verify":    {"check a config directory against its lockfile of file hashes", runVerify},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "tracedconfig: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: tracedconfig <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,1172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/debug"
+	"github.com/at15/tracedconfig/sealed"
+	"github.com/at15/tracedconfig/signing"
+)
+
+// runCmd runs the command line and returns the exit code with stdout and stderr.
+func runCmd(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFile writes content to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, stderr := runCmd()
+	if code != 2 || !strings.Contains(stderr, "schema") {
+		t.Errorf("run() = %d, stderr %q", code, stderr)
+	}
+	code, _, stderr = runCmd("nope")
+	if code != 2 || !strings.Contains(stderr, `unknown command "nope"`) {
+		t.Errorf("run(nope) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.go", "package app\n\ntype Config struct {\n\t// Port to listen on.\n\tPort int `json:\"port\" default:\"80\"`\n}\n")
+
+	code, stdout, stderr := runCmd("schema", "-type", "Config", "-id", "https://example.com/app.json", dir)
+	if code != 0 {
+		t.Fatalf("schema = %d, stderr %q", code, stderr)
+	}
+	for _, want := range []string{`"$id": "https://example.com/app.json"`, `"description": "Port to listen on."`, `"default": 80`} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %s:\n%s", want, stdout)
+		}
+	}
+
+	if code, _, _ := runCmd("schema", dir); code != 2 {
+		t.Errorf("schema without -type = %d, want 2", code)
+	}
+	if code, _, stderr := runCmd("schema", "-type", "Other", dir); code != 1 || !strings.Contains(stderr, "type Other not found") {
+		t.Errorf("schema -type Other = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.go", "package app\n\n// Config is the app config.\ntype Config struct {\n\t// Port to listen on.\n\tPort int `json:\"port\" default:\"80\"`\n}\n")
+
+	code, stdout, stderr := runCmd("docs", "-type", "Config", dir)
+	if code != 0 {
+		t.Fatalf("docs = %d, stderr %q", code, stderr)
+	}
+	if !strings.Contains(stdout, "| `port` | integer | `80` |  | Port to listen on. |") {
+		t.Errorf("output:\n%s", stdout)
+	}
+	if code, _, _ := runCmd("docs"); code != 2 {
+		t.Errorf("docs without -type = %d, want 2", code)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.go", "package app\n\ntype Config struct {\n\t// Port to listen on.\n\tPort int `json:\"port\" default:\"80\"`\n}\n")
+
+	code, stdout, stderr := runCmd("init", "-type", "Config", "-format", "yaml", dir)
+	if code != 0 {
+		t.Fatalf("init = %d, stderr %q", code, stderr)
+	}
+	if stdout != "# Port to listen on.\nport: 80\n" {
+		t.Errorf("output:\n%s", stdout)
+	}
+
+	out := filepath.Join(dir, "app.toml")
+	if code, _, stderr := runCmd("init", "-type", "Config", "-o", out, dir); code != 0 {
+		t.Fatalf("init -o = %d, stderr %q", code, stderr)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil || string(b) != "# Port to listen on.\nport = 80\n" {
+		t.Errorf("file = %q, %v", b, err)
+	}
+	if code, _, stderr := runCmd("init", "-type", "Config", "-o", out, dir); code != 1 || !strings.Contains(stderr, "exists") {
+		t.Errorf("init over existing file = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCmd("init", "-type", "Config", "-o", out, "-force", dir); code != 0 {
+		t.Errorf("init -force = %d", code)
+	}
+	if code, _, stderr := runCmd("init", "-type", "Config", "-format", "ini", dir); code != 1 || !strings.Contains(stderr, "unsupported") {
+		t.Errorf("init -format ini = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.go", "package app\n\ntype Config struct {\n\tPort int `json:\"port\"`\n}\n")
+	out := filepath.Join(dir, "app.schema.json")
+
+	code, stdout, stderr := runCmd("editor", "-type", "Config", "-o", out, "-files", "conf/*.json,conf/*.yaml", dir)
+	if code != 0 {
+		t.Fatalf("editor = %d, stderr %q", code, stderr)
+	}
+	for _, want := range []string{`"json.schemas"`, `"url": "./app.schema.json"`, `"./app.schema.json": [`, `"conf/*.yaml"`} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %s:\n%s", want, stdout)
+		}
+	}
+	b, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(b), `"$id": "./app.schema.json"`) {
+		t.Errorf("schema file = %s, %v", b, err)
+	}
+
+	code, stdout, _ = runCmd("editor", "-type", "Config", "-o", out, "-url", "https://example.com/app.json", "-modeline", dir)
+	if code != 0 || stdout != "# yaml-language-server: $schema=https://example.com/app.json\n" {
+		t.Errorf("editor -modeline = %d, %q", code, stdout)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := writeFile(t, dir, "app.schema.json", `{"type": "object", "properties": {"port": {"type": "integer"}}}`)
+	config := writeFile(t, dir, "app.json", "{\n  \"prot\": 80,\n  \"port\": 80,\n  \"port\": 81\n}\n")
+
+	code, stdout, stderr := runCmd("lint", "-schema", schemaFile, config)
+	if code != 1 {
+		t.Fatalf("lint = %d, stderr %q", code, stderr)
+	}
+	for _, want := range []string{
+		config + `:2:3: warning: prot: unknown key "prot", did you mean "port"? [TC1004 similar-key]`,
+		config + `:4:3: error: port: duplicate key "port"`,
+		"4:   \"port\": 81\n   ^ start",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, stdout, _ := runCmd("lint", "-rules", "duplicate-key=warning", config); code != 0 || strings.Contains(stdout, "similar-key") {
+		t.Errorf("lint -rules = %d, output:\n%s", code, stdout)
+	}
+	if code, stdout, _ := runCmd("lint", "-schema", schemaFile, "-rules", "TC1004=off,TC1001=warning", config); code != 0 || strings.Contains(stdout, "similar-key") {
+		t.Errorf("lint -rules with codes = %d, output:\n%s", code, stdout)
+	}
+	broken := writeFile(t, dir, "broken.json", `{"a": tru}`)
+	if code, stdout, _ := runCmd("lint", broken); code != 1 || !strings.Contains(stdout, broken+":1:7: error: invalid boolean [TC1000 syntax]") {
+		t.Errorf("lint broken = %d, output:\n%s", code, stdout)
+	}
+	if code, _, stderr := runCmd("lint", "-rules", "nope=off", config); code != 2 || !strings.Contains(stderr, `unknown lint rule "nope"`) {
+		t.Errorf("lint -rules nope = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestLint_SuppressionComments(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := writeFile(t, dir, "app.schema.json", `{"type": "object", "properties": {"port": {"type": "integer"}}}`)
+	yamlFile := writeFile(t, dir, "app.yaml", "# tracedconfig:ignore similar-key -- read by the legacy loader\nprot: 80\nport: 80\n# tracedconfig:ignore\nlisten: a\n")
+	code, stdout, stderr := runCmd("lint", "-schema", schemaFile, "-fail-on", "info", yamlFile)
+	if code != 0 || stdout != "" {
+		t.Errorf("lint of YAML = %d, %q, %q, want the findings suppressed", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCmd("lint", "-schema", schemaFile, "-rules", "unknown-key=off", "-fail-on", "warning", yamlFile); code != 1 || !strings.Contains(stdout, yamlFile+`:4:1: warning: listen: unused suppression of every code`) {
+		t.Errorf("lint of YAML = %d, output:\n%s", code, stdout)
+	}
+	iniFile := writeFile(t, dir, "app.ini", "[server]\n; tracedconfig:ignore similar-key\nprot = 80\n")
+	schemaFile = writeFile(t, dir, "ini.schema.json", `{"type": "object", "properties": {"server": {"type": "object", "properties": {"port": {"type": "string"}}}}}`)
+	if code, stdout, stderr := runCmd("lint", "-schema", schemaFile, "-fail-on", "info", iniFile); code != 0 || stdout != "" {
+		t.Errorf("lint of INI = %d, %q, %q, want the finding suppressed", code, stdout, stderr)
+	}
+}
+
+func TestLint_Config(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app.schema.json", `{"type": "object", "properties": {"port": {"type": "integer"}}}`)
+	config := writeFile(t, dir, "app.json", `{"prot": 80, "host": ""}`)
+	cliConfig := writeFile(t, dir, "lint.json", `{"lint": {"schema": "app.schema.json", "rules": {"TC1002": "off"}, "failOn": "warning"}}`)
+
+	code, stdout, stderr := runCmd("lint", "-config", cliConfig, config)
+	if code != 1 || !strings.Contains(stdout, "similar-key") || strings.Contains(stdout, "empty-value") {
+		t.Errorf("lint -config = %d, output:\n%s\nstderr %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCmd("lint", "-config", cliConfig, "-fail-on", "error", config); code != 0 || !strings.Contains(stdout, "similar-key") {
+		t.Errorf("lint -fail-on error = %d, output:\n%s", code, stdout)
+	}
+	if code, _, _ := runCmd("lint", "-config", cliConfig, "-rules", "similar-key=info", config); code != 0 {
+		t.Errorf("lint -rules over config = %d, want 0", code)
+	}
+	if code, _, stderr := runCmd("lint", "-fail-on", "sometimes", config); code != 2 || !strings.Contains(stderr, `invalid fail-on "sometimes"`) {
+		t.Errorf("lint -fail-on sometimes = %d, stderr %q", code, stderr)
+	}
+	bad := writeFile(t, dir, "bad.json", `{"lint": {"failOn": "always"}}`)
+	if code, _, stderr := runCmd("lint", "-config", bad, config); code != 2 || !strings.Contains(stderr, "lint.failOn: invalid fail-on") {
+		t.Errorf("lint -config bad = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCmd("lint", "-config", filepath.Join(dir, "missing.json"), config); code != 2 {
+		t.Errorf("lint -config missing = %d, want 2", code)
+	}
+}
+
+func TestLint_Format(t *testing.T) {
+	dir := t.TempDir()
+	config := writeFile(t, dir, "app.json", `{"port": 80, "port": 81}`)
+	code, stdout, _ := runCmd("lint", "-format", "junit", config)
+	if code != 1 || !strings.HasPrefix(stdout, "<?xml") || !strings.Contains(stdout, `<failure message="duplicate key &#34;port&#34;`) {
+		t.Errorf("lint -format junit = %d, output:\n%s", code, stdout)
+	}
+	code, stdout, _ = runCmd("lint", "-format", "github", config)
+	if want := "::error file=" + config + ",line=1,col=14,endLine=1,endColumn=20,title=TC1001 duplicate-key::port: duplicate key"; code != 1 || !strings.HasPrefix(stdout, want) {
+		t.Errorf("lint -format github = %d, output:\n%s", code, stdout)
+	}
+	if code, _, _ := runCmd("lint", "-format", "html", config); code != 2 {
+		t.Errorf("lint -format html = %d, want 2", code)
+	}
+}
+
+func TestLint_Baseline(t *testing.T) {
+	dir := t.TempDir()
+	config := writeFile(t, dir, "app.json", `{"port": 80, "port": 81}`)
+	baseline := filepath.Join(dir, "baseline.json")
+
+	if code, stdout, stderr := runCmd("lint", "-baseline", baseline, "-update-baseline", config); code != 0 || stdout != "recorded 1 findings in "+baseline+"\n" {
+		t.Fatalf("lint -update-baseline = %d, %q, stderr %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCmd("lint", "-baseline", baseline, config); code != 0 || stdout != "" {
+		t.Errorf("lint -baseline = %d, output:\n%s", code, stdout)
+	}
+	writeFile(t, dir, "app.json", `{"port": 80, "port": 81, "host": "", "host": ""}`)
+	code, stdout, _ := runCmd("lint", "-baseline", baseline, config)
+	if code != 1 || strings.Contains(stdout, "port:") || !strings.Contains(stdout, `host: duplicate key "host"`) {
+		t.Errorf("lint -baseline after a change = %d, output:\n%s", code, stdout)
+	}
+	if code, _, _ := runCmd("lint", "-update-baseline", config); code != 2 {
+		t.Errorf("lint -update-baseline without -baseline = %d, want 2", code)
+	}
+}
+
+func TestLint_Fix(t *testing.T) {
+	dir := t.TempDir()
+	schemaFile := writeFile(t, dir, "app.schema.json", `{"type": "object", "properties": {"debug": {"type": "boolean"}, "port": {"type": "integer"}, "level": {"enum": ["debug", "info"]}}}`)
+	config := writeFile(t, dir, "app.json", "{\n  \"debug\": \"true\", // for now\n  \"port\": \"80s\",\n  \"level\": \"INFO\"\n}\n")
+
+	code, stdout, _ := runCmd("lint", "-schema", schemaFile, config)
+	if code != 1 || !strings.Contains(stdout, "fix: replace with true\n") || !strings.Contains(stdout, `fix: replace with "info"`) {
+		t.Errorf("lint = %d, output:\n%s", code, stdout)
+	}
+	code, stdout, stderr := runCmd("lint", "-schema", schemaFile, "-fix", config)
+	if code != 1 || stderr != "tracedconfig lint: fixed 2 findings in "+config+"\n" {
+		t.Errorf("lint -fix = %d, stderr %q", code, stderr)
+	}
+	if !strings.Contains(stdout, "port: expected integer, got string") || strings.Contains(stdout, "debug:") {
+		t.Errorf("lint -fix reported:\n%s", stdout)
+	}
+	if b, _ := os.ReadFile(config); string(b) != "{\n  \"debug\": true, // for now\n  \"port\": \"80s\",\n  \"level\": \"info\"\n}\n" {
+		t.Errorf("lint -fix wrote:\n%s", b)
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	dir := t.TempDir()
+	code, key, _ := runCmd("encrypt", "-genkey")
+	if code != 0 {
+		t.Fatalf("encrypt -genkey = %d", code)
+	}
+	keyFile := writeFile(t, dir, "key", key)
+
+	code, stdout, stderr := runCmd("encrypt", "-key-file", keyFile, "a", "b")
+	if code != 0 {
+		t.Fatalf("encrypt = %d, stderr %q", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ENC[") {
+		t.Fatalf("output = %q", stdout)
+	}
+	c, err := loadCipher(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sealed.Decrypt(c, lines[1]); err != nil || got != "b" {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+
+	stdin = strings.NewReader("from stdin\n")
+	defer func() { stdin = os.Stdin }()
+	if code, stdout, _ := runCmd("encrypt", "-key-file", keyFile); code != 0 || strings.Count(stdout, "ENC[") != 1 {
+		t.Errorf("encrypt from stdin = %d, %q", code, stdout)
+	}
+	if code, _, _ := runCmd("encrypt"); code != 2 {
+		t.Errorf("encrypt without key = %d, want 2", code)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, _ := runCmd("encrypt", "-genkey")
+	_, newKey, _ := runCmd("encrypt", "-genkey")
+	oldFile := writeFile(t, dir, "old.key", oldKey)
+	newFile := writeFile(t, dir, "new.key", newKey)
+	_, enc, _ := runCmd("encrypt", "-key-file", oldFile, "hunter2")
+	config := writeFile(t, dir, "app.json", "{\n  \"db\": {\"password\": \""+strings.TrimSpace(enc)+"\"}\n}\n")
+	plain := writeFile(t, dir, "plain.json", `{"port": 80}`)
+
+	code, stdout, stderr := runCmd("rekey", "-old-key-file", oldFile, "-new-key-file", newFile, config, plain)
+	if code != 0 {
+		t.Fatalf("rekey = %d, stderr %q", code, stderr)
+	}
+	if want := config + ":2:22: db.password\nrotated 1 values in 1 files\n"; stdout != want {
+		t.Errorf("rekey output = %q, want %q", stdout, want)
+	}
+	c, err := loadCipher(newFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := tracedconfig.NewConfig(sealed.Source(tracedconfig.File(config), c))
+	if err := cfg.Load(context.Background()); err != nil {
+		t.Fatalf("Load() with the new key error = %v", err)
+	}
+
+	// the new key cannot decrypt values of the old one, nothing is written
+	before, _ := os.ReadFile(config)
+	if code, _, stderr := runCmd("rekey", "-old-key-file", oldFile, "-new-key-file", oldFile, config); code != 1 || !strings.Contains(stderr, "db.password") {
+		t.Errorf("rekey with the wrong key = %d, stderr %q", code, stderr)
+	}
+	if after, _ := os.ReadFile(config); !bytes.Equal(before, after) {
+		t.Error("rekey with the wrong key changed the file")
+	}
+	if code, _, _ := runCmd("rekey", config); code != 2 {
+		t.Errorf("rekey without keys = %d, want 2", code)
+	}
+}
+
+func TestWriteKeepingMode(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.json", `{"a": 1}`)
+	if err := os.Chmod(a, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// a failure on any file leaves every file as it was
+	err := writeKeepingMode(fileWrite{path: a, data: []byte(`{"a": 2}`)}, fileWrite{path: filepath.Join(dir, "missing.json")})
+	if err == nil {
+		t.Fatal("writeKeepingMode() of a missing file succeeded")
+	}
+	if b, _ := os.ReadFile(a); string(b) != `{"a": 1}` {
+		t.Errorf("writeKeepingMode() changed a.json to %s", b)
+	}
+	if err := writeKeepingMode(fileWrite{path: a, data: []byte(`{"a": 3}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(a); string(b) != `{"a": 3}` {
+		t.Errorf("writeKeepingMode() wrote %s", b)
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0o600 {
+		t.Errorf("writeKeepingMode() changed the mode to %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("writeKeepingMode() left temporary files: %v", entries)
+	}
+}
+
+func TestSign(t *testing.T) {
+	dir := t.TempDir()
+	code, key, _ := runCmd("sign", "-genkey")
+	if code != 0 {
+		t.Fatalf("sign -genkey = %d", code)
+	}
+	keyFile := writeFile(t, dir, "key", key)
+	code, pub, _ := runCmd("sign", "-key-file", keyFile, "-public")
+	if code != 0 {
+		t.Fatalf("sign -public = %d", code)
+	}
+	pubKey, err := signing.ParsePublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.Keyring{"ci": pubKey}
+
+	a := writeFile(t, dir, "a.json", `{"port": 80}`)
+	b := writeFile(t, dir, "b.json", `{"port": 81}`)
+	if code, _, stderr := runCmd("sign", "-key-file", keyFile, "-signer", "ci", a); code != 0 {
+		t.Fatalf("sign = %d, stderr %q", code, stderr)
+	}
+	if code, _, stderr := runCmd("sign", "-key-file", keyFile, "-signer", "ci", "-detached", b); code != 0 {
+		t.Fatalf("sign -detached = %d, stderr %q", code, stderr)
+	}
+	c := tracedconfig.NewConfig(
+		signing.Source(tracedconfig.File(a), keys),
+		signing.DetachedSource(tracedconfig.File(b), keys, b+".sig"),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+	if code, _, _ := runCmd("sign", "-key-file", keyFile, a); code != 2 {
+		t.Errorf("sign without signer = %d, want 2", code)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app.json", `{"port": 80}`)
+	if code, stdout, _ := runCmd("verify", "-update", dir); code != 0 || !strings.Contains(stdout, "locked 1 files") {
+		t.Fatalf("verify -update = %d, %q", code, stdout)
+	}
+	if code, stdout, stderr := runCmd("verify", dir); code != 0 {
+		t.Fatalf("verify = %d, %q %q", code, stdout, stderr)
+	}
+	writeFile(t, dir, "app.json", `{"port": 81}`)
+	code, stdout, stderr := runCmd("verify", dir)
+	if code != 1 || !strings.HasPrefix(stdout, "modified app.json") || !strings.Contains(stderr, "1 files differ") {
+		t.Errorf("verify after change = %d, %q %q", code, stdout, stderr)
+	}
+	if code, _, _ := runCmd("verify"); code != 2 {
+		t.Errorf("verify without dir = %d, want 2", code)
+	}
+}
+
+func TestHook(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if _, err := git("init", "--quiet"); err != nil {
+		t.Skipf("git is not available: %v", err)
+	}
+
+	writeFile(t, dir, "bad.json", `{"port": 80, "port": 81}`)
+	writeFile(t, dir, "notes.txt", `{"port": 80, "port": 81}`)
+	writeFile(t, dir, "unstaged.json", `{"port": 80, "port": 81}`)
+	if _, err := git("add", "bad.json", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// the working tree is fixed but the staged content is what gets committed
+	writeFile(t, dir, "bad.json", `{"port": 81}`)
+
+	code, stdout, stderr := runCmd("hook", "-staged")
+	if code != 1 || stdout != "bad.json:1:14: error: port: duplicate key \"port\", first defined at bad.json:1:2, only the last one is used [TC1001 duplicate-key]\n" {
+		t.Errorf("hook -staged = %d, output:\n%s", code, stdout)
+	}
+	if stderr != "tracedconfig hook: 1 findings in 1 files, 1 files fail\n" {
+		t.Errorf("hook -staged stderr = %q", stderr)
+	}
+	if code, stdout, _ := runCmd("hook", "bad.json"); code != 0 || stdout != "" {
+		t.Errorf("hook bad.json = %d, output:\n%s", code, stdout)
+	}
+	if code, _, _ := runCmd("hook", "-staged", "bad.json"); code != 2 {
+		t.Errorf("hook -staged with files = %d, want 2", code)
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.json", "{\n  \"server\": {\"host\": \"localhost\", \"port\": 80},\n  \"peers\": [\"a\", \"b\"]\n}")
+	prod := writeFile(t, dir, "prod.json", `{"server": {"port": 443}}`)
+	stdin = strings.NewReader("ls\ncd server\nshow port\ndef port\ncd ..\nfind local\ntree 2\ncd $.peers[1]\nnope\nquit\n")
+	defer func() { stdin = os.Stdin }()
+
+	code, stdout, stderr := runCmd("browse", base, prod)
+	if code != 0 {
+		t.Fatalf("browse = %d, stderr %q", code, stderr)
+	}
+	for _, want := range []string{
+		"$> server: {2 keys}  (" + base + ":2:13)\npeers: [2 items]  (" + base + ":3:12)\n$> $.server> ",
+		"$.server> type: number\nspan: " + prod + ":1:21\nserver.port = 443\n  set by " + prod + ":1:21 (" + prod + ")\n  overrides 80 from " + base + ":2:43",
+		"$.server> " + prod + ":1:21\n1: {\"server\": {\"port\": 443}}",
+		"$> $.server.host = \"localhost\"  (" + base + ":2:22)\n",
+		"$> server: {2 keys}  (" + base + ":2:13)\n  host: \"localhost\"  (" + base + ":2:22)\n",
+		"$.peers[1]> error: unknown command \"nope\", try help\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("browse output missing %q:\n%s", want, stdout)
+		}
+	}
+	if code, _, _ := runCmd("browse"); code != 2 {
+		t.Errorf("browse without files = %d, want 2", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := writeFile(t, dir, "old.json", `{"port": 80, "host": "a"}`)
+	changed := writeFile(t, dir, "new.json", `{"host": "a", "port": 81}`)
+
+	if code, stdout, _ := runCmd("diff", old, changed); code != 1 || stdout != "~ port: 80 -> 81\n~ $: keys reordered\n" {
+		t.Errorf("diff = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("diff", "-ignore-order", "-format", "markdown", old, changed); code != 1 || !strings.Contains(stdout, "| modified | `port` | `80`<br>"+old+":1:10 |") {
+		t.Errorf("diff -format markdown = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("diff", "-format", "html", old, old); code != 0 || stdout != "<p>No changes.</p>\n" {
+		t.Errorf("diff -format html of equal files = %d, %q", code, stdout)
+	}
+	if code, _, _ := runCmd("diff", old); code != 2 {
+		t.Errorf("diff with one file = %d, want 2", code)
+	}
+}
+
+func TestGenTypes(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.json", `{"port": 80, "timeout": "5s", "db": {"url": "postgres://a"}}`)
+	b := writeFile(t, dir, "b.json", `{"port": 81, "timeout": "1m", "debug": true}`)
+
+	code, stdout, stderr := runCmd("gen-types", "-package", "app", a, b)
+	if code != 0 {
+		t.Fatalf("gen-types = %d, stderr %q", code, stderr)
+	}
+	for _, want := range []string{
+		"package app\n",
+		"type Config struct {",
+		"Port    int           `json:\"port\" required:\"true\"`",
+		"Timeout time.Duration `json:\"timeout\" required:\"true\"`",
+		"DB      DB            `json:\"db\"`",
+		"type DB struct {",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %s:\n%s", want, stdout)
+		}
+	}
+
+	out := filepath.Join(dir, "config.go")
+	if code, _, stderr := runCmd("gen-types", "-type", "Settings", "-o", out, a); code != 0 {
+		t.Fatalf("gen-types -o = %d, stderr %q", code, stderr)
+	}
+	if b, err := os.ReadFile(out); err != nil || !strings.Contains(string(b), "type Settings struct {") {
+		t.Errorf("written source = %q, %v", b, err)
+	}
+
+	list := writeFile(t, dir, "list.json", `[1]`)
+	if code, _, stderr := runCmd("gen-types", list); code != 1 || !strings.Contains(stderr, "expected an object") {
+		t.Errorf("gen-types of an array = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCmd("gen-types"); code != 2 {
+		t.Errorf("gen-types without files = %d, want 2", code)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	c := tracedconfig.NewConfig(tracedconfig.File(path))
+	for _, data := range []string{`{"port": 80}`, `{"port": 81}`} {
+		writeFile(t, filepath.Dir(path), "app.json", data)
+		if err := c.Load(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.StripPrefix("/debug/config", debug.Handler(c)))
+	defer srv.Close()
+
+	code, stdout, stderr := runCmd("rollback", srv.URL+"/debug/config/")
+	if code != 0 || stdout != "~ port: 81 -> 80\n" {
+		t.Errorf("rollback = %d, %q, stderr %q", code, stdout, stderr)
+	}
+	if code, _, stderr := runCmd("rollback", "-n", "2", srv.URL+"/debug/config"); code != 1 || !strings.Contains(stderr, "409 Conflict: cannot roll back 2 snapshots") {
+		t.Errorf("rollback -n 2 = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCmd("rollback", "-n", "0", srv.URL); code != 2 {
+		t.Errorf("rollback -n 0 = %d, want 2", code)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	dir := t.TempDir()
+	j := writeFile(t, dir, "app.json", "{\n  \"server\": {\"port\": 8080}\n}")
+	y := writeFile(t, dir, "app.yaml", "server:\n  port: 9090\n")
+	other := writeFile(t, dir, "other.toml", "[db]\nurl = \"x\"\n")
+
+	if code, stdout, _ := runCmd("locate", "$.server.port", j, y); code != 0 || stdout != j+":2:22\n"+y+":2:9\n" {
+		t.Errorf("locate = %d, %q", code, stdout)
+	}
+	code, stdout, _ := runCmd("locate", "$.server.port", j, "--format=json")
+	want := `{"file":"` + j + `","line":2,"col":22,"endLine":2,"endCol":26}` + "\n"
+	if code != 0 || stdout != want {
+		t.Errorf("locate -format json = %d, %q, want %q", code, stdout, want)
+	}
+	code, stdout, stderr := runCmd("locate", "server.port", y, other)
+	if code != 1 || stdout != y+":2:9\n" || !strings.Contains(stderr, other+" does not set server.port") {
+		t.Errorf("locate of a missing path = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, _ := runCmd("locate", "$.a", j, "-format", "xml"); code != 2 {
+		t.Errorf("locate -format xml = %d, want 2", code)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.json", `{"server": {"timeout": "5s", "read_timeout": 30}, "note": "no timeout here"}`)
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := writeFile(t, dir, "nested/b.yaml", "servers:\n  - host: a\n    timeout: 10s\n")
+	writeFile(t, dir, "README.md", "timeout")
+
+	code, stdout, _ := runCmd("grep", "timeout", dir+"/...")
+	want := a + `:1:24: server.timeout = "5s"` + "\n" +
+		a + ":1:46: server.read_timeout = 30\n" +
+		a + `:1:59: note = "no timeout here"` + "\n" +
+		b + ":3:14: servers[0].timeout = \"10s\"\n"
+	if code != 0 || stdout != want {
+		t.Errorf("grep = %d, %q, want %q", code, stdout, want)
+	}
+	if code, stdout, _ := runCmd("grep", "-keys", "-type", "string", "timeout", dir+"/..."); code != 0 || strings.Count(stdout, "\n") != 2 || strings.Contains(stdout, "note") {
+		t.Errorf("grep -keys -type string = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("grep", "-i", "TIMEOUT", dir, "-path", "**.timeout"); code != 0 || stdout != a+`:1:24: server.timeout = "5s"`+"\n" {
+		t.Errorf("grep of a directory with -path = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("grep", "-path", "servers[*].*", "-values", "^a$", b); code != 0 || stdout != b+":2:11: servers[0].host = \"a\"\n" {
+		t.Errorf("grep -path -values = %d, %q", code, stdout)
+	}
+	if code, _, _ := runCmd("grep", "-F", "(", a); code != 1 {
+		t.Errorf("grep without a match = %d, want 1", code)
+	}
+	if code, _, _ := runCmd("grep", "-type", "date", "x", a); code != 2 {
+		t.Errorf("grep -type date = %d, want 2", code)
+	}
+}
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+	j := writeFile(t, dir, "a.json", "{\n  // servers\n  \"servers\": [\n    {\"host\": \"a\", \"timeout\": \"10s\"},\n    {\"host\": \"b\", \"timeout\": \"30s\"}\n  ]\n}\n")
+	y := writeFile(t, dir, "b.yaml", "servers:\n  - host: c   # primary\n    timeout: 5s\n")
+	tm := writeFile(t, dir, "c.toml", "[[servers]]\nhost = \"d\"\ntimeout = \"1m\" # slow\n")
+
+	code, stdout, stderr := runCmd("set", "$.servers[*].timeout", "30s", dir, "-w")
+	if code != 0 {
+		t.Fatalf("set = %d, stderr %q", code, stderr)
+	}
+	wantDiff := "--- " + j + "\n+++ " + j + "\n@@ -4,1 +4,1 @@\n-    {\"host\": \"a\", \"timeout\": \"10s\"},\n+    {\"host\": \"a\", \"timeout\": \"30s\"},\n" +
+		"--- " + y + "\n+++ " + y + "\n@@ -3,1 +3,1 @@\n-    timeout: 5s\n+    timeout: 30s\n" +
+		"--- " + tm + "\n+++ " + tm + "\n@@ -3,1 +3,1 @@\n-timeout = \"1m\" # slow\n+timeout = \"30s\" # slow\n"
+	if stdout != wantDiff {
+		t.Errorf("set diff = %q, want %q", stdout, wantDiff)
+	}
+	for file, want := range map[string]string{
+		j:  "{\n  // servers\n  \"servers\": [\n    {\"host\": \"a\", \"timeout\": \"30s\"},\n    {\"host\": \"b\", \"timeout\": \"30s\"}\n  ]\n}\n",
+		y:  "servers:\n  - host: c   # primary\n    timeout: 30s\n",
+		tm: "[[servers]]\nhost = \"d\"\ntimeout = \"30s\" # slow\n",
+	} {
+		if b, _ := os.ReadFile(file); string(b) != want {
+			t.Errorf("set -w wrote %s:\n%s", file, b)
+		}
+	}
+
+	// values are JSON unless -string, written in the style of the file
+	if code, stdout, _ := runCmd("set", "servers[0].host", `{"name": "x", "ports": [1, 2]}`, y, tm); code != 0 ||
+		!strings.Contains(stdout, `+  - host: {"name":"x","ports":[1,2]}   # primary`) ||
+		!strings.Contains(stdout, `+host = { name = "x", ports = [1, 2] }`) {
+		t.Errorf("set of an object = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("set", "-string", "servers[0].host", "8080", y); code != 0 || !strings.Contains(stdout, `+  - host: "8080"   # primary`) {
+		t.Errorf("set -string = %d, %q", code, stdout)
+	}
+	if b, _ := os.ReadFile(y); !strings.Contains(string(b), "host: c ") {
+		t.Errorf("set without -w changed the file:\n%s", b)
+	}
+	if code, _, stderr := runCmd("set", "servers[0].host", "null", tm); code != 2 || !strings.Contains(stderr, "TOML has no null") {
+		t.Errorf("set of null in TOML = %d, %q", code, stderr)
+	}
+	if code, _, _ := runCmd("set", "-w", "servers[0].host", "null", j, tm); code != 2 {
+		t.Errorf("set -w of null in JSON and TOML = %d", code)
+	}
+	if b, _ := os.ReadFile(j); !strings.Contains(string(b), `"host": "a"`) {
+		t.Errorf("set -w changed %s although another file failed:\n%s", j, b)
+	}
+	if code, stdout, _ := runCmd("set", "$.servers[*].timeout", "30s", j); code != 0 || stdout != "" {
+		t.Errorf("set of unchanged values = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("set", "nope", "1", j); code != 1 || !strings.Contains(stderr, "no value matches nope") {
+		t.Errorf("set of a missing path = %d, %q", code, stderr)
+	}
+
+	// a pattern matching a value and values inside it only replaces the outermost one
+	nested := writeFile(t, dir, "nested.json", "{\"a\": {\"b\": [1, 2]}, \"c\": 3}\n")
+	if err := os.Chmod(nested, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []string{"**", "a.**"} {
+		if code, _, stderr := runCmd("set", pattern, "5", nested); code != 0 {
+			t.Errorf("set %s = %d, stderr %q", pattern, code, stderr)
+		}
+	}
+	if code, _, stderr := runCmd("set", "-w", "**", "5", nested); code != 0 {
+		t.Fatalf("set -w ** = %d, stderr %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(nested); string(b) != "{\"a\": 5, \"c\": 5}\n" {
+		t.Errorf("set -w ** wrote %s", b)
+	}
+	if info, err := os.Stat(nested); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("set -w changed the mode: %v %v", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("set -w left temporary files: %v", entries)
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	j := writeFile(t, dir, "a.json", "{\n  \"db\": {\n    \"timeout\": \"5s\", // per query\n    \"pool\": 10\n  },\n  \"server\": {\"port\": 80}\n}\n")
+	y := writeFile(t, dir, "b.yaml", "db:\n  timeout: 5s  # per query\n  pool: 10\n")
+	tm := writeFile(t, dir, "c.toml", "[db]\ntimeout = \"5s\"\n")
+
+	code, stdout, stderr := runCmd("rename", "db.timeout", "db.query_timeout", dir, "-w")
+	if code != 0 {
+		t.Fatalf("rename = %d, stderr %q", code, stderr)
+	}
+	for file, want := range map[string]string{
+		j:  "{\n  \"db\": {\n    \"query_timeout\": \"5s\", // per query\n    \"pool\": 10\n  },\n  \"server\": {\"port\": 80}\n}\n",
+		y:  "db:\n  query_timeout: 5s  # per query\n  pool: 10\n",
+		tm: "[db]\nquery_timeout = \"5s\"\n",
+	} {
+		if b, _ := os.ReadFile(file); string(b) != want {
+			t.Errorf("rename -w wrote %s:\n%s", file, b)
+		}
+	}
+	if !strings.Contains(stdout, "@@ -2,1 +2,1 @@\n-  timeout: 5s  # per query\n+  query_timeout: 5s  # per query\n") {
+		t.Errorf("rename diff = %q", stdout)
+	}
+
+	code, stdout, stderr = runCmd("rename", "-alias", "db.pool", "db.pool_size", y)
+	want := "@@ -3,1 +3,3 @@\n-  pool: 10\n+  # Deprecated: renamed to db.pool_size\n+  pool: 10\n+  pool_size: 10\n"
+	if code != 0 || !strings.Contains(stdout, want) {
+		t.Errorf("rename -alias = %d, %q, %q", code, stdout, stderr)
+	}
+
+	// moving to another object rewrites JSON only
+	code, stdout, stderr = runCmd("rename", "db.pool", "server.pool", j, "-w")
+	if code != 0 {
+		t.Fatalf("rename to another object = %d, stderr %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(j); string(b) != "{\n  \"db\": {\n    \"query_timeout\": \"5s\" // per query\n  },\n  \"server\": {\"port\": 80, \"pool\": 10}\n}\n" {
+		t.Errorf("rename to another object wrote:\n%s", b)
+	}
+	if code, _, stderr := runCmd("rename", "db.pool", "server.pool", y); code != 2 || !strings.Contains(stderr, "only supported in JSON files") {
+		t.Errorf("rename to another object in YAML = %d, %q", code, stderr)
+	}
+	if code, _, stderr := runCmd("rename", "-alias", "server.port", "listen.http.port", j); code != 0 {
+		t.Errorf("rename -alias into new objects = %d, %q", code, stderr)
+	} else if _, stdout, _ := runCmd("rename", "-alias", "server.port", "listen.http.port", j); !strings.Contains(stdout, `+  "server": {/* Deprecated: renamed to listen.http.port */ "port": 80, "pool": 10},`+"\n"+`+  "listen": {"http": {"port": 80}}`) {
+		t.Errorf("rename -alias into new objects diff = %q", stdout)
+	}
+	if code, _, stderr := runCmd("rename", "db.query_timeout", "server.port", j); code != 2 || !strings.Contains(stderr, "server.port is already set") {
+		t.Errorf("rename onto an existing key = %d, %q", code, stderr)
+	}
+	headers := writeFile(t, dir, "headers.toml", "[db]\nhost = \"a\"\n[db.pool]\nsize = 1\n")
+	if code, _, stderr := runCmd("rename", "db", "database", headers); code != 2 || !strings.Contains(stderr, "cannot rename db in place: the edited file reads differently") {
+		t.Errorf("rename of a key in several TOML headers = %d, %q", code, stderr)
+	}
+	if code, _, _ := runCmd("rename", "nope", "other", dir); code != 1 {
+		t.Errorf("rename of a missing key = %d, want 1", code)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dir := t.TempDir()
+	cfg := writeFile(t, t.TempDir(), ".tracedconfig.json", `{"normalize": {"sortKeys": true, "profiles": {"wide": {"indent": 4, "normalizeNumbers": true}}}}`)
+	a := writeFile(t, dir, "a.json", "{\"b\": 1.50, // rate\n\"a\": [1,\n2]}")
+	clean := writeFile(t, dir, "clean.json", "{\n  \"a\": 1\n}\n")
+	writeFile(t, dir, "c.yaml", "a: 1\n")
+
+	code, stdout, stderr := runCmd("normalize", "-config", cfg, a)
+	if want := "{\n  \"a\": [1, 2],\n  \"b\": 1.50 // rate\n}\n"; code != 0 || stdout != want {
+		t.Errorf("normalize = %d, %q, %q", code, stdout, stderr)
+	}
+	code, stdout, _ = runCmd("normalize", "-config", cfg, "-profile", "wide", "-arrays", "expand", "-sort-keys=false", a)
+	if want := "{\n    \"b\": 1.5, // rate\n    \"a\": [\n        1,\n        2\n    ]\n}\n"; code != 0 || stdout != want {
+		t.Errorf("normalize -profile = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("normalize", "-config", cfg, "-profile", "nope", a); code != 2 || !strings.Contains(stderr, `unknown profile "nope"`) {
+		t.Errorf("normalize with an unknown profile = %d, %q", code, stderr)
+	}
+
+	code, stdout, stderr = runCmd("normalize", "-config", cfg, "-l", dir)
+	if code != 1 || stdout != a+"\n" || !strings.Contains(stderr, "skipping "+filepath.Join(dir, "c.yaml")) {
+		t.Errorf("normalize -l = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := runCmd("normalize", "-config", cfg, "-w", dir); code != 0 {
+		t.Fatalf("normalize -w = %d, %q", code, stderr)
+	}
+	if code, stdout, _ := runCmd("normalize", "-config", cfg, "-l", a, clean); code != 0 || stdout != "" {
+		t.Errorf("normalize -l after -w = %d, %q", code, stdout)
+	}
+
+	bad := writeFile(t, t.TempDir(), "bad.json", `{"normalize": {"profiles": {"x": {"arrays": "wrap"}}}}`)
+	if code, _, stderr := runCmd("normalize", "-config", bad, a); code != 2 || !strings.Contains(stderr, `invalid arrays "wrap"`) {
+		t.Errorf("normalize with an invalid config = %d, %q", code, stderr)
+	}
+}
+
+func TestRepeats(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.json", "{\n  \"api\": \"https://api.example.com\"\n}\n")
+	b := writeFile(t, dir, "b.yaml", "api: https://api.example.com\nlevel: info\n")
+	c := writeFile(t, dir, "c.toml", "[client]\napi = \"https://api.example.com\"\n")
+
+	code, stdout, stderr := runCmd("repeats", dir)
+	want := a + `:2:10: info: api: value "https://api.example.com" is repeated 3 times, consider extracting it to a shared key like "api" [TC2010 repeated-value]` + "\n" +
+		"  " + b + ":1:6: repeated at api\n" +
+		"  " + c + ":2:7: repeated at client.api\n"
+	if code != 1 || stdout != want {
+		t.Errorf("repeats = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCmd("repeats", "-min-count", "4", dir); code != 0 || stdout != "" {
+		t.Errorf("repeats -min-count 4 = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("repeats", "-format", "github", "-min-count", "2", a, b); code != 1 || !strings.HasPrefix(stdout, "::notice file="+a+",line=2,col=10,") {
+		t.Errorf("repeats -format github = %d, %q", code, stdout)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", "db:\n  host: db.internal\n  port: 5432\ndsn: postgres://${db.host}:${db.port}/${db.name}\n")
+	prod := writeFile(t, dir, "prod.json", `{"db": {"name": "app"}}`)
+
+	if code, stdout, stderr := runCmd("refs", base, prod); code != 0 || stdout != "" {
+		t.Errorf("refs with every layer = %d, %q, %q", code, stdout, stderr)
+	}
+	code, stdout, _ := runCmd("refs", base)
+	for _, want := range []string{
+		base + ":4:6: error: dsn: reference ${db.name} does not resolve, db.name is not set [TC4002 dead-reference]",
+		base + ":2:3: note: db has the keys host, port",
+	} {
+		if code != 1 || !strings.Contains(stdout, want) {
+			t.Errorf("refs = %d, output missing %q:\n%s", code, want, stdout)
+		}
+	}
+	alias := writeFile(t, dir, "alias.yaml", "defaults: &defaults\n  retries: 3\nclient: *default\n")
+	code, stdout, _ = runCmd("refs", alias)
+	if want := alias + `:3:9: error: unknown anchor "default", did you mean *defaults? [TC4002 dead-reference]`; code != 1 || !strings.Contains(stdout, want) {
+		t.Errorf("refs with a dead alias = %d, output:\n%s", code, stdout)
+	}
+	if code, _, _ := runCmd("refs", filepath.Join(dir, "missing.json")); code != 2 {
+		t.Errorf("refs with a missing file = %d, want 2", code)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", "db:\n  host: db.internal\ndsn: postgres://${db.host}/${db.name}\nurl: ${dsn}?sslmode=${env:SSLMODE}\n")
+	prod := writeFile(t, dir, "prod.json", `{"db": {"host": "db.prod"}}`)
+
+	code, stdout, stderr := runCmd("graph", base, prod)
+	want := `digraph config {
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_0" {
+    label="` + base + `";
+    "dsn";
+    "url";
+  }
+  subgraph "cluster_1" {
+    label="` + prod + `";
+    "db.host";
+  }
+  "db.name" [style=dashed, color=red];
+  "dsn" -> "db.host" [label="${db.host}"];
+  "dsn" -> "db.name" [label="${db.name}"];
+  "url" -> "dsn" [label="${dsn}"];
+}
+`
+	if code != 0 || stdout != want {
+		t.Errorf("graph = %d, %q\n%s", code, stderr, stdout)
+	}
+
+	code, stdout, _ = runCmd("graph", "-format", "json", base)
+	var g graph
+	if err := json.Unmarshal([]byte(stdout), &g); err != nil || code != 0 {
+		t.Fatalf("graph -format json = %d, %v\n%s", code, err, stdout)
+	}
+	if len(g.Keys) != 4 || g.Keys[1] != (graphKey{Path: "db.host", File: base, Line: 2, Col: 9}) || len(g.Edges) != 3 || g.Edges[2] != (graphEdge{From: "url", To: "dsn", Ref: "${dsn}"}) {
+		t.Errorf("graph -format json = %+v", g)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	dir := t.TempDir()
+	dev := writeFile(t, dir, "dev.json", `{"db": {"host": "dev-db", "pool": 5}, "debug": false, "cache": {"ttl": 30}}`)
+	stg := writeFile(t, dir, "staging.yaml", "db:\n  host: stg-db\n  pool: 5\ndebug: false\n")
+	prod := writeFile(t, dir, "p.toml", "debug = true\n[db]\nhost = \"prod-db\"\npool = 5\n[cache]\nttl = 30\n")
+
+	code, stdout, stderr := runCmd("matrix", "-expect", "db.host", dev, stg, "prod="+prod)
+	want := "PATH       dev       staging   prod       NOTE\n" +
+		"db.host    \"dev-db\"  \"stg-db\"  \"prod-db\"  expected to differ\n" +
+		"debug      false     false     true       differs in prod\n" +
+		"cache.ttl  30        -         30         missing in staging\n"
+	if code != 1 || stdout != want {
+		t.Errorf("matrix = %d, %q\n%s", code, stderr, stdout)
+	}
+	code, stdout, _ = runCmd("matrix", "-format", "markdown", "-all", dev, stg)
+	want = "| PATH | dev | staging | NOTE |\n| --- | --- | --- | --- |\n" +
+		"| `db.host` | \"dev-db\" | \"stg-db\" | differs |\n" +
+		"| `db.pool` | 5 | 5 |  |\n" +
+		"| `debug` | false | false |  |\n" +
+		"| `cache.ttl` | 30 | - | missing in staging |\n"
+	if code != 1 || stdout != want {
+		t.Errorf("matrix -format markdown -all = %d\n%s", code, stdout)
+	}
+	if _, stdout, _ := runCmd("matrix", "-format", "markdown", dev, stg, prod); !strings.Contains(stdout, "| `debug` | false | false | **true** | differs in p |") {
+		t.Errorf("matrix -format markdown highlights\n%s", stdout)
+	}
+	if code, stdout, _ := runCmd("matrix", "-format", "markdown", dev, prod); code != 1 || !strings.Contains(stdout, "| `debug` | false | true | differs |") {
+		t.Errorf("matrix of two = %d\n%s", code, stdout)
+	}
+	same := writeFile(t, dir, "same.json", `{"db": {"host": "dev-db", "pool": 5.0}, "debug": false, "cache": {"ttl": 30}}`)
+	if code, stdout, _ := runCmd("matrix", dev, same); code != 0 || stdout != "PATH  dev  same  NOTE\n" {
+		t.Errorf("matrix of equal configs = %d, %q", code, stdout)
+	}
+}
+
+func TestTFVars(t *testing.T) {
+	dir := t.TempDir()
+	dev := writeFile(t, dir, "dev.tfvars", "region         = \"eu-west-1\" # primary\ninstance_count = 2\n")
+	prod := writeFile(t, dir, "prod.tfvars.json", "{\"region\": \"eu-west-1\", \"instance_count\": 6}\n")
+
+	code, stdout, stderr := runCmd("set", "region", "us-east-1", dev, "-w")
+	if code != 0 || !strings.Contains(stdout, "+region         = \"us-east-1\" # primary\n") {
+		t.Fatalf("set = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := runCmd("rename", "instance_count", "instances", dev, "-w"); code != 0 {
+		t.Fatalf("rename = %d, %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(dev); string(b) != "region         = \"us-east-1\" # primary\ninstances = 2\n" {
+		t.Errorf("edited %s:\n%s", dev, b)
+	}
+	if code, stdout, _ := runCmd("matrix", "dev="+dev, "prod="+prod); code != 1 || !strings.Contains(stdout, "instances") || !strings.Contains(stdout, "us-east-1") {
+		t.Errorf("matrix = %d, %q", code, stdout)
+	}
+	broken := writeFile(t, dir, "broken.tfvars", "region = var.region\n")
+	if code, _, stderr := runCmd("locate", "region", broken); code != 2 || !strings.Contains(stderr, "var.region is not a literal value") {
+		t.Errorf("locate in a file with a reference = %d, %q", code, stderr)
+	}
+}
+
+func TestK8s(t *testing.T) {
+	const spec = `{"definitions": {
+		"io.k8s.api.core.v1.ConfigMap": {"type": "object", "properties": {
+			"apiVersion": {"type": "string"}, "kind": {"type": "string"},
+			"metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+			"data": {"type": "object", "additionalProperties": {"type": "string"}}},
+			"x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]},
+		"io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object", "properties": {"name": {"type": "string"}}}}}`
+	dir := t.TempDir()
+	specFile := writeFile(t, dir, "openapi.json", spec)
+	good := writeFile(t, dir, "good.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata: {name: app}\ndata:\n  LOG_LEVEL: debug\n")
+	bad := writeFile(t, dir, "bad.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  nmae: app\ndata:\n  WORKERS: 4\n---\napiVersion: v1\nkind: ConfigMapp\n")
+
+	if code, stdout, stderr := runCmd("k8s", "-spec", specFile, good); code != 0 || stdout != "" {
+		t.Errorf("k8s of a valid manifest = %d, %q, %q", code, stdout, stderr)
+	}
+	code, stdout, _ := runCmd("k8s", bad, "-spec", specFile)
+	for _, want := range []string{
+		bad + `:4:3: error: metadata.nmae: unknown field "nmae", did you mean "name"? [TC1004 similar-key]` + "\n",
+		"4:   nmae: app\n   ^ start\n",
+		bad + `:6:12: error: data.WORKERS: expected string, got number [TC2003 type-mismatch]`,
+		"fix: replace with \"4\"\n",
+		bad + `:9:7: error: kind: unknown kind "ConfigMapp", did you mean "ConfigMap"? [TC1007 unknown-kind]`,
+	} {
+		if code != 1 || !strings.Contains(stdout, want) {
+			t.Errorf("k8s = %d, %s\nwant %q", code, stdout, want)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openapi/v2" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(spec))
+	}))
+	defer srv.Close()
+	if code, stdout, _ := runCmd("k8s", "-spec", srv.URL+"/openapi/v2", "-format", "github", dir); code != 1 ||
+		!strings.Contains(stdout, "::error file="+bad+",line=4,col=3") {
+		t.Errorf("k8s -spec url -format github = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("k8s", "-spec", srv.URL+"/nope", good); code != 2 || !strings.Contains(stderr, "404 Not Found") {
+		t.Errorf("k8s with a missing spec = %d, %q", code, stderr)
+	}
+	if code, _, _ := runCmd("k8s", good); code != 2 {
+		t.Errorf("k8s without -spec = %d, want 2", code)
+	}
+}
+
+func TestHelm(t *testing.T) {
+	chart := t.TempDir()
+	writeFile(t, chart, "values.yaml", "image:\n  repository: nginx\n  tag: \"1.25\"\nreplicas: 1\n")
+	dir := t.TempDir()
+	prod := writeFile(t, dir, "prod.yaml", "image:\n  tag: \"1.26\"\n")
+
+	code, stdout, stderr := runCmd("helm", chart, "image.tag", "replicas", "-f", prod, "--set", "replicas=3")
+	want := "image.tag = \"1.26\"\n" +
+		"  set by " + prod + ":2:8 (" + prod + ")\n" +
+		"  overrides \"1.25\" from " + filepath.Join(chart, "values.yaml") + ":3:8 (" + filepath.Join(chart, "values.yaml") + ")\n" +
+		"replicas = 3\n" +
+		"  set by --set:1:10 (--set replicas=3)\n" +
+		"  overrides 1 from " + filepath.Join(chart, "values.yaml") + ":4:11 (" + filepath.Join(chart, "values.yaml") + ")\n"
+	if code != 0 || stdout != want {
+		t.Errorf("helm = %d, %q, %q\nwant %q", code, stdout, stderr, want)
+	}
+	if code, stdout, _ := runCmd("helm", "-set-string", "replicas=3", chart); code != 0 ||
+		stdout != "{\n  \"image\": {\n    \"repository\": \"nginx\",\n    \"tag\": \"1.25\"\n  },\n  \"replicas\": \"3\"\n}\n" {
+		t.Errorf("helm without paths = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("helm", chart, "image.digest"); code != 1 || !strings.Contains(stderr, "image.digest is not set") {
+		t.Errorf("helm of a missing path = %d, %q", code, stderr)
+	}
+	if code, _, stderr := runCmd("helm", chart, "-set", "a[x]=1"); code != 2 || !strings.Contains(stderr, `invalid index "x" at --set:1:2`) {
+		t.Errorf("helm with an invalid -set = %d, %q", code, stderr)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "compose.yaml", "services:\n  web:\n    image: nginx:${TAG:-latest}\n    environment:\n      LOG_LEVEL: info\n      DB_HOST: db\n")
+	override := writeFile(t, dir, "compose.override.yaml", "services:\n  web:\n    environment:\n      LOG_LEVEL: ${LOG_LEVEL}\n")
+	writeFile(t, dir, ".env", "TAG=1.27\nLOG_LEVEL=debug\n")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	code, stdout, stderr := runCmd("compose", "-f", base, "-f", override, "web", "LOG_LEVEL")
+	want := "LOG_LEVEL = \"warn\"\n" +
+		"  set by " + override + ":4:18 (" + override + ")\n" +
+		"  overrides \"info\" from " + base + ":5:18 (" + base + ")\n" +
+		"  ${LOG_LEVEL} in " + override + ":4:18 expands LOG_LEVEL=\"warn\" from the environment\n"
+	if code != 0 || stdout != want {
+		t.Errorf("compose = %d, %q, %q\nwant %q", code, stdout, stderr, want)
+	}
+	if code, stdout, _ := runCmd("compose", "-f", base, "-f", override, "web"); code != 0 || !strings.Contains(stdout, "DB_HOST=db") ||
+		!strings.Contains(stdout, "LOG_LEVEL=warn") {
+		t.Errorf("compose web = %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runCmd("compose", "-f", base); code != 0 || !strings.Contains(stdout, `"image": "nginx:1.27"`) {
+		t.Errorf("compose without a service = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("compose", "-f", base, "web", "PORT"); code != 1 || !strings.Contains(stderr, "PORT is not set in the environment of web") {
+		t.Errorf("compose of a missing variable = %d, %q", code, stderr)
+	}
+	if code, _, stderr := runCmd("compose", "-f", base, "api"); code != 2 || !strings.Contains(stderr, "no service api, the services are web") {
+		t.Errorf("compose of a missing service = %d, %q", code, stderr)
+	}
+}
+
+func TestINI(t *testing.T) {
+	dir := t.TempDir()
+	conf := writeFile(t, dir, "app.ini", "[server]\nport = 8080 \n")
+	unit := writeFile(t, dir, "app.service", "[Service]\nEnvironment=A=1\nEnvironment=B=2\n")
+
+	if code, _, stderr := runCmd("set", "server.port", "9090", conf, "-w"); code != 0 {
+		t.Fatalf("set = %d, %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(conf); string(b) != "[server]\nport = 9090 \n" {
+		t.Errorf("set -w wrote\n%s", b)
+	}
+	if code, _, stderr := runCmd("set", "server.port", "[1]", conf); code != 2 || !strings.Contains(stderr, "INI values are single lines") {
+		t.Errorf("set of a list = %d, %q", code, stderr)
+	}
+	if code, stdout, stderr := runCmd("locate", "Service.Environment[1]", unit); code != 0 || !strings.Contains(stdout, unit+":3:13") {
+		t.Errorf("locate = %d, %q, %q", code, stdout, stderr)
+	}
+}
+
+func TestBlockConfig(t *testing.T) {
+	dir := t.TempDir()
+	old := writeFile(t, dir, "old.conf", "server {\n    listen 80;\n}\n")
+	changed := writeFile(t, dir, "new.conf", "server {\n    listen 8080;\n}\n")
+	caddy := writeFile(t, dir, "Caddyfile", "example.com {\n\trespond ok\n}\n")
+
+	if code, stdout, _ := runCmd("diff", old, changed); code != 1 || stdout != "~ server[0].block.listen[0].args[0]: \"80\" -> \"8080\"\n" {
+		t.Errorf("diff = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("set", "-string", `["example.com"][0].block.respond[0].args[0]`, "all good", caddy, "-w"); code != 0 {
+		t.Fatalf("set = %d, %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(caddy); string(b) != "example.com {\n\trespond \"all good\"\n}\n" {
+		t.Errorf("set -w wrote\n%s", b)
+	}
+}
+
+func TestProperties(t *testing.T) {
+	dir := t.TempDir()
+	props := writeFile(t, dir, "app.properties", "servers = a, \\\n    b\nport: 8080\n")
+
+	if code, stdout, stderr := runCmd("locate", "servers", props); code != 0 || !strings.Contains(stdout, props+":1:11") {
+		t.Errorf("locate = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := runCmd("set", "-string", "servers", "a, b, c", props, "-w"); code != 0 {
+		t.Fatalf("set = %d, %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(props); string(b) != "servers = a, b, c\nport: 8080\n" {
+		t.Errorf("set -w wrote\n%s", b)
+	}
+}
+
+func TestTable(t *testing.T) {
+	dir := t.TempDir()
+	zones := writeFile(t, dir, "zones.csv", "region,zone\neu,eu-west-1a\n")
+
+	if code, stdout, stderr := runCmd("locate", "[0].zone", zones); code != 0 || !strings.Contains(stdout, zones+":2:4") {
+		t.Errorf("locate = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, _, stderr := runCmd("set", "-string", "[0].zone", "eu-west-1a, eu-west-1b", zones, "-w"); code != 0 {
+		t.Fatalf("set = %d, %q", code, stderr)
+	}
+	if b, _ := os.ReadFile(zones); string(b) != "region,zone\neu,\"eu-west-1a, eu-west-1b\"\n" {
+		t.Errorf("set -w wrote\n%s", b)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.yaml", "servers:\n  - host: a\n    port: 80\ndb:\n  port: 5432\n")
+	prod := writeFile(t, dir, "prod.json", `{"db": {"port": 6432}}`)
+
+	code, stdout, stderr := runCmd("query", "$..port", base, prod)
+	want := "servers[0].port = 80  " + base + ":3:11 (" + base + ")\n" +
+		"db.port = 6432        " + prod + ":1:17 (" + prod + ")\n"
+	if code != 0 || stdout != want {
+		t.Errorf("query = %d, %q, %q\nwant %q", code, stdout, stderr, want)
+	}
+	if code, stdout, _ := runCmd("query", "-format", "json", "db.port", base, prod); code != 0 ||
+		stdout != `{"path":"db.port","value":6432,"layer":"`+prod+`","file":"`+prod+`","line":1,"col":17,"endLine":1,"endCol":21}`+"\n" {
+		t.Errorf("query -format json = %d, %q", code, stdout)
+	}
+	if code, _, stderr := runCmd("query", "$..timeout", base); code != 1 || !strings.Contains(stderr, "nothing matches $..timeout") {
+		t.Errorf("query without matches = %d, %q", code, stderr)
+	}
+	if code, _, _ := runCmd("query", "servers[?(@.port)]", base); code != 2 {
+		t.Errorf("query with a filter = %d, want 2", code)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "team-a.yaml", "db:\n  host: db\n  port: 5432\n")
+	agreed := writeFile(t, dir, "team-b.json", `{"db": {"port": 6432}, "@override": ["db.port"]}`)
+	clash := writeFile(t, dir, "team-c.json", `{"db": {"host": "replica"}}`)
+
+	if code, stdout, stderr := runCmd("conflicts", base, agreed); code != 0 || stdout != "" {
+		t.Errorf("conflicts with an override = %d, %q, %q", code, stdout, stderr)
+	}
+	code, stdout, _ := runCmd("conflicts", base, agreed, clash)
+	for _, want := range []string{
+		clash + `:1:17: error: db.host: "replica" from ` + clash + ` conflicts with "db" from ` + base + `, list host in @override to override it [TC4003 merge-conflict]`,
+		base + ":2:9: note: set by " + base,
+	} {
+		if code != 1 || !strings.Contains(stdout, want) {
+			t.Errorf("conflicts = %d, output missing %q:\n%s", code, want, stdout)
+		}
+	}
+}
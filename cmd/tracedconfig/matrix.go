@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runMatrix compares the configs of several environments as a matrix of key × environment, see
+// slowjson.Matrix. Keys set in some environments only and values that differ are listed, and the
+// environments disagreeing with the majority are named, e.g. the one with debug enabled. Paths matching
+// -expect, e.g. "db.host,**.replicas", are expected to differ. An environment is a file, named by its
+// base name, or name=file. It exits with 1 when a key is missing somewhere or differs unexpectedly.
+func runMatrix(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text or markdown")
+	all := fs.Bool("all", false, "list the keys every environment agrees on as well")
+	expect := fs.String("expect", "", "comma separated path `patterns` expected to differ, * matches within a key and ** across keys")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig matrix [-format text|markdown] [-all] [-expect pattern,...] [name=]file...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) < 2 || *format != "text" && *format != "markdown" {
+		fs.Usage()
+		return 2
+	}
+	var expected []*regexp.Regexp
+	for _, p := range strings.Split(*expect, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			expected = append(expected, globPath(p))
+		}
+	}
+	names := make([]string, len(rest))
+	docs := make([]*slowjson.Node, len(rest))
+	for i, arg := range rest {
+		name, file, ok := strings.Cut(arg, "=")
+		if !ok {
+			file = arg
+			name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig matrix: %v\n", err)
+			return 2
+		}
+		names[i], docs[i] = name, root
+	}
+
+	code := 0
+	table := [][]string{append(append([]string{"PATH"}, names...), "NOTE")}
+	var highlight [][]bool
+	for _, r := range slowjson.Matrix(docs...) {
+		path := r.Path.String()
+		note := ""
+		var outliers []int
+		switch {
+		case r.Missing():
+			var missing []string
+			for i, v := range r.Values {
+				if v == nil {
+					missing = append(missing, names[i])
+				}
+			}
+			note = "missing in " + strings.Join(missing, ", ")
+			code = 1
+		case !r.Differs():
+		case matchesAny(expected, path):
+			note = "expected to differ"
+		default:
+			outliers = r.Outliers()
+			note = "differs"
+			if len(outliers) > 0 {
+				var in []string
+				for _, i := range outliers {
+					in = append(in, names[i])
+				}
+				note = "differs in " + strings.Join(in, ", ")
+			}
+			code = 1
+		}
+		if note == "" && !*all {
+			continue
+		}
+		row := []string{path}
+		marks := make([]bool, len(names))
+		for _, v := range r.Values {
+			cell := "-"
+			if v != nil {
+				cell = summary(v)
+			}
+			row = append(row, cell)
+		}
+		for _, i := range outliers {
+			marks[i] = true
+		}
+		table = append(table, append(row, note))
+		highlight = append(highlight, marks)
+	}
+
+	if *format == "markdown" {
+		writeMarkdownMatrix(stdout, table, highlight)
+		return code
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, row := range table {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	return code
+}
+
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, p := range patterns {
+		if p.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMarkdownMatrix writes the table with the highlighted values of each row in bold.
+func writeMarkdownMatrix(w io.Writer, table [][]string, highlight [][]bool) {
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	fmt.Fprintf(w, "| %s |\n", strings.Join(table[0], " | "))
+	fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(table[0])))
+	for i, row := range table[1:] {
+		out := []string{"`" + row[0] + "`"}
+		for j, v := range row[1 : len(row)-1] {
+			v = cell(v)
+			if highlight[i][j] {
+				v = "**" + v + "**"
+			}
+			out = append(out, v)
+		}
+		out = append(out, row[len(row)-1])
+		fmt.Fprintf(w, "| %s |\n", strings.Join(out, " | "))
+	}
+}
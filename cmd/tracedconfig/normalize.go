@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runNormalize rewrites JSON config files in a canonical style: keys sorted, numbers in their shortest
+// form and arrays collapsed or expanded, keeping comments. The style comes from the normalize section of
+// the CLI config, or one of its profiles with -profile, and flags override it, so a config repo converges
+// on a single style. Without -l or -w the normalized files are printed. With -l it lists the files not in
+// the style and exits with 1 when there are any, for CI; with -w it rewrites them. Other formats are
+// skipped.
+func runNormalize(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	config := fs.String("config", "", "CLI config `file`, default "+defaultConfigName+" when it exists")
+	profile := fs.String("profile", "", "style profile of the CLI config to use instead of its default style")
+	sortKeys := fs.Bool("sort-keys", false, "sort object members by key")
+	numbers := fs.Bool("normalize-numbers", false, "write numbers in their shortest form, e.g. 1.50 as 1.5")
+	arrays := fs.String("arrays", "", "array `layout`: auto, expand or collapse (default auto)")
+	indent := fs.Int("indent", 0, "spaces per nesting level, 0 indents with tabs (default 2)")
+	maxWidth := fs.Int("max-width", 0, "line width auto collapses arrays of scalars within (default 80)")
+	list := fs.Bool("l", false, "list the files not in the style instead of printing them")
+	write := fs.Bool("w", false, "write the normalized files instead of printing them")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig normalize [-config file] [-profile name] [-sort-keys] [-normalize-numbers] [-arrays auto|expand|collapse] [-indent n] [-max-width n] [-l] [-w] file|dir|dir/...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || !validArrays(*arrays) {
+		fs.Usage()
+		return 2
+	}
+	cfg, err := loadConfig(*config)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig normalize: %v\n", err)
+		return 2
+	}
+	sc := cfg.Normalize.styleConfig
+	if *profile != "" {
+		p, ok := cfg.Normalize.Profiles[*profile]
+		if !ok {
+			fmt.Fprintf(stderr, "tracedconfig normalize: unknown profile %q\n", *profile)
+			return 2
+		}
+		sc = p
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "sort-keys":
+			sc.SortKeys = *sortKeys
+		case "normalize-numbers":
+			sc.NormalizeNumbers = *numbers
+		case "arrays":
+			sc.Arrays = *arrays
+		case "indent":
+			sc.Indent, sc.Tabs = *indent, *indent == 0
+		case "max-width":
+			sc.MaxWidth = *maxWidth
+		}
+	})
+	style := sc.style()
+	files, err := expandFiles(rest)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig normalize: %v\n", err)
+		return 2
+	}
+
+	differ := false
+	var writes []fileWrite
+	for _, file := range files {
+		if fileFormat(file) != formatJSON {
+			fmt.Fprintf(stderr, "tracedconfig normalize: skipping %s, only JSON files are normalized\n", file)
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig normalize: %v\n", err)
+			return 2
+		}
+		root, err := parseData(file, data)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig normalize: %v\n", err)
+			return 2
+		}
+		out := style.Format(root)
+		if !*list && !*write {
+			fmt.Fprint(stdout, out)
+			continue
+		}
+		if out == string(data) {
+			continue
+		}
+		differ = true
+		if *list {
+			fmt.Fprintln(stdout, file)
+		}
+		if *write {
+			writes = append(writes, fileWrite{path: file, data: []byte(out)})
+		}
+	}
+	if err := writeKeepingMode(writes...); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig normalize: %v\n", err)
+		return 2
+	}
+	if differ && *list && !*write {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// queryResult is the JSON output of query.
+type queryResult struct {
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+	// Layer is the file setting the value.
+	Layer   string `json:"layer"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	EndLine int    `json:"endLine"`
+	EndCol  int    `json:"endCol"`
+}
+
+// runQuery prints the values matching a JSONPath expression after merging the files as layers, each with
+// the layer and position that set it, see tracedconfig.Config.Query. The json format is one object per
+// line. It exits with 1 when nothing matches, like grep.
+func runQuery(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig query [-format text|json] expr layer...")
+		fmt.Fprintln(stderr, "expr is JSONPath, e.g. $.servers[*].port or $..timeout, layers are merged in order")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) < 2 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+	var layers []merge.Layer
+	for _, file := range rest[1:] {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig query: %v\n", err)
+			return 2
+		}
+		layers = append(layers, merge.Layer{Name: file, Root: root})
+	}
+	res, err := merge.Merge(layers...)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig query: %v\n", err)
+		return 2
+	}
+	matches, err := res.Root.Query(rest[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig query: %v\n", err)
+		return 2
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(stderr, "tracedconfig query: nothing matches %s\n", rest[0])
+		return 1
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	enc := json.NewEncoder(stdout)
+	for _, m := range matches {
+		value, err := slowjson.Marshal(m.Node)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig query: %v\n", err)
+			return 2
+		}
+		path := m.Path.String()
+		if path == "" {
+			path = "$"
+		}
+		o, _ := res.Origin(m.Path.String())
+		if *format == "text" {
+			fmt.Fprintf(tw, "%s = %s\t%s (%s)\n", path, value, m.Node.Location(), o.Layer)
+			continue
+		}
+		enc.Encode(queryResult{Path: path, Value: value, Layer: o.Layer, File: m.Node.File,
+			Line: m.Node.StartLine, Col: m.Node.StartCol, EndLine: m.Node.EndLine, EndCol: m.Node.EndCol})
+	}
+	tw.Flush()
+	return 0
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/lint"
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runRefs checks that every ${path} interpolation resolves after merging the files as layers, in order,
+// and that every YAML alias names an anchor, see lint.DeadReferences. Findings show the reference and
+// the keys set near the missing one. It exits with 1 when a reference is dead.
+func runRefs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("refs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output `format`: text or github")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig refs [-format text|github] layer...")
+		fmt.Fprintln(stderr, "layers are merged in order, later ones overriding earlier ones")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || *format != "text" && *format != "github" {
+		fs.Usage()
+		return 2
+	}
+	var layers []merge.Layer
+	var diags []diag.Diagnostic
+	for _, file := range rest {
+		root, err := parseFile(file)
+		var perr *slowjson.ParseError
+		if err != nil && !errors.As(err, &perr) {
+			fmt.Fprintf(stderr, "tracedconfig refs: %v\n", err)
+			return 2
+		}
+		if err != nil {
+			for _, d := range diag.FromError(err) {
+				// an alias to an unknown anchor fails parsing
+				if strings.HasPrefix(d.Message, "unknown anchor") {
+					d.Code = diag.CodeDeadReference
+				}
+				diags = append(diags, d)
+			}
+			continue
+		}
+		layers = append(layers, merge.Layer{Name: file, Root: root})
+	}
+	if len(diags) == 0 {
+		res, err := merge.Merge(layers...)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig refs: %v\n", err)
+			return 2
+		}
+		diags = lint.NewRunner(lint.DeadReferences()).Run(res.Root)
+	}
+	if *format == "github" {
+		if err := diag.WriteGitHub(stdout, diags); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig refs: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprint(stdout, d.Render(1, 1))
+		}
+	}
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/tfvars"
+)
+
+// runRename renames or moves a key in config files for schema evolution across many files, e.g.
+// "rename db.timeout database.timeout ./configs/...", and prints a diff of the changes. Only the text of
+// the key is rewritten, so formatting and comments are kept. Renaming within an object works in every
+// format, moving a key to another object only in JSON files. With -alias the old key stays, marked
+// deprecated, next to the new one so programs reading either keep working during a migration. Files
+// are only written with -w. It exits with 1 when no file has the key and 2 on errors.
+func runRename(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "write the changes to the files instead of only printing the diff")
+	alias := fs.Bool("alias", false, "keep the old key as a deprecated alias of the new one")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig rename [-w] [-alias] old.path new.path file|dir|dir/...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) < 3 {
+		fs.Usage()
+		return 2
+	}
+	r := renamer{alias: *alias}
+	for i, s := range rest[:2] {
+		p, err := slowjson.ParsePath(s)
+		if err == nil && (len(p) == 0 || p[len(p)-1].IsIndex) {
+			err = fmt.Errorf("%s does not name a key", s)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig rename: %v\n", err)
+			return 2
+		}
+		r.paths[i] = p
+	}
+	if r.paths[1].HasPrefix(r.paths[0]) {
+		fmt.Fprintf(stderr, "tracedconfig rename: cannot move %s below itself\n", r.paths[0])
+		return 2
+	}
+	files, err := expandFiles(rest[2:])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig rename: %v\n", err)
+		return 2
+	}
+
+	found := false
+	var writes []fileWrite
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig rename: %v\n", err)
+			return 2
+		}
+		edits, err := r.rename(file, data)
+		if err == errNoKey {
+			continue
+		}
+		found = true
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig rename: %s: %v\n", file, err)
+			return 2
+		}
+		writeDiff(stdout, file, string(data), edits)
+		if *write {
+			writes = append(writes, fileWrite{path: file, data: []byte(applyEdits(string(data), 0, edits))})
+		}
+	}
+	if err := writeKeepingMode(writes...); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig rename: %v\n", err)
+		return 2
+	}
+	if !found {
+		fmt.Fprintf(stderr, "tracedconfig rename: no file sets %s\n", r.paths[0])
+		return 1
+	}
+	return 0
+}
+
+var errNoKey = fmt.Errorf("no such key")
+
+type renamer struct {
+	// paths are the old and the new path.
+	paths [2]slowjson.Path
+	alias bool
+}
+
+// rename returns the edits renaming the key in the config file, errNoKey when the file does not set it.
+// The edited file is parsed again and compared with the expected tree, so an edit the format does not
+// allow, e.g. of a key repeated in TOML table headers, is an error rather than a broken file.
+func (r renamer) rename(file string, data []byte) ([]edit, error) {
+	root, err := parseData(file, data)
+	if err != nil {
+		return nil, err
+	}
+	oldPath, newPath := r.paths[0], r.paths[1]
+	val := root.Lookup(oldPath)
+	if val == nil {
+		return nil, errNoKey
+	}
+	if root.Lookup(newPath) != nil {
+		return nil, fmt.Errorf("%s is already set at %s", newPath, root.Lookup(newPath).Location())
+	}
+	key := val.Parent
+	src := string(data)
+	format := fileFormat(file)
+	name := newPath[len(newPath)-1].Key
+
+	var edits []edit
+	if r.alias {
+		edits = append(edits, deprecate(src, key, format, newPath)...)
+	}
+	switch {
+	case newPath[:len(newPath)-1].Equal(oldPath[:len(oldPath)-1]) && !r.alias:
+		text, err := keyText(name, format)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit{start: key.StartOffset, end: key.EndOffset, text: text})
+	case newPath[:len(newPath)-1].Equal(oldPath[:len(oldPath)-1]):
+		// the new member follows the old one
+		text, err := keyText(name, format)
+		if err != nil {
+			return nil, err
+		}
+		text += src[key.EndOffset:val.EndOffset]
+		edits = append(edits, edit{start: val.EndOffset, end: val.EndOffset, text: memberSeparator(src, key) + text})
+	case format != formatJSON:
+		return nil, fmt.Errorf("moving %s to another object is only supported in JSON files", oldPath)
+	default:
+		if !r.alias {
+			edits = append(edits, removeMember(src, key)...)
+		}
+		e, err := insertMember(src, root, newPath, key, val)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, *e)
+	}
+	edits = sortEdits(edits)
+
+	// check the result against the rename applied to the tree
+	want, _ := parseData(file, data)
+	moved := want.Lookup(oldPath)
+	movedKey, parent := moved.Parent, moved.Parent.Parent
+	switch {
+	case newPath[:len(newPath)-1].Equal(oldPath[:len(oldPath)-1]) && !r.alias:
+		movedKey.Value = name
+	case newPath[:len(newPath)-1].Equal(oldPath[:len(oldPath)-1]):
+		parent.Children = append(parent.Children, &slowjson.Node{Type: slowjson.NodeString, Value: name, Children: []*slowjson.Node{moved}})
+	default:
+		if !r.alias {
+			for i, k := range parent.Children {
+				if k == movedKey {
+					parent.Children = append(parent.Children[:i:i], parent.Children[i+1:]...)
+					break
+				}
+			}
+		}
+		if err := want.Set(newPath, moved); err != nil {
+			return nil, err
+		}
+	}
+	got, err := parseData(file, []byte(applyEdits(src, 0, edits)))
+	if err == nil && len(slowjson.DiffOptions{IgnoreOrder: true}.Diff(want, got)) > 0 {
+		err = fmt.Errorf("the edited file reads differently")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot rename %s in place: %v", oldPath, err)
+	}
+	return edits, nil
+}
+
+// keyText formats name as a key of format.
+func keyText(name, format string) (string, error) {
+	if format == formatTOML && isTOMLBareKey(name) || format == formatTFVars && tfvars.IsIdentifier(name) {
+		return name, nil
+	}
+	return render(&slowjson.Node{Type: slowjson.NodeString, Value: name}, format)
+}
+
+// lineIndent returns the indentation of the node when it starts its line, ok is false when other
+// text precedes it. A YAML sequence entry "- " counts as indentation.
+func lineIndent(src string, n *slowjson.Node) (indent string, ok bool) {
+	prefix := src[strings.LastIndexByte(src[:n.StartOffset], '\n')+1 : n.StartOffset]
+	if strings.Trim(prefix, " \t-") != "" {
+		return "", false
+	}
+	return strings.Repeat(" ", n.StartCol-1), true
+}
+
+// isFlow reports whether the members of the object of key are separated by commas.
+func isFlow(src string, key *slowjson.Node) bool {
+	obj := key.Parent
+	return obj != nil && obj.StartOffset < len(src) && src[obj.StartOffset] == '{'
+}
+
+// memberSeparator returns the text between the value of key and a member inserted after it.
+func memberSeparator(src string, key *slowjson.Node) string {
+	indent, own := lineIndent(src, key)
+	switch {
+	case own && isFlow(src, key):
+		return ",\n" + indent
+	case own:
+		return "\n" + indent
+	default:
+		return ", "
+	}
+}
+
+// deprecate returns the edit marking key as a deprecated alias of newPath with a comment, none when the
+// format has no comment that fits before the key.
+func deprecate(src string, key *slowjson.Node, format string, newPath slowjson.Path) []edit {
+	note := "Deprecated: renamed to " + newPath.String()
+	marker := "# "
+	if format == formatJSON {
+		marker = "// "
+	}
+	if indent, own := lineIndent(src, key); own {
+		return []edit{{start: key.StartOffset, end: key.StartOffset, text: marker + note + "\n" + indent}}
+	}
+	if format == formatJSON {
+		return []edit{{start: key.StartOffset, end: key.StartOffset, text: "/* " + note + " */ "}}
+	}
+	// YAML and TOML comments run to the end of the line
+	return nil
+}
+
+// removeMember returns the edits removing the member key from its JSON object. A member on lines of
+// its own is removed with those lines, including a comment after its value.
+func removeMember(src string, key *slowjson.Node) []edit {
+	obj := key.Parent
+	val := key.Children[0]
+	next, prev := key.NextSibling(), key.PrevSibling()
+	_, own := lineIndent(src, key)
+	lineStart := strings.LastIndexByte(src[:key.StartOffset], '\n') + 1
+	lineEnd := len(src)
+	if i := strings.IndexByte(src[val.EndOffset:], '\n'); i >= 0 {
+		lineEnd = val.EndOffset + i
+	}
+	switch {
+	case next != nil && own && lineEnd < next.StartOffset:
+		return []edit{{start: lineStart, end: lineEnd + 1}}
+	case next != nil:
+		return []edit{{start: key.StartOffset, end: next.StartOffset}}
+	case prev != nil && own:
+		// drop the comma after the previous value and the lines of the member
+		comma := commaAfter(src, prev.Children[0].EndOffset)
+		return []edit{{start: comma, end: comma + 1}, {start: lineStart - 1, end: lineEnd}}
+	case prev != nil:
+		return []edit{{start: prev.Children[0].EndOffset, end: val.EndOffset}}
+	default:
+		return []edit{{start: obj.StartOffset + 1, end: obj.EndOffset - 1}}
+	}
+}
+
+// commaAfter returns the offset of the comma following a JSON value at off, skipping comments.
+func commaAfter(src string, off int) int {
+	for off < len(src) {
+		switch {
+		case strings.HasPrefix(src[off:], "//"), strings.HasPrefix(src[off:], "/*"):
+			end := "\n"
+			if src[off+1] == '*' {
+				end = "*/"
+			}
+			i := strings.Index(src[off+2:], end)
+			if i < 0 {
+				return len(src)
+			}
+			off += 2 + i + len(end)
+		case src[off] == ',':
+			return off
+		default:
+			off++
+		}
+	}
+	return off
+}
+
+// insertMember returns the edit adding the member key with the value val at path of the JSON root,
+// creating missing objects.
+func insertMember(src string, root *slowjson.Node, path slowjson.Path, key, val *slowjson.Node) (*edit, error) {
+	// find the deepest existing object on the path
+	target, depth := root, 0
+	for depth < len(path)-1 {
+		n := target.Lookup(path[depth : depth+1])
+		if n == nil {
+			break
+		}
+		target, depth = n, depth+1
+	}
+	if target.Type != slowjson.NodeObject {
+		return nil, fmt.Errorf("cannot move into %s, it is not an object", path[:depth])
+	}
+	var indent string
+	sep := ""
+	at := target.StartOffset + 1
+	if n := len(target.Children); n > 0 {
+		last := target.Children[n-1]
+		at = last.Children[0].EndOffset
+		sep = memberSeparator(src, last)
+		indent, _ = lineIndent(src, last)
+	}
+	// keep the layout of the value relative to its key
+	oldIndent, _ := lineIndent(src, key)
+	text := src[val.StartOffset:val.EndOffset]
+	if indent != oldIndent {
+		lines := strings.Split(text, "\n")
+		for i := 1; i < len(lines); i++ {
+			lines[i] = indent + strings.TrimPrefix(lines[i], oldIndent)
+		}
+		text = strings.Join(lines, "\n")
+	}
+	for i := len(path) - 1; i >= depth; i-- {
+		name, _ := keyText(path[i].Key, formatJSON)
+		text = name + ": " + text
+		if i > depth {
+			text = "{" + text + "}"
+		}
+	}
+	return &edit{start: at, end: at, text: sep + text}, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/lint"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runRepeats reports values repeated across config files, e.g. the same URL pasted into every service,
+// with the location of every occurrence and a suggestion to define it once, see lint.RepeatedValues.
+// -format github prints GitHub Actions annotations instead. It exits with 1 when a value is repeated.
+func runRepeats(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repeats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	minCount := fs.Int("min-count", 0, "report values occurring at least `n` times (default 3)")
+	minLength := fs.Int("min-length", 0, "report values of at least `n` characters (default 8)")
+	format := fs.String("format", "text", "output `format`: text or github")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig repeats [-min-count n] [-min-length n] [-format text|github] file|dir|dir/...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) == 0 || *format != "text" && *format != "github" {
+		fs.Usage()
+		return 2
+	}
+	files, err := expandFiles(rest)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig repeats: %v\n", err)
+		return 2
+	}
+	var roots []*slowjson.Node
+	for _, file := range files {
+		root, err := parseFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig repeats: %v\n", err)
+			return 2
+		}
+		roots = append(roots, root)
+	}
+	repeated := lint.RepeatedValues(roots, lint.RepeatedOptions{MinCount: *minCount, MinLength: *minLength})
+	var diags []diag.Diagnostic
+	for _, r := range repeated {
+		diags = append(diags, r.Diagnostic())
+	}
+	if *format == "github" {
+		if err := diag.WriteGitHub(stdout, diags); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig repeats: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(stdout, d)
+			for _, r := range d.Related {
+				fmt.Fprintf(stdout, "  %s: %s\n", r.Span, r.Message)
+			}
+		}
+	}
+	if len(repeated) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// runRollback asks a running process to restore an earlier config through its debug endpoint, see
+// debug.Handler, and prints the changes the rollback made.
+func runRollback(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 1, "number of applied snapshots to go back")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig rollback [-n 1] debug-url")
+		fmt.Fprintln(stderr, "debug-url is where debug.Handler is mounted, e.g. http://localhost:6060/debug/config")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *n < 1 {
+		fs.Usage()
+		return 2
+	}
+	u, err := url.Parse(strings.TrimSuffix(fs.Arg(0), "/") + "/rollback")
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig rollback: %v\n", err)
+		return 2
+	}
+	u.RawQuery = url.Values{"n": {strconv.Itoa(*n)}}.Encode()
+	resp, err := http.Post(u.String(), "text/plain", nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig rollback: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig rollback: %v\n", err)
+		return 1
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(stderr, "tracedconfig rollback: %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		return 1
+	}
+	stdout.Write(body)
+	return 0
+}
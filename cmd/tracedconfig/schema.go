@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/at15/tracedconfig"
+)
+
+// runSchema prints the schema of a struct read from Go source, for editors that validate config files.
+func runSchema(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeName := fs.String("type", "", "name of the config struct `type`")
+	id := fs.String("id", "", "$id of the generated schema")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig schema -type Config [package dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *typeName == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	s, err := tracedconfig.GenerateSchemaFromSource(dir, *typeName)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig schema: %v\n", err)
+		return 1
+	}
+	s.ID = *id
+	b, err := s.MarshalIndent()
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig schema: %v\n", err)
+		return 1
+	}
+	stdout.Write(b)
+	return 0
+}
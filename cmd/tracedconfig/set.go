@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// runSet changes the values matching a path pattern in config files, e.g. every servers[*].timeout,
+// and prints a diff of the changes. Only the text of the values is replaced, so formatting and comments
+// are kept. The value is read as JSON, anything that is not JSON as a string, and written in the style
+// of each file. Files are only written with -w. It exits with 1 when no value matches and 2 on errors.
+func runSet(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "write the changes to the files instead of only printing the diff")
+	asString := fs.Bool("string", false, "set the value as a string even if it is valid JSON, e.g. \"8080\"")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig set [-w] [-string] path value file|dir|dir/...")
+		fmt.Fprintln(stderr, "path is e.g. $.servers[*].timeout, * matches within a key and ** across keys")
+		fs.PrintDefaults()
+	}
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(rest) < 3 {
+		fs.Usage()
+		return 2
+	}
+	pattern := globPath(rest[0])
+	value := &slowjson.Node{Type: slowjson.NodeString, Value: rest[1]}
+	if !*asString {
+		value = slowjson.ParseValueOrString(rest[1], "")
+	}
+	files, err := expandFiles(rest[2:])
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig set: %v\n", err)
+		return 2
+	}
+
+	matched := false
+	var writes []fileWrite
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig set: %v\n", err)
+			return 2
+		}
+		root, err := parseData(file, data)
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig set: %v\n", err)
+			return 2
+		}
+		text, err := render(value, fileFormat(file))
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig set: %s: %v\n", file, err)
+			return 2
+		}
+		var edits []edit
+		walkValues(root, nil, func(path slowjson.Path, n *slowjson.Node) {
+			if len(path) == 0 || !pattern.MatchString(path.String()) {
+				return
+			}
+			matched = true
+			if len(slowjson.Diff(n, value)) > 0 {
+				edits = append(edits, edit{start: n.StartOffset, end: n.EndOffset, text: text})
+			}
+		})
+		edits = sortEdits(edits)
+		writeDiff(stdout, file, string(data), edits)
+		if !*write || len(edits) == 0 {
+			continue
+		}
+		out := applyEdits(string(data), 0, edits)
+		if _, err := parseData(file, []byte(out)); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig set: %s would not parse after the change: %v\n", file, err)
+			return 2
+		}
+		writes = append(writes, fileWrite{path: file, data: []byte(out)})
+	}
+	if err := writeKeepingMode(writes...); err != nil {
+		fmt.Fprintf(stderr, "tracedconfig set: %v\n", err)
+		return 2
+	}
+	if !matched {
+		fmt.Fprintf(stderr, "tracedconfig set: no value matches %s\n", rest[0])
+		return 1
+	}
+	return 0
+}
+
+// fileWrite is the new content of an existing file.
+type fileWrite struct {
+	path string
+	data []byte
+}
+
+// writeKeepingMode replaces the content of existing files, keeping their permissions. Each file is first
+// written to a temporary file in its directory, and the temporary files only replace the originals once
+// all of them are written, so an error never leaves a file half written or only some files changed.
+func writeKeepingMode(writes ...fileWrite) error {
+	tmps := make([]string, 0, len(writes))
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+	targets := make([]string, 0, len(writes))
+	for _, w := range writes {
+		// replace the file a symlink points to rather than the symlink
+		target, err := filepath.EvalSymlinks(w.path)
+		if err != nil {
+			return err
+		}
+		tmp, err := writeTemp(target, w.data)
+		if err != nil {
+			return err
+		}
+		tmps = append(tmps, tmp)
+		targets = append(targets, target)
+	}
+	for i, target := range targets {
+		if err := os.Rename(tmps[i], target); err != nil {
+			return err
+		}
+	}
+	tmps = nil
+	return nil
+}
+
+// writeTemp writes data to a new temporary file next to path with the permissions of path and returns
+// its name.
+func writeTemp(path string, data []byte) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/at15/tracedconfig/signing"
+)
+
+// runSign signs config files with an ed25519 key, embedding the signature or writing it next to the file.
+func runSign(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key-file", "", "`file` holding the base64 encoded private key")
+	signer := fs.String("signer", "", "`name` of the signer, recorded in provenance")
+	detached := fs.Bool("detached", false, "write the signature to <file>.sig instead of embedding it")
+	genKey := fs.Bool("genkey", false, "print a new private key and exit")
+	public := fs.Bool("public", false, "print the public key of the private key for keyrings and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig sign -key-file key -signer name [-detached] file...")
+		fmt.Fprintln(stderr, "       tracedconfig sign -key-file key -public")
+		fmt.Fprintln(stderr, "       tracedconfig sign -genkey")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *genKey {
+		priv, _, err := signing.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig sign: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, priv)
+		return 0
+	}
+	if *keyFile == "" || !*public && (*signer == "" || fs.NArg() == 0) {
+		fs.Usage()
+		return 2
+	}
+	b, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig sign: %v\n", err)
+		return 1
+	}
+	key, err := signing.ParsePrivateKey(string(b))
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig sign: %v\n", err)
+		return 1
+	}
+	if *public {
+		fmt.Fprintln(stdout, signing.PublicKey(key))
+		return 0
+	}
+	for _, path := range fs.Args() {
+		sigPath := ""
+		if *detached {
+			sigPath = path + ".sig"
+		}
+		if err := signing.SignFile(path, sigPath, *signer, key); err != nil {
+			fmt.Fprintf(stderr, "tracedconfig sign: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/at15/tracedconfig/manifest"
+)
+
+// runVerify checks a config directory against its lockfile and prints every drifted file.
+// It exits with 1 on drift, -update rewrites the lockfile from the directory instead.
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	lock := fs.String("lock", "", "lockfile `path`, default "+manifest.DefaultName+" in the directory")
+	update := fs.Bool("update", false, "write the lockfile for the current directory contents")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tracedconfig verify [-lock file] [-update] dir")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := fs.Arg(0)
+	lockPath := *lock
+	if lockPath == "" {
+		lockPath = filepath.Join(dir, manifest.DefaultName)
+	}
+	if *update {
+		m, err := manifest.Generate(dir)
+		if err == nil {
+			err = m.Write(lockPath)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "tracedconfig verify: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "locked %d files in %s\n", len(m.Entries), lockPath)
+		return 0
+	}
+	drift, err := manifest.VerifyWith(dir, lockPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "tracedconfig verify: %v\n", err)
+		return 1
+	}
+	for _, d := range drift {
+		fmt.Fprintln(stdout, d)
+	}
+	if len(drift) > 0 {
+		fmt.Fprintf(stderr, "tracedconfig verify: %d files differ from %s\n", len(drift), lockPath)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,230 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// ParseEnv parses a .env file or the env_file of a service into an object of strings: NAME=value lines,
+// optionally prefixed with export, and # comments. A value is unquoted, ending at a # after a space and
+// trimmed, single-quoted and taken literally, or double-quoted with \n, \r, \t, \" and \\ escapes.
+// Quoted values may span lines. A NAME without = has a null value, it is taken from the environment
+// where the file is used. ${VAR} references are kept as written, see Load.
+func ParseEnv(data []byte, file string) (*slowjson.Node, error) {
+	p := &envParser{src: string(data), file: file, line: 1, col: 1}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root := p.span(slowjson.NodeObject, p.mark(), p.mark())
+	for {
+		p.skipSpaces()
+		switch p.peek() {
+		case 0:
+			p.setEnd(root)
+			return root, nil
+		case '\n', '\r':
+			p.next()
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+		if rest := p.src[p.off:]; strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+			p.advance(len("export"))
+			p.skipSpaces()
+		}
+		key, err := p.entry()
+		if err != nil {
+			return nil, err
+		}
+		key.Parent = root
+		root.Children = append(root.Children, key)
+	}
+}
+
+type envParser struct {
+	src  string
+	file string
+
+	off, line, col int
+}
+
+type envMark struct {
+	off, line, col int
+}
+
+func (p *envParser) mark() envMark {
+	return envMark{p.off, p.line, p.col}
+}
+
+func (p *envParser) errorf(m envMark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *envParser) span(typ slowjson.NodeType, start, end envMark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *envParser) setEnd(n *slowjson.Node) {
+	n.EndLine, n.EndCol, n.EndOffset = p.line, p.col, p.off
+}
+
+func (p *envParser) peek() byte {
+	if p.off < len(p.src) {
+		return p.src[p.off]
+	}
+	return 0
+}
+
+// next consumes a rune.
+func (p *envParser) next() {
+	if p.off >= len(p.src) {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+func (p *envParser) advance(n int) {
+	for end := p.off + n; p.off < end; {
+		p.next()
+	}
+}
+
+func (p *envParser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.next()
+	}
+}
+
+func (p *envParser) skipLine() {
+	for p.peek() != 0 && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// endOfLine consumes spaces and a comment up to the end of the line after a quoted value.
+func (p *envParser) endOfLine() error {
+	p.skipSpaces()
+	switch p.peek() {
+	case '#':
+		p.skipLine()
+	case 0, '\n', '\r':
+	default:
+		return p.errorf(p.mark(), "unexpected text after the quoted value")
+	}
+	return nil
+}
+
+// entry parses a NAME=value line.
+func (p *envParser) entry() (*slowjson.Node, error) {
+	start := p.mark()
+	for c := p.peek(); c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'; c = p.peek() {
+		p.next()
+	}
+	if p.off == start.off {
+		return nil, p.errorf(start, "expected a variable name")
+	}
+	key := p.span(slowjson.NodeString, start, p.mark())
+	key.Value = p.src[start.off:p.off]
+	p.skipSpaces()
+	if c := p.peek(); c == 0 || c == '\n' || c == '\r' || c == '#' {
+		v := p.span(slowjson.NodeNull, start, start)
+		v.Value = "null"
+		v.Parent = key
+		key.Children = []*slowjson.Node{v}
+		p.skipLine()
+		return key, nil
+	}
+	if p.peek() != '=' {
+		return nil, p.errorf(p.mark(), "expected = after %s", key.Value)
+	}
+	p.next()
+	p.skipSpaces()
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	v.Parent = key
+	key.Children = []*slowjson.Node{v}
+	return key, nil
+}
+
+func (p *envParser) value() (*slowjson.Node, error) {
+	start := p.mark()
+	switch quote := p.peek(); quote {
+	case '\'', '"':
+		p.next()
+		var b strings.Builder
+		for p.peek() != quote {
+			c := p.peek()
+			if c == 0 {
+				return nil, p.errorf(start, "unterminated quoted value")
+			}
+			if c == '\\' && quote == '"' {
+				p.next()
+				switch e := p.peek(); e {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\':
+					b.WriteByte(e)
+				default:
+					b.WriteByte('\\')
+					continue
+				}
+				p.next()
+				continue
+			}
+			r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+			b.WriteRune(r)
+			p.next()
+		}
+		p.next()
+		v := p.span(slowjson.NodeString, start, p.mark())
+		v.Value = b.String()
+		return v, p.endOfLine()
+	}
+	end := p.off
+	for c := p.peek(); c != 0 && c != '\n'; c = p.peek() {
+		if c == '#' && (p.off == start.off || p.src[p.off-1] == ' ' || p.src[p.off-1] == '\t') {
+			break
+		}
+		p.next()
+		if c != ' ' && c != '\t' && c != '\r' {
+			end = p.off
+		}
+	}
+	v := p.span(slowjson.NodeString, start, start)
+	v.Value = p.src[start.off:end]
+	v.EndOffset = end
+	v.EndLine = start.line
+	v.EndCol = start.col + utf8.RuneCountInString(v.Value)
+	p.skipLine()
+	return v, nil
+}
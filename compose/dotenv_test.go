@@ -0,0 +1,67 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestParseEnv(t *testing.T) {
+	env, err := ParseEnv([]byte(`# database
+DB_HOST=db.internal   # primary
+export DB_PORT = 5432
+PASSWORD='p@ss#word $HOME'
+GREETING="hello\n\"world\"" # quoted
+MULTI="a
+b"
+EMPTY=
+URL=http://example.com/#anchor
+HOME_DIR
+`), ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := slowjson.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"DB_HOST":"db.internal","DB_PORT":"5432","PASSWORD":"p@ss#word $HOME","GREETING":"hello\n\"world\"","MULTI":"a\nb","EMPTY":"","URL":"http://example.com/#anchor","HOME_DIR":null}`
+	if string(b) != want {
+		t.Errorf("ParseEnv() = %s\nwant %s", b, want)
+	}
+	for path, loc := range map[string]string{"DB_HOST": ".env:2:9", "DB_PORT": ".env:3:18", "MULTI": ".env:6:7", "EMPTY": ".env:8:7"} {
+		if got := env.Get(path).Location(); got != loc {
+			t.Errorf("%s at %s, want %s", path, got, loc)
+		}
+	}
+	if v := env.Get("DB_HOST"); v.EndCol != 20 {
+		t.Errorf("DB_HOST ends at col %d", v.EndCol)
+	}
+
+	for _, tt := range []struct{ input, want string }{
+		{"=1", "expected a variable name at x.env:1:1"},
+		{"A 1", "expected = after A at x.env:1:3"},
+		{"A='1", "unterminated quoted value at x.env:1:3"},
+		{`A="1" 2`, "unexpected text after the quoted value at x.env:1:7"},
+	} {
+		if _, err := ParseEnv([]byte(tt.input), "x.env"); err == nil || err.Error() != tt.want {
+			t.Errorf("ParseEnv(%q) error = %v, want %s", tt.input, err, tt.want)
+		}
+	}
+	if env, err := ParseEnv([]byte("\uFEFFA=1\r\nB=2\r\n"), ""); err != nil || !strings.Contains(string(must(t, env)), `"A":"1","B":"2"`) {
+		t.Errorf("ParseEnv() with a BOM and CRLF = %s, %v", must(t, env), err)
+	}
+}
+
+func must(t *testing.T, n *slowjson.Node) []byte {
+	t.Helper()
+	if n == nil {
+		return nil
+	}
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,201 @@
+package compose
+
+import (
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Substitution is a variable expanded into a value.
+type Substitution struct {
+	// Node is the string value the variable was expanded into, in the tree of its file.
+	Node *slowjson.Node
+	// Text is the expression as written, e.g. "${TAG:-latest}".
+	Text string
+	// Name is the variable, e.g. "TAG".
+	Name string
+	// From is where the variable is set: an entry of the .env file, or a node with the File Environ
+	// for the process environment. It is nil when the variable is unset.
+	From *slowjson.Node
+}
+
+// Environ is the File of the nodes of variables from the process environment.
+const Environ = "environment"
+
+// interpolator expands ${VAR} expressions like docker compose.
+type interpolator struct {
+	// vars are the variables by name, the process environment overriding the .env file.
+	vars  map[string]*slowjson.Node
+	subs  []Substitution
+	diags []diag.Diagnostic
+}
+
+// walk expands the string values below n in place.
+func (in *interpolator) walk(n *slowjson.Node) error {
+	if n.Type == slowjson.NodeString && !n.IsKey() && strings.Contains(n.Value, "$") {
+		v, err := in.expand(n.Value, n)
+		if err != nil {
+			return err
+		}
+		n.Value = v
+	}
+	for _, c := range n.Children {
+		if err := in.walk(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand returns s with its variables expanded, $$ stands for a literal $. n is the value s is part of.
+// Unset variables without a default expand to nothing with a warning. ${VAR:?message} and ${VAR?message}
+// fail when VAR is unset or, with the colon, empty.
+func (in *interpolator) expand(s string, n *slowjson.Node) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; {
+		case c == '$':
+			b.WriteByte('$')
+			i++
+		case c == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", n.Errorf("invalid interpolation format for %q: unterminated ${", s)
+			}
+			v, err := in.braced(s[i:end+1], n)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i = end
+		case isNameStart(c):
+			j := i + 2
+			for j < len(s) && isName(s[j]) {
+				j++
+			}
+			b.WriteString(in.lookup(s[i:j], s[i+1:j], n))
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// braced expands a ${...} expression.
+func (in *interpolator) braced(text string, n *slowjson.Node) (string, error) {
+	inner := text[2 : len(text)-1]
+	j := 0
+	for j < len(inner) && isName(inner[j]) {
+		j++
+	}
+	name, rest := inner[:j], inner[j:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", n.Errorf("invalid interpolation format for %q: invalid variable name in %s", n.Value, text)
+	}
+	if rest == "" {
+		return in.lookup(text, name, n), nil
+	}
+	op := rest[:1]
+	if strings.HasPrefix(rest, ":") && len(rest) > 1 {
+		op = rest[:2]
+	}
+	arg := rest[len(op):]
+	from, set := in.vars[name]
+	value := ""
+	if set {
+		value = from.Value
+	}
+	nonEmpty := set && (value != "" || !strings.HasPrefix(op, ":"))
+	in.subs = append(in.subs, Substitution{Node: n, Text: text, Name: name, From: from})
+	switch op {
+	case ":-", "-":
+		if nonEmpty {
+			return value, nil
+		}
+		return in.expand(arg, n)
+	case ":?", "?":
+		if nonEmpty {
+			return value, nil
+		}
+		msg, err := in.expand(arg, n)
+		if err != nil {
+			return "", err
+		}
+		return "", n.Errorf("required variable %s is missing a value: %s", name, msg)
+	case ":+", "+":
+		if nonEmpty {
+			return in.expand(arg, n)
+		}
+		return "", nil
+	}
+	return "", n.Errorf("invalid interpolation format for %q: unknown operator in %s", n.Value, text)
+}
+
+// lookup returns the value of the variable name written as text, warning when it is unset.
+func (in *interpolator) lookup(text, name string, n *slowjson.Node) string {
+	from, ok := in.vars[name]
+	in.subs = append(in.subs, Substitution{Node: n, Text: text, Name: name, From: from})
+	if !ok {
+		d := diag.Warningf(n, "variable %s is not set, %s expands to a blank string", name, text)
+		in.diags = append(in.diags, d)
+		return ""
+	}
+	return from.Value
+}
+
+// closingBrace returns the index of the } closing the ${ whose content starts at start, -1 if none.
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isName(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
+
+// environ returns the variables of env, in the form of os.Environ, as string nodes with the File Environ.
+// A node's Source is its NAME=value line so it renders like one.
+func environ(env []string) map[string]*slowjson.Node {
+	vars := map[string]*slowjson.Node{}
+	for _, kv := range env {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		col := len(name) + 2
+		vars[name] = &slowjson.Node{
+			Type:        slowjson.NodeString,
+			Value:       value,
+			StartLine:   1,
+			StartCol:    col,
+			EndLine:     1,
+			EndCol:      col + len(value),
+			StartOffset: col - 1,
+			EndOffset:   len(kv),
+			File:        Environ,
+			Source:      kv,
+		}
+	}
+	return vars
+}
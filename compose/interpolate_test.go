@@ -0,0 +1,60 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestInterpolator_Expand(t *testing.T) {
+	vars := environ([]string{"TAG=1.2", "EMPTY=", "HOST=db"})
+	tests := []struct {
+		in, want string
+		subs     int
+	}{
+		{"nginx:${TAG}", "nginx:1.2", 1},
+		{"nginx:$TAG-alpine", "nginx:1.2-alpine", 1},
+		{"cost: $$5 and $", "cost: $5 and $", 0},
+		{"${MISSING:-latest}", "latest", 1},
+		{"${EMPTY:-latest}|${EMPTY-latest}", "latest|", 2},
+		{"${MISSING-${HOST:-x}}", "db", 2},
+		{"${HOST:+set}|${EMPTY:+set}|${EMPTY+set}|${MISSING+set}", "set||set|", 4},
+		{"${HOST:?needed}", "db", 1},
+		{"${MISSING}x", "x", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			in := &interpolator{vars: vars}
+			got, err := in.expand(tt.in, &slowjson.Node{Type: slowjson.NodeString, Value: tt.in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || len(in.subs) != tt.subs {
+				t.Errorf("expand() = %q with %d substitutions, want %q with %d", got, len(in.subs), tt.want, tt.subs)
+			}
+		})
+	}
+
+	in := &interpolator{vars: vars}
+	in.expand("${MISSING}", &slowjson.Node{Type: slowjson.NodeString})
+	if len(in.diags) != 1 || in.diags[0].Message != "variable MISSING is not set, ${MISSING} expands to a blank string" {
+		t.Errorf("diagnostics = %v", in.diags)
+	}
+	if s := in.subs[0]; s.Name != "MISSING" || s.Text != "${MISSING}" || s.From != nil {
+		t.Errorf("substitution = %+v", s)
+	}
+
+	for _, tt := range []struct{ in, want string }{
+		{"${EMPTY:?set EMPTY in .env}", "required variable EMPTY is missing a value: set EMPTY in .env"},
+		{"${MISSING?}", "required variable MISSING is missing a value: "},
+		{"${TAG", `invalid interpolation format for "${TAG": unterminated ${`},
+		{"${1X}", `invalid interpolation format for "${1X}": invalid variable name in ${1X}`},
+		{"${TAG/x/y}", `invalid interpolation format for "${TAG/x/y}": unknown operator in ${TAG/x/y}`},
+	} {
+		n := &slowjson.Node{Type: slowjson.NodeString, Value: tt.in}
+		if _, err := (&interpolator{vars: vars}).expand(tt.in, n); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expand(%q) error = %v, want %s", tt.in, err, tt.want)
+		}
+	}
+}
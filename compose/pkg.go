@@ -0,0 +1,6 @@
+// Package compose loads Docker Compose projects the way docker compose does: every compose file is
+// interpolated with the variables of the .env file and the process environment, then the files are
+// merged in order. Provenance is kept throughout, so Environment can explain why a container gets a
+// variable: which compose file or env_file set it, what it overrode and where an interpolated
+// ${VAR} came from.
+package compose
@@ -0,0 +1,321 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// Options configures Load.
+type Options struct {
+	// Files are the compose files, later ones overriding earlier ones like docker compose -f a -f b.
+	Files []string
+	// EnvFile holds the variables for interpolation, .env in the directory of the first file by
+	// default. It may be missing.
+	EnvFile string
+	// Environ is the process environment in the form of os.Environ, its variables override those of
+	// EnvFile. os.Environ() is used when it is nil.
+	Environ []string
+}
+
+// Project is a loaded compose project.
+type Project struct {
+	// Result is the merged compose model with the provenance of every value.
+	*merge.Result
+	// Substitutions are the variables expanded into values of the compose files and env_files.
+	Substitutions []Substitution
+
+	dir    string
+	vars   map[string]*slowjson.Node
+	layers []merge.Layer
+	interp *interpolator
+	// envFiles are the interpolated env_files of services by path, read once.
+	envFiles map[string]*slowjson.Node
+}
+
+// Load reads a compose project. Every file is interpolated on its own, the list forms of environment
+// and labels become maps, and the files are merged: maps key by key, command and entrypoint replaced, a
+// volume replacing the one with the same target and other sequences, e.g. ports, appended. Warnings
+// about unset variables are in the Diagnostics of the Result.
+func Load(opts Options) (*Project, error) {
+	if len(opts.Files) == 0 {
+		return nil, errors.New("no compose files")
+	}
+	env := opts.Environ
+	if env == nil {
+		env = os.Environ()
+	}
+	p := &Project{dir: filepath.Dir(opts.Files[0]), vars: map[string]*slowjson.Node{}, envFiles: map[string]*slowjson.Node{}}
+	p.interp = &interpolator{vars: p.vars}
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = filepath.Join(p.dir, ".env")
+	}
+	fromEnv := environ(env)
+	for name, n := range fromEnv {
+		p.vars[name] = n
+	}
+	dotenv, err := p.readEnvFile(envFile, opts.EnvFile != "")
+	if err != nil {
+		return nil, err
+	}
+	if dotenv != nil {
+		// an entry sees the earlier ones, the environment overrides them all
+		for _, key := range dotenv.Children {
+			v := key.Children[0]
+			if _, ok := fromEnv[key.Value]; ok || v.Type == slowjson.NodeNull {
+				continue
+			}
+			if err := p.expandEnvValue(v); err != nil {
+				return nil, err
+			}
+			p.vars[key.Value] = v
+		}
+	}
+	for _, file := range opts.Files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		root, err := yaml.Parse(b, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.interp.walk(root); err != nil {
+			return nil, err
+		}
+		normalize(root)
+		p.layers = append(p.layers, merge.Layer{Name: file, Root: root})
+	}
+	res, err := merge.Options{ArrayIdentity: arrayIdentity}.Merge(p.layers...)
+	if err != nil {
+		return nil, err
+	}
+	p.Result = res
+	p.finish()
+	return p, nil
+}
+
+// finish publishes the substitutions and warnings of the interpolator.
+func (p *Project) finish() {
+	p.Substitutions = p.interp.subs
+	p.Diagnostics = append(p.Diagnostics, p.interp.diags...)
+	p.interp.diags = nil
+}
+
+// readEnvFile parses an env file, nil when it is missing and need not exist.
+func (p *Project) readEnvFile(path string, required bool) (*slowjson.Node, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseEnv(b, path)
+}
+
+// envFile reads and interpolates the env_file of a service, nil when it is missing and need not exist.
+func (p *Project) envFile(path string, required bool) (*slowjson.Node, error) {
+	if env, ok := p.envFiles[path]; ok {
+		return env, nil
+	}
+	env, err := p.readEnvFile(path, required)
+	if err != nil || env == nil {
+		return nil, err
+	}
+	for _, key := range env.Children {
+		if err := p.expandEnvValue(key.Children[0]); err != nil {
+			return nil, err
+		}
+	}
+	p.envFiles[path] = env
+	return env, nil
+}
+
+// expandEnvValue interpolates a value of an env file unless it is single-quoted.
+func (p *Project) expandEnvValue(v *slowjson.Node) error {
+	if v.Type != slowjson.NodeString || v.StartOffset < len(v.Source) && v.Source[v.StartOffset] == '\'' || !strings.Contains(v.Value, "$") {
+		return nil
+	}
+	s, err := p.interp.expand(v.Value, v)
+	if err != nil {
+		return err
+	}
+	v.Value = s
+	return nil
+}
+
+// Services returns the names of the services, sorted.
+func (p *Project) Services() []string {
+	services := p.Root.Get("services")
+	if services == nil {
+		return nil
+	}
+	names := services.Keys()
+	sort.Strings(names)
+	return names
+}
+
+// Environment returns the variables of the container of service with their provenance: the entries of
+// its env_files in order, overridden by its environment in every compose file. Entries without a value
+// take it from the interpolation variables and are left out when it is unset, like docker compose does.
+// Explain on the result tells why the container gets a variable.
+func (p *Project) Environment(service string) (*merge.Result, error) {
+	path := slowjson.Path{}.Key("services").Key(service)
+	svc := p.Root.Lookup(path)
+	if svc == nil {
+		return nil, fmt.Errorf("no service %s, the services are %s", service, strings.Join(p.Services(), ", "))
+	}
+	var layers []merge.Layer
+	if files := svc.Get("env_file"); files != nil {
+		for _, f := range files.Children {
+			file, required := f.Value, true
+			if f.Type == slowjson.NodeObject {
+				file = f.Get("path").Value
+				required = f.Get("required") == nil || f.Get("required").Value != "false"
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(p.dir, file)
+			}
+			env, err := p.envFile(file, required)
+			if err != nil {
+				return nil, f.Errorf("env_file: %w", err)
+			}
+			if env == nil {
+				continue
+			}
+			layers = append(layers, merge.Layer{Name: "env_file " + file, Root: p.resolve(env)})
+		}
+	}
+	for _, l := range p.layers {
+		if env := l.Root.Lookup(path.Key("environment")); env != nil && env.Type == slowjson.NodeObject {
+			layers = append(layers, merge.Layer{Name: l.Name, Root: p.resolve(env)})
+		}
+	}
+	p.finish()
+	return merge.Merge(layers...)
+}
+
+// resolve returns the variables of env with the scalars as strings and the values of null entries taken
+// from the interpolation variables, dropping those that are unset.
+func (p *Project) resolve(env *slowjson.Node) *slowjson.Node {
+	out := *env
+	out.Children = nil
+	for _, key := range env.Children {
+		if len(key.Children) == 0 {
+			continue
+		}
+		v := key.Children[0]
+		switch v.Type {
+		case slowjson.NodeNull:
+			if v = p.vars[key.Value]; v == nil {
+				continue
+			}
+		case slowjson.NodeNumber, slowjson.NodeBoolean:
+			s := *v
+			s.Type = slowjson.NodeString
+			v = &s
+		}
+		k := *key
+		k.Parent = &out
+		k.Children = []*slowjson.Node{v}
+		out.Children = append(out.Children, &k)
+	}
+	return &out
+}
+
+// normalize rewrites the list forms of the environment and labels of services, e.g. "- KEY=value",
+// as maps, and a single env_file as a list, so the files merge key by key.
+func normalize(root *slowjson.Node) {
+	services := root.Get("services")
+	if services == nil || services.Type != slowjson.NodeObject {
+		return
+	}
+	for _, key := range services.Children {
+		if len(key.Children) == 0 || key.Children[0].Type != slowjson.NodeObject {
+			continue
+		}
+		svc := key.Children[0]
+		for _, field := range []string{"environment", "labels"} {
+			if list := svc.Get(field); list != nil && list.Type == slowjson.NodeArray {
+				replace(list, listToMap(list))
+			}
+		}
+		if f := svc.Get("env_file"); f != nil && f.Type == slowjson.NodeString {
+			list := *f
+			list.Type, list.Value = slowjson.NodeArray, ""
+			elem := *f
+			elem.Parent = &list
+			list.Children = []*slowjson.Node{&elem}
+			replace(f, &list)
+		}
+	}
+}
+
+// replace puts n in place of the value old.
+func replace(old, n *slowjson.Node) {
+	n.Parent = old.Parent
+	old.Parent.Children = []*slowjson.Node{n}
+}
+
+// listToMap turns "KEY=value" items into a map, a "KEY" without = has a null value. Keys and values
+// take the position of their item.
+func listToMap(list *slowjson.Node) *slowjson.Node {
+	obj := *list
+	obj.Type, obj.Children = slowjson.NodeObject, nil
+	for _, item := range list.Children {
+		name, value, ok := strings.Cut(item.Value, "=")
+		k := *item
+		k.Type, k.Value, k.Parent = slowjson.NodeString, name, &obj
+		v := *item
+		v.Type, v.Value, v.Parent = slowjson.NodeString, value, &k
+		if !ok {
+			v.Type, v.Value = slowjson.NodeNull, "null"
+		}
+		k.Children = []*slowjson.Node{&v}
+		obj.Children = append(obj.Children, &k)
+	}
+	return &obj
+}
+
+// arrayIdentity identifies the elements of the sequences of services that are merged rather than
+// replaced: volumes by their target, the others, e.g. ports or dns, by their value.
+func arrayIdentity(path slowjson.Path) func(*slowjson.Node) string {
+	if len(path) != 3 || path[0].Key != "services" || path[2].IsIndex {
+		return nil
+	}
+	switch path[2].Key {
+	case "command", "entrypoint":
+		return nil
+	case "volumes":
+		return volumeTarget
+	}
+	return func(elem *slowjson.Node) string {
+		b, _ := slowjson.Marshal(elem)
+		return string(b)
+	}
+}
+
+// volumeTarget returns the path in the container of a volume, e.g. /data for "./data:/data:ro".
+func volumeTarget(v *slowjson.Node) string {
+	if v.Type == slowjson.NodeObject {
+		if t := v.Get("target"); t != nil {
+			return t.Value
+		}
+		return ""
+	}
+	parts := strings.Split(v.Value, ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
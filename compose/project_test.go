@@ -0,0 +1,129 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := writeFile(t, dir, ".env", "TAG=1.25\nDB_HOST=localhost\nDB_URL=postgres://${DB_HOST}/app\n")
+	webEnv := writeFile(t, dir, "web.env", "LOG_LEVEL=info\nDB_URL=${DB_URL}\nSECRET='${not expanded}'\n")
+	base := writeFile(t, dir, "compose.yaml", `services:
+  web:
+    image: nginx:${TAG}
+    command: ["serve"]
+    env_file: web.env
+    environment:
+      - LOG_LEVEL=debug
+      - DEBUG
+      - REGION
+    ports: ["80:80"]
+    volumes: ["./data:/data", "logs:/var/log"]
+  db:
+    image: postgres
+`)
+	override := writeFile(t, dir, "compose.override.yaml", `services:
+  web:
+    command: ["serve", "--dev"]
+    environment:
+      LOG_LEVEL: trace
+      WORKERS: 4
+    ports: ["8080:8080", "80:80"]
+    volumes: ["./dev-data:/data"]
+`)
+
+	p, err := Load(Options{Files: []string{base, override}, Environ: []string{"DEBUG=1", "TAG=1.26"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := p.Root.Get("services.web")
+	b, _ := slowjson.Marshal(web)
+	want := `{"image":"nginx:1.26","command":["serve","--dev"],"env_file":["web.env"],"environment":{"LOG_LEVEL":"trace","DEBUG":null,"REGION":null,"WORKERS":4},"ports":["80:80","8080:8080"],"volumes":["./dev-data:/data","logs:/var/log"]}`
+	if string(b) != want {
+		t.Errorf("services.web = %s\nwant %s", b, want)
+	}
+	if strings.Join(p.Services(), ",") != "db,web" {
+		t.Errorf("Services() = %v", p.Services())
+	}
+	if len(p.Substitutions) != 2 || p.Substitutions[1].Text != "${TAG}" || p.Substitutions[1].From.File != Environ {
+		t.Errorf("Substitutions = %+v", p.Substitutions)
+	}
+
+	env, err := p.Environment("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = slowjson.Marshal(env.Root)
+	want = `{"LOG_LEVEL":"trace","DB_URL":"postgres://localhost/app","SECRET":"${not expanded}","DEBUG":"1","WORKERS":"4"}`
+	if string(b) != want {
+		t.Errorf("Environment() = %s\nwant %s", b, want)
+	}
+	got, err := env.Explain("LOG_LEVEL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `LOG_LEVEL = "trace"
+  set by ` + override + `:5:18 (` + override + `)
+  overrides "debug" from ` + base + `:7:9 (` + base + `)
+  overrides "info" from ` + webEnv + `:1:11 (env_file ` + webEnv + `)
+`
+	if got != want {
+		t.Errorf("Explain(LOG_LEVEL) =\n%s\nwant\n%s", got, want)
+	}
+	if o, _ := env.Origin("DEBUG"); o.Node.File != Environ || o.Layer != base {
+		t.Errorf("Origin(DEBUG) = %+v", o)
+	}
+	o, _ := env.Origin("DB_URL")
+	var from []string
+	for _, s := range p.Substitutions {
+		if s.Node == o.Node {
+			from = append(from, s.Text+" from "+s.From.Location())
+		}
+	}
+	if strings.Join(from, ",") != "${DB_URL} from "+dotenv+":3:8" {
+		t.Errorf("substitutions of DB_URL = %v", from)
+	}
+	if _, err := p.Environment("api"); err == nil || err.Error() != "no service api, the services are db, web" {
+		t.Errorf("Environment(api) error = %v", err)
+	}
+	if len(p.Diagnostics) != 0 {
+		t.Errorf("Diagnostics = %v", p.Diagnostics)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "compose.yaml", "services:\n  web:\n    image: ${IMAGE:?set IMAGE}\n")
+	if _, err := Load(Options{Files: []string{file}, Environ: []string{}}); err == nil || err.Error() != `services.web.image: required variable IMAGE is missing a value: set IMAGE at `+file+`:3:12` {
+		t.Errorf("Load() error = %v", err)
+	}
+	if _, err := Load(Options{Files: []string{file}, EnvFile: filepath.Join(dir, "missing.env")}); err == nil || !strings.Contains(err.Error(), "missing.env") {
+		t.Errorf("Load() with a missing -env-file error = %v", err)
+	}
+
+	file = writeFile(t, dir, "warn.yaml", "services:\n  web:\n    image: app:${TAG}\n    env_file: [{path: optional.env, required: false}]\n")
+	p, err := Load(Options{Files: []string{file}, Environ: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Diagnostics) != 1 || p.Diagnostics[0].String() != file+":3:12: warning: services.web.image: variable TAG is not set, ${TAG} expands to a blank string" {
+		t.Errorf("Diagnostics = %v", p.Diagnostics)
+	}
+	if env, err := p.Environment("web"); err != nil || len(env.Root.Children) != 0 {
+		t.Errorf("Environment() with a missing optional env_file = %v", err)
+	}
+}
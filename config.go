@@ -0,0 +1,202 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Config is the merge of its sources, later sources overriding earlier ones.
+// It is safe for concurrent use, Load replaces the merged tree atomically.
+type Config struct {
+	// Merge configures how the sources are merged.
+	Merge merge.Options
+	// Limits bounds the document of every source, see Limit for limits of a single source.
+	Limits Limits
+	// Versions is the range of configVersion values of the sources the program reads, see VersionRange.
+	Versions VersionRange
+	// TrackUsage records the values read by Get and Decode, see UnusedKeys.
+	TrackUsage bool
+	// OnAccessViolation is called for reads through a Module outside its declared prefixes, e.g. to
+	// log them while adopting modules. Such reads panic when it is nil.
+	OnAccessViolation func(*AccessViolation)
+	// BeforeApply is called by every Load but the first with the current tree, the newly merged one
+	// and the changes between them, unless nothing changed. Returning an error keeps the current tree
+	// and fails the Load, so applications can require approval, canary a change or rate-limit churn
+	// from remote sources. The trees must not be modified.
+	BeforeApply func(old, new *slowjson.Node, changes []slowjson.Change) error
+
+	sources []Source
+
+	// loadMu serializes Load, so a Load approves and applies its tree against the one it replaces
+	loadMu sync.Mutex
+
+	mu        sync.RWMutex
+	res       *merge.Result
+	snapshots []snapshot
+
+	healthMu sync.Mutex
+	health   []SourceHealth
+
+	reloadMu sync.Mutex
+	reloads  []Reload
+
+	usageMu sync.Mutex
+	used    map[string]bool
+}
+
+// NewConfig creates a Config over sources, call Load before reading it.
+func NewConfig(sources ...Source) *Config {
+	return &Config{sources: sources}
+}
+
+// Load loads every source and merges them. The previous tree is kept when any source fails or
+// BeforeApply rejects the change.
+// A DependentSource is loaded after the sources it depends on, the merge order stays the order of the sources.
+// Every Load is recorded in Reloads. Concurrent Loads run one after the other.
+func (c *Config) Load(ctx context.Context) error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	start := time.Now()
+	res, err := c.load(ctx)
+	var changes []slowjson.Change
+	if err == nil {
+		c.mu.RLock()
+		old := c.res
+		c.mu.RUnlock()
+		first := old == nil
+		if first {
+			old = &merge.Result{Root: &slowjson.Node{Type: slowjson.NodeObject}}
+		}
+		changes = slowjson.Diff(old.Root, res.Root)
+		if !first && len(changes) > 0 && c.BeforeApply != nil {
+			if herr := c.BeforeApply(old.Root, res.Root, changes); herr != nil {
+				err = fmt.Errorf("reload rejected: %w", herr)
+				changes = nil
+			}
+		}
+		if err == nil {
+			c.mu.Lock()
+			c.res = res
+			if first || len(changes) > 0 {
+				c.recordSnapshot(start, res)
+			}
+			c.mu.Unlock()
+		}
+	}
+	c.recordReload(Reload{At: start, Err: err, Changes: changes})
+	return err
+}
+
+func (c *Config) load(ctx context.Context) (*merge.Result, error) {
+	ctx = withMaxBytes(ctx, c.Limits.MaxBytes)
+	order, err := LoadOrder(c.sources)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]merge.Layer, len(c.sources))
+	for _, i := range order {
+		s := c.sources[i]
+		var n *slowjson.Node
+		if ds, ok := s.(DependentSource); ok {
+			var deps *merge.Result
+			deps, err = c.Merge.Merge(dependencies(ds, c.sources, layers)...)
+			if err == nil {
+				n, err = ds.LoadWith(ctx, deps.Root)
+			}
+		} else {
+			n, err = s.Load(ctx)
+		}
+		if err == nil {
+			err = c.Limits.Check(n)
+		}
+		if err == nil {
+			err = c.Versions.Check(n)
+		}
+		c.recordHealth(i, time.Now(), err)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", s.Name(), err)
+		}
+		layers[i] = merge.Layer{Name: s.Name(), Root: n}
+		if ms, ok := s.(MetadataSource); ok {
+			layers[i].Meta = ms.Metadata()
+		}
+	}
+	return c.Merge.Merge(layers...)
+}
+
+// dependencies returns the loaded layers ds depends on in source order.
+func dependencies(ds DependentSource, sources []Source, layers []merge.Layer) []merge.Layer {
+	names := map[string]bool{}
+	for _, name := range ds.DependsOn() {
+		names[name] = true
+	}
+	var deps []merge.Layer
+	for i, s := range sources {
+		if names[s.Name()] {
+			deps = append(deps, layers[i])
+		}
+	}
+	return deps
+}
+
+func (c *Config) result() *merge.Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.res == nil {
+		return &merge.Result{Root: &slowjson.Node{Type: slowjson.NodeObject}}
+	}
+	return c.res
+}
+
+// Root returns the merged tree, an empty object before the first Load.
+// Its nodes keep the positions and file names of the sources that set them.
+func (c *Config) Root() *slowjson.Node {
+	return c.result().Root
+}
+
+// Get returns the merged value at path, see slowjson.Node.Get.
+func (c *Config) Get(path string, opts ...slowjson.GetOption) *slowjson.Node {
+	n := c.Root().Get(path, opts...)
+	c.markUsed(n)
+	return n
+}
+
+// Decode decodes the merged tree into v.
+func (c *Config) Decode(v interface{}) error {
+	return c.decoder().Decode(v)
+}
+
+// decoder returns a Decoder of the merged tree that records usage when tracked.
+func (c *Config) decoder() *Decoder {
+	d := NewDecoder(c.Root())
+	if c.TrackUsage {
+		d.read = c.markUsed
+	}
+	return d
+}
+
+// Diagnostics returns the problems found by the last merge, e.g. overrides of final keys.
+func (c *Config) Diagnostics() []diag.Diagnostic {
+	return c.result().Diagnostics
+}
+
+// Origin returns the source that last set the value at path.
+func (c *Config) Origin(path string) (merge.Origin, bool) {
+	return c.result().Origin(path)
+}
+
+// History returns every source that set the value at path, oldest first, see merge.Result.History.
+func (c *Config) History(path string) ([]merge.Origin, error) {
+	return c.result().History(path)
+}
+
+// Explain describes the value at path and every source that set it.
+func (c *Config) Explain(path string) (string, error) {
+	return c.result().Explain(path)
+}
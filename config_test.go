@@ -0,0 +1,137 @@
+package tracedconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte("{\n  \"server\": {\"port\": 8080}\n}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig(Bytes("defaults.json", []byte(`{"server": {"host": "localhost", "port": 80}}`)), File(path))
+	if got := len(c.Root().Children); got != 0 {
+		t.Errorf("Root() before Load has %d children", got)
+	}
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var cfg struct {
+		Server server `json:"server"`
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != 8080 {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if o, ok := c.Origin("server.port"); !ok || o.Layer != path {
+		t.Errorf("Origin() = %v, %v", o, ok)
+	}
+	if port := c.Get("server.port"); port.Location() != path+":2:22" {
+		t.Errorf("Get() at %s", port.Location())
+	}
+	got, err := c.Explain("server.port")
+	if err != nil || !strings.Contains(got, "overrides 80 from defaults.json:1:42 (defaults.json)") {
+		t.Errorf("Explain() = %q, %v", got, err)
+	}
+}
+
+func TestConfig_LoadError(t *testing.T) {
+	c := NewConfig(Bytes("a.json", []byte(`{"a": 1}`)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.sources = append(c.sources, File(filepath.Join(t.TempDir(), "missing.json")))
+	err := c.Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "load ") {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Get("a") == nil {
+		t.Error("previous tree should be kept after a failed Load")
+	}
+}
+
+func TestConfig_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte(`{"port": 80}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig(File(path))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan error, 1)
+	go c.Watch(ctx, WatchOptions{Interval: time.Millisecond, OnReload: func(err error) {
+		select {
+		case reloaded <- err:
+		default:
+		}
+	}})
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload")
+	}
+	if got := c.Get("port"); got == nil || got.Value != "80" {
+		t.Errorf("port = %v after reload", got)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	if err := WriteFile(path, []byte("{}"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(link, []byte(`{"port": 80}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != `{"port": 80}` {
+		t.Errorf("WriteFile() wrote %q through the symlink", b)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("WriteFile() replaced the symlink: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("WriteFile() mode = %v, want 0600", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("WriteFile() left %d files in the directory, want 2", len(entries))
+	}
+}
+
+func TestConfig_Diagnostics(t *testing.T) {
+	c := NewConfig(
+		Bytes("base.json", []byte(`{"auth": {"required": true}, "@final": ["auth"]}`)),
+		Bytes("local.json", []byte(`{"auth": {"required": false}}`)),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("auth.required").Value; got != "true" {
+		t.Errorf("auth.required = %s, the final value should win", got)
+	}
+	diags := c.Diagnostics()
+	if len(diags) != 1 || !strings.Contains(diags[0].String(), "auth is final and cannot be overridden by local.json") {
+		t.Errorf("Diagnostics() = %v", diags)
+	}
+}
@@ -0,0 +1,105 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Handler serves c:
+//
+//	/config                the merged tree as JSON
+//	/explain?path=a.b      the value at path and every source that set it
+//	/health                the health of every source as JSON, 503 when any source is failing
+//	POST /rollback?n=1     restores the tree n loads back, see Config.Rollback, and lists the changes
+func Handler(c *tracedconfig.Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		b, err := slowjson.MarshalOptions{Indent: "  "}.Marshal(c.Root())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(b, '\n'))
+	})
+	mux.HandleFunc("/explain", func(w http.ResponseWriter, r *http.Request) {
+		s, err := c.Explain(r.URL.Query().Get("path"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, s)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		health := c.Health()
+		code := http.StatusOK
+		out := make([]sourceHealth, len(health))
+		for i, h := range health {
+			out[i] = sourceHealth{
+				Name:     h.Name,
+				Status:   h.Status.String(),
+				Fallback: h.Fallback,
+				Failures: h.Failures,
+			}
+			if h.Err != nil {
+				out[i].Error = h.Err.Error()
+			}
+			if !h.LastAttempt.IsZero() {
+				out[i].LastAttempt = h.LastAttempt.UTC().Format(time.RFC3339)
+			}
+			if !h.LastSuccess.IsZero() {
+				out[i].LastSuccess = h.LastSuccess.UTC().Format(time.RFC3339)
+			}
+			if h.Status == tracedconfig.StatusFailing {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	})
+	mux.HandleFunc("/rollback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "rollback requires POST", http.StatusMethodNotAllowed)
+			return
+		}
+		n := 1
+		if q := r.URL.Query().Get("n"); q != "" {
+			var err error
+			if n, err = strconv.Atoi(q); err != nil {
+				http.Error(w, fmt.Sprintf("invalid n %q", q), http.StatusBadRequest)
+				return
+			}
+		}
+		before := c.Root()
+		if err := c.Rollback(n); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, ch := range slowjson.Diff(before, c.Root()) {
+			fmt.Fprintln(w, ch)
+		}
+	})
+	return mux
+}
+
+type sourceHealth struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	Fallback    string `json:"fallback,omitempty"`
+	Failures    int    `json:"failures,omitempty"`
+	LastAttempt string `json:"lastAttempt,omitempty"`
+	LastSuccess string `json:"lastSuccess,omitempty"`
+}
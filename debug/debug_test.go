@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+func get(t *testing.T, h http.Handler, url string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestHandler(t *testing.T) {
+	c := tracedconfig.NewConfig(
+		tracedconfig.Bytes("defaults.json", []byte(`{"port": 80}`)),
+		tracedconfig.Bytes("app.json", []byte(`{"port": 8080}`)),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := Handler(c)
+
+	if code, body := get(t, h, "/config"); code != 200 || body != "{\n  \"port\": 8080\n}\n" {
+		t.Errorf("/config = %d %q", code, body)
+	}
+	code, body := get(t, h, "/explain?path=port")
+	if code != 200 || !strings.Contains(body, "port = 8080") || !strings.Contains(body, "defaults.json") {
+		t.Errorf("/explain = %d %q", code, body)
+	}
+	if code, _ := get(t, h, "/explain?path=missing"); code != 404 {
+		t.Errorf("/explain of a missing path = %d", code)
+	}
+	code, body = get(t, h, "/health")
+	if code != 200 || !strings.Contains(body, `"name": "app.json"`) || !strings.Contains(body, `"status": "healthy"`) {
+		t.Errorf("/health = %d %q", code, body)
+	}
+}
+
+func TestUI(t *testing.T) {
+	c := tracedconfig.NewConfig(
+		tracedconfig.Bytes("defaults.json", []byte(`{"server": {"port": 80, "host": "a"}}`)),
+		tracedconfig.Bytes("app.json", []byte(`{"server": {"port": 8080}}`)),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := UI(c, UIOptions{})
+
+	if code, body := get(t, h, "/"); code != 200 || !strings.Contains(body, "<title>tracedconfig</title>") {
+		t.Errorf("/ = %d", code)
+	}
+	code, body := get(t, h, "/api/tree?filter=PORT")
+	if code != 200 || !strings.Contains(body, `"path": "server.port"`) || strings.Contains(body, "server.host") {
+		t.Errorf("/api/tree = %d %s", code, body)
+	}
+	code, body = get(t, h, "/api/origins?path=server.port")
+	if code != 200 || !strings.Contains(body, `"layer": "defaults.json"`) || !strings.Contains(body, `"location": "app.json:1:21"`) {
+		t.Errorf("/api/origins = %d %s", code, body)
+	}
+	if code, _ := get(t, h, "/api/origins?path=server.nope"); code != 404 {
+		t.Errorf("/api/origins of a missing path = %d", code)
+	}
+	code, body = get(t, h, "/api/reloads")
+	if code != 200 || !strings.Contains(body, `"+ server: {\"port\":8080,\"host\":\"a\"}"`) {
+		t.Errorf("/api/reloads = %d %s", code, body)
+	}
+
+	h = UI(c, UIOptions{Auth: func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return errors.New("invalid token")
+		}
+		return nil
+	}})
+	if code, body := get(t, h, "/api/tree"); code != 401 || !strings.Contains(body, "invalid token") {
+		t.Errorf("/api/tree without token = %d %q", code, body)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/tree", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Errorf("/api/tree with token = %d", rec.Code)
+	}
+}
+
+func TestHandler_Rollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	c := tracedconfig.NewConfig(tracedconfig.File(path))
+	ctx := context.Background()
+	for _, data := range []string{`{"port": 80}`, `{"port": 81}`} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := Handler(c)
+
+	if code, _ := get(t, h, "/rollback"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /rollback = %d", code)
+	}
+	post := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", url, nil))
+		return rec.Code, rec.Body.String()
+	}
+	if code, body := post("/rollback?n=x"); code != http.StatusBadRequest {
+		t.Errorf("POST /rollback?n=x = %d %q", code, body)
+	}
+	if code, body := post("/rollback?n=1"); code != 200 || body != "~ port: 81 -> 80\n" {
+		t.Errorf("POST /rollback = %d %q", code, body)
+	}
+	if code, body := post("/rollback"); code != http.StatusConflict || !strings.Contains(body, "cannot roll back 1 snapshots") {
+		t.Errorf("POST /rollback past the oldest = %d %q", code, body)
+	}
+}
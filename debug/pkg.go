@@ -0,0 +1,4 @@
+// Package debug serves the state of a Config over HTTP for operators: the merged tree, where a value
+// came from, and the health of every source. UI adds a page to browse the tree, its provenance and its
+// reload history. Mount them on an internal port only or behind UIOptions.Auth, they show all values.
+package debug
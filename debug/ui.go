@@ -0,0 +1,244 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// UIOptions configures UI.
+type UIOptions struct {
+	// Auth checks every request, a non-nil error is answered with 401 and the error message.
+	// Without it every request is allowed.
+	Auth func(r *http.Request) error
+}
+
+// UI serves a single page to browse the merged tree of c, filter it by key, follow the chain of
+// sources that set a value and read the reload history. The page reads the JSON endpoints below it:
+//
+//	/api/tree?filter=port     every value whose path contains filter, case-insensitively
+//	/api/origins?path=a.b     the value at path and the sources that set it, oldest first
+//	/api/reloads              the recent loads with their errors and changes, oldest first
+//
+// Mount it under a prefix with http.StripPrefix.
+func UI(c *tracedconfig.Config, opts UIOptions) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(uiPage))
+	})
+	mux.HandleFunc("/api/tree", func(w http.ResponseWriter, r *http.Request) {
+		filter := strings.ToLower(r.URL.Query().Get("filter"))
+		values := []uiValue{}
+		walk(c.Root(), func(n *slowjson.Node) {
+			if p := n.Path().String(); p != "" && strings.Contains(strings.ToLower(p), filter) {
+				values = append(values, valueOf(n))
+			}
+		})
+		writeJSON(w, http.StatusOK, values)
+	})
+	mux.HandleFunc("/api/origins", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		history, err := c.History(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n := c.Get(path)
+		if n == nil && len(history) == 0 {
+			http.Error(w, path+" is not set", http.StatusNotFound)
+			return
+		}
+		out := uiOrigins{Path: path, Origins: []uiOrigin{}}
+		if n != nil {
+			v := valueOf(n)
+			out.Value = &v
+		}
+		for _, o := range history {
+			out.Origins = append(out.Origins, uiOrigin{
+				Layer:    o.Layer,
+				Profile:  o.Profile,
+				Location: o.Node.Location(),
+				Value:    compact(o.Node),
+				Unset:    o.Unset,
+				Meta:     o.Meta,
+			})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("/api/reloads", func(w http.ResponseWriter, r *http.Request) {
+		out := []uiReload{}
+		for _, rl := range c.Reloads() {
+			u := uiReload{At: rl.At.UTC().Format(time.RFC3339), Rollback: rl.Rollback, Changes: []string{}, Omitted: rl.Omitted}
+			if rl.Err != nil {
+				u.Error = rl.Err.Error()
+			}
+			for _, ch := range rl.Changes {
+				u.Changes = append(u.Changes, ch.String())
+			}
+			out = append(out, u)
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	if opts.Auth == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := opts.Auth(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type uiValue struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Location string `json:"location"`
+	Depth    int    `json:"depth"`
+}
+
+type uiOrigin struct {
+	Layer    string            `json:"layer"`
+	Profile  string            `json:"profile,omitempty"`
+	Location string            `json:"location"`
+	Value    string            `json:"value"`
+	Unset    bool              `json:"unset,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+type uiOrigins struct {
+	Path    string     `json:"path"`
+	Value   *uiValue   `json:"value,omitempty"`
+	Origins []uiOrigin `json:"origins"`
+}
+
+type uiReload struct {
+	At       string   `json:"at"`
+	Error    string   `json:"error,omitempty"`
+	Rollback bool     `json:"rollback,omitempty"`
+	Changes  []string `json:"changes"`
+	Omitted  int      `json:"omitted,omitempty"`
+}
+
+// walk calls fn for every value below n in document order, keys are skipped.
+func walk(n *slowjson.Node, fn func(*slowjson.Node)) {
+	for _, c := range n.Children {
+		if c.IsKey() {
+			c = c.Children[0]
+		}
+		fn(c)
+		walk(c, fn)
+	}
+}
+
+func valueOf(n *slowjson.Node) uiValue {
+	v := uiValue{Path: n.Path().String(), Location: n.Location(), Depth: len(n.Path()) - 1}
+	switch n.Type {
+	case slowjson.NodeObject:
+		v.Type = "object"
+	case slowjson.NodeArray:
+		v.Type = "array"
+	case slowjson.NodeString:
+		v.Type = "string"
+	case slowjson.NodeNumber:
+		v.Type = "number"
+	case slowjson.NodeBoolean:
+		v.Type = "boolean"
+	default:
+		v.Type = "null"
+	}
+	if n.Type != slowjson.NodeObject && n.Type != slowjson.NodeArray {
+		v.Value = compact(n)
+	}
+	return v
+}
+
+// compact marshals n on one line.
+func compact(n *slowjson.Node) string {
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		return n.Value
+	}
+	return string(b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// uiPage is the page served by UI, it only talks to the endpoints next to it.
+const uiPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tracedconfig</title>
+<style>
+body { font: 14px sans-serif; margin: 0; display: flex; height: 100vh; }
+#left { width: 50%; overflow: auto; border-right: 1px solid #ccc; padding: 8px; }
+#right { flex: 1; overflow: auto; padding: 8px; }
+#filter { width: 100%; box-sizing: border-box; margin-bottom: 8px; }
+.row { font-family: monospace; cursor: pointer; white-space: pre; }
+.row:hover { background: #eef; }
+.loc, .meta { color: #888; }
+h2 { font-size: 15px; }
+</style>
+</head>
+<body>
+<div id="left"><input id="filter" placeholder="filter keys"><div id="tree"></div></div>
+<div id="right"><h2>Provenance</h2><div id="origins">select a value</div><h2>Reloads</h2><div id="reloads"></div></div>
+<script>
+function el(tag, cls, text) { var e = document.createElement(tag); if (cls) e.className = cls; e.textContent = text; return e; }
+function get(url, fn) { fetch(url).then(function (r) { return r.ok ? r.json() : r.text().then(function (t) { throw new Error(t); }); }).then(fn).catch(function (e) { alert(e.message); }); }
+function tree() {
+  get("api/tree?filter=" + encodeURIComponent(document.getElementById("filter").value), function (values) {
+    var t = document.getElementById("tree"); t.textContent = "";
+    values.forEach(function (v) {
+      var row = el("div", "row", "  ".repeat(v.depth) + v.path + (v.value ? " = " + v.value : " (" + v.type + ")") + "  ");
+      row.appendChild(el("span", "loc", v.location));
+      row.onclick = function () { origins(v.path); };
+      t.appendChild(row);
+    });
+  });
+}
+function origins(path) {
+  get("api/origins?path=" + encodeURIComponent(path), function (o) {
+    var d = document.getElementById("origins"); d.textContent = "";
+    d.appendChild(el("div", "row", o.path + (o.value ? " = " + (o.value.value || o.value.type) : " is unset")));
+    o.origins.slice().reverse().forEach(function (s, i) {
+      var what = s.unset ? "unset by " : (i == 0 ? "set by " : "overrides " + s.value + " from ");
+      var row = el("div", "row", "  " + what + s.location + " (" + s.layer + (s.profile ? ", profile " + s.profile : "") + ")");
+      if (s.meta) row.appendChild(el("span", "meta", " " + JSON.stringify(s.meta)));
+      d.appendChild(row);
+    });
+  });
+}
+function reloads() {
+  get("api/reloads", function (rs) {
+    var d = document.getElementById("reloads"); d.textContent = "";
+    rs.slice().reverse().forEach(function (r) {
+      d.appendChild(el("div", "row", r.at + (r.error ? " failed: " + r.error : (r.rollback ? " rolled back, " : " ") + (r.changes.length + (r.omitted || 0)) + " changes")));
+      r.changes.forEach(function (c) { d.appendChild(el("div", "row", "  " + c)); });
+    });
+  });
+}
+document.getElementById("filter").oninput = tree;
+tree(); reloads();
+</script>
+</body>
+</html>
+`
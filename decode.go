@@ -0,0 +1,529 @@
+package tracedconfig
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/schema"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Decoder decodes a node tree into Go values.
+type Decoder struct {
+	// WeaklyTyped coerces values of the wrong type where the intent is clear, e.g. "8080" to int,
+	// 1 to true, 8080 to "8080" and a single value to a one element slice.
+	// Each coercion is recorded as a warning in Diagnostics.
+	WeaklyTyped bool
+	// UseNumber decodes numbers into interface values as json.Number rather than float64, like
+	// json.Decoder.UseNumber, so integers such as 1000000 keep their digits.
+	UseNumber bool
+	// Diagnostics collects warnings produced while decoding.
+	Diagnostics []diag.Diagnostic
+
+	root *slowjson.Node
+	// read is called with the values decoded as a whole, scalars and values taken by a registered
+	// decoder, an unmarshaler or an interface, for Config usage tracking.
+	read func(n *slowjson.Node)
+}
+
+// NewDecoder creates a Decoder for the tree rooted at n.
+func NewDecoder(n *slowjson.Node) *Decoder {
+	return &Decoder{root: n}
+}
+
+// Decode decodes n into v using a default Decoder.
+func Decode(n *slowjson.Node, v interface{}) error {
+	return NewDecoder(n).Decode(v)
+}
+
+// Decode decodes the tree into v, which must be a non-nil pointer.
+// Decoding goes on after a value fails so every problem is reported at once: the error is a
+// *slowjson.ValidationError, or a *slowjson.MultiError of them when several values failed.
+// Struct fields are matched by their json tag name or, case-insensitively, by field name.
+// Object keys without a matching field are ignored. Strings decoded into a field with a format tag,
+// e.g. format:"email", or into a slice field with one must be of that format, see schema.CheckFormat.
+// Types with a registered decoder use it, otherwise json.Unmarshaler and encoding.TextUnmarshaler
+// implementations are honored so existing custom types work unchanged.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", v)
+	}
+	if d.root == nil {
+		return fmt.Errorf("nil node")
+	}
+	return d.decode(d.root, rv.Elem())
+}
+
+func (d *Decoder) decode(n *slowjson.Node, rv reflect.Value) error {
+	if len(n.Children) == 0 {
+		d.markRead(n)
+	}
+	if n.Type == slowjson.NodeNull {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if ok, err := decodeRegistered(n, rv); ok {
+		d.markRead(n)
+		return err
+	}
+	if ok, err := decodeUnmarshaler(n, rv); ok {
+		d.markRead(n)
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(n, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return n.Errorf("cannot decode into non-empty interface %s", rv.Type())
+		}
+		d.markRead(n)
+		v, err := d.generic(n)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(&v).Elem())
+		return nil
+	case reflect.Struct:
+		return d.decodeStruct(n, rv)
+	case reflect.Map:
+		return d.decodeMap(n, rv)
+	case reflect.Slice:
+		if d.WeaklyTyped && n.Type != slowjson.NodeArray && n.Type != slowjson.NodeObject {
+			d.warn(n, "wrapped %s in a single element %s", typeName(n.Type), rv.Type())
+			s := reflect.MakeSlice(rv.Type(), 1, 1)
+			if err := d.decode(n, s.Index(0)); err != nil {
+				return err
+			}
+			rv.Set(s)
+			return nil
+		}
+		if err := expect(n, slowjson.NodeArray, rv.Type()); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(rv.Type(), len(n.Children), len(n.Children))
+		var errs []error
+		for i, child := range n.Children {
+			errs = append(errs, d.decode(child, s.Index(i)))
+		}
+		rv.Set(s)
+		return slowjson.Join(errs...)
+	case reflect.Array:
+		if err := expect(n, slowjson.NodeArray, rv.Type()); err != nil {
+			return err
+		}
+		if len(n.Children) != rv.Len() {
+			return n.Errorf("expected %d elements for %s, got %d", rv.Len(), rv.Type(), len(n.Children))
+		}
+		var errs []error
+		for i, child := range n.Children {
+			errs = append(errs, d.decode(child, rv.Index(i)))
+		}
+		return slowjson.Join(errs...)
+	case reflect.String:
+		n = d.coerce(n, slowjson.NodeString, rv.Type())
+		if err := expect(n, slowjson.NodeString, rv.Type()); err != nil {
+			return err
+		}
+		rv.SetString(n.Value)
+		return nil
+	case reflect.Bool:
+		n = d.coerce(n, slowjson.NodeBoolean, rv.Type())
+		if err := expect(n, slowjson.NodeBoolean, rv.Type()); err != nil {
+			return err
+		}
+		rv.SetBool(n.Value == "true")
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = d.coerce(n, slowjson.NodeNumber, rv.Type())
+		if err := expect(n, slowjson.NodeNumber, rv.Type()); err != nil {
+			return err
+		}
+		i, err := strconv.ParseInt(n.Value, 10, rv.Type().Bits())
+		if err != nil {
+			return n.Errorf("invalid %s %q: %w", rv.Type(), n.Value, numError(err))
+		}
+		rv.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n = d.coerce(n, slowjson.NodeNumber, rv.Type())
+		if err := expect(n, slowjson.NodeNumber, rv.Type()); err != nil {
+			return err
+		}
+		u, err := strconv.ParseUint(n.Value, 10, rv.Type().Bits())
+		if err != nil {
+			return n.Errorf("invalid %s %q: %w", rv.Type(), n.Value, numError(err))
+		}
+		rv.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n = d.coerce(n, slowjson.NodeNumber, rv.Type())
+		if err := expect(n, slowjson.NodeNumber, rv.Type()); err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(n.Value, rv.Type().Bits())
+		if err != nil {
+			return n.Errorf("invalid %s %q: %w", rv.Type(), n.Value, numError(err))
+		}
+		rv.SetFloat(f)
+		return nil
+	default:
+		return n.Errorf("unsupported type %s", rv.Type())
+	}
+}
+
+// markRead reports a value decoded as a whole to read.
+func (d *Decoder) markRead(n *slowjson.Node) {
+	if d.read != nil {
+		d.read(n)
+	}
+}
+
+func (d *Decoder) decodeStruct(n *slowjson.Node, rv reflect.Value) error {
+	if err := expect(n, slowjson.NodeObject, rv.Type()); err != nil {
+		return err
+	}
+	fields := structFields(rv.Type())
+	var errs []error
+	for _, key := range n.Children {
+		if len(key.Children) == 0 {
+			continue
+		}
+		f, ok := fields.find(key.Value)
+		if !ok {
+			continue
+		}
+		fv, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			errs = append(errs, key.Errorf("%w", err))
+			continue
+		}
+		if err := d.decode(key.Children[0], fv); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, checkFormat(key.Children[0], f.format))
+	}
+	return slowjson.Join(errs...)
+}
+
+// checkFormat checks a string value decoded into a field with a format tag, or the strings of an array
+// decoded into a slice field, see schema.CheckFormat.
+func checkFormat(n *slowjson.Node, format string) error {
+	if format == "" {
+		return nil
+	}
+	if n.Type == slowjson.NodeArray {
+		var errs []error
+		for _, elem := range n.Children {
+			errs = append(errs, checkFormat(elem, format))
+		}
+		return slowjson.Join(errs...)
+	}
+	if n.Type != slowjson.NodeString {
+		return nil
+	}
+	if err := schema.CheckFormat(format, n.Value); err != nil {
+		verr := n.Errorf("%q is not a valid %s: %w", n.Value, format, err)
+		verr.(*slowjson.ValidationError).Code = diag.CodeInvalidFormat
+		return verr
+	}
+	return nil
+}
+
+func (d *Decoder) decodeMap(n *slowjson.Node, rv reflect.Value) error {
+	if err := expect(n, slowjson.NodeObject, rv.Type()); err != nil {
+		return err
+	}
+	keyType := rv.Type().Key()
+	textKey := reflect.PointerTo(keyType).Implements(textUnmarshalerType)
+	if keyType.Kind() != reflect.String && !textKey {
+		return n.Errorf("unsupported map key type %s", keyType)
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv.Type()))
+	}
+	var errs []error
+	for _, key := range n.Children {
+		if len(key.Children) == 0 {
+			continue
+		}
+		ev := reflect.New(rv.Type().Elem()).Elem()
+		if err := d.decode(key.Children[0], ev); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var kv reflect.Value
+		if textKey {
+			kv = reflect.New(keyType)
+			if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key.Value)); err != nil {
+				errs = append(errs, key.Errorf("invalid key: %w", err))
+				continue
+			}
+			kv = kv.Elem()
+		} else {
+			kv = reflect.ValueOf(key.Value).Convert(keyType)
+		}
+		rv.SetMapIndex(kv, ev)
+	}
+	return slowjson.Join(errs...)
+}
+
+// coerce converts a scalar to the wanted node type when WeaklyTyped is set and records a warning.
+// It returns n unchanged when no coercion applies, the caller then reports the type mismatch.
+func (d *Decoder) coerce(n *slowjson.Node, want slowjson.NodeType, t reflect.Type) *slowjson.Node {
+	if !d.WeaklyTyped || n.Type == want {
+		return n
+	}
+	var value string
+	switch {
+	case want == slowjson.NodeString && (n.Type == slowjson.NodeNumber || n.Type == slowjson.NodeBoolean):
+		value = n.Value
+	case want == slowjson.NodeNumber && n.Type == slowjson.NodeString:
+		value = strings.TrimSpace(n.Value)
+		if value == "" {
+			value = "0"
+		}
+	case want == slowjson.NodeNumber && n.Type == slowjson.NodeBoolean:
+		value = "0"
+		if n.Value == "true" {
+			value = "1"
+		}
+	case want == slowjson.NodeBoolean && n.Type == slowjson.NodeString:
+		b, err := strconv.ParseBool(strings.TrimSpace(n.Value))
+		if err != nil {
+			return n
+		}
+		value = strconv.FormatBool(b)
+	case want == slowjson.NodeBoolean && n.Type == slowjson.NodeNumber:
+		f, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return n
+		}
+		value = strconv.FormatBool(f != 0)
+	default:
+		return n
+	}
+	d.warn(n, "coerced %s %q to %s", typeName(n.Type), n.Value, t)
+	c := *n
+	c.Type, c.Value = want, value
+	return &c
+}
+
+func (d *Decoder) warn(n *slowjson.Node, format string, args ...interface{}) {
+	w := diag.Warningf(n, format, args...)
+	w.Code = diag.CodeCoercedValue
+	d.Diagnostics = append(d.Diagnostics, w)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodeUnmarshaler hands the node to types implementing json.Unmarshaler or encoding.TextUnmarshaler.
+// TextUnmarshaler only receives strings. Errors they return are wrapped with the node position.
+func decodeUnmarshaler(n *slowjson.Node, rv reflect.Value) (bool, error) {
+	// pointers are allocated first and checked again on their element
+	if rv.Kind() == reflect.Ptr || !rv.CanAddr() {
+		return false, nil
+	}
+	switch u := rv.Addr().Interface().(type) {
+	case json.Unmarshaler:
+		b, err := slowjson.Marshal(n)
+		if err != nil {
+			return true, err
+		}
+		if err := u.UnmarshalJSON(b); err != nil {
+			return true, n.Errorf("%w", err)
+		}
+		return true, nil
+	case encoding.TextUnmarshaler:
+		if n.Type != slowjson.NodeString {
+			return true, mismatch(n, slowjson.NodeString, rv.Type())
+		}
+		if err := u.UnmarshalText([]byte(n.Value)); err != nil {
+			return true, n.Errorf("%w", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// generic converts the node to the same types encoding/json uses for interface{}, json.Number for
+// numbers with UseNumber.
+func (d *Decoder) generic(n *slowjson.Node) (interface{}, error) {
+	switch n.Type {
+	case slowjson.NodeObject:
+		m := make(map[string]interface{}, len(n.Children))
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			v, err := d.generic(key.Children[0])
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	case slowjson.NodeArray:
+		s := make([]interface{}, 0, len(n.Children))
+		for _, child := range n.Children {
+			v, err := d.generic(child)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		return s, nil
+	case slowjson.NodeString:
+		return n.Value, nil
+	case slowjson.NodeNumber:
+		f, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return nil, n.Errorf("invalid number %q: %w", n.Value, numError(err))
+		}
+		if d.UseNumber {
+			return json.Number(n.Value), nil
+		}
+		return f, nil
+	case slowjson.NodeBoolean:
+		return n.Value == "true", nil
+	case slowjson.NodeNull:
+		return nil, nil
+	default:
+		return nil, n.Errorf("unknown node type %d", n.Type)
+	}
+}
+
+// expect returns an error if the node is not of the given type.
+func expect(n *slowjson.Node, want slowjson.NodeType, t reflect.Type) error {
+	if n.Type != want {
+		return mismatch(n, want, t)
+	}
+	return nil
+}
+
+// mismatch returns a type mismatch error with diag.CodeTypeMismatch.
+func mismatch(n *slowjson.Node, want slowjson.NodeType, t reflect.Type) error {
+	err := n.Errorf("expected %s for %s, got %s", typeName(want), t, typeName(n.Type))
+	err.(*slowjson.ValidationError).Code = diag.CodeTypeMismatch
+	return err
+}
+
+func typeName(t slowjson.NodeType) string {
+	switch t {
+	case slowjson.NodeObject:
+		return "object"
+	case slowjson.NodeArray:
+		return "array"
+	case slowjson.NodeString:
+		return "string"
+	case slowjson.NodeNumber:
+		return "number"
+	case slowjson.NodeBoolean:
+		return "boolean"
+	case slowjson.NodeNull:
+		return "null"
+	default:
+		return "unknown"
+	}
+}
+
+// numError drops the strconv function name and repeated input from the message.
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
+
+type field struct {
+	name  string
+	index []int
+	// format is the format tag, checked on string values.
+	format string
+}
+
+type fieldList []field
+
+// find matches the key exactly first, then case-insensitively like encoding/json.
+func (fl fieldList) find(key string) (field, bool) {
+	for _, f := range fl {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fl {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// structFields lists the decodable fields of t, promoting fields of embedded structs.
+func structFields(t reflect.Type) fieldList {
+	var fields fieldList
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, skip := fieldName(sf)
+		if skip {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && name == "" {
+			for _, inner := range structFields(ft) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{name: name, index: []int{i}, format: sf.Tag.Get("format")})
+	}
+	return fields
+}
+
+// fieldName returns the name from the json tag and whether the field is skipped with "-".
+func fieldName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates nil embedded pointers.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
@@ -0,0 +1,240 @@
+package tracedconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func parse(t *testing.T, input string) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return n
+}
+
+type base struct {
+	Name string `json:"name"`
+}
+
+type server struct {
+	Host string
+	Port uint16 `json:"port"`
+}
+
+type testConfig struct {
+	base
+	Debug   bool              `json:"debug"`
+	Ratio   float64           `json:"ratio"`
+	Servers []server          `json:"servers"`
+	Labels  map[string]string `json:"labels"`
+	Extra   interface{}       `json:"extra"`
+	Backup  *server           `json:"backup"`
+	Pair    [2]int            `json:"pair"`
+	Ignored string            `json:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	input := `{
+  "name": "app",
+  "debug": true,
+  "ratio": 0.5,
+  "servers": [{"host": "a", "port": 80}, {"HOST": "b", "port": 8080}],
+  "labels": {"env": "prod"},
+  "extra": {"list": [1, "x", null]},
+  "backup": {"host": "c"},
+  "pair": [1, 2],
+  "unknown": 1,
+  "-": "x"
+}`
+	var c testConfig
+	if err := Decode(parse(t, input), &c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Name != "app" || !c.Debug || c.Ratio != 0.5 || c.Ignored != "" {
+		t.Errorf("Decode() got %+v", c)
+	}
+	if len(c.Servers) != 2 || c.Servers[1].Host != "b" || c.Servers[1].Port != 8080 {
+		t.Errorf("Decode() got servers %+v", c.Servers)
+	}
+	if c.Labels["env"] != "prod" || c.Backup == nil || c.Backup.Host != "c" || c.Pair != [2]int{1, 2} {
+		t.Errorf("Decode() got %+v", c)
+	}
+	extra, ok := c.Extra.(map[string]interface{})
+	if !ok || len(extra["list"].([]interface{})) != 3 {
+		t.Errorf("Decode() got extra %#v", c.Extra)
+	}
+}
+
+func TestDecode_Null(t *testing.T) {
+	c := testConfig{Backup: &server{}, Labels: map[string]string{"a": "b"}}
+	if err := Decode(parse(t, `{"backup": null, "labels": null}`), &c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Backup != nil || c.Labels != nil {
+		t.Errorf("Decode() got %+v", c)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+	}{
+		{
+			name:      "type mismatch",
+			input:     `{"debug": "yes"}`,
+			wantError: "debug: expected boolean for bool, got string at line 1 col 11",
+		},
+		{
+			name:      "overflow",
+			input:     "{\n  \"servers\": [{\"port\": 70000}]\n}",
+			wantError: `servers[0].port: invalid uint16 "70000": value out of range at line 2 col 24`,
+		},
+		{
+			name:      "not an integer",
+			input:     `{"pair": [1, 2.5]}`,
+			wantError: `pair[1]: invalid int "2.5": invalid syntax`,
+		},
+		{
+			name:      "array length",
+			input:     `{"pair": [1]}`,
+			wantError: "pair: expected 2 elements for [2]int, got 1",
+		},
+		{
+			name:      "object expected",
+			input:     `{"labels": []}`,
+			wantError: "labels: expected object for map[string]string, got array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c testConfig
+			err := Decode(parse(t, tt.input), &c)
+			if err == nil {
+				t.Fatal("Decode() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Decode() error = %v, want error containing %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDecode_MultipleErrors(t *testing.T) {
+	var c testConfig
+	err := Decode(parse(t, `{"debug": "yes", "ratio": true, "servers": [{"port": -1}, {"port": "x"}], "name": "ok"}`), &c)
+	var m *slowjson.MultiError
+	if !errors.As(err, &m) {
+		t.Fatalf("Decode() error = %v, want *slowjson.MultiError", err)
+	}
+	var paths []string
+	for _, e := range m.Errors {
+		var verr *slowjson.ValidationError
+		if !errors.As(e, &verr) {
+			t.Fatalf("error %v is not a *slowjson.ValidationError", e)
+		}
+		paths = append(paths, verr.Path.String())
+	}
+	if got, want := strings.Join(paths, ","), "debug,ratio,servers[0].port,servers[1].port"; got != want {
+		t.Errorf("error paths = %s, want %s", got, want)
+	}
+	var verr *slowjson.ValidationError
+	if !errors.As(m.Errors[0], &verr) || verr.Code != diag.CodeTypeMismatch {
+		t.Errorf("Errors[0] = %#v, want a type mismatch", m.Errors[0])
+	}
+	if c.Name != "ok" {
+		t.Errorf("Name = %q, the valid fields should still be decoded", c.Name)
+	}
+}
+
+func TestDecode_Format(t *testing.T) {
+	var c struct {
+		Admin   string   `json:"admin" format:"email"`
+		Version string   `json:"version" format:"semver"`
+		Hosts   []string `json:"hosts" format:"hostname"`
+	}
+	err := Decode(parse(t, `{"admin": "ops@example.com", "version": "v1.2.0", "hosts": ["a", "-b"]}`), &c)
+	var m *slowjson.MultiError
+	if !errors.As(err, &m) || len(m.Errors) != 2 {
+		t.Fatalf("Decode() error = %v, want two errors", err)
+	}
+	want := []string{
+		`version: "v1.2.0" is not a valid semver: expected a version without the leading v at line 1 col 41`,
+		`hosts[1]: "-b" is not a valid hostname: label "-b" starts or ends with a hyphen at line 1 col 66`,
+	}
+	for i, e := range m.Errors {
+		var verr *slowjson.ValidationError
+		if !errors.As(e, &verr) || verr.Code != diag.CodeInvalidFormat || e.Error() != want[i] {
+			t.Errorf("Errors[%d] = %v, want %s with code %s", i, e, want[i], diag.CodeInvalidFormat)
+		}
+	}
+	if c.Admin != "ops@example.com" || len(c.Hosts) != 2 {
+		t.Errorf("Decode() = %+v, the other fields should still be decoded", c)
+	}
+}
+
+func TestDecode_InvalidTarget(t *testing.T) {
+	var c testConfig
+	if err := Decode(parse(t, `{}`), c); err == nil {
+		t.Error("Decode() into non-pointer expected error")
+	}
+}
+
+type weakConfig struct {
+	Port    int      `json:"port"`
+	Debug   bool     `json:"debug"`
+	Verbose bool     `json:"verbose"`
+	Name    string   `json:"name"`
+	Ratio   float64  `json:"ratio"`
+	Hosts   []string `json:"hosts"`
+	Retries uint     `json:"retries"`
+}
+
+func TestDecoder_WeaklyTyped(t *testing.T) {
+	input := `{
+  "port": "8080",
+  "debug": 1,
+  "verbose": "false",
+  "name": 42,
+  "ratio": true,
+  "hosts": "a",
+  "retries": ""
+}`
+	d := NewDecoder(parse(t, input))
+	d.WeaklyTyped = true
+	var c weakConfig
+	if err := d.Decode(&c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := weakConfig{Port: 8080, Debug: true, Name: "42", Ratio: 1, Hosts: []string{"a"}}
+	if c.Port != want.Port || c.Debug != want.Debug || c.Verbose || c.Name != want.Name ||
+		c.Ratio != want.Ratio || len(c.Hosts) != 1 || c.Hosts[0] != "a" || c.Retries != 0 {
+		t.Errorf("Decode() got %+v, want %+v", c, want)
+	}
+	if len(d.Diagnostics) != 7 {
+		t.Fatalf("Decode() got %d diagnostics, want 7: %v", len(d.Diagnostics), d.Diagnostics)
+	}
+	if got := d.Diagnostics[0].String(); got != `2:11: warning: port: coerced string "8080" to int [TC2002 coerced-value]` {
+		t.Errorf("Diagnostics[0] = %q", got)
+	}
+	if got := d.Diagnostics[5].String(); got != `7:12: warning: hosts: wrapped string in a single element []string [TC2002 coerced-value]` {
+		t.Errorf("Diagnostics[5] = %q", got)
+	}
+
+	// Without the option the same input fails, and values that can't be coerced still fail.
+	if err := Decode(parse(t, input), &c); err == nil {
+		t.Error("Decode() without WeaklyTyped expected error")
+	}
+	d = NewDecoder(parse(t, `{"debug": "maybe"}`))
+	d.WeaklyTyped = true
+	if err := d.Decode(&c); err == nil || !strings.Contains(err.Error(), "expected boolean for bool, got string") {
+		t.Errorf("Decode() error = %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// DependentSource is implemented by sources that need config from other sources to load,
+// e.g. a secret store whose address is set in a file layer.
+type DependentSource interface {
+	Source
+	// DependsOn lists the names of the sources LoadWith needs.
+	DependsOn() []string
+	// LoadWith loads the source, deps is the merge of the sources named in DependsOn.
+	LoadWith(ctx context.Context, deps *slowjson.Node) (*slowjson.Node, error)
+}
+
+// LoadOrder returns the indexes of sources in an order that loads every DependentSource after its
+// dependencies, keeping the given order otherwise. It fails on unknown dependencies and cycles.
+func LoadOrder(sources []Source) ([]int, error) {
+	byName := map[string][]int{}
+	for i, s := range sources {
+		byName[s.Name()] = append(byName[s.Name()], i)
+	}
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(sources))
+	order := make([]int, 0, len(sources))
+	var stack []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for j, name := range stack {
+				if name == sources[i].Name() {
+					start = j
+				}
+			}
+			cycle := append(stack[start:len(stack):len(stack)], sources[i].Name())
+			return fmt.Errorf("source dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[i] = visiting
+		stack = append(stack, sources[i].Name())
+		if ds, ok := sources[i].(DependentSource); ok {
+			for _, dep := range ds.DependsOn() {
+				deps, ok := byName[dep]
+				if !ok {
+					return fmt.Errorf("source %s depends on unknown source %s", sources[i].Name(), dep)
+				}
+				for _, j := range deps {
+					if err := visit(j); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+		order = append(order, i)
+		return nil
+	}
+	for i := range sources {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Deferred creates a source that is built from the config of the sources it depends on when it is
+// loaded, e.g. a Vault source whose address comes from the file layer:
+//
+//	Deferred("vault", []string{"app.json"}, func(deps *slowjson.Node) (Source, error) {
+//		return vault.New(deps.Get("vault.addr").Value), nil
+//	})
+func Deferred(name string, dependsOn []string, build func(deps *slowjson.Node) (Source, error)) DependentSource {
+	return &deferredSource{name: name, dependsOn: dependsOn, build: build}
+}
+
+type deferredSource struct {
+	name      string
+	dependsOn []string
+	build     func(deps *slowjson.Node) (Source, error)
+
+	mu    sync.Mutex
+	built Source
+}
+
+func (s *deferredSource) Name() string        { return s.name }
+func (s *deferredSource) DependsOn() []string { return s.dependsOn }
+
+// Load fails, the source can only be loaded by a Config that provides its dependencies.
+func (s *deferredSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	return nil, fmt.Errorf("source %s depends on %s, load it through a Config", s.name, strings.Join(s.dependsOn, ", "))
+}
+
+func (s *deferredSource) LoadWith(ctx context.Context, deps *slowjson.Node) (*slowjson.Node, error) {
+	src, err := s.build(deps)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.built = src
+	s.mu.Unlock()
+	return src.Load(ctx)
+}
+
+// Metadata forwards the metadata of the built source.
+func (s *deferredSource) Metadata() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ms, ok := s.built.(MetadataSource); ok {
+		return ms.Metadata()
+	}
+	return nil
+}
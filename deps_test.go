@@ -0,0 +1,77 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDeferred(t *testing.T) {
+	var addr string
+	vault := Deferred("vault", []string{"app.json"}, func(deps *slowjson.Node) (Source, error) {
+		n := deps.Get("vault.addr")
+		if n == nil {
+			return nil, fmt.Errorf("vault.addr is not set")
+		}
+		addr = n.Value
+		return Bytes("vault", []byte(`{"db": {"password": "s3cret"}}`)), nil
+	})
+	// the dependent source is listed first, so it is merged first but loaded last
+	c := NewConfig(vault, Bytes("app.json", []byte(`{"vault": {"addr": "https://vault:8200"}, "db": {"user": "app"}}`)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if addr != "https://vault:8200" {
+		t.Errorf("build got addr %q", addr)
+	}
+	if got := c.Get("db.password"); got == nil || got.Value != "s3cret" {
+		t.Errorf("db.password = %v", got)
+	}
+	if o, _ := c.Origin("db.password"); o.Layer != "vault" {
+		t.Errorf("Origin() = %v", o)
+	}
+
+	if _, err := vault.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "load it through a Config") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestLoadOrder(t *testing.T) {
+	dep := func(name string, deps ...string) Source {
+		return Deferred(name, deps, func(*slowjson.Node) (Source, error) { return Bytes(name, []byte(`{}`)), nil })
+	}
+	file := Bytes("file", []byte(`{}`))
+	tests := []struct {
+		name      string
+		sources   []Source
+		want      string
+		wantError string
+	}{
+		{"no dependencies", []Source{file, Bytes("env", nil)}, "0,1", ""},
+		{"dependency later", []Source{dep("vault", "file"), file}, "1,0", ""},
+		{"chain", []Source{dep("b", "a"), dep("a", "file"), file}, "2,1,0", ""},
+		{"unknown", []Source{dep("vault", "consul")}, "", "source vault depends on unknown source consul"},
+		{"cycle", []Source{dep("a", "b"), dep("b", "c"), dep("c", "b")}, "", "source dependency cycle: b -> c -> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := LoadOrder(tt.sources)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("LoadOrder() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(order)), ","), "[]")
+			if got != tt.want {
+				t.Errorf("LoadOrder() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package diag
+
+import (
+	"strings"
+)
+
+// Stable codes of the diagnostics reported by this module. A code never changes meaning once released,
+// so configs can suppress findings by code and documentation can link to them.
+// The thousands group the area: 1 structure, 2 values, 3 secrets, 4 merging, 5 policy.
+const (
+	CodeSyntax            = "TC1000"
+	CodeDuplicateKey      = "TC1001"
+	CodeEmptyValue        = "TC1002"
+	CodeUnknownKey        = "TC1003"
+	CodeSimilarKey        = "TC1004"
+	CodeUnusedSuppression = "TC1005"
+	CodeLimit             = "TC1006"
+	CodeUnknownKind       = "TC1007"
+	CodeInvalidValue      = "TC2001"
+	CodeCoercedValue      = "TC2002"
+	CodeTypeMismatch      = "TC2003"
+	CodeInvalidEnum       = "TC2004"
+	CodeConstraint        = "TC2005"
+	CodeMissingKey        = "TC2006"
+	CodeDuplicateItem     = "TC2007"
+	CodeInvalidFormat     = "TC2008"
+	CodeVersion           = "TC2009"
+	CodeRepeatedValue     = "TC2010"
+	CodePlaintextSecret   = "TC3001"
+	CodeSecret            = "TC3002"
+	CodeFinalKey          = "TC4001"
+	CodeDeadReference     = "TC4002"
+	CodeMergeConflict     = "TC4003"
+	CodePolicy            = "TC5001"
+)
+
+// DocsURL is the page explaining every code, CodeInfo.URL links to its sections.
+var DocsURL = "https://github.com/at15/tracedconfig/blob/main/docs/codes.md"
+
+// CodeInfo documents a diagnostic code.
+type CodeInfo struct {
+	Code string
+	// Name is the short name of the check, e.g. the lint rule "duplicate-key".
+	Name    string
+	Summary string
+}
+
+// URL links to the explanation of the code.
+func (c CodeInfo) URL() string {
+	return DocsURL + "#" + strings.ToLower(c.Code)
+}
+
+var codes = []CodeInfo{
+	{CodeSyntax, "syntax", "the input is not valid JSON"},
+	{CodeDuplicateKey, "duplicate-key", "a key is defined twice in the same object, only the last one is used"},
+	{CodeEmptyValue, "empty-value", "a value is null or an empty string, array or object"},
+	{CodeUnknownKey, "unknown-key", "a key is not in the schema and has no effect"},
+	{CodeSimilarKey, "similar-key", "a key is not in the schema but close to one that is, likely a typo"},
+	{CodeUnusedSuppression, "unused-suppression", "a suppression comment silences nothing"},
+	{CodeLimit, "limit", "a document is larger or deeper than its source allows"},
+	{CodeUnknownKind, "unknown-kind", "a Kubernetes manifest has an apiVersion and kind the OpenAPI spec has no schema for"},
+	{CodeInvalidValue, "invalid-value", "a value cannot be decoded, e.g. a number out of range"},
+	{CodeCoercedValue, "coerced-value", "a value of the wrong type was converted, e.g. the string \"80\" to a number"},
+	{CodeTypeMismatch, "type-mismatch", "a value has a different type than expected"},
+	{CodeInvalidEnum, "invalid-enum", "a value is not one of the allowed values"},
+	{CodeConstraint, "constraint", "a value violates a schema constraint, e.g. a range or pattern"},
+	{CodeMissingKey, "missing-key", "a required key is missing"},
+	{CodeDuplicateItem, "duplicate-item", "an array item, or a field of it, must be unique but repeats an earlier one"},
+	{CodeInvalidFormat, "invalid-format", "a string is not of its format, e.g. an email address or a hostname"},
+	{CodeVersion, "config-version", "the configVersion of a document is outside the range the program reads"},
+	{CodeRepeatedValue, "repeated-value", "the same value is pasted in many places instead of defined once"},
+	{CodePlaintextSecret, "plaintext-secret", "a key named like a secret has a literal value"},
+	{CodeSecret, "secret", "a value looks like a credential, e.g. an access key"},
+	{CodeFinalKey, "final-key", "a layer overrides a key an earlier layer declared @final"},
+	{CodeDeadReference, "dead-reference", "a ${ref} interpolation or a YAML alias points at nothing after merging the layers"},
+	{CodeMergeConflict, "merge-conflict", "two layers set a key to different values in a strict merge without an @override"},
+	{CodePolicy, "policy", "a policy rule is not satisfied"},
+}
+
+// Codes returns every code in order.
+func Codes() []CodeInfo {
+	return append([]CodeInfo(nil), codes...)
+}
+
+// LookupCode finds a code by its code, e.g. "TC1001", or its name, e.g. "duplicate-key".
+func LookupCode(codeOrName string) (CodeInfo, bool) {
+	for _, c := range codes {
+		if strings.EqualFold(c.Code, codeOrName) || c.Name == codeOrName {
+			return c, true
+		}
+	}
+	return CodeInfo{}, false
+}
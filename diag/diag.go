@@ -0,0 +1,249 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Severity is how serious a diagnostic is.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Span is a range in a source file, lines and columns are 1-based.
+// Binary sources only have offsets.
+type Span struct {
+	File        string
+	StartLine   int
+	StartCol    int
+	EndLine     int
+	EndCol      int
+	StartOffset int
+	EndOffset   int
+}
+
+// SpanOf returns the span covered by the node.
+func SpanOf(n *slowjson.Node) Span {
+	return Span{
+		File:        n.File,
+		StartLine:   n.StartLine,
+		StartCol:    n.StartCol,
+		EndLine:     n.EndLine,
+		EndCol:      n.EndCol,
+		StartOffset: n.StartOffset,
+		EndOffset:   n.EndOffset,
+	}
+}
+
+// String formats the start of the span as file:line:col.
+func (s Span) String() string {
+	var pos string
+	if s.StartLine > 0 {
+		pos = fmt.Sprintf("%d:%d", s.StartLine, s.StartCol)
+	} else {
+		pos = fmt.Sprintf("offset %d", s.StartOffset)
+	}
+	if s.File == "" {
+		return pos
+	}
+	if s.StartLine > 0 {
+		return s.File + ":" + pos
+	}
+	return s.File + " " + pos
+}
+
+// Diagnostic is a message about a node in the config.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	// Path is the key path of the node, see slowjson.Path.
+	Path string
+	Span Span
+	// Node is the node the diagnostic is about, it is used to render source context and may be nil.
+	Node *slowjson.Node
+	// Code identifies the check that produced the diagnostic, a stable code like CodeDuplicateKey for
+	// the checks of this module or the name of a custom lint rule. It may be empty.
+	Code string
+	// Related points at other nodes involved, e.g. the first definition of a duplicate.
+	Related []Related
+	// Fix resolves the diagnostic when the intent is obvious, e.g. the string "true" where a boolean
+	// is expected. It is nil otherwise.
+	Fix *Fix
+}
+
+// Fix is a machine-applicable change: the node of the diagnostic is replaced with Value. Tools write
+// Value in the format of the file, only replacing the text of the node so the rest keeps its formatting.
+type Fix struct {
+	// Message describes the change, e.g. "replace with true".
+	Message string
+	Value   *slowjson.Node
+}
+
+// NewFix creates a fix replacing a node with value.
+func NewFix(value *slowjson.Node) *Fix {
+	text, err := slowjson.Marshal(value)
+	if err != nil {
+		text = []byte(value.Value)
+	}
+	return &Fix{Message: "replace with " + string(text), Value: value}
+}
+
+// Related is a note about another node involved in a diagnostic.
+type Related struct {
+	Message string
+	Span    Span
+	Node    *slowjson.Node
+}
+
+// RelatedTo creates a note about n.
+func RelatedTo(n *slowjson.Node, format string, args ...interface{}) Related {
+	return Related{Message: fmt.Sprintf(format, args...), Span: SpanOf(n), Node: n}
+}
+
+// New creates a diagnostic about n.
+func New(n *slowjson.Node, severity Severity, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Path:     n.Path().String(),
+		Span:     SpanOf(n),
+		Node:     n,
+	}
+}
+
+// Errorf creates an error diagnostic about n.
+func Errorf(n *slowjson.Node, format string, args ...interface{}) Diagnostic {
+	return New(n, SeverityError, format, args...)
+}
+
+// Warningf creates a warning diagnostic about n.
+func Warningf(n *slowjson.Node, format string, args ...interface{}) Diagnostic {
+	return New(n, SeverityWarning, format, args...)
+}
+
+// String formats the diagnostic on one line, e.g. "config.json:3:5: warning: server.port: message [TC1004 similar-key]".
+// Known codes are followed by their name.
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	sb.WriteString(d.Span.String())
+	sb.WriteString(": ")
+	sb.WriteString(d.Severity.String())
+	sb.WriteString(": ")
+	if d.Path != "" {
+		sb.WriteString(d.Path)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Message)
+	if d.Code != "" {
+		sb.WriteString(" [")
+		sb.WriteString(d.Code)
+		if info, ok := LookupCode(d.Code); ok && info.Code == d.Code {
+			sb.WriteString(" ")
+			sb.WriteString(info.Name)
+		}
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+// Render formats the diagnostic followed by the source lines around the node, when available,
+// and every related note as "file:line:col: note: message" with its source lines. When the related
+// nodes are in the same source as the node, e.g. two fields of a cross-field rule, the notes are
+// followed by a single snippet pointing at all of them.
+// A fix is described as "fix: message".
+// Known codes end with a link to their explanation.
+func (d Diagnostic) Render(linesBefore, linesAfter int) string {
+	s := d.String() + "\n"
+	if source, ok := d.sharedSource(); ok {
+		marks := []mark{markOf(d.Node, d.Severity.String())}
+		for _, r := range d.Related {
+			s += r.Span.String() + ": note: " + r.Message + "\n"
+			if r.Node != nil && r.Node.Source != "" {
+				marks = append(marks, markOf(r.Node, r.Message))
+			}
+		}
+		s += snippet(source, marks, linesBefore, linesAfter)
+	} else {
+		if d.Node != nil && d.Node.Source != "" {
+			s += d.Node.DebugContext(linesBefore, linesAfter)
+		}
+		for _, r := range d.Related {
+			s += r.Span.String() + ": note: " + r.Message + "\n"
+			if r.Node != nil && r.Node.Source != "" {
+				s += r.Node.DebugContext(linesBefore, linesAfter)
+			}
+		}
+	}
+	if d.Fix != nil {
+		s += "fix: " + d.Fix.Message + "\n"
+	}
+	if info, ok := LookupCode(d.Code); ok && info.Code == d.Code {
+		s += "see " + info.URL() + "\n"
+	}
+	return s
+}
+
+// FromError converts an error from parsing or decoding to diagnostics, one per error of a
+// slowjson.MultiError. Syntax errors get CodeSyntax, and validation errors their own code or CodeInvalidValue.
+func FromError(err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	var m *slowjson.MultiError
+	if errors.As(err, &m) {
+		var diags []Diagnostic
+		for _, e := range m.Errors {
+			diags = append(diags, FromError(e)...)
+		}
+		return diags
+	}
+	var verr *slowjson.ValidationError
+	if errors.As(err, &verr) && verr.Node != nil {
+		d := New(verr.Node, SeverityError, "%v", verr.Err)
+		d.Code = verr.Code
+		if d.Code == "" {
+			d.Code = CodeInvalidValue
+		}
+		return []Diagnostic{d}
+	}
+	d := Diagnostic{Severity: SeverityError, Message: err.Error()}
+	var perr *slowjson.ParseError
+	if errors.As(err, &perr) {
+		pos := perr.Pos
+		d.Message = perr.Msg
+		d.Code = CodeSyntax
+		d.Span = Span{File: pos.File, StartLine: pos.Line, StartCol: pos.Col, EndLine: pos.Line, EndCol: pos.Col,
+			StartOffset: pos.Offset, EndOffset: pos.Offset}
+	}
+	return []Diagnostic{d}
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDiagnostic_String(t *testing.T) {
+	p := slowjson.NewParser("{\n  \"server\": {\"port\": \"80\"}\n}")
+	p.File = "config.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	port := root.Get("server.port")
+	d := Warningf(port, "coerced %q to int", port.Value)
+	if got, want := d.String(), `config.json:2:22: warning: server.port: coerced "80" to int`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	d.Code = "coerce"
+	if got := d.String(); !strings.HasSuffix(got, "to int [coerce]") {
+		t.Errorf("String() with code = %q", got)
+	}
+	r := d.Render(0, 0)
+	if !strings.Contains(r, `2:   "server": {"port": "80"}`) || !strings.Contains(r, "^ start") {
+		t.Errorf("Render() = %q", r)
+	}
+	if HasErrors([]Diagnostic{d}) || !HasErrors([]Diagnostic{d, Errorf(root, "bad")}) {
+		t.Error("HasErrors() got wrong result")
+	}
+}
+
+func TestSpan_String(t *testing.T) {
+	tests := []struct {
+		span Span
+		want string
+	}{
+		{Span{StartLine: 1, StartCol: 2}, "1:2"},
+		{Span{File: "a.json", StartLine: 1, StartCol: 2}, "a.json:1:2"},
+		{Span{StartOffset: 9}, "offset 9"},
+		{Span{File: "a.msgpack", StartOffset: 9}, "a.msgpack offset 9"},
+	}
+	for _, tt := range tests {
+		if got := tt.span.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLookupCode(t *testing.T) {
+	for _, key := range []string{"TC1001", "tc1001", "duplicate-key"} {
+		info, ok := LookupCode(key)
+		if !ok || info.Code != CodeDuplicateKey || info.Name != "duplicate-key" {
+			t.Errorf("LookupCode(%q) = %+v, %v", key, info, ok)
+		}
+	}
+	if _, ok := LookupCode("no-fixme"); ok {
+		t.Error("LookupCode(no-fixme) found a code")
+	}
+	seen := map[string]bool{}
+	for _, c := range Codes() {
+		if seen[c.Code] || seen[c.Name] {
+			t.Errorf("code %s %s registered twice", c.Code, c.Name)
+		}
+		seen[c.Code], seen[c.Name] = true, true
+	}
+	if got, want := (CodeInfo{Code: "TC1001"}).URL(), DocsURL+"#tc1001"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+}
+
+func TestDiagnostic_RenderCode(t *testing.T) {
+	root, err := slowjson.NewParser(`{"a": 1}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Errorf(root.Get("a"), "duplicate")
+	d.Code = CodeDuplicateKey
+	if got := d.String(); !strings.HasSuffix(got, "duplicate [TC1001 duplicate-key]") {
+		t.Errorf("String() = %q", got)
+	}
+	if got := d.Render(0, 0); !strings.HasSuffix(got, "see "+DocsURL+"#tc1001\n") {
+		t.Errorf("Render() = %q", got)
+	}
+}
+
+func TestDiagnostic_RenderFix(t *testing.T) {
+	root, err := slowjson.NewParser(`{"debug": "true"}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Errorf(root.Get("debug"), "expected boolean, got string")
+	d.Fix = NewFix(&slowjson.Node{Type: slowjson.NodeBoolean, Value: "true"})
+	if got := d.Render(0, 0); !strings.HasSuffix(got, "fix: replace with true\n") {
+		t.Errorf("Render() = %q", got)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	p := slowjson.NewParser(`{"a": tru}`)
+	p.File = "app.json"
+	_, err := p.Parse()
+	diags := FromError(err)
+	if len(diags) != 1 || diags[0].String() != "app.json:1:7: error: invalid boolean [TC1000 syntax]" {
+		t.Errorf("FromError(parse error) = %v", diags)
+	}
+
+	root, err := slowjson.NewParser(`{"a": 1, "b": 2}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatch := root.Get("b").Errorf("expected string")
+	mismatch.(*slowjson.ValidationError).Code = CodeTypeMismatch
+	diags = FromError(slowjson.Join(root.Get("a").Errorf("out of range"), mismatch))
+	want := []string{
+		"1:7: error: a: out of range [TC2001 invalid-value]",
+		"1:15: error: b: expected string [TC2003 type-mismatch]",
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("FromError() = %v, want %v", diags, want)
+	}
+	for i, d := range diags {
+		if d.String() != want[i] {
+			t.Errorf("FromError()[%d] = %q, want %q", i, d, want[i])
+		}
+	}
+	if FromError(nil) != nil {
+		t.Error("FromError(nil) != nil")
+	}
+}
+
+func TestRender_SharedSource(t *testing.T) {
+	lines := make([]string, 12)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`  "k%d": %d,`, i, i)
+	}
+	input := "{\n" + strings.Join(lines, "\n") + "\n  \"end\": 0\n}"
+	p := slowjson.NewParser(input)
+	p.File = "c.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Errorf(root.Get("k0"), "k0 must be below k10")
+	d.Related = []Related{RelatedTo(root.Get("k10"), "k10 is here"), RelatedTo(root.Get("k1"), "so is k1")}
+	want := `c.json:2:9: error: k0: k0 must be below k10
+c.json:12:10: note: k10 is here
+c.json:3:9: note: so is k1
+ 1: {
+ 2:   "k0": 0,
+            ^ error
+ 3:   "k1": 1,
+            ^ so is k1
+ 4:   "k2": 2,
+  ...
+11:   "k9": 9,
+12:   "k10": 10,
+             ^ k10 is here
+13:   "k11": 11,
+`
+	if got := d.Render(1, 1); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+
+	// related nodes of another source keep their own context
+	other, _ := slowjson.NewParser(`{"x": 1}`).Parse()
+	d.Related = []Related{RelatedTo(other.Get("x"), "elsewhere")}
+	if got := d.Render(0, 0); !strings.Contains(got, "^ start") || strings.Contains(got, "^ error") {
+		t.Errorf("Render() =\n%s", got)
+	}
+}
@@ -0,0 +1,67 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteGitHub writes the diagnostics as GitHub Actions workflow commands, e.g.
+//
+//	::error file=app.json,line=3,col=5,endLine=3,endColumn=11,title=TC1001 duplicate-key::port: duplicate key "port"
+//
+// so they show inline on pull requests. Errors, warnings and infos become error, warning and notice annotations.
+func WriteGitHub(w io.Writer, diags []Diagnostic) error {
+	for _, d := range diags {
+		if _, err := io.WriteString(w, githubCommand(d)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func githubCommand(d Diagnostic) string {
+	level := "error"
+	switch d.Severity {
+	case SeverityWarning:
+		level = "warning"
+	case SeverityInfo:
+		level = "notice"
+	}
+	var props []string
+	if d.Span.File != "" {
+		props = append(props, "file="+githubEscapeProperty(d.Span.File))
+	}
+	if s := d.Span; s.StartLine > 0 {
+		props = append(props, fmt.Sprintf("line=%d,col=%d", s.StartLine, s.StartCol))
+		if s.EndLine > 0 {
+			props = append(props, fmt.Sprintf("endLine=%d,endColumn=%d", s.EndLine, s.EndCol))
+		}
+	}
+	if d.Code != "" {
+		title := d.Code
+		if info, ok := LookupCode(d.Code); ok && info.Code == d.Code {
+			title += " " + info.Name
+		}
+		props = append(props, "title="+githubEscapeProperty(title))
+	}
+	msg := d.Message
+	if d.Path != "" {
+		msg = d.Path + ": " + msg
+	}
+	cmd := "::" + level
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + githubEscapeData(msg)
+}
+
+// githubEscapeData escapes the message of a workflow command.
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubEscapeProperty escapes a property value of a workflow command.
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,31 @@
+package diag
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	p := slowjson.NewParser("{\n  \"port\": 80,\n  \"port\": 81\n}")
+	p.File = "config/app,v1.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup := Errorf(root.Children[1], "duplicate key %q\nfirst at 2:3", "port")
+	dup.Code = CodeDuplicateKey
+	info := New(root.Children[1].Children[0], SeverityInfo, "100%% fine")
+	info.Code = "custom"
+	var buf bytes.Buffer
+	if err := WriteGitHub(&buf, []Diagnostic{dup, info, {Severity: SeverityWarning, Message: "no position"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=config/app%2Cv1.json,line=3,col=3,endLine=3,endColumn=9,title=TC1001 duplicate-key::port: duplicate key \"port\"%0Afirst at 2:3\n" +
+		"::notice file=config/app%2Cv1.json,line=3,col=11,endLine=3,endColumn=13,title=custom::port: 100%25 fine\n" +
+		"::warning::no position\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteGitHub() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package diag
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// FileReport is the diagnostics of one checked file.
+type FileReport struct {
+	File        string
+	Diagnostics []Diagnostic
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the reports as a JUnit XML document that CI systems like Jenkins and GitLab show in
+// their test views. Every file is a test suite and every diagnostic a test case named after its code and
+// path, a file without diagnostics has a single passing case. Diagnostics at least as severe as failAt
+// are failures, the others pass and keep their message in the case output.
+func WriteJUnit(w io.Writer, name string, reports []FileReport, failAt Severity) error {
+	doc := junitSuites{Name: name}
+	for _, r := range reports {
+		suite := junitSuite{Name: r.File}
+		for _, d := range r.Diagnostics {
+			c := junitCase{Name: junitCaseName(d), ClassName: r.File}
+			if d.Severity <= failAt {
+				c.Failure = &junitFailure{Message: d.Message, Type: d.Severity.String(), Text: d.Render(1, 1)}
+				suite.Failures++
+			} else {
+				c.SystemOut = d.String()
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitCase{Name: "no findings", ClassName: r.File})
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, b)
+	return err
+}
+
+// junitCaseName names a case like "TC1001 server.port at 3:5", unique enough for test views to tell cases apart.
+func junitCaseName(d Diagnostic) string {
+	name := d.Code
+	if name == "" {
+		name = d.Severity.String()
+	}
+	if d.Path != "" {
+		name += " " + d.Path
+	}
+	pos := d.Span
+	pos.File = ""
+	return name + " at " + pos.String()
+}
@@ -0,0 +1,45 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	p := slowjson.NewParser(`{"port": 80, "port": "<81>"}`)
+	p.File = "app.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup := Errorf(root.Children[1], "duplicate key")
+	dup.Code = CodeDuplicateKey
+	empty := Warningf(root.Children[1].Children[0], "suspicious <value>")
+
+	var buf bytes.Buffer
+	reports := []FileReport{{File: "app.json", Diagnostics: []Diagnostic{dup, empty}}, {File: "ok.json"}}
+	if err := WriteJUnit(&buf, "lint", reports, SeverityError); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<testsuites name="lint" tests="3" failures="1">`,
+		`<testsuite name="app.json" tests="2" failures="1">`,
+		`<testcase name="TC1001 port at 1:14" classname="app.json">`,
+		`<failure message="duplicate key" type="error">app.json:1:14: error: port: duplicate key [TC1001 duplicate-key]`,
+		`<system-out>app.json:1:22: warning: port: suspicious &lt;value&gt;</system-out>`,
+		`<testcase name="no findings" classname="ok.json"></testcase>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteJUnit() missing %s:\n%s", want, out)
+		}
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Errorf("WriteJUnit() wrote invalid XML: %v", err)
+	}
+}
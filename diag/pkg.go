@@ -0,0 +1,3 @@
+// Package diag defines diagnostics, findings about a config tied to a position in its source.
+// Decoders, validators and linters report diagnostics so they can be rendered and filtered the same way.
+package diag
@@ -0,0 +1,78 @@
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// mark points at the start of a span in a snippet.
+type mark struct {
+	line, col int
+	label     string
+}
+
+// snippet renders the lines of source around every mark, each line followed by a caret under the
+// marks starting on it. Gaps between the shown lines are elided with "...".
+func snippet(source string, marks []mark, linesBefore, linesAfter int) string {
+	lines := strings.Split(source, "\n")
+	type interval struct{ from, to int }
+	var shown []interval
+	sort.SliceStable(marks, func(i, j int) bool {
+		if marks[i].line != marks[j].line {
+			return marks[i].line < marks[j].line
+		}
+		return marks[i].col < marks[j].col
+	})
+	for _, m := range marks {
+		iv := interval{max(m.line-linesBefore, 1), min(m.line+linesAfter, len(lines))}
+		if k := len(shown) - 1; k >= 0 && iv.from <= shown[k].to+1 {
+			shown[k].to = max(shown[k].to, iv.to)
+			continue
+		}
+		shown = append(shown, iv)
+	}
+	if len(shown) == 0 {
+		return ""
+	}
+	width := len(fmt.Sprint(shown[len(shown)-1].to))
+	var sb strings.Builder
+	for i, iv := range shown {
+		if i > 0 {
+			fmt.Fprintf(&sb, "%*s\n", width+3, "...")
+		}
+		for l := iv.from; l <= iv.to; l++ {
+			fmt.Fprintf(&sb, "%*d: %s\n", width, l, lines[l-1])
+			for _, m := range marks {
+				if m.line == l {
+					fmt.Fprintf(&sb, "%s^ %s\n", strings.Repeat(" ", width+2+m.col-1), m.label)
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// sharedSource returns the source of d.Node when every related node with a source was parsed from it
+// too, so they can be shown in one snippet.
+func (d Diagnostic) sharedSource() (string, bool) {
+	if d.Node == nil || d.Node.Source == "" || d.Node.StartLine == 0 || len(d.Related) == 0 {
+		return "", false
+	}
+	for _, r := range d.Related {
+		if r.Node == nil || r.Node.Source == "" {
+			continue
+		}
+		if r.Node.Source != d.Node.Source || r.Node.File != d.Node.File {
+			return "", false
+		}
+	}
+	return d.Node.Source, true
+}
+
+// markOf points at the start of n.
+func markOf(n *slowjson.Node, label string) mark {
+	return mark{line: n.StartLine, col: n.StartCol, label: label}
+}
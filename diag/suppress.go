@@ -0,0 +1,156 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// SuppressDirective starts a comment that silences findings on the node after it, e.g.
+//
+//	// tracedconfig:ignore TC1001 TC1004 -- kept for old clients
+//	"port": 80,
+//
+// Codes are separated by spaces or commas and may be names like "duplicate-key", text after "--" is
+// a free form reason. Without codes every finding on the node is silenced. YAML and TOML files use
+// "# tracedconfig:ignore", INI files "#" or ";", on the line before the key.
+// The directive covers the node and everything below it, before the root value it covers the whole file.
+const SuppressDirective = "tracedconfig:ignore"
+
+// Suppression is a suppression comment found in a tree.
+type Suppression struct {
+	// Codes are the codes it silences as written, empty for every code.
+	Codes  []string
+	Reason string
+	// Node is the node the comment is attached to.
+	Node *slowjson.Node
+	// Span is the position of the comment.
+	Span Span
+	// Used counts the diagnostics it silenced.
+	Used int
+}
+
+// matches reports whether the suppression silences d.
+func (s *Suppression) matches(d Diagnostic) bool {
+	if !s.covers(d.Node) {
+		return false
+	}
+	if len(s.Codes) == 0 {
+		return true
+	}
+	for _, code := range s.Codes {
+		if code == d.Code {
+			return true
+		}
+		if info, ok := LookupCode(code); ok && info.Code == d.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether n is the suppressed node or below it.
+func (s *Suppression) covers(n *slowjson.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n == s.Node {
+			return true
+		}
+	}
+	return false
+}
+
+// Suppressions finds the suppression comments in the tree, in source order.
+func Suppressions(root *slowjson.Node) []*Suppression {
+	var sups []*Suppression
+	var walk func(n *slowjson.Node)
+	walk = func(n *slowjson.Node) {
+		for _, t := range n.Leading {
+			if s, ok := parseSuppression(t); ok {
+				s.Node = n
+				s.Span.File = n.File
+				sups = append(sups, s)
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return sups
+}
+
+// parseSuppression parses a suppression comment, "// tracedconfig:ignore ..." or "/* tracedconfig:ignore ... */".
+// "#" and ";" comments are accepted as well, the parsers of YAML, TOML and INI attach them to nodes.
+func parseSuppression(t slowjson.Trivia) (*Suppression, bool) {
+	if !t.IsComment() {
+		return nil, false
+	}
+	text := strings.TrimSuffix(t.Text, "*/")
+	for _, marker := range []string{"//", "/*", "#", ";"} {
+		if strings.HasPrefix(text, marker) {
+			text = text[len(marker):]
+			break
+		}
+	}
+	text = strings.TrimSpace(text)
+	if text != SuppressDirective && !strings.HasPrefix(text, SuppressDirective+" ") {
+		return nil, false
+	}
+	text, reason, _ := strings.Cut(text[len(SuppressDirective):], "--")
+	s := &Suppression{
+		Codes:  strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }),
+		Reason: strings.TrimSpace(reason),
+		Span: Span{
+			StartLine:   t.StartLine,
+			StartCol:    t.StartCol,
+			EndLine:     t.StartLine,
+			EndCol:      t.StartCol + len(t.Text),
+			StartOffset: t.StartOffset,
+			EndOffset:   t.StartOffset + len(t.Text),
+		},
+	}
+	return s, true
+}
+
+// Suppress drops the diagnostics silenced by a suppression and counts them in its Used.
+func Suppress(diags []Diagnostic, sups []*Suppression) []Diagnostic {
+	var kept []Diagnostic
+	for _, d := range diags {
+		silenced := false
+		for _, s := range sups {
+			if s.matches(d) {
+				s.Used++
+				silenced = true
+				break
+			}
+		}
+		if !silenced {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// Unused reports the suppressions that silenced nothing, with CodeUnusedSuppression.
+// Stale suppressions hide nothing today but would hide new findings, so they are worth removing.
+func Unused(sups []*Suppression) []Diagnostic {
+	var diags []Diagnostic
+	for _, s := range sups {
+		if s.Used > 0 {
+			continue
+		}
+		what := "every code"
+		if len(s.Codes) > 0 {
+			what = strings.Join(s.Codes, ", ")
+		}
+		diags = append(diags, Diagnostic{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("unused suppression of %s", what),
+			Path:     s.Node.Path().String(),
+			Span:     s.Span,
+			Code:     CodeUnusedSuppression,
+		})
+	}
+	return diags
+}
@@ -0,0 +1,70 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestSuppressions(t *testing.T) {
+	input := `{
+  // tracedconfig:ignore TC1001, similar-key -- legacy
+  "a": {"b": 1},
+  /* tracedconfig:ignore */
+  "c": [
+    // tracedconfig:ignore TC1002
+    2
+  ],
+  // not a tracedconfig:ignore directive
+  "d": 3,
+  // tracedconfig:ignored
+  "e": 4
+}`
+	root, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sups := Suppressions(root)
+	if len(sups) != 3 {
+		t.Fatalf("Suppressions() found %d, want 3", len(sups))
+	}
+	if got := strings.Join(sups[0].Codes, ","); got != "TC1001,similar-key" || sups[0].Reason != "legacy" || sups[0].Node.Path().String() != "a" {
+		t.Errorf("Suppressions()[0] = %+v", sups[0])
+	}
+	if sups[0].Span.String() != "2:3" {
+		t.Errorf("Span = %s, want 2:3", sups[0].Span)
+	}
+	if len(sups[1].Codes) != 0 || sups[1].Node.Path().String() != "c" {
+		t.Errorf("Suppressions()[1] = %+v", sups[1])
+	}
+	if sups[2].Node.Path().String() != "c[0]" {
+		t.Errorf("Suppressions()[2] on %s, want c[0]", sups[2].Node.Path())
+	}
+
+	withCode := func(path, code string) Diagnostic {
+		d := Warningf(root.Get(path), "finding")
+		d.Code = code
+		return d
+	}
+	diags := Suppress([]Diagnostic{
+		withCode("a.b", CodeSimilarKey),
+		withCode("a.b", CodeEmptyValue),
+		withCode("c", "custom"),
+		withCode("d", CodeDuplicateKey),
+	}, sups)
+	var kept []string
+	for _, d := range diags {
+		kept = append(kept, d.Path+" "+d.Code)
+	}
+	if got := strings.Join(kept, ","); got != "a.b TC1002,d TC1001" {
+		t.Errorf("Suppress() kept %s", got)
+	}
+	if sups[0].Used != 1 || sups[1].Used != 1 || sups[2].Used != 0 {
+		t.Errorf("Used = %d %d %d, want 1 1 0", sups[0].Used, sups[1].Used, sups[2].Used)
+	}
+	unused := Unused(sups)
+	if len(unused) != 1 || unused[0].String() != "6:5: warning: c[0]: unused suppression of TC1002 [TC1005 unused-suppression]" {
+		t.Errorf("Unused() = %v", unused)
+	}
+}
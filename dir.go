@@ -0,0 +1,62 @@
+package tracedconfig
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// DirSource loads every matching file of a directory, e.g. a conf.d directory, merged in lexical order
+// so later files override earlier ones. Nodes keep the file they were parsed from for provenance.
+type DirSource struct {
+	Path string
+	// Pattern selects the files, "*.json" when empty, see filepath.Match.
+	Pattern string
+	// Concurrency is how many files are parsed at a time, GOMAXPROCS when zero. The result does not
+	// depend on it.
+	Concurrency int
+	// Merge configures how the files are merged.
+	Merge merge.Options
+}
+
+// Dir creates a source loading the JSON files in dir.
+func Dir(dir string) *DirSource {
+	return &DirSource{Path: dir}
+}
+
+// Name returns the directory.
+func (s *DirSource) Name() string {
+	return s.Path
+}
+
+// Load parses the matching files and merges them. An empty directory loads as an empty object.
+func (s *DirSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	pattern := s.Pattern
+	if pattern == "" {
+		pattern = "*.json"
+	}
+	paths, err := filepath.Glob(filepath.Join(s.Path, pattern))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := slowjson.ParseFilesFunc(paths, s.Concurrency, func(path string) ([]byte, error) {
+		return ReadFile(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return &slowjson.Node{Type: slowjson.NodeObject, File: s.Path}, nil
+	}
+	layers := make([]merge.Layer, len(nodes))
+	for i, n := range nodes {
+		layers[i] = merge.Layer{Name: paths[i], Root: n}
+	}
+	res, err := s.Merge.Merge(layers...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Root, nil
+}
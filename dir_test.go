@@ -0,0 +1,45 @@
+package tracedconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-defaults.json": `{"port": 80, "log": {"level": "info"}}`,
+		"20-site.json":     `{"log": {"level": "debug"}}`,
+		"30-local.json":    `{"port": 8080}`,
+		"notes.txt":        `not config`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, concurrency := range []int{0, 1} {
+		src := Dir(dir)
+		src.Concurrency = concurrency
+		c := NewConfig(src)
+		if err := c.Load(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if port := c.Get("port"); port == nil || port.Value != "8080" || filepath.Base(port.File) != "30-local.json" {
+			t.Errorf("port = %+v, want 8080 from 30-local.json", port)
+		}
+		if level := c.Get("log.level"); level == nil || level.Value != "debug" {
+			t.Errorf("log.level = %+v, want debug", level)
+		}
+	}
+
+	empty := Dir(t.TempDir())
+	n, err := empty.Load(context.Background())
+	if err != nil || n.Type != slowjson.NodeObject || len(n.Children) != 0 {
+		t.Errorf("Load() of an empty directory = %+v, %v", n, err)
+	}
+}
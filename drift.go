@@ -0,0 +1,161 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Drift is the difference between a config struct and the config files written for it.
+type Drift struct {
+	// UnreadKeys are keys of the files no field decodes, so they have no effect, in file order.
+	UnreadKeys []UnreadKey
+	// UnsetFields are fields no file sets, so they keep their defaults, in field order. Only the
+	// outermost field of an unset struct is listed.
+	UnsetFields []UnsetField
+}
+
+// UnreadKey is a key of a config file that no field of the struct decodes.
+type UnreadKey struct {
+	// Path is the path of the key in its file, e.g. "servers[1].hots".
+	Path string
+	Key  *slowjson.Node
+}
+
+func (k UnreadKey) String() string {
+	return fmt.Sprintf("%s: %s is not read by any field", k.Key.Location(), k.Path)
+}
+
+// UnsetField is a field of the config struct that no config file sets.
+type UnsetField struct {
+	// Path is the config path of the field, "[*]" stands for every element of an array and ".*" for
+	// every value of a map, e.g. "servers[*].timeout".
+	Path string
+	// Field is the Go field, qualified by the struct type declaring it, e.g. "ServerConfig.Timeout".
+	Field string
+}
+
+func (f UnsetField) String() string {
+	return fmt.Sprintf("%s (%s) is not set by any file", f.Field, f.Path)
+}
+
+// CheckDrift compares the fields of the config struct v with the keys of the config files, to find keys
+// nobody reads, e.g. left over from a removed feature or misspelled, and fields nothing sets, e.g.
+// added to the struct but never rolled out. Keys match fields like in Decode. Values decoded by a
+// registered decoder, an unmarshaler or into an interface count as read as a whole.
+func CheckDrift(v interface{}, files ...*slowjson.Node) (*Drift, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("cannot check drift for nil")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot check drift for %s, expected a struct", t)
+	}
+	d := &Drift{}
+	d.walk(t, "", files)
+	sort.SliceStable(d.UnreadKeys, func(i, j int) bool {
+		a, b := d.UnreadKeys[i].Key, d.UnreadKeys[j].Key
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.StartOffset < b.StartOffset
+	})
+	return d, nil
+}
+
+// walk matches the values nodes, which are all the values for t found in the files, against t.
+func (d *Drift) walk(t reflect.Type, path string, nodes []*slowjson.Node) {
+	// like Decode, a registered decoder wins over following the pointer
+	for {
+		if _, ok := lookupDecoder(t); ok {
+			return
+		}
+		if t.Kind() != reflect.Ptr {
+			break
+		}
+		t = t.Elem()
+	}
+	if pt := reflect.PointerTo(t); pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var elems []*slowjson.Node
+		for _, n := range nodes {
+			if n.Type == slowjson.NodeArray {
+				elems = append(elems, n.Children...)
+			}
+		}
+		d.walk(t.Elem(), path+"[*]", elems)
+	case reflect.Map:
+		var values []*slowjson.Node
+		for _, n := range nodes {
+			if n.Type == slowjson.NodeObject {
+				for _, key := range n.Children {
+					values = append(values, key.Children...)
+				}
+			}
+		}
+		d.walk(t.Elem(), joinDriftPath(path, "*"), values)
+	case reflect.Struct:
+		d.walkStruct(t, path, nodes)
+	}
+}
+
+func (d *Drift) walkStruct(t reflect.Type, path string, nodes []*slowjson.Node) {
+	var objects []*slowjson.Node
+	for _, n := range nodes {
+		if n.Type == slowjson.NodeObject {
+			objects = append(objects, n)
+		}
+	}
+	if len(objects) == 0 {
+		// not set, or of the wrong type which Decode reports
+		return
+	}
+	fields := structFields(t)
+	values := make([][]*slowjson.Node, len(fields))
+	for _, n := range objects {
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			f, ok := fields.find(key.Value)
+			if !ok {
+				d.UnreadKeys = append(d.UnreadKeys, UnreadKey{Path: key.Path().String(), Key: key})
+				continue
+			}
+			for i := range fields {
+				if fields[i].name == f.name {
+					values[i] = append(values[i], key.Children[0])
+					break
+				}
+			}
+		}
+	}
+	typeName := t.Name()
+	if typeName == "" {
+		typeName = t.String()
+	}
+	for i, f := range fields {
+		sf := t.FieldByIndex(f.index)
+		fieldPath := joinDriftPath(path, f.name)
+		if len(values[i]) == 0 {
+			d.UnsetFields = append(d.UnsetFields, UnsetField{Path: fieldPath, Field: typeName + "." + sf.Name})
+			continue
+		}
+		d.walk(sf.Type, fieldPath, values[i])
+	}
+}
+
+func joinDriftPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
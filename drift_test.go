@@ -0,0 +1,66 @@
+package tracedconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestCheckDrift(t *testing.T) {
+	var files []*slowjson.Node
+	for name, input := range map[string]string{
+		"a.json": `{"name": "a", "server": {"host": "x", "timeout": "1s", "bind": ":80"}, "limits": {"mem": "1gb"}}`,
+		"b.json": `{"server": {"Port": 80}, "backups": [{"host": "y", "retries": 3}], "debug": true, "extra": {"any": {"thing": 1}}}`,
+	} {
+		p := slowjson.NewParser(input)
+		p.File = name
+		n, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, n)
+	}
+	d, err := CheckDrift(&schemaConfig{}, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, k := range d.UnreadKeys {
+		got = append(got, k.String())
+	}
+	want := []string{
+		"a.json:1:56: server.bind is not read by any field",
+		"b.json:1:52: backups[0].retries is not read by any field",
+		"b.json:1:68: debug is not read by any field",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("UnreadKeys =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got = nil
+	for _, f := range d.UnsetFields {
+		got = append(got, f.String())
+	}
+	want = []string{
+		"schemaServer.Mode (server.mode) is not set by any file",
+		"schemaServer.Legacy (server.legacy) is not set by any file",
+		"schemaServer.Port (backups[*].port) is not set by any file",
+		"schemaServer.Timeout (backups[*].timeout) is not set by any file",
+		"schemaServer.Mode (backups[*].mode) is not set by any file",
+		"schemaServer.Legacy (backups[*].legacy) is not set by any file",
+		"schemaConfig.Next (next) is not set by any file",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("UnsetFields =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckDrift_NotStruct(t *testing.T) {
+	if _, err := CheckDrift(map[string]int{}); err == nil {
+		t.Error("CheckDrift(map) error = nil")
+	}
+	if _, err := CheckDrift(nil); err == nil {
+		t.Error("CheckDrift(nil) error = nil")
+	}
+}
@@ -0,0 +1,393 @@
+package tracedconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/at15/tracedconfig/secrets"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Export formats.
+const (
+	ExportJSON   = "json"
+	ExportYAML   = "yaml"
+	ExportTOML   = "toml"
+	ExportDotenv = "dotenv"
+	ExportShell  = "shell"
+)
+
+// Redacted replaces the values Export redacts.
+const Redacted = "[REDACTED]"
+
+// ExportOptions configures Config.Export.
+type ExportOptions struct {
+	// Redact lists paths whose values are replaced by Redacted, e.g. "db.password". Objects and arrays
+	// are replaced as a whole.
+	Redact []string
+	// RedactSecrets also redacts the values of keys named like secrets, e.g. "password" or "apiKey", and
+	// strings that look like credentials, see the secrets package.
+	RedactSecrets bool
+	// EnvPrefix is prepended to the variable names of dotenv and shell exports, e.g. "APP_".
+	EnvPrefix string
+}
+
+// Export writes the merged config in format, one of the Export constants, to hand the resolved values to
+// processes that do not use this package:
+//
+//	json    indented JSON
+//	yaml    block style YAML with every string quoted
+//	toml    tables for objects and arrays of tables for arrays of objects, null values are left out
+//	dotenv  NAME=value lines, e.g. SERVER_PORT=8080 for server.port and HOSTS_0=a for hosts[0]
+//	shell   export NAME='value' lines for sh and bash
+//
+// TOML, dotenv and shell need an object at the root. Variable names are the upper-cased path with every
+// other character than letters and digits replaced by "_", paths mapping to the same name are an error.
+func (c *Config) Export(format string, opts ExportOptions) ([]byte, error) {
+	e := &exporter{opts: opts}
+	for _, r := range opts.Redact {
+		p, err := slowjson.ParsePath(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact path %q: %w", r, err)
+		}
+		e.redact = append(e.redact, p)
+	}
+	root := e.copy(c.Root())
+	slowjson.SetParents(root)
+	if format != ExportJSON && format != ExportYAML && root.Type != slowjson.NodeObject {
+		return nil, fmt.Errorf("cannot export %s, the config is not an object", format)
+	}
+	var err error
+	switch format {
+	case ExportJSON:
+		var b []byte
+		b, err = slowjson.MarshalOptions{Indent: "  "}.Marshal(root)
+		e.b.Write(b)
+		e.b.WriteByte('\n')
+	case ExportYAML:
+		e.yaml(root, 0, false)
+	case ExportTOML:
+		err = e.toml(root, "")
+	case ExportDotenv, ExportShell:
+		err = e.env(root, format)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(e.b.String()), nil
+}
+
+type exporter struct {
+	opts   ExportOptions
+	redact []slowjson.Path
+	b      strings.Builder
+}
+
+// copy returns the tree of n with the redacted values replaced, so the writers need not know about it.
+func (e *exporter) copy(n *slowjson.Node) *slowjson.Node {
+	if e.redacted(n) {
+		return &slowjson.Node{Type: slowjson.NodeString, Value: Redacted}
+	}
+	out := &slowjson.Node{Type: n.Type, Value: n.Value}
+	for _, child := range n.Children {
+		if n.Type != slowjson.NodeObject {
+			out.Children = append(out.Children, e.copy(child))
+			continue
+		}
+		if len(child.Children) == 0 {
+			continue
+		}
+		key := &slowjson.Node{Type: slowjson.NodeString, Value: child.Value}
+		key.Children = []*slowjson.Node{e.copy(child.Children[0])}
+		out.Children = append(out.Children, key)
+	}
+	return out
+}
+
+func (e *exporter) redacted(n *slowjson.Node) bool {
+	if len(e.redact) > 0 {
+		p := n.Path()
+		for _, r := range e.redact {
+			if p.Equal(r) {
+				return true
+			}
+		}
+	}
+	if !e.opts.RedactSecrets || n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray || n.Type == slowjson.NodeNull {
+		return false
+	}
+	// the key of the value, or of the array holding it
+	key := n.Parent
+	for key != nil && key.Type == slowjson.NodeArray {
+		key = key.Parent
+	}
+	if key != nil && key.IsKey() && secrets.IsSecretKey(key.Value) {
+		return true
+	}
+	if n.Type != slowjson.NodeString {
+		return false
+	}
+	_, ok := secrets.Detect(n.Value)
+	return ok
+}
+
+// scalar formats a string, number, boolean or null as JSON, which YAML and TOML accept for strings,
+// numbers and booleans.
+func scalar(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeString:
+		b, _ := json.Marshal(n.Value)
+		return string(b)
+	case slowjson.NodeNull:
+		return "null"
+	default:
+		return n.Value
+	}
+}
+
+var (
+	yamlKeyPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	yamlSpecialKeys = regexp.MustCompile(`(?i)^(true|false|null|yes|no|on|off|y|n)$`)
+	tomlKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+func yamlKey(k string) string {
+	if yamlKeyPattern.MatchString(k) && !yamlSpecialKeys.MatchString(k) {
+		return k
+	}
+	b, _ := json.Marshal(k)
+	return string(b)
+}
+
+func tomlKey(k string) string {
+	if tomlKeyPattern.MatchString(k) {
+		return k
+	}
+	b, _ := json.Marshal(k)
+	return string(b)
+}
+
+// yaml writes n at depth. A value following "- " continues its line, so inline skips the indentation
+// of its first line.
+func (e *exporter) yaml(n *slowjson.Node, depth int, inline bool) {
+	indent := strings.Repeat("  ", depth)
+	switch {
+	case n.Type == slowjson.NodeObject && len(n.Children) > 0:
+		for i, key := range n.Children {
+			if i > 0 || !inline {
+				e.b.WriteString(indent)
+			}
+			e.b.WriteString(yamlKey(key.Value) + ":")
+			e.yamlValue(key.Children[0], depth+1)
+		}
+	case n.Type == slowjson.NodeArray && len(n.Children) > 0:
+		for i, elem := range n.Children {
+			if i > 0 || !inline {
+				e.b.WriteString(indent)
+			}
+			e.b.WriteString("- ")
+			if isContainer(elem) {
+				e.yaml(elem, depth+1, true)
+			} else {
+				e.b.WriteString(yamlScalar(elem) + "\n")
+			}
+		}
+	default:
+		e.b.WriteString(yamlScalar(n) + "\n")
+	}
+}
+
+// yamlValue writes the value of a key, on the next lines when it is a container.
+func (e *exporter) yamlValue(n *slowjson.Node, depth int) {
+	if !isContainer(n) {
+		e.b.WriteString(" " + yamlScalar(n) + "\n")
+		return
+	}
+	e.b.WriteString("\n")
+	e.yaml(n, depth, false)
+}
+
+// yamlScalar formats scalars and empty containers.
+func yamlScalar(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeObject:
+		return "{}"
+	case slowjson.NodeArray:
+		return "[]"
+	}
+	return scalar(n)
+}
+
+// isContainer reports whether n is an object or array with children.
+func isContainer(n *slowjson.Node) bool {
+	return (n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray) && len(n.Children) > 0
+}
+
+// toml writes the keys of the table n named name, followed by its sub-tables and arrays of tables.
+func (e *exporter) toml(n *slowjson.Node, name string) error {
+	var tables, arrays []*slowjson.Node
+	for _, key := range n.Children {
+		v := key.Children[0]
+		switch {
+		case v.Type == slowjson.NodeNull:
+			// TOML has no null
+		case v.Type == slowjson.NodeObject:
+			tables = append(tables, key)
+		case isTableArray(v):
+			arrays = append(arrays, key)
+		default:
+			s, err := tomlInline(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", v.Path(), err)
+			}
+			fmt.Fprintf(&e.b, "%s = %s\n", tomlKey(key.Value), s)
+		}
+	}
+	for _, key := range tables {
+		full := joinTable(name, key.Value)
+		fmt.Fprintf(&e.b, "\n[%s]\n", full)
+		if err := e.toml(key.Children[0], full); err != nil {
+			return err
+		}
+	}
+	for _, key := range arrays {
+		full := joinTable(name, key.Value)
+		for _, elem := range key.Children[0].Children {
+			fmt.Fprintf(&e.b, "\n[[%s]]\n", full)
+			if err := e.toml(elem, full); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinTable(name, key string) string {
+	if name == "" {
+		return tomlKey(key)
+	}
+	return name + "." + tomlKey(key)
+}
+
+// isTableArray reports whether n is a non-empty array of objects, written as an array of tables.
+func isTableArray(n *slowjson.Node) bool {
+	if n.Type != slowjson.NodeArray || len(n.Children) == 0 {
+		return false
+	}
+	for _, elem := range n.Children {
+		if elem.Type != slowjson.NodeObject {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlInline formats a value on one line, inline tables for objects.
+func tomlInline(n *slowjson.Node) (string, error) {
+	switch n.Type {
+	case slowjson.NodeNull:
+		return "", fmt.Errorf("TOML has no null")
+	case slowjson.NodeArray:
+		items := make([]string, len(n.Children))
+		for i, elem := range n.Children {
+			s, err := tomlInline(elem)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case slowjson.NodeObject:
+		if len(n.Children) == 0 {
+			return "{}", nil
+		}
+		items := make([]string, len(n.Children))
+		for i, key := range n.Children {
+			s, err := tomlInline(key.Children[0])
+			if err != nil {
+				return "", err
+			}
+			items[i] = tomlKey(key.Value) + " = " + s
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return scalar(n), nil
+}
+
+var envNameInvalid = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// envName converts a path to a variable name, e.g. servers[0].host to SERVERS_0_HOST.
+func envName(prefix string, p slowjson.Path) string {
+	parts := make([]string, len(p))
+	for i, seg := range p {
+		if seg.IsIndex {
+			parts[i] = fmt.Sprint(seg.Index)
+		} else {
+			parts[i] = seg.Key
+		}
+	}
+	name := envNameInvalid.ReplaceAllString(strings.ToUpper(prefix+strings.Join(parts, "_")), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// env writes a variable per scalar of root, empty objects and arrays have none.
+func (e *exporter) env(root *slowjson.Node, format string) error {
+	seen := map[string]slowjson.Path{}
+	var walk func(n *slowjson.Node, p slowjson.Path) error
+	walk = func(n *slowjson.Node, p slowjson.Path) error {
+		switch n.Type {
+		case slowjson.NodeObject:
+			for _, key := range n.Children {
+				if err := walk(key.Children[0], p.Key(key.Value)); err != nil {
+					return err
+				}
+			}
+			return nil
+		case slowjson.NodeArray:
+			for i, elem := range n.Children {
+				if err := walk(elem, p.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		name := envName(e.opts.EnvPrefix, p)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("%s and %s both export as %s", other, p, name)
+		}
+		seen[name] = p
+		value := n.Value
+		if n.Type == slowjson.NodeNull {
+			value = ""
+		}
+		if format == ExportShell {
+			fmt.Fprintf(&e.b, "export %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+		} else {
+			fmt.Fprintf(&e.b, "%s=%s\n", name, dotenvValue(value))
+		}
+		return nil
+	}
+	return walk(root, nil)
+}
+
+var dotenvPlain = regexp.MustCompile(`^[A-Za-z0-9_./:@,+-]*$`)
+
+// dotenvValue quotes values that are not plain, in single quotes unless that cannot hold the value.
+func dotenvValue(v string) string {
+	switch {
+	case dotenvPlain.MatchString(v):
+		return v
+	case !strings.ContainsAny(v, "'\n"):
+		return "'" + v + "'"
+	default:
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+		return `"` + r.Replace(v) + `"`
+	}
+}
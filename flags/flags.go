@@ -0,0 +1,294 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Context is what a flag is evaluated for, e.g. the user of a request.
+type Context struct {
+	// Key identifies the subject for percentage rollouts, the same key always gets the same bucket.
+	Key string
+	// Attributes are matched by the when clause of rules.
+	Attributes map[string]string
+}
+
+type contextKey struct{}
+
+// WithContext returns ctx carrying the flag evaluation context.
+func WithContext(ctx context.Context, fc Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, fc)
+}
+
+// FromContext returns the flag evaluation context carried by ctx.
+func FromContext(ctx context.Context) (Context, bool) {
+	fc, ok := ctx.Value(contextKey{}).(Context)
+	return fc, ok
+}
+
+// Set is a parsed set of flags. It does not change, parse again after the config reloads.
+type Set struct {
+	flags map[string]*flag
+}
+
+type flag struct {
+	name    string
+	node    *slowjson.Node
+	enabled bool
+	rules   []rule
+}
+
+type rule struct {
+	node       *slowjson.Node
+	when       map[string][]string
+	percentage float64 // -1 when not set
+	enabled    bool
+	// shorthand is the rule made from the flag's own percentage field
+	shorthand bool
+}
+
+// Result is the outcome of evaluating a flag.
+type Result struct {
+	Flag    string
+	Enabled bool
+	// Reason says why, e.g. "rule 1 matched" or "default".
+	Reason string
+	// Node is the rule or flag that decided, nil for unknown flags.
+	Node *slowjson.Node
+}
+
+// String formats the result with the position of the deciding rule,
+// e.g. "search-v2 = true: rule 1 matched at flags.json:7:9".
+func (r Result) String() string {
+	if r.Node == nil {
+		return fmt.Sprintf("%s = %t: %s", r.Flag, r.Enabled, r.Reason)
+	}
+	return fmt.Sprintf("%s = %t: %s at %s", r.Flag, r.Enabled, r.Reason, r.Node.Location())
+}
+
+// FromConfig parses the flags in the object at path of the merged config.
+// A missing object gives an empty set.
+func FromConfig(c *tracedconfig.Config, path string) (*Set, error) {
+	n := c.Get(path)
+	if n == nil {
+		return &Set{flags: map[string]*flag{}}, nil
+	}
+	return Parse(n)
+}
+
+// Parse parses an object of flag definitions. Unknown fields are errors so typos do not
+// silently change who gets a feature.
+func Parse(n *slowjson.Node) (*Set, error) {
+	if n.Type != slowjson.NodeObject {
+		return nil, n.Errorf("flags must be an object")
+	}
+	s := &Set{flags: map[string]*flag{}}
+	for _, key := range n.Children {
+		if len(key.Children) == 0 {
+			continue
+		}
+		f, err := parseFlag(key.Value, key.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		s.flags[key.Value] = f
+	}
+	return s, nil
+}
+
+func parseFlag(name string, n *slowjson.Node) (*flag, error) {
+	if n.Type != slowjson.NodeObject {
+		return nil, n.Errorf("flag must be an object")
+	}
+	f := &flag{name: name, node: n}
+	var percentage *rule
+	for _, key := range n.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "enabled":
+			b, err := boolValue(v)
+			if err != nil {
+				return nil, err
+			}
+			f.enabled = b
+		case "percentage":
+			p, err := percentValue(v)
+			if err != nil {
+				return nil, err
+			}
+			percentage = &rule{node: v, percentage: p, enabled: true, shorthand: true}
+		case "rules":
+			if v.Type != slowjson.NodeArray {
+				return nil, v.Errorf("rules must be an array")
+			}
+			for _, rn := range v.Children {
+				r, err := parseRule(rn)
+				if err != nil {
+					return nil, err
+				}
+				f.rules = append(f.rules, r)
+			}
+		case "description":
+		default:
+			return nil, key.Errorf("unknown flag field %q", key.Value)
+		}
+	}
+	// the percentage shorthand applies after explicit rules
+	if percentage != nil {
+		f.rules = append(f.rules, *percentage)
+	}
+	return f, nil
+}
+
+func parseRule(n *slowjson.Node) (rule, error) {
+	r := rule{node: n, percentage: -1, enabled: true}
+	if n.Type != slowjson.NodeObject {
+		return r, n.Errorf("rule must be an object")
+	}
+	for _, key := range n.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "when":
+			if v.Type != slowjson.NodeObject {
+				return r, v.Errorf("when must be an object")
+			}
+			r.when = map[string][]string{}
+			for _, attr := range v.Children {
+				values, err := stringValues(attr.Children[0])
+				if err != nil {
+					return r, err
+				}
+				r.when[attr.Value] = values
+			}
+		case "percentage":
+			p, err := percentValue(v)
+			if err != nil {
+				return r, err
+			}
+			r.percentage = p
+		case "enabled":
+			b, err := boolValue(v)
+			if err != nil {
+				return r, err
+			}
+			r.enabled = b
+		default:
+			return r, key.Errorf("unknown rule field %q", key.Value)
+		}
+	}
+	return r, nil
+}
+
+func boolValue(n *slowjson.Node) (bool, error) {
+	if n.Type != slowjson.NodeBoolean {
+		return false, n.Errorf("expected true or false")
+	}
+	return n.Value == "true", nil
+}
+
+func percentValue(n *slowjson.Node) (float64, error) {
+	if n.Type != slowjson.NodeNumber {
+		return 0, n.Errorf("percentage must be a number")
+	}
+	p, err := strconv.ParseFloat(n.Value, 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, n.Errorf("percentage must be between 0 and 100")
+	}
+	return p, nil
+}
+
+func stringValues(n *slowjson.Node) ([]string, error) {
+	switch n.Type {
+	case slowjson.NodeString, slowjson.NodeNumber, slowjson.NodeBoolean:
+		return []string{n.Value}, nil
+	case slowjson.NodeArray:
+		var values []string
+		for _, c := range n.Children {
+			if c.Type == slowjson.NodeArray {
+				return nil, c.Errorf("expected a value")
+			}
+			v, err := stringValues(c)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v...)
+		}
+		return values, nil
+	default:
+		return nil, n.Errorf("expected a value or an array of values")
+	}
+}
+
+// Names returns the names of the flags, sorted.
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.flags))
+	for name := range s.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether the flag is on for the evaluation context carried by ctx.
+// Unknown flags are off.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	fc, _ := FromContext(ctx)
+	return s.Evaluate(name, fc).Enabled
+}
+
+// Evaluate evaluates the flag for fc and explains the outcome.
+func (s *Set) Evaluate(name string, fc Context) Result {
+	f, ok := s.flags[name]
+	if !ok {
+		return Result{Flag: name, Reason: "unknown flag"}
+	}
+	for i, r := range f.rules {
+		if !r.matches(name, fc) {
+			continue
+		}
+		reason := fmt.Sprintf("rule %d matched", i+1)
+		if r.shorthand {
+			reason = "percentage matched"
+		}
+		return Result{Flag: name, Enabled: r.enabled, Reason: reason, Node: r.node}
+	}
+	return Result{Flag: name, Enabled: f.enabled, Reason: "default", Node: f.node}
+}
+
+func (r rule) matches(flag string, fc Context) bool {
+	for attr, values := range r.when {
+		v, ok := fc.Attributes[attr]
+		if !ok || !contains(values, v) {
+			return false
+		}
+	}
+	if r.percentage >= 0 {
+		return bucket(flag, fc.Key) < r.percentage
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket maps the key to [0, 100) per flag, so rollouts of different flags are independent.
+func bucket(flag, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(flag))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
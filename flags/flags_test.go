@@ -0,0 +1,129 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+const flagsJSON = `{
+  "flags": {
+    "new-ui": {"enabled": true},
+    "beta": {"percentage": 25},
+    "search-v2": {
+      "description": "new search backend",
+      "rules": [
+        {"when": {"plan": "free"}, "enabled": false},
+        {"when": {"country": ["DE", "FR"]}},
+        {"when": {"plan": "pro"}, "percentage": 50}
+      ]
+    }
+  }
+}`
+
+func load(t *testing.T) *Set {
+	t.Helper()
+	c := tracedconfig.NewConfig(tracedconfig.Bytes("flags.json", []byte(flagsJSON)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := FromConfig(c, "flags")
+	if err != nil {
+		t.Fatalf("FromConfig() error = %v", err)
+	}
+	return s
+}
+
+func TestSet_Evaluate(t *testing.T) {
+	s := load(t)
+	if got := strings.Join(s.Names(), ","); got != "beta,new-ui,search-v2" {
+		t.Errorf("Names() = %s", got)
+	}
+	tests := []struct {
+		flag string
+		ctx  Context
+		want string
+	}{
+		{"new-ui", Context{}, "new-ui = true: default at flags.json:3:15"},
+		{"missing", Context{}, "missing = false: unknown flag"},
+		{"search-v2", Context{Attributes: map[string]string{"plan": "free", "country": "DE"}}, "search-v2 = false: rule 1 matched at flags.json:8:9"},
+		{"search-v2", Context{Attributes: map[string]string{"country": "fr"}}, "search-v2 = true: rule 2 matched at flags.json:9:9"},
+		{"search-v2", Context{Attributes: map[string]string{"country": "US"}}, "search-v2 = false: default at flags.json:5:18"},
+	}
+	for _, tt := range tests {
+		if got := s.Evaluate(tt.flag, tt.ctx).String(); got != tt.want {
+			t.Errorf("Evaluate(%s, %v) = %q, want %q", tt.flag, tt.ctx, got, tt.want)
+		}
+	}
+}
+
+func TestSet_Percentage(t *testing.T) {
+	s := load(t)
+	on := 0
+	for i := 0; i < 2000; i++ {
+		r := s.Evaluate("beta", Context{Key: fmt.Sprintf("user-%d", i)})
+		if r.Enabled {
+			on++
+			if r.Reason != "percentage matched" || r.Node.Location() != "flags.json:4:28" {
+				t.Fatalf("Evaluate() = %v", r)
+			}
+		}
+	}
+	if on < 400 || on > 600 {
+		t.Errorf("%d of 2000 enabled, want about 500", on)
+	}
+	// the same key always gets the same answer
+	first := s.Evaluate("beta", Context{Key: "alice"}).Enabled
+	for i := 0; i < 10; i++ {
+		if s.Evaluate("beta", Context{Key: "alice"}).Enabled != first {
+			t.Fatal("percentage evaluation is not deterministic")
+		}
+	}
+}
+
+func TestSet_Enabled(t *testing.T) {
+	s := load(t)
+	ctx := WithContext(context.Background(), Context{Attributes: map[string]string{"country": "DE"}})
+	if !s.Enabled(ctx, "search-v2") || !s.Enabled(context.Background(), "new-ui") || s.Enabled(ctx, "missing") {
+		t.Error("Enabled() got wrong result")
+	}
+	if fc, ok := FromContext(ctx); !ok || fc.Attributes["country"] != "DE" {
+		t.Errorf("FromContext() = %v, %v", fc, ok)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{`[]`, "flags must be an object"},
+		{`{"a": true}`, "a: flag must be an object"},
+		{`{"a": {"enable": true}}`, `a.enable: unknown flag field "enable"`},
+		{`{"a": {"percentage": 120}}`, "a.percentage: percentage must be between 0 and 100 at line 1 col 22"},
+		{`{"a": {"rules": [{"when": {"x": {}}}]}}`, "a.rules[0].when.x: expected a value or an array of values"},
+		{`{"a": {"rules": [{"if": {}}]}}`, `a.rules[0].if: unknown rule field "if"`},
+		{`{"a": {"enabled": "yes"}}`, "a.enabled: expected true or false"},
+	}
+	for _, tt := range tests {
+		n, err := slowjson.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(n); err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Parse(%s) error = %v, want %q", tt.input, err, tt.wantError)
+		}
+	}
+}
+
+func TestFromConfig_Missing(t *testing.T) {
+	c := tracedconfig.NewConfig()
+	s, err := FromConfig(c, "flags")
+	if err != nil || len(s.Names()) != 0 {
+		t.Errorf("FromConfig() = %v, %v", s.Names(), err)
+	}
+}
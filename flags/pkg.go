@@ -0,0 +1,21 @@
+// Package flags evaluates feature flags defined in config.
+//
+// Flags live in an object keyed by flag name, usually under "flags":
+//
+//	"flags": {
+//	  "new-ui": {"enabled": true},
+//	  "beta": {"percentage": 25},
+//	  "search-v2": {
+//	    "rules": [
+//	      {"when": {"country": ["DE", "FR"]}},
+//	      {"when": {"plan": "free"}, "enabled": false},
+//	      {"percentage": 10}
+//	    ]
+//	  }
+//	}
+//
+// Rules are tried in order and the first one matching the evaluation context decides, a rule
+// enables the flag unless it sets "enabled": false. Without a matching rule the flag's "enabled"
+// value, false by default, applies. Percentages bucket the context key deterministically.
+// Every Result points at the rule that decided it.
+package flags
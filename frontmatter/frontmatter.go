@@ -0,0 +1,115 @@
+// Package frontmatter extracts the YAML or TOML front matter of Markdown and other text files into
+// positioned slowjson nodes, for static-site and docs tooling that validates or decodes page metadata
+// with the rest of this module.
+package frontmatter
+
+import (
+	"bytes"
+
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/toml"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// Front matter formats.
+const (
+	// FormatYAML front matter is enclosed in "---" lines, it may also end with a "..." line.
+	FormatYAML = "yaml"
+	// FormatTOML front matter is enclosed in "+++" lines.
+	FormatTOML = "toml"
+)
+
+// Document is a file split into its front matter and body.
+type Document struct {
+	// Format is FormatYAML or FormatTOML, empty when the file has no front matter.
+	Format string
+	// Root is the front matter, always an object, nil when the file has none. Its positions are
+	// those in the file.
+	Root *slowjson.Node
+	// Body is the text after the front matter, the whole file when it has none.
+	Body []byte
+	// BodyLine is the line of the file the body starts on.
+	BodyLine int
+}
+
+// Extract splits data into its front matter and body. file is recorded as the file of the nodes.
+// Front matter starts on the first line, an opening delimiter without a closing one is an error.
+func Extract(data []byte, file string) (*Document, error) {
+	start := 0
+	if bytes.HasPrefix(data, []byte("\uFEFF")) {
+		start = len("\uFEFF")
+	}
+	var format string
+	var closers []string
+	switch delimiter(data[start:]) {
+	case "---":
+		format, closers = FormatYAML, []string{"---", "..."}
+	case "+++":
+		format, closers = FormatTOML, []string{"+++"}
+	default:
+		return &Document{Body: data, BodyLine: 1}, nil
+	}
+
+	// find the closing delimiter line
+	lineNo := 1
+	off := start + bytes.IndexByte(data[start:], '\n') + 1
+	closeAt := -1
+	for off > start && off < len(data) {
+		lineNo++
+		line := delimiter(data[off:])
+		for _, c := range closers {
+			if line == c {
+				closeAt = off
+			}
+		}
+		if closeAt >= 0 {
+			break
+		}
+		i := bytes.IndexByte(data[off:], '\n')
+		if i < 0 {
+			break
+		}
+		off += i + 1
+	}
+	if closeAt < 0 {
+		return nil, &slowjson.ParseError{
+			Pos: slowjson.Position{File: file, Line: 1, Col: 1, Offset: start},
+			Msg: "unterminated " + format + " front matter",
+		}
+	}
+	bodyStart := len(data)
+	if i := bytes.IndexByte(data[closeAt:], '\n'); i >= 0 {
+		bodyStart = closeAt + i + 1
+	}
+
+	var root *slowjson.Node
+	var err error
+	if format == FormatYAML {
+		// the opening "---" starts the document, positions count from the start of the file
+		root, err = yaml.Parse(data[:closeAt], file)
+	} else {
+		// blank the opening "+++" so positions count from the start of the file
+		src := append([]byte(nil), data[:closeAt]...)
+		copy(src[start:], "   ")
+		root, err = toml.Parse(src, file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch root.Type {
+	case slowjson.NodeObject:
+	case slowjson.NodeNull:
+		root = &slowjson.Node{Type: slowjson.NodeObject, StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1, File: file}
+	default:
+		return nil, root.Errorf("front matter must be a mapping")
+	}
+	return &Document{Format: format, Root: root, Body: data[bodyStart:], BodyLine: lineNo + 1}, nil
+}
+
+// delimiter returns the first line of data without trailing whitespace.
+func delimiter(data []byte) string {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	return string(bytes.TrimRight(data, " \t\r"))
+}
@@ -0,0 +1,79 @@
+package frontmatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   string
+		title    string
+		titleAt  int
+		body     string
+		bodyLine int
+	}{
+		{"yaml", "---\ntitle: Hello\ntags: [a, b]\n---\n# Hello\n", FormatYAML, "Hello", 2, "# Hello\n", 5},
+		{"yaml dots", "---\ntitle: Hello\n...\nbody", FormatYAML, "Hello", 2, "body", 4},
+		{"toml", "+++\ndraft = true\ntitle = \"Hello\"\n+++\n\nbody\n", FormatTOML, "Hello", 3, "\nbody\n", 5},
+		{"crlf", "---\r\ntitle: Hello\r\n---\r\nbody", FormatYAML, "Hello", 2, "body", 4},
+		{"empty", "---\n---\nbody", FormatYAML, "", 0, "body", 3},
+		{"none", "# Title\n---\n", "", "", 0, "# Title\n---\n", 1},
+		{"no trailing body", "+++\ntitle = \"Hello\"\n+++", FormatTOML, "Hello", 2, "", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Extract([]byte(tt.input), "page.md")
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if doc.Format != tt.format || string(doc.Body) != tt.body || doc.BodyLine != tt.bodyLine {
+				t.Errorf("Extract() = %q, body %q at line %d", doc.Format, doc.Body, doc.BodyLine)
+			}
+			if tt.format == "" {
+				if doc.Root != nil {
+					t.Errorf("Extract() root = %v, want nil", doc.Root)
+				}
+				return
+			}
+			if doc.Root.Type != slowjson.NodeObject {
+				t.Fatalf("Extract() root type = %v", doc.Root.Type)
+			}
+			if tt.title == "" {
+				return
+			}
+			title := doc.Root.Get("title")
+			if title == nil || title.Value != tt.title || title.StartLine != tt.titleAt || title.File != "page.md" {
+				t.Errorf("Extract() title = %+v", title)
+			}
+			if got := tt.input[title.StartOffset:title.EndOffset]; !strings.Contains(got, tt.title) {
+				t.Errorf("Extract() title offsets cover %q", got)
+			}
+		})
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"---\ntitle: x\n", "unterminated yaml front matter at page.md:1:1"},
+		{"+++", "unterminated toml front matter at page.md:1:1"},
+		{"---\n- a\n---\n", "front matter must be a mapping at page.md:2:1"},
+		{"---\ntitle: [x\n---\n", "unterminated flow collection, expected ']' at page.md:2:8"},
+		{"+++\ntitle = x\n+++\n", `invalid value "x", quote strings at page.md:2:9`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Extract([]byte(tt.input), "page.md")
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Extract() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Repo is a remote repository fetched into a local bare repository.
+type Repo struct {
+	// URL is anything git fetch accepts, e.g. "https://github.com/org/config.git" or "file:///srv/config".
+	URL string
+	// Ref is the branch, tag or commit to load, "HEAD" when empty.
+	Ref string
+	// Dir holds the local bare repository, it is created on the first fetch.
+	Dir string
+
+	mu     sync.Mutex
+	commit string
+}
+
+// New creates a repo fetching ref of url into dir.
+func New(url, ref, dir string) *Repo {
+	return &Repo{URL: url, Ref: ref, Dir: dir}
+}
+
+// Fetch fetches the latest commit of Ref and returns its SHA.
+func (r *Repo) Fetch(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(r.Dir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, "init", "--bare", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+	ref := r.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := r.git(ctx, "fetch", "--quiet", "--depth=1", r.URL, ref); err != nil {
+		return "", err
+	}
+	out, err := r.git(ctx, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return "", err
+	}
+	r.commit = strings.TrimSpace(string(out))
+	return r.commit, nil
+}
+
+// Commit returns the SHA of the last fetch, empty before the first.
+func (r *Repo) Commit() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commit
+}
+
+// ReadFile reads path at commit.
+func (r *Repo) ReadFile(ctx context.Context, commit, path string) ([]byte, error) {
+	return r.git(ctx, "show", commit+":"+path)
+}
+
+// git runs the git subcommand sub in Dir.
+func (r *Repo) git(ctx context.Context, sub string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.Dir, sub}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", sub, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", sub, err)
+	}
+	return out, nil
+}
+
+// File creates a source loading the JSON file at path in the repository.
+// Every Load fetches the repository first so it sees the latest commit of Ref.
+func (r *Repo) File(path string) *Source {
+	return &Source{repo: r, path: path}
+}
+
+// Source loads a JSON file from a repository.
+type Source struct {
+	repo *Repo
+	path string
+
+	mu     sync.Mutex
+	commit string
+}
+
+// Name returns the repository URL and path, e.g. "https://github.com/org/config.git//app.json".
+// It is the file of the loaded nodes.
+func (s *Source) Name() string {
+	return s.repo.URL + "//" + s.path
+}
+
+// Load fetches the repository and parses the file at the fetched commit.
+func (s *Source) Load(ctx context.Context) (*slowjson.Node, error) {
+	commit, err := s.repo.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.repo.ReadFile(ctx, commit, s.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tracedconfig.CheckSize(ctx, s.Name(), len(data)); err != nil {
+		return nil, err
+	}
+	p := slowjson.NewParser(string(data))
+	p.File = s.Name()
+	n, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.commit = commit
+	s.mu.Unlock()
+	return n, nil
+}
+
+// Metadata records the commit and path of the last load.
+func (s *Source) Metadata() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]string{"commit": s.commit, "path": s.path}
+}
@@ -0,0 +1,75 @@
+package gitsource
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+// upstream creates a repository with a commit per content of app.json and returns its URL and commits.
+func upstream(t *testing.T, contents ...string) (string, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "--quiet", "--initial-branch=main")
+	var commits []string
+	for _, c := range contents {
+		if err := os.WriteFile(filepath.Join(dir, "app.json"), []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "app.json")
+		run("commit", "--quiet", "-m", "update")
+		commits = append(commits, run("rev-parse", "HEAD"))
+	}
+	return "file://" + dir, commits
+}
+
+func TestSource(t *testing.T) {
+	url, commits := upstream(t, "{\n  \"port\": 80\n}", "{\n  \"port\": 81\n}")
+	repo := New(url, "main", filepath.Join(t.TempDir(), "cache"))
+	c := tracedconfig.NewConfig(repo.File("app.json"))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("port").Value; got != "81" {
+		t.Errorf("port = %s, want 81", got)
+	}
+	if repo.Commit() != commits[1] {
+		t.Errorf("Commit() = %s, want %s", repo.Commit(), commits[1])
+	}
+	o, _ := c.Origin("port")
+	want := url + "//app.json:2:11 (" + url + "//app.json, commit=" + commits[1] + ", path=app.json)"
+	if o.String() != want {
+		t.Errorf("Origin() = %q, want %q", o.String(), want)
+	}
+
+	pinned := tracedconfig.NewConfig(New(url, commits[0], filepath.Join(t.TempDir(), "cache")).File("app.json"))
+	if err := pinned.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinned.Get("port").Value; got != "80" {
+		t.Errorf("port at first commit = %s, want 80", got)
+	}
+
+	missing := tracedconfig.NewConfig(repo.File("missing.json"))
+	if err := missing.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "git show:") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package gitsource loads config files from a Git repository for GitOps-style delivery.
+// Every value records the commit and path it was read from, so the audit trail is the repository history.
+// It runs the git binary, which must be installed.
+package gitsource
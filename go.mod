@@ -1,3 +1,5 @@
 module github.com/at15/tracedconfig
 
 go 1.23.1
+
+require google.golang.org/protobuf v1.36.5
@@ -0,0 +1,186 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// HealthStatus is the state of a source after its last load.
+type HealthStatus int
+
+const (
+	// StatusUnknown sources were not loaded yet.
+	StatusUnknown HealthStatus = iota
+	// StatusHealthy sources loaded.
+	StatusHealthy
+	// StatusDegraded sources failed but served a fallback.
+	StatusDegraded
+	// StatusFailing sources failed without a fallback, Config keeps the previous tree.
+	StatusFailing
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case StatusUnknown:
+		return "unknown"
+	case StatusHealthy:
+		return "healthy"
+	case StatusDegraded:
+		return "degraded"
+	case StatusFailing:
+		return "failing"
+	default:
+		return fmt.Sprintf("HealthStatus(%d)", int(s))
+	}
+}
+
+// SourceHealth is the health of a source of a Config.
+type SourceHealth struct {
+	Name   string
+	Status HealthStatus
+	// Err is the error of the last load, nil when it succeeded without a fallback.
+	Err error
+	// Fallback describes what was served instead of the source, e.g. "last good snapshot".
+	Fallback string
+	// Failures counts the loads failed in a row, including degraded ones.
+	Failures    int
+	LastAttempt time.Time
+	LastSuccess time.Time
+}
+
+// FallbackReporter is implemented by sources that serve a fallback when loading fails, see Resilient.
+// Fallback describes what the last Load served and the error that caused it, served is empty when
+// the source itself was loaded.
+type FallbackReporter interface {
+	Source
+	Fallback() (served string, cause error)
+}
+
+// Health returns the health of every source in order.
+func (c *Config) Health() []SourceHealth {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	out := make([]SourceHealth, len(c.sources))
+	for i, s := range c.sources {
+		out[i] = SourceHealth{Name: s.Name()}
+		if i < len(c.health) {
+			out[i] = c.health[i]
+		}
+	}
+	return out
+}
+
+// recordHealth updates the health of the i-th source after a load with err.
+func (c *Config) recordHealth(i int, at time.Time, err error) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	for len(c.health) < len(c.sources) {
+		c.health = append(c.health, SourceHealth{Name: c.sources[len(c.health)].Name()})
+	}
+	s := c.sources[i]
+	h := c.health[i]
+	failures := h.Failures
+	h.LastAttempt, h.Err, h.Fallback = at, err, ""
+	switch {
+	case err != nil:
+		h.Status = StatusFailing
+		h.Failures++
+	default:
+		h.Status = StatusHealthy
+		h.LastSuccess = at
+		h.Failures = 0
+		if fr, ok := s.(FallbackReporter); ok {
+			if served, cause := fr.Fallback(); served != "" {
+				h.Status, h.Err, h.Fallback = StatusDegraded, cause, served
+				h.Failures = failures + 1
+			}
+		}
+	}
+	c.health[i] = h
+}
+
+// ResilienceOptions configures Resilient.
+type ResilienceOptions struct {
+	// Timeout bounds each load of the source, no limit when zero.
+	Timeout time.Duration
+	// LastGood serves the last successfully loaded tree when a load fails.
+	LastGood bool
+	// File is a local JSON file served when a load fails and there is no last good tree,
+	// e.g. a copy shipped with the deployment.
+	File string
+}
+
+// Resilient wraps a source, typically a remote store, so a failing or slow load falls back to the last
+// good snapshot or a local file instead of failing the Config. Fallbacks show up in Config.Health and
+// in provenance as the metadata "fallback".
+func Resilient(src Source, opts ResilienceOptions) Source {
+	return &resilientSource{Source: src, opts: opts}
+}
+
+type resilientSource struct {
+	Source
+	opts ResilienceOptions
+
+	mu       sync.Mutex
+	lastGood *slowjson.Node
+	served   string
+	cause    error
+}
+
+func (s *resilientSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+	n, err := s.Source.Load(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.served, s.cause = "", nil
+		if s.opts.LastGood {
+			s.lastGood = n
+		}
+		return n, nil
+	}
+	switch {
+	case s.lastGood != nil:
+		s.served, s.cause = "last good snapshot", err
+		return s.lastGood, nil
+	case s.opts.File != "":
+		fn, ferr := File(s.opts.File).Load(ctx)
+		if ferr != nil {
+			return nil, fmt.Errorf("%w, fallback %s: %v", err, s.opts.File, ferr)
+		}
+		s.served, s.cause = "file "+s.opts.File, err
+		return fn, nil
+	}
+	return nil, err
+}
+
+func (s *resilientSource) Fallback() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.served, s.cause
+}
+
+func (s *resilientSource) Metadata() map[string]string {
+	meta := map[string]string{}
+	if ms, ok := s.Source.(MetadataSource); ok {
+		for k, v := range ms.Metadata() {
+			meta[k] = v
+		}
+	}
+	if served, _ := s.Fallback(); served != "" {
+		meta["fallback"] = served
+	}
+	return meta
+}
+
+func (s *resilientSource) Changed(ctx context.Context) (bool, error) {
+	return SourceChanged(ctx, s.Source)
+}
@@ -0,0 +1,99 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// flakySource loads data until fail is set.
+type flakySource struct {
+	data  string
+	fail  error
+	delay time.Duration
+}
+
+func (s *flakySource) Name() string { return "remote" }
+
+func (s *flakySource) Load(ctx context.Context) (*slowjson.Node, error) {
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	p := slowjson.NewParser(s.data)
+	p.File = "remote"
+	return p.Parse()
+}
+
+func TestResilient(t *testing.T) {
+	remote := &flakySource{data: `{"port": 80}`}
+	c := NewConfig(Resilient(remote, ResilienceOptions{LastGood: true, Timeout: time.Second}))
+	if h := c.Health()[0]; h.Status != StatusUnknown || h.Name != "remote" {
+		t.Errorf("Health() before Load = %+v", h)
+	}
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Health()[0]; h.Status != StatusHealthy || h.LastSuccess.IsZero() {
+		t.Errorf("Health() = %+v", h)
+	}
+
+	remote.fail = errors.New("connection refused")
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() with last good snapshot error = %v", err)
+	}
+	h := c.Health()[0]
+	if h.Status != StatusDegraded || h.Fallback != "last good snapshot" || h.Failures != 1 || h.Err == nil {
+		t.Errorf("Health() = %+v", h)
+	}
+	if o, _ := c.Origin("port"); o.Meta["fallback"] != "last good snapshot" {
+		t.Errorf("Origin() = %v", o)
+	}
+
+	remote.fail = nil
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Health()[0]; h.Status != StatusHealthy || h.Failures != 0 || h.Fallback != "" {
+		t.Errorf("Health() after recovery = %+v", h)
+	}
+}
+
+func TestResilient_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fallback.json")
+	if err := os.WriteFile(path, []byte(`{"port": 8080}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	slow := &flakySource{data: `{"port": 80}`, delay: time.Minute}
+	c := NewConfig(Resilient(slow, ResilienceOptions{Timeout: 10 * time.Millisecond, File: path}))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("port").Value; got != "8080" {
+		t.Errorf("port = %s, want the fallback file", got)
+	}
+	h := c.Health()[0]
+	if h.Status != StatusDegraded || h.Fallback != "file "+path || !errors.Is(h.Err, context.DeadlineExceeded) {
+		t.Errorf("Health() = %+v", h)
+	}
+
+	failing := NewConfig(&flakySource{fail: errors.New("boom")})
+	if err := failing.Load(context.Background()); err == nil {
+		t.Fatal("Load() should fail without a fallback")
+	}
+	if h := failing.Health()[0]; h.Status != StatusFailing || h.Failures != 1 || !strings.Contains(h.Err.Error(), "boom") {
+		t.Errorf("Health() = %+v", h)
+	}
+}
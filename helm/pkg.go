@@ -0,0 +1,5 @@
+// Package helm loads the values of a Helm chart the way helm install and helm upgrade compute them: the
+// defaults of the chart's values.yaml, overridden by -f value files in order and then by --set and
+// --set-string expressions. Every input is a source of a tracedconfig.Config, so Explain answers which
+// file or --set expression set a value and what it overrode.
+package helm
@@ -0,0 +1,196 @@
+package helm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// maxIndex bounds list indexes of --set names like Helm does, a larger index is more likely a mistake
+// than a list of that length.
+const maxIndex = 65536
+
+// assignment is one name=value of a --set expression.
+type assignment struct {
+	path  slowjson.Path
+	value *slowjson.Node
+}
+
+// parseSet parses a --set expression like Helm: comma separated name=value pairs where a name is dotted
+// keys with [i] list indexes, e.g. servers[0].port, and a value is a scalar or a {a,b} list. A backslash
+// escapes the next character, e.g. \, or \. in a key. Values are typed like Helm types them: true, false,
+// null and integers without a leading zero, anything else is a string. With asString every value is a
+// string, like for --set-string. Nodes are positioned in expr, their File is file.
+func parseSet(expr, file string, asString bool) ([]assignment, error) {
+	p := &setParser{src: expr, file: file, asString: asString}
+	var as []assignment
+	for p.off < len(p.src) {
+		path, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, assignment{path: path, value: value})
+		if p.peek() == ',' {
+			p.off++
+		}
+	}
+	return as, nil
+}
+
+type setParser struct {
+	src, file string
+	off       int
+	asString  bool
+}
+
+func (p *setParser) peek() byte {
+	if p.off < len(p.src) {
+		return p.src[p.off]
+	}
+	return 0
+}
+
+func (p *setParser) pos(off int) slowjson.Position {
+	return slowjson.Position{File: p.file, Line: 1, Col: utf8.RuneCountInString(p.src[:off]) + 1, Offset: off}
+}
+
+func (p *setParser) errorf(off int, format string, args ...interface{}) error {
+	return &slowjson.ParseError{Pos: p.pos(off), Msg: fmt.Sprintf(format, args...)}
+}
+
+// text reads up to the first unescaped byte of stops or the end, removing the escapes.
+func (p *setParser) text(stops string) string {
+	var b strings.Builder
+	for p.off < len(p.src) && !strings.ContainsRune(stops, rune(p.src[p.off])) {
+		if p.src[p.off] == '\\' && p.off+1 < len(p.src) {
+			p.off++
+		}
+		b.WriteByte(p.src[p.off])
+		p.off++
+	}
+	return b.String()
+}
+
+// name reads a name and the = after it.
+func (p *setParser) name() (slowjson.Path, error) {
+	start := p.off
+	var path slowjson.Path
+	for {
+		keyStart := p.off
+		key := p.text(".[=,")
+		if key == "" {
+			return nil, p.errorf(keyStart, "expected a key")
+		}
+		path = path.Key(key)
+		for p.peek() == '[' {
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			path = path.Index(i)
+		}
+		switch p.peek() {
+		case '.':
+			p.off++
+		case '=':
+			p.off++
+			return path, nil
+		case ',', 0:
+			return nil, p.errorf(start, "key %q has no value", p.src[start:p.off])
+		default:
+			return nil, p.errorf(p.off, "expected ., [ or = after an index")
+		}
+	}
+}
+
+// index reads a [i] list index.
+func (p *setParser) index() (int, error) {
+	start := p.off
+	p.off++
+	digits := p.text("]")
+	if p.peek() != ']' {
+		return 0, p.errorf(start, "unterminated index")
+	}
+	p.off++
+	i, err := strconv.Atoi(digits)
+	switch {
+	case err != nil || i < 0:
+		return 0, p.errorf(start, "invalid index %q", digits)
+	case i > maxIndex:
+		return 0, p.errorf(start, "index %d is larger than the maximum %d", i, maxIndex)
+	}
+	return i, nil
+}
+
+// value reads a scalar or a {a,b} list.
+func (p *setParser) value() (*slowjson.Node, error) {
+	if p.peek() != '{' {
+		return p.scalar(","), nil
+	}
+	list := p.node(slowjson.NodeArray, "", p.off)
+	p.off++
+	for p.peek() != '}' {
+		if p.peek() == 0 {
+			return nil, p.errorf(list.StartOffset, "unterminated list")
+		}
+		item := p.scalar(",}")
+		item.Parent = list
+		list.Children = append(list.Children, item)
+		if p.peek() == ',' {
+			p.off++
+		}
+	}
+	p.off++
+	if c := p.peek(); c != ',' && c != 0 {
+		return nil, p.errorf(p.off, "expected , after the list")
+	}
+	p.end(list)
+	return list, nil
+}
+
+// scalar reads a value up to the first unescaped byte of stops and types it.
+func (p *setParser) scalar(stops string) *slowjson.Node {
+	start := p.off
+	text := p.text(stops)
+	n := p.node(slowjson.NodeString, text, start)
+	p.end(n)
+	if p.asString {
+		return n
+	}
+	switch {
+	case strings.EqualFold(text, "true"), strings.EqualFold(text, "false"):
+		n.Type, n.Value = slowjson.NodeBoolean, strings.ToLower(text)
+	case strings.EqualFold(text, "null"):
+		n.Type, n.Value = slowjson.NodeNull, "null"
+	case text == "0" || text != "" && text[0] != '0':
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			n.Type, n.Value = slowjson.NodeNumber, strconv.FormatInt(i, 10)
+		}
+	}
+	return n
+}
+
+func (p *setParser) node(typ slowjson.NodeType, value string, start int) *slowjson.Node {
+	pos := p.pos(start)
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   1,
+		StartCol:    pos.Col,
+		StartOffset: start,
+		File:        p.file,
+		Source:      p.src,
+	}
+}
+
+// end sets the end of n to the current position.
+func (p *setParser) end(n *slowjson.Node) {
+	n.EndLine, n.EndCol, n.EndOffset = 1, p.pos(p.off).Col, p.off
+}
@@ -0,0 +1,66 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		expr     string
+		asString bool
+		want     string
+	}{
+		{"image.tag=v2", false, `image.tag="v2"`},
+		{"replicas=3,debug=true,name=null,zero=0,octal=012,big=1e3,empty=", false, `replicas=3 debug=true name=null zero=0 octal="012" big="1e3" empty=""`},
+		{"replicas=3,debug=TRUE", true, `replicas="3" debug="TRUE"`},
+		{"servers[1].port=8080,matrix[0][2]=x", false, `servers[1].port=8080 matrix[0][2]="x"`},
+		{`hosts={a.example.com,b.example.com},ports={80,443}`, false, `hosts=["a.example.com","b.example.com"] ports=[80,443]`},
+		{`annotations.kubernetes\.io/ingress\.class=nginx,msg=a\,b`, false, `annotations["kubernetes.io/ingress.class"]="nginx" msg="a,b"`},
+		{"none={}", false, `none=[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			as, err := parseSet(tt.expr, "--set", tt.asString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range as {
+				b, err := slowjson.Marshal(a.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, a.path.String()+"="+string(b))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("parseSet() = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+
+	as, _ := parseSet("a=1,bé=x", "--set", false)
+	if v := as[1].value; v.Location() != "--set:1:8" || v.EndCol != 9 {
+		t.Errorf("value at %s to col %d", v.Location(), v.EndCol)
+	}
+
+	for _, tt := range []struct{ expr, want string }{
+		{"image.tag", `key "image.tag" has no value at --set:1:1`},
+		{"a=1,b", `key "b" has no value at --set:1:5`},
+		{"a..b=1", "expected a key at --set:1:3"},
+		{"=1", "expected a key at --set:1:1"},
+		{"a[x]=1", `invalid index "x" at --set:1:2`},
+		{"a[-1]=1", `invalid index "-1" at --set:1:2`},
+		{"a[1=1", "unterminated index at --set:1:2"},
+		{"a[70000]=1", "index 70000 is larger than the maximum 65536 at --set:1:2"},
+		{"a[0]b=1", "expected ., [ or = after an index at --set:1:5"},
+		{"a={1,2", "unterminated list at --set:1:3"},
+		{"a={1}x", "expected , after the list at --set:1:6"},
+	} {
+		if _, err := parseSet(tt.expr, "--set", false); err == nil || err.Error() != tt.want {
+			t.Errorf("parseSet(%q) error = %v, want %s", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+// Values are the inputs helm install and helm upgrade merge into the values of a chart.
+type Values struct {
+	// Chart is the chart directory, its values.yaml holds the defaults. A chart without one has none.
+	Chart string
+	// Files are the -f value files, later ones overriding earlier ones.
+	Files []string
+	// Set are the --set expressions, applied after the files in order.
+	Set []string
+	// SetString are the --set-string expressions, applied after Set. Their values are always strings.
+	SetString []string
+}
+
+// MergeOptions merge the sources of Values like Helm: maps key by key, lists and other values replaced,
+// and null in an override removing the default.
+var MergeOptions = merge.Options{NullUnsets: true}
+
+// Sources returns the sources of the values in the order Helm applies them: the chart defaults, the
+// files and the expressions. An expression is named after its flag, e.g. "--set image.tag=v2", and its
+// values are positioned in it. A list index in an expression changes one element of the list the files
+// or earlier expressions set, like Helm, while a list only the chart sets is replaced.
+func (v Values) Sources() []tracedconfig.Source {
+	sources := []tracedconfig.Source{&chartSource{path: filepath.Join(v.Chart, "values.yaml")}}
+	var user []string
+	for _, file := range v.Files {
+		sources = append(sources, yaml.File(file))
+		user = append(user, file)
+	}
+	names := map[string]int{}
+	add := func(flag, expr string) {
+		name := flag + " " + expr
+		// a repeated expression needs a name of its own to depend on the earlier one
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s (%d)", name, names[name])
+		}
+		sources = append(sources, &setSource{
+			name:      name,
+			flag:      flag,
+			expr:      expr,
+			asString:  flag == "--set-string",
+			dependsOn: append([]string(nil), user...),
+		})
+		user = append(user, name)
+	}
+	for _, expr := range v.Set {
+		add("--set", expr)
+	}
+	for _, expr := range v.SetString {
+		add("--set-string", expr)
+	}
+	return sources
+}
+
+// Config returns a Config over Sources merged with MergeOptions, call Load before reading it.
+func (v Values) Config() *tracedconfig.Config {
+	c := tracedconfig.NewConfig(v.Sources()...)
+	c.Merge = MergeOptions
+	return c
+}
+
+// chartSource reads the values.yaml of a chart, which may be missing.
+type chartSource struct {
+	path string
+}
+
+func (s *chartSource) Name() string {
+	return s.path
+}
+
+func (s *chartSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	n, err := yaml.File(s.path).Load(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &slowjson.Node{Type: slowjson.NodeObject}, nil
+	}
+	return n, err
+}
+
+// setSource is a --set or --set-string expression. It depends on the value files and the expressions
+// before it, whose lists it indexes into.
+type setSource struct {
+	name, flag, expr string
+	asString         bool
+	dependsOn        []string
+}
+
+func (s *setSource) Name() string {
+	return s.name
+}
+
+func (s *setSource) DependsOn() []string {
+	return s.dependsOn
+}
+
+func (s *setSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	return s.LoadWith(ctx, &slowjson.Node{Type: slowjson.NodeObject})
+}
+
+func (s *setSource) LoadWith(ctx context.Context, deps *slowjson.Node) (*slowjson.Node, error) {
+	as, err := parseSet(s.expr, s.flag, s.asString)
+	if err != nil {
+		return nil, err
+	}
+	root := &slowjson.Node{Type: slowjson.NodeObject, StartLine: 1, StartCol: 1, File: s.flag, Source: s.expr}
+	for _, a := range as {
+		put(root, deps, a)
+	}
+	return root, nil
+}
+
+// put sets a.value at a.path below root, creating objects and lists on the way. Created keys and objects
+// take the position of the value. A list the path indexes into starts as a copy of the list at the same
+// path in base, if any, and is padded with nulls up to the index.
+func put(root, base *slowjson.Node, a assignment) {
+	cur := root
+	for i, seg := range a.path {
+		var next *slowjson.Node
+		var key *slowjson.Node
+		if seg.IsIndex {
+			for len(cur.Children) <= seg.Index {
+				cur.Children = append(cur.Children, synthetic(slowjson.NodeNull, "null", a.value, cur))
+			}
+			next = cur.Children[seg.Index]
+		} else {
+			for _, k := range cur.Children {
+				if k.Value == seg.Key {
+					key = k
+				}
+			}
+			if key == nil {
+				key = synthetic(slowjson.NodeString, seg.Key, a.value, cur)
+				cur.Children = append(cur.Children, key)
+			}
+			if len(key.Children) > 0 {
+				next = key.Children[0]
+			}
+		}
+		if i < len(a.path)-1 {
+			want := slowjson.NodeObject
+			if a.path[i+1].IsIndex {
+				want = slowjson.NodeArray
+			}
+			if next != nil && next.Type == want {
+				cur = next
+				continue
+			}
+			next = synthetic(want, "", a.value, nil)
+			if prev := base.Lookup(a.path[:i+1]); want == slowjson.NodeArray && prev != nil && prev.Type == slowjson.NodeArray {
+				next = clone(prev, nil)
+			}
+		} else {
+			next = a.value
+		}
+		if seg.IsIndex {
+			next.Parent = cur
+			cur.Children[seg.Index] = next
+		} else {
+			next.Parent = key
+			key.Children = []*slowjson.Node{next}
+		}
+		cur = next
+	}
+}
+
+// synthetic creates a node at the position of at below parent.
+func synthetic(typ slowjson.NodeType, value string, at, parent *slowjson.Node) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		Parent:      parent,
+		StartLine:   at.StartLine,
+		StartCol:    at.StartCol,
+		EndLine:     at.EndLine,
+		EndCol:      at.EndCol,
+		StartOffset: at.StartOffset,
+		EndOffset:   at.EndOffset,
+		File:        at.File,
+		Source:      at.Source,
+	}
+}
+
+// clone deep copies n below parent.
+func clone(n, parent *slowjson.Node) *slowjson.Node {
+	c := *n
+	c.Parent = parent
+	c.Children = make([]*slowjson.Node, len(n.Children))
+	for i, child := range n.Children {
+		c.Children[i] = clone(child, &c)
+	}
+	return &c
+}
@@ -0,0 +1,87 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValues_Config(t *testing.T) {
+	chart := t.TempDir()
+	writeFile(t, chart, "values.yaml", `image:
+  repository: nginx
+  tag: "1.25"
+replicas: 1
+resources:
+  limits: {cpu: 500m}
+ports: [80]
+servers:
+  - {name: a, port: 80}
+`)
+	dir := t.TempDir()
+	staging := writeFile(t, dir, "staging.yaml", "image:\n  tag: 1.26-rc\nservers:\n  - {name: a, port: 8080}\n  - {name: b, port: 8081}\n")
+	prod := writeFile(t, dir, "prod.yaml", "replicas: 3\nresources: null\n")
+
+	c := Values{
+		Chart:     chart,
+		Files:     []string{staging, prod},
+		Set:       []string{"image.tag=1.26,servers[1].port=9090", "ports[1]=443"},
+		SetString: []string{"replicas=5"},
+	}.Config()
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := slowjson.Marshal(c.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"image":{"repository":"nginx","tag":"1.26"},"replicas":"5","ports":[null,443],"servers":[{"name":"a","port":8080},{"name":"b","port":9090}]}`
+	if string(b) != want {
+		t.Errorf("Root() = %s\nwant %s", b, want)
+	}
+
+	got, err := c.Explain("image.tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `image.tag = "1.26"
+  set by --set:1:11 (--set image.tag=1.26,servers[1].port=9090)
+  overrides "1.26-rc" from ` + staging + `:2:8 (` + staging + `)
+  overrides "1.25" from ` + filepath.Join(chart, "values.yaml") + `:3:8 (` + filepath.Join(chart, "values.yaml") + `)
+`
+	if got != want {
+		t.Errorf("Explain(image.tag) =\n%s\nwant\n%s", got, want)
+	}
+	if o, ok := c.Origin("resources"); !ok || !o.Unset || o.Layer != prod {
+		t.Errorf("Origin(resources) = %+v", o)
+	}
+	// the --set rewrites the list, elements it copies keep the position of their value file
+	if o, ok := c.Origin("servers[0].port"); !ok || o.Layer != "--set image.tag=1.26,servers[1].port=9090" || o.Node.Location() != staging+":4:21" {
+		t.Errorf("Origin(servers[0].port) = %+v", o)
+	}
+
+	// a chart without values.yaml has no defaults, a repeated expression applies twice
+	c = Values{Chart: t.TempDir(), Set: []string{"a[0]=x", "a[1]=y", "a[0]=x"}}.Config()
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := slowjson.Marshal(c.Root()); string(b) != `{"a":["x","y"]}` {
+		t.Errorf("Root() = %s", b)
+	}
+	c = Values{Chart: t.TempDir(), Set: []string{"a"}}.Config()
+	if err := c.Load(context.Background()); err == nil || err.Error() != `load --set a: key "a" has no value at --set:1:1` {
+		t.Errorf("Load() error = %v", err)
+	}
+}
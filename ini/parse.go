@@ -0,0 +1,194 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Options configures Parse.
+type Options struct {
+	// Repeated reads keys like systemd reads unit files: every key is a list of the values assigned to
+	// it in order, e.g. ExecStartPre, and assigning the empty string empties the list. Each value keeps
+	// the position of its own assignment, and the list that of the assignment it started at. Without
+	// Repeated a key may be assigned once per section.
+	Repeated bool
+}
+
+// Parse parses an INI file into an object of its sections, see Options for repeated keys. file is
+// recorded as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	return Options{}.Parse(data, file)
+}
+
+// Parse parses an INI file into an object of its sections.
+func (o Options) Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{opts: o, src: string(data), file: file}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	slowjson.SetParents(root)
+	slowjson.AttachComments(p.src, []*slowjson.Node{root}, "#", ";")
+	return root, nil
+}
+
+type parser struct {
+	opts Options
+	src  string
+	file string
+
+	// the current line, without the line break, and where it starts
+	line        string
+	lineNo, off int
+
+	top      *section
+	sections map[string]*section
+	cur      *section
+}
+
+// pos returns the position of byte i of the current line.
+func (p *parser) pos(i int) slowjson.Position {
+	return slowjson.Position{File: p.file, Line: p.lineNo, Col: 1 + utf8.RuneCountInString(p.line[:i]), Offset: p.off + i}
+}
+
+func (p *parser) errorf(i int, format string, args ...interface{}) error {
+	return &slowjson.ParseError{Pos: p.pos(i), Msg: fmt.Sprintf(format, args...)}
+}
+
+// span returns a node for bytes start to end of the current line.
+func (p *parser) span(typ slowjson.NodeType, value string, start, end int) *slowjson.Node {
+	s, e := p.pos(start), p.pos(end)
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   s.Line,
+		StartCol:    s.Col,
+		StartOffset: s.Offset,
+		EndLine:     e.Line,
+		EndCol:      e.Col,
+		EndOffset:   e.Offset,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+// extend moves the end of n to that of last.
+func extend(n, last *slowjson.Node) {
+	n.EndLine, n.EndCol, n.EndOffset = last.EndLine, last.EndCol, last.EndOffset
+}
+
+// section is an object being filled, with its keys by name.
+type section struct {
+	obj  *slowjson.Node
+	keys map[string]*slowjson.Node
+}
+
+func (p *parser) parse() (*slowjson.Node, error) {
+	root := &slowjson.Node{Type: slowjson.NodeObject, StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1, Source: p.src, File: p.file}
+	p.top = &section{obj: root, keys: map[string]*slowjson.Node{}}
+	p.sections, p.cur = map[string]*section{}, p.top
+	rest := p.src
+	if strings.HasPrefix(rest, "\uFEFF") {
+		rest = rest[len("\uFEFF"):]
+		p.off = len("\uFEFF")
+	}
+	for p.lineNo = 1; rest != ""; p.lineNo++ {
+		p.line, rest, _ = strings.Cut(rest, "\n")
+		p.line = strings.TrimSuffix(p.line, "\r")
+		if err := p.parseLine(); err != nil {
+			return nil, err
+		}
+		end := p.pos(len(p.line))
+		root.EndLine, root.EndCol, root.EndOffset = end.Line, end.Col, end.Offset
+		p.off += len(p.line)
+		if strings.HasPrefix(p.src[p.off:], "\r") {
+			p.off++
+		}
+		p.off++
+	}
+	return root, nil
+}
+
+func (p *parser) parseLine() error {
+	trimmed := strings.TrimLeft(p.line, " \t")
+	indent := len(p.line) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, " \t")
+	switch {
+	case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+		return nil
+	case trimmed[0] == '[':
+		end := strings.IndexByte(trimmed, ']')
+		if end < 0 {
+			return p.errorf(indent+len(trimmed), "expected ] to close the section")
+		}
+		if end != len(trimmed)-1 {
+			return p.errorf(indent+end+1, "expected the end of the line after the section")
+		}
+		name := strings.TrimSpace(trimmed[1:end])
+		if name == "" {
+			return p.errorf(indent+1, "expected a section name")
+		}
+		if s, ok := p.sections[name]; ok {
+			p.cur = s
+			return nil
+		}
+		start := indent + 1 + strings.Index(trimmed[1:], name)
+		if prev := p.top.keys[name]; prev != nil {
+			return p.errorf(start, "section %s is also a key at %s", name, prev.Location())
+		}
+		key := p.span(slowjson.NodeString, name, start, start+len(name))
+		obj := p.span(slowjson.NodeObject, "", indent, indent+len(trimmed))
+		key.Children = []*slowjson.Node{obj}
+		p.top.obj.Children = append(p.top.obj.Children, key)
+		p.cur = &section{obj: obj, keys: map[string]*slowjson.Node{}}
+		p.sections[name] = p.cur
+		return nil
+	}
+	eq := strings.IndexByte(trimmed, '=')
+	if eq < 0 {
+		return p.errorf(indent+len(trimmed), "expected = after %s", trimmed)
+	}
+	name := strings.TrimRight(trimmed[:eq], " \t")
+	if name == "" {
+		return p.errorf(indent, "expected a key before =")
+	}
+	value := strings.TrimLeft(trimmed[eq+1:], " \t")
+	vstart := indent + len(trimmed) - len(value)
+	key := p.span(slowjson.NodeString, name, indent, indent+len(name))
+	val := p.span(slowjson.NodeString, value, vstart, vstart+len(value))
+	s := p.cur
+	prev := s.keys[name]
+	switch {
+	case !p.opts.Repeated && prev != nil:
+		return p.errorf(indent, "key %s is already set at %s", name, prev.Location())
+	case !p.opts.Repeated:
+		key.Children = []*slowjson.Node{val}
+		s.obj.Children = append(s.obj.Children, key)
+		s.keys[name] = key
+	case prev == nil || value == "":
+		// a new list, starting at this assignment
+		list := p.span(slowjson.NodeArray, "", vstart, vstart+len(value))
+		if value != "" {
+			list.Children = []*slowjson.Node{val}
+		}
+		key.Children = []*slowjson.Node{list}
+		if prev == nil {
+			s.obj.Children = append(s.obj.Children, key)
+		} else {
+			*prev = *key
+			key = prev
+		}
+		s.keys[name] = key
+	default:
+		list := prev.Children[0]
+		list.Children = append(list.Children, val)
+		extend(list, val)
+	}
+	if s != p.top {
+		extend(s.obj, val)
+	}
+	return nil
+}
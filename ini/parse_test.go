@@ -0,0 +1,96 @@
+package ini
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := "\uFEFFname = app\r\n; a comment\r\n[server]\r\n  host = 0.0.0.0  \r\nport=8080\r\n\r\n# another\r\n[ database ]\r\nurl = postgres://db/app?ssl=true\r\n[server]\r\nempty =\r\n"
+	n, err := Parse([]byte(input), "app.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"app","server":{"host":"0.0.0.0","port":"8080","empty":""},"database":{"url":"postgres://db/app?ssl=true"}}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	host := n.Get("server.host")
+	if host.Location() != "app.ini:4:10" || host.EndCol != 17 || host.Parent.Parent.Parent.Value != "server" {
+		t.Errorf("server.host at %s to col %d", host.Location(), host.EndCol)
+	}
+	if url := n.Get("database.url"); url.StartOffset != len(input)-len("postgres://db/app?ssl=true\r\n[server]\r\nempty =\r\n") {
+		t.Errorf("database.url at offset %d", url.StartOffset)
+	}
+	if empty := n.Get("server.empty"); empty.Location() != "app.ini:11:8" {
+		t.Errorf("server.empty at %s", empty.Location())
+	}
+}
+
+func TestParse_Repeated(t *testing.T) {
+	input := `[Service]
+ExecStartPre=/bin/mkdir -p /run/app
+ExecStartPre=/bin/chown app /run/app
+Environment=A=1
+Environment=
+Environment=B=2
+Environment=C=3
+Type=simple
+[Install]
+WantedBy=multi-user.target
+[Service]
+ExecStartPre=
+`
+	n, err := Options{Repeated: true}.Parse([]byte(input), "app.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Service":{"ExecStartPre":[],"Environment":["B=2","C=3"],"Type":["simple"]},"Install":{"WantedBy":["multi-user.target"]}}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	env := n.Get("Service.Environment")
+	if env.Location() != "app.service:5:13" || env.EndLine != 7 || env.Parent.Location() != "app.service:5:1" {
+		t.Errorf("Service.Environment at %s to line %d, key at %s", env.Location(), env.EndLine, env.Parent.Location())
+	}
+	if c := env.Children[1]; c.Location() != "app.service:7:13" || c.Parent != env {
+		t.Errorf("Service.Environment[1] at %s", c.Location())
+	}
+	if pre := n.Get("Service.ExecStartPre"); pre.Location() != "app.service:12:14" || n.Get("Service").Children[0].Value != "ExecStartPre" {
+		t.Errorf("Service.ExecStartPre reset at %s", pre.Location())
+	}
+
+	if _, err := Parse([]byte(input), "app.service"); err == nil || err.Error() != "key ExecStartPre is already set at app.service:2:1 at app.service:3:1" {
+		t.Errorf("Parse() without Repeated error = %v", err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[a", `expected ] to close the section at f.ini:1:3`},
+		{"[a] x", `expected the end of the line after the section at f.ini:1:4`},
+		{"[ ]", `expected a section name at f.ini:1:2`},
+		{"a = 1\n[a]", `section a is also a key at f.ini:1:1 at f.ini:2:2`},
+		{"[a]\n  value", `expected = after value at f.ini:2:8`},
+		{"= 1", `expected a key before = at f.ini:1:1`},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.input), "f.ini")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %s", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package ini parses INI files and systemd units into slowjson nodes, every section, key and value
+// keeping the line and column it was written at.
+//
+// Sections become objects of the root, keys before the first section members of the root. Values are
+// strings with the surrounding whitespace trimmed. Lines starting with # or ; are comments. A section
+// given more than once continues the earlier one, like systemd and Python's configparser read them.
+// Unit reads repeated keys as lists the way systemd does, see Options.Repeated.
+package ini
@@ -0,0 +1,39 @@
+package ini
+
+import (
+	"context"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FileSource is a config source reading an INI file.
+type FileSource struct {
+	Path    string
+	Options Options
+}
+
+// File creates a source reading the INI file at path, with keys assigned once per section.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Unit creates a source reading the systemd unit at path, with repeated keys as lists. The lists of a
+// later source, e.g. a drop-in, replace those of earlier ones as a whole, as if it emptied them first.
+func Unit(path string) *FileSource {
+	return &FileSource{Path: path, Options: Options{Repeated: true}}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Options.Parse(b, s.Path)
+}
@@ -0,0 +1,47 @@
+package ini
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	unit := writeFile(t, dir, "app.service", "[Service]\nEnvironment=A=1\nEnvironment=B=2\n")
+	dropIn := writeFile(t, dir, "override.conf", "[Service]\nEnvironment=\nEnvironment=C=3\n")
+	c := tracedconfig.NewConfig(Unit(unit), Unit(dropIn))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg struct {
+		Service struct {
+			Environment []string
+		}
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Service.Environment) != 1 || cfg.Service.Environment[0] != "C=3" {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if n := c.Get("Service.Environment[0]"); n.File != dropIn || n.StartLine != 3 {
+		t.Errorf("Service.Environment[0] set at %s:%d", n.File, n.StartLine)
+	}
+
+	if _, err := File(unit).Load(context.Background()); err == nil {
+		t.Error("Load() of repeated keys without Repeated succeeded")
+	}
+}
@@ -0,0 +1,183 @@
+// Package benchcmp compares go test -bench output with a committed baseline so parser changes can show
+// their wins and regressions are caught before they are merged.
+package benchcmp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is one benchmark, the best of its runs when it ran several times with -count.
+type Result struct {
+	Name        string
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// Parse reads the benchmark lines of go test -bench output, other lines are skipped. Names lose their
+// -GOMAXPROCS suffix so baselines compare across machines. Every metric keeps its lowest value across
+// runs, which is the least disturbed by noise.
+func Parse(r io.Reader) (map[string]Result, error) {
+	results := map[string]Result{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := fields[0]
+		if i := strings.LastIndex(name, "-"); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		cur := Result{Name: name, NsPerOp: -1, BytesPerOp: -1, AllocsPerOp: -1}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid value %q", name, fields[i])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				cur.NsPerOp = v
+			case "B/op":
+				cur.BytesPerOp = v
+			case "allocs/op":
+				cur.AllocsPerOp = v
+			}
+		}
+		if prev, ok := results[name]; ok {
+			cur.NsPerOp = best(prev.NsPerOp, cur.NsPerOp)
+			cur.BytesPerOp = best(prev.BytesPerOp, cur.BytesPerOp)
+			cur.AllocsPerOp = best(prev.AllocsPerOp, cur.AllocsPerOp)
+		}
+		results[name] = cur
+	}
+	return results, sc.Err()
+}
+
+// best returns the lower of two metrics, -1 stands for not reported.
+func best(a, b float64) float64 {
+	switch {
+	case a < 0:
+		return b
+	case b < 0:
+		return a
+	}
+	return math.Min(a, b)
+}
+
+// Thresholds are how much slower or bigger a benchmark may get before it regresses, as a fraction of the
+// baseline, e.g. 0.1 allows 10%. A negative threshold does not check the metric, e.g. time when the
+// baseline was recorded on another machine.
+type Thresholds struct {
+	Time   float64
+	Bytes  float64
+	Allocs float64
+}
+
+// Delta compares a benchmark with its baseline. Ratios are current over baseline, 0 when either side did
+// not report the metric.
+type Delta struct {
+	Name                string
+	Base, Current       Result
+	Time, Bytes, Allocs float64
+	Regressed           bool
+	Missing, New        bool
+}
+
+// Compare matches the current results with the baseline by name, in name order. A benchmark regresses
+// when a ratio exceeds 1 plus its threshold. Benchmarks only in the baseline are Missing, which counts
+// as a regression so a renamed benchmark also updates the baseline, and those only in current are New.
+func Compare(base, current map[string]Result, t Thresholds) []Delta {
+	names := map[string]bool{}
+	for name := range base {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	var deltas []Delta
+	for name := range names {
+		b, inBase := base[name]
+		c, inCurrent := current[name]
+		d := Delta{Name: name, Base: b, Current: c, Missing: !inCurrent, New: !inBase}
+		if inBase && inCurrent {
+			d.Time = ratio(b.NsPerOp, c.NsPerOp)
+			d.Bytes = ratio(b.BytesPerOp, c.BytesPerOp)
+			d.Allocs = ratio(b.AllocsPerOp, c.AllocsPerOp)
+			d.Regressed = exceeds(d.Time, t.Time) || exceeds(d.Bytes, t.Bytes) || exceeds(d.Allocs, t.Allocs)
+		}
+		d.Regressed = d.Regressed || d.Missing
+		deltas = append(deltas, d)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Name < deltas[j].Name })
+	return deltas
+}
+
+// exceeds reports whether r is above 1 plus threshold, never for a negative threshold.
+func exceeds(r, threshold float64) bool {
+	return threshold >= 0 && r > 1+threshold
+}
+
+// ratio is current over base, a zero base only compares with zero.
+func ratio(base, current float64) float64 {
+	switch {
+	case base < 0 || current < 0:
+		return 0
+	case base == 0 && current == 0:
+		return 1
+	case base == 0:
+		return math.Inf(1)
+	}
+	return current / base
+}
+
+// Write prints a table of the deltas with the change of each metric in percent, regressions are marked.
+func Write(w io.Writer, deltas []Delta) error {
+	for _, d := range deltas {
+		var line string
+		switch {
+		case d.Missing:
+			line = fmt.Sprintf("%-40s missing from the current run", d.Name)
+		case d.New:
+			line = fmt.Sprintf("%-40s new, %s ns/op %s B/op %s allocs/op", d.Name,
+				metric(d.Current.NsPerOp), metric(d.Current.BytesPerOp), metric(d.Current.AllocsPerOp))
+		default:
+			line = fmt.Sprintf("%-40s time %8s  bytes %8s  allocs %8s", d.Name, percent(d.Time), percent(d.Bytes), percent(d.Allocs))
+		}
+		if d.Regressed {
+			line += "  REGRESSION"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func metric(v float64) string {
+	if v < 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func percent(r float64) string {
+	switch {
+	case r == 0:
+		return "-"
+	case math.IsInf(r, 1):
+		return "+inf"
+	}
+	return fmt.Sprintf("%+.1f%%", (r-1)*100)
+}
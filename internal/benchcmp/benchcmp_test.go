@@ -0,0 +1,69 @@
+package benchcmp
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseline = `goos: linux
+pkg: github.com/at15/tracedconfig/slowjson
+BenchmarkParse/small-8     	   10000	     20000 ns/op	  12.29 MB/s	    9000 B/op	     100 allocs/op
+BenchmarkParse/small-8     	   10000	     18000 ns/op	  12.29 MB/s	    9000 B/op	     100 allocs/op
+BenchmarkParse/large-8     	      10	  25000000 ns/op	    9000000 B/op	  100000 allocs/op
+BenchmarkPrint/small-8     	  100000	      1000 ns/op
+PASS
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(baseline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Parse() = %v, want 3 results", got)
+	}
+	small := got["BenchmarkParse/small"]
+	if small.NsPerOp != 18000 || small.BytesPerOp != 9000 || small.AllocsPerOp != 100 {
+		t.Errorf("Parse() small = %+v, want the best of both runs", small)
+	}
+	if p := got["BenchmarkPrint/small"]; p.NsPerOp != 1000 || p.AllocsPerOp != -1 {
+		t.Errorf("Parse() print = %+v", p)
+	}
+	if _, err := Parse(strings.NewReader("BenchmarkX-8 10 abc ns/op\n")); err == nil {
+		t.Error("Parse() accepted an invalid value")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base, _ := Parse(strings.NewReader(baseline))
+	current, _ := Parse(strings.NewReader(`
+BenchmarkParse/small-4     	   10000	     19000 ns/op	    9000 B/op	     101 allocs/op
+BenchmarkParse/large-4     	      10	  12000000 ns/op	    4000000 B/op	   50000 allocs/op
+BenchmarkMarshal/small-4   	   10000	      5000 ns/op	    1000 B/op	      10 allocs/op
+`))
+	deltas := Compare(base, current, Thresholds{Time: 0.2, Bytes: 0.1})
+	want := map[string]bool{
+		"BenchmarkMarshal/small": false, // new
+		"BenchmarkParse/large":   false, // faster
+		"BenchmarkParse/small":   true,  // one more allocation
+		"BenchmarkPrint/small":   true,  // missing
+	}
+	if d := Compare(base, current, Thresholds{Time: -1, Bytes: -1, Allocs: -1}); !d[3].Missing || d[2].Regressed {
+		t.Errorf("Compare() without thresholds = %+v", d)
+	}
+	if len(deltas) != len(want) {
+		t.Fatalf("Compare() = %+v", deltas)
+	}
+	for _, d := range deltas {
+		if d.Regressed != want[d.Name] {
+			t.Errorf("Compare() %s regressed = %t, want %t", d.Name, d.Regressed, want[d.Name])
+		}
+	}
+	var sb strings.Builder
+	Write(&sb, deltas)
+	for _, s := range []string{"BenchmarkParse/large", "time   -52.0%", "allocs    +1.0%  REGRESSION", "missing from the current run", "new, 5000 ns/op"} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("Write() =\n%s\nmissing %q", sb.String(), s)
+		}
+	}
+}
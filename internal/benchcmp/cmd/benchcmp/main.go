@@ -0,0 +1,60 @@
+// Command benchcmp compares go test -bench output read from stdin with a baseline file and exits 1 on
+// a regression:
+//
+//	go test -run '^$' -bench . -benchmem -count 5 ./slowjson | go run ./internal/benchcmp/cmd/benchcmp slowjson/testdata/bench.txt
+//
+// Allocations and bytes are checked by default since they do not depend on the machine, pass -time to
+// also check ns/op when the baseline was recorded on the same machine. Update the baseline by saving the
+// benchmark output to it, see make bench-baseline.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/at15/tracedconfig/internal/benchcmp"
+)
+
+func main() {
+	var t benchcmp.Thresholds
+	flag.Float64Var(&t.Time, "time", -1, "allowed slowdown in ns/op as a fraction of the baseline, negative to not check time")
+	flag.Float64Var(&t.Bytes, "bytes", 0.1, "allowed growth in B/op as a fraction of the baseline")
+	flag.Float64Var(&t.Allocs, "allocs", 0, "allowed growth in allocs/op as a fraction of the baseline")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: go test -bench . -benchmem | benchcmp [flags] baseline")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchcmp: %v\n", err)
+		os.Exit(1)
+	}
+	base, err := benchcmp.Parse(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchcmp: %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+	current, err := benchcmp.Parse(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchcmp: %v\n", err)
+		os.Exit(1)
+	}
+	if len(current) == 0 {
+		fmt.Fprintln(os.Stderr, "benchcmp: no benchmark results on stdin")
+		os.Exit(1)
+	}
+	deltas := benchcmp.Compare(base, current, t)
+	benchcmp.Write(os.Stdout, deltas)
+	for _, d := range deltas {
+		if d.Regressed {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package suggest finds likely intended names for misspelled keys.
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// Distance returns the Levenshtein edit distance between a and b, counting runes.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// MaxDistance is how many edits a name may be away from a candidate to be suggested.
+// It grows with the length so short names do not match everything.
+func MaxDistance(name string) int {
+	switch n := len([]rune(name)); {
+	case n <= 3:
+		return 1
+	case n <= 8:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Closest returns the candidate nearest to name, ignoring case, if it is within MaxDistance.
+// Ties go to the earlier candidate.
+func Closest(name string, candidates []string) (string, bool) {
+	best, bestDist := "", MaxDistance(name)+1
+	lower := strings.ToLower(name)
+	for _, c := range candidates {
+		if d := Distance(lower, strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best, best != ""
+}
+
+// All returns the candidates within MaxDistance of name, ignoring case, nearest first.
+// Ties keep the order of candidates.
+func All(name string, candidates []string) []string {
+	type match struct {
+		c string
+		d int
+	}
+	var matches []match
+	lower, max := strings.ToLower(name), MaxDistance(name)
+	for _, c := range candidates {
+		if d := Distance(lower, strings.ToLower(c)); d <= max {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].d < matches[j].d })
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.c
+	}
+	return out
+}
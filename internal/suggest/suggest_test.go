@@ -0,0 +1,55 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"port", "port", 0},
+		{"port", "prot", 2},
+		{"timeout", "timout", 1},
+		{"host", "", 4},
+		{"héllo", "hello", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"host", "port", "timeout", "max_connections"}
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"timout", "timeout", true},
+		{"Prot", "port", true},
+		{"max_conections", "max_connections", true},
+		{"hots", "host", true},
+		{"debug", "", false},
+		{"ab", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Closest(tt.name, candidates)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Closest(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	got := All("dbug", []string{"info", "Debug", "dug", "warn", "debug"})
+	if len(got) != 3 || got[0] != "Debug" || got[1] != "dug" || got[2] != "debug" {
+		t.Errorf("All() = %v, want [Debug dug debug]", got)
+	}
+	if got := All("trace", []string{"info", "warn"}); len(got) != 0 {
+		t.Errorf("All() = %v, want none", got)
+	}
+}
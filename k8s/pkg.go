@@ -0,0 +1,4 @@
+// Package k8s validates Kubernetes manifests, routing every document by its apiVersion and kind to the
+// schema of that kind in an OpenAPI spec, e.g. the one served by a cluster at /openapi/v2. Findings are
+// diagnostics, so they render with source snippets like the other validators of this module.
+package k8s
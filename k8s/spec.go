@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/schema"
+)
+
+// Spec holds the schemas of the kinds of a Kubernetes OpenAPI spec.
+type Spec struct {
+	defs map[string]*schema.Schema
+	// kinds maps a kind to the apiVersions serving it and the name of its definition.
+	kinds map[string]map[string]string
+}
+
+// groupVersionKind is an entry of the x-kubernetes-group-version-kind extension of a definition.
+type groupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// apiVersion is the apiVersion of manifests of the kind, "v1" for the core group and "group/version"
+// otherwise.
+func (gvk groupVersionKind) apiVersion() string {
+	if gvk.Group == "" {
+		return gvk.Version
+	}
+	return gvk.Group + "/" + gvk.Version
+}
+
+// ParseSpec reads an OpenAPI spec, Swagger 2.0 with definitions like the /openapi/v2 endpoint of the API
+// server or OpenAPI 3 with components.schemas. Definitions become schemas with their $refs resolved, and
+// those with an x-kubernetes-group-version-kind are the kinds manifests are validated against.
+// Integer-or-string fields, e.g. a port name or number, and quantities like "500m" or 2 accept both types.
+func ParseSpec(data []byte) (*Spec, error) {
+	var doc struct {
+		Definitions map[string]*schema.Schema `json:"definitions"`
+		Components  struct {
+			Schemas map[string]*schema.Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+	defs := doc.Definitions
+	if len(defs) == 0 {
+		defs = doc.Components.Schemas
+	}
+	if len(defs) == 0 {
+		return nil, errors.New("invalid OpenAPI spec: it has neither definitions nor components.schemas")
+	}
+	s := &Spec{defs: defs, kinds: map[string]map[string]string{}}
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.resolve(defs[name], map[*schema.Schema]bool{}); err != nil {
+			return nil, fmt.Errorf("definition %s: %w", name, err)
+		}
+		switch {
+		case strings.HasSuffix(name, ".util.intstr.IntOrString"):
+			either(defs[name], "integer")
+		case strings.HasSuffix(name, ".api.resource.Quantity"):
+			either(defs[name], "number")
+		}
+		raw, ok := defs[name].Extra["x-kubernetes-group-version-kind"]
+		if !ok {
+			continue
+		}
+		var gvks []groupVersionKind
+		if err := json.Unmarshal(raw, &gvks); err != nil {
+			return nil, fmt.Errorf("definition %s: invalid x-kubernetes-group-version-kind: %w", name, err)
+		}
+		for _, gvk := range gvks {
+			if s.kinds[gvk.Kind] == nil {
+				s.kinds[gvk.Kind] = map[string]string{}
+			}
+			s.kinds[gvk.Kind][gvk.apiVersion()] = name
+		}
+	}
+	return s, nil
+}
+
+// Schema returns the schema of manifests of kind in apiVersion, e.g. "Deployment" in "apps/v1".
+func (s *Spec) Schema(apiVersion, kind string) (*schema.Schema, bool) {
+	name, ok := s.kinds[kind][apiVersion]
+	if !ok {
+		return nil, false
+	}
+	return s.defs[name], true
+}
+
+// APIVersions returns the apiVersions serving kind, sorted.
+func (s *Spec) APIVersions(kind string) []string {
+	var versions []string
+	for v := range s.kinds[kind] {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Kinds returns every kind of the spec, sorted.
+func (s *Spec) Kinds() []string {
+	kinds := make([]string, 0, len(s.kinds))
+	for k := range s.kinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// resolve replaces the $refs below sc with the definitions they point to, in place. Definitions refer to
+// each other in cycles, e.g. JSONSchemaProps, so the schemas form a graph and visited stops the walk.
+func (s *Spec) resolve(sc *schema.Schema, visited map[*schema.Schema]bool) error {
+	if sc == nil || visited[sc] {
+		return nil
+	}
+	visited[sc] = true
+	if preserve, ok := sc.Extra["x-kubernetes-preserve-unknown-fields"]; ok && string(preserve) == "true" && sc.AdditionalProperties == nil {
+		sc.AdditionalProperties = &schema.Schema{}
+	}
+	if intOrString, ok := sc.Extra["x-kubernetes-int-or-string"]; ok && string(intOrString) == "true" || sc.Format == "int-or-string" {
+		either(sc, "integer")
+	}
+	children := []**schema.Schema{&sc.AdditionalProperties, &sc.Items, &sc.If, &sc.Then, &sc.Else}
+	for i := range sc.Properties {
+		children = append(children, &sc.Properties[i].Schema)
+	}
+	for i := range sc.AnyOf {
+		children = append(children, &sc.AnyOf[i])
+	}
+	for i := range sc.OneOf {
+		children = append(children, &sc.OneOf[i])
+	}
+	for _, child := range children {
+		if *child == nil {
+			continue
+		}
+		target, err := s.target(*child)
+		if err != nil {
+			return err
+		}
+		*child = target
+		if err := s.resolve(target, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// target returns the definition sc refers to with $ref, or with an allOf of a single $ref like OpenAPI 3
+// specs write references with a description or default, and sc itself otherwise.
+func (s *Spec) target(sc *schema.Schema) (*schema.Schema, error) {
+	if raw, ok := sc.Extra["allOf"]; ok {
+		var all []*schema.Schema
+		if err := json.Unmarshal(raw, &all); err != nil {
+			return nil, fmt.Errorf("invalid allOf: %w", err)
+		}
+		if len(all) == 1 {
+			sc = all[0]
+		}
+	}
+	raw, ok := sc.Extra["$ref"]
+	if !ok {
+		return sc, nil
+	}
+	var ref string
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return nil, fmt.Errorf("invalid $ref: %w", err)
+	}
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	def, ok := s.defs[name]
+	if !ok {
+		return nil, fmt.Errorf("$ref %s points to no definition", ref)
+	}
+	return def, nil
+}
+
+// either makes sc accept strings and values of typ, e.g. integers for ports given by name or number.
+func either(sc *schema.Schema, typ string) {
+	sc.Type, sc.Format = "", ""
+	sc.AnyOf = []*schema.Schema{{Type: typ}, {Type: "string"}}
+}
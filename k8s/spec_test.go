@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+// swagger is a trimmed down /openapi/v2 spec of a cluster.
+const swagger = `{
+  "swagger": "2.0",
+  "definitions": {
+    "io.k8s.api.apps.v1.Deployment": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    },
+    "io.k8s.api.apps.v1.DeploymentSpec": {
+      "type": "object",
+      "required": ["selector", "template"],
+      "properties": {
+        "replicas": {"type": "integer", "format": "int32"},
+        "selector": {"type": "object", "properties": {"matchLabels": {"type": "object", "additionalProperties": {"type": "string"}}}},
+        "template": {"$ref": "#/definitions/io.k8s.api.core.v1.PodTemplateSpec"}
+      }
+    },
+    "io.k8s.api.core.v1.PodTemplateSpec": {
+      "type": "object",
+      "properties": {
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      }
+    },
+    "io.k8s.api.core.v1.Pod": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "Pod", "version": "v1"}]
+    },
+    "io.k8s.api.core.v1.PodSpec": {
+      "type": "object",
+      "required": ["containers"],
+      "properties": {
+        "containers": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Container"}},
+        "restartPolicy": {"type": "string", "enum": ["Always", "OnFailure", "Never"]}
+      }
+    },
+    "io.k8s.api.core.v1.Container": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string"},
+        "image": {"type": "string"},
+        "imagePullPolicy": {"type": "string"},
+        "ports": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.api.core.v1.ContainerPort"}},
+        "livenessProbe": {"type": "object", "properties": {"httpGet": {"type": "object", "properties": {"port": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.util.intstr.IntOrString"}}}}},
+        "resources": {"type": "object", "properties": {"limits": {"type": "object", "additionalProperties": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity"}}}}
+      }
+    },
+    "io.k8s.api.core.v1.ContainerPort": {
+      "type": "object",
+      "required": ["containerPort"],
+      "properties": {"containerPort": {"type": "integer", "format": "int32"}, "name": {"type": "string"}}
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "ownerReferences": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}}
+      }
+    },
+    "io.k8s.apimachinery.pkg.util.intstr.IntOrString": {"type": "string", "format": "int-or-string"},
+    "io.k8s.apimachinery.pkg.api.resource.Quantity": {"type": "string"}
+  }
+}`
+
+func TestParseSpec(t *testing.T) {
+	s, err := ParseSpec([]byte(swagger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(s.Kinds(), ","); got != "Deployment,Pod" {
+		t.Errorf("Kinds() = %s", got)
+	}
+	if got := s.APIVersions("Deployment"); len(got) != 1 || got[0] != "apps/v1" {
+		t.Errorf("APIVersions() = %v", got)
+	}
+	d, ok := s.Schema("apps/v1", "Deployment")
+	if !ok {
+		t.Fatal("no schema for apps/v1 Deployment")
+	}
+	pod, _ := s.Schema("v1", "Pod")
+	if d.Properties.Get("spec").Properties.Get("template").Properties.Get("spec") != pod.Properties.Get("spec") {
+		t.Error("$refs to the same definition resolve to different schemas")
+	}
+	if _, ok := s.Schema("v1", "Deployment"); ok {
+		t.Error("Schema() found Deployment in v1")
+	}
+
+	openAPI3 := `{"openapi": "3.0.0", "components": {"schemas": {
+		"io.k8s.api.core.v1.ConfigMap": {"type": "object", "properties": {
+			"data": {"type": "object", "additionalProperties": {"type": "string"}},
+			"metadata": {"allOf": [{"$ref": "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}], "default": {}}},
+			"x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]},
+		"io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object", "properties": {"name": {"type": "string"}}}}}}`
+	s, err = ParseSpec([]byte(openAPI3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm, ok := s.Schema("v1", "ConfigMap"); !ok || cm.Properties.Get("metadata").Properties.Get("name") == nil {
+		t.Errorf("allOf $ref not resolved")
+	}
+
+	for _, tt := range []struct{ spec, want string }{
+		{`[]`, "invalid OpenAPI spec"},
+		{`{"swagger": "2.0"}`, "it has neither definitions nor components.schemas"},
+		{`{"definitions": {"a": {"properties": {"b": {"$ref": "#/definitions/c"}}}}}`, "definition a: $ref #/definitions/c points to no definition"},
+	} {
+		if _, err := ParseSpec([]byte(tt.spec)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseSpec(%s) error = %v, want %s", tt.spec, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/internal/suggest"
+	"github.com/at15/tracedconfig/schema"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Validate checks the manifests docs, e.g. the documents of a YAML stream, and returns the diagnostics in
+// document order. Every manifest needs an apiVersion and a kind of the spec, and is validated against the
+// schema of its kind, see schema.Schema.Validate. Fields the schema does not know are errors, like the
+// strict field validation of the API server rejects them, with a suggestion when one is a few edits
+// away. The items of a List are validated as manifests, empty documents are skipped.
+func (s *Spec) Validate(docs ...*slowjson.Node) []diag.Diagnostic {
+	var diags []diag.Diagnostic
+	for _, doc := range docs {
+		diags = append(diags, s.validate(doc)...)
+	}
+	return diags
+}
+
+// manifest is what every manifest has, whatever its kind.
+var manifest = &schema.Schema{
+	Type:     "object",
+	Required: []string{"apiVersion", "kind"},
+	Properties: schema.Properties{
+		{Name: "apiVersion", Schema: &schema.Schema{Type: "string"}},
+		{Name: "kind", Schema: &schema.Schema{Type: "string"}},
+	},
+}
+
+func (s *Spec) validate(doc *slowjson.Node) []diag.Diagnostic {
+	if doc == nil || doc.Type == slowjson.NodeNull || doc.Type == slowjson.NodeObject && len(doc.Children) == 0 {
+		return nil
+	}
+	if diags := manifest.Validate(doc); len(diags) > 0 {
+		return diags
+	}
+	apiVersion, kind := doc.Get("apiVersion"), doc.Get("kind")
+	if items := doc.Get("items"); strings.HasSuffix(kind.Value, "List") && items != nil && items.Type == slowjson.NodeArray {
+		return s.Validate(items.Children...)
+	}
+	sc, ok := s.Schema(apiVersion.Value, kind.Value)
+	if !ok {
+		return []diag.Diagnostic{s.unknownKind(apiVersion, kind)}
+	}
+	diags := sc.Validate(doc)
+	unknownFields(doc, sc, &diags)
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Node.StartOffset < diags[j].Node.StartOffset
+	})
+	return diags
+}
+
+// unknownKind reports a manifest whose kind has no schema in apiVersion: a kind served by other
+// apiVersions, a kind a few edits away from a known one, or one the spec does not have, e.g. of a custom
+// resource, which is a warning as the manifest may well be valid.
+func (s *Spec) unknownKind(apiVersion, kind *slowjson.Node) diag.Diagnostic {
+	var d diag.Diagnostic
+	if versions := s.APIVersions(kind.Value); len(versions) > 0 {
+		d = diag.Errorf(apiVersion, "kind %s is not served by %s, use %s", kind.Value, apiVersion.Value, strings.Join(versions, " or "))
+	} else if name, ok := suggest.Closest(kind.Value, s.Kinds()); ok {
+		d = diag.Errorf(kind, "unknown kind %q, did you mean %q?", kind.Value, name)
+	} else {
+		d = diag.Warningf(kind, "no schema for kind %s of %s in the OpenAPI spec, the manifest is not validated", kind.Value, apiVersion.Value)
+	}
+	d.Code = diag.CodeUnknownKind
+	return d
+}
+
+// unknownFields reports the keys below n the schema sc does not allow. Unlike Decode the API server
+// matches fields case-sensitively.
+func unknownFields(n *slowjson.Node, sc *schema.Schema, diags *[]diag.Diagnostic) {
+	if sc == nil {
+		return
+	}
+	switch n.Type {
+	case slowjson.NodeArray:
+		for _, elem := range n.Children {
+			unknownFields(elem, sc.Items, diags)
+		}
+	case slowjson.NodeObject:
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			prop := sc.Properties.Get(key.Value)
+			switch {
+			case prop != nil:
+				unknownFields(key.Children[0], prop, diags)
+			case sc.AdditionalProperties != nil:
+				unknownFields(key.Children[0], sc.AdditionalProperties, diags)
+			case len(sc.Properties) > 0:
+				*diags = append(*diags, unknownField(key, sc.Properties))
+			}
+		}
+	}
+}
+
+func unknownField(key *slowjson.Node, props schema.Properties) diag.Diagnostic {
+	names := make([]string, len(props))
+	for i, p := range props {
+		names[i] = p.Name
+	}
+	var d diag.Diagnostic
+	if name, ok := suggest.Closest(key.Value, names); ok {
+		d = diag.Errorf(key, "unknown field %q, did you mean %q?", key.Value, name)
+		d.Code = diag.CodeSimilarKey
+	} else {
+		d = diag.Errorf(key, "unknown field %q, the API server rejects it", key.Value)
+		d.Code = diag.CodeUnknownKey
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/yaml"
+)
+
+func TestSpec_Validate(t *testing.T) {
+	s, err := ParseSpec([]byte(swagger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{"valid", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels: {app: web}
+spec:
+  replicas: 2
+  selector: {matchLabels: {app: web}}
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx
+          ports: [{containerPort: 80, name: http}]
+          livenessProbe: {httpGet: {port: http}}
+          resources: {limits: {cpu: 500m, memory: 1}}
+`, nil},
+		{"violations", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replica: 2
+  selector: {matchLabels: {app: web}}
+  template:
+    spec:
+      restartPolicy: always
+      containers:
+        - image: nginx
+          ports: [{containerPort: "80"}]
+          livenessProbe: {httpGet: {port: true}}
+          volumeMounts: []
+`, []string{
+			`6:3: error: spec.replica: unknown field "replica", did you mean "replicas"? [TC1004 similar-key]`,
+			`10:22: error: spec.template.spec.restartPolicy: invalid value "always", expected one of "Always", "OnFailure", "Never"; did you mean "Always"? [TC2004 invalid-enum]`,
+			`12:11: error: spec.template.spec.containers[0]: missing required key "name" [TC2006 missing-key]`,
+			`13:35: error: spec.template.spec.containers[0].ports[0].containerPort: expected integer, got string [TC2003 type-mismatch]`,
+			`14:43: error: spec.template.spec.containers[0].livenessProbe.httpGet.port: does not match any of the 2 allowed schemas, closest is anyOf[0]: expected integer, got boolean [TC2005 constraint]`,
+			`15:11: error: spec.template.spec.containers[0].volumeMounts: unknown field "volumeMounts", the API server rejects it [TC1003 unknown-key]`,
+		}},
+		{"moved kind", "apiVersion: extensions/v1beta1\nkind: Deployment\n", []string{
+			`1:13: error: apiVersion: kind Deployment is not served by extensions/v1beta1, use apps/v1 [TC1007 unknown-kind]`,
+		}},
+		{"misspelled kind", "apiVersion: apps/v1\nkind: Deploymnt\n", []string{
+			`2:7: error: kind: unknown kind "Deploymnt", did you mean "Deployment"? [TC1007 unknown-kind]`,
+		}},
+		{"custom resource", "apiVersion: example.com/v1\nkind: Widget\nspec: {}\n", []string{
+			`2:7: warning: kind: no schema for kind Widget of example.com/v1 in the OpenAPI spec, the manifest is not validated [TC1007 unknown-kind]`,
+		}},
+		{"no kind", "apiVersion: 1\nmetadata: {}\n", []string{
+			`1:13: error: apiVersion: expected string, got number [TC2003 type-mismatch]`,
+			`1:1: error: missing required key "kind" [TC2006 missing-key]`,
+		}},
+		{"stream", "---\napiVersion: v1\nkind: Pod\nspec:\n  containers: [{name: a}]\n---\n# empty\n---\napiVersion: v1\nkind: List\nitems:\n  - {apiVersion: v1, kind: Pod, spec: {}}\n", []string{
+			`12:39: error: items[0].spec: missing required key "containers" [TC2006 missing-key]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := yaml.ParseStream([]byte(tt.manifest), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range s.Validate(docs...) {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Validate() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Limits bounds the documents of a source, so a runaway source, e.g. a remote store that was pushed a
+// gigantic document, fails its load and the previous config is kept instead of merging it. Zero fields
+// are not checked.
+type Limits struct {
+	// MaxBytes is the largest input, for binary formats the span of the document. Loads pass it to
+	// their sources in the context, sources reading with ReadFile or checking CheckSize fail before
+	// parsing a larger input. Check enforces it on the parsed document of any other source.
+	MaxBytes int
+	// MaxKeys is the most object keys in the whole document.
+	MaxKeys int
+	// MaxDepth is the deepest nesting of objects and arrays. The parsers reject anything deeper than
+	// slowjson.MaxDepth before a document gets here, so only lower values have an effect.
+	MaxDepth int
+}
+
+// Check returns a *slowjson.ValidationError with code diag.CodeLimit for the first limit n exceeds.
+func (l Limits) Check(n *slowjson.Node) error {
+	if l == (Limits{}) || n == nil {
+		return nil
+	}
+	s := n.Stats()
+	size := s.SourceBytes
+	if size == 0 {
+		size = n.EndOffset - n.StartOffset
+	}
+	var err error
+	switch {
+	case l.MaxBytes > 0 && size > l.MaxBytes:
+		err = n.Errorf("document is %d bytes, more than the limit of %d", size, l.MaxBytes)
+	case l.MaxKeys > 0 && s.Keys > l.MaxKeys:
+		err = n.Errorf("document has %d keys, more than the limit of %d", s.Keys, l.MaxKeys)
+	case l.MaxDepth > 0 && s.MaxDepth > l.MaxDepth:
+		err = n.Errorf("document is nested %d levels deep, more than the limit of %d", s.MaxDepth, l.MaxDepth)
+	default:
+		return nil
+	}
+	err.(*slowjson.ValidationError).Code = diag.CodeLimit
+	return err
+}
+
+type maxBytesKey struct{}
+
+// withMaxBytes returns ctx limiting the input of sources to max bytes, a tighter limit of ctx is kept.
+func withMaxBytes(ctx context.Context, max int) context.Context {
+	if max <= 0 {
+		return ctx
+	}
+	if cur, ok := ctx.Value(maxBytesKey{}).(int); ok && cur <= max {
+		return ctx
+	}
+	return context.WithValue(ctx, maxBytesKey{}, max)
+}
+
+// CheckSize returns an error with code diag.CodeLimit when an input of size bytes named file exceeds
+// the MaxBytes limit of the load ctx belongs to. Sources holding their input in memory call it before
+// parsing.
+func CheckSize(ctx context.Context, file string, size int) error {
+	max, ok := ctx.Value(maxBytesKey{}).(int)
+	if !ok || size <= max {
+		return nil
+	}
+	return &slowjson.ValidationError{
+		Pos:  slowjson.Position{File: file},
+		Err:  fmt.Errorf("document is more than the limit of %d bytes", max),
+		Code: diag.CodeLimit,
+	}
+}
+
+// ReadFile reads the file at path like os.ReadFile, but stops reading and returns an error from
+// CheckSize once the file exceeds the MaxBytes limit of the load ctx belongs to.
+func ReadFile(ctx context.Context, path string) ([]byte, error) {
+	max, ok := ctx.Value(maxBytesKey{}).(int)
+	if !ok {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSize(ctx, path, len(b)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Limit wraps a source so its documents must stay within limits, see Limits. It applies on top of
+// Config.Limits, e.g. to give a remote source tighter limits than local files.
+func Limit(src Source, limits Limits) Source {
+	return &limitSource{Source: src, limits: limits}
+}
+
+type limitSource struct {
+	Source
+	limits Limits
+}
+
+func (s *limitSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	n, err := s.Source.Load(withMaxBytes(ctx, s.limits.MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limits.Check(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (s *limitSource) Metadata() map[string]string               { return metadata(s.Source) }
+func (s *limitSource) Changed(ctx context.Context) (bool, error) { return SourceChanged(ctx, s.Source) }
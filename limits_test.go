@@ -0,0 +1,126 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestLimits_Check(t *testing.T) {
+	doc := `{"a": {"b": [1, 2]}, "c": 3}`
+	tests := []struct {
+		limits Limits
+		want   string
+	}{
+		{Limits{}, ""},
+		{Limits{MaxBytes: len(doc), MaxKeys: 3, MaxDepth: 3}, ""},
+		{Limits{MaxBytes: 10}, "document is 28 bytes, more than the limit of 10"},
+		{Limits{MaxKeys: 2}, "document has 3 keys, more than the limit of 2"},
+		{Limits{MaxDepth: 2}, "document is nested 3 levels deep, more than the limit of 2"},
+	}
+	for _, tt := range tests {
+		n, err := Bytes("remote", []byte(doc)).Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tt.limits.Check(n)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Check(%+v) error = %v", tt.limits, err)
+			}
+			continue
+		}
+		var ve *slowjson.ValidationError
+		if !errors.As(err, &ve) || ve.Code != diag.CodeLimit || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Check(%+v) error = %v, want %q with code %s", tt.limits, err, tt.want, diag.CodeLimit)
+		}
+	}
+}
+
+func TestConfig_Limits(t *testing.T) {
+	ctx := context.Background()
+	remote := &switchSource{name: "remote", doc: `{"port": 80}`}
+	c := NewConfig(Bytes("defaults", []byte(`{"port": 1, "host": "a"}`)), Limit(remote, Limits{MaxKeys: 2}))
+	c.Limits = Limits{MaxDepth: 3}
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.doc = `{"port": 81, "a": 1, "b": 2}`
+	err := c.Load(ctx)
+	if err == nil || !strings.Contains(err.Error(), "load remote: document has 3 keys") {
+		t.Errorf("Load() error = %v, want the key limit of remote", err)
+	}
+	if d := diag.FromError(err); len(d) != 1 || d[0].Code != diag.CodeLimit {
+		t.Errorf("FromError() = %v", d)
+	}
+	if port := c.Get("port"); port.Value != "80" {
+		t.Errorf("port = %s, want the previous 80", port.Value)
+	}
+
+	remote.doc = `{"x": [[[[1]]]]}`
+	if err := c.Load(ctx); err == nil || !strings.Contains(err.Error(), "nested 5 levels deep") {
+		t.Errorf("Load() error = %v, want the depth limit of the config", err)
+	}
+}
+
+func TestConfig_MaxBytesBeforeParsing(t *testing.T) {
+	ctx := context.Background()
+	// the documents are not JSON, a source that parsed them would fail with a syntax error
+	big := strings.Repeat("x", 100)
+	path := filepath.Join(t.TempDir(), "big.json")
+	if err := os.WriteFile(path, []byte(big), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		c    *Config
+	}{
+		{"file", NewConfig(File(path))},
+		{"dir", NewConfig(Dir(filepath.Dir(path)))},
+		{"bytes", NewConfig(Bytes("remote", []byte(big)))},
+		{"limit", NewConfig(Limit(Bytes("remote", []byte(big)), Limits{MaxBytes: 10}))},
+	}
+	for _, tt := range tests {
+		if tt.name != "limit" {
+			tt.c.Limits = Limits{MaxBytes: 10}
+		}
+		err := tt.c.Load(ctx)
+		var ve *slowjson.ValidationError
+		if !errors.As(err, &ve) || ve.Code != diag.CodeLimit || !strings.Contains(err.Error(), "more than the limit of 10 bytes") {
+			t.Errorf("%s: Load() error = %v, want the byte limit", tt.name, err)
+		}
+	}
+
+	// the fallback file of a resilient source is read within the limit too
+	c := NewConfig(Resilient(&flakySource{fail: errors.New("down")}, ResilienceOptions{File: path}))
+	c.Limits = Limits{MaxBytes: 10}
+	if err := c.Load(ctx); err == nil || !strings.Contains(err.Error(), "fallback "+path+": document is more than the limit of 10 bytes") {
+		t.Errorf("Load() error = %v, want the byte limit of the fallback", err)
+	}
+
+	// a tighter limit of the config applies to a wrapped source
+	c = NewConfig(Limit(Bytes("remote", []byte(big)), Limits{MaxBytes: 1000}))
+	c.Limits = Limits{MaxBytes: 10}
+	if err := c.Load(ctx); err == nil || !strings.Contains(err.Error(), "more than the limit of 10 bytes") {
+		t.Errorf("Load() error = %v, want the byte limit of the config", err)
+	}
+}
+
+// switchSource serves doc, tests change it between loads.
+type switchSource struct {
+	name string
+	doc  string
+}
+
+func (s *switchSource) Name() string { return s.name }
+
+func (s *switchSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	return Bytes(s.name, []byte(s.doc)).Load(ctx)
+}
@@ -0,0 +1,221 @@
+package lint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Baseline records the findings of a config at one point in time so only new findings are reported
+// afterwards, letting large configs adopt the linter rule by rule.
+// Findings are identified by file, key path and code and not by line, so edits elsewhere in the file
+// do not turn recorded findings into new ones. Files are recorded relative to the baseline file, so the
+// baseline matches wherever the linter runs from.
+type Baseline struct {
+	Entries []BaselineEntry
+	// dir is the directory of the baseline file the entries are relative to, empty when the entries name
+	// files as the diagnostics do.
+	dir string
+}
+
+// BaselineEntry is a recorded finding, Count is how many findings share its file, path and code.
+type BaselineEntry struct {
+	File  string `json:"file"`
+	Path  string `json:"path"`
+	Code  string `json:"code"`
+	Count int    `json:"count"`
+}
+
+type baselineKey struct {
+	file, path, code string
+}
+
+func (b *Baseline) keyOf(d diag.Diagnostic) baselineKey {
+	return baselineKey{file: b.entryFile(d.Span.File), path: d.Path, code: d.Code}
+}
+
+// entryFile returns how the entries name file, relative to dir with slashes.
+func (b *Baseline) entryFile(file string) string {
+	if b.dir == "" {
+		return file
+	}
+	dir, err := filepath.Abs(b.dir)
+	if err != nil {
+		return file
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// file returns the path of the file an entry names.
+func (b *Baseline) file(entry string) string {
+	f := filepath.FromSlash(entry)
+	if b.dir == "" || filepath.IsAbs(f) {
+		return f
+	}
+	return filepath.Join(b.dir, f)
+}
+
+// NewBaseline records diags.
+func NewBaseline(diags []diag.Diagnostic) *Baseline {
+	b := &Baseline{}
+	counts := map[baselineKey]int{}
+	for _, d := range diags {
+		counts[b.keyOf(d)]++
+	}
+	for k, n := range counts {
+		b.Entries = append(b.Entries, BaselineEntry{File: k.file, Path: k.path, Code: k.code, Count: n})
+	}
+	b.sort()
+	return b
+}
+
+func (b *Baseline) sort() {
+	sort.Slice(b.Entries, func(i, j int) bool {
+		x, y := b.Entries[i], b.Entries[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		return x.Code < y.Code
+	})
+}
+
+// Filter returns the diagnostics not in the baseline. When a path has more findings of a code than
+// recorded, the ones after the recorded count are new.
+func (b *Baseline) Filter(diags []diag.Diagnostic) []diag.Diagnostic {
+	left := map[baselineKey]int{}
+	for _, e := range b.Entries {
+		left[baselineKey{file: e.File, path: e.Path, code: e.Code}] += e.Count
+	}
+	var fresh []diag.Diagnostic
+	for _, d := range diags {
+		k := b.keyOf(d)
+		if left[k] > 0 {
+			left[k]--
+			continue
+		}
+		fresh = append(fresh, d)
+	}
+	return fresh
+}
+
+// ParseBaseline reads a baseline file, a JSON object with a "findings" array of entries.
+func ParseBaseline(root *slowjson.Node) (*Baseline, error) {
+	if root.Type != slowjson.NodeObject {
+		return nil, root.Errorf("baseline must be an object")
+	}
+	b := &Baseline{}
+	for _, key := range root.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "version":
+			if v.Type != slowjson.NodeNumber || v.Value != "1" {
+				return nil, v.Errorf("unsupported baseline version %s", v.Value)
+			}
+		case "findings":
+			if v.Type != slowjson.NodeArray {
+				return nil, v.Errorf("findings must be an array")
+			}
+			for _, f := range v.Children {
+				e, err := parseBaselineEntry(f)
+				if err != nil {
+					return nil, err
+				}
+				b.Entries = append(b.Entries, e)
+			}
+		default:
+			return nil, key.Errorf("unknown baseline field %q", key.Value)
+		}
+	}
+	return b, nil
+}
+
+func parseBaselineEntry(n *slowjson.Node) (BaselineEntry, error) {
+	e := BaselineEntry{Count: 1}
+	if n.Type != slowjson.NodeObject {
+		return e, n.Errorf("finding must be an object")
+	}
+	for _, key := range n.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "file", "path", "code":
+			if v.Type != slowjson.NodeString {
+				return e, v.Errorf("%s must be a string", key.Value)
+			}
+			switch key.Value {
+			case "file":
+				e.File = v.Value
+			case "path":
+				e.Path = v.Value
+			default:
+				e.Code = v.Value
+			}
+		case "count":
+			count, err := strconv.Atoi(v.Value)
+			if v.Type != slowjson.NodeNumber || err != nil || count < 1 {
+				return e, v.Errorf("count must be a positive integer")
+			}
+			e.Count = count
+		default:
+			return e, key.Errorf("unknown finding field %q", key.Value)
+		}
+	}
+	return e, nil
+}
+
+// LoadBaseline reads and parses the baseline file at path, its files are relative to the directory of path.
+func LoadBaseline(path string) (*Baseline, error) {
+	root, err := slowjson.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseBaseline(root)
+	if err != nil {
+		return nil, err
+	}
+	b.dir = filepath.Dir(path)
+	return b, nil
+}
+
+// Marshal encodes the baseline as indented JSON, one finding per line so diffs stay readable.
+func (b *Baseline) Marshal() []byte {
+	out := []byte("{\n  \"version\": 1,\n  \"findings\": [")
+	for i, e := range b.Entries {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		line, _ := json.Marshal(e)
+		out = append(out, "\n    "...)
+		out = append(out, line...)
+	}
+	if len(b.Entries) > 0 {
+		out = append(out, "\n  "...)
+	}
+	return append(out, "]\n}\n"...)
+}
+
+// Write writes the baseline to path with its files relative to the directory of path.
+func (b *Baseline) Write(path string) error {
+	out := &Baseline{dir: filepath.Dir(path)}
+	for _, e := range b.Entries {
+		e.File = out.entryFile(b.file(e.File))
+		out.Entries = append(out.Entries, e)
+	}
+	out.sort()
+	return os.WriteFile(path, out.Marshal(), 0o644)
+}
@@ -0,0 +1,118 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestBaseline(t *testing.T) {
+	r := NewRunner(NewRule("no-todo", findStrings("todo")))
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	p := slowjson.NewParser(`{"a": "todo", "b": ["todo", "todo"]}`)
+	p.File = file
+	before, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "baseline.json")
+	if err := NewBaseline(r.Run(before)).Write(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error = %v", err)
+	}
+	if len(b.Entries) != 3 {
+		t.Fatalf("Entries = %+v", b.Entries)
+	}
+	if got := string(b.Marshal()); !strings.Contains(got, `{"file":"app.json","path":"a","code":"no-todo","count":1}`) {
+		t.Errorf("Marshal() =\n%s", got)
+	}
+
+	// moved lines keep their findings recorded, a new finding and one more on a recorded path are reported
+	p = slowjson.NewParser("{\n\n  \"a\": \"todo\",\n  \"b\": [\"todo\", \"todo\"],\n  \"c\": \"todo\"\n}")
+	p.File = file
+	after, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range b.Filter(r.Run(after)) {
+		got = append(got, d.String())
+	}
+	if want := file + `:5:8: warning: c: found "todo" [no-todo]`; strings.Join(got, "\n") != want {
+		t.Errorf("Filter() =\n%s\nwant\n%s", strings.Join(got, "\n"), want)
+	}
+	if got := b.Filter([]diag.Diagnostic{diag.Warningf(after.Get("a"), "x")}); len(got) != 1 {
+		t.Errorf("Filter() dropped a finding with another code: %v", got)
+	}
+}
+
+func TestBaseline_RelativeFiles(t *testing.T) {
+	r := NewRunner(NewRule("no-todo", findStrings("todo")))
+	dir := t.TempDir()
+	run := func(file string) []diag.Diagnostic {
+		p := slowjson.NewParser(`{"a": "todo"}`)
+		p.File = file
+		n, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Run(n)
+	}
+	path := filepath.Join(dir, "ci", "baseline.json")
+	if err := os.Mkdir(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewBaseline(run(filepath.Join(dir, "app.json"))).Write(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error = %v", err)
+	}
+	if len(b.Entries) != 1 || b.Entries[0].File != "../app.json" {
+		t.Fatalf("Entries = %+v, want the file relative to the baseline", b.Entries)
+	}
+	// the same file named another way is recorded, another file is not
+	if got := b.Filter(run(filepath.Join(dir, "ci", "..", "app.json"))); len(got) != 0 {
+		t.Errorf("Filter() = %v, want the recorded finding dropped", got)
+	}
+	if got := b.Filter(run(filepath.Join(dir, "ci", "app.json"))); len(got) != 1 {
+		t.Errorf("Filter() = %v, want the finding of another file", got)
+	}
+
+	// rewriting a loaded baseline elsewhere keeps naming the same files
+	moved := filepath.Join(dir, "baseline.json")
+	if err := b.Write(moved); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = LoadBaseline(moved); err != nil || len(b.Entries) != 1 || b.Entries[0].File != "app.json" {
+		t.Fatalf("LoadBaseline() = %+v, %v", b, err)
+	}
+}
+
+func TestParseBaseline_Errors(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{`[]`, "baseline must be an object"},
+		{`{"version": 2}`, "unsupported baseline version 2"},
+		{`{"findings": [{"file": 1}]}`, "file must be a string"},
+		{`{"findings": [{"count": 1.5}]}`, "count must be a positive integer"},
+		{`{"findings": [{"line": 3}]}`, `unknown finding field "line"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseBaseline(parse(t, tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("ParseBaseline(%s) error = %v, want %q", tt.input, err, tt.wantError)
+		}
+	}
+}
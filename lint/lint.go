@@ -0,0 +1,172 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Rule is a check over a config tree.
+type Rule interface {
+	// Name identifies the rule in configuration. The Code of its diagnostics is the stable code
+	// registered for the name in diag, e.g. "TC1001" for "duplicate-key", or the name itself,
+	// unless the rule sets one.
+	Name() string
+	Check(root *slowjson.Node) []diag.Diagnostic
+}
+
+type funcRule struct {
+	name  string
+	check func(root *slowjson.Node) []diag.Diagnostic
+}
+
+func (r funcRule) Name() string { return r.name }
+
+func (r funcRule) Check(root *slowjson.Node) []diag.Diagnostic { return r.check(root) }
+
+// NewRule creates a rule from a function.
+func NewRule(name string, check func(root *slowjson.Node) []diag.Diagnostic) Rule {
+	return funcRule{name: name, check: check}
+}
+
+// Runner runs a set of rules. Rules are enabled with the severity they report unless configured otherwise.
+type Runner struct {
+	rules    []Rule
+	disabled map[string]bool
+	severity map[string]diag.Severity
+}
+
+// NewRunner creates a runner for rules.
+func NewRunner(rules ...Rule) *Runner {
+	return &Runner{
+		rules:    rules,
+		disabled: map[string]bool{},
+		severity: map[string]diag.Severity{},
+	}
+}
+
+// Add registers more rules.
+func (r *Runner) Add(rules ...Rule) {
+	r.rules = append(r.rules, rules...)
+}
+
+// Rules returns the names of the registered rules.
+func (r *Runner) Rules() []string {
+	names := make([]string, len(r.rules))
+	for i, rule := range r.rules {
+		names[i] = rule.Name()
+	}
+	return names
+}
+
+// Enable turns a disabled rule back on.
+func (r *Runner) Enable(name string) {
+	delete(r.disabled, name)
+}
+
+// Disable skips the rule when running.
+func (r *Runner) Disable(name string) {
+	r.disabled[name] = true
+}
+
+// SetSeverity overrides the severity of every diagnostic the rule reports.
+func (r *Runner) SetSeverity(name string, s diag.Severity) {
+	r.severity[name] = s
+}
+
+// Configure applies settings keyed by rule name or code, e.g. "duplicate-key" or "TC1001". A setting is "off", "on" or a severity:
+// "error", "warning" or "info". Unknown rules and settings are errors so typos do not go unnoticed.
+func (r *Runner) Configure(settings map[string]string) error {
+	known := map[string]bool{unusedSuppression: true}
+	for _, rule := range r.rules {
+		known[rule.Name()] = true
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		name := key
+		if info, ok := diag.LookupCode(key); ok && !known[key] {
+			name = info.Name
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown lint rule %q", key)
+		}
+		switch setting := strings.ToLower(settings[key]); setting {
+		case "off":
+			r.Disable(name)
+		case "on":
+			r.Enable(name)
+		default:
+			s, err := ParseSeverity(setting)
+			if err != nil {
+				return fmt.Errorf("lint rule %s: %w", name, err)
+			}
+			r.Enable(name)
+			r.SetSeverity(name, s)
+		}
+	}
+	return nil
+}
+
+// ParseSeverity parses "error", "warning" or "info".
+func ParseSeverity(s string) (diag.Severity, error) {
+	for _, sev := range []diag.Severity{diag.SeverityError, diag.SeverityWarning, diag.SeverityInfo} {
+		if s == sev.String() {
+			return sev, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q, expected error, warning or info", s)
+}
+
+// unusedSuppression is the name of the report of unused suppression comments, it is configured like a rule.
+const unusedSuppression = "unused-suppression"
+
+// Run checks root with every enabled rule and returns the diagnostics ordered by position.
+// Findings silenced by suppression comments, see diag.SuppressDirective, are dropped and
+// comments that silenced nothing are reported as "unused-suppression".
+func (r *Runner) Run(root *slowjson.Node) []diag.Diagnostic {
+	var diags []diag.Diagnostic
+	for _, rule := range r.rules {
+		name := rule.Name()
+		if r.disabled[name] {
+			continue
+		}
+		code := name
+		if info, ok := diag.LookupCode(name); ok {
+			code = info.Code
+		}
+		for _, d := range rule.Check(root) {
+			if d.Code == "" {
+				d.Code = code
+			}
+			if s, ok := r.severity[name]; ok {
+				d.Severity = s
+			}
+			diags = append(diags, d)
+		}
+	}
+	sups := diag.Suppressions(root)
+	diags = diag.Suppress(diags, sups)
+	if !r.disabled[unusedSuppression] {
+		for _, d := range diag.Unused(sups) {
+			if s, ok := r.severity[unusedSuppression]; ok {
+				d.Severity = s
+			}
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Span, diags[j].Span
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.StartOffset < b.StartOffset
+	})
+	return diags
+}
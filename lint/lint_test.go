@@ -0,0 +1,122 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func parse(t *testing.T, input string) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return n
+}
+
+// findStrings reports every string value equal to s.
+func findStrings(s string) func(*slowjson.Node) []diag.Diagnostic {
+	return func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		var walk func(n *slowjson.Node)
+		walk = func(n *slowjson.Node) {
+			if n.Type == slowjson.NodeString && !n.IsKey() && n.Value == s {
+				diags = append(diags, diag.Warningf(n, "found %q", s))
+			}
+			for _, c := range n.Children {
+				walk(c)
+			}
+		}
+		walk(root)
+		return diags
+	}
+}
+
+func TestRunner(t *testing.T) {
+	root := parse(t, `{"a": "todo", "b": "fixme", "c": "todo"}`)
+	r := NewRunner(NewRule("no-todo", findStrings("todo")))
+	r.Add(NewRule("no-fixme", findStrings("fixme")))
+	if got := strings.Join(r.Rules(), ","); got != "no-todo,no-fixme" {
+		t.Errorf("Rules() = %s", got)
+	}
+
+	var got []string
+	for _, d := range r.Run(root) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`1:7: warning: a: found "todo" [no-todo]`,
+		`1:20: warning: b: found "fixme" [no-fixme]`,
+		`1:34: warning: c: found "todo" [no-todo]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Run() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	r.Disable("no-todo")
+	r.SetSeverity("no-fixme", diag.SeverityError)
+	diags := r.Run(root)
+	if len(diags) != 1 || diags[0].Code != "no-fixme" || diags[0].Severity != diag.SeverityError {
+		t.Errorf("Run() after configuration = %v", diags)
+	}
+	r.Enable("no-todo")
+	if diags := r.Run(root); len(diags) != 3 {
+		t.Errorf("Run() after Enable = %v", diags)
+	}
+}
+
+func TestRunner_Configure(t *testing.T) {
+	root := parse(t, `{"a": "todo", "b": "fixme"}`)
+	r := NewRunner(NewRule("no-todo", findStrings("todo")), NewRule("no-fixme", findStrings("fixme")))
+	if err := r.Configure(map[string]string{"no-todo": "off", "no-fixme": "Info"}); err != nil {
+		t.Fatal(err)
+	}
+	diags := r.Run(root)
+	if len(diags) != 1 || diags[0].Severity != diag.SeverityInfo {
+		t.Errorf("Run() = %v", diags)
+	}
+
+	tests := []struct {
+		settings  map[string]string
+		wantError string
+	}{
+		{map[string]string{"no-todos": "off"}, `unknown lint rule "no-todos"`},
+		{map[string]string{"no-todo": "fatal"}, `lint rule no-todo: invalid severity "fatal"`},
+	}
+	for _, tt := range tests {
+		err := r.Configure(tt.settings)
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Configure(%v) error = %v, want %q", tt.settings, err, tt.wantError)
+		}
+	}
+}
+
+func TestRunner_Suppressions(t *testing.T) {
+	root := parse(t, `{
+  // tracedconfig:ignore no-todo
+  "a": "todo",
+  // tracedconfig:ignore no-fixme
+  "b": "todo"
+}`)
+	r := NewRunner(NewRule("no-todo", findStrings("todo")), NewRule("no-fixme", findStrings("fixme")))
+	var got []string
+	for _, d := range r.Run(root) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`4:3: warning: b: unused suppression of no-fixme [TC1005 unused-suppression]`,
+		`5:8: warning: b: found "todo" [no-todo]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Run() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if err := r.Configure(map[string]string{"unused-suppression": "off"}); err != nil {
+		t.Fatal(err)
+	}
+	if diags := r.Run(root); len(diags) != 1 {
+		t.Errorf("Run() with unused-suppression off = %v", diags)
+	}
+}
@@ -0,0 +1,2 @@
+// Package lint runs checks over a parsed config tree and reports position tracked diagnostics.
+package lint
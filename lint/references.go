@@ -0,0 +1,121 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/internal/suggest"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// interpolation matches a ${ref} in a string value.
+var interpolation = regexp.MustCompile(`\$\{([^{}]*)\}`)
+
+// envReference matches references to the environment rather than to the config: ${env:NAME} and
+// upper case names like ${DB_PASSWORD}.
+var envReference = regexp.MustCompile(`^(env:.*|[A-Z_][A-Z0-9_]*)$`)
+
+// maxNearbyKeys bounds the keys listed in the note of a dead reference.
+const maxNearbyKeys = 10
+
+// Reference is a ${path} interpolation in a string value.
+type Reference struct {
+	// Node is the string value containing the reference.
+	Node *slowjson.Node
+	// Text is the reference as written, e.g. "${db.host}".
+	Text string
+	// Path is the referenced path, nil when Text does not hold a valid path.
+	Path slowjson.Path
+}
+
+// References returns the ${path} interpolations in the string values of root in document order.
+// References to the environment, ${env:NAME} or ${UPPER_CASE}, are left out.
+func References(root *slowjson.Node) []Reference {
+	var refs []Reference
+	walk(root, func(n *slowjson.Node) {
+		if n.IsKey() || n.Type != slowjson.NodeString {
+			return
+		}
+		for _, m := range interpolation.FindAllStringSubmatch(n.Value, -1) {
+			ref := strings.TrimSpace(m[1])
+			if envReference.MatchString(ref) {
+				continue
+			}
+			r := Reference{Node: n, Text: m[0]}
+			if path, err := slowjson.ParsePath(ref); err == nil && len(path) > 0 {
+				r.Path = path
+			}
+			refs = append(refs, r)
+		}
+	})
+	return refs
+}
+
+// DeadReferences reports References whose path, e.g. ${db.host} or ${servers[0].url}, is not set in the
+// tree. Run it on the tree merged from every layer, a reference may point at a key another layer sets.
+// The diagnostic suggests a close key and notes the keys set nearby.
+func DeadReferences() Rule {
+	return NewRule("dead-reference", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		for _, r := range References(root) {
+			switch {
+			case r.Path == nil:
+				diags = append(diags, diag.Errorf(r.Node, "invalid reference %s", r.Text))
+			case root.Lookup(r.Path) == nil:
+				diags = append(diags, deadReference(root, r.Node, r.Text, r.Path))
+			}
+		}
+		return diags
+	})
+}
+
+// deadReference reports the reference text in n to path, which is not set in root.
+func deadReference(root, n *slowjson.Node, text string, path slowjson.Path) diag.Diagnostic {
+	// the deepest value on the path that is set
+	near, depth := root, 0
+	for depth < len(path) {
+		next := near.Lookup(path[depth : depth+1])
+		if next == nil {
+			break
+		}
+		near, depth = next, depth+1
+	}
+	d := diag.Errorf(n, "reference %s does not resolve, %s is not set", text, path[:depth+1])
+	var keys []string
+	if near.Type == slowjson.NodeObject {
+		for _, k := range near.Children {
+			keys = append(keys, k.Value)
+		}
+	}
+	if !path[depth].IsIndex {
+		if name, ok := suggest.Closest(path[depth].Key, keys); ok {
+			fixed := append(append(path[:depth:depth], slowjson.Path{{Key: name}}...), path[depth+1:]...)
+			d.Message += ", did you mean ${" + fixed.String() + "}?"
+		}
+	}
+	where := "the root"
+	if depth > 0 {
+		where = path[:depth].String()
+	}
+	var note string
+	switch {
+	case near.Type == slowjson.NodeArray:
+		note = fmt.Sprintf("%s is an array of %d items", where, len(near.Children))
+	case near.Type != slowjson.NodeObject:
+		note = where + " is not an object"
+	case len(keys) == 0:
+		note = where + " has no keys"
+	case len(keys) > maxNearbyKeys:
+		note = fmt.Sprintf("%s has the keys %s and %d more", where, strings.Join(keys[:maxNearbyKeys], ", "), len(keys)-maxNearbyKeys)
+	default:
+		note = where + " has the keys " + strings.Join(keys, ", ")
+	}
+	if near.StartLine > 0 {
+		d.Related = append(d.Related, diag.RelatedTo(near, "%s", note))
+	} else {
+		d.Message += " (" + note + ")"
+	}
+	return d
+}
@@ -0,0 +1,46 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDeadReferences(t *testing.T) {
+	input := `{
+  "db": {"host": "db.internal", "port": 5432},
+  "servers": [{"url": "http://a"}],
+  "dsn": "postgres://${db.host}:${db.prot}/app?password=${DB_PASSWORD}&user=${env:USER}",
+  "first": "${servers[0].url} ${servers[1].url}",
+  "cache": "${redis.host} ${dsn.host}",
+  "bad": "${a..b}"
+}`
+	p := slowjson.NewParser(input)
+	p.File = "app.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := NewRunner(DeadReferences()).Run(root)
+	want := []string{
+		`app.json:4:10: error: dsn: reference ${db.prot} does not resolve, db.prot is not set, did you mean ${db.port}? [TC4002 dead-reference]`,
+		`app.json:5:12: error: first: reference ${servers[1].url} does not resolve, servers[1] is not set [TC4002 dead-reference]`,
+		`app.json:6:12: error: cache: reference ${redis.host} does not resolve, redis is not set [TC4002 dead-reference]`,
+		`app.json:6:12: error: cache: reference ${dsn.host} does not resolve, dsn.host is not set [TC4002 dead-reference]`,
+		`app.json:7:10: error: bad: invalid reference ${a..b} [TC4002 dead-reference]`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("Run() = %v", diags)
+	}
+	for i, d := range diags {
+		if d.String() != want[i] {
+			t.Errorf("diags[%d] = %s\nwant %s", i, d, want[i])
+		}
+	}
+	notes := []string{"db has the keys host, port", "servers is an array of 1 items", "the root has the keys db, servers, dsn, first, cache, bad", "dsn is not an object"}
+	for i, note := range notes {
+		if len(diags[i].Related) != 1 || diags[i].Related[0].Message != note {
+			t.Errorf("diags[%d].Related = %v, want %q", i, diags[i].Related, note)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// RepeatedOptions tunes RepeatedValues.
+type RepeatedOptions struct {
+	// MinCount is how many times a value must occur to be reported, 3 when zero.
+	MinCount int
+	// MinLength is the shortest value in characters that is reported, 8 when zero, so ports, flags and
+	// words like "info" repeated on purpose are not.
+	MinLength int
+}
+
+// Repeated is a value that occurs in several places.
+type Repeated struct {
+	// Value is the text of the value, e.g. the URL.
+	Value string
+	// Occurrences are the nodes having the value in file and document order.
+	Occurrences []*slowjson.Node
+}
+
+// RepeatedValues finds identical string and number values that occur in many places across roots, e.g.
+// the same URL pasted into every service, most repeated first. Each is better defined once, in a shared
+// key or a YAML anchor, so changing it cannot miss a copy. Values a YAML alias copied count once.
+func RepeatedValues(roots []*slowjson.Node, opts RepeatedOptions) []Repeated {
+	if opts.MinCount == 0 {
+		opts.MinCount = 3
+	}
+	if opts.MinLength == 0 {
+		opts.MinLength = 8
+	}
+	type value struct {
+		typ  slowjson.NodeType
+		text string
+	}
+	type position struct {
+		file   string
+		offset int
+	}
+	groups := map[value][]*slowjson.Node{}
+	var order []value
+	seen := map[position]bool{}
+	for _, root := range roots {
+		walk(root, func(n *slowjson.Node) {
+			if n.IsKey() || n.Type != slowjson.NodeString && n.Type != slowjson.NodeNumber || utf8.RuneCountInString(n.Value) < opts.MinLength {
+				return
+			}
+			pos := position{n.File, n.StartOffset}
+			if seen[pos] {
+				return
+			}
+			seen[pos] = true
+			v := value{n.Type, n.Value}
+			if _, ok := groups[v]; !ok {
+				order = append(order, v)
+			}
+			groups[v] = append(groups[v], n)
+		})
+	}
+	var out []Repeated
+	for _, v := range order {
+		if nodes := groups[v]; len(nodes) >= opts.MinCount {
+			out = append(out, Repeated{Value: v.text, Occurrences: nodes})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Occurrences) > len(out[j].Occurrences) })
+	return out
+}
+
+// Diagnostic reports the value on its first occurrence with a note on every other one. It suggests
+// the key most occurrences are named by as the name of the shared key, or of an anchor when every
+// occurrence is in the same YAML file.
+func (r Repeated) Diagnostic() diag.Diagnostic {
+	first := r.Occurrences[0]
+	text, err := slowjson.Marshal(first)
+	if err != nil {
+		text = []byte(r.Value)
+	}
+	where := "a shared key"
+	if name := r.commonKey(); name != "" {
+		where = fmt.Sprintf("a shared key like %q", name)
+		if r.sameYAMLFile() {
+			where = fmt.Sprintf("an anchor like &%s", name)
+		}
+	} else if r.sameYAMLFile() {
+		where = "an anchor"
+	}
+	d := diag.New(first, diag.SeverityInfo, "value %s is repeated %d times, consider extracting it to %s", text, len(r.Occurrences), where)
+	d.Code = diag.CodeRepeatedValue
+	for _, n := range r.Occurrences[1:] {
+		d.Related = append(d.Related, diag.RelatedTo(n, "repeated at %s", n.Path()))
+	}
+	return d
+}
+
+// commonKey returns the key naming the most occurrences, the first one on ties, empty when none
+// is named by a key.
+func (r Repeated) commonKey() string {
+	counts := map[string]int{}
+	best := ""
+	for _, n := range r.Occurrences {
+		if n.Parent == nil || !n.Parent.IsKey() {
+			continue
+		}
+		name := n.Parent.Value
+		counts[name]++
+		if counts[name] > counts[best] {
+			best = name
+		}
+	}
+	return best
+}
+
+func (r Repeated) sameYAMLFile() bool {
+	file := r.Occurrences[0].File
+	if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+		return false
+	}
+	for _, n := range r.Occurrences {
+		if n.File != file {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/yaml"
+)
+
+func TestRepeatedValues(t *testing.T) {
+	parse := func(file, input string) *slowjson.Node {
+		p := slowjson.NewParser(input)
+		p.File = file
+		n, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	a := parse("a.json", `{"api": {"url": "https://api.example.com"}, "level": "info", "port": 12345678}`)
+	b := parse("b.json", `{"billing": {"url": "https://api.example.com", "endpoint": "https://api.example.com"}, "level": "info", "timeout": 12345678}`)
+	c := parse("c.json", `{"level": "info", "id": 12345678}`)
+
+	got := RepeatedValues([]*slowjson.Node{a, b, c}, RepeatedOptions{})
+	if len(got) != 2 || got[0].Value != "https://api.example.com" || len(got[0].Occurrences) != 3 || got[1].Value != "12345678" {
+		t.Fatalf("RepeatedValues() = %v", got)
+	}
+	d := got[0].Diagnostic()
+	want := `a.json:1:17: info: api.url: value "https://api.example.com" is repeated 3 times, consider extracting it to a shared key like "url" [TC2010 repeated-value]`
+	if d.String() != want {
+		t.Errorf("Diagnostic() = %s", d)
+	}
+	if len(d.Related) != 2 || d.Related[1].Span.String() != "b.json:1:60" || d.Related[1].Message != "repeated at billing.endpoint" {
+		t.Errorf("Diagnostic().Related = %v", d.Related)
+	}
+	if got := RepeatedValues([]*slowjson.Node{a, b, c}, RepeatedOptions{MinCount: 4}); len(got) != 0 {
+		t.Errorf("RepeatedValues(MinCount 4) = %v", got)
+	}
+	if got := RepeatedValues([]*slowjson.Node{a, b, c}, RepeatedOptions{MinLength: 4}); len(got) != 3 || got[1].Value != "info" {
+		t.Errorf("RepeatedValues(MinLength 4) = %v", got)
+	}
+}
+
+func TestRepeatedValues_YAML(t *testing.T) {
+	root, err := yaml.Parse([]byte("a:\n  host: db.internal.example\nb:\n  host: db.internal.example\nc:\n  host: &h db.internal.example\nd:\n  host: *h\n"), "app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := RepeatedValues([]*slowjson.Node{root}, RepeatedOptions{})
+	if len(got) != 1 || len(got[0].Occurrences) != 3 {
+		t.Fatalf("RepeatedValues() = %v, aliases count once", got)
+	}
+	if d := got[0].Diagnostic(); !strings.Contains(d.Message, "an anchor like &host") {
+		t.Errorf("Diagnostic() = %s", d)
+	}
+}
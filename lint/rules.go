@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/internal/suggest"
+	"github.com/at15/tracedconfig/schema"
+	"github.com/at15/tracedconfig/secrets"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Builtin returns the built-in rules. Rules that need a schema are left out when s is nil.
+func Builtin(s *schema.Schema) []Rule {
+	rules := []Rule{DuplicateKeys(), EmptyValues(), PlaintextSecrets(), Secrets()}
+	if s != nil {
+		rules = append(rules, SimilarKeys(s), UnknownKeys(s), Schema(s))
+	}
+	return rules
+}
+
+// DuplicateKeys reports keys defined more than once in the same object. Only the last one takes effect.
+func DuplicateKeys() Rule {
+	return NewRule("duplicate-key", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		walk(root, func(n *slowjson.Node) {
+			if n.Type != slowjson.NodeObject {
+				return
+			}
+			first := map[string]*slowjson.Node{}
+			for _, key := range n.Children {
+				if prev, ok := first[key.Value]; ok {
+					diags = append(diags, diag.Errorf(key, "duplicate key %q, first defined at %s, only the last one is used", key.Value, prev.Location()))
+					continue
+				}
+				first[key.Value] = key
+			}
+		})
+		return diags
+	})
+}
+
+// EmptyValues reports empty or blank strings and nulls, which usually mean a value was forgotten.
+func EmptyValues() Rule {
+	return NewRule("empty-value", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		walk(root, func(n *slowjson.Node) {
+			switch {
+			case n.IsKey():
+			case n.Type == slowjson.NodeString && strings.TrimSpace(n.Value) == "":
+				diags = append(diags, diag.Warningf(n, "empty value"))
+			case n.Type == slowjson.NodeNull:
+				diags = append(diags, diag.Warningf(n, "null value"))
+			}
+		})
+		return diags
+	})
+}
+
+// secretReference matches values that point at a secret instead of containing it.
+var secretReference = regexp.MustCompile(`^(\$\{.+\}|\$[A-Za-z_][A-Za-z0-9_]*|ENC\[.*\]|[a-z][a-z0-9+.-]*://.*)$`)
+
+// PlaintextSecrets reports literal values of keys named like secrets, e.g. "password": "hunter2".
+// Values that reference a secret such as ${DB_PASSWORD}, ENC[...] or vault://path are allowed.
+func PlaintextSecrets() Rule {
+	return NewRule("plaintext-secret", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		walk(root, func(n *slowjson.Node) {
+			if !n.IsKey() || len(n.Children) == 0 || !secrets.IsSecretKey(n.Value) {
+				return
+			}
+			v := n.Children[0]
+			if v.Type != slowjson.NodeString || strings.TrimSpace(v.Value) == "" || secretReference.MatchString(v.Value) {
+				return
+			}
+			diags = append(diags, diag.Warningf(v, "%s looks like a plaintext secret, reference it from the environment or a secret store", n.Value))
+		})
+		return diags
+	})
+}
+
+// Secrets reports values that look like credentials wherever they are, see secrets.Scan.
+func Secrets() Rule {
+	return NewRule("secret", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		for _, f := range secrets.Scan(root) {
+			diags = append(diags, diag.Errorf(f.Node, "value %s looks like a credential (%s)", secrets.Redact(f.Node.Value), f.Kind))
+		}
+		return diags
+	})
+}
+
+// SimilarKeys reports keys that are not in the schema but are a few edits away from one that is.
+func SimilarKeys(s *schema.Schema) Rule {
+	return NewRule("similar-key", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		unknownKeys(root, s, func(key *slowjson.Node, props schema.Properties) {
+			if name, ok := suggest.Closest(key.Value, propertyNames(props)); ok {
+				diags = append(diags, diag.Warningf(key, "unknown key %q, did you mean %q?", key.Value, name))
+			}
+		})
+		return diags
+	})
+}
+
+// UnknownKeys reports keys that are not in the schema and not similar to any key that is.
+// Decode ignores them, so they have no effect.
+func UnknownKeys(s *schema.Schema) Rule {
+	return NewRule("unknown-key", func(root *slowjson.Node) []diag.Diagnostic {
+		var diags []diag.Diagnostic
+		unknownKeys(root, s, func(key *slowjson.Node, props schema.Properties) {
+			if _, ok := suggest.Closest(key.Value, propertyNames(props)); !ok {
+				diags = append(diags, diag.Warningf(key, "unknown key %q is not in the schema and has no effect", key.Value))
+			}
+		})
+		return diags
+	})
+}
+
+// Schema reports values that violate s, see schema.Schema.Validate. Diagnostics keep the code of the
+// violation, e.g. "TC2004" for a value outside an enum, and the rule is configured as "schema".
+func Schema(s *schema.Schema) Rule {
+	return NewRule("schema", s.Validate)
+}
+
+// unknownKeys calls report for every key of n not allowed by s, with the properties s does allow.
+// Keys match case-insensitively like in Decode.
+func unknownKeys(n *slowjson.Node, s *schema.Schema, report func(key *slowjson.Node, props schema.Properties)) {
+	if s == nil {
+		return
+	}
+	switch n.Type {
+	case slowjson.NodeArray:
+		for _, elem := range n.Children {
+			unknownKeys(elem, s.Items, report)
+		}
+	case slowjson.NodeObject:
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			prop := lookupProperty(s.Properties, key.Value)
+			switch {
+			case prop != nil:
+				unknownKeys(key.Children[0], prop, report)
+			case s.AdditionalProperties != nil:
+				unknownKeys(key.Children[0], s.AdditionalProperties, report)
+			case len(s.Properties) > 0:
+				report(key, s.Properties)
+			}
+		}
+	}
+}
+
+func lookupProperty(props schema.Properties, name string) *schema.Schema {
+	if p := props.Get(name); p != nil {
+		return p
+	}
+	for _, p := range props {
+		if strings.EqualFold(p.Name, name) {
+			return p.Schema
+		}
+	}
+	return nil
+}
+
+func propertyNames(props schema.Properties) []string {
+	names := make([]string, len(props))
+	for i, p := range props {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// walk calls f for n and every node below it.
+func walk(n *slowjson.Node, f func(n *slowjson.Node)) {
+	f(n)
+	for _, c := range n.Children {
+		walk(c, f)
+	}
+}
@@ -0,0 +1,236 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// DefaultName is the lockfile name inside the config directory, it is never listed itself.
+const DefaultName = "tracedconfig.lock"
+
+// hashPrefix names the hash algorithm so it can change without breaking old lockfiles.
+const hashPrefix = "sha256:"
+
+// Entry is a file and its hash.
+type Entry struct {
+	// Path is relative to the directory and uses forward slashes.
+	Path string
+	Hash string
+	// Node is the hash in the parsed lockfile, nil for generated entries.
+	Node *slowjson.Node
+}
+
+// Manifest lists the files of a config directory sorted by path.
+type Manifest struct {
+	Entries []Entry
+}
+
+// Generate hashes every regular file below dir. The lockfile and hidden files and directories, e.g. .git, are skipped.
+func Generate(dir string) (*Manifest, error) {
+	m := &Manifest{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == DefaultName {
+			return nil
+		}
+		hash, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, Entry{Path: rel, Hash: hash})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m, nil
+}
+
+// HashFile returns the hash of a file as written in lockfiles, e.g. "sha256:9f86...".
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Parse reads a lockfile, a JSON object with a "files" object mapping paths to hashes.
+func Parse(root *slowjson.Node) (*Manifest, error) {
+	if root.Type != slowjson.NodeObject {
+		return nil, root.Errorf("lockfile must be an object")
+	}
+	m := &Manifest{}
+	for _, key := range root.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "version":
+			if v.Type != slowjson.NodeNumber || v.Value != "1" {
+				return nil, v.Errorf("unsupported lockfile version %s", v.Value)
+			}
+		case "files":
+			if v.Type != slowjson.NodeObject {
+				return nil, v.Errorf("files must be an object")
+			}
+			for _, file := range v.Children {
+				hash := file.Children[0]
+				if hash.Type != slowjson.NodeString || !strings.HasPrefix(hash.Value, hashPrefix) {
+					return nil, hash.Errorf("hash must be a string starting with %q", hashPrefix)
+				}
+				m.Entries = append(m.Entries, Entry{Path: file.Value, Hash: hash.Value, Node: hash})
+			}
+		default:
+			return nil, key.Errorf("unknown lockfile field %q", key.Value)
+		}
+	}
+	sort.SliceStable(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m, nil
+}
+
+// Load reads and parses the lockfile at path.
+func Load(path string) (*Manifest, error) {
+	root, err := slowjson.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(root)
+}
+
+// Marshal encodes the manifest as an indented lockfile.
+func (m *Manifest) Marshal() []byte {
+	var sb strings.Builder
+	sb.WriteString("{\n  \"version\": 1,\n  \"files\": {")
+	for i, e := range m.Entries {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "\n    %q: %q", e.Path, e.Hash)
+	}
+	if len(m.Entries) > 0 {
+		sb.WriteString("\n  ")
+	}
+	sb.WriteString("}\n}\n")
+	return []byte(sb.String())
+}
+
+// Write writes the manifest to path.
+func (m *Manifest) Write(path string) error {
+	return os.WriteFile(path, m.Marshal(), 0o644)
+}
+
+// DriftKind is how a file differs from the lockfile.
+type DriftKind int
+
+const (
+	// Modified files have a different hash than recorded.
+	Modified DriftKind = iota
+	// Missing files are in the lockfile but not on disk.
+	Missing
+	// Added files are on disk but not in the lockfile.
+	Added
+)
+
+func (k DriftKind) String() string {
+	switch k {
+	case Modified:
+		return "modified"
+	case Missing:
+		return "missing"
+	case Added:
+		return "added"
+	default:
+		return fmt.Sprintf("DriftKind(%d)", int(k))
+	}
+}
+
+// Drift is a file that differs from the lockfile.
+type Drift struct {
+	Kind DriftKind
+	Path string
+	// Entry is the lockfile entry, zero for added files.
+	Entry Entry
+}
+
+// String formats the drift as "modified app.json (locked at tracedconfig.lock:4:15)".
+func (d Drift) String() string {
+	if d.Entry.Node != nil {
+		return fmt.Sprintf("%s %s (locked at %s)", d.Kind, d.Path, d.Entry.Node.Location())
+	}
+	return fmt.Sprintf("%s %s", d.Kind, d.Path)
+}
+
+// Compare returns the differences of actual from m, sorted by path.
+func (m *Manifest) Compare(actual *Manifest) []Drift {
+	have := map[string]Entry{}
+	for _, e := range actual.Entries {
+		have[e.Path] = e
+	}
+	locked := map[string]bool{}
+	var drift []Drift
+	for _, e := range m.Entries {
+		locked[e.Path] = true
+		a, ok := have[e.Path]
+		switch {
+		case !ok:
+			drift = append(drift, Drift{Kind: Missing, Path: e.Path, Entry: e})
+		case a.Hash != e.Hash:
+			drift = append(drift, Drift{Kind: Modified, Path: e.Path, Entry: e})
+		}
+	}
+	for _, e := range actual.Entries {
+		if !locked[e.Path] {
+			drift = append(drift, Drift{Kind: Added, Path: e.Path})
+		}
+	}
+	sort.SliceStable(drift, func(i, j int) bool { return drift[i].Path < drift[j].Path })
+	return drift
+}
+
+// Verify compares dir with its lockfile DefaultName and returns the drift, empty when they match.
+func Verify(dir string) ([]Drift, error) {
+	return VerifyWith(dir, filepath.Join(dir, DefaultName))
+}
+
+// VerifyWith compares dir with the lockfile at lockPath.
+func VerifyWith(dir, lockPath string) ([]Drift, error) {
+	m, err := Load(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := Generate(dir)
+	if err != nil {
+		return nil, err
+	}
+	return m.Compare(actual), nil
+}
@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app.json", `{"port": 80}`)
+	writeFile(t, dir, "env/prod.json", `{"port": 443}`)
+	writeFile(t, dir, ".git/HEAD", "ref")
+	writeFile(t, dir, ".hidden.json", "{}")
+
+	m, err := Generate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range m.Entries {
+		paths = append(paths, e.Path)
+	}
+	if got := strings.Join(paths, ","); got != "app.json,env/prod.json" {
+		t.Fatalf("paths = %s", got)
+	}
+	if !strings.HasPrefix(m.Entries[0].Hash, "sha256:") || len(m.Entries[0].Hash) != len("sha256:")+64 {
+		t.Errorf("hash = %s", m.Entries[0].Hash)
+	}
+	if err := m.Write(filepath.Join(dir, DefaultName)); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err := Verify(dir)
+	if err != nil || len(drift) != 0 {
+		t.Fatalf("Verify() = %v, %v", drift, err)
+	}
+
+	writeFile(t, dir, "app.json", `{"port": 8080}`)
+	writeFile(t, dir, "extra.json", `{}`)
+	if err := os.Remove(filepath.Join(dir, "env/prod.json")); err != nil {
+		t.Fatal(err)
+	}
+	drift, err = Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range drift {
+		got = append(got, d.String())
+	}
+	lock := filepath.Join(dir, DefaultName)
+	want := []string{
+		"modified app.json (locked at " + lock + ":4:17)",
+		"missing env/prod.json (locked at " + lock + ":5:22)",
+		"added extra.json",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Verify() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+	}{
+		{"valid", `{"version": 1, "files": {"a.json": "sha256:00"}}`, ""},
+		{"version", `{"version": 2, "files": {}}`, "unsupported lockfile version 2"},
+		{"hash", `{"files": {"a.json": "md5:00"}}`, `files["a.json"]: hash must be a string starting with "sha256:"`},
+		{"unknown field", `{"file": {}}`, `unknown lockfile field "file"`},
+		{"not an object", `[]`, "lockfile must be an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := slowjson.NewParser(tt.input).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Parse(root)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package manifest records the hash of every file in a config directory in a lockfile and reports
+// drift between the lockfile, i.e. what was reviewed, and what is on disk.
+package manifest
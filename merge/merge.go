@@ -0,0 +1,471 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Layer is one config document taking part in a merge.
+type Layer struct {
+	// Name identifies the layer in provenance, e.g. "defaults" or "env".
+	Name string
+	Root *slowjson.Node
+	// Meta describes the layer in provenance, e.g. a version or the identity of its signer.
+	Meta map[string]string
+}
+
+// Options configures Merge.
+type Options struct {
+	// ArrayKeys lists arrays of objects that are merged element by element instead of replaced.
+	// It maps the path of the array, with indexes written as [*], to the key identifying an element,
+	// e.g. "servers": "name" or "clusters[*].nodes": "id". Elements of a later layer modify the element
+	// with the same identity and are appended when there is none.
+	ArrayKeys map[string]string
+	// ArrayIdentity, when set, is called with the path of every array not in ArrayKeys, e.g.
+	// services.web.ports, and returns how to identify its elements, or nil to replace the array as a
+	// whole. Elements of a later layer replace the element with the same identity and are appended
+	// when there is none, so a func returning the element as JSON appends the new elements.
+	ArrayIdentity func(path slowjson.Path) func(elem *slowjson.Node) string
+	// UnsetMarker is the string value that removes a key set by an earlier layer, Unset when empty.
+	UnsetMarker string
+	// NullUnsets makes null remove a key set by an earlier layer too, like Helm treats null in values
+	// overriding the defaults of a chart. A null for a key no earlier layer set is kept.
+	NullUnsets bool
+	// Strict makes two layers setting a key to different values a conflict unless the later layer
+	// lists the key in an OverrideKey member. Conflicts are reported in Result.Diagnostics with both
+	// definitions and the earlier value is kept, for configs several teams maintain. Objects are still
+	// merged key by key, and a layer may override what it set itself, e.g. in a profile block.
+	Strict bool
+	// Profiles are the active profiles. An object member "@profile:<name>" of an active profile is
+	// merged into the object containing it after its other members, in the order of Profiles.
+	// Blocks of inactive profiles are dropped.
+	Profiles []string
+}
+
+// Unset is the default marker removing a key, e.g. {"tls": "!unset"}.
+const Unset = "!unset"
+
+// ProfilePrefix starts the keys of profile blocks, e.g. "@profile:production".
+const ProfilePrefix = "@profile:"
+
+// FinalKey lists keys later layers cannot override, relative to the object containing it, e.g.
+// {"tls": {"verify": true}, "@final": ["tls.verify"]}. Overrides are dropped and reported in Result.Diagnostics.
+const FinalKey = "@final"
+
+// OverrideKey lists keys a layer means to override in a Strict merge, relative to the object containing
+// it, e.g. {"db": {"port": 6432}, "@override": ["db.port"]}. Listing a key covers the keys below it.
+// Without Strict it is an ordinary key.
+const OverrideKey = "@override"
+
+// Merge merges layers in order with default options.
+func Merge(layers ...Layer) (*Result, error) {
+	return Options{}.Merge(layers...)
+}
+
+// Merge merges layers in order. Objects are merged key by key, arrays listed in ArrayKeys
+// element by element, and any other value of a later layer replaces the earlier one.
+func (o Options) Merge(layers ...Layer) (*Result, error) {
+	m := &merger{opts: o, res: &Result{history: map[string][]Origin{}, children: map[string]map[string]bool{}}}
+	for i, l := range layers {
+		if l.Root == nil {
+			continue
+		}
+		m.layer = i
+		root, err := m.apply(nil, m.res.Root, l.Root, Origin{Layer: l.Name, Meta: l.Meta})
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", l.Name, err)
+		}
+		m.res.Root = root
+	}
+	if m.res.Root == nil {
+		m.res.Root = &slowjson.Node{Type: slowjson.NodeObject}
+	}
+	return m.res, nil
+}
+
+type merger struct {
+	opts   Options
+	res    *Result
+	layer  int
+	frozen []frozenKey
+	// overrides are the keys listed in OverrideKey members
+	overrides []frozenKey
+}
+
+// frozenKey is a key declared final, or listed in an OverrideKey member.
+type frozenKey struct {
+	path  slowjson.Path
+	layer int
+	from  Origin // Node is the declaring entry of FinalKey or OverrideKey
+}
+
+// apply merges the layer value over into cur, the merged value so far at path, and returns the new value.
+// cur belongs to the merged tree and is modified in place. from is the origin without its Node.
+func (m *merger) apply(path slowjson.Path, cur, over *slowjson.Node, from Origin) (*slowjson.Node, error) {
+	switch {
+	case cur != nil && cur.Type == slowjson.NodeObject && over.Type == slowjson.NodeObject:
+		m.res.record(path, from.at(over))
+		if err := m.declareFinal(path, over, from); err != nil {
+			return nil, err
+		}
+		if err := m.declareOverrides(path, over, from); err != nil {
+			return nil, err
+		}
+		for _, key := range over.Children {
+			if len(key.Children) == 0 || m.isDirective(key.Value) {
+				continue
+			}
+			existing := findKey(cur, key.Value)
+			if m.violatesFinal(path.Key(key.Value), existing, key.Children[0], from) {
+				continue
+			}
+			if m.conflicts(path.Key(key.Value), existing, key.Children[0], from) {
+				continue
+			}
+			if m.isUnset(key.Children[0]) && (existing != nil || key.Children[0].Type != slowjson.NodeNull) {
+				if existing != nil {
+					cur.Children = append(cur.Children[:existing.Index()], cur.Children[existing.Index()+1:]...)
+				}
+				m.unset(path.Key(key.Value), key.Children[0], from)
+				continue
+			}
+			var prev *slowjson.Node
+			if existing != nil {
+				prev = existing.Children[0]
+			}
+			v, err := m.apply(path.Key(key.Value), prev, key.Children[0], from)
+			if err != nil {
+				return nil, err
+			}
+			k := copyNode(key, cur)
+			k.Children = []*slowjson.Node{v}
+			v.Parent = k
+			if existing != nil {
+				cur.Children[existing.Index()] = k
+			} else {
+				cur.Children = append(cur.Children, k)
+			}
+		}
+		return cur, m.applyProfiles(path, cur, over, from)
+	case cur != nil && cur.Type == slowjson.NodeArray && over.Type == slowjson.NodeArray && m.arrayKey(path) != "":
+		return m.applyKeyed(path, cur, over, from, m.arrayKey(path))
+	case cur != nil && cur.Type == slowjson.NodeArray && over.Type == slowjson.NodeArray && m.opts.ArrayIdentity != nil && m.opts.ArrayIdentity(path) != nil:
+		return m.applyIdentified(path, cur, over, from, m.opts.ArrayIdentity(path))
+	default:
+		m.res.forget(path)
+		return m.clone(path, over, nil, from)
+	}
+}
+
+// applyProfiles merges the blocks of active profiles in over into the merged object cur.
+func (m *merger) applyProfiles(path slowjson.Path, cur, over *slowjson.Node, from Origin) error {
+	for _, profile := range m.opts.Profiles {
+		key := findKey(over, ProfilePrefix+profile)
+		if key == nil {
+			continue
+		}
+		block := key.Children[0]
+		if block.Type != slowjson.NodeObject {
+			return block.Errorf("profile block %s must be an object", key.Value)
+		}
+		pfrom := from
+		pfrom.Profile = profile
+		if _, err := m.apply(path, cur, block, pfrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyKeyed merges the elements of over into cur by the identity key.
+func (m *merger) applyKeyed(path slowjson.Path, cur, over *slowjson.Node, from Origin, key string) (*slowjson.Node, error) {
+	m.res.record(path, from.at(over))
+	for _, elem := range over.Children {
+		id, ok := identity(elem, key)
+		if !ok {
+			return nil, elem.Errorf("array element has no %q key to merge by", key)
+		}
+		i := -1
+		for j, c := range cur.Children {
+			if cid, _ := identity(c, key); cid == id {
+				i = j
+			}
+		}
+		var prev *slowjson.Node
+		if i < 0 {
+			i = len(cur.Children)
+			cur.Children = append(cur.Children, nil)
+		} else {
+			prev = cur.Children[i]
+		}
+		v, err := m.apply(path.Index(i), prev, elem, from)
+		if err != nil {
+			return nil, err
+		}
+		v.Parent = cur
+		cur.Children[i] = v
+	}
+	return cur, nil
+}
+
+// applyIdentified merges the elements of over into cur, replacing those with the same identity.
+func (m *merger) applyIdentified(path slowjson.Path, cur, over *slowjson.Node, from Origin, id func(*slowjson.Node) string) (*slowjson.Node, error) {
+	m.res.record(path, from.at(over))
+	for _, elem := range over.Children {
+		i := len(cur.Children)
+		for j, c := range cur.Children {
+			if id(c) == id(elem) {
+				i = j
+			}
+		}
+		if i == len(cur.Children) {
+			cur.Children = append(cur.Children, nil)
+		}
+		m.res.forget(path.Index(i))
+		v, err := m.clone(path.Index(i), elem, cur, from)
+		if err != nil {
+			return nil, err
+		}
+		cur.Children[i] = v
+	}
+	return cur, nil
+}
+
+func (m *merger) arrayKey(path slowjson.Path) string {
+	if len(m.opts.ArrayKeys) == 0 {
+		return ""
+	}
+	return m.opts.ArrayKeys[pattern(path)]
+}
+
+// pattern writes path with indexes replaced by [*].
+func pattern(path slowjson.Path) string {
+	var sb strings.Builder
+	for i, seg := range path {
+		if seg.IsIndex {
+			sb.WriteString("[*]")
+			continue
+		}
+		s := slowjson.Path{seg}.String()
+		if i > 0 && !strings.HasPrefix(s, "[") {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+// identity returns the value of key in an object element, typed so 1 and "1" differ.
+func identity(elem *slowjson.Node, key string) (string, bool) {
+	if elem.Type != slowjson.NodeObject {
+		return "", false
+	}
+	k := findKey(elem, key)
+	if k == nil {
+		return "", false
+	}
+	v := k.Children[0]
+	if v.Type == slowjson.NodeObject || v.Type == slowjson.NodeArray {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%s", v.Type, v.Value), true
+}
+
+// findKey returns the last key node named name with a value, duplicates are resolved like Lookup.
+func findKey(obj *slowjson.Node, name string) *slowjson.Node {
+	var found *slowjson.Node
+	for _, key := range obj.Children {
+		if key.Value == name && len(key.Children) > 0 {
+			found = key
+		}
+	}
+	return found
+}
+
+// clone copies the layer value n into the merged tree and records it as set by from.
+// Unset markers and profile blocks are resolved on the way.
+func (m *merger) clone(path slowjson.Path, n, parent *slowjson.Node, from Origin) (*slowjson.Node, error) {
+	c := copyNode(n, parent)
+	m.res.record(path, from.at(n))
+	for i, child := range n.Children {
+		if n.Type != slowjson.NodeObject {
+			v, err := m.clone(path.Index(i), child, c, from)
+			if err != nil {
+				return nil, err
+			}
+			c.Children = append(c.Children, v)
+			continue
+		}
+		// only the last of duplicate keys takes effect
+		if len(child.Children) == 0 || findKey(n, child.Value) != child || m.isDirective(child.Value) {
+			continue
+		}
+		childPath := path.Key(child.Value)
+		// nothing is set below a cloned value, so a null unsets nothing and stays
+		if m.isUnset(child.Children[0]) && child.Children[0].Type != slowjson.NodeNull {
+			m.unset(childPath, child.Children[0], from)
+			continue
+		}
+		k := copyNode(child, c)
+		v, err := m.clone(childPath, child.Children[0], k, from)
+		if err != nil {
+			return nil, err
+		}
+		k.Children = []*slowjson.Node{v}
+		c.Children = append(c.Children, k)
+	}
+	if n.Type == slowjson.NodeObject {
+		if err := m.declareFinal(path, n, from); err != nil {
+			return nil, err
+		}
+		if err := m.declareOverrides(path, n, from); err != nil {
+			return nil, err
+		}
+		return c, m.applyProfiles(path, c, n, from)
+	}
+	return c, nil
+}
+
+// declareFinal freezes the keys listed in the FinalKey member of the layer object obj at path.
+func (m *merger) declareFinal(path slowjson.Path, obj *slowjson.Node, from Origin) error {
+	key := findKey(obj, FinalKey)
+	if key == nil {
+		return nil
+	}
+	list := key.Children[0]
+	if list.Type != slowjson.NodeArray {
+		return list.Errorf("%s must be an array of paths", FinalKey)
+	}
+	for _, entry := range list.Children {
+		if entry.Type != slowjson.NodeString {
+			return entry.Errorf("%s must be an array of paths", FinalKey)
+		}
+		rel, err := slowjson.ParsePath(entry.Value)
+		if err != nil || len(rel) == 0 {
+			return entry.Errorf("invalid final path %q", entry.Value)
+		}
+		p := append(append(slowjson.Path{}, path...), rel...)
+		m.frozen = append(m.frozen, frozenKey{path: p, layer: m.layer, from: from.at(entry)})
+	}
+	return nil
+}
+
+// declareOverrides records the keys listed in the OverrideKey member of the layer object obj at path.
+// Without Strict there is nothing to override and the member is kept as config.
+func (m *merger) declareOverrides(path slowjson.Path, obj *slowjson.Node, from Origin) error {
+	if !m.opts.Strict {
+		return nil
+	}
+	key := findKey(obj, OverrideKey)
+	if key == nil {
+		return nil
+	}
+	list := key.Children[0]
+	if list.Type != slowjson.NodeArray {
+		return list.Errorf("%s must be an array of paths", OverrideKey)
+	}
+	for _, entry := range list.Children {
+		if entry.Type != slowjson.NodeString {
+			return entry.Errorf("%s must be an array of paths", OverrideKey)
+		}
+		rel, err := slowjson.ParsePath(entry.Value)
+		if err != nil || len(rel) == 0 {
+			return entry.Errorf("invalid override path %q", entry.Value)
+		}
+		p := append(append(slowjson.Path{}, path...), rel...)
+		m.overrides = append(m.overrides, frozenKey{path: p, layer: m.layer, from: from.at(entry)})
+	}
+	return nil
+}
+
+// conflicts reports whether setting the layer value over at path, where the merged value is the key
+// existing, is a conflict of a Strict merge, and records it.
+func (m *merger) conflicts(path slowjson.Path, existing, over *slowjson.Node, from Origin) bool {
+	if !m.opts.Strict || existing == nil {
+		return false
+	}
+	prev := existing.Children[0]
+	switch {
+	case m.isUnset(over):
+	case prev.Type == slowjson.NodeObject && over.Type == slowjson.NodeObject:
+		return false
+	case prev.Type == slowjson.NodeArray && over.Type == slowjson.NodeArray && m.arrayKey(path) != "":
+		return false
+	case slowjson.Equal(prev, over, slowjson.EqualOptions{}):
+		return false
+	}
+	h := m.res.history[path.String()]
+	if len(h) == 0 || h[len(h)-1].Layer == from.Layer {
+		return false
+	}
+	for _, o := range m.overrides {
+		if o.layer == m.layer && path.HasPrefix(o.path) {
+			return false
+		}
+	}
+	d := diag.Errorf(over, "%s from %s conflicts with %s from %s, list %s in %s to override it",
+		value(over), from.Layer, value(prev), h[len(h)-1].Layer, slowjson.Path{}.Key(path[len(path)-1].Key), OverrideKey)
+	d.Code = diag.CodeMergeConflict
+	d.Related = append(d.Related, diag.RelatedTo(h[len(h)-1].Node, "set by %s", h[len(h)-1].Layer))
+	m.res.Diagnostics = append(m.res.Diagnostics, d)
+	return true
+}
+
+// isDirective reports whether the object key is an instruction to the merge rather than config.
+// OverrideKey is only one in a Strict merge, otherwise it is a key like any other.
+func (m *merger) isDirective(key string) bool {
+	return strings.HasPrefix(key, ProfilePrefix) || key == FinalKey || key == OverrideKey && m.opts.Strict
+}
+
+// violatesFinal reports whether setting the layer value over at path, where the merged value is
+// the key existing, overrides a key frozen by an earlier layer, and records the violation.
+// Objects merged into an object above a frozen key only violate it when they reach it.
+func (m *merger) violatesFinal(path slowjson.Path, existing, over *slowjson.Node, from Origin) bool {
+	for _, f := range m.frozen {
+		if f.layer >= m.layer {
+			continue
+		}
+		below := path.HasPrefix(f.path)
+		above := f.path.HasPrefix(path) && !below
+		mergeable := existing != nil && existing.Children[0].Type == slowjson.NodeObject &&
+			over.Type == slowjson.NodeObject && !m.isUnset(over)
+		if !below && !(above && !mergeable) {
+			continue
+		}
+		d := diag.Errorf(over, "%s is final and cannot be overridden by %s", f.path, from.Layer)
+		d.Code = diag.CodeFinalKey
+		d.Related = append(d.Related, diag.RelatedTo(f.from.Node, "declared final by %s", f.from.Layer))
+		if v := m.res.Root.Lookup(f.path); v != nil {
+			d.Related = append(d.Related, diag.RelatedTo(v, "final value"))
+		}
+		m.res.Diagnostics = append(m.res.Diagnostics, d)
+		return true
+	}
+	return false
+}
+
+func (m *merger) isUnset(n *slowjson.Node) bool {
+	marker := m.opts.UnsetMarker
+	if marker == "" {
+		marker = Unset
+	}
+	return n.Type == slowjson.NodeString && n.Value == marker || m.opts.NullUnsets && n.Type == slowjson.NodeNull
+}
+
+// unset records the removal of the value at path by the marker node.
+func (m *merger) unset(path slowjson.Path, marker *slowjson.Node, from Origin) {
+	m.res.forget(path)
+	o := from.at(marker)
+	o.Unset = true
+	m.res.record(path, o)
+}
+
+func copyNode(n, parent *slowjson.Node) *slowjson.Node {
+	c := *n
+	c.Parent = parent
+	c.Children = nil
+	return &c
+}
@@ -0,0 +1,478 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func layer(t *testing.T, name, input string) Layer {
+	t.Helper()
+	p := slowjson.NewParser(input)
+	p.File = name + ".json"
+	n, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return Layer{Name: name, Root: n}
+}
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		layers []string
+		want   string
+	}{
+		{"objects merge", Options{}, []string{
+			`{"server": {"host": "localhost", "port": 80}, "debug": false}`,
+			`{"server": {"port": 8080}, "name": "app"}`,
+		}, `{"server":{"host":"localhost","port":8080},"debug":false,"name":"app"}`},
+		{"arrays replace", Options{}, []string{
+			`{"tags": ["a", "b"]}`,
+			`{"tags": ["c"]}`,
+		}, `{"tags":["c"]}`},
+		{"type change replaces", Options{}, []string{
+			`{"a": {"b": 1}}`,
+			`{"a": [1]}`,
+			`{"a": {"c": 2}}`,
+		}, `{"a":{"c":2}}`},
+		{"duplicate keys", Options{}, []string{
+			`{"a": 1, "a": 2}`,
+			`{"b": 3}`,
+		}, `{"a":2,"b":3}`},
+		{"keyed arrays", Options{ArrayKeys: map[string]string{"servers": "name"}}, []string{
+			`{"servers": [{"name": "a", "port": 1}, {"name": "b", "port": 2}]}`,
+			`{"servers": [{"name": "b", "port": 20}, {"name": "c", "port": 3}]}`,
+		}, `{"servers":[{"name":"a","port":1},{"name":"b","port":20},{"name":"c","port":3}]}`},
+		{"nested keyed arrays", Options{ArrayKeys: map[string]string{"clusters": "id", "clusters[*].nodes": "id"}}, []string{
+			`{"clusters": [{"id": 1, "nodes": [{"id": "x", "up": true}]}]}`,
+			`{"clusters": [{"id": 1, "nodes": [{"id": "x", "up": false}, {"id": "y"}]}, {"id": "1"}]}`,
+		}, `{"clusters":[{"id":1,"nodes":[{"id":"x","up":false},{"id":"y"}]},{"id":"1"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layers []Layer
+			for i, in := range tt.layers {
+				layers = append(layers, layer(t, string(rune('a'+i)), in))
+			}
+			res, err := tt.opts.Merge(layers...)
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if got := marshal(t, res.Root); got != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge_LayersUnchanged(t *testing.T) {
+	base := layer(t, "base", `{"server": {"port": 80}}`)
+	over := layer(t, "over", `{"server": {"host": "x"}}`)
+	if _, err := Merge(base, over); err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, base.Root); got != `{"server":{"port":80}}` {
+		t.Errorf("base layer modified: %s", got)
+	}
+}
+
+func TestMerge_Positions(t *testing.T) {
+	res, err := Merge(
+		layer(t, "defaults", "{\n  \"server\": {\"port\": 80, \"host\": \"x\"}\n}"),
+		layer(t, "prod", "{\"server\": {\n  \"port\": 8080}}"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := res.Root.Get("server.port")
+	if err := port.Errorf("bad port"); err.Error() != "server.port: bad port at prod.json:2:11" {
+		t.Errorf("Errorf() = %v", err)
+	}
+	host := res.Root.Get("server.host")
+	if host.Location() != "defaults.json:2:34" || host.Parent.Parent.Parent.Parent != res.Root {
+		t.Errorf("host at %s", host.Location())
+	}
+}
+
+func TestResult_Explain(t *testing.T) {
+	res, err := Options{ArrayKeys: map[string]string{"servers": "name"}}.Merge(
+		layer(t, "defaults", `{"server": {"port": 80}, "servers": [{"name": "a", "port": 1}]}`),
+		layer(t, "prod", `{"server": {"port": 8080}, "servers": [{"name": "a", "port": 2}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := res.Explain("server.port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "server.port = 8080\n" +
+		"  set by prod.json:1:21 (prod)\n" +
+		"  overrides 80 from defaults.json:1:21 (defaults)\n"
+	if got != want {
+		t.Errorf("Explain() =\n%s\nwant\n%s", got, want)
+	}
+
+	h, err := res.History("servers[0].port")
+	if err != nil || len(h) != 2 || h[0].Layer != "defaults" || h[1].Node.Value != "2" {
+		t.Errorf("History() = %v, %v", h, err)
+	}
+	if o, ok := res.Origin("servers[0]"); !ok || o.Layer != "prod" {
+		t.Errorf("Origin() = %v, %v", o, ok)
+	}
+	if _, err := res.Explain("server.missing"); err == nil || !strings.Contains(err.Error(), "server.missing is not set") {
+		t.Errorf("Explain() error = %v", err)
+	}
+}
+
+func TestResult_HistoryReplaced(t *testing.T) {
+	res, err := Merge(
+		layer(t, "a", `{"db": {"host": "x", "port": 1}}`),
+		layer(t, "b", `{"db": "postgres://x"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := res.History("db.host"); len(h) != 0 {
+		t.Errorf("History(db.host) = %v, want none after replacement", h)
+	}
+	if h, _ := res.History("db"); len(h) != 2 {
+		t.Errorf("History(db) = %v", h)
+	}
+
+	res, err = Merge(
+		layer(t, "a", `{"db": {"host": "x", "opts": {"ssl": true}}, "dbx": {"y": 1}, "list": [{"a": 1}]}`),
+		layer(t, "b", `{"db": "postgres://x", "list": [2]}`),
+		layer(t, "c", `{"db": {"host": "z"}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{"db": 3, "db.host": 1, "db.opts": 0, "db.opts.ssl": 0, "dbx.y": 1, "list[0]": 1, "list[0].a": 0} {
+		if h, _ := res.History(path); len(h) != want {
+			t.Errorf("History(%s) = %v, want %d origins", path, h, want)
+		}
+	}
+}
+
+func TestMerge_KeyedArrayError(t *testing.T) {
+	_, err := Options{ArrayKeys: map[string]string{"servers": "name"}}.Merge(
+		layer(t, "a", `{"servers": [{"name": "a"}]}`),
+		layer(t, "b", `{"servers": [{"port": 1}]}`),
+	)
+	if err == nil || !strings.Contains(err.Error(), `layer b: servers[0]: array element has no "name" key to merge by at b.json:1:14`) {
+		t.Errorf("Merge() error = %v", err)
+	}
+}
+
+func TestMerge_ArrayIdentity(t *testing.T) {
+	opts := Options{ArrayIdentity: func(path slowjson.Path) func(*slowjson.Node) string {
+		if path.String() != "ports" {
+			return nil
+		}
+		return func(elem *slowjson.Node) string {
+			port, _, _ := strings.Cut(elem.Value, ":")
+			return port
+		}
+	}}
+	res, err := opts.Merge(
+		layer(t, "base", `{"ports": ["80:80", "443:443"], "dns": ["a"]}`),
+		layer(t, "override", `{"ports": ["8080:8080", "80:8000"], "dns": ["b"]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, res.Root); got != `{"ports":["80:8000","443:443","8080:8080"],"dns":["b"]}` {
+		t.Errorf("Merge() = %s", got)
+	}
+	if h, _ := res.History("ports[0]"); len(h) != 2 || h[0].Layer != "base" || h[1].Layer != "override" {
+		t.Errorf("History(ports[0]) = %v", h)
+	}
+	if o, _ := res.Origin("ports[1]"); o.Layer != "base" {
+		t.Errorf("Origin(ports[1]) = %v", o)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	if got := pattern(slowjson.MustParsePath(`clusters[2].nodes[0]["a.b"]`)); got != `clusters[*].nodes[*]["a.b"]` {
+		t.Errorf("pattern() = %s", got)
+	}
+}
+
+func TestMerge_Unset(t *testing.T) {
+	res, err := Merge(
+		layer(t, "defaults", `{"tls": {"cert": "a.pem"}, "port": 80, "debug": "!unset"}`),
+		layer(t, "prod", `{"tls": "!unset", "port": 8080, "extra": {"x": "!unset", "y": 1}}`),
+		layer(t, "local", `{"port": "!unset"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, res.Root); got != `{"extra":{"y":1}}` {
+		t.Errorf("Merge() = %s", got)
+	}
+	got, err := res.Explain("port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "port is unset\n" +
+		"  unset by local.json:1:10 (local)\n" +
+		"  overrides 8080 from prod.json:1:27 (prod)\n" +
+		"  overrides 80 from defaults.json:1:36 (defaults)\n"
+	if got != want {
+		t.Errorf("Explain() =\n%s\nwant\n%s", got, want)
+	}
+	if h, _ := res.History("tls.cert"); len(h) != 0 {
+		t.Errorf("History(tls.cert) = %v, want none after unset", h)
+	}
+	if o, ok := res.Origin("extra.x"); !ok || !o.Unset || o.Layer != "prod" {
+		t.Errorf("Origin(extra.x) = %+v", o)
+	}
+}
+
+func TestMerge_UnsetMarker(t *testing.T) {
+	res, err := Options{UnsetMarker: "~"}.Merge(
+		layer(t, "a", `{"a": 1, "b": "!unset"}`),
+		layer(t, "b", `{"a": "~"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, res.Root); got != `{"b":"!unset"}` {
+		t.Errorf("Merge() = %s", got)
+	}
+}
+
+func TestMerge_NullUnsets(t *testing.T) {
+	res, err := Options{NullUnsets: true}.Merge(
+		layer(t, "chart", `{"image": {"tag": "1.0", "digest": null}, "resources": {"cpu": 1}}`),
+		layer(t, "user", `{"image": {"tag": null}, "resources": null, "extra": null}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, res.Root); got != `{"image":{"digest":null},"extra":null}` {
+		t.Errorf("Merge() = %s", got)
+	}
+	if o, ok := res.Origin("image.tag"); !ok || !o.Unset || o.Layer != "user" {
+		t.Errorf("Origin(image.tag) = %+v", o)
+	}
+}
+
+func TestMerge_Profiles(t *testing.T) {
+	defaults := layer(t, "defaults", `{
+  "log": "debug",
+  "@profile:production": {"log": "warn", "server": {"replicas": 3}},
+  "@profile:eu": {"region": "eu-west-1", "server": {"replicas": 5}},
+  "server": {"port": 80, "@profile:production": {"port": 443}}
+}`)
+	tests := []struct {
+		profiles []string
+		want     string
+	}{
+		{nil, `{"log":"debug","server":{"port":80}}`},
+		{[]string{"production"}, `{"log":"warn","server":{"port":443,"replicas":3}}`},
+		{[]string{"production", "eu"}, `{"log":"warn","server":{"port":443,"replicas":5},"region":"eu-west-1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.profiles, ","), func(t *testing.T) {
+			res, err := Options{Profiles: tt.profiles}.Merge(defaults)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := marshal(t, res.Root); got != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	res, err := Options{Profiles: []string{"production"}}.Merge(defaults, layer(t, "local", `{"@profile:production": {"log": "info"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := res.Explain("log")
+	want := "log = \"info\"\n" +
+		"  set by local.json:1:33 (local, profile production)\n" +
+		"  overrides \"warn\" from defaults.json:3:34 (defaults, profile production)\n" +
+		"  overrides \"debug\" from defaults.json:2:10 (defaults)\n"
+	if got != want {
+		t.Errorf("Explain() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMerge_ProfileError(t *testing.T) {
+	_, err := Options{Profiles: []string{"prod"}}.Merge(layer(t, "a", `{"@profile:prod": 1}`))
+	if err == nil || !strings.Contains(err.Error(), "profile block @profile:prod must be an object") {
+		t.Errorf("Merge() error = %v", err)
+	}
+}
+
+func TestMerge_Final(t *testing.T) {
+	tests := []struct {
+		name  string
+		over  string
+		want  string
+		diags []string
+	}{
+		{"other keys", `{"tls": {"cert": "b.pem"}, "port": 81}`,
+			`{"tls":{"verify":true,"cert":"b.pem"},"port":81}`, nil},
+		{"final key", `{"tls": {"verify": false}}`,
+			`{"tls":{"verify":true,"cert":"a.pem"},"port":80}`,
+			[]string{"over.json:1:20: error: tls.verify: tls.verify is final and cannot be overridden by over [TC4001 final-key]"}},
+		{"replace parent", `{"tls": null}`,
+			`{"tls":{"verify":true,"cert":"a.pem"},"port":80}`,
+			[]string{"over.json:1:9: error: tls: tls.verify is final and cannot be overridden by over [TC4001 final-key]"}},
+		{"unset", `{"tls": {"verify": "!unset"}}`,
+			`{"tls":{"verify":true,"cert":"a.pem"},"port":80}`,
+			[]string{"over.json:1:20: error: tls.verify: tls.verify is final and cannot be overridden by over [TC4001 final-key]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Merge(
+				layer(t, "base", `{"tls": {"verify": true, "cert": "a.pem"}, "port": 80, "@final": ["tls.verify"]}`),
+				layer(t, "over", tt.over),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := marshal(t, res.Root); got != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+			var diags []string
+			for _, d := range res.Diagnostics {
+				diags = append(diags, d.String())
+			}
+			if strings.Join(diags, "\n") != strings.Join(tt.diags, "\n") {
+				t.Errorf("Diagnostics = %q, want %q", diags, tt.diags)
+			}
+		})
+	}
+}
+
+func TestMerge_FinalRelated(t *testing.T) {
+	res, err := Merge(
+		layer(t, "base", "{\n  \"tls\": {\n    \"verify\": true,\n    \"@final\": [\"verify\"]\n  }\n}"),
+		layer(t, "mid", `{"tls": {"verify": true}}`),
+		layer(t, "prod", `{"tls": {"verify": false}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 2 {
+		t.Fatalf("Diagnostics = %v", res.Diagnostics)
+	}
+	r := res.Diagnostics[1].Render(0, 0)
+	for _, want := range []string{
+		"prod.json:1:20: error: tls.verify: tls.verify is final and cannot be overridden by prod",
+		"base.json:4:16: note: declared final by base",
+		"base.json:3:15: note: final value",
+	} {
+		if !strings.Contains(r, want) {
+			t.Errorf("Render() = %s\nwant %q", r, want)
+		}
+	}
+
+	// a layer may set the keys it declares final itself
+	res, err = Merge(layer(t, "base", `{"a": 1, "a": 2, "@final": ["a"]}`))
+	if err != nil || len(res.Diagnostics) != 0 {
+		t.Errorf("Merge() = %v, %v", res.Diagnostics, err)
+	}
+	if _, err := Merge(layer(t, "base", `{"@final": "a"}`)); err == nil || !strings.Contains(err.Error(), "@final must be an array of paths") {
+		t.Errorf("Merge() error = %v", err)
+	}
+}
+
+func TestMerge_Strict(t *testing.T) {
+	tests := []struct {
+		name  string
+		over  string
+		want  string
+		diags []string
+	}{
+		{"new and equal keys", `{"db": {"host": "db", "pool": 5}, "port": 80}`,
+			`{"db":{"host":"db","pool":5},"port":80}`, nil},
+		{"conflict", `{"db": {"host": "replica"}, "port": 81}`,
+			`{"db":{"host":"db"},"port":80}`,
+			[]string{
+				`team-b.json:1:17: error: db.host: "replica" from team-b conflicts with "db" from team-a, list host in @override to override it [TC4003 merge-conflict]`,
+				`team-b.json:1:37: error: port: 81 from team-b conflicts with 80 from team-a, list port in @override to override it [TC4003 merge-conflict]`,
+			}},
+		{"override", `{"db": {"host": "replica"}, "port": 81, "@override": ["db", "port"]}`,
+			`{"db":{"host":"replica"},"port":81}`, nil},
+		{"nested override", `{"db": {"host": "replica", "@override": ["host"]}}`,
+			`{"db":{"host":"replica"},"port":80}`, nil},
+		{"unset", `{"port": "!unset"}`,
+			`{"db":{"host":"db"},"port":80}`,
+			[]string{`team-b.json:1:10: error: port: "!unset" from team-b conflicts with 80 from team-a, list port in @override to override it [TC4003 merge-conflict]`}},
+		{"type change", `{"db": "postgres://db"}`,
+			`{"db":{"host":"db"},"port":80}`,
+			[]string{`team-b.json:1:8: error: db: "postgres://db" from team-b conflicts with {"host":"db"} from team-a, list db in @override to override it [TC4003 merge-conflict]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Options{Strict: true}.Merge(
+				layer(t, "team-a", `{"db": {"host": "db"}, "port": 80}`),
+				layer(t, "team-b", tt.over),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := marshal(t, res.Root); got != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+			var diags []string
+			for _, d := range res.Diagnostics {
+				diags = append(diags, d.String())
+			}
+			if strings.Join(diags, "\n") != strings.Join(tt.diags, "\n") {
+				t.Errorf("Diagnostics = %q, want %q", diags, tt.diags)
+			}
+		})
+	}
+}
+
+func TestMerge_StrictRelated(t *testing.T) {
+	res, err := Options{Strict: true, Profiles: []string{"prod"}}.Merge(
+		layer(t, "team-a", "{\n  \"port\": 80\n}"),
+		layer(t, "team-b", `{"port": 81}`),
+		// a layer may override itself in a profile block
+		layer(t, "team-c", `{"timeout": 1, "@profile:prod": {"timeout": 5}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("Diagnostics = %v", res.Diagnostics)
+	}
+	if r := res.Diagnostics[0].Render(0, 0); !strings.Contains(r, "team-a.json:2:11: note: set by team-a") {
+		t.Errorf("Render() = %s", r)
+	}
+	if got := marshal(t, res.Root); got != `{"port":80,"timeout":5}` {
+		t.Errorf("Merge() = %s", got)
+	}
+
+	// without Strict the marker is an ordinary key, whatever its value
+	res, err = Merge(layer(t, "a", `{"port": 80}`), layer(t, "b", `{"port": 81, "@override": ["port"]}`))
+	if err != nil || marshal(t, res.Root) != `{"port":81,"@override":["port"]}` {
+		t.Errorf("Merge() = %v, %v", res, err)
+	}
+	res, err = Merge(layer(t, "a", `{"@override": "x", "b": 1}`))
+	if err != nil || marshal(t, res.Root) != `{"@override":"x","b":1}` {
+		t.Errorf("Merge() = %v, %v", res, err)
+	}
+	if _, err := (Options{Strict: true}).Merge(layer(t, "a", `{"@override": [1]}`)); err == nil || !strings.Contains(err.Error(), "@override must be an array of paths") {
+		t.Errorf("Merge() error = %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package merge combines layered config trees, later layers overriding earlier ones, and records
+// which layer set every value so the merged result can be explained.
+//
+// Nodes of the merged tree are copies of the layer nodes they came from, so positions and file
+// names in errors about merged values point at the file that set them.
+package merge
@@ -0,0 +1,166 @@
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Origin is a layer setting a value.
+type Origin struct {
+	Layer string
+	// Profile is the profile block the value came from, empty outside of profile blocks.
+	Profile string
+	// Node is the value in the layer's own tree, its File and position are where the value was written.
+	Node *slowjson.Node
+	// Unset means the layer removed the value with the unset marker, Node is the marker.
+	Unset bool
+	// Meta is the metadata of the layer.
+	Meta map[string]string
+}
+
+// String formats the origin as "file:line:col (layer)", followed by the profile and the
+// layer metadata when present, e.g. "app.json:3:5 (app.json, profile prod, signer=ci)".
+func (o Origin) String() string {
+	var sb strings.Builder
+	sb.WriteString(o.Node.Location())
+	sb.WriteString(" (")
+	sb.WriteString(o.Layer)
+	if o.Profile != "" {
+		sb.WriteString(", profile ")
+		sb.WriteString(o.Profile)
+	}
+	keys := make([]string, 0, len(o.Meta))
+	for k := range o.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, ", %s=%s", k, o.Meta[k])
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (o Origin) at(n *slowjson.Node) Origin {
+	o.Node = n
+	return o
+}
+
+// Result is a merged tree with the provenance of every value.
+type Result struct {
+	Root *slowjson.Node
+	// Diagnostics reports overrides of final keys, which were dropped.
+	Diagnostics []diag.Diagnostic
+	// history lists the origins of each path, oldest first
+	history map[string][]Origin
+	// children indexes the recorded paths by their parent, so forget only visits the paths below
+	children map[string]map[string]bool
+}
+
+func (r *Result) record(path slowjson.Path, o Origin) {
+	p := path.String()
+	if _, ok := r.history[p]; !ok {
+		r.link(path)
+	}
+	r.history[p] = append(r.history[p], o)
+}
+
+// link adds path and its parents to children, up to the first parent already linked.
+func (r *Result) link(path slowjson.Path) {
+	for ; len(path) > 0; path = path[:len(path)-1] {
+		parent, p := path[:len(path)-1].String(), path.String()
+		if r.children[parent][p] {
+			return
+		}
+		if r.children[parent] == nil {
+			r.children[parent] = map[string]bool{}
+		}
+		r.children[parent][p] = true
+	}
+}
+
+// forget drops the history below path when its value is replaced as a whole.
+func (r *Result) forget(path slowjson.Path) {
+	r.forgetBelow(path.String())
+}
+
+func (r *Result) forgetBelow(p string) {
+	for c := range r.children[p] {
+		delete(r.history, c)
+		r.forgetBelow(c)
+	}
+	delete(r.children, p)
+}
+
+// History returns every layer that set the value at path, oldest first.
+// Layers that merged into an object or keyed array at path are included.
+func (r *Result) History(path string) ([]Origin, error) {
+	p, err := slowjson.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.history[p.String()], nil
+}
+
+// Origin returns the layer that last set the value at path.
+func (r *Result) Origin(path string) (Origin, bool) {
+	h, err := r.History(path)
+	if err != nil || len(h) == 0 {
+		return Origin{}, false
+	}
+	return h[len(h)-1], true
+}
+
+// Explain describes the value at path and the layers that set or unset it, newest first, e.g.
+//
+//	server.port = 8080
+//	  set by prod.json:3:13 (prod)
+//	  overrides 80 from defaults.json:2:13 (defaults)
+func (r *Result) Explain(path string) (string, error) {
+	p, err := slowjson.ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	name := p.String()
+	if name == "" {
+		name = "$"
+	}
+	h := r.history[p.String()]
+	var sb strings.Builder
+	if n := r.Root.Lookup(p); n != nil {
+		fmt.Fprintf(&sb, "%s = %s\n", name, value(n))
+	} else if len(h) > 0 && h[len(h)-1].Unset {
+		fmt.Fprintf(&sb, "%s is unset\n", name)
+	} else {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	for i := len(h) - 1; i >= 0; i-- {
+		switch {
+		case i == len(h)-1 && h[i].Unset:
+			fmt.Fprintf(&sb, "  unset by %s\n", h[i])
+		case i == len(h)-1:
+			fmt.Fprintf(&sb, "  set by %s\n", h[i])
+		case h[i].Unset:
+			fmt.Fprintf(&sb, "  overrides unset from %s\n", h[i])
+		default:
+			fmt.Fprintf(&sb, "  overrides %s from %s\n", value(h[i].Node), h[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// value formats a node compactly for explanations.
+func value(n *slowjson.Node) string {
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		return n.Value
+	}
+	if s := string(b); len(s) <= 60 {
+		return s
+	}
+	return string(b[:57]) + "..."
+}
@@ -0,0 +1,113 @@
+package tracedconfig
+
+import (
+	"context"
+	"strings"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Mount wraps a source so its document is placed under path, e.g. a document {"port": 80} mounted
+// at "runtime" sets runtime.port. The source cannot set anything outside path.
+func Mount(src Source, path string) Source {
+	return &mountSource{Source: src, path: path}
+}
+
+type mountSource struct {
+	Source
+	path string
+}
+
+func (s *mountSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	p, err := slowjson.ParsePath(s.path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.Source.Load(ctx)
+	if err != nil || len(p) == 0 {
+		return n, err
+	}
+	root := &slowjson.Node{
+		Type:      slowjson.NodeObject,
+		File:      n.File,
+		StartLine: n.StartLine,
+		StartCol:  n.StartCol,
+	}
+	if err := root.Set(p, n); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (s *mountSource) Metadata() map[string]string               { return metadata(s.Source) }
+func (s *mountSource) Changed(ctx context.Context) (bool, error) { return SourceChanged(ctx, s.Source) }
+
+// Restrict wraps a source so it may only set keys below the given paths, e.g. environment variables
+// limited to "runtime". A key outside them fails the load, so a layer cannot accidentally override
+// an unrelated section.
+func Restrict(src Source, paths ...string) Source {
+	return &restrictSource{Source: src, paths: paths}
+}
+
+type restrictSource struct {
+	Source
+	paths []string
+}
+
+func (s *restrictSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	mounts := make([]slowjson.Path, len(s.paths))
+	for i, path := range s.paths {
+		p, err := slowjson.ParsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		mounts[i] = p
+	}
+	n, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMounts(n, nil, mounts, s.paths); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// checkMounts reports the first key of the object n at path that is outside mounts.
+func checkMounts(n *slowjson.Node, path slowjson.Path, mounts []slowjson.Path, names []string) error {
+	for _, m := range mounts {
+		if path.HasPrefix(m) {
+			return nil
+		}
+	}
+	if n.Type != slowjson.NodeObject {
+		return n.Errorf("outside the mounts %s of the source", strings.Join(names, ", "))
+	}
+	for _, key := range n.Children {
+		p := path.Key(key.Value)
+		inside := false
+		for _, m := range mounts {
+			inside = inside || p.HasPrefix(m) || m.HasPrefix(p)
+		}
+		if !inside || len(key.Children) == 0 {
+			return key.Errorf("outside the mounts %s of the source", strings.Join(names, ", "))
+		}
+		if err := checkMounts(key.Children[0], p, mounts, names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *restrictSource) Metadata() map[string]string { return metadata(s.Source) }
+func (s *restrictSource) Changed(ctx context.Context) (bool, error) {
+	return SourceChanged(ctx, s.Source)
+}
+
+// metadata returns the metadata of src, nil when it has none. Wrapping sources forward it.
+func metadata(src Source) map[string]string {
+	if ms, ok := src.(MetadataSource); ok {
+		return ms.Metadata()
+	}
+	return nil
+}
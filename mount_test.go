@@ -0,0 +1,52 @@
+package tracedconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	c := NewConfig(
+		Bytes("app.json", []byte(`{"runtime": {"workers": 2}, "db": {"host": "db"}}`)),
+		Mount(Bytes("runtime.json", []byte(`{"workers": 8}`)), "runtime"),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("runtime.workers"); got == nil || got.Value != "8" || got.Location() != "runtime.json:1:13" {
+		t.Errorf("runtime.workers = %v", got)
+	}
+	if got := c.Get("db.host"); got == nil || got.Value != "db" {
+		t.Errorf("db.host = %v", got)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantError string
+	}{
+		{"inside", `{"runtime": {"workers": 8}, "log": {"level": "debug"}}`, ""},
+		{"mount itself", `{"runtime": "!unset"}`, ""},
+		{"outside", `{"runtime": {"workers": 8}, "db": {"host": "evil"}}`, "db: outside the mounts runtime, log.level of the source at env:1:29"},
+		{"sibling of nested mount", `{"log": {"format": "json"}}`, "log.format: outside the mounts"},
+		{"scalar above mount", `{"log": "debug"}`, "log: outside the mounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig(Restrict(Bytes("env", []byte(tt.doc)), "runtime", "log.level"))
+			err := c.Load(context.Background())
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
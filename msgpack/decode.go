@@ -0,0 +1,270 @@
+package msgpack
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// timestampExt is the extension type reserved for timestamps by the spec.
+const timestampExt = -1
+
+// Decode decodes a single MessagePack value into a node tree.
+// Binary and extension values become base64 strings, timestamps become RFC3339 strings.
+func Decode(data []byte) (*slowjson.Node, error) {
+	d := &decoder{data: data}
+	n, err := d.decodeValue(0)
+	if err != nil {
+		return n, err
+	}
+	slowjson.SetParents(n)
+	if d.pos != len(d.data) {
+		return n, fmt.Errorf("unexpected trailing data at offset %d", d.pos)
+	}
+	return n, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) decodeValue(depth int) (*slowjson.Node, error) {
+	if depth > slowjson.MaxDepth {
+		return nil, fmt.Errorf("exceeded max depth %d at offset %d", slowjson.MaxDepth, d.pos)
+	}
+	start := d.pos
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	n := &slowjson.Node{StartOffset: start}
+	switch {
+	case b <= 0x7f:
+		d.setNumber(n, strconv.FormatUint(uint64(b), 10))
+	case b >= 0xe0:
+		d.setNumber(n, strconv.FormatInt(int64(int8(b)), 10))
+	case b >= 0x80 && b <= 0x8f:
+		return d.decodeMap(n, int(b&0x0f), depth)
+	case b >= 0x90 && b <= 0x9f:
+		return d.decodeArray(n, int(b&0x0f), depth)
+	case b >= 0xa0 && b <= 0xbf:
+		return d.decodeString(n, int(b&0x1f))
+	default:
+		return d.decodeTyped(n, b, depth)
+	}
+	return n, nil
+}
+
+func (d *decoder) decodeTyped(n *slowjson.Node, b byte, depth int) (*slowjson.Node, error) {
+	switch b {
+	case 0xc0:
+		n.Type, n.Value = slowjson.NodeNull, "null"
+	case 0xc2:
+		n.Type, n.Value = slowjson.NodeBoolean, "false"
+	case 0xc3:
+		n.Type, n.Value = slowjson.NodeBoolean, "true"
+	case 0xc4, 0xc5, 0xc6:
+		size, err := d.readUint(1 << (b - 0xc4))
+		if err != nil {
+			return n, err
+		}
+		raw, err := d.readBytes(int(size))
+		if err != nil {
+			return n, err
+		}
+		n.Type, n.Value = slowjson.NodeString, base64.StdEncoding.EncodeToString(raw)
+	case 0xc7, 0xc8, 0xc9:
+		size, err := d.readUint(1 << (b - 0xc7))
+		if err != nil {
+			return n, err
+		}
+		return d.decodeExt(n, int(size))
+	case 0xca:
+		v, err := d.readUint(4)
+		if err != nil {
+			return n, err
+		}
+		if err := d.setFloat(n, float64(math.Float32frombits(uint32(v))), 32); err != nil {
+			return n, err
+		}
+		return n, nil
+	case 0xcb:
+		v, err := d.readUint(8)
+		if err != nil {
+			return n, err
+		}
+		if err := d.setFloat(n, math.Float64frombits(v), 64); err != nil {
+			return n, err
+		}
+		return n, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.readUint(1 << (b - 0xcc))
+		if err != nil {
+			return n, err
+		}
+		d.setNumber(n, strconv.FormatUint(v, 10))
+		return n, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		v, err := d.readUint(size)
+		if err != nil {
+			return n, err
+		}
+		// sign extend from the encoded width
+		shift := 64 - 8*size
+		d.setNumber(n, strconv.FormatInt(int64(v<<shift)>>shift, 10))
+		return n, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(n, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		size, err := d.readUint(1 << (b - 0xd9))
+		if err != nil {
+			return n, err
+		}
+		return d.decodeString(n, int(size))
+	case 0xdc, 0xdd:
+		size, err := d.readUint(2 << (b - 0xdc))
+		if err != nil {
+			return n, err
+		}
+		return d.decodeArray(n, int(size), depth)
+	case 0xde, 0xdf:
+		size, err := d.readUint(2 << (b - 0xde))
+		if err != nil {
+			return n, err
+		}
+		return d.decodeMap(n, int(size), depth)
+	default:
+		return n, fmt.Errorf("invalid type byte 0x%x at offset %d", b, n.StartOffset)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeMap(n *slowjson.Node, size int, depth int) (*slowjson.Node, error) {
+	n.Type = slowjson.NodeObject
+	n.Children = []*slowjson.Node{}
+	for i := 0; i < size; i++ {
+		key, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		if key.Type != slowjson.NodeString && key.Type != slowjson.NodeNumber {
+			return n, fmt.Errorf("map key must be a string or integer at offset %d", key.StartOffset)
+		}
+		key.Type = slowjson.NodeString
+		value, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		key.Children = []*slowjson.Node{value}
+		n.Children = append(n.Children, key)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeArray(n *slowjson.Node, size int, depth int) (*slowjson.Node, error) {
+	n.Type = slowjson.NodeArray
+	n.Children = []*slowjson.Node{}
+	for i := 0; i < size; i++ {
+		child, err := d.decodeValue(depth + 1)
+		if err != nil {
+			return n, err
+		}
+		n.Children = append(n.Children, child)
+	}
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeString(n *slowjson.Node, size int) (*slowjson.Node, error) {
+	raw, err := d.readBytes(size)
+	if err != nil {
+		return n, err
+	}
+	n.Type, n.Value = slowjson.NodeString, string(raw)
+	n.EndOffset = d.pos
+	return n, nil
+}
+
+func (d *decoder) decodeExt(n *slowjson.Node, size int) (*slowjson.Node, error) {
+	typ, err := d.readByte()
+	if err != nil {
+		return n, err
+	}
+	raw, err := d.readBytes(size)
+	if err != nil {
+		return n, err
+	}
+	n.Type = slowjson.NodeString
+	n.EndOffset = d.pos
+	if int8(typ) != timestampExt {
+		n.Value = base64.StdEncoding.EncodeToString(raw)
+		return n, nil
+	}
+	var t time.Time
+	switch size {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(raw)), 0)
+	case 8:
+		v := binary.BigEndian.Uint64(raw)
+		t = time.Unix(int64(v&0x3ffffffff), int64(v>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(raw[4:])), int64(binary.BigEndian.Uint32(raw[:4])))
+	default:
+		return n, fmt.Errorf("invalid timestamp length %d at offset %d", size, n.StartOffset)
+	}
+	n.Value = t.UTC().Format(time.RFC3339Nano)
+	return n, nil
+}
+
+func (d *decoder) setNumber(n *slowjson.Node, v string) {
+	n.Type, n.Value = slowjson.NodeNumber, v
+	n.EndOffset = d.pos
+}
+
+// setFloat sets n to the float f of the given bit size, NaN and infinities have no JSON number and are rejected.
+func (d *decoder) setFloat(n *slowjson.Node, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return &slowjson.ParseError{Pos: slowjson.Position{Offset: n.StartOffset}, Msg: fmt.Sprintf("non-finite float %v", f)}
+	}
+	d.setNumber(n, strconv.FormatFloat(f, 'g', -1, bitSize))
+	return nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("unexpected end of input at offset %d", d.pos)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readBytes(size int) ([]byte, error) {
+	if size < 0 || size > len(d.data)-d.pos {
+		return nil, fmt.Errorf("unexpected end of input at offset %d, need %d bytes", d.pos, size)
+	}
+	b := d.data[d.pos : d.pos+size]
+	d.pos += size
+	return b, nil
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.readBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
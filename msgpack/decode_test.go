@@ -0,0 +1,97 @@
+package msgpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestDecode(t *testing.T) {
+	// {"server": {"port": 8080, "tls": true}, "tags": ["a", nil]}
+	data := []byte{0x82,
+		0xa6, 's', 'e', 'r', 'v', 'e', 'r', 0x82,
+		0xa4, 'p', 'o', 'r', 't', 0xcd, 0x1f, 0x90,
+		0xa3, 't', 'l', 's', 0xc3,
+		0xa4, 't', 'a', 'g', 's', 0x92, 0xa1, 'a', 0xc0,
+	}
+	n, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if n.Type != slowjson.NodeObject || len(n.Children) != 2 {
+		t.Fatalf("Decode() got type = %v, len = %v", n.Type, len(n.Children))
+	}
+	server := n.Children[0]
+	if server.Value != "server" || server.StartOffset != 1 || server.EndOffset != 8 {
+		t.Errorf("Decode() got key %q [%d, %d)", server.Value, server.StartOffset, server.EndOffset)
+	}
+	port := server.Children[0].Children[0].Children[0]
+	if port.Type != slowjson.NodeNumber || port.Value != "8080" || port.StartOffset != 14 || port.EndOffset != 17 {
+		t.Errorf("Decode() got port %q [%d, %d)", port.Value, port.StartOffset, port.EndOffset)
+	}
+	tags := n.Children[1].Children[0]
+	if tags.Type != slowjson.NodeArray || len(tags.Children) != 2 || tags.Children[1].Type != slowjson.NodeNull {
+		t.Errorf("Decode() got tags %+v", tags)
+	}
+	if n.EndOffset != len(data) {
+		t.Errorf("Decode() got root end = %d, want %d", n.EndOffset, len(data))
+	}
+}
+
+func TestDecode_Scalars(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		wantType slowjson.NodeType
+		wantVal  string
+	}{
+		{"negative fixint", []byte{0xff}, slowjson.NodeNumber, "-1"},
+		{"int16", []byte{0xd1, 0xfc, 0x18}, slowjson.NodeNumber, "-1000"},
+		{"uint64", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, slowjson.NodeNumber, "18446744073709551615"},
+		{"float64", []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, slowjson.NodeNumber, "1.5"},
+		{"false", []byte{0xc2}, slowjson.NodeBoolean, "false"},
+		{"bin8", []byte{0xc4, 0x02, 0x01, 0x02}, slowjson.NodeString, "AQI="},
+		{"str8", []byte{0xd9, 0x02, 'h', 'i'}, slowjson.NodeString, "hi"},
+		{"timestamp32", []byte{0xd6, 0xff, 0x65, 0x53, 0xf1, 0x00}, slowjson.NodeString, "2023-11-14T22:13:20Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Decode(tt.input)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if n.Type != tt.wantType || n.Value != tt.wantVal {
+				t.Errorf("Decode() got %v %q, want %v %q", n.Type, n.Value, tt.wantType, tt.wantVal)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []byte
+		wantError string
+	}{
+		{"empty", []byte{}, "unexpected end of input at offset 0"},
+		{"truncated string", []byte{0xa5, 'a'}, "unexpected end of input at offset 1"},
+		{"truncated map", []byte{0x81, 0xa1, 'a'}, "unexpected end of input at offset 3"},
+		{"invalid key", []byte{0x81, 0x90, 0x01}, "map key must be a string or integer at offset 1"},
+		{"reserved", []byte{0xc1}, "invalid type byte 0xc1 at offset 0"},
+		{"trailing", []byte{0x01, 0x02}, "unexpected trailing data at offset 1"},
+		{"float32 NaN", []byte{0x91, 0xca, 0x7f, 0xc0, 0x00, 0x00}, "non-finite float NaN at offset 1"},
+		{"float64 infinity", []byte{0x81, 0xa1, 'a', 0xcb, 0x7f, 0xf0, 0, 0, 0, 0, 0, 0}, "non-finite float +Inf at offset 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.input)
+			if err == nil {
+				t.Fatal("Decode() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Decode() error = %v, want error containing %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package msgpack decodes MessagePack payloads into slowjson nodes, each spanning the byte offsets of its
+// value so errors can point into the payload.
+//
+// Every value has a JSON counterpart except bin and ext: both become base64 strings, apart from the
+// timestamp extension, which becomes an RFC3339 string. Map keys must be strings or integers.
+package msgpack
@@ -0,0 +1,121 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Object is the content of a stored object.
+type Object struct {
+	Data []byte
+	// Version identifies the content, e.g. the S3 version id, the GCS generation or an ETag.
+	Version string
+}
+
+// Client reads objects, implement it with the S3 or GCS SDK.
+type Client interface {
+	// Get reads the object.
+	Get(ctx context.Context, bucket, key string) (Object, error)
+	// Version returns the current version of the object without reading it, e.g. with HeadObject.
+	Version(ctx context.Context, bucket, key string) (string, error)
+}
+
+// Source loads a JSON config object from a bucket.
+type Source struct {
+	client Client
+	scheme string
+	bucket string
+	key    string
+
+	mu      sync.Mutex
+	version string
+}
+
+// S3 creates a source reading s3://bucket/key.
+func S3(client Client, bucket, key string) *Source {
+	return &Source{client: client, scheme: "s3", bucket: bucket, key: key}
+}
+
+// GCS creates a source reading gs://bucket/key.
+func GCS(client Client, bucket, key string) *Source {
+	return &Source{client: client, scheme: "gs", bucket: bucket, key: key}
+}
+
+// Name returns the object URL, e.g. "s3://bucket/config.json", it is the file of the loaded nodes.
+func (s *Source) Name() string {
+	return fmt.Sprintf("%s://%s/%s", s.scheme, s.bucket, s.key)
+}
+
+// Load reads and parses the object.
+func (s *Source) Load(ctx context.Context) (*slowjson.Node, error) {
+	obj, err := s.client.Get(ctx, s.bucket, s.key)
+	if err != nil {
+		return nil, err
+	}
+	if err := tracedconfig.CheckSize(ctx, s.Name(), len(obj.Data)); err != nil {
+		return nil, err
+	}
+	p := slowjson.NewParser(string(obj.Data))
+	p.File = s.Name()
+	n, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.version = obj.Version
+	s.mu.Unlock()
+	return n, nil
+}
+
+// Metadata records the bucket, key and version of the last loaded object.
+func (s *Source) Metadata() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := map[string]string{"bucket": s.bucket, "key": s.key}
+	if s.version != "" {
+		meta["version"] = s.version
+	}
+	return meta
+}
+
+// Changed reports whether the object has a different version than the last loaded one.
+// It is true before the first Load and for objects without versions.
+func (s *Source) Changed(ctx context.Context) (bool, error) {
+	v, err := s.client.Version(ctx, s.bucket, s.key)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return v == "" || v != s.version, nil
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Interval between version checks, polling is off when zero.
+	Interval time.Duration
+	// Notify triggers a version check, e.g. on an S3 event notification or a Pub/Sub message.
+	Notify <-chan struct{}
+	// OnReload is called after every reload, and for failed version checks, with the error or nil.
+	OnReload func(error)
+}
+
+// Watch reloads c when any of sources has changed, checking on every interval and notification.
+// It is Config.Watch limited to the version checks of sources, and blocks until ctx is done.
+func Watch(ctx context.Context, c *tracedconfig.Config, opts WatchOptions, sources ...*Source) error {
+	detectors := make([]tracedconfig.ChangeDetector, len(sources))
+	for i, s := range sources {
+		detectors[i] = s
+	}
+	return c.Watch(ctx, tracedconfig.WatchOptions{
+		Interval: opts.Interval,
+		Notify:   opts.Notify,
+		OnReload: opts.OnReload,
+		Sources:  detectors,
+	})
+}
@@ -0,0 +1,112 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/at15/tracedconfig"
+)
+
+type fakeClient struct {
+	mu      sync.Mutex
+	objects map[string]Object
+	gets    int
+}
+
+func (c *fakeClient) put(key, data, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[key] = Object{Data: []byte(data), Version: version}
+}
+
+func (c *fakeClient) Get(ctx context.Context, bucket, key string) (Object, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	obj, ok := c.objects[bucket+"/"+key]
+	if !ok {
+		return Object{}, errors.New("no such key")
+	}
+	return obj, nil
+}
+
+func (c *fakeClient) Version(ctx context.Context, bucket, key string) (string, error) {
+	obj, err := c.Get(ctx, bucket, key)
+	return obj.Version, err
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{objects: map[string]Object{}}
+	client.put("conf/app.json", "{\n  \"port\": 80\n}", "v1")
+	client.put("conf/prod.json", `{"host": "example.com"}`, "")
+
+	app := S3(client, "conf", "app.json")
+	c := tracedconfig.NewConfig(app, GCS(client, "conf", "prod.json"))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	o, _ := c.Origin("port")
+	if got, want := o.String(), "s3://conf/app.json:2:11 (s3://conf/app.json, bucket=conf, key=app.json, version=v1)"; got != want {
+		t.Errorf("Origin() = %q, want %q", got, want)
+	}
+	o, _ = c.Origin("host")
+	if got, want := o.String(), "gs://conf/prod.json:1:10 (gs://conf/prod.json, bucket=conf, key=prod.json)"; got != want {
+		t.Errorf("Origin() = %q, want %q", got, want)
+	}
+
+	if changed, err := app.Changed(context.Background()); err != nil || changed {
+		t.Errorf("Changed() = %v, %v, want false", changed, err)
+	}
+	client.put("conf/app.json", `{"port": 81}`, "v2")
+	if changed, err := app.Changed(context.Background()); err != nil || !changed {
+		t.Errorf("Changed() = %v, %v, want true", changed, err)
+	}
+
+	missing := tracedconfig.NewConfig(S3(client, "conf", "missing.json"))
+	if err := missing.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "load s3://conf/missing.json: no such key") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client := &fakeClient{objects: map[string]Object{}}
+	client.put("conf/app.json", `{"port": 80}`, "v1")
+	app := S3(client, "conf", "app.json")
+	// Bytes cannot tell whether it changed, Watch only checks app
+	c := tracedconfig.NewConfig(tracedconfig.Bytes("defaults.json", []byte(`{"port": 1}`)), app)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notify := make(chan struct{})
+	reloaded := make(chan error, 2)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, c, WatchOptions{Notify: notify, OnReload: func(err error) { reloaded <- err }}, app)
+	}()
+
+	// an unchanged version does not reload
+	notify <- struct{}{}
+	client.put("conf/app.json", `{"port": 81}`, "v2")
+	notify <- struct{}{}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after notification")
+	}
+	if got := c.Get("port").Value; got != "81" {
+		t.Errorf("port = %s after reload, want 81", got)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch() = %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package objectstore loads config objects from S3 and GCS buckets. The storage SDK stays with the
+// caller behind the Client interface, and every value records the bucket, key and object version it came from.
+package objectstore
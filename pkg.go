@@ -0,0 +1,6 @@
+// Package tracedconfig decodes position-tracked config nodes into Go values.
+// Errors point at the path, line and column of the value that failed to decode.
+//
+// Config merges several sources, e.g. defaults, a file and the environment, and remembers
+// which source set every value so it can be explained.
+package tracedconfig
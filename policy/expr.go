@@ -0,0 +1,495 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Rule is a boolean expression the config must satisfy.
+//
+// Expressions use CEL-like syntax: key paths (server.port, servers[0].host, labels["app"]),
+// number, string, true, false and null literals, comparison, arithmetic and logical operators,
+// and the functions has(path), get("path-with.odd_keys"), size(x), matches(s, re),
+// startsWith(s, prefix), endsWith(s, suffix) and contains(s, sub).
+// Referencing a missing key is an evaluation error, guard it with has.
+type Rule struct {
+	Name string
+	Expr string
+	// Message describes the violation, Expr is used when empty.
+	Message string
+}
+
+// Policy is a set of compiled rules, it implements Evaluator.
+type Policy struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	expr ast.Expr
+}
+
+var funcs = map[string]int{
+	"has":        1,
+	"get":        1,
+	"size":       1,
+	"matches":    2,
+	"startsWith": 2,
+	"endsWith":   2,
+	"contains":   2,
+}
+
+// Compile parses the rules and checks they only use supported syntax.
+func Compile(rules ...Rule) (*Policy, error) {
+	p := &Policy{}
+	for _, r := range rules {
+		expr, err := parser.ParseExpr(r.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if err := checkSyntax(expr); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		p.rules = append(p.rules, compiledRule{Rule: r, expr: expr})
+	}
+	return p, nil
+}
+
+// MustCompile is like Compile but panics on error.
+func MustCompile(rules ...Rule) *Policy {
+	p, err := Compile(rules...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func checkSyntax(expr ast.Expr) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case nil, *ast.Ident, *ast.BasicLit, *ast.ParenExpr, *ast.SelectorExpr, *ast.IndexExpr:
+		case *ast.UnaryExpr:
+			if n.Op != token.NOT && n.Op != token.SUB {
+				err = fmt.Errorf("col %d: unsupported operator %s", n.Pos(), n.Op)
+			}
+		case *ast.BinaryExpr:
+			switch n.Op {
+			case token.LAND, token.LOR, token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ,
+				token.ADD, token.SUB, token.MUL, token.QUO, token.REM:
+			default:
+				err = fmt.Errorf("col %d: unsupported operator %s", n.OpPos, n.Op)
+			}
+		case *ast.CallExpr:
+			id, ok := n.Fun.(*ast.Ident)
+			if !ok {
+				err = fmt.Errorf("col %d: unsupported call", n.Pos())
+				break
+			}
+			arity, ok := funcs[id.Name]
+			switch {
+			case !ok:
+				err = fmt.Errorf("col %d: unknown function %s", n.Pos(), id.Name)
+			case len(n.Args) != arity:
+				err = fmt.Errorf("col %d: %s takes %d arguments", n.Pos(), id.Name, arity)
+			}
+			// the function name is not a key path
+			for _, arg := range n.Args {
+				if err == nil {
+					err = checkSyntax(arg)
+				}
+			}
+			return false
+		default:
+			err = fmt.Errorf("col %d: unsupported expression", n.Pos())
+		}
+		return err == nil
+	})
+	return err
+}
+
+// Evaluate runs every rule against root. Rules that cannot be evaluated, e.g. because they
+// reference a missing key, are reported in the joined error and do not stop the others.
+func (p *Policy) Evaluate(root *slowjson.Node) ([]Violation, error) {
+	var violations []Violation
+	var errs []error
+	for _, r := range p.rules {
+		ev := &evaluator{root: root, expr: r.Expr}
+		v, err := ev.eval(r.expr)
+		if err == nil {
+			if _, ok := v.(bool); !ok {
+				err = fmt.Errorf("expression is %s, not bool", kindOf(v))
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.Name, err))
+			continue
+		}
+		if !v.(bool) {
+			msg := r.Message
+			if msg == "" {
+				msg = r.Expr
+			}
+			violations = append(violations, Violation{Rule: r.Name, Message: msg, Nodes: ev.refs, Missing: ev.missing})
+		}
+	}
+	return violations, errors.Join(errs...)
+}
+
+// evaluator evaluates one expression and records the config values it references.
+// Values are nil, bool, float64, string or *slowjson.Node for objects and arrays.
+type evaluator struct {
+	root    *slowjson.Node
+	expr    string
+	refs    []*slowjson.Node
+	missing []Missing
+}
+
+func (ev *evaluator) ref(n *slowjson.Node) {
+	for _, r := range ev.refs {
+		if r == n {
+			return
+		}
+	}
+	ev.refs = append(ev.refs, n)
+}
+
+func (ev *evaluator) eval(e ast.Expr) (interface{}, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return ev.eval(e.X)
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT, token.FLOAT:
+			return strconv.ParseFloat(e.Value, 64)
+		case token.STRING:
+			return strconv.Unquote(e.Value)
+		}
+		return nil, fmt.Errorf("unsupported literal %s", e.Value)
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	case *ast.UnaryExpr:
+		x, err := ev.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == token.NOT {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, fmt.Errorf("operator ! on %s", kindOf(x))
+			}
+			return !b, nil
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - on %s", kindOf(x))
+		}
+		return -f, nil
+	case *ast.BinaryExpr:
+		return ev.binary(e)
+	case *ast.CallExpr:
+		if name := e.Fun.(*ast.Ident).Name; name != "get" {
+			return ev.call(name, e.Args)
+		}
+	}
+	n, err := ev.lookup(e)
+	if err != nil {
+		return nil, err
+	}
+	ev.ref(n)
+	return nodeValue(n)
+}
+
+// lookup resolves a key path expression to the value node without recording it.
+func (ev *evaluator) lookup(e ast.Expr) (*slowjson.Node, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return ev.lookup(e.X)
+	case *ast.Ident:
+		return member(ev.root, slowjson.PathSegment{Key: e.Name})
+	case *ast.SelectorExpr:
+		parent, err := ev.lookup(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return member(parent, slowjson.PathSegment{Key: e.Sel.Name})
+	case *ast.IndexExpr:
+		parent, err := ev.lookup(e.X)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := ev.eval(e.Index)
+		if err != nil {
+			return nil, err
+		}
+		switch idx := idx.(type) {
+		case float64:
+			if idx != math.Trunc(idx) || idx < 0 {
+				return nil, fmt.Errorf("invalid index %v", idx)
+			}
+			return member(parent, slowjson.PathSegment{Index: int(idx), IsIndex: true})
+		case string:
+			return member(parent, slowjson.PathSegment{Key: idx})
+		}
+		return nil, fmt.Errorf("index is %s, not number or string", kindOf(idx))
+	case *ast.CallExpr:
+		// only get reaches here, other calls do not produce nodes
+		if id, ok := e.Fun.(*ast.Ident); ok && id.Name == "get" {
+			arg, err := ev.eval(e.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			s, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("get takes a string, not %s", kindOf(arg))
+			}
+			path, err := slowjson.ParsePath(s)
+			if err != nil {
+				return nil, err
+			}
+			n := ev.root
+			for _, seg := range path {
+				if n, err = member(n, seg); err != nil {
+					return nil, err
+				}
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("expression is not a key path")
+}
+
+// closest returns the deepest existing value on the key path e, the root when none exists.
+func (ev *evaluator) closest(e ast.Expr) *slowjson.Node {
+	var parent ast.Expr
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return ev.closest(e.X)
+	case *ast.SelectorExpr:
+		parent = e.X
+	case *ast.IndexExpr:
+		parent = e.X
+	default:
+		return ev.root
+	}
+	if n, err := ev.lookup(parent); err == nil {
+		return n
+	}
+	return ev.closest(parent)
+}
+
+func member(parent *slowjson.Node, seg slowjson.PathSegment) (*slowjson.Node, error) {
+	n := parent.Lookup(slowjson.Path{seg})
+	if n == nil {
+		path := append(parent.Path(), seg)
+		if seg.IsIndex {
+			return nil, fmt.Errorf("no element %s", path)
+		}
+		return nil, fmt.Errorf("no such key %s", path)
+	}
+	return n, nil
+}
+
+func nodeValue(n *slowjson.Node) (interface{}, error) {
+	switch n.Type {
+	case slowjson.NodeString:
+		return n.Value, nil
+	case slowjson.NodeNumber:
+		f, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return nil, n.Errorf("invalid number %s", n.Value)
+		}
+		return f, nil
+	case slowjson.NodeBoolean:
+		return n.Value == "true", nil
+	case slowjson.NodeNull:
+		return nil, nil
+	default:
+		return n, nil
+	}
+}
+
+func (ev *evaluator) binary(e *ast.BinaryExpr) (interface{}, error) {
+	x, err := ev.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+	if e.Op == token.LAND || e.Op == token.LOR {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s on %s", e.Op, kindOf(x))
+		}
+		if (e.Op == token.LAND && !b) || (e.Op == token.LOR && b) {
+			return b, nil
+		}
+		y, err := ev.eval(e.Y)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := y.(bool); !ok {
+			return nil, fmt.Errorf("operator %s on %s", e.Op, kindOf(y))
+		}
+		return y, nil
+	}
+	y, err := ev.eval(e.Y)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case token.EQL:
+		return equal(x, y), nil
+	case token.NEQ:
+		return !equal(x, y), nil
+	}
+	if xs, ok := x.(string); ok {
+		ys, ok := y.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator %s on string and %s", e.Op, kindOf(y))
+		}
+		switch e.Op {
+		case token.ADD:
+			return xs + ys, nil
+		case token.LSS:
+			return xs < ys, nil
+		case token.LEQ:
+			return xs <= ys, nil
+		case token.GTR:
+			return xs > ys, nil
+		case token.GEQ:
+			return xs >= ys, nil
+		}
+		return nil, fmt.Errorf("operator %s on string", e.Op)
+	}
+	xf, xok := x.(float64)
+	yf, yok := y.(float64)
+	if !xok || !yok {
+		return nil, fmt.Errorf("operator %s on %s and %s", e.Op, kindOf(x), kindOf(y))
+	}
+	switch e.Op {
+	case token.LSS:
+		return xf < yf, nil
+	case token.LEQ:
+		return xf <= yf, nil
+	case token.GTR:
+		return xf > yf, nil
+	case token.GEQ:
+		return xf >= yf, nil
+	case token.ADD:
+		return xf + yf, nil
+	case token.SUB:
+		return xf - yf, nil
+	case token.MUL:
+		return xf * yf, nil
+	case token.QUO, token.REM:
+		if yf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if e.Op == token.QUO {
+			return xf / yf, nil
+		}
+		return math.Mod(xf, yf), nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", e.Op)
+}
+
+func equal(x, y interface{}) bool {
+	xn, xok := x.(*slowjson.Node)
+	yn, yok := y.(*slowjson.Node)
+	if xok && yok {
+		xb, xerr := slowjson.Marshal(xn)
+		yb, yerr := slowjson.Marshal(yn)
+		return xerr == nil && yerr == nil && string(xb) == string(yb)
+	}
+	if xok || yok {
+		return false
+	}
+	return x == y
+}
+
+func (ev *evaluator) call(name string, args []ast.Expr) (interface{}, error) {
+	if name == "has" {
+		n, err := ev.lookup(args[0])
+		if err != nil {
+			path := ev.expr[args[0].Pos()-1 : args[0].End()-1]
+			ev.missing = append(ev.missing, Missing{Path: path, Parent: ev.closest(args[0])})
+			return false, nil
+		}
+		ev.ref(n)
+		return true, nil
+	}
+	vals := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	if name == "size" {
+		switch v := vals[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *slowjson.Node:
+			return float64(len(v.Children)), nil
+		}
+		return nil, fmt.Errorf("size of %s", kindOf(vals[0]))
+	}
+	s, sok := vals[0].(string)
+	t, tok := vals[1].(string)
+	if !sok || !tok {
+		return nil, fmt.Errorf("%s takes strings, not %s and %s", name, kindOf(vals[0]), kindOf(vals[1]))
+	}
+	switch name {
+	case "matches":
+		re, err := regexp.Compile(t)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	case "startsWith":
+		return strings.HasPrefix(s, t), nil
+	case "endsWith":
+		return strings.HasSuffix(s, t), nil
+	default:
+		return strings.Contains(s, t), nil
+	}
+}
+
+func kindOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *slowjson.Node:
+		if v.Type == slowjson.NodeArray {
+			return "array"
+		}
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,197 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+const input = `{
+  "server": {"host": "0.0.0.0", "port": 80, "tls": false},
+  "servers": [{"name": "a", "weight": 2}, {"name": "b", "weight": 3}],
+  "labels": {"app-name": "web"},
+  "debug": null
+}`
+
+func parse(t *testing.T) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return n
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	tests := []struct {
+		expr  string
+		pass  bool
+		paths []string
+	}{
+		{`server.port > 1024`, false, []string{"server.port"}},
+		{`server.port == 80 && !server.tls`, true, []string{"server.port", "server.tls"}},
+		{`server.tls || server.port != 80`, false, []string{"server.tls", "server.port"}},
+		{`servers[0].weight + servers[1].weight == 5`, true, []string{"servers[0].weight", "servers[1].weight"}},
+		{`size(servers) <= 1`, false, []string{"servers"}},
+		{`labels["app-name"] == "web"`, true, []string{`labels["app-name"]`}},
+		{`get("labels.app-name") == "web"`, true, []string{`labels["app-name"]`}},
+		{`!has(server.password) || size(server.password) > 8`, true, nil},
+		{`has(server.host) && server.host != "0.0.0.0"`, false, []string{"server.host"}},
+		{`startsWith(server.host, "0.") && endsWith(server.host, ".0") && contains(server.host, ".0.")`, true, []string{"server.host"}},
+		{`matches(servers[1].name, "^[a-z]+$")`, true, []string{"servers[1].name"}},
+		{`debug == null`, true, []string{"debug"}},
+		{`server == server`, true, []string{"server"}},
+		{`-server.port < 0 && server.port % 7 == 3 && server.port / 8 == 10`, true, []string{"server.port"}},
+		{`servers[0] != servers[1]`, true, []string{"servers[0]", "servers[1]"}},
+	}
+	root := parse(t)
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(Rule{Name: "r", Expr: tt.expr})
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			violations, err := p.Evaluate(root)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if tt.pass {
+				if len(violations) != 0 {
+					t.Errorf("Evaluate() = %v, want pass", violations)
+				}
+				return
+			}
+			if len(violations) != 1 {
+				t.Fatalf("Evaluate() = %v, want one violation", violations)
+			}
+			var paths []string
+			for _, n := range violations[0].Nodes {
+				paths = append(paths, n.Path().String())
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.paths, ",") {
+				t.Errorf("referenced %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestPolicy_Violation(t *testing.T) {
+	p := MustCompile(
+		Rule{Name: "min-port", Expr: "server.port > 1024", Message: "port must be above 1024"},
+		Rule{Name: "tls", Expr: "server.tls"},
+	)
+	violations, err := p.Evaluate(parse(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 2 {
+		t.Fatalf("Evaluate() = %v", violations)
+	}
+	if got := violations[0].String(); got != "min-port: port must be above 1024 (server.port at line 2 col 41)" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := violations[1].Message; got != "server.tls" {
+		t.Errorf("Message = %q, want the expression", got)
+	}
+	d := violations[0].Diagnostic(diag.SeverityError)
+	if d.Path != "server.port" || d.Span.StartLine != 2 || !strings.Contains(d.Message, "min-port") {
+		t.Errorf("Diagnostic() = %+v", d)
+	}
+}
+
+func TestPolicy_EvaluateError(t *testing.T) {
+	tests := []struct {
+		expr      string
+		wantError string
+	}{
+		{`server.password == "x"`, "no such key server.password"},
+		{`servers[5].name == "x"`, "no element servers[5]"},
+		{`server.port`, "expression is number, not bool"},
+		{`server.host > 1`, "operator > on string and number"},
+		{`server.port / 0 == 1`, "division by zero"},
+		{`size(server.port) == 1`, "size of number"},
+		{`matches(server.host, "(")`, "error parsing regexp"},
+	}
+	root := parse(t)
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p := MustCompile(Rule{Name: "bad", Expr: tt.expr}, Rule{Name: "ok", Expr: "server.port > 1024"})
+			violations, err := p.Evaluate(root)
+			if err == nil || !strings.Contains(err.Error(), "rule bad: "+tt.wantError) {
+				t.Errorf("Evaluate() error = %v, want %q", err, tt.wantError)
+			}
+			if len(violations) != 1 || violations[0].Rule != "ok" {
+				t.Errorf("other rules should still run, got %v", violations)
+			}
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		expr      string
+		wantError string
+	}{
+		{`server.port >`, "rule r: "},
+		{`server.port & 1`, "unsupported operator &"},
+		{`lower(server.host) == "x"`, "unknown function lower"},
+		{`has(a, b)`, "has takes 1 arguments"},
+		{`func() {}`, "unsupported expression"},
+		{`server.host.lower()`, "unsupported call"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(Rule{Name: "r", Expr: tt.expr})
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestEvaluatorFunc(t *testing.T) {
+	var e Evaluator = EvaluatorFunc(func(root *slowjson.Node) ([]Violation, error) {
+		return []Violation{{Rule: "custom", Message: "always", Nodes: []*slowjson.Node{root}}}, nil
+	})
+	violations, _ := e.Evaluate(parse(t))
+	if got := violations[0].String(); got != "custom: always (line 1 col 1)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestViolation_DiagnosticCrossField(t *testing.T) {
+	p := slowjson.NewParser("{\n  \"tls\": {\n    \"enabled\": true,\n    \"key\": \"k.pem\"\n  },\n  \"port\": 443\n}")
+	p.File = "app.json"
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol := MustCompile(
+		Rule{Name: "tls-cert", Expr: `!tls.enabled || has(tls.cert)`, Message: "tls.cert is required when tls is enabled"},
+		Rule{Name: "tls-port", Expr: `!tls.enabled || port == 443 && has(tls.key)`},
+	)
+	violations, err := pol.Evaluate(root)
+	if err != nil || len(violations) != 1 {
+		t.Fatalf("Evaluate() = %v, %v", violations, err)
+	}
+	v := violations[0]
+	if len(v.Missing) != 1 || v.Missing[0].Path != "tls.cert" || v.Missing[0].Parent.Path().String() != "tls" {
+		t.Errorf("Missing = %+v, want tls.cert below tls", v.Missing)
+	}
+	d := v.Diagnostic(diag.SeverityError)
+	if d.Path != "tls.enabled" || len(d.Related) != 1 {
+		t.Fatalf("Diagnostic() = %+v", d)
+	}
+	want := `app.json:3:16: error: tls.enabled: tls-cert: tls.cert is required when tls is enabled [TC5001 policy]
+app.json:2:10: note: tls.cert is missing
+2:   "tls": {
+            ^ tls.cert is missing
+3:     "enabled": true,
+                  ^ error
+`
+	if got := d.Render(0, 0); !strings.HasPrefix(got, want) {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
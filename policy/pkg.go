@@ -0,0 +1,5 @@
+// Package policy evaluates organization wide rules against a config tree.
+//
+// Evaluator is the extension point for engines such as CEL or Rego. Compile provides a
+// built-in engine for CEL-like boolean expressions, e.g. `server.port > 1024`.
+package policy
@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Evaluator checks a config tree against policy rules.
+// The error is for rules that could not be evaluated, not for rules that failed.
+type Evaluator interface {
+	Evaluate(root *slowjson.Node) ([]Violation, error)
+}
+
+// EvaluatorFunc adapts a function to Evaluator.
+type EvaluatorFunc func(root *slowjson.Node) ([]Violation, error)
+
+// Evaluate calls f(root).
+func (f EvaluatorFunc) Evaluate(root *slowjson.Node) ([]Violation, error) {
+	return f(root)
+}
+
+// Violation is a rule the config does not satisfy.
+type Violation struct {
+	Rule    string
+	Message string
+	// Nodes are the values the rule referenced, in the order they were referenced.
+	Nodes []*slowjson.Node
+	// Missing are the keys the rule checked with has and did not find.
+	Missing []Missing
+}
+
+// Missing is a key path a rule expected, with the closest value that does exist, e.g. the tls object
+// for a missing tls.cert, so diagnostics can point at where the key belongs.
+type Missing struct {
+	Path   string
+	Parent *slowjson.Node
+}
+
+// String formats the violation with the locations of the referenced values,
+// e.g. "min-port: port must be above 1024 (server.port at line 3 col 13)".
+func (v Violation) String() string {
+	var sb strings.Builder
+	sb.WriteString(v.Rule)
+	sb.WriteString(": ")
+	sb.WriteString(v.Message)
+	if len(v.Nodes) > 0 {
+		sb.WriteString(" (")
+		for i, n := range v.Nodes {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if path := n.Path().String(); path != "" {
+				sb.WriteString(path)
+				sb.WriteString(" at ")
+			}
+			sb.WriteString(n.Location())
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// Diagnostic converts the violation to a diagnostic about its first referenced value, the other
+// values and the missing keys are related notes, so a cross-field rule points at every field involved.
+func (v Violation) Diagnostic(severity diag.Severity) diag.Diagnostic {
+	var related []diag.Related
+	for _, n := range v.Nodes {
+		related = append(related, diag.RelatedTo(n, "%s referenced by %s", pathOf(n), v.Rule))
+	}
+	for _, m := range v.Missing {
+		related = append(related, diag.RelatedTo(m.Parent, "%s is missing", m.Path))
+	}
+	if len(related) == 0 {
+		return diag.Diagnostic{Severity: severity, Message: v.Rule + ": " + v.Message, Code: diag.CodePolicy}
+	}
+	d := diag.New(related[0].Node, severity, "%s: %s", v.Rule, v.Message)
+	d.Code = diag.CodePolicy
+	d.Related = related[1:]
+	return d
+}
+
+// pathOf names n in notes, "$" for the root.
+func pathOf(n *slowjson.Node) string {
+	if p := n.Path().String(); p != "" {
+		return p
+	}
+	return "$"
+}
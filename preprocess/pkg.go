@@ -0,0 +1,3 @@
+// Package preprocess renders config files as Go templates before parsing.
+// It keeps a line map from the rendered output back to the template so errors point at the file the user edited.
+package preprocess
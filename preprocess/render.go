@@ -0,0 +1,155 @@
+package preprocess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/sourcemap"
+)
+
+// marker is inserted into template text to record which template line produced the output.
+// It is stripped from the rendered result.
+const marker = "\x00tcline"
+
+// Options configures rendering.
+type Options struct {
+	// Funcs are added on top of the default helpers from Funcs, overriding them on name clash.
+	Funcs template.FuncMap
+}
+
+// Result is the rendered output and the source map back to the template.
+type Result struct {
+	Name   string
+	Output string
+	// Map maps lines of Output to template lines, columns are unknown.
+	Map *sourcemap.Map
+}
+
+// Render executes the template text with data using the default options.
+func Render(name, text string, data interface{}) (*Result, error) {
+	return Options{}.Render(name, text, data)
+}
+
+// Render executes the template text with data, data is the value context available as dot.
+func (o Options) Render(name, text string, data interface{}) (*Result, error) {
+	t := template.New(name).Funcs(Funcs()).Option("missingkey=error")
+	if o.Funcs != nil {
+		t = t.Funcs(o.Funcs)
+	}
+	t, err := t.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			annotate(tmpl.Tree.Root, text)
+		}
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return nil, err
+	}
+	out, lines := strip(sb.String())
+	m := sourcemap.New(name + ".out")
+	for i, line := range lines {
+		m.Add(i+1, 1, sourcemap.Position{File: name, Line: line})
+	}
+	return &Result{Name: name, Output: out, Map: m}, nil
+}
+
+// TemplateLine maps a 1-based line in the rendered output to the 1-based template line that produced it.
+// Lines produced by actions are attributed to the template line the action is on.
+func (r *Result) TemplateLine(line int) int {
+	p, ok := r.Map.Resolve(line, 1)
+	if !ok {
+		return 0
+	}
+	return p.Line
+}
+
+// Parse parses the rendered output as JSON, errors are reported against the template line.
+func (r *Result) Parse() (*slowjson.Node, error) {
+	p := slowjson.NewParser(r.Output)
+	n, err := p.Parse()
+	if err != nil {
+		line, col := p.Pos()
+		return n, sourcemap.Errorf(r.Map, r.Map.Name, line, col, fmt.Errorf("%w (rendered line %d col %d)", err, line, col))
+	}
+	return n, nil
+}
+
+// annotate inserts a marker at the start of every text node and after each newline in it.
+func annotate(node parse.Node, src string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			annotate(child, src)
+		}
+	case *parse.TextNode:
+		line := 1 + strings.Count(src[:min(int(n.Pos), len(src))], "\n")
+		var sb strings.Builder
+		sb.WriteString(lineMarker(line))
+		for _, b := range n.Text {
+			sb.WriteByte(b)
+			if b == '\n' {
+				line++
+				sb.WriteString(lineMarker(line))
+			}
+		}
+		n.Text = []byte(sb.String())
+	case *parse.IfNode:
+		annotate(n.List, src)
+		annotate(n.ElseList, src)
+	case *parse.RangeNode:
+		annotate(n.List, src)
+		annotate(n.ElseList, src)
+	case *parse.WithNode:
+		annotate(n.List, src)
+		annotate(n.ElseList, src)
+	}
+}
+
+func lineMarker(line int) string {
+	return marker + strconv.Itoa(line) + "\x00"
+}
+
+// strip removes markers from out and returns the template line for each output line.
+func strip(out string) (string, []int) {
+	var (
+		sb        strings.Builder
+		lines     []int
+		current   = 1
+		lineStart = true
+	)
+	for i := 0; i < len(out); {
+		if strings.HasPrefix(out[i:], marker) {
+			if end := strings.IndexByte(out[i+len(marker):], 0); end >= 0 {
+				if line, err := strconv.Atoi(out[i+len(marker) : i+len(marker)+end]); err == nil {
+					current = line
+				}
+				i += len(marker) + end + 1
+				continue
+			}
+		}
+		if lineStart {
+			lines = append(lines, current)
+			lineStart = false
+		}
+		sb.WriteByte(out[i])
+		if out[i] == '\n' {
+			lineStart = true
+		}
+		i++
+	}
+	if lineStart {
+		lines = append(lines, current)
+	}
+	return sb.String(), lines
+}
@@ -0,0 +1,101 @@
+package preprocess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	text := `{
+{{- range $i, $s := .Servers}}
+  "{{$s}}": {{$i}},
+{{- end}}
+  "env": {{quote (default "dev" .Env)}},
+  "tags": {{toJson .Tags}}
+}`
+	data := map[string]interface{}{
+		"Servers": []string{"a", "b"},
+		"Env":     "",
+		"Tags":    []string{"x"},
+	}
+	r, err := Render("config.json.tmpl", text, data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `{
+  "a": 0,
+  "b": 1,
+  "env": "dev",
+  "tags": ["x"]
+}`
+	if r.Output != want {
+		t.Fatalf("Render() got output\n%s\nwant\n%s", r.Output, want)
+	}
+	n, err := r.Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(n.Children) != 4 {
+		t.Errorf("Parse() got %d keys, want 4", len(n.Children))
+	}
+	// Both range iterations come from template line 3.
+	for line, want := range map[int]int{1: 1, 2: 3, 3: 3, 4: 5, 5: 6, 6: 7} {
+		if got := r.TemplateLine(line); got != want {
+			t.Errorf("TemplateLine(%d) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestResult_Parse_Error(t *testing.T) {
+	text := `{
+  "a": 1,
+{{- if .Broken}}
+  "b": tru
+{{- end}}
+}`
+	r, err := Render("bad.tmpl", text, map[string]interface{}{"Broken": true})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	_, err = r.Parse()
+	if err == nil {
+		t.Fatal("Parse() expected error, got nil")
+	}
+	if !strings.HasPrefix(err.Error(), "bad.tmpl:4: invalid boolean") {
+		t.Errorf("Parse() error = %v, want error at bad.tmpl:4", err)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantError string
+	}{
+		{"parse error", "{{ .A", "unclosed action"},
+		{"missing key", `{"a": {{ .Missing }}}`, "map has no entry for key"},
+		{"required", `{"a": {{ required "a is required" .A }}}`, "a is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render("t", tt.text, map[string]interface{}{"A": ""})
+			if err == nil {
+				t.Fatal("Render() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Render() error = %v, want error containing %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestOptions_Funcs(t *testing.T) {
+	o := Options{Funcs: map[string]interface{}{"port": func() int { return 8080 }}}
+	r, err := o.Render("t", `{"port": {{port}}}`, nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if r.Output != `{"port": 8080}` {
+		t.Errorf("Render() got %s", r.Output)
+	}
+}
@@ -0,0 +1,280 @@
+package properties
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Parse parses a .properties file into an object of its keys. file is recorded as the file of the
+// nodes. The nodes of keys and values continued over several lines start where they are written on the
+// first line and end on the last one.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{src: string(data), file: file, line: 1, col: 1}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root := p.span(slowjson.NodeObject, "", p.mark(), p.mark())
+	keys := map[string]int{}
+	for {
+		text, at, ok := p.logicalLine()
+		if !ok {
+			break
+		}
+		key, err := p.entry(text, at)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := keys[key.Value]; ok {
+			root.Children[i] = key
+			continue
+		}
+		keys[key.Value] = len(root.Children)
+		root.Children = append(root.Children, key)
+	}
+	end := p.mark()
+	root.EndLine, root.EndCol, root.EndOffset = end.line, end.col, end.off
+	slowjson.SetParents(root)
+	return root, nil
+}
+
+type parser struct {
+	src  string
+	file string
+
+	off, line, col int
+}
+
+type mark struct {
+	off, line, col int
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) span(typ slowjson.NodeType, value string, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+// skipSpace consumes the whitespace at the start of a line.
+func (p *parser) skipSpace() {
+	for isSpace(p.peek()) {
+		p.next()
+	}
+}
+
+// logicalLine reads the next line holding an entry, joined with the lines it continues on, skipping
+// blank lines and comments. at holds the position of every byte of text and, last, of its end.
+func (p *parser) logicalLine() (text string, at []mark, ok bool) {
+	for {
+		p.skipSpace()
+		switch c := p.peek(); {
+		case p.eof():
+			return "", nil, false
+		case c == '\n' || c == '\r':
+			p.next()
+			continue
+		case c == '#' || c == '!':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+			continue
+		}
+		break
+	}
+	var b strings.Builder
+	for {
+		start := p.off
+		for !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			// the bytes of a rune share its position
+			_, size := utf8.DecodeRuneInString(p.src[p.off:])
+			for i := 0; i < size; i++ {
+				at = append(at, p.mark())
+			}
+			b.WriteString(p.src[p.off : p.off+size])
+			p.next()
+		}
+		line := p.src[start:p.off]
+		backslashes := len(line) - len(strings.TrimRight(line, `\`))
+		if backslashes%2 == 0 {
+			break
+		}
+		// drop the backslash and continue after the whitespace of the next line
+		text := b.String()
+		b.Reset()
+		b.WriteString(text[:len(text)-1])
+		at = at[:len(at)-1]
+		if p.peek() == '\r' {
+			p.next()
+		}
+		p.next()
+		p.skipSpace()
+	}
+	at = append(at, p.mark())
+	return b.String(), at, true
+}
+
+// entry parses the logical line text into a key node with its value.
+func (p *parser) entry(text string, at []mark) (*slowjson.Node, error) {
+	end := 0
+	for end < len(text) && !isSpace(text[end]) && text[end] != '=' && text[end] != ':' {
+		if text[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	end = min(end, len(text))
+	name, err := p.unescape(text[:end], at)
+	if err != nil {
+		return nil, err
+	}
+	key := p.span(slowjson.NodeString, name, at[0], at[end])
+	v := end
+	for v < len(text) && isSpace(text[v]) {
+		v++
+	}
+	if v < len(text) && (text[v] == '=' || text[v] == ':') {
+		v++
+		for v < len(text) && isSpace(text[v]) {
+			v++
+		}
+	}
+	value, err := p.unescape(text[v:], at[v:])
+	if err != nil {
+		return nil, err
+	}
+	key.Children = []*slowjson.Node{p.span(slowjson.NodeString, value, at[v], at[len(at)-1])}
+	return key, nil
+}
+
+// unescape decodes the escapes of s, whose bytes are at the positions at.
+func (p *parser) unescape(s string, at []mark) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, err := p.unicode(s, i-1, at)
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+5:], `\u`) {
+				if low, err := p.unicode(s, i+5, at); err == nil && utf16.DecodeRune(r, low) != utf8.RuneError {
+					r = utf16.DecodeRune(r, low)
+					i += 6
+				}
+			}
+			b.WriteRune(r)
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// unicode decodes the \uXXXX escape at byte i of s.
+func (p *parser) unicode(s string, i int, at []mark) (rune, error) {
+	if i+6 > len(s) {
+		return 0, p.errorf(at[i], "malformed \\uXXXX escape, expected 4 hex digits")
+	}
+	n, err := strconv.ParseUint(s[i+2:i+6], 16, 16)
+	if err != nil {
+		return 0, p.errorf(at[i], "malformed \\uXXXX escape, expected 4 hex digits")
+	}
+	return rune(n), nil
+}
+
+// Escape returns s as a value of a .properties file, on one line and reading back as s.
+func Escape(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && strings.TrimLeft(s[:i], " ") == "":
+			// leading spaces would be read as part of the separator
+			b.WriteString(`\ `)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
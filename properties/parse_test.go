@@ -0,0 +1,93 @@
+package properties
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := "# app settings\n" +
+		"! also a comment\n" +
+		"server.port=8080\n" +
+		"server.host : 0.0.0.0\n" +
+		"greeting Hello\\u0020W\\u00f6rld \\uD83D\\uDE00\n" +
+		"fruits = apple, \\\n" +
+		"         banana, \\\r\n" +
+		"    cherry\n" +
+		"path=C:\\\\temp\\\\\n" +
+		"key\\ with\\=sep = v\\tt\n" +
+		"empty=\n" +
+		"  indented   = ünïcode\n" +
+		"server.port = 9090\n" +
+		"last = trailing \\"
+	n, err := Parse([]byte(input), "app.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"server.port":  "9090",
+		"server.host":  "0.0.0.0",
+		"greeting":     "Hello Wörld 😀",
+		"fruits":       "apple, banana, cherry",
+		"path":         `C:\temp\`,
+		"key with=sep": "v\tt",
+		"empty":        "",
+		"indented":     "ünïcode",
+		"last":         "trailing ",
+	}
+	if len(n.Children) != len(want) || n.Children[0].Value != "server.port" || n.Children[1].Value != "server.host" {
+		t.Errorf("Parse() keys = %v", n.Keys())
+	}
+	for _, key := range n.Children {
+		if v := key.Children[0]; v.Type != slowjson.NodeString || v.Value != want[key.Value] {
+			t.Errorf("%s = %q, want %q", key.Value, v.Value, want[key.Value])
+		}
+	}
+
+	fruits := n.Lookup(slowjson.Path{}.Key("fruits"))
+	if fruits.Location() != "app.properties:6:10" || fruits.EndLine != 8 || fruits.EndCol != 11 {
+		t.Errorf("fruits at %s to %d:%d", fruits.Location(), fruits.EndLine, fruits.EndCol)
+	}
+	if port := n.Lookup(slowjson.Path{}.Key("server.port")); port.Location() != "app.properties:13:15" || port.Parent.Location() != "app.properties:13:1" {
+		t.Errorf("server.port at %s", port.Location())
+	}
+	indented := n.Lookup(slowjson.Path{}.Key("indented"))
+	if indented.Location() != "app.properties:12:16" || indented.EndCol != 23 || input[indented.StartOffset:indented.EndOffset] != "ünïcode" {
+		t.Errorf("indented at %s to col %d", indented.Location(), indented.EndCol)
+	}
+	if sep := n.Lookup(slowjson.Path{}.Key("key with=sep")).Parent; sep.Location() != "app.properties:10:1" || sep.EndCol != 15 {
+		t.Errorf("key with=sep at %s to col %d", sep.Location(), sep.EndCol)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		`a = \u00`:           `malformed \uXXXX escape, expected 4 hex digits at f:1:5`,
+		"a = \\\n  x\\u12g4": `malformed \uXXXX escape, expected 4 hex digits at f:2:4`,
+		`\uzzzz = 1`:         `malformed \uXXXX escape, expected 4 hex digits at f:1:1`,
+	} {
+		_, err := Parse([]byte(input), "f")
+		if err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v, want %s", input, err, want)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for _, s := range []string{"plain", "  leading and trailing  ", `C:\temp`, "multi\nline\ttab", "ünïcode = : #", "\x00"} {
+		n, err := Parse([]byte("k="+Escape(s)), "")
+		if err != nil || n.Children[0].Children[0].Value != s {
+			t.Errorf("Parse(Escape(%q)) = %v, %v", s, n, err)
+		}
+	}
+}
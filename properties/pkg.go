@@ -0,0 +1,8 @@
+// Package properties parses Java .properties files, the config of JVM services, into slowjson nodes
+// with the line and column of every key and value.
+//
+// A file becomes an object of its keys, which stay flat: server.port is the key "server.port". The
+// syntax is that of java.util.Properties: keys end at =, : or whitespace, a line ending in a backslash
+// continues on the next one, and \uXXXX and the other escapes are decoded. Files are read as UTF-8, like
+// resource bundles since Java 9. A key given more than once takes the last value, as Java does.
+package properties
@@ -0,0 +1,32 @@
+package properties
+
+import (
+	"context"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FileSource is a config source reading a .properties file.
+type FileSource struct {
+	Path string
+}
+
+// File creates a source reading the .properties file at path.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, s.Path)
+}
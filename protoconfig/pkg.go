@@ -0,0 +1,7 @@
+// Package protoconfig decodes slowjson nodes into protobuf messages using protojson semantics.
+// Unknown fields and enum mismatches are reported with the line and column of the offending node.
+//
+// Only the JSON mapping is supported, the config is written in JSON or any format that loads into
+// nodes, e.g. YAML. The protobuf text format is out of scope: prototext exposes no positions, so its
+// errors could not point at the offending line.
+package protoconfig
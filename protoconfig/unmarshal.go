@@ -0,0 +1,179 @@
+package protoconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// UnmarshalOptions configures how a node is decoded into a message.
+type UnmarshalOptions struct {
+	// DiscardUnknown ignores keys that do not map to a field instead of returning an error.
+	DiscardUnknown bool
+}
+
+// Unmarshal decodes the node into m using the default options.
+func Unmarshal(n *slowjson.Node, m proto.Message) error {
+	return UnmarshalOptions{}.Unmarshal(n, m)
+}
+
+// Unmarshal checks the node against the message descriptor, so errors carry positions,
+// then hands the value to protojson for the actual decoding.
+func (o UnmarshalOptions) Unmarshal(n *slowjson.Node, m proto.Message) error {
+	if n == nil {
+		return fmt.Errorf("nil node")
+	}
+	if err := o.checkMessage(n, m.ProtoReflect().Descriptor()); err != nil {
+		return err
+	}
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		return err
+	}
+	uo := protojson.UnmarshalOptions{DiscardUnknown: o.DiscardUnknown}
+	if err := uo.Unmarshal(b, m); err != nil {
+		return n.Errorf("%w", err)
+	}
+	return nil
+}
+
+func (o UnmarshalOptions) checkMessage(n *slowjson.Node, md protoreflect.MessageDescriptor) error {
+	// Well known types have their own JSON mapping, leave them to protojson.
+	if isWellKnown(md) || n.Type == slowjson.NodeNull {
+		return nil
+	}
+	if n.Type != slowjson.NodeObject {
+		return typeError(n, "object", string(md.FullName()))
+	}
+	fields := md.Fields()
+	for _, key := range n.Children {
+		fd := fields.ByJSONName(key.Value)
+		if fd == nil {
+			fd = fields.ByTextName(key.Value)
+		}
+		if fd == nil {
+			if o.DiscardUnknown {
+				continue
+			}
+			return key.Errorf("unknown field %q in %s", key.Value, md.FullName())
+		}
+		if len(key.Children) == 0 {
+			continue
+		}
+		if err := o.checkField(key.Children[0], fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o UnmarshalOptions) checkField(n *slowjson.Node, fd protoreflect.FieldDescriptor) error {
+	if n.Type == slowjson.NodeNull {
+		return nil
+	}
+	switch {
+	case fd.IsMap():
+		if n.Type != slowjson.NodeObject {
+			return typeError(n, "object", string(fd.FullName()))
+		}
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			if err := o.checkSingular(key.Children[0], fd.MapValue()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fd.IsList():
+		if n.Type != slowjson.NodeArray {
+			return typeError(n, "array", string(fd.FullName()))
+		}
+		for _, child := range n.Children {
+			if err := o.checkSingular(child, fd); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return o.checkSingular(n, fd)
+	}
+}
+
+func (o UnmarshalOptions) checkSingular(n *slowjson.Node, fd protoreflect.FieldDescriptor) error {
+	if n.Type == slowjson.NodeNull {
+		return nil
+	}
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return o.checkMessage(n, fd.Message())
+	case protoreflect.EnumKind:
+		return checkEnum(n, fd.Enum())
+	case protoreflect.BoolKind:
+		if n.Type != slowjson.NodeBoolean {
+			return typeError(n, "boolean", string(fd.FullName()))
+		}
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		if n.Type != slowjson.NodeString {
+			return typeError(n, "string", string(fd.FullName()))
+		}
+	default:
+		// protojson accepts numbers either as literals or quoted strings.
+		if n.Type != slowjson.NodeNumber && n.Type != slowjson.NodeString {
+			return typeError(n, "number", string(fd.FullName()))
+		}
+	}
+	return nil
+}
+
+func checkEnum(n *slowjson.Node, ed protoreflect.EnumDescriptor) error {
+	switch n.Type {
+	case slowjson.NodeNumber:
+		return nil
+	case slowjson.NodeString:
+		if ed.Values().ByName(protoreflect.Name(n.Value)) != nil {
+			return nil
+		}
+		names := make([]string, 0, ed.Values().Len())
+		for i := 0; i < ed.Values().Len(); i++ {
+			names = append(names, string(ed.Values().Get(i).Name()))
+		}
+		return n.Errorf("invalid value %q for enum %s, expected one of %s", n.Value, ed.FullName(), strings.Join(names, ", "))
+	default:
+		return typeError(n, "enum name or number", string(ed.FullName()))
+	}
+}
+
+func typeError(n *slowjson.Node, want string, name string) error {
+	return n.Errorf("expected %s for %s", want, name)
+}
+
+// wellKnown lists messages whose JSON form is not a plain object of their fields.
+var wellKnown = map[protoreflect.FullName]bool{
+	"google.protobuf.Any":         true,
+	"google.protobuf.Duration":    true,
+	"google.protobuf.Timestamp":   true,
+	"google.protobuf.FieldMask":   true,
+	"google.protobuf.Struct":      true,
+	"google.protobuf.Value":       true,
+	"google.protobuf.ListValue":   true,
+	"google.protobuf.Empty":       true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.BytesValue":  true,
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.UInt64Value": true,
+}
+
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return wellKnown[md.FullName()]
+}
@@ -0,0 +1,100 @@
+package protoconfig
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func parse(t *testing.T, input string) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return n
+}
+
+func TestUnmarshal_Success(t *testing.T) {
+	input := `{
+    "name": "Config",
+    "field": [
+        {"name": "port", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT32"},
+        {"name": "host", "number": "2", "type_name": ".Host", "type": 11}
+    ]
+}`
+	var m descriptorpb.DescriptorProto
+	if err := Unmarshal(parse(t, input), &m); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if m.GetName() != "Config" || len(m.GetField()) != 2 {
+		t.Fatalf("Unmarshal() got %v", &m)
+	}
+	if m.GetField()[0].GetType() != descriptorpb.FieldDescriptorProto_TYPE_INT32 {
+		t.Errorf("Unmarshal() got type = %v", m.GetField()[0].GetType())
+	}
+	if m.GetField()[1].GetTypeName() != ".Host" {
+		t.Errorf("Unmarshal() got type_name = %v", m.GetField()[1].GetTypeName())
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantError string
+	}{
+		{
+			name:      "unknown field",
+			input:     "{\n  \"name\": \"a\",\n  \"nmae\": \"b\"\n}",
+			wantError: `unknown field "nmae" in google.protobuf.DescriptorProto at line 3 col 3`,
+		},
+		{
+			name:      "nested unknown field",
+			input:     `{"field": [{"name": "a", "labels": 1}]}`,
+			wantError: `field[0].labels: unknown field "labels" in google.protobuf.FieldDescriptorProto at line 1 col 26`,
+		},
+		{
+			name:      "enum mismatch",
+			input:     `{"field": [{"label": "OPTIONAL"}]}`,
+			wantError: `field[0].label: invalid value "OPTIONAL" for enum google.protobuf.FieldDescriptorProto.Label`,
+		},
+		{
+			name:      "wrong type",
+			input:     `{"name": 1}`,
+			wantError: "expected string for google.protobuf.DescriptorProto.name at line 1 col 10",
+		},
+		{
+			name:      "repeated field not array",
+			input:     `{"field": {}}`,
+			wantError: "expected array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m descriptorpb.DescriptorProto
+			err := Unmarshal(parse(t, tt.input), &m)
+			if err == nil {
+				t.Fatal("Unmarshal() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Unmarshal() error = %v, want error containing %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestUnmarshalOptions_DiscardUnknown(t *testing.T) {
+	var m descriptorpb.DescriptorProto
+	err := UnmarshalOptions{DiscardUnknown: true}.Unmarshal(parse(t, `{"name": "a", "extra": {"x": 1}}`), &m)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if m.GetName() != "a" {
+		t.Errorf("Unmarshal() got name = %v", m.GetName())
+	}
+}
@@ -0,0 +1,41 @@
+package tracedconfig
+
+import (
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// QueryResult is a value of the merged config matched by Query, with the source that set it.
+type QueryResult struct {
+	Path slowjson.Path
+	// Node is the merged value, its File and position are where the source wrote it.
+	Node *slowjson.Node
+	// Origin is the source that last set the value. For a value without a history of its own it is
+	// that of the closest enclosing value that has one, and the zero Origin when none has.
+	Origin merge.Origin
+}
+
+// Query returns the values of the merged config matching the JSONPath expression expr, e.g.
+// servers[*].port or $..timeout, see slowjson.Node.Query, each with the source that set it.
+func (c *Config) Query(expr string) ([]QueryResult, error) {
+	res := c.result()
+	matches, err := res.Root.Query(expr)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]QueryResult, len(matches))
+	for i, m := range matches {
+		c.markUsed(m.Node)
+		results[i] = QueryResult{Path: m.Path, Node: m.Node}
+		for p := m.Path; ; p = p[:len(p)-1] {
+			if o, ok := res.Origin(p.String()); ok {
+				results[i].Origin = o
+				break
+			}
+			if len(p) == 0 {
+				break
+			}
+		}
+	}
+	return results, nil
+}
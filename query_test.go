@@ -0,0 +1,39 @@
+package tracedconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Query(t *testing.T) {
+	c := NewConfig(
+		Bytes("defaults.json", []byte(`{"servers": [{"host": "a", "port": 80}], "db": {"port": 5432}}`)),
+		Bytes("prod.json", []byte("{\n  \"db\": {\"port\": 6432},\n  \"cache\": {\"port\": 6379}\n}")),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.Query("$..port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path.String()+"="+r.Node.Value+" "+r.Origin.String())
+	}
+	want := []string{
+		"servers[0].port=80 defaults.json:1:36 (defaults.json)",
+		"db.port=6432 prod.json:2:18 (prod.json)",
+		"cache.port=6379 prod.json:3:21 (prod.json)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Query() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if results[1].Node.Location() != "prod.json:2:18" {
+		t.Errorf("Query() node at %s", results[1].Node.Location())
+	}
+	if _, err := c.Query("servers[?(@.port)]"); err == nil {
+		t.Error("Query() with a filter succeeded")
+	}
+}
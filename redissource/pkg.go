@@ -0,0 +1,3 @@
+// Package redissource loads config from Redis hashes and keys mapped to config paths, and turns
+// keyspace notifications into reloads. The Redis client stays with the caller behind the Client interface.
+package redissource
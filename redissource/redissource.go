@@ -0,0 +1,136 @@
+package redissource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Client is the part of a Redis client the source uses, implement it with your Redis library.
+type Client interface {
+	// Get returns the string value of key, ok is false when it does not exist.
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+	// HGetAll returns the fields of the hash key, empty when it does not exist.
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	// Subscribe subscribes to channels and returns the names of the channels of received messages.
+	// The channel is closed when ctx is done or the subscription fails.
+	Subscribe(ctx context.Context, channels ...string) (<-chan string, error)
+}
+
+// Mapping maps a Redis key to a config path.
+type Mapping struct {
+	// Key is the Redis key.
+	Key string
+	// Path is where the value goes in the config, the root when empty.
+	Path string
+	// Hash means Key is a hash whose fields are paths below Path, e.g. field "server.port".
+	// Otherwise Key is a string holding a JSON document or a single value.
+	Hash bool
+}
+
+// Source loads config from Redis keys.
+type Source struct {
+	client   Client
+	name     string
+	mappings []Mapping
+}
+
+// New creates a source loading the mapped keys in order, later mappings overriding earlier ones.
+// The nodes of a key are in the file "redis:key", or "redis:key[field]" for hash fields, so provenance
+// records the Redis key of every value.
+func New(client Client, name string, mappings ...Mapping) *Source {
+	return &Source{client: client, name: name, mappings: mappings}
+}
+
+// Name returns the name given to New.
+func (s *Source) Name() string {
+	return s.name
+}
+
+// Load reads the mapped keys, missing keys are skipped.
+func (s *Source) Load(ctx context.Context) (*slowjson.Node, error) {
+	root := &slowjson.Node{Type: slowjson.NodeObject, File: s.name}
+	for _, m := range s.mappings {
+		base, err := slowjson.ParsePath(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("mapping %s: %w", m.Key, err)
+		}
+		if !m.Hash {
+			v, ok, err := s.client.Get(ctx, m.Key)
+			if err != nil {
+				return nil, fmt.Errorf("get %s: %w", m.Key, err)
+			}
+			if !ok {
+				continue
+			}
+			if err := set(root, base, slowjson.ParseValueOrString(v, "redis:"+m.Key)); err != nil {
+				return nil, fmt.Errorf("key %s: %w", m.Key, err)
+			}
+			continue
+		}
+		fields, err := s.client.HGetAll(ctx, m.Key)
+		if err != nil {
+			return nil, fmt.Errorf("hgetall %s: %w", m.Key, err)
+		}
+		names := make([]string, 0, len(fields))
+		for f := range fields {
+			names = append(names, f)
+		}
+		sort.Strings(names)
+		for _, f := range names {
+			path, err := slowjson.ParsePath(f)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: field %w", m.Key, err)
+			}
+			v := slowjson.ParseValueOrString(fields[f], fmt.Sprintf("redis:%s[%s]", m.Key, f))
+			if err := set(root, append(base[:len(base):len(base)], path...), v); err != nil {
+				return nil, fmt.Errorf("key %s: %w", m.Key, err)
+			}
+		}
+	}
+	return root, nil
+}
+
+// set puts v at path, an object at the root is merged into it.
+func set(root *slowjson.Node, path slowjson.Path, v *slowjson.Node) error {
+	if len(path) > 0 {
+		return root.Set(path, v)
+	}
+	if v.Type != slowjson.NodeObject {
+		return v.Errorf("a value mapped to the root must be an object")
+	}
+	for _, key := range v.Children {
+		if err := root.Set(slowjson.Path{{Key: key.Value}}, key.Children[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Notifications subscribes to the keyspace notifications of the mapped keys in database db and
+// returns a channel that receives a value whenever one changes, pass it as Notify to Config.Watch.
+// Notifications that arrive while a previous one is pending are coalesced. The server must have
+// notify-keyspace-events enabled, e.g. "Kgh" for generic, string and hash commands.
+func (s *Source) Notifications(ctx context.Context, db int) (<-chan struct{}, error) {
+	channels := make([]string, 0, len(s.mappings))
+	for _, m := range s.mappings {
+		channels = append(channels, fmt.Sprintf("__keyspace@%d__:%s", db, m.Key))
+	}
+	msgs, err := s.client.Subscribe(ctx, channels...)
+	if err != nil {
+		return nil, err
+	}
+	notify := make(chan struct{}, 1)
+	go func() {
+		defer close(notify)
+		for range msgs {
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return notify, nil
+}
@@ -0,0 +1,129 @@
+package redissource
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/at15/tracedconfig"
+)
+
+type fakeClient struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	subs    []string
+	msgs    chan string
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.strings[key]
+	return v, ok, nil
+}
+
+func (c *fakeClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fields := map[string]string{}
+	for k, v := range c.hashes[key] {
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+func (c *fakeClient) Subscribe(ctx context.Context, channels ...string) (<-chan string, error) {
+	c.subs = channels
+	return c.msgs, nil
+}
+
+func (c *fakeClient) hset(key, field, value string) {
+	c.mu.Lock()
+	c.hashes[key][field] = value
+	c.mu.Unlock()
+	c.msgs <- "__keyspace@0__:" + key
+}
+
+func TestSource(t *testing.T) {
+	client := &fakeClient{
+		strings: map[string]string{"app:defaults": `{"server": {"port": 80}, "debug": false}`, "app:motd": "hello"},
+		hashes:  map[string]map[string]string{"app:settings": {"server.port": "8080", "server.host": "example.com"}},
+		msgs:    make(chan string),
+	}
+	s := New(client, "redis", Mapping{Key: "app:defaults"}, Mapping{Key: "app:settings", Hash: true},
+		Mapping{Key: "app:motd", Path: "ui.motd"}, Mapping{Key: "app:missing", Path: "x"})
+	c := tracedconfig.NewConfig(s)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Server struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"server"`
+		Debug bool `json:"debug"`
+		UI    struct {
+			Motd string `json:"motd"`
+		} `json:"ui"`
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.Host != "example.com" || cfg.UI.Motd != "hello" {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	for path, want := range map[string]string{
+		"server.port": "redis:app:settings[server.port]:1:1",
+		"debug":       "redis:app:defaults:1:35",
+		"ui.motd":     "redis:app:motd:1:1",
+	} {
+		if got := c.Get(path).Location(); got != want {
+			t.Errorf("%s at %s, want %s", path, got, want)
+		}
+	}
+	if c.Get("x") != nil {
+		t.Error("missing keys should be skipped")
+	}
+
+	client.strings["app:bad"] = "[1]"
+	err := tracedconfig.NewConfig(New(client, "redis", Mapping{Key: "app:bad"})).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "key app:bad: a value mapped to the root must be an object") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	client := &fakeClient{
+		hashes: map[string]map[string]string{"flags": {"beta": "false"}},
+		msgs:   make(chan string),
+	}
+	s := New(client, "redis", Mapping{Key: "flags", Path: "flags", Hash: true})
+	c := tracedconfig.NewConfig(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notify, err := s.Notifications(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(client.subs, ","); got != "__keyspace@0__:flags" {
+		t.Errorf("subscribed to %s", got)
+	}
+	reloaded := make(chan error, 1)
+	go c.Watch(ctx, tracedconfig.WatchOptions{Notify: notify, OnReload: func(err error) { reloaded <- err }})
+
+	client.hset("flags", "beta", "true")
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after a keyspace notification")
+	}
+	if got := c.Get("flags.beta"); got == nil || got.Value != "true" {
+		t.Errorf("flags.beta = %v", got)
+	}
+}
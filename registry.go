@@ -0,0 +1,62 @@
+package tracedconfig
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// DecodeFunc decodes a node into a value of the type it is registered for.
+// Returned errors are wrapped with the path and position of the node, so they should not include them.
+type DecodeFunc func(n *slowjson.Node) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]DecodeFunc{}
+)
+
+// RegisterDecoder registers f to decode values of type t, replacing any previous decoder for t.
+// Register a pointer type to receive the pointer, e.g. *regexp.Regexp.
+// It is usually called from an init function.
+func RegisterDecoder(t reflect.Type, f DecodeFunc) {
+	if t == nil || f == nil {
+		panic("tracedconfig: RegisterDecoder with nil type or func")
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[t] = f
+}
+
+func lookupDecoder(t reflect.Type) (DecodeFunc, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	f, ok := decoders[t]
+	return f, ok
+}
+
+// decodeRegistered decodes rv using a registered decoder, it returns false if there is none.
+func decodeRegistered(n *slowjson.Node, rv reflect.Value) (bool, error) {
+	f, ok := lookupDecoder(rv.Type())
+	if !ok {
+		return false, nil
+	}
+	v, err := f(n)
+	if err != nil {
+		return true, n.Errorf("%w", err)
+	}
+	if v == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return true, nil
+	}
+	val := reflect.ValueOf(v)
+	switch {
+	case val.Type().AssignableTo(rv.Type()):
+		rv.Set(val)
+	case val.Type().ConvertibleTo(rv.Type()):
+		rv.Set(val.Convert(rv.Type()))
+	default:
+		return true, n.Errorf("decoder for %s returned %T", rv.Type(), v)
+	}
+	return true, nil
+}
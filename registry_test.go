@@ -0,0 +1,69 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+)
+
+type registryConfig struct {
+	Level  logLevel   `json:"level"`
+	Levels []logLevel `json:"levels"`
+	Bad    badType    `json:"bad"`
+}
+
+type badType struct{}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder(reflect.TypeOf(logLevel(0)), func(n *slowjson.Node) (interface{}, error) {
+		switch n.Value {
+		case "debug":
+			return levelDebug, nil
+		case "info":
+			return int(levelInfo), nil // converted to logLevel
+		default:
+			return nil, fmt.Errorf("unknown log level %q", n.Value)
+		}
+	})
+	RegisterDecoder(reflect.TypeOf(badType{}), func(n *slowjson.Node) (interface{}, error) {
+		return "not a badType", nil
+	})
+	defer func() {
+		decodersMu.Lock()
+		delete(decoders, reflect.TypeOf(logLevel(0)))
+		delete(decoders, reflect.TypeOf(badType{}))
+		decodersMu.Unlock()
+	}()
+
+	var c registryConfig
+	if err := Decode(parse(t, `{"level": "info", "levels": ["debug", "info"]}`), &c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Level != levelInfo || len(c.Levels) != 2 || c.Levels[0] != levelDebug {
+		t.Errorf("Decode() got %+v", c)
+	}
+
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{"{\n  \"levels\": [\"warn\"]\n}", `levels[0]: unknown log level "warn" at line 2 col 14`},
+		{`{"bad": 1}`, "bad: decoder for tracedconfig.badType returned string at line 1 col 9"},
+	}
+	for _, tt := range tests {
+		err := Decode(parse(t, tt.input), &c)
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Decode(%s) error = %v, want error containing %v", tt.input, err, tt.wantError)
+		}
+	}
+}
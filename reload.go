@@ -0,0 +1,135 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// maxReloads is how many loads Config.Reloads keeps.
+const maxReloads = 50
+
+// maxReloadChanges is how many changes a Reload keeps, the others are only counted.
+const maxReloadChanges = 100
+
+// maxSnapshots is how many applied trees Config keeps for Rollback, the current one included.
+const maxSnapshots = 10
+
+// Reload is a Load of a Config.
+type Reload struct {
+	At time.Time
+	// Err is why the load failed, the previous tree was kept then.
+	Err error
+	// Changes are the differences to the previous tree, the first load adds every value. They are
+	// empty for failed loads, including those rejected by BeforeApply. Their nodes are copies without
+	// parents or source text, so the reloads kept do not keep old trees alive.
+	Changes []slowjson.Change
+	// Omitted counts the changes beyond the first 100, which are not kept.
+	Omitted int
+	// Rollback is set for the changes of Config.Rollback rather than a Load.
+	Rollback bool
+}
+
+// Snapshot is a merged tree applied by a Load.
+type Snapshot struct {
+	At   time.Time
+	Root *slowjson.Node
+}
+
+type snapshot struct {
+	at  time.Time
+	res *merge.Result
+}
+
+// Reloads returns the last loads, oldest first.
+func (c *Config) Reloads() []Reload {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	return append([]Reload(nil), c.reloads...)
+}
+
+// recordReload keeps r with the first maxReloadChanges of its changes detached from their trees.
+func (c *Config) recordReload(r Reload) {
+	if len(r.Changes) > maxReloadChanges {
+		r.Omitted = len(r.Changes) - maxReloadChanges
+		r.Changes = r.Changes[:maxReloadChanges]
+	}
+	if r.Changes != nil {
+		changes := make([]slowjson.Change, len(r.Changes))
+		for i, ch := range r.Changes {
+			changes[i] = slowjson.Change{Kind: ch.Kind, Path: ch.Path, Old: detach(ch.Old, nil), New: detach(ch.New, nil)}
+		}
+		r.Changes = changes
+	}
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	c.reloads = append(c.reloads, r)
+	if len(c.reloads) > maxReloads {
+		c.reloads = append([]Reload(nil), c.reloads[len(c.reloads)-maxReloads:]...)
+	}
+}
+
+// Snapshots returns the trees applied by the last loads, oldest first, the last one is current.
+func (c *Config) Snapshots() []Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Snapshot, len(c.snapshots))
+	for i, s := range c.snapshots {
+		out[i] = Snapshot{At: s.at, Root: s.res.Root}
+	}
+	return out
+}
+
+// Rollback restores the tree applied n loads before the current one, e.g. 1 for the previous, so a
+// bad push of a remote source is reverted without a redeploy. The snapshots after it are dropped, so
+// another Rollback goes further back. The next Load applies the sources again, e.g. when Watch sees
+// them change, use BeforeApply to hold changes back meanwhile. Watch counts a source that is not a
+// ChangeDetector as changed on every check, so it undoes the Rollback on its next interval unless
+// BeforeApply rejects the change. It waits for a running Load and is recorded in Reloads.
+func (c *Config) Rollback(n int) error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	c.mu.Lock()
+	if n < 1 || n >= len(c.snapshots) {
+		kept := len(c.snapshots)
+		c.mu.Unlock()
+		return fmt.Errorf("cannot roll back %d snapshots, %d are kept including the current one", n, kept)
+	}
+	cur := c.res
+	target := c.snapshots[len(c.snapshots)-1-n]
+	c.snapshots = c.snapshots[:len(c.snapshots)-n]
+	c.res = target.res
+	c.mu.Unlock()
+	c.recordReload(Reload{At: time.Now(), Changes: slowjson.Diff(cur.Root, target.res.Root), Rollback: true})
+	return nil
+}
+
+// detach copies n and the values below it, dropping the source text and trivia, parent is the parent of
+// the copy.
+func detach(n, parent *slowjson.Node) *slowjson.Node {
+	if n == nil {
+		return nil
+	}
+	c := &slowjson.Node{
+		Type: n.Type, Value: n.Value, Parent: parent, File: n.File,
+		StartLine: n.StartLine, StartCol: n.StartCol, EndLine: n.EndLine, EndCol: n.EndCol,
+		StartOffset: n.StartOffset, EndOffset: n.EndOffset,
+	}
+	if len(n.Children) > 0 {
+		c.Children = make([]*slowjson.Node, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = detach(child, c)
+		}
+	}
+	return c
+}
+
+// recordSnapshot adds the applied res, c.mu must be held.
+func (c *Config) recordSnapshot(at time.Time, res *merge.Result) {
+	c.snapshots = append(c.snapshots, snapshot{at: at, res: res})
+	if len(c.snapshots) > maxSnapshots {
+		c.snapshots = append([]snapshot(nil), c.snapshots[len(c.snapshots)-maxSnapshots:]...)
+	}
+}
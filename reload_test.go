@@ -0,0 +1,283 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestConfig_Reloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"port": 80, "host": "a"}`)
+	c := NewConfig(File(path))
+	ctx := context.Background()
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	write(`{"port": 81, "host": "a"}`)
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	write(`{"port": `)
+	if err := c.Load(ctx); err == nil {
+		t.Fatal("Load() of invalid JSON succeeded")
+	}
+
+	reloads := c.Reloads()
+	if len(reloads) != 3 {
+		t.Fatalf("Reloads() = %d, want 3", len(reloads))
+	}
+	if len(reloads[0].Changes) != 2 || reloads[0].Err != nil {
+		t.Errorf("first reload = %+v, want 2 added values", reloads[0])
+	}
+	if len(reloads[1].Changes) != 1 || reloads[1].Changes[0].String() != "~ port: 80 -> 81" {
+		t.Errorf("second reload changes = %v", reloads[1].Changes)
+	}
+	if reloads[2].Err == nil || reloads[2].Changes != nil {
+		t.Errorf("failed reload = %+v", reloads[2])
+	}
+	if !reloads[0].At.Before(reloads[2].At) && !reloads[0].At.Equal(reloads[2].At) {
+		t.Error("Reloads() are not oldest first")
+	}
+
+	for i := 0; i < maxReloads; i++ {
+		c.Load(ctx)
+	}
+	if got := len(c.Reloads()); got != maxReloads {
+		t.Errorf("Reloads() kept %d, want %d", got, maxReloads)
+	}
+}
+
+func TestConfig_ReloadsBounded(t *testing.T) {
+	src := &gatedSource{}
+	c := NewConfig(src)
+	var b strings.Builder
+	b.WriteString(`{"a": {"b": 1}`)
+	for i := 0; i < maxReloadChanges+20; i++ {
+		fmt.Fprintf(&b, `, "k%d": %d`, i, i)
+	}
+	src.data = b.String() + "}"
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := c.Reloads()[0]
+	if len(r.Changes) != maxReloadChanges || r.Omitted != 21 {
+		t.Fatalf("reload kept %d changes and omitted %d", len(r.Changes), r.Omitted)
+	}
+	// kept changes do not reference the loaded tree
+	a := r.Changes[0].New
+	if a.Parent != nil || a.Source != "" || a.Children[0].Children[0].Parent != a.Children[0] {
+		t.Errorf("change node is not detached: %+v", a)
+	}
+	if r.Changes[0].String() != `+ a: {"b":1}` || a.Children[0].Children[0].Location() != "line 1 col 13" {
+		t.Errorf("change = %s at %s", r.Changes[0], a.Children[0].Children[0].Location())
+	}
+}
+
+func TestConfig_BeforeApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"port": 80}`)
+	c := NewConfig(File(path))
+	var calls []string
+	c.BeforeApply = func(old, new *slowjson.Node, changes []slowjson.Change) error {
+		calls = append(calls, changes[0].String())
+		if new.Get("port").Value == "0" {
+			return errors.New("port 0 needs approval")
+		}
+		return nil
+	}
+	ctx := context.Background()
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	write(`{"port": 0}`)
+	if err := c.Load(ctx); err == nil || err.Error() != "reload rejected: port 0 needs approval" {
+		t.Errorf("Load() error = %v", err)
+	}
+	if got := c.Get("port").Value; got != "80" {
+		t.Errorf("port after rejected reload = %s, want 80", got)
+	}
+	write(`{"port": 81}`)
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// nothing changed, nothing to approve
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"~ port: 80 -> 0", "~ port: 80 -> 81"}; strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("BeforeApply calls = %q, want %q", calls, want)
+	}
+	if r := c.Reloads()[1]; r.Err == nil || r.Changes != nil {
+		t.Errorf("rejected reload = %+v", r)
+	}
+}
+
+// countingSource returns {"n": <count of loads>}.
+type countingSource struct {
+	n atomic.Int64
+}
+
+func (s *countingSource) Name() string { return "counter" }
+
+func (s *countingSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	// give concurrent loads time to overlap
+	time.Sleep(time.Millisecond)
+	return slowjson.NewParser(fmt.Sprintf(`{"n": %d}`, s.n.Add(1))).Parse()
+}
+
+func TestConfig_ConcurrentLoads(t *testing.T) {
+	c := NewConfig(&countingSource{})
+	ctx := context.Background()
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// every approval sees the tree the previous one applied
+	var mu sync.Mutex
+	var olds, news []string
+	c.BeforeApply = func(old, new *slowjson.Node, changes []slowjson.Change) error {
+		mu.Lock()
+		olds, news = append(olds, old.Get("n").Value), append(news, new.Get("n").Value)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Load(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(olds) != 20 || olds[0] != "1" {
+		t.Fatalf("BeforeApply calls = %v -> %v", olds, news)
+	}
+	for i := 1; i < len(olds); i++ {
+		if olds[i] != news[i-1] {
+			t.Fatalf("BeforeApply call %d approved against %s, the applied tree was %s", i, olds[i], news[i-1])
+		}
+	}
+	if got := c.Get("n").Value; got != news[len(news)-1] {
+		t.Errorf("n = %s, want the last approved %s", got, news[len(news)-1])
+	}
+}
+
+func TestConfig_Rollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewConfig(File(path))
+	ctx := context.Background()
+	for _, port := range []string{"80", "81", "81", "82"} {
+		write(`{"port": ` + port + `}`)
+		if err := c.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the load without changes adds no snapshot
+	if got := len(c.Snapshots()); got != 3 {
+		t.Fatalf("Snapshots() = %d, want 3", got)
+	}
+	if err := c.Rollback(3); err == nil || err.Error() != "cannot roll back 3 snapshots, 3 are kept including the current one" {
+		t.Errorf("Rollback(3) error = %v", err)
+	}
+	if err := c.Rollback(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("port").Value; got != "81" {
+		t.Errorf("port after Rollback(1) = %s, want 81", got)
+	}
+	if o, ok := c.Origin("port"); !ok || o.Node.Value != "81" {
+		t.Errorf("Origin(port) = %v, %v", o, ok)
+	}
+	reloads := c.Reloads()
+	if r := reloads[len(reloads)-1]; !r.Rollback || len(r.Changes) != 1 || r.Changes[0].String() != "~ port: 82 -> 81" {
+		t.Errorf("rollback reload = %+v", r)
+	}
+	if err := c.Rollback(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("port").Value; got != "80" {
+		t.Errorf("port after second Rollback(1) = %s, want 80", got)
+	}
+	if err := c.Rollback(1); err == nil {
+		t.Error("Rollback(1) past the oldest snapshot succeeded")
+	}
+}
+
+// gatedSource returns data, blocking Load while gate is set until it is closed.
+type gatedSource struct {
+	data    string
+	gate    chan struct{}
+	started chan struct{}
+}
+
+func (s *gatedSource) Name() string { return "gated" }
+
+func (s *gatedSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	if s.gate != nil {
+		close(s.started)
+		<-s.gate
+	}
+	return slowjson.NewParser(s.data).Parse()
+}
+
+func TestConfig_RollbackWaitsForLoad(t *testing.T) {
+	src := &gatedSource{}
+	c := NewConfig(src)
+	ctx := context.Background()
+	for _, port := range []string{"80", "81"} {
+		src.data = `{"port": ` + port + `}`
+		if err := c.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src.data, src.gate, src.started = `{"port": 82}`, make(chan struct{}), make(chan struct{})
+	loaded := make(chan error)
+	go func() { loaded <- c.Load(ctx) }()
+	<-src.started
+	rolledBack := make(chan error)
+	go func() { rolledBack <- c.Rollback(1) }()
+	select {
+	case err := <-rolledBack:
+		t.Fatalf("Rollback() = %v during a Load", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(src.gate)
+	if err := <-loaded; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-rolledBack; err != nil {
+		t.Fatal(err)
+	}
+	// the rollback undid the load rather than the load overwriting the rollback
+	if got := c.Get("port").Value; got != "81" {
+		t.Errorf("port = %s, want 81", got)
+	}
+}
@@ -0,0 +1,202 @@
+package tracedconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	tmplparse "text/template/parse"
+
+	"github.com/at15/tracedconfig/merge"
+)
+
+// RenderError is a failure to parse or execute a template of Render.
+type RenderError struct {
+	// Template is where the template failed, "file:line:col" or "file:line" for syntax errors.
+	Template string
+	// Action is the failing action, e.g. ".server.port" or "required .db.host", empty for syntax errors.
+	Action string
+	// Path is the config path the action used, empty when it has none or its dot is not the config root,
+	// e.g. inside range.
+	Path string
+	// Origin is where the value at Path was set, nil when it is not set.
+	Origin *merge.Origin
+	Err    error
+}
+
+func (e *RenderError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Template)
+	sb.WriteString(": ")
+	if e.Action != "" {
+		sb.WriteString("<" + e.Action + ">: ")
+	}
+	sb.WriteString(e.Err.Error())
+	switch {
+	case e.Path == "":
+	case e.Origin != nil:
+		fmt.Fprintf(&sb, " (%s is set at %s)", e.Path, e.Origin)
+	default:
+		fmt.Fprintf(&sb, " (%s is not set in the config)", e.Path)
+	}
+	return sb.String()
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Render executes the text/template in templateFile with the merged config of c as dot, to generate
+// derived files such as an nginx.conf or systemd unit. Objects are maps so values are used as
+// {{ .server.port }}, and a key missing from the config is an error rather than "<no value>".
+// Besides the standard functions templates can call
+//
+//	required VALUE   fails when the value is missing, null or empty
+//	json VALUE       the value encoded as JSON, e.g. for a quoted string
+//	origin "a.b"     where the value at the path was set, e.g. for a comment in the output
+//
+// Errors are *RenderError, giving the template position and the origin of the config value involved.
+func Render(templateFile string, c *Config) ([]byte, error) {
+	b, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, err
+	}
+	return RenderTemplate(templateFile, string(b), c)
+}
+
+// RenderTemplate is Render for the template text, name is the file name used in errors.
+func RenderTemplate(name, text string, c *Config) ([]byte, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(renderFuncs(c)).Parse(text)
+	if err != nil {
+		return nil, renderError(err, nil, c)
+	}
+	// numbers stay json.Number so they render as written, e.g. 1000000 rather than 1e+06
+	d := c.decoder()
+	d.UseNumber = true
+	var data interface{}
+	if err := d.Decode(&data); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, renderError(err, relativeNodes(t), c)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderFuncs(c *Config) template.FuncMap {
+	return template.FuncMap{
+		"required": func(v interface{}) (interface{}, error) {
+			if v == nil {
+				return nil, errors.New("required value is missing")
+			}
+			if rv := reflect.ValueOf(v); (rv.Kind() == reflect.String || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.Len() == 0 {
+				return nil, errors.New("required value is empty")
+			}
+			return v, nil
+		},
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"origin": func(path string) (string, error) {
+			o, ok := c.Origin(path)
+			if !ok {
+				return "", fmt.Errorf("%s is not set in the config", path)
+			}
+			return o.Node.Location(), nil
+		},
+	}
+}
+
+var (
+	// templateErrorPattern splits "template: name:line:col: executing "name" at <action>: msg" and
+	// "template: name:line: msg".
+	templateErrorPattern = regexp.MustCompile(`(?s)^template: (.*?):(\d+)(?::(\d+))?: (?:executing ".*?" at <(.*?)>: )?(.*)$`)
+	// fieldPattern finds the first field chain of an action, e.g. ".server.port" or "$.server.port".
+	fieldPattern = regexp.MustCompile(`(?:^|[\s(])(\$?(?:\.[A-Za-z_][A-Za-z0-9_]*)+)`)
+)
+
+// renderError converts an error of text/template, relative holds the locations of nodes whose dot is
+// not the config root.
+func renderError(err error, relative map[string]bool, c *Config) error {
+	m := templateErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	loc := m[1] + ":" + m[2]
+	if m[3] != "" {
+		loc += ":" + m[3]
+	}
+	rerr := &RenderError{Template: loc, Action: m[4], Err: errors.New(m[5])}
+	field := fieldPattern.FindStringSubmatch(rerr.Action)
+	if field == nil || (!strings.HasPrefix(field[1], "$") && relative[loc]) {
+		return rerr
+	}
+	rerr.Path = strings.TrimPrefix(strings.TrimPrefix(field[1], "$"), ".")
+	if o, ok := c.Origin(rerr.Path); ok {
+		rerr.Origin = &o
+	}
+	return rerr
+}
+
+// relativeNodes returns the locations, as "name:line:col", of the nodes of t whose dot is not the config
+// root: those inside range and with and those of other templates than t, which are called with any dot.
+func relativeNodes(t *template.Template) map[string]bool {
+	out := map[string]bool{}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		tree := tmpl.Tree
+		var walk func(n tmplparse.Node, relative bool)
+		walk = func(n tmplparse.Node, relative bool) {
+			if n == nil || reflect.ValueOf(n).IsNil() {
+				return
+			}
+			if relative {
+				loc, _ := tree.ErrorContext(n)
+				out[loc] = true
+			}
+			switch n := n.(type) {
+			case *tmplparse.ListNode:
+				for _, c := range n.Nodes {
+					walk(c, relative)
+				}
+			case *tmplparse.ActionNode:
+				walk(n.Pipe, relative)
+			case *tmplparse.PipeNode:
+				for _, c := range n.Cmds {
+					walk(c, relative)
+				}
+			case *tmplparse.CommandNode:
+				for _, c := range n.Args {
+					walk(c, relative)
+				}
+			case *tmplparse.ChainNode:
+				walk(n.Node, relative)
+			case *tmplparse.IfNode:
+				walk(n.Pipe, relative)
+				walk(n.List, relative)
+				walk(n.ElseList, relative)
+			case *tmplparse.RangeNode:
+				walk(n.Pipe, relative)
+				walk(n.List, true)
+				walk(n.ElseList, relative)
+			case *tmplparse.WithNode:
+				walk(n.Pipe, relative)
+				walk(n.List, true)
+				walk(n.ElseList, relative)
+			case *tmplparse.TemplateNode:
+				walk(n.Pipe, relative)
+			}
+		}
+		walk(tree.Root, tmpl.Name() != t.Name())
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func renderConfig(t *testing.T) *Config {
+	t.Helper()
+	c := NewConfig(
+		Bytes("defaults.json", []byte(`{"server": {"host": "localhost", "port": 80}, "upstreams": [{"addr": "a:1"}, {"name": "b"}]}`)),
+		Bytes("prod.json", []byte("{\n  \"server\": {\"port\": 8080, \"name\": \"\"}\n}")),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRender(t *testing.T) {
+	c := renderConfig(t)
+	path := filepath.Join(t.TempDir(), "nginx.conf.tmpl")
+	tmpl := "# port from {{ origin \"server.port\" }}\nlisten {{ .server.host }}:{{ .server.port }};\nserver_name {{ json .server.host }};\n"
+	if err := os.WriteFile(path, []byte(tmpl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Render(path, c)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "# port from prod.json:2:22\nlisten localhost:8080;\nserver_name \"localhost\";\n"
+	if string(got) != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderTemplate_Numbers(t *testing.T) {
+	c := NewConfig(Bytes("app.json", []byte(`{"max": 1000000, "id": 9007199254740993, "ratio": 1.50}`)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := RenderTemplate("unit.tmpl", "{{ .max }} {{ .id }} {{ .ratio }} {{ json . }}", c)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	want := `1000000 9007199254740993 1.50 {"id":9007199254740993,"max":1000000,"ratio":1.50}`
+	if string(got) != want {
+		t.Errorf("RenderTemplate() = %s, want %s", got, want)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	c := renderConfig(t)
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantPath string
+	}{
+		{
+			name:     "missing key",
+			template: "listen {{ .server.tls }}",
+			want:     `unit.tmpl:1:17: <.server.tls>: map has no entry for key "tls" (server.tls is not set in the config)`,
+			wantPath: "server.tls",
+		},
+		{
+			name:     "required",
+			template: "line one\nname {{ required .server.name }}",
+			want:     `unit.tmpl:2:8: <required .server.name>: error calling required: required value is empty (server.name is set at prod.json:2:36 (prod.json))`,
+			wantPath: "server.name",
+		},
+		{
+			name:     "inside range",
+			template: "{{ range .upstreams }}{{ .addr }}\n{{ end }}",
+			want:     `unit.tmpl:1:25: <.addr>: map has no entry for key "addr"`,
+		},
+		{
+			name:     "root inside range",
+			template: "{{ range .upstreams }}{{ $.server.user }}{{ end }}",
+			want:     `unit.tmpl:1:26: <$.server.user>: map has no entry for key "user" (server.user is not set in the config)`,
+			wantPath: "server.user",
+		},
+		{
+			name:     "syntax",
+			template: "ok\n{{ .server.port ",
+			want:     `unit.tmpl:2: unclosed action`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderTemplate("unit.tmpl", tt.template, c)
+			var rerr *RenderError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("RenderTemplate() error = %v, want *RenderError", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("RenderTemplate() error =\n%s\nwant\n%s", err, tt.want)
+			}
+			if rerr.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", rerr.Path, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// RolloutKey is the root member of a config that stages the rollout of its changes over a fleet, e.g.
+// {"@rollout": {"id": "db-migration", "percent": 10, "instances": ["canary-1"]}}. See StagedRollout.
+const RolloutKey = "@rollout"
+
+// Rollout is the value of RolloutKey.
+type Rollout struct {
+	// ID names the rollout. Instances are selected by a hash of the ID and the instance ID, so every
+	// rollout selects a different part of the fleet and raising Percent only adds instances.
+	ID string `json:"id"`
+	// Percent of the instances apply the change, from 0 to 100.
+	Percent float64 `json:"percent"`
+	// Instances apply the change regardless of Percent, e.g. canary hosts.
+	Instances []string `json:"instances"`
+}
+
+// Includes reports whether the instance applies changes of the rollout.
+func (r Rollout) Includes(instanceID string) bool {
+	for _, id := range r.Instances {
+		if id == instanceID {
+			return true
+		}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(r.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(instanceID))
+	// buckets of a hundredth of a percent
+	return float64(h.Sum64()%10000) < r.Percent*100
+}
+
+// StagedRollout returns a Config.BeforeApply hook for the instance, e.g. its hostname, that applies
+// a reload only when the RolloutKey member of the new config includes the instance. Changes without
+// the member apply everywhere and a percent of 100 completes a rollout. Instances left out keep their
+// config until the rollout reaches them. The first Load is not staged, so a restarted instance starts
+// with the newest config.
+func StagedRollout(instanceID string) func(old, new *slowjson.Node, changes []slowjson.Change) error {
+	return func(old, new *slowjson.Node, changes []slowjson.Change) error {
+		n := new.Lookup(slowjson.Path{{Key: RolloutKey}})
+		if n == nil {
+			return nil
+		}
+		var r Rollout
+		if err := Decode(n, &r); err != nil {
+			return err
+		}
+		if r.Percent < 0 || r.Percent > 100 {
+			return n.Errorf("rollout percent %g is not between 0 and 100", r.Percent)
+		}
+		if !r.Includes(instanceID) {
+			return fmt.Errorf("instance %s is not in the first %g%% of rollout %q set at %s", instanceID, r.Percent, r.ID, n.Location())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRollout_Includes(t *testing.T) {
+	r := Rollout{ID: "r1", Percent: 25}
+	in := 0
+	for i := 0; i < 1000; i++ {
+		if r.Includes(fmt.Sprintf("host-%d", i)) {
+			in++
+		}
+	}
+	if in < 200 || in > 300 {
+		t.Errorf("25%% rollout includes %d of 1000 instances", in)
+	}
+	// raising the percentage only adds instances
+	more := Rollout{ID: "r1", Percent: 50}
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("host-%d", i)
+		if r.Includes(id) && !more.Includes(id) {
+			t.Fatalf("%s left the rollout when it grew", id)
+		}
+	}
+	if (Rollout{Percent: 0, Instances: []string{"canary"}}).Includes("other") {
+		t.Error("0% rollout includes an instance not listed")
+	}
+	if !(Rollout{Percent: 0, Instances: []string{"canary"}}).Includes("canary") {
+		t.Error("listed instance not included")
+	}
+	if !(Rollout{Percent: 100}).Includes("any") {
+		t.Error("100% rollout does not include every instance")
+	}
+}
+
+func TestStagedRollout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"port": 80}`)
+	c := NewConfig(File(path))
+	c.BeforeApply = StagedRollout("web-7")
+	ctx := context.Background()
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	write(`{"port": 81, "@rollout": {"id": "port", "percent": 0, "instances": ["canary-1"]}}`)
+	err := c.Load(ctx)
+	if err == nil || !strings.Contains(err.Error(), `instance web-7 is not in the first 0% of rollout "port" set at `+path+":1:26") {
+		t.Errorf("Load() error = %v", err)
+	}
+	if got := c.Get("port").Value; got != "80" {
+		t.Errorf("port = %s, want 80 while held back", got)
+	}
+
+	write(`{"port": 81, "@rollout": {"id": "port", "percent": 100}}`)
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("port").Value; got != "81" {
+		t.Errorf("port = %s, want 81 after the rollout completed", got)
+	}
+
+	write(`{"port": 82, "@rollout": {"percent": 101}}`)
+	if err := c.Load(ctx); err == nil || !strings.Contains(err.Error(), "rollout percent 101 is not between 0 and 100") {
+		t.Errorf("Load() of invalid rollout error = %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// Association binds a published schema URL to the config files it describes.
+type Association struct {
+	URL   string
+	Files []string // glob patterns like "config/*.yaml"
+}
+
+// VSCodeSettings returns the settings.json fragment that associates the schema with its files.
+// JSON files go to json.schemas and YAML files to yaml.schemas, read by the yaml-language-server extension.
+func VSCodeSettings(as ...Association) ([]byte, error) {
+	type jsonSchema struct {
+		FileMatch []string `json:"fileMatch"`
+		URL       string   `json:"url"`
+	}
+	settings := struct {
+		JSON []jsonSchema        `json:"json.schemas,omitempty"`
+		YAML map[string][]string `json:"yaml.schemas,omitempty"`
+	}{}
+	for _, a := range as {
+		var jsonFiles, yamlFiles []string
+		for _, f := range a.Files {
+			switch strings.ToLower(path.Ext(f)) {
+			case ".yaml", ".yml":
+				yamlFiles = append(yamlFiles, f)
+			default:
+				jsonFiles = append(jsonFiles, f)
+			}
+		}
+		if len(jsonFiles) > 0 {
+			settings.JSON = append(settings.JSON, jsonSchema{FileMatch: jsonFiles, URL: a.URL})
+		}
+		if len(yamlFiles) > 0 {
+			if settings.YAML == nil {
+				settings.YAML = map[string][]string{}
+			}
+			settings.YAML[a.URL] = append(settings.YAML[a.URL], yamlFiles...)
+		}
+	}
+	b, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// YAMLModeline returns the comment that points yaml-language-server at the schema from inside a YAML file.
+func YAMLModeline(url string) string {
+	return "# yaml-language-server: $schema=" + url
+}
@@ -0,0 +1,36 @@
+package schema
+
+import "testing"
+
+func TestVSCodeSettings(t *testing.T) {
+	b, err := VSCodeSettings(Association{URL: "./app.schema.json", Files: []string{"config/*.json", "config/*.yaml", "app.yml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "json.schemas": [
+    {
+      "fileMatch": [
+        "config/*.json"
+      ],
+      "url": "./app.schema.json"
+    }
+  ],
+  "yaml.schemas": {
+    "./app.schema.json": [
+      "config/*.yaml",
+      "app.yml"
+    ]
+  }
+}
+`
+	if string(b) != want {
+		t.Errorf("VSCodeSettings() =\n%s\nwant\n%s", b, want)
+	}
+}
+
+func TestYAMLModeline(t *testing.T) {
+	if got := YAMLModeline("https://example.com/app.json"); got != "# yaml-language-server: $schema=https://example.com/app.json" {
+		t.Errorf("YAMLModeline() = %q", got)
+	}
+}
@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FormatFunc checks a string of a format and returns why it does not match, without repeating the value.
+type FormatFunc func(value string) error
+
+// KeywordFunc checks the value n against the argument of a custom keyword, e.g. "4Gi" for
+// "x-k8s-maxQuantity": "4Gi" in a schema. Errors are reported at n with diag.CodeConstraint, return a
+// *slowjson.ValidationError, e.g. from child.Errorf, to point at another node or set another code.
+type KeywordFunc func(arg json.RawMessage, n *slowjson.Node) error
+
+var (
+	extensionsMu sync.RWMutex
+	keywords     = map[string]KeywordFunc{}
+)
+
+// RegisterFormat registers f to check strings of the named format, replacing a built-in or previously
+// registered one. Validate and Decode, for format tags, use it from then on.
+// It is usually called from an init function.
+func RegisterFormat(name string, f FormatFunc) {
+	if name == "" || f == nil {
+		panic("schema: RegisterFormat with empty name or nil func")
+	}
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	formats[name] = f
+}
+
+// RegisterKeyword registers f to validate values whose schema has the named keyword, replacing any
+// previous func for it. Name it with an "x-" prefix so other JSON Schema tools ignore it. Schemas keep
+// keywords they do not model in Extra when decoded from JSON, so registered keywords work for schema
+// files as well as for schemas built in Go.
+// It is usually called from an init function.
+func RegisterKeyword(name string, f KeywordFunc) {
+	if name == "" || f == nil {
+		panic("schema: RegisterKeyword with empty name or nil func")
+	}
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	keywords[name] = f
+}
+
+func lookupKeyword(name string) (KeywordFunc, bool) {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	f, ok := keywords[name]
+	return f, ok
+}
+
+// validateKeywords runs the registered keywords of s.Extra on n in the order of their names,
+// keywords nothing is registered for are ignored.
+func (s *Schema) validateKeywords(n *slowjson.Node, diags *[]diag.Diagnostic) {
+	names := make([]string, 0, len(s.Extra))
+	for name := range s.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, ok := lookupKeyword(name)
+		if !ok {
+			continue
+		}
+		err := f(s.Extra[name], n)
+		if err == nil {
+			continue
+		}
+		var d diag.Diagnostic
+		var verr *slowjson.ValidationError
+		if errors.As(err, &verr) && verr.Node != nil {
+			d = diag.Errorf(verr.Node, "%v", verr.Err)
+			d.Code = verr.Code
+		} else {
+			d = diag.Errorf(n, "%v", err)
+		}
+		if d.Code == "" {
+			d.Code = diag.CodeConstraint
+		}
+		*diags = append(*diags, d)
+	}
+}
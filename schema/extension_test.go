@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+var quantityPattern = regexp.MustCompile(`^([0-9]+)(Ki|Mi|Gi)?$`)
+
+// quantity parses a small subset of Kubernetes quantities for the tests.
+func quantity(s string) (int64, error) {
+	m := quantityPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, errors.New("expected a quantity like 512Mi")
+	}
+	n, _ := strconv.ParseInt(m[1], 10, 64)
+	shift := map[string]uint{"": 0, "Ki": 10, "Mi": 20, "Gi": 30}[m[2]]
+	return n << shift, nil
+}
+
+func init() {
+	RegisterFormat("test-quantity", func(v string) error {
+		_, err := quantity(v)
+		return err
+	})
+	RegisterKeyword("x-test-maxQuantity", func(arg json.RawMessage, n *slowjson.Node) error {
+		var max string
+		if err := json.Unmarshal(arg, &max); err != nil {
+			return fmt.Errorf("invalid x-test-maxQuantity in the schema: %v", err)
+		}
+		limit, err := quantity(max)
+		v, verr := quantity(n.Value)
+		if err != nil || verr != nil {
+			return nil
+		}
+		if v > limit {
+			return fmt.Errorf("%s is more than %s", n.Value, max)
+		}
+		return nil
+	})
+	RegisterKeyword("x-test-noDefault", func(arg json.RawMessage, n *slowjson.Node) error {
+		for _, key := range n.Children {
+			if key.Value == "default" {
+				verr := key.Errorf("default is reserved")
+				verr.(*slowjson.ValidationError).Code = diag.CodeUnknownKey
+				return verr
+			}
+		}
+		return nil
+	})
+}
+
+func TestRegisterKeyword(t *testing.T) {
+	var s Schema
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"x-test-noDefault": true,
+		"x-other": 1,
+		"properties": {
+			"memory": {"type": "string", "format": "test-quantity", "x-test-maxQuantity": "1Gi"}
+		}
+	}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"valid", `{"memory": "512Mi"}`, nil},
+		{"format", `{"memory": "lots"}`, []string{
+			`1:12: error: memory: "lots" is not a valid test-quantity: expected a quantity like 512Mi [TC2008 invalid-format]`,
+		}},
+		{"keyword", `{"memory": "2Gi"}`, []string{
+			`1:12: error: memory: 2Gi is more than 1Gi [TC2005 constraint]`,
+		}},
+		{"keyword node and code", `{"default": 1}`, []string{
+			`1:2: error: default: default is reserved [TC1003 unknown-key]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, &s, tt.input, tt.want)
+		})
+	}
+}
+
+func TestRegisterFormat_Replace(t *testing.T) {
+	if err := CheckFormat("test-quantity", "1Ki"); err != nil {
+		t.Fatalf("CheckFormat() = %v", err)
+	}
+	RegisterFormat("test-replaced", func(string) error { return errors.New("first") })
+	RegisterFormat("test-replaced", func(string) error { return errors.New("second") })
+	if err := CheckFormat("test-replaced", "x"); err == nil || err.Error() != "second" {
+		t.Errorf("CheckFormat() = %v, want the last registered format", err)
+	}
+}
@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// formats checks the values of the string formats Validate knows, by name of the format keyword.
+// RegisterFormat adds to it.
+var formats = map[string]FormatFunc{
+	"email":     checkEmail,
+	"hostname":  checkHostname,
+	"uri":       checkURI,
+	"ipv4":      func(v string) error { return checkIP(v, "an IPv4", netip.Addr.Is4) },
+	"ipv6":      func(v string) error { return checkIP(v, "an IPv6", netip.Addr.Is6) },
+	"ip":        func(v string) error { return checkIP(v, "an IP", func(netip.Addr) bool { return true }) },
+	"cidr":      checkCIDR,
+	"semver":    checkSemver,
+	"duration":  checkDuration,
+	"date-time": checkDateTime,
+	"regex":     checkRegex,
+}
+
+// CheckFormat checks value against the named string format, e.g. "email" or "ipv4", and returns why it
+// does not match. Formats neither built in nor registered are accepted, like the JSON Schema
+// specification asks.
+func CheckFormat(format, value string) error {
+	extensionsMu.RLock()
+	check, ok := formats[format]
+	extensionsMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return check(value)
+}
+
+func checkEmail(v string) error {
+	addr, err := mail.ParseAddress(v)
+	if err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "mail: "))
+	}
+	if addr.Address != v {
+		return errors.New("expected a bare address like user@example.com")
+	}
+	return nil
+}
+
+// checkHostname follows RFC 1123: dot separated labels of letters, digits and hyphens, up to 63
+// characters each and 253 in total, not starting or ending with a hyphen.
+func checkHostname(v string) error {
+	if v == "" {
+		return errors.New("empty hostname")
+	}
+	if len(v) > 253 {
+		return errors.New("longer than 253 characters")
+	}
+	for _, label := range strings.Split(v, ".") {
+		switch {
+		case label == "":
+			return errors.New("empty label")
+		case len(label) > 63:
+			return fmt.Errorf("label %q is longer than 63 characters", label)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return fmt.Errorf("label %q starts or ends with a hyphen", label)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid character %q", r)
+			}
+		}
+	}
+	return nil
+}
+
+// checkURI accepts absolute URIs, a relative reference like "/path" has no scheme.
+func checkURI(v string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return err
+	}
+	if u.Scheme == "" {
+		return errors.New("missing scheme, e.g. https://")
+	}
+	return nil
+}
+
+func checkIP(v, what string, is func(netip.Addr) bool) error {
+	addr, err := netip.ParseAddr(v)
+	if err != nil || !is(addr) {
+		return fmt.Errorf("expected %s address", what)
+	}
+	return nil
+}
+
+func checkCIDR(v string) error {
+	if _, err := netip.ParsePrefix(v); err != nil {
+		return errors.New("expected an address and prefix length like 10.0.0.0/8")
+	}
+	return nil
+}
+
+// semverPattern is the regular expression suggested by semver.org.
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+func checkSemver(v string) error {
+	if !semverPattern.MatchString(v) {
+		if strings.HasPrefix(v, "v") && semverPattern.MatchString(v[1:]) {
+			return errors.New("expected a version without the leading v")
+		}
+		return errors.New("expected a version like 1.2.3")
+	}
+	return nil
+}
+
+// checkDuration accepts Go durations like "1m30s", which is how time.Duration fields are decoded.
+func checkDuration(v string) error {
+	if _, err := time.ParseDuration(v); err != nil {
+		return errors.New("expected a duration like 1m30s")
+	}
+	return nil
+}
+
+func checkDateTime(v string) error {
+	if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+		return errors.New("expected an RFC 3339 timestamp like 2006-01-02T15:04:05Z")
+	}
+	return nil
+}
+
+func checkRegex(v string) error {
+	if _, err := regexp.Compile(v); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package schema
+
+import "testing"
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		value   string
+		wantErr string
+	}{
+		{"email", "ops@example.com", ""},
+		{"email", "Ops <ops@example.com>", "expected a bare address like user@example.com"},
+		{"email", "ops@", "missing '@' or angle-addr"},
+		{"hostname", "api-1.example.com", ""},
+		{"hostname", "-api.example.com", `label "-api" starts or ends with a hyphen`},
+		{"hostname", "api..example.com", "empty label"},
+		{"hostname", "api_1.example.com", "invalid character '_'"},
+		{"uri", "https://example.com/path?q=1", ""},
+		{"uri", "/path", "missing scheme, e.g. https://"},
+		{"uri", "http://[::1", "missing ']' in host"},
+		{"ipv4", "10.0.0.1", ""},
+		{"ipv4", "::1", "expected an IPv4 address"},
+		{"ipv6", "fe80::1", ""},
+		{"ipv6", "10.0.0.1", "expected an IPv6 address"},
+		{"ip", "256.0.0.1", "expected an IP address"},
+		{"cidr", "10.0.0.0/8", ""},
+		{"cidr", "10.0.0.0", "expected an address and prefix length like 10.0.0.0/8"},
+		{"semver", "1.2.3-rc.1+build.5", ""},
+		{"semver", "v1.2.3", "expected a version without the leading v"},
+		{"semver", "1.2", "expected a version like 1.2.3"},
+		{"duration", "1m30s", ""},
+		{"duration", "90", "expected a duration like 1m30s"},
+		{"date-time", "2024-05-01T10:00:00+02:00", ""},
+		{"date-time", "2024-05-01", "expected an RFC 3339 timestamp like 2006-01-02T15:04:05Z"},
+		{"regex", "^a+$", ""},
+		{"regex", "a(", "missing closing ): `a(`"},
+		{"x-unknown", "anything", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.value, func(t *testing.T) {
+			err := CheckFormat(tt.format, tt.value)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("CheckFormat(%q, %q) = %q, want %q", tt.format, tt.value, got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in upper case in Go names, e.g. "api_url" is APIURL.
+var initialisms = map[string]bool{
+	"acl": true, "api": true, "cpu": true, "db": true, "dns": true, "grpc": true, "html": true,
+	"http": true, "https": true, "id": true, "ip": true, "json": true, "jwt": true, "sql": true,
+	"ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true, "ui": true, "uri": true,
+	"url": true, "uuid": true, "xml": true, "yaml": true,
+}
+
+// GoTypes returns gofmt'ed Go source declaring a struct type name for the object schema s, and one for
+// each nested object with properties, in package pkg. Fields are tagged with their json key and with the
+// required, format and desc tags GenerateSchema reads, so the schema of the types matches s. Objects
+// without properties become maps, "duration" strings time.Duration and untyped values interface{}.
+func GoTypes(s *Schema, pkg, name string) ([]byte, error) {
+	g := &goTypes{names: map[string]bool{}}
+	g.structType(s, name, "")
+	if g.err != nil {
+		return nil, g.err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if g.usesTime {
+		b.WriteString("import \"time\"\n\n")
+	}
+	for _, decl := range g.decls {
+		b.WriteString(decl.String())
+		b.WriteString("\n")
+	}
+	return format.Source([]byte(b.String()))
+}
+
+type goTypes struct {
+	decls    []*strings.Builder
+	names    map[string]bool
+	usesTime bool
+	err      error
+}
+
+// structType declares a struct for s named name, or parent+name when that is taken, and returns it.
+func (g *goTypes) structType(s *Schema, name, parent string) string {
+	if g.names[name] {
+		name = parent + name
+	}
+	for i := 2; g.names[name]; i++ {
+		name = strings.TrimRight(name, "0123456789") + strconv.Itoa(i)
+	}
+	g.names[name] = true
+	decl := &strings.Builder{}
+	g.decls = append(g.decls, decl)
+
+	if s.Description != "" {
+		fmt.Fprintf(decl, "// %s %s\n", name, s.Description)
+	}
+	fmt.Fprintf(decl, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	for _, p := range s.Properties {
+		if strings.ContainsAny(p.Name, ",\"`") {
+			g.err = fmt.Errorf("key %q of %s cannot be a json tag", p.Name, name)
+			return name
+		}
+		field := goName(p.Name)
+		for i := 2; fields[field]; i++ {
+			field = goName(p.Name) + strconv.Itoa(i)
+		}
+		fields[field] = true
+		typ := g.goType(p.Schema, field, name)
+
+		key := p.Name
+		if key == "-" {
+			key = "-,"
+		}
+		tag := fmt.Sprintf("json:%q", key)
+		if s.IsRequired(p.Name) {
+			tag += ` required:"true"`
+		}
+		if p.Schema.Format != "" && typ != "time.Duration" && typ != "[]time.Duration" {
+			tag += fmt.Sprintf(" format:%q", p.Schema.Format)
+		}
+		if p.Schema.Description != "" {
+			tag += fmt.Sprintf(" desc:%q", p.Schema.Description)
+		}
+		fmt.Fprintf(decl, "\t%s %s `%s`\n", field, typ, tag)
+	}
+	decl.WriteString("}\n")
+	return name
+}
+
+// goType returns the Go type of values of s, declaring structs named after hint.
+func (g *goTypes) goType(s *Schema, hint, parent string) string {
+	if s == nil {
+		return "interface{}"
+	}
+	switch s.Type {
+	case "object":
+		if len(s.Properties) > 0 {
+			return g.structType(s, hint, parent)
+		}
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, singular(hint), parent)
+		}
+		return "map[string]interface{}"
+	case "array":
+		return "[]" + g.goType(s.Items, singular(hint), parent)
+	case "string":
+		if s.Format == "duration" {
+			g.usesTime = true
+			return "time.Duration"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	default:
+		return "interface{}"
+	}
+}
+
+// goName converts a config key to an exported Go identifier, e.g. "max_conns" and "max-conns" are
+// MaxConns and "apiKey" is APIKey.
+func goName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])):
+			// a new word in camelCase or after an acronym as in "HTTPServer"
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// singular names the elements of a list, e.g. Server for Servers and Entry for Entries.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name + "Item"
+}
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoTypes(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "listen_addr", Schema: &Schema{Type: "string", Description: "Address to listen on."}},
+			{Name: "apiKey", Schema: &Schema{Type: "string"}},
+			{Name: "timeout", Schema: &Schema{Type: "string", Format: "duration"}},
+			{Name: "admin", Schema: &Schema{Type: "string", Format: "email"}},
+			{Name: "ratio", Schema: &Schema{Type: "number"}},
+			{Name: "labels", Schema: &Schema{Type: "object"}},
+			{Name: "limits", Schema: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}},
+			{Name: "any", Schema: &Schema{}},
+			{Name: "servers", Schema: &Schema{Type: "array", Items: &Schema{
+				Type:       "object",
+				Properties: Properties{{Name: "host", Schema: &Schema{Type: "string"}}, {Name: "tls", Schema: &Schema{Type: "boolean"}}},
+				Required:   []string{"host"},
+			}}},
+			{Name: "server", Schema: &Schema{Type: "object", Properties: Properties{{Name: "port", Schema: &Schema{Type: "integer"}}}}},
+		},
+		Required: []string{"listen_addr"},
+	}
+	b, err := GoTypes(s, "config", "Config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package config\n\nimport \"time\"\n\n" +
+		"type Config struct {\n" +
+		"\tListenAddr string                 `json:\"listen_addr\" required:\"true\" desc:\"Address to listen on.\"`\n" +
+		"\tAPIKey     string                 `json:\"apiKey\"`\n" +
+		"\tTimeout    time.Duration          `json:\"timeout\"`\n" +
+		"\tAdmin      string                 `json:\"admin\" format:\"email\"`\n" +
+		"\tRatio      float64                `json:\"ratio\"`\n" +
+		"\tLabels     map[string]interface{} `json:\"labels\"`\n" +
+		"\tLimits     map[string]int         `json:\"limits\"`\n" +
+		"\tAny        interface{}            `json:\"any\"`\n" +
+		"\tServers    []Server               `json:\"servers\"`\n" +
+		"\tServer     ConfigServer           `json:\"server\"`\n" +
+		"}\n\n" +
+		"type Server struct {\n" +
+		"\tHost string `json:\"host\" required:\"true\"`\n" +
+		"\tTLS  bool   `json:\"tls\"`\n" +
+		"}\n\n" +
+		"type ConfigServer struct {\n" +
+		"\tPort int `json:\"port\"`\n" +
+		"}\n"
+	if string(b) != want {
+		t.Errorf("GoTypes() =\n%s\nwant\n%s", b, want)
+	}
+}
+
+func TestGoTypes_BadKey(t *testing.T) {
+	s := &Schema{Type: "object", Properties: Properties{{Name: "a,b", Schema: &Schema{}}}}
+	if _, err := GoTypes(s, "config", "Config"); err == nil || !strings.Contains(err.Error(), `key "a,b" of Config`) {
+		t.Errorf("GoTypes() error = %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for key, want := range map[string]string{
+		"port":        "Port",
+		"max_conns":   "MaxConns",
+		"max-conns":   "MaxConns",
+		"apiKey":      "APIKey",
+		"HTTPServer":  "HTTPServer",
+		"db.url":      "DBURL",
+		"9lives":      "X9lives",
+		"-":           "X",
+		"user_id":     "UserID",
+		"ServiceName": "ServiceName",
+	} {
+		if got := goName(key); got != want {
+			t.Errorf("goName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
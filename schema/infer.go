@@ -0,0 +1,129 @@
+package schema
+
+import (
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// durationPattern matches Go durations with a unit, so plain numbers in strings are not taken for one.
+var durationPattern = regexp.MustCompile(`^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$`)
+
+// Infer derives a schema from example documents. Objects get the union of the keys seen, in order of
+// first appearance, and with more than one example the keys present in every one are required. Numbers
+// are integers when no example has a fraction, strings that are all Go durations get the duration
+// format, and values seen with different types get no type. Nulls say nothing about the type.
+func Infer(examples ...*slowjson.Node) *Schema {
+	s := &Schema{}
+	inf := inferrer{}
+	for _, n := range examples {
+		inf.add(s, n)
+	}
+	inf.finish(s, len(examples))
+	return s
+}
+
+type inferrer struct {
+	// seen counts the values merged into each schema and presence counts, per object schema, the
+	// objects having each key.
+	seen     map[*Schema]int
+	presence map[*Schema]map[string]int
+	// durations is false for string schemas with a value that is not a duration.
+	durations map[*Schema]bool
+	// mixed marks schemas seen with different types.
+	mixed map[*Schema]bool
+}
+
+// add merges the example value n into s.
+func (inf *inferrer) add(s *Schema, n *slowjson.Node) {
+	if n == nil || n.Type == slowjson.NodeNull {
+		return
+	}
+	if inf.seen == nil {
+		inf.seen = map[*Schema]int{}
+		inf.presence = map[*Schema]map[string]int{}
+		inf.durations = map[*Schema]bool{}
+		inf.mixed = map[*Schema]bool{}
+	}
+	typ := typeName(n)
+	if typ == "number" {
+		if v, ok := new(big.Rat).SetString(n.Value); ok && v.IsInt() {
+			typ = "integer"
+		}
+	}
+	switch {
+	case inf.mixed[s]:
+		return
+	case s.Type == "":
+		s.Type = typ
+	case s.Type == "integer" && typ == "number", s.Type == "number" && typ == "integer":
+		s.Type = "number"
+	case s.Type != typ:
+		// no single type fits, leave the value open
+		*s = Schema{}
+		inf.mixed[s] = true
+		return
+	}
+	inf.seen[s]++
+	switch n.Type {
+	case slowjson.NodeString:
+		if _, err := time.ParseDuration(n.Value); err != nil || !durationPattern.MatchString(n.Value) {
+			inf.durations[s] = false
+		} else if _, ok := inf.durations[s]; !ok {
+			inf.durations[s] = true
+		}
+	case slowjson.NodeArray:
+		if s.Items == nil {
+			s.Items = &Schema{}
+		}
+		for _, elem := range n.Children {
+			inf.add(s.Items, elem)
+		}
+	case slowjson.NodeObject:
+		if inf.presence[s] == nil {
+			inf.presence[s] = map[string]int{}
+		}
+		counted := map[string]bool{}
+		for _, key := range n.Children {
+			if len(key.Children) == 0 {
+				continue
+			}
+			prop := s.Properties.Get(key.Value)
+			if prop == nil {
+				prop = &Schema{}
+				s.Properties = append(s.Properties, Property{Name: key.Value, Schema: prop})
+			}
+			if !counted[key.Value] {
+				counted[key.Value] = true
+				inf.presence[s][key.Value]++
+			}
+			inf.add(prop, key.Children[0])
+		}
+	}
+}
+
+// finish sets the formats and required keys once every example was added, objects count as examples
+// of their schema.
+func (inf *inferrer) finish(s *Schema, examples int) {
+	if s == nil {
+		return
+	}
+	if s.Type == "string" && inf.durations[s] {
+		s.Format = "duration"
+	}
+	if examples > 1 {
+		for _, p := range s.Properties {
+			if inf.presence[s][p.Name] == inf.seen[s] {
+				s.Required = append(s.Required, p.Name)
+			}
+		}
+	}
+	for _, p := range s.Properties {
+		inf.finish(p.Schema, inf.seen[s])
+	}
+	if s.Items != nil {
+		inf.finish(s.Items, inf.seen[s.Items])
+	}
+}
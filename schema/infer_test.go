@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestInfer(t *testing.T) {
+	var examples []*slowjson.Node
+	for _, src := range []string{
+		`{"port": 80, "timeout": "30s", "ratio": 1, "tags": ["a"], "servers": [{"host": "a", "weight": 1}], "extra": null, "mixed": 1}`,
+		`{"port": 81, "timeout": "1m", "ratio": 0.5, "tags": [], "servers": [{"host": "b"}], "debug": true, "mixed": "x"}`,
+	} {
+		n, err := slowjson.NewParser(src).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		examples = append(examples, n)
+	}
+	b, err := Infer(examples...).MarshalIndent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(strings.Fields(string(b)), " ")
+	want := `{ "type": "object", "properties": { ` +
+		`"port": { "type": "integer" }, ` +
+		`"timeout": { "type": "string", "format": "duration" }, ` +
+		`"ratio": { "type": "number" }, ` +
+		`"tags": { "type": "array", "items": { "type": "string" } }, ` +
+		`"servers": { "type": "array", "items": { "type": "object", "properties": { "host": { "type": "string" }, "weight": { "type": "integer" } }, "required": [ "host" ] } }, ` +
+		`"extra": {}, "mixed": {}, "debug": { "type": "boolean" } }, ` +
+		`"required": [ "port", "timeout", "ratio", "tags", "servers", "mixed" ] }`
+	if got != want {
+		t.Errorf("Infer() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInfer_SingleExample(t *testing.T) {
+	n, err := slowjson.NewParser(`{"port": "8080", "hosts": [{"name": "a"}, {"name": "b", "port": 1}]}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Infer(n)
+	if len(s.Required) != 0 {
+		t.Errorf("Required = %v, want none from a single example", s.Required)
+	}
+	if p := s.Properties.Get("port"); p.Type != "string" || p.Format != "" {
+		t.Errorf("port = %+v, want a string without format", p)
+	}
+	// the elements of an array are several examples
+	if items := s.Properties.Get("hosts").Items; len(items.Required) != 1 || items.Required[0] != "name" {
+		t.Errorf("hosts items Required = %v, want [name]", items.Required)
+	}
+}
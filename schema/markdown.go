@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Markdown writes a reference table of every key in the schema, one row per leaf or object.
+// Array elements are written as "key[]" and map values as "key.*".
+func Markdown(w io.Writer, s *Schema) error {
+	var b strings.Builder
+	if s.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", s.Title)
+	}
+	if s.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", s.Description)
+	}
+	b.WriteString("| Key | Type | Default | Required | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	writeRows(&b, "", s)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRows(b *strings.Builder, prefix string, s *Schema) {
+	for _, p := range s.Properties {
+		key := prefix + p.Name
+		writeRow(b, key, p.Schema, s.IsRequired(p.Name))
+		writeChildren(b, key, p.Schema)
+	}
+}
+
+func writeChildren(b *strings.Builder, key string, s *Schema) {
+	switch {
+	case len(s.Properties) > 0:
+		writeRows(b, key+".", s)
+	case s.Items != nil && (len(s.Items.Properties) > 0 || s.Items.Items != nil || s.Items.AdditionalProperties != nil):
+		writeChildren(b, key+"[]", s.Items)
+	case s.AdditionalProperties != nil && len(s.AdditionalProperties.Properties) > 0:
+		writeRows(b, key+".*.", s.AdditionalProperties)
+	}
+}
+
+func writeRow(b *strings.Builder, key string, s *Schema, required bool) {
+	def := ""
+	if s.Default != nil {
+		v, _ := json.Marshal(s.Default)
+		def = "`" + string(v) + "`"
+	}
+	req := ""
+	if required {
+		req = "yes"
+	}
+	key = "`" + key + "`"
+	desc := s.Description
+	if len(s.Enum) > 0 {
+		choices := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			v, _ := json.Marshal(e)
+			choices[i] = "`" + string(v) + "`"
+		}
+		desc = strings.TrimSpace(desc + " One of " + strings.Join(choices, ", ") + ".")
+	}
+	if s.Deprecated {
+		key = "~~" + key + "~~"
+		if !strings.Contains(desc, "Deprecated") {
+			desc = strings.TrimSpace("**Deprecated.** " + desc)
+		}
+	}
+	fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n", key, cell(TypeName(s)), def, req, cell(desc))
+}
+
+// TypeName describes the type of s for humans, e.g. "string (duration)", "[]integer" or "integer | string".
+func TypeName(s *Schema) string {
+	switch {
+	case len(s.AnyOf) > 0 || len(s.OneOf) > 0:
+		alts := s.alternatives()
+		names := make([]string, len(alts))
+		for i, a := range alts {
+			names[i] = TypeName(a)
+		}
+		return strings.Join(names, " | ")
+	case s.Type == "array" && s.Items != nil:
+		return "[]" + TypeName(s.Items)
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		return "map[string]" + TypeName(s.AdditionalProperties)
+	case s.Type == "":
+		return "any"
+	case s.Format != "":
+		return s.Type + " (" + s.Format + ")"
+	default:
+		return s.Type
+	}
+}
+
+// cell escapes text for a table cell.
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
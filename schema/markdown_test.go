@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	port := &Schema{Type: "integer", Default: 8080, Description: "Port a|b."}
+	s := &Schema{
+		Title:       "Config",
+		Description: "App config.",
+		Type:        "object",
+		Properties: Properties{
+			{Name: "server", Schema: &Schema{
+				Type: "object",
+				Properties: Properties{
+					{Name: "port", Schema: port},
+					{Name: "timeout", Schema: &Schema{Type: "string", Format: "duration"}},
+				},
+				Required: []string{"port"},
+			}},
+			{Name: "peers", Schema: &Schema{Type: "array", Items: &Schema{
+				Type:       "object",
+				Properties: Properties{{Name: "url", Schema: &Schema{Type: "string"}}},
+			}}},
+			{Name: "limits", Schema: &Schema{Type: "object", AdditionalProperties: &Schema{AnyOf: []*Schema{{Type: "integer"}, {Type: "string"}}}}},
+			{Name: "mode", Schema: &Schema{Type: "string", Enum: []interface{}{"dev", "prod"}}},
+			{Name: "legacy", Schema: &Schema{Type: "boolean", Deprecated: true}},
+			{Name: "extra", Schema: &Schema{}},
+		},
+	}
+	var b strings.Builder
+	if err := Markdown(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Config\n\nApp config.\n\n" +
+		"| Key | Type | Default | Required | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| `server` | object |  |  |  |\n" +
+		"| `server.port` | integer | `8080` | yes | Port a\\|b. |\n" +
+		"| `server.timeout` | string (duration) |  |  |  |\n" +
+		"| `peers` | []object |  |  |  |\n" +
+		"| `peers[].url` | string |  |  |  |\n" +
+		"| `limits` | map[string]integer \\| string |  |  |  |\n" +
+		"| `mode` | string |  |  | One of `\"dev\"`, `\"prod\"`. |\n" +
+		"| ~~`legacy`~~ | boolean |  |  | **Deprecated.** |\n" +
+		"| `extra` | any |  |  |  |\n"
+	if got := b.String(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
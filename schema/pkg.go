@@ -0,0 +1,2 @@
+// Package schema models the subset of JSON Schema used to describe config files.
+package schema
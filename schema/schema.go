@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Draft is the JSON Schema dialect written to $schema.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema. Only the keywords needed for config files are modeled.
+type Schema struct {
+	Schema      string        `json:"$schema,omitempty"`
+	ID          string        `json:"$id,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Type        string        `json:"type,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Deprecated  bool          `json:"deprecated,omitempty"`
+	Minimum     *float64      `json:"minimum,omitempty"`
+	Maximum     *float64      `json:"maximum,omitempty"`
+
+	MinItems    *int `json:"minItems,omitempty"`
+	MaxItems    *int `json:"maxItems,omitempty"`
+	UniqueItems bool `json:"uniqueItems,omitempty"`
+	// UniqueBy is a key path that must be unique among the objects of an array, e.g. "name" for a list
+	// of named servers. It is an extension keyword, validators that do not know it ignore it.
+	UniqueBy string `json:"x-uniqueBy,omitempty"`
+
+	Properties           Properties `json:"properties,omitempty"`
+	Required             []string   `json:"required,omitempty"`
+	AdditionalProperties *Schema    `json:"additionalProperties,omitempty"`
+	Items                *Schema    `json:"items,omitempty"`
+	AnyOf                []*Schema  `json:"anyOf,omitempty"`
+	OneOf                []*Schema  `json:"oneOf,omitempty"`
+
+	// If selects Then for values valid against it and Else for the others.
+	If   *Schema `json:"if,omitempty"`
+	Then *Schema `json:"then,omitempty"`
+	Else *Schema `json:"else,omitempty"`
+
+	// Extra holds the keywords not modeled above, e.g. ones for RegisterKeyword, with their raw values.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// compiled holds Pattern and Enum prepared by the first validation, see compile.
+	compiled atomic.Pointer[compiled] `json:"-"`
+}
+
+// schemaFields is Schema without its methods, to encode and decode the modeled keywords.
+type schemaFields Schema
+
+// knownKeywords are the JSON names of the modeled keywords.
+var knownKeywords = func() map[string]bool {
+	known := map[string]bool{}
+	t := reflect.TypeOf(schemaFields{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			known[name] = true
+		}
+	}
+	return known
+}()
+
+// MarshalJSON writes the modeled keywords followed by Extra in the order of their names.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal((*schemaFields)(s))
+	if err != nil || len(s.Extra) == 0 {
+		return b, err
+	}
+	names := make([]string, 0, len(s.Extra))
+	for name := range s.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := bytes.NewBuffer(b[:len(b)-1])
+	for _, name := range names {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(s.Extra[name])
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: %w", name, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads the modeled keywords and keeps the others in Extra.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*schemaFields)(s)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	s.Extra = nil
+	for name, value := range all {
+		if knownKeywords[name] {
+			continue
+		}
+		if s.Extra == nil {
+			s.Extra = map[string]json.RawMessage{}
+		}
+		s.Extra[name] = value
+	}
+	return nil
+}
+
+// alternatives returns the branches of anyOf followed by those of oneOf.
+func (s *Schema) alternatives() []*Schema {
+	return append(append([]*Schema(nil), s.AnyOf...), s.OneOf...)
+}
+
+// Property is a named property of an object schema.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties keeps properties in declaration order, which JSON objects decoded into maps lose.
+type Properties []Property
+
+// Get returns the schema of the named property or nil.
+func (ps Properties) Get(name string) *Schema {
+	for _, p := range ps {
+		if p.Name == name {
+			return p.Schema
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the properties as an object in declaration order.
+func (ps Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, p := range ps {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads an object keeping the order of its keys.
+func (ps *Properties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	*ps = nil
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s Schema
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		*ps = append(*ps, Property{Name: tok.(string), Schema: &s})
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// IsRequired reports whether the object schema lists name as required.
+func (s *Schema) IsRequired(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalIndent encodes the schema as indented JSON with a trailing newline.
+func (s *Schema) MarshalIndent() ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProperties_JSON(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "zeta", Schema: &Schema{Type: "string"}},
+			{Name: "alpha", Schema: &Schema{Type: "integer", Default: 0}},
+		},
+		Required: []string{"zeta"},
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"type":"object","properties":{"zeta":{"type":"string"},"alpha":{"type":"integer","default":0}},"required":["zeta"]}`
+	if string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+
+	var got Schema
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(got.Properties) != 2 || got.Properties[0].Name != "zeta" || got.Properties.Get("alpha").Type != "integer" {
+		t.Errorf("Unmarshal() got %+v", got.Properties)
+	}
+	if !got.IsRequired("zeta") || got.IsRequired("alpha") {
+		t.Error("IsRequired() got wrong result")
+	}
+	if err := json.Unmarshal([]byte(`{"properties": []}`), &got); err == nil {
+		t.Error("Unmarshal() expected error for non-object properties")
+	}
+}
+
+func TestSchema_JSONExtra(t *testing.T) {
+	in := `{"type":"string","x-unit":"bytes","properties":{"a":{"x-b":[1,2]}},"x-max":"4Gi"}`
+	var s Schema
+	if err := json.Unmarshal([]byte(in), &s); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if string(s.Extra["x-unit"]) != `"bytes"` || string(s.Extra["x-max"]) != `"4Gi"` || len(s.Extra) != 2 {
+		t.Errorf("Extra = %s", s.Extra)
+	}
+	if string(s.Properties.Get("a").Extra["x-b"]) != "[1,2]" {
+		t.Errorf("properties.a.Extra = %s", s.Properties.Get("a").Extra)
+	}
+	b, err := json.Marshal(&s)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"type":"string","properties":{"a":{"x-b":[1,2]}},"x-max":"4Gi","x-unit":"bytes"}`; string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+}
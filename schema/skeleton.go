@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Skeleton formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// Skeleton writes a starter config in the given format with every key of the schema set to its default,
+// its first enum choice or the zero value of its type. Descriptions, choices and required or deprecated
+// status are written as comments. JSON output uses // comments, which slowjson accepts.
+func Skeleton(w io.Writer, s *Schema, format string) error {
+	sk := &skeleton{}
+	switch format {
+	case FormatJSON:
+		sk.json(s, 0)
+		sk.b.WriteByte('\n')
+	case FormatYAML:
+		sk.yaml(s, 0)
+	case FormatTOML:
+		sk.toml(s, "")
+	default:
+		return fmt.Errorf("unsupported skeleton format %q", format)
+	}
+	_, err := io.WriteString(w, sk.b.String())
+	return err
+}
+
+type skeleton struct {
+	b strings.Builder
+}
+
+// comments writes the description and constraints of a property prefixed by marker.
+func (sk *skeleton) comments(indent, marker string, s *Schema, required bool) {
+	var lines []string
+	if s.Description != "" {
+		lines = append(lines, s.Description)
+	}
+	if len(s.Enum) > 0 {
+		choices := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			b, _ := json.Marshal(e)
+			choices[i] = string(b)
+		}
+		lines = append(lines, "One of "+strings.Join(choices, ", ")+".")
+	}
+	if required {
+		lines = append(lines, "Required.")
+	}
+	if s.Deprecated && !strings.Contains(s.Description, "Deprecated") {
+		lines = append(lines, "Deprecated.")
+	}
+	for _, l := range lines {
+		fmt.Fprintf(&sk.b, "%s%s %s\n", indent, marker, l)
+	}
+}
+
+// sampleValue is the value written for a schema without properties, or nil for an object with properties.
+func sampleValue(s *Schema) interface{} {
+	switch {
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AnyOf) > 0 || len(s.OneOf) > 0:
+		return sampleValue(s.alternatives()[0])
+	}
+	switch s.Type {
+	case "string":
+		return ""
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []interface{}{}
+	case "object":
+		if len(s.Properties) > 0 {
+			return nil
+		}
+		return map[string]interface{}{}
+	}
+	return nil
+}
+
+func (sk *skeleton) json(s *Schema, depth int) {
+	v := sampleValue(s)
+	if v != nil || len(s.Properties) == 0 {
+		b, _ := json.Marshal(v)
+		sk.b.Write(b)
+		return
+	}
+	indent := strings.Repeat("  ", depth+1)
+	sk.b.WriteString("{\n")
+	for i, p := range s.Properties {
+		sk.comments(indent, "//", p.Schema, s.IsRequired(p.Name))
+		name, _ := json.Marshal(p.Name)
+		fmt.Fprintf(&sk.b, "%s%s: ", indent, name)
+		sk.json(p.Schema, depth+1)
+		if i < len(s.Properties)-1 {
+			sk.b.WriteByte(',')
+		}
+		sk.b.WriteByte('\n')
+	}
+	sk.b.WriteString(strings.Repeat("  ", depth) + "}")
+}
+
+func (sk *skeleton) yaml(s *Schema, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, p := range s.Properties {
+		sk.comments(indent, "#", p.Schema, s.IsRequired(p.Name))
+		fmt.Fprintf(&sk.b, "%s%s:", indent, bareKey(p.Name))
+		if v := sampleValue(p.Schema); v != nil || len(p.Schema.Properties) == 0 {
+			// JSON values are valid YAML flow values
+			b, _ := json.Marshal(v)
+			fmt.Fprintf(&sk.b, " %s\n", b)
+			continue
+		}
+		sk.b.WriteByte('\n')
+		sk.yaml(p.Schema, depth+1)
+	}
+}
+
+func (sk *skeleton) toml(s *Schema, table string) {
+	// TOML requires the keys of a table before its sub-tables
+	var tables []Property
+	for _, p := range s.Properties {
+		v := sampleValue(p.Schema)
+		if v == nil && len(p.Schema.Properties) > 0 {
+			tables = append(tables, p)
+			continue
+		}
+		if v == nil {
+			// TOML has no null, leave the key for the user to fill in
+			sk.comments("", "#", p.Schema, s.IsRequired(p.Name))
+			fmt.Fprintf(&sk.b, "# %s = \n", bareKey(p.Name))
+			continue
+		}
+		sk.comments("", "#", p.Schema, s.IsRequired(p.Name))
+		fmt.Fprintf(&sk.b, "%s = %s\n", bareKey(p.Name), tomlValue(v))
+	}
+	for _, p := range tables {
+		name := bareKey(p.Name)
+		if table != "" {
+			name = table + "." + name
+		}
+		sk.b.WriteByte('\n')
+		sk.comments("", "#", p.Schema, s.IsRequired(p.Name))
+		fmt.Fprintf(&sk.b, "[%s]\n", name)
+		sk.toml(p.Schema, name)
+	}
+}
+
+func tomlValue(v interface{}) string {
+	switch v := v.(type) {
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = tomlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = bareKey(k) + " = " + tomlValue(v[k])
+		}
+		if len(items) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	default:
+		// JSON strings, numbers and booleans are valid TOML
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// bareKey quotes keys that are not valid unquoted YAML or TOML keys.
+func bareKey(k string) string {
+	if bareKeyPattern.MatchString(k) {
+		return k
+	}
+	b, _ := json.Marshal(k)
+	return string(b)
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func skeletonSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "name", Schema: &Schema{Type: "string", Description: "Service name."}},
+			{Name: "server", Schema: &Schema{
+				Type: "object",
+				Properties: Properties{
+					{Name: "port", Schema: &Schema{Type: "integer", Default: 8080}},
+					{Name: "mode", Schema: &Schema{Type: "string", Enum: []interface{}{"dev", "prod"}}},
+					{Name: "tls", Schema: &Schema{Type: "object", Properties: Properties{
+						{Name: "cert", Schema: &Schema{Type: "string"}},
+					}}},
+				},
+				Required: []string{"port"},
+			}},
+			{Name: "tags", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+			{Name: "legacy", Schema: &Schema{Type: "boolean", Deprecated: true}},
+		},
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, `{
+  // Service name.
+  "name": "",
+  "server": {
+    // Required.
+    "port": 8080,
+    // One of "dev", "prod".
+    "mode": "dev",
+    "tls": {
+      "cert": ""
+    }
+  },
+  "tags": [],
+  // Deprecated.
+  "legacy": false
+}
+`},
+		{FormatYAML, `# Service name.
+name: ""
+server:
+  # Required.
+  port: 8080
+  # One of "dev", "prod".
+  mode: "dev"
+  tls:
+    cert: ""
+tags: []
+# Deprecated.
+legacy: false
+`},
+		{FormatTOML, `# Service name.
+name = ""
+tags = []
+# Deprecated.
+legacy = false
+
+[server]
+# Required.
+port = 8080
+# One of "dev", "prod".
+mode = "dev"
+
+[server.tls]
+cert = ""
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			if err := Skeleton(&b, skeletonSchema(), tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Skeleton() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkeleton_JSONParses(t *testing.T) {
+	var b strings.Builder
+	if err := Skeleton(&b, skeletonSchema(), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	n, err := slowjson.NewParser(b.String()).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if port := n.Get("server.port"); port == nil || port.Value != "8080" {
+		t.Errorf("server.port = %v", port)
+	}
+}
+
+func TestSkeleton_UnknownFormat(t *testing.T) {
+	err := Skeleton(&strings.Builder{}, skeletonSchema(), "ini")
+	if err == nil || !strings.Contains(err.Error(), `unsupported skeleton format "ini"`) {
+		t.Errorf("Skeleton() error = %v", err)
+	}
+}
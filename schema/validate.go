@@ -0,0 +1,424 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/internal/suggest"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Validate checks the value n against s and returns an error diagnostic for every violation, in document
+// order: type mismatches, values outside enum, minimum or maximum, strings not of their format, see
+// CheckFormat, or not matching pattern, missing required keys, arrays with too few or too many items or
+// duplicates, values matching no anyOf branch or not exactly one oneOf branch, violations of the then or
+// else branch selected by if and of keywords registered with RegisterKeyword. Keys the schema does not
+// know are not reported, see the unknown-key lint rule, and null is accepted for any type like Decode
+// does, see the empty-value rule. Diagnostics carry their code, e.g. diag.CodeInvalidEnum, and a fix when
+// the intended value is obvious: a string differing from an enum value only in case, or a string like
+// "true" or "80" where a boolean or number is expected, and the reverse.
+func (s *Schema) Validate(n *slowjson.Node) []diag.Diagnostic {
+	var diags []diag.Diagnostic
+	s.validate(n, &diags)
+	return diags
+}
+
+func (s *Schema) validate(n *slowjson.Node, diags *[]diag.Diagnostic) {
+	if s == nil || n == nil || n.Type == slowjson.NodeNull {
+		return
+	}
+	report := func(code string, format string, args ...interface{}) {
+		d := diag.Errorf(n, format, args...)
+		d.Code = code
+		*diags = append(*diags, d)
+	}
+	fix := func(value *slowjson.Node) {
+		if value != nil {
+			(*diags)[len(*diags)-1].Fix = diag.NewFix(value)
+		}
+	}
+	if s.Type != "" && !hasType(n, s.Type) {
+		report(diag.CodeTypeMismatch, "expected %s, got %s", s.Type, typeName(n))
+		fix(convert(n, s.Type))
+		return
+	}
+	if len(s.AnyOf) > 0 {
+		s.validateBranches(n, "anyOf", s.AnyOf, diags)
+	}
+	if len(s.OneOf) > 0 {
+		s.validateBranches(n, "oneOf", s.OneOf, diags)
+	}
+	if s.If != nil {
+		s.validateConditional(n, diags)
+	}
+	if len(s.Extra) > 0 {
+		s.validateKeywords(n, diags)
+	}
+	if len(s.Enum) > 0 && !inEnum(n, s.compile().enum) {
+		report(diag.CodeInvalidEnum, "%s", enumMessage(n, s.Enum))
+		fix(enumFix(n, s.Enum))
+	}
+	switch n.Type {
+	case slowjson.NodeNumber:
+		v, ok := new(big.Rat).SetString(n.Value)
+		if !ok {
+			break
+		}
+		if s.Minimum != nil && v.Cmp(new(big.Rat).SetFloat64(*s.Minimum)) < 0 {
+			report(diag.CodeConstraint, "%s is less than the minimum %v", n.Value, *s.Minimum)
+		}
+		if s.Maximum != nil && v.Cmp(new(big.Rat).SetFloat64(*s.Maximum)) > 0 {
+			report(diag.CodeConstraint, "%s is greater than the maximum %v", n.Value, *s.Maximum)
+		}
+	case slowjson.NodeString:
+		if s.Format != "" {
+			if err := CheckFormat(s.Format, n.Value); err != nil {
+				report(diag.CodeInvalidFormat, "%q is not a valid %s: %v", n.Value, s.Format, err)
+			}
+		}
+		if s.Pattern == "" {
+			break
+		}
+		c := s.compile()
+		if c.patternErr != nil {
+			report(diag.CodeConstraint, "invalid pattern %q in the schema: %v", s.Pattern, c.patternErr)
+		} else if !c.pattern.MatchString(n.Value) {
+			report(diag.CodeConstraint, "%q does not match the pattern %s", n.Value, s.Pattern)
+		}
+	case slowjson.NodeObject:
+		seen := map[string]bool{}
+		for _, key := range n.Children {
+			seen[key.Value] = true
+			if len(key.Children) == 0 {
+				continue
+			}
+			prop := s.Properties.Get(key.Value)
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			prop.validate(key.Children[0], diags)
+		}
+		for _, name := range s.Required {
+			if !seen[name] {
+				report(diag.CodeMissingKey, "missing required key %q", name)
+			}
+		}
+	case slowjson.NodeArray:
+		if s.MinItems != nil && len(n.Children) < *s.MinItems {
+			report(diag.CodeConstraint, "has %d items, fewer than the minimum %d", len(n.Children), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(n.Children) > *s.MaxItems {
+			report(diag.CodeConstraint, "has %d items, more than the maximum %d", len(n.Children), *s.MaxItems)
+		}
+		for _, elem := range n.Children {
+			s.Items.validate(elem, diags)
+		}
+		if s.UniqueItems {
+			duplicates(n.Children, "", diags)
+		}
+		if s.UniqueBy != "" {
+			s.uniqueBy(n, diags)
+		}
+	}
+}
+
+// compiled is Pattern and Enum of a schema prepared for validation.
+type compiled struct {
+	pattern    *regexp.Regexp
+	patternErr error
+	// enum holds the nodes of the enum values, nil for values that cannot be encoded.
+	enum []*slowjson.Node
+}
+
+// compile returns Pattern and Enum prepared for validation, they are prepared once per schema so a
+// schema validating many documents compiles its pattern once. Changes to Pattern or Enum after the
+// first validation are not seen.
+func (s *Schema) compile() *compiled {
+	if c := s.compiled.Load(); c != nil {
+		return c
+	}
+	c := &compiled{}
+	if s.Pattern != "" {
+		c.pattern, c.patternErr = regexp.Compile(s.Pattern)
+	}
+	for _, e := range s.Enum {
+		c.enum = append(c.enum, enumNode(e))
+	}
+	if !s.compiled.CompareAndSwap(nil, c) {
+		return s.compiled.Load()
+	}
+	return c
+}
+
+// duplicates reports every value equal to an earlier one, with a note on the first. what names the
+// values in messages, the elements themselves when empty.
+func duplicates(values []*slowjson.Node, what string, diags *[]diag.Diagnostic) {
+	var firsts []*slowjson.Node
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		var first *slowjson.Node
+		for _, f := range firsts {
+			if slowjson.Equal(f, v, slowjson.EqualOptions{NumericValue: true}) {
+				first = f
+				break
+			}
+		}
+		if first == nil {
+			firsts = append(firsts, v)
+			continue
+		}
+		value, _ := slowjson.Marshal(v)
+		msg := fmt.Sprintf("duplicate item %s", value)
+		if what != "" {
+			msg = fmt.Sprintf("duplicate %s %s", what, value)
+		}
+		d := diag.Errorf(v, "%s", msg)
+		d.Code = diag.CodeDuplicateItem
+		d.Related = []diag.Related{diag.RelatedTo(first, "first defined here")}
+		*diags = append(*diags, d)
+	}
+}
+
+// uniqueBy reports objects of the array n sharing the value at s.UniqueBy, objects without it are skipped.
+func (s *Schema) uniqueBy(n *slowjson.Node, diags *[]diag.Diagnostic) {
+	p, err := slowjson.ParsePath(s.UniqueBy)
+	if err != nil {
+		d := diag.Errorf(n, "invalid x-uniqueBy %q in the schema: %v", s.UniqueBy, err)
+		d.Code = diag.CodeConstraint
+		*diags = append(*diags, d)
+		return
+	}
+	values := make([]*slowjson.Node, len(n.Children))
+	for i, elem := range n.Children {
+		if elem.Type == slowjson.NodeObject {
+			values[i] = elem.Lookup(p)
+		}
+	}
+	duplicates(values, s.UniqueBy, diags)
+}
+
+// branch is the outcome of validating a value against one branch of anyOf or oneOf.
+type branch struct {
+	name  string
+	diags []diag.Diagnostic
+	// wrongType is set when the value itself does not have the type of the branch.
+	wrongType bool
+	// enums counts values outside an enum, typically a "kind" key telling the branches apart.
+	enums int
+}
+
+// closer reports whether the value came closer to matching b than c: having the type of the branch
+// beats not having it, then fewer values outside an enum and then fewer violations win.
+func (b branch) closer(c branch) bool {
+	if b.wrongType != c.wrongType {
+		return !b.wrongType
+	}
+	if b.enums != c.enums {
+		return b.enums < c.enums
+	}
+	return len(b.diags) < len(c.diags)
+}
+
+// validateBranches checks n against the branches of the anyOf or oneOf keyword. When none matches, the
+// diagnostic names the closest branch and why it failed, with a note on every violation of it and a note
+// on why each other branch failed. For oneOf, matching several branches is reported as well.
+func (s *Schema) validateBranches(n *slowjson.Node, keyword string, schemas []*Schema, diags *[]diag.Diagnostic) {
+	var failed []branch
+	var matched []string
+	for i, bs := range schemas {
+		b := branch{name: fmt.Sprintf("%s[%d]", keyword, i), diags: bs.Validate(n)}
+		if len(b.diags) == 0 {
+			matched = append(matched, b.name)
+			continue
+		}
+		for _, d := range b.diags {
+			b.wrongType = b.wrongType || (d.Node == n && d.Code == diag.CodeTypeMismatch)
+			if d.Code == diag.CodeInvalidEnum {
+				b.enums++
+			}
+		}
+		failed = append(failed, b)
+	}
+	if len(matched) > 1 && keyword == "oneOf" {
+		d := diag.Errorf(n, "matches %d of the %d oneOf schemas (%s), expected exactly one",
+			len(matched), len(schemas), strings.Join(matched, ", "))
+		d.Code = diag.CodeConstraint
+		*diags = append(*diags, d)
+	}
+	if len(matched) > 0 {
+		return
+	}
+	best := failed[0]
+	for _, b := range failed[1:] {
+		if b.closer(best) {
+			best = b
+		}
+	}
+	d := diag.Errorf(n, "does not match any of the %d allowed schemas, closest is %s: %s",
+		len(schemas), best.name, branchReason(n, best))
+	d.Code = diag.CodeConstraint
+	for _, bd := range best.diags {
+		d.Related = append(d.Related, diag.RelatedTo(bd.Node, "%s: %s", best.name, relative(n, bd)))
+	}
+	for _, b := range failed {
+		if b.name != best.name {
+			d.Related = append(d.Related, diag.RelatedTo(n, "%s failed: %s", b.name, branchReason(n, b)))
+		}
+	}
+	*diags = append(*diags, d)
+}
+
+// validateConditional checks n against s.Then when it is valid against s.If and against s.Else otherwise.
+// Violations of the selected branch carry a note saying why it applies.
+func (s *Schema) validateConditional(n *slowjson.Node, diags *[]diag.Diagnostic) {
+	cond := s.If.Validate(n)
+	target, note := s.Then, "then applies because the value matches the if schema"
+	if len(cond) > 0 {
+		target = s.Else
+		note = "else applies because the value does not match the if schema: " + relative(n, cond[0])
+	}
+	for _, d := range target.Validate(n) {
+		d.Related = append(d.Related, diag.RelatedTo(n, "%s", note))
+		*diags = append(*diags, d)
+	}
+}
+
+// branchReason summarizes why b failed for n: its first violation and how many more there are.
+func branchReason(n *slowjson.Node, b branch) string {
+	reason := relative(n, b.diags[0])
+	if len(b.diags) > 1 {
+		reason += fmt.Sprintf(" (and %d more)", len(b.diags)-1)
+	}
+	return reason
+}
+
+// relative is the message of d prefixed with the path of its node below n, if it is not n itself.
+func relative(n *slowjson.Node, d diag.Diagnostic) string {
+	if d.Node == n || d.Node == nil {
+		return d.Message
+	}
+	p := d.Node.Path()
+	if depth := len(n.Path()); len(p) > depth {
+		p = p[depth:]
+	}
+	return p.String() + ": " + d.Message
+}
+
+// hasType reports whether n is of the JSON Schema type typ, integers are numbers without a fraction.
+func hasType(n *slowjson.Node, typ string) bool {
+	switch typ {
+	case "integer":
+		v, ok := new(big.Rat).SetString(n.Value)
+		return n.Type == slowjson.NodeNumber && ok && v.IsInt()
+	case "number":
+		return n.Type == slowjson.NodeNumber
+	}
+	return typeName(n) == typ
+}
+
+func typeName(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeObject:
+		return "object"
+	case slowjson.NodeArray:
+		return "array"
+	case slowjson.NodeString:
+		return "string"
+	case slowjson.NodeNumber:
+		return "number"
+	case slowjson.NodeBoolean:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// convert returns n as a value of typ when the conversion is lossless, e.g. the string "true" as a
+// boolean or the number 8080 as a string, nil otherwise.
+func convert(n *slowjson.Node, typ string) *slowjson.Node {
+	switch {
+	case n.Type == slowjson.NodeString && typ == "boolean":
+		if v := strings.ToLower(strings.TrimSpace(n.Value)); v == "true" || v == "false" {
+			return &slowjson.Node{Type: slowjson.NodeBoolean, Value: v}
+		}
+	case n.Type == slowjson.NodeString && (typ == "number" || typ == "integer"):
+		v := slowjson.ParseValueOrString(strings.TrimSpace(n.Value), "")
+		if v.Type == slowjson.NodeNumber && hasType(v, typ) {
+			return &slowjson.Node{Type: slowjson.NodeNumber, Value: v.Value}
+		}
+	case (n.Type == slowjson.NodeNumber || n.Type == slowjson.NodeBoolean) && typ == "string":
+		return &slowjson.Node{Type: slowjson.NodeString, Value: n.Value}
+	}
+	return nil
+}
+
+// enumFix returns the only string of enum equal to the string n but for case, nil if there is none.
+func enumFix(n *slowjson.Node, enum []interface{}) *slowjson.Node {
+	if n.Type != slowjson.NodeString {
+		return nil
+	}
+	var match *slowjson.Node
+	for _, e := range enum {
+		if s, ok := e.(string); ok && strings.EqualFold(s, n.Value) {
+			if match != nil {
+				return nil
+			}
+			match = &slowjson.Node{Type: slowjson.NodeString, Value: s}
+		}
+	}
+	return match
+}
+
+// enumNode converts an enum value to a node for comparison, nil if it cannot be encoded.
+func enumNode(v interface{}) *slowjson.Node {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	n, err := slowjson.NewParser(string(b)).Parse()
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+func inEnum(n *slowjson.Node, enum []*slowjson.Node) bool {
+	for _, en := range enum {
+		if en != nil && slowjson.Equal(n, en, slowjson.EqualOptions{NumericValue: true}) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumMessage lists the allowed values and, for a string, the ones within a few edits of it.
+func enumMessage(n *slowjson.Node, enum []interface{}) string {
+	allowed := make([]string, len(enum))
+	var strs []string
+	for i, e := range enum {
+		b, _ := json.Marshal(e)
+		allowed[i] = string(b)
+		if s, ok := e.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	value, _ := slowjson.Marshal(n)
+	msg := fmt.Sprintf("invalid value %s, expected one of %s", value, strings.Join(allowed, ", "))
+	if n.Type != slowjson.NodeString {
+		return msg
+	}
+	similar := suggest.All(n.Value, strs)
+	if len(similar) == 0 {
+		return msg
+	}
+	for i, s := range similar {
+		similar[i] = fmt.Sprintf("%q", s)
+	}
+	return msg + "; did you mean " + strings.Join(similar, " or ") + "?"
+}
@@ -0,0 +1,322 @@
+package schema
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func float(f float64) *float64 { return &f }
+
+func integer(i int) *int { return &i }
+
+func TestSchema_Validate(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "level", Schema: &Schema{Type: "string", Enum: []interface{}{"debug", "info", "warn", "error"}}},
+			{Name: "mode", Schema: &Schema{Enum: []interface{}{"fast", 1.0, true}}},
+			{Name: "port", Schema: &Schema{Type: "integer", Minimum: float(1), Maximum: float(65535)}},
+			{Name: "name", Schema: &Schema{Type: "string", Pattern: `^[a-z]+$`}},
+			{Name: "host", Schema: &Schema{Type: "string", Format: "hostname"}},
+			{Name: "tags", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+			{Name: "size", Schema: &Schema{AnyOf: []*Schema{{Type: "integer"}, {Type: "string", Pattern: `^\d+[kmg]b$`}}}},
+		},
+		AdditionalProperties: &Schema{Type: "boolean"},
+		Required:             []string{"level", "port"},
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"valid", `{"level": "info", "mode": 1e0, "port": 80, "name": "api", "tags": ["a"], "size": "10mb", "extra": true, "name2": null}`, nil},
+		{"enum typo", `{"level": "debgu", "port": 80}`, []string{
+			`1:11: error: level: invalid value "debgu", expected one of "debug", "info", "warn", "error"; did you mean "debug"? [TC2004 invalid-enum]`,
+		}},
+		{"enum case", `{"level": "WARN", "port": 80}`, []string{
+			`1:11: error: level: invalid value "WARN", expected one of "debug", "info", "warn", "error"; did you mean "warn"? [TC2004 invalid-enum]`,
+		}},
+		{"enum without suggestion", `{"level": "info", "mode": 2, "port": 80}`, []string{
+			`1:27: error: mode: invalid value 2, expected one of "fast", 1, true [TC2004 invalid-enum]`,
+		}},
+		{"type", `{"level": 1, "port": 80.5, "tags": ["a", 2], "extra": "yes"}`, []string{
+			`1:11: error: level: expected string, got number [TC2003 type-mismatch]`,
+			`1:22: error: port: expected integer, got number [TC2003 type-mismatch]`,
+			`1:42: error: tags[1]: expected string, got number [TC2003 type-mismatch]`,
+			`1:55: error: extra: expected boolean, got string [TC2003 type-mismatch]`,
+		}},
+		{"range and pattern", `{"level": "info", "port": 70000, "name": "API"}`, []string{
+			`1:27: error: port: 70000 is greater than the maximum 65535 [TC2005 constraint]`,
+			`1:42: error: name: "API" does not match the pattern ^[a-z]+$ [TC2005 constraint]`,
+		}},
+		{"format", `{"level": "info", "port": 1, "host": "api_1"}`, []string{
+			`1:38: error: host: "api_1" is not a valid hostname: invalid character '_' [TC2008 invalid-format]`,
+		}},
+		{"required", `{"level": "info"}`, []string{
+			`1:1: error: missing required key "port" [TC2006 missing-key]`,
+		}},
+		{"anyOf", `{"level": "info", "port": 1, "size": "big"}`, []string{
+			`1:38: error: size: does not match any of the 2 allowed schemas, closest is anyOf[1]: "big" does not match the pattern ^\d+[kmg]b$ [TC2005 constraint]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, s, tt.input, tt.want)
+		})
+	}
+}
+
+func TestSchema_Validate_Compiled(t *testing.T) {
+	name := &Schema{Type: "string", Pattern: `^[a-z]+$`, Enum: []interface{}{"api", "web", "API"}}
+	s := &Schema{Type: "object", Properties: Properties{{Name: "name", Schema: name}}}
+	n, err := slowjson.NewParser(`{"name": "API"}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if diags := s.Validate(n); len(diags) != 1 || !strings.Contains(diags[0].Message, "does not match the pattern") {
+				t.Errorf("Validate() = %v", diags)
+			}
+		}()
+	}
+	wg.Wait()
+	c := name.compiled.Load()
+	if c == nil || len(c.enum) != 3 {
+		t.Fatalf("compiled = %+v, want the pattern and enum prepared", c)
+	}
+	s.Validate(n)
+	if name.compiled.Load() != c {
+		t.Errorf("Validate() compiled the schema again")
+	}
+}
+
+func TestSchema_Validate_Array(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "zones", Schema: &Schema{Type: "array", MinItems: integer(1), MaxItems: integer(3), UniqueItems: true}},
+			{Name: "servers", Schema: &Schema{Type: "array", UniqueBy: "name"}},
+			{Name: "bad", Schema: &Schema{Type: "array", UniqueBy: "a..b"}},
+		},
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"valid", `{"zones": ["a", "b"], "servers": [{"name": "x"}, {"name": "y"}, {}, 1]}`, nil},
+		{"too few", `{"zones": []}`, []string{
+			`1:11: error: zones: has 0 items, fewer than the minimum 1 [TC2005 constraint]`,
+		}},
+		{"too many", `{"zones": ["a", "b", "c", "d"]}`, []string{
+			`1:11: error: zones: has 4 items, more than the maximum 3 [TC2005 constraint]`,
+		}},
+		{"unique items", `{"zones": [{"a": 1}, 1, {"a": 1}]}`, []string{
+			`1:25: error: zones[2]: duplicate item {"a":1} [TC2007 duplicate-item]`,
+		}},
+		{"unique numbers", `{"zones": [1, 1.0]}`, []string{
+			`1:15: error: zones[1]: duplicate item 1.0 [TC2007 duplicate-item]`,
+		}},
+		{"unique by", `{"servers": [{"name": "x"}, {"name": "y"}, {"name": "x"}]}`, []string{
+			`1:53: error: servers[2].name: duplicate name "x" [TC2007 duplicate-item]`,
+		}},
+		{"invalid unique by", `{"bad": [{}]}`, []string{
+			`1:9: error: bad: invalid x-uniqueBy "a..b" in the schema: `,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, s, tt.input, tt.want)
+		})
+	}
+}
+
+func TestSchema_Validate_Branches(t *testing.T) {
+	file := &Schema{Type: "object", Required: []string{"path"}, Properties: Properties{
+		{Name: "kind", Schema: &Schema{Enum: []interface{}{"file"}}},
+		{Name: "path", Schema: &Schema{Type: "string"}},
+	}}
+	http := &Schema{Type: "object", Required: []string{"url"}, Properties: Properties{
+		{Name: "kind", Schema: &Schema{Enum: []interface{}{"http"}}},
+		{Name: "url", Schema: &Schema{Type: "string"}},
+		{Name: "timeout", Schema: &Schema{Type: "integer"}},
+	}}
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "source", Schema: &Schema{OneOf: []*Schema{{Type: "string"}, file, http}}},
+			{Name: "level", Schema: &Schema{OneOf: []*Schema{{Type: "integer"}, {Type: "number", Minimum: float(0)}}}},
+			{Name: "tls", Schema: &Schema{
+				Type: "object",
+				If:   &Schema{Properties: Properties{{Name: "enabled", Schema: &Schema{Enum: []interface{}{true}}}}, Required: []string{"enabled"}},
+				Then: &Schema{Required: []string{"cert"}},
+				Else: &Schema{Properties: Properties{{Name: "cert", Schema: &Schema{Type: "null"}}}},
+			}},
+		},
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"valid", `{"source": {"kind": "http", "url": "x"}, "level": 0.5, "tls": {"enabled": true, "cert": "c"}}`, nil},
+		{"closest branch", `{"source": {"kind": "http", "url": 1, "timeout": "1s"}}`, []string{
+			`1:12: error: source: does not match any of the 3 allowed schemas, closest is oneOf[2]: url: expected string, got number (and 1 more) [TC2005 constraint]`,
+		}},
+		{"type mismatch is farthest", `{"source": 1}`, []string{
+			`1:12: error: source: does not match any of the 3 allowed schemas, closest is oneOf[0]: expected string, got number [TC2005 constraint]`,
+		}},
+		{"several match", `{"level": 1}`, []string{
+			`1:11: error: level: matches 2 of the 2 oneOf schemas (oneOf[0], oneOf[1]), expected exactly one [TC2005 constraint]`,
+		}},
+		{"then", `{"tls": {"enabled": true}}`, []string{
+			`1:9: error: tls: missing required key "cert" [TC2006 missing-key]`,
+		}},
+		{"else", `{"tls": {"enabled": false, "cert": "c"}}`, []string{
+			`1:36: error: tls.cert: expected null, got string [TC2003 type-mismatch]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidate(t, s, tt.input, tt.want)
+		})
+	}
+}
+
+func TestSchema_Validate_BranchNotes(t *testing.T) {
+	s := &Schema{Properties: Properties{
+		{Name: "size", Schema: &Schema{AnyOf: []*Schema{{Type: "integer"}, {Type: "object", Required: []string{"value", "unit"}}}}},
+		{Name: "tls", Schema: &Schema{If: &Schema{Required: []string{"enabled"}}, Else: &Schema{Required: []string{"enabled"}}}},
+	}}
+	n, err := slowjson.NewParser("{\n  \"size\": {\"value\": 1},\n  \"tls\": {}\n}").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := s.Validate(n)
+	if len(diags) != 2 {
+		t.Fatalf("Validate() = %v", diags)
+	}
+	var notes []string
+	for _, d := range diags {
+		for _, r := range d.Related {
+			notes = append(notes, r.Span.String()+": "+r.Message)
+		}
+	}
+	want := []string{
+		`2:11: anyOf[1]: missing required key "unit"`,
+		`2:11: anyOf[0] failed: expected integer, got object`,
+		`3:10: else applies because the value does not match the if schema: missing required key "enabled"`,
+	}
+	if strings.Join(notes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("notes =\n%s\nwant\n%s", strings.Join(notes, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// checkValidate compares the diagnostics of validating input with want, a want ending in ": " only
+// has to be a prefix.
+func checkValidate(t *testing.T, s *Schema, input string, want []string) {
+	t.Helper()
+	n, err := slowjson.NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i, d := range s.Validate(n) {
+		str := d.String()
+		if i < len(want) && strings.HasSuffix(want[i], ": ") && strings.HasPrefix(str, want[i]) {
+			str = want[i]
+		}
+		got = append(got, str)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Validate() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSchema_Validate_DuplicateRelated(t *testing.T) {
+	s := &Schema{Type: "array", UniqueBy: "name"}
+	n, err := slowjson.NewParser("[\n  {\"name\": \"a\"},\n  {\"name\": \"a\"}\n]").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := s.Validate(n)
+	if len(diags) != 1 || len(diags[0].Related) != 1 {
+		t.Fatalf("Validate() = %v", diags)
+	}
+	r := diags[0].Render(0, 0)
+	for _, want := range []string{"2:   {\"name\": \"a\"},\n", "^ first defined here", "3:   {\"name\": \"a\"}\n"} {
+		if !strings.Contains(r, want) {
+			t.Errorf("Render() =\n%s\nmissing %q", r, want)
+		}
+	}
+}
+
+func TestSchema_Validate_Fix(t *testing.T) {
+	s := &Schema{
+		Type: "object",
+		Properties: Properties{
+			{Name: "level", Schema: &Schema{Type: "string", Enum: []interface{}{"debug", "info", "Info"}}},
+			{Name: "mode", Schema: &Schema{Enum: []interface{}{"fast", "slow"}}},
+			{Name: "debug", Schema: &Schema{Type: "boolean"}},
+			{Name: "port", Schema: &Schema{Type: "integer"}},
+			{Name: "name", Schema: &Schema{Type: "string"}},
+		},
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"mode": "FAST"}`, `replace with "fast"`},
+		{`{"level": "INFO"}`, ""},
+		{`{"mode": "fats"}`, ""},
+		{`{"debug": "True"}`, "replace with true"},
+		{`{"debug": "yes"}`, ""},
+		{`{"port": " 8080"}`, "replace with 8080"},
+		{`{"port": "80.5"}`, ""},
+		{`{"port": "80s"}`, ""},
+		{`{"name": 1.50}`, `replace with "1.50"`},
+		{`{"name": false}`, `replace with "false"`},
+	}
+	for _, tt := range tests {
+		n, err := slowjson.NewParser(tt.input).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		diags := s.Validate(n)
+		if len(diags) != 1 {
+			t.Fatalf("Validate(%s) = %v", tt.input, diags)
+		}
+		got := ""
+		if f := diags[0].Fix; f != nil {
+			got = f.Message
+		}
+		if got != tt.want {
+			t.Errorf("Validate(%s) fix = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSchema_Validate_Render(t *testing.T) {
+	s := &Schema{Properties: Properties{{Name: "level", Schema: &Schema{Enum: []interface{}{"debug", "info"}}}}}
+	n, err := slowjson.NewParser("{\n  \"level\": \"inf\"\n}").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := s.Validate(n)
+	if len(diags) != 1 {
+		t.Fatalf("Validate() = %v", diags)
+	}
+	r := diags[0].Render(0, 0)
+	for _, want := range []string{`did you mean "info"?`, "2:   \"level\": \"inf\"\n            ^ start", "#tc2004"} {
+		if !strings.Contains(r, want) {
+			t.Errorf("Render() =\n%s\nmissing %q", r, want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package tracedconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/at15/tracedconfig/schema"
+)
+
+// knownSchemas describes the types decoded by the built-in decoders, keyed by reflect type.
+var knownSchemas = map[reflect.Type]func() *schema.Schema{
+	reflect.TypeOf(time.Duration(0)): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "duration"}
+	},
+	reflect.TypeOf(time.Time{}): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "date-time"}
+	},
+	reflect.TypeOf(url.URL{}): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "uri"}
+	},
+	reflect.TypeOf(net.IP{}): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "ip"}
+	},
+	reflect.TypeOf(net.IPNet{}): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "cidr"}
+	},
+	reflect.TypeOf(regexp.Regexp{}): func() *schema.Schema {
+		return &schema.Schema{Type: "string", Format: "regex"}
+	},
+	reflect.TypeOf(ByteSize(0)): func() *schema.Schema {
+		return &schema.Schema{AnyOf: []*schema.Schema{
+			{Type: "integer"},
+			{Type: "string", Pattern: `^[0-9.]+\s*([kKmMgGtTpP]i?[bB]|[bB])?$`},
+		}}
+	},
+}
+
+// knownSchemasByName is knownSchemas keyed by import path and type name, for source based generation.
+var knownSchemasByName = func() map[string]func() *schema.Schema {
+	m := make(map[string]func() *schema.Schema, len(knownSchemas))
+	for t, f := range knownSchemas {
+		m[t.PkgPath()+"."+t.Name()] = f
+	}
+	return m
+}()
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// GenerateSchema derives a JSON Schema from the config struct v using the same field names as Decode.
+// Field metadata comes from struct tags:
+//
+//	desc:"..."        description
+//	default:"..."     default value, converted to the field's type
+//	enum:"a,b,c"      allowed values
+//	format:"email"    string format, also checked by Decode, see schema.CheckFormat
+//	min:"1" max:"10"  numeric range, or the number of items of a slice
+//	unique:"true"     slice items must be unique, unique:"name" the name field of its items
+//	required:"true"   the key must be present
+//	deprecated:"..."  marks the key deprecated with a reason
+//
+// Use GenerateSchemaFromSource to also pick up doc comments.
+func GenerateSchema(v interface{}) (*schema.Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("cannot generate schema for nil")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	g := &schemaGen{visiting: map[reflect.Type]bool{}}
+	s, err := g.typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = schema.Draft
+	if s.Title == "" {
+		s.Title = t.Name()
+	}
+	return s, nil
+}
+
+type schemaGen struct {
+	visiting map[reflect.Type]bool
+}
+
+func (g *schemaGen) typeSchema(t reflect.Type) (*schema.Schema, error) {
+	if f, ok := knownSchemas[t]; ok {
+		return f(), nil
+	}
+	if t.Kind() != reflect.Ptr {
+		pt := reflect.PointerTo(t)
+		switch {
+		case pt.Implements(jsonUnmarshalerType):
+			// can be any JSON value
+			return &schema.Schema{}, nil
+		case pt.Implements(textUnmarshalerType):
+			return &schema.Schema{Type: "string"}, nil
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.Bool:
+		return &schema.Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &schema.Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schema.Schema{Type: "number"}, nil
+	case reflect.String:
+		return &schema.Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &schema.Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema.Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String && !reflect.PointerTo(t.Key()).Implements(textUnmarshalerType) {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema.Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) (*schema.Schema, error) {
+	if g.visiting[t] {
+		return &schema.Schema{Type: "object", Description: "recursive " + t.String()}, nil
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	s := &schema.Schema{Type: "object"}
+	for _, f := range structFields(t) {
+		sf := t.FieldByIndex(f.index)
+		ps, err := g.typeSchema(sf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, sf.Name, err)
+		}
+		if err := applyTags(ps, sf.Tag); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, sf.Name, err)
+		}
+		s.Properties = append(s.Properties, schema.Property{Name: f.name, Schema: ps})
+		if sf.Tag.Get("required") == "true" {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s, nil
+}
+
+// applyTags copies field metadata from struct tags into the property schema.
+func applyTags(s *schema.Schema, tag reflect.StructTag) error {
+	if desc, ok := tag.Lookup("desc"); ok {
+		s.Description = desc
+	}
+	if format, ok := tag.Lookup("format"); ok {
+		s.Format = format
+	}
+	if reason, ok := tag.Lookup("deprecated"); ok {
+		s.Deprecated = true
+		if reason != "" {
+			s.Description = strings.TrimSpace(s.Description + " Deprecated: " + reason)
+		}
+	}
+	if def, ok := tag.Lookup("default"); ok {
+		v, err := tagValue(s, def)
+		if err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+		s.Default = v
+	}
+	if enum, ok := tag.Lookup("enum"); ok {
+		for _, e := range strings.Split(enum, ",") {
+			v, err := tagValue(s, strings.TrimSpace(e))
+			if err != nil {
+				return fmt.Errorf("invalid enum: %w", err)
+			}
+			s.Enum = append(s.Enum, v)
+		}
+	}
+	if s.Type == "array" {
+		for _, bound := range []struct {
+			name string
+			dst  **int
+		}{{"min", &s.MinItems}, {"max", &s.MaxItems}} {
+			if v, ok := tag.Lookup(bound.name); ok {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid %s %q", bound.name, v)
+				}
+				*bound.dst = &n
+			}
+		}
+		switch unique := tag.Get("unique"); unique {
+		case "", "false":
+		case "true":
+			s.UniqueItems = true
+		default:
+			s.UniqueBy = unique
+		}
+		return nil
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **float64
+	}{{"min", &s.Minimum}, {"max", &s.Maximum}} {
+		if v, ok := tag.Lookup(bound.name); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", bound.name, v)
+			}
+			*bound.dst = &f
+		}
+	}
+	return nil
+}
+
+// tagValue converts a tag value to the JSON type of the schema.
+func tagValue(s *schema.Schema, v string) (interface{}, error) {
+	switch s.Type {
+	case "integer":
+		return strconv.ParseInt(v, 10, 64)
+	case "number":
+		return strconv.ParseFloat(v, 64)
+	case "boolean":
+		return strconv.ParseBool(v)
+	case "array", "object":
+		var out interface{}
+		if err := json.Unmarshal([]byte(v), &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
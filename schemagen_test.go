@@ -0,0 +1,192 @@
+package tracedconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaServer struct {
+	Host    string        `json:"host" desc:"Listen address." default:"localhost" required:"true"`
+	Port    int           `json:"port" default:"8080" min:"1" max:"65535"`
+	Timeout time.Duration `json:"timeout" default:"30s"`
+	Mode    string        `json:"mode" enum:"dev,prod"`
+	Legacy  bool          `json:"legacy" deprecated:"use mode"`
+}
+
+type schemaConfig struct {
+	base
+	Server  schemaServer           `json:"server"`
+	Backups []*schemaServer        `json:"backups" min:"1" max:"3" unique:"host"`
+	Limits  map[string]ByteSize    `json:"limits"`
+	Next    *schemaConfig          `json:"next"`
+	Extra   map[string]interface{} `json:"extra"`
+	Skip    string                 `json:"-"`
+	private string
+}
+
+func TestGenerateSchema(t *testing.T) {
+	s, err := GenerateSchema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("GenerateSchema() error = %v", err)
+	}
+	if s.Title != "schemaConfig" || s.Type != "object" || s.Schema == "" {
+		t.Errorf("root = %+v", s)
+	}
+	var names []string
+	for _, p := range s.Properties {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "name,server,backups,limits,next,extra" {
+		t.Errorf("properties = %s", got)
+	}
+
+	server := s.Properties.Get("server")
+	host := server.Properties.Get("host")
+	if host.Description != "Listen address." || host.Default != "localhost" || !server.IsRequired("host") {
+		t.Errorf("host = %+v", host)
+	}
+	port := server.Properties.Get("port")
+	if port.Type != "integer" || port.Default != int64(8080) || *port.Minimum != 1 || *port.Maximum != 65535 {
+		t.Errorf("port = %+v", port)
+	}
+	if timeout := server.Properties.Get("timeout"); timeout.Type != "string" || timeout.Format != "duration" {
+		t.Errorf("timeout = %+v", timeout)
+	}
+	if mode := server.Properties.Get("mode"); len(mode.Enum) != 2 || mode.Enum[1] != "prod" {
+		t.Errorf("mode = %+v", mode)
+	}
+	if legacy := server.Properties.Get("legacy"); !legacy.Deprecated || !strings.Contains(legacy.Description, "use mode") {
+		t.Errorf("legacy = %+v", legacy)
+	}
+	if backups := s.Properties.Get("backups"); backups.Type != "array" || backups.Items.Properties.Get("port") == nil ||
+		backups.Minimum != nil || *backups.MinItems != 1 || *backups.MaxItems != 3 || backups.UniqueBy != "host" {
+		t.Errorf("backups = %+v", backups)
+	}
+	if limits := s.Properties.Get("limits"); limits.AdditionalProperties == nil || len(limits.AdditionalProperties.AnyOf) != 2 {
+		t.Errorf("limits = %+v", limits)
+	}
+	if next := s.Properties.Get("next"); !strings.HasPrefix(next.Description, "recursive") {
+		t.Errorf("next = %+v", next)
+	}
+}
+
+func TestGenerateSchemaError(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         interface{}
+		wantError string
+	}{
+		{"nil", nil, "nil"},
+		{"chan", struct{ C chan int }{}, "unsupported type chan int"},
+		{"bad default", struct {
+			N int `default:"x"`
+		}{}, "invalid default"},
+		{"map key", struct{ M map[int]string }{}, "unsupported map key type int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateSchema(tt.v)
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("GenerateSchema() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+const schemaSource = `package app
+
+import (
+	"time"
+
+	tc "github.com/at15/tracedconfig"
+)
+
+// Config is the application config.
+type Config struct {
+	// Addr is the listen address.
+	Addr    string        ` + "`json:\"addr\" default:\":8080\"`" + `
+	Timeout time.Duration ` + "`json:\"timeout\"`" + ` // request timeout
+	Limit   tc.ByteSize   ` + "`json:\"limit\" desc:\"Body limit.\"`" + `
+	Level   Level         ` + "`json:\"level\"`" + `
+	Peers   []Peer
+	Options
+}
+
+type Options struct {
+	Verbose bool ` + "`json:\"verbose\"`" + `
+}
+
+// Level is a log level.
+type Level string
+
+type Peer struct {
+	URL string ` + "`json:\"url\" required:\"true\"`" + `
+}
+`
+
+func TestGenerateSchemaFromSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(schemaSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := GenerateSchemaFromSource(dir, "Config")
+	if err != nil {
+		t.Fatalf("GenerateSchemaFromSource() error = %v", err)
+	}
+	if s.Description != "Config is the application config." {
+		t.Errorf("description = %q", s.Description)
+	}
+	tests := []struct {
+		name, typ, format, desc string
+	}{
+		{"addr", "string", "", "Addr is the listen address."},
+		{"timeout", "string", "duration", "request timeout"},
+		{"limit", "", "", "Body limit."},
+		{"level", "string", "", ""},
+		{"Peers", "array", "", ""},
+		{"verbose", "boolean", "", ""},
+	}
+	for _, tt := range tests {
+		p := s.Properties.Get(tt.name)
+		if p == nil {
+			t.Errorf("missing property %s", tt.name)
+			continue
+		}
+		if p.Type != tt.typ || p.Format != tt.format || p.Description != tt.desc {
+			t.Errorf("%s = %+v", tt.name, p)
+		}
+	}
+	if len(s.Properties.Get("limit").AnyOf) != 2 {
+		t.Errorf("limit should be a byte size")
+	}
+	if !s.Properties.Get("Peers").Items.IsRequired("url") {
+		t.Errorf("peer url should be required")
+	}
+
+	if _, err := GenerateSchemaFromSource(dir, "Missing"); err == nil || !strings.Contains(err.Error(), "type Missing not found") {
+		t.Errorf("GenerateSchemaFromSource() error = %v", err)
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := map[string]string{
+		"time":                       "time",
+		"net/url":                    "url",
+		"gopkg.in/yaml.v3":           "yaml",
+		"github.com/jackc/pgx/v5":    "pgx",
+		"github.com/mattn/go-isatty": "isatty",
+	}
+	for path, want := range tests {
+		if got := assumedPackageName(path); got != want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", path, got, want)
+		}
+	}
+	// the name of a package that can be found is read from its files
+	g := &sourceGen{dir: ".", names: map[string]string{}}
+	if got := g.packageName("github.com/at15/tracedconfig/internal/benchcmp/cmd/benchcmp"); got != "main" {
+		t.Errorf("packageName() = %q, want main", got)
+	}
+}
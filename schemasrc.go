@@ -0,0 +1,274 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/at15/tracedconfig/schema"
+)
+
+// GenerateSchemaFromSource is like GenerateSchema but reads the struct named typeName from the Go package in dir.
+// Doc comments on types and fields become descriptions unless a desc tag overrides them.
+func GenerateSchemaFromSource(dir, typeName string) (*schema.Schema, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	g := &sourceGen{dir: dir, types: map[string]*sourceType{}, visiting: map[string]bool{}, names: map[string]string{}}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			g.collect(f)
+		}
+	}
+	st, ok := g.types[typeName]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+	s, err := g.namedSchema(typeName, st)
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = schema.Draft
+	s.Title = typeName
+	return s, nil
+}
+
+type sourceType struct {
+	spec    *ast.TypeSpec
+	doc     string
+	imports map[string]string // local name to import path of the declaring file
+}
+
+type sourceGen struct {
+	dir      string
+	types    map[string]*sourceType
+	visiting map[string]bool
+	names    map[string]string // package name by import path
+}
+
+func (g *sourceGen) collect(f *ast.File) {
+	imports := map[string]string{}
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		} else {
+			name = g.packageName(path)
+		}
+		imports[name] = path
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			g.types[ts.Name.Name] = &sourceType{spec: ts, doc: docText(doc), imports: imports}
+		}
+	}
+}
+
+// packageName returns the name of the package imported as path, read from its files when it is found
+// from the generated package and otherwise assumed from the path like goimports does.
+func (g *sourceGen) packageName(path string) string {
+	if name, ok := g.names[path]; ok {
+		return name
+	}
+	name := assumedPackageName(path)
+	if pkg, err := build.Import(path, g.dir, 0); err == nil && pkg.Name != "" {
+		name = pkg.Name
+	}
+	g.names[path] = name
+	return name
+}
+
+// assumedPackageName guesses the package name of an import path from its last element, skipping a
+// major version, a "go-" prefix and anything from the first character that cannot be in a name, e.g.
+// yaml for gopkg.in/yaml.v3 and pgx for github.com/jackc/pgx/v5.
+func assumedPackageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (g *sourceGen) namedSchema(name string, st *sourceType) (*schema.Schema, error) {
+	if g.visiting[name] {
+		return &schema.Schema{Type: "object", Description: "recursive " + name}, nil
+	}
+	g.visiting[name] = true
+	defer delete(g.visiting, name)
+
+	s, err := g.exprSchema(st.spec.Type, st.imports)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if s.Description == "" {
+		s.Description = st.doc
+	}
+	return s, nil
+}
+
+func (g *sourceGen) exprSchema(expr ast.Expr, imports map[string]string) (*schema.Schema, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if s := basicSchema(e.Name); s != nil {
+			return s, nil
+		}
+		if st, ok := g.types[e.Name]; ok {
+			s, err := g.namedSchema(e.Name, st)
+			if err != nil {
+				return nil, err
+			}
+			// the type's doc describes the type, not the field using it
+			s.Description = ""
+			return s, nil
+		}
+		return nil, fmt.Errorf("unsupported type %s", e.Name)
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type expression")
+		}
+		name := imports[pkg.Name] + "." + e.Sel.Name
+		if f, ok := knownSchemasByName[name]; ok {
+			return f(), nil
+		}
+		// types from other packages are opaque without type checking
+		return &schema.Schema{}, nil
+	case *ast.StarExpr:
+		return g.exprSchema(e.X, imports)
+	case *ast.ArrayType:
+		items, err := g.exprSchema(e.Elt, imports)
+		if err != nil {
+			return nil, err
+		}
+		return &schema.Schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		values, err := g.exprSchema(e.Value, imports)
+		if err != nil {
+			return nil, err
+		}
+		return &schema.Schema{Type: "object", AdditionalProperties: values}, nil
+	case *ast.InterfaceType:
+		return &schema.Schema{}, nil
+	case *ast.StructType:
+		return g.structSchema(e, imports)
+	default:
+		return nil, fmt.Errorf("unsupported type expression %T", expr)
+	}
+}
+
+func (g *sourceGen) structSchema(st *ast.StructType, imports map[string]string) (*schema.Schema, error) {
+	s := &schema.Schema{Type: "object"}
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			v, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(v)
+		}
+		name, skip := fieldName(reflect.StructField{Tag: tag})
+		if skip {
+			continue
+		}
+		if len(f.Names) == 0 && name == "" {
+			// embedded struct, promote its fields
+			embedded, err := g.exprSchema(f.Type, imports)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties = append(s.Properties, embedded.Properties...)
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{embeddedName(f.Type)}
+		}
+		for _, id := range names {
+			if !id.IsExported() {
+				continue
+			}
+			ps, err := g.exprSchema(f.Type, imports)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", id.Name, err)
+			}
+			ps.Description = docText(f.Doc)
+			if ps.Description == "" {
+				ps.Description = docText(f.Comment)
+			}
+			if err := applyTags(ps, tag); err != nil {
+				return nil, fmt.Errorf("%s: %w", id.Name, err)
+			}
+			key := name
+			if key == "" {
+				key = id.Name
+			}
+			s.Properties = append(s.Properties, schema.Property{Name: key, Schema: ps})
+			if tag.Get("required") == "true" {
+				s.Required = append(s.Required, key)
+			}
+		}
+	}
+	return s, nil
+}
+
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.Ident:
+		return e
+	}
+	return ast.NewIdent("_")
+}
+
+func basicSchema(name string) *schema.Schema {
+	switch name {
+	case "bool":
+		return &schema.Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+		return &schema.Schema{Type: "integer"}
+	case "float32", "float64":
+		return &schema.Schema{Type: "number"}
+	case "string":
+		return &schema.Schema{Type: "string"}
+	case "any":
+		return &schema.Schema{}
+	}
+	return nil
+}
+
+func docText(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(cg.Text()), " ")
+}
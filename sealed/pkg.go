@@ -0,0 +1,3 @@
+// Package sealed encrypts individual config values as ENC[...] strings so secrets can live in
+// config files that stay readable and diffable, and decrypts them when the config is loaded.
+package sealed
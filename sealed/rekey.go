@@ -0,0 +1,84 @@
+package sealed
+
+import (
+	"os"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Rotated is a value Rekey re-encrypted.
+type Rotated struct {
+	Path slowjson.Path
+	// Pos is where the value starts in the document.
+	Pos slowjson.Position
+}
+
+// Rekey decrypts every ENC[...] value of the JSON document data with from and encrypts it again with to.
+// Only the bytes of those values change, so comments, ordering and indentation are kept. Values to can
+// already decrypt are left alone, which makes an interrupted rotation safe to run again. Nothing is
+// rotated when a value cannot be decrypted, the error points at it. file names the document in positions.
+func Rekey(data []byte, file string, from, to Cipher) ([]byte, []Rotated, error) {
+	p := slowjson.NewParser(string(data))
+	p.File = file
+	root, err := p.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	src := string(data)
+	var sb strings.Builder
+	var rotated []Rotated
+	last := 0
+	var walk func(n *slowjson.Node) error
+	walk = func(n *slowjson.Node) error {
+		if n.Type == slowjson.NodeString && !n.IsKey() && IsEncrypted(n.Value) {
+			plaintext, err := Decrypt(from, n.Value)
+			if err != nil {
+				if _, errTo := Decrypt(to, n.Value); errTo == nil {
+					return nil
+				}
+				return n.Errorf("%w", err)
+			}
+			enc, err := Encrypt(to, plaintext)
+			if err != nil {
+				return n.Errorf("%w", err)
+			}
+			// keep the quote the value is written with
+			quote := src[n.StartOffset : n.StartOffset+1]
+			sb.WriteString(src[last:n.StartOffset])
+			sb.WriteString(quote + enc + quote)
+			last = n.EndOffset
+			rotated = append(rotated, Rotated{Path: n.Path(), Pos: n.Position()})
+		}
+		for _, child := range n.Children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, nil, err
+	}
+	sb.WriteString(src[last:])
+	return []byte(sb.String()), rotated, nil
+}
+
+// RekeyFile rotates the ENC[...] values of the file at path like Rekey and replaces it with
+// tracedconfig.WriteFile, so it keeps its permissions and a failure leaves the file as it was.
+// The file is not written when nothing was rotated.
+func RekeyFile(path string, from, to Cipher) ([]Rotated, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out, rotated, err := Rekey(data, path, from, to)
+	if err != nil || len(rotated) == 0 {
+		return nil, err
+	}
+	if err := tracedconfig.WriteFile(path, out, 0o600); err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
@@ -0,0 +1,78 @@
+package sealed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var encPattern = regexp.MustCompile(`ENC\[[^\]]*\]`)
+
+func TestRekey(t *testing.T) {
+	from := testCipher(t)
+	to, _ := NewAESCipher(bytes.Repeat([]byte{9}, 32))
+	password, _ := Encrypt(from, "hunter2")
+	token, _ := Encrypt(from, "t0ken")
+	current, _ := Encrypt(to, "already rotated")
+	doc := "{\n  // database\n  \"db\": {\"password\": \"" + password + "\", \"user\": \"app\"},\n" +
+		"  \"tokens\": [\"" + token + "\", \"" + current + "\"]\n}\n"
+
+	out, rotated, err := Rekey([]byte(doc), "app.jsonc", from, to)
+	if err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+	var report []string
+	for _, r := range rotated {
+		report = append(report, r.Pos.String()+" "+r.Path.String())
+	}
+	if got, want := strings.Join(report, ", "), "app.jsonc:3:22 db.password, app.jsonc:4:14 tokens[0]"; got != want {
+		t.Errorf("Rekey() rotated %s, want %s", got, want)
+	}
+	if got, want := encPattern.ReplaceAllString(string(out), "ENC"), encPattern.ReplaceAllString(doc, "ENC"); got != want {
+		t.Errorf("Rekey() changed more than the values:\n%s", out)
+	}
+	values := encPattern.FindAllString(string(out), -1)
+	want := []string{"hunter2", "t0ken", "already rotated"}
+	for i, v := range values {
+		if got, err := Decrypt(to, v); err != nil || got != want[i] {
+			t.Errorf("value %d = %q, %v, want %q", i, got, err, want[i])
+		}
+	}
+	if values[2] != current {
+		t.Error("Rekey() re-encrypted a value already under the new key")
+	}
+
+	other, _ := NewAESCipher(bytes.Repeat([]byte{1}, 32))
+	stranger, _ := Encrypt(other, "x")
+	_, _, err = Rekey([]byte(`{"a": "`+password+`", "b": "`+stranger+`"}`), "", from, to)
+	if err == nil || !strings.Contains(err.Error(), "b: decrypt: wrong key or tampered value at line 1 col") {
+		t.Errorf("Rekey() with a foreign value error = %v", err)
+	}
+}
+
+func TestRekeyFile(t *testing.T) {
+	from := testCipher(t)
+	to, _ := NewAESCipher(bytes.Repeat([]byte{9}, 32))
+	v, _ := Encrypt(from, "hunter2")
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte(`{"password": "`+v+`"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := RekeyFile(path, from, to)
+	if err != nil || len(rotated) != 1 {
+		t.Fatalf("RekeyFile() = %v, %v", rotated, err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want it kept", info.Mode())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("RekeyFile() left temporary files: %v", entries)
+	}
+	if rotated, err := RekeyFile(path, from, to); err != nil || len(rotated) != 0 {
+		t.Errorf("RekeyFile() again = %v, %v, want nothing rotated", rotated, err)
+	}
+}
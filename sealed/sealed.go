@@ -0,0 +1,194 @@
+package sealed
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+const (
+	prefix = "ENC["
+	suffix = "]"
+)
+
+// Cipher encrypts and decrypts value payloads. Implement it to call a KMS.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// Funcs adapts functions, e.g. KMS client calls, to Cipher.
+type Funcs struct {
+	EncryptFunc func(plaintext []byte) ([]byte, error)
+	DecryptFunc func(ciphertext []byte) ([]byte, error)
+}
+
+// Encrypt calls EncryptFunc.
+func (f Funcs) Encrypt(plaintext []byte) ([]byte, error) {
+	if f.EncryptFunc == nil {
+		return nil, fmt.Errorf("encryption is not supported")
+	}
+	return f.EncryptFunc(plaintext)
+}
+
+// Decrypt calls DecryptFunc.
+func (f Funcs) Decrypt(ciphertext []byte) ([]byte, error) {
+	if f.DecryptFunc == nil {
+		return nil, fmt.Errorf("decryption is not supported")
+	}
+	return f.DecryptFunc(ciphertext)
+}
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESCipher creates an AES-GCM cipher from a 16, 24 or 32 byte key.
+func NewAESCipher(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCM{aead: aead}, nil
+}
+
+// Encrypt seals plaintext with a random nonce that is prepended to the result.
+func (c aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a result of Encrypt.
+func (c aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: wrong key or tampered value")
+	}
+	return plaintext, nil
+}
+
+// GenerateKey returns a new random 32 byte key encoded as base64, the format ParseKey reads.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey decodes a base64 key, surrounding whitespace is ignored so keys can be read from files.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether s is an ENC[...] value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// Encrypt encrypts a value and wraps it as ENC[...].
+func Encrypt(c Cipher, plaintext string) (string, error) {
+	b, err := c.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return prefix + base64.StdEncoding.EncodeToString(b) + suffix, nil
+}
+
+// Decrypt decrypts an ENC[...] value.
+func Decrypt(c Cipher, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not ENC[...]")
+	}
+	b, err := base64.StdEncoding.DecodeString(value[len(prefix) : len(value)-len(suffix)])
+	if err != nil {
+		return "", fmt.Errorf("invalid ENC value: %w", err)
+	}
+	plaintext, err := c.Decrypt(b)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// DecryptTree replaces every ENC[...] string below root with its plaintext, in place.
+// Nodes keep their positions so errors about decrypted values still point at the file.
+// Only Value changes: the raw span in Source still holds the ENC[...] text, so slowjson.Print and
+// error context show the encrypted form while Marshal and decoding see the plaintext.
+// It returns the decrypted nodes.
+func DecryptTree(root *slowjson.Node, c Cipher) ([]*slowjson.Node, error) {
+	var decrypted []*slowjson.Node
+	var walk func(n *slowjson.Node) error
+	walk = func(n *slowjson.Node) error {
+		if n.Type == slowjson.NodeString && !n.IsKey() && IsEncrypted(n.Value) {
+			v, err := Decrypt(c, n.Value)
+			if err != nil {
+				return n.Errorf("%w", err)
+			}
+			n.Value = v
+			decrypted = append(decrypted, n)
+		}
+		for _, child := range n.Children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return decrypted, walk(root)
+}
+
+// Source wraps a config source to decrypt its ENC[...] values when it is loaded.
+func Source(src tracedconfig.Source, c Cipher) tracedconfig.Source {
+	return &source{Source: src, cipher: c}
+}
+
+type source struct {
+	tracedconfig.Source
+	cipher Cipher
+}
+
+// Metadata forwards the metadata of the wrapped source.
+func (s *source) Metadata() map[string]string {
+	if ms, ok := s.Source.(tracedconfig.MetadataSource); ok {
+		return ms.Metadata()
+	}
+	return nil
+}
+
+func (s *source) Changed(ctx context.Context) (bool, error) {
+	return tracedconfig.SourceChanged(ctx, s.Source)
+}
+
+func (s *source) Load(ctx context.Context) (*slowjson.Node, error) {
+	n, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := DecryptTree(n, s.cipher); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,136 @@
+package sealed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/tracedconfigtest"
+)
+
+func testCipher(t *testing.T) Cipher {
+	t.Helper()
+	c, err := NewAESCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := testCipher(t)
+	v, err := Encrypt(c, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(v) || strings.Contains(v, "hunter2") {
+		t.Fatalf("Encrypt() = %s", v)
+	}
+	if again, _ := Encrypt(c, "hunter2"); again == v {
+		t.Error("Encrypt() should use a random nonce")
+	}
+	got, err := Decrypt(c, v)
+	if err != nil || got != "hunter2" {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+
+	other, _ := NewAESCipher(bytes.Repeat([]byte{8}, 32))
+	if _, err := Decrypt(other, v); err == nil || !strings.Contains(err.Error(), "wrong key or tampered value") {
+		t.Errorf("Decrypt() with wrong key error = %v", err)
+	}
+	for _, bad := range []string{"plain", "ENC[!!]", "ENC[AAAA]"} {
+		if _, err := Decrypt(c, bad); err == nil {
+			t.Errorf("Decrypt(%q) should fail", bad)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	s, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(s + "\n")
+	if err != nil || len(key) != 32 {
+		t.Fatalf("ParseKey() = %d bytes, %v", len(key), err)
+	}
+	if _, err := ParseKey("not base64!"); err == nil {
+		t.Error("ParseKey() should fail on invalid input")
+	}
+	if _, err := NewAESCipher([]byte("short")); err == nil {
+		t.Error("NewAESCipher() should reject a short key")
+	}
+}
+
+func TestSource(t *testing.T) {
+	c := testCipher(t)
+	secret, _ := Encrypt(c, "s3cret")
+	src := tracedconfig.Bytes("app.json", []byte(`{"db": {"user": "app", "password": "`+secret+`"}}`))
+	cfg := tracedconfig.NewConfig(Source(src, c))
+	if err := cfg.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	pw := cfg.Get("db.password")
+	if pw.Value != "s3cret" || pw.Location() != "app.json:1:36" {
+		t.Errorf("db.password = %q at %s", pw.Value, pw.Location())
+	}
+	if o, ok := cfg.Origin("db.password"); !ok || o.Layer != "app.json" {
+		t.Errorf("Origin() = %v", o)
+	}
+	if got := strings.TrimSpace(slowjson.Print(pw)); got != `"`+secret+`"` {
+		t.Errorf("Print() = %s, want the encrypted text", got)
+	}
+	if b, err := slowjson.Marshal(pw); err != nil || string(b) != `"s3cret"` {
+		t.Errorf("Marshal() = %s, %v", b, err)
+	}
+
+	bad := tracedconfig.Bytes("bad.json", []byte(`{"token": "ENC[AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA]"}`))
+	err := tracedconfig.NewConfig(Source(bad, c)).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "token: decrypt: wrong key or tampered value at bad.json:1:11") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestSource_Changed(t *testing.T) {
+	remote := tracedconfigtest.NewSource("remote", `{"port": 80}`)
+	src := Source(remote, testCipher(t)).(tracedconfig.ChangeDetector)
+	if _, err := src.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := src.Changed(context.Background()); ok || err != nil {
+		t.Errorf("Changed() = %v, %v before a change", ok, err)
+	}
+	remote.Set(`{"port": 81}`)
+	if ok, err := src.Changed(context.Background()); !ok || err != nil {
+		t.Errorf("Changed() = %v, %v after a change", ok, err)
+	}
+	if ok, _ := Source(tracedconfig.Bytes("a.json", nil), testCipher(t)).(tracedconfig.ChangeDetector).Changed(context.Background()); !ok {
+		t.Error("Changed() of a source that cannot tell = false")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	kms := Funcs{
+		EncryptFunc: func(p []byte) ([]byte, error) { return append([]byte("kms:"), p...), nil },
+		DecryptFunc: func(c []byte) ([]byte, error) {
+			if !bytes.HasPrefix(c, []byte("kms:")) {
+				return nil, errors.New("kms rejected")
+			}
+			return c[4:], nil
+		},
+	}
+	v, err := Encrypt(kms, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decrypt(kms, v); err != nil || got != "x" {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+	if _, err := Encrypt(Funcs{}, "x"); err == nil {
+		t.Error("Encrypt() without EncryptFunc should fail")
+	}
+}
@@ -0,0 +1,3 @@
+// Package secrets finds credentials committed in config values, such as cloud access keys,
+// tokens, private keys and other high entropy strings.
+package secrets
@@ -0,0 +1,120 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Pattern recognizes one kind of credential.
+type Pattern struct {
+	Kind   string
+	Regexp *regexp.Regexp
+}
+
+// Patterns are the credential formats Scan looks for.
+var Patterns = []Pattern{
+	{"aws-access-key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{"url-password", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@$]+@`)},
+}
+
+// keyPattern matches key names that usually hold secrets.
+var keyPattern = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[_-]?key|private[_-]?key|credential)`)
+
+// IsSecretKey reports whether a key is named like one holding a secret, e.g. "password" or "apiKey".
+func IsSecretKey(name string) bool {
+	return keyPattern.MatchString(name)
+}
+
+// Kind of findings for strings that match no pattern but look random.
+const KindHighEntropy = "high-entropy"
+
+var (
+	base64Token = regexp.MustCompile(`^[A-Za-z0-9+/_=-]{20,}$`)
+	hexToken    = regexp.MustCompile(`^[0-9a-fA-F]{32,}$`)
+)
+
+// Finding is a string value that looks like a credential.
+type Finding struct {
+	Kind string
+	Node *slowjson.Node
+}
+
+// String describes the finding without revealing the value, e.g. "aws-access-key AKIA... at line 3 col 12".
+func (f Finding) String() string {
+	prefix := "at"
+	if path := f.Node.Path().String(); path != "" {
+		prefix = "in " + path + " at"
+	}
+	return fmt.Sprintf("%s %s %s %s", f.Kind, Redact(f.Node.Value), prefix, f.Node.Location())
+}
+
+// Redact keeps the first four characters of a secret so it can be identified.
+func Redact(s string) string {
+	r := []rune(s)
+	if len(r) <= 8 {
+		return "****"
+	}
+	return string(r[:4]) + "..."
+}
+
+// Scan returns the string values below root that look like credentials, in document order.
+// Keys are not scanned.
+func Scan(root *slowjson.Node) []Finding {
+	var findings []Finding
+	var walk func(n *slowjson.Node)
+	walk = func(n *slowjson.Node) {
+		if n.Type == slowjson.NodeString && !n.IsKey() {
+			if kind, ok := Detect(n.Value); ok {
+				findings = append(findings, Finding{Kind: kind, Node: n})
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return findings
+}
+
+// Detect returns the kind of credential s looks like.
+func Detect(s string) (string, bool) {
+	for _, p := range Patterns {
+		if p.Regexp.MatchString(s) {
+			return p.Kind, true
+		}
+	}
+	switch {
+	case hexToken.MatchString(s) && Entropy(s) >= 3.5:
+		return KindHighEntropy, true
+	case base64Token.MatchString(s) && !hexToken.MatchString(s) && Entropy(s) >= 4.2:
+		return KindHighEntropy, true
+	}
+	return "", false
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
@@ -0,0 +1,5 @@
+// Package signing signs config documents with ed25519 and verifies them when they are loaded,
+// so a deployment only accepts config written by a trusted signer.
+// The signature covers the parsed document rather than its bytes: comments, whitespace and key order
+// may change without breaking it, any change to a key or value does.
+package signing
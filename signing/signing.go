@@ -0,0 +1,347 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Field is the top-level key holding an embedded signature, e.g.
+// {"port": 80, "$signature": {"signer": "ci", "signature": "..."}}.
+const Field = "$signature"
+
+// domain separates config signatures from other uses of the same key.
+const domain = "tracedconfig-signature\x00"
+
+// Signature is the signature of a config document by a named signer.
+type Signature struct {
+	Signer string
+	Value  []byte
+	// Node is the parsed signature, nil for signatures created by Sign.
+	Node *slowjson.Node
+}
+
+// String encodes the signature as the JSON object stored in Field or a detached signature file.
+func (s Signature) String() string {
+	n := &slowjson.Node{Type: slowjson.NodeObject}
+	for _, kv := range [][2]string{{"signer", s.Signer}, {"signature", base64.StdEncoding.EncodeToString(s.Value)}} {
+		n.Children = append(n.Children, &slowjson.Node{
+			Type:     slowjson.NodeString,
+			Value:    kv[0],
+			Children: []*slowjson.Node{{Type: slowjson.NodeString, Value: kv[1]}},
+		})
+	}
+	b, _ := slowjson.Marshal(n)
+	return string(b)
+}
+
+// ParseSignature reads a signature object written by Signature.String.
+func ParseSignature(n *slowjson.Node) (Signature, error) {
+	sig := Signature{Node: n}
+	if n.Type != slowjson.NodeObject {
+		return sig, n.Errorf("signature must be an object")
+	}
+	var value string
+	for _, key := range n.Children {
+		v := key.Children[0]
+		switch key.Value {
+		case "signer":
+			if v.Type != slowjson.NodeString || v.Value == "" {
+				return sig, v.Errorf("signer must be a non-empty string")
+			}
+			sig.Signer = v.Value
+		case "signature":
+			if v.Type != slowjson.NodeString {
+				return sig, v.Errorf("signature must be a string")
+			}
+			b, err := base64.StdEncoding.DecodeString(v.Value)
+			if err != nil {
+				return sig, v.Errorf("invalid signature: %v", err)
+			}
+			value, sig.Value = v.Value, b
+		default:
+			return sig, key.Errorf("unknown signature field %q", key.Value)
+		}
+	}
+	if sig.Signer == "" || value == "" {
+		return sig, n.Errorf("signature needs signer and signature")
+	}
+	return sig, nil
+}
+
+// GenerateKey returns a new key pair encoded as base64, the format ParsePrivateKey and ParsePublicKey read.
+func GenerateKey() (private, public string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.Seed()), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// ParsePrivateKey decodes a base64 private key, surrounding whitespace is ignored so keys can be read from files.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid private key: want %d bytes, got %d", ed25519.SeedSize, len(b))
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+// ParsePublicKey decodes a base64 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: want %d bytes, got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// PublicKey returns the base64 public key of a private key.
+func PublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// Keyring holds the public keys of trusted signers by name.
+type Keyring map[string]ed25519.PublicKey
+
+// ParseKeyring reads a keyring object mapping signer names to base64 public keys, e.g. {"ci": "..."}.
+func ParseKeyring(n *slowjson.Node) (Keyring, error) {
+	if n.Type != slowjson.NodeObject {
+		return nil, n.Errorf("keyring must be an object")
+	}
+	keys := Keyring{}
+	for _, key := range n.Children {
+		v := key.Children[0]
+		if v.Type != slowjson.NodeString {
+			return nil, v.Errorf("public key must be a string")
+		}
+		pub, err := ParsePublicKey(v.Value)
+		if err != nil {
+			return nil, v.Errorf("%w", err)
+		}
+		keys[key.Value] = pub
+	}
+	return keys, nil
+}
+
+// Sign signs the document root as signer. A Field member of root is not covered by the signature.
+func Sign(root *slowjson.Node, signer string, key ed25519.PrivateKey) (Signature, error) {
+	if signer == "" {
+		return Signature{}, fmt.Errorf("signer must not be empty")
+	}
+	msg, err := payload(root, signer)
+	if err != nil {
+		return Signature{}, err
+	}
+	return Signature{Signer: signer, Value: ed25519.Sign(key, msg)}, nil
+}
+
+// Verify checks that sig is a signature of root by a signer in keys.
+// A Field member of root is not covered by the signature.
+func (k Keyring) Verify(root *slowjson.Node, sig Signature) error {
+	pub, ok := k[sig.Signer]
+	if !ok {
+		return sig.errorf("unknown signer %q", sig.Signer)
+	}
+	msg, err := payload(root, sig.Signer)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, msg, sig.Value) {
+		return sig.errorf("signature by %q does not match, the config was modified after signing", sig.Signer)
+	}
+	return nil
+}
+
+func (s Signature) errorf(format string, args ...interface{}) error {
+	if s.Node != nil {
+		return s.Node.Errorf(format, args...)
+	}
+	return fmt.Errorf(format, args...)
+}
+
+// payload is the signed message: the signer and the document encoded with sorted keys, without Field.
+func payload(root *slowjson.Node, signer string) ([]byte, error) {
+	doc := root
+	if root.Type == slowjson.NodeObject {
+		doc = &slowjson.Node{Type: slowjson.NodeObject}
+		for _, key := range root.Children {
+			if key.Value != Field {
+				doc.Children = append(doc.Children, key)
+			}
+		}
+	}
+	b, err := slowjson.MarshalOptions{SortKeys: true}.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(domain+signer+"\x00"), b...), nil
+}
+
+// Extract returns the embedded signature of root and removes Field from root. A second Field member is
+// an error, it is not covered by the signature and would otherwise be loaded unsigned.
+func Extract(root *slowjson.Node) (Signature, error) {
+	if root.Type != slowjson.NodeObject {
+		return Signature{}, root.Errorf("config is not signed, %s needs an object", Field)
+	}
+	at := -1
+	for i, key := range root.Children {
+		if key.Value != Field {
+			continue
+		}
+		if at >= 0 {
+			return Signature{}, key.Errorf("duplicate %s", Field)
+		}
+		at = i
+	}
+	if at < 0 {
+		return Signature{}, root.Errorf("config is not signed, missing %s", Field)
+	}
+	sig, err := ParseSignature(root.Children[at].Children[0])
+	if err != nil {
+		return sig, err
+	}
+	root.Children = append(root.Children[:at:at], root.Children[at+1:]...)
+	return sig, nil
+}
+
+// Embed writes sig into the JSON document data as its Field member, replacing an existing one.
+// The rest of the document is kept byte for byte.
+func Embed(data []byte, sig Signature) ([]byte, error) {
+	root, err := slowjson.NewParser(string(data)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if root.Type != slowjson.NodeObject {
+		return nil, root.Errorf("cannot embed a signature, the document is not an object")
+	}
+	src := string(data)
+	for _, key := range root.Children {
+		if key.Value == Field {
+			v := key.Children[0]
+			return []byte(src[:v.StartOffset] + sig.String() + src[v.EndOffset:]), nil
+		}
+	}
+	member := fmt.Sprintf("%q: %s", Field, sig.String())
+	if len(root.Children) == 0 {
+		at := root.EndOffset - 1
+		return []byte(src[:at] + member + src[at:]), nil
+	}
+	last := root.Children[len(root.Children)-1]
+	lineStart := strings.LastIndexByte(src[:last.StartOffset], '\n') + 1
+	indent := src[lineStart:last.StartOffset]
+	sep := ",\n" + indent
+	if strings.TrimSpace(indent) != "" {
+		// the last member shares its line with other tokens
+		sep = ", "
+	}
+	at := last.Children[0].EndOffset
+	return []byte(src[:at] + sep + member + src[at:]), nil
+}
+
+// Source wraps a config source so it only loads when it carries an embedded signature by a signer in keys.
+// The signature is removed from the loaded tree and the signer is recorded in provenance as "signer".
+func Source(src tracedconfig.Source, keys Keyring) tracedconfig.Source {
+	return &source{Source: src, keys: keys}
+}
+
+// DetachedSource is like Source but reads the signature from the file sigPath, e.g. "app.json.sig".
+func DetachedSource(src tracedconfig.Source, keys Keyring, sigPath string) tracedconfig.Source {
+	return &source{Source: src, keys: keys, sigPath: sigPath}
+}
+
+type source struct {
+	tracedconfig.Source
+	keys    Keyring
+	sigPath string
+
+	mu     sync.Mutex
+	signer string
+}
+
+func (s *source) Load(ctx context.Context) (*slowjson.Node, error) {
+	n, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sig Signature
+	if s.sigPath != "" {
+		sn, err := slowjson.ParseFile(s.sigPath)
+		if err != nil {
+			return nil, err
+		}
+		if sig, err = ParseSignature(sn); err != nil {
+			return nil, err
+		}
+	} else if sig, err = Extract(n); err != nil {
+		return nil, err
+	}
+	if err := s.keys.Verify(n, sig); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.signer = sig.Signer
+	s.mu.Unlock()
+	return n, nil
+}
+
+func (s *source) Changed(ctx context.Context) (bool, error) {
+	return tracedconfig.SourceChanged(ctx, s.Source)
+}
+
+// Metadata records the signer of the last verified load next to the metadata of the wrapped source.
+func (s *source) Metadata() map[string]string {
+	meta := map[string]string{}
+	if ms, ok := s.Source.(tracedconfig.MetadataSource); ok {
+		for k, v := range ms.Metadata() {
+			meta[k] = v
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.signer != "" {
+		meta["signer"] = s.signer
+	}
+	return meta
+}
+
+// SignFile signs the JSON file at path as signer. When sigPath is empty the signature is embedded
+// into the file, otherwise it is written to sigPath. Files are replaced atomically and keep their permissions.
+func SignFile(path, sigPath, signer string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	p := slowjson.NewParser(string(data))
+	p.File = path
+	root, err := p.Parse()
+	if err != nil {
+		return err
+	}
+	sig, err := Sign(root, signer, key)
+	if err != nil {
+		return err
+	}
+	if sigPath != "" {
+		return tracedconfig.WriteFile(sigPath, []byte(sig.String()+"\n"), 0o644)
+	}
+	out, err := Embed(data, sig)
+	if err != nil {
+		return err
+	}
+	return tracedconfig.WriteFile(path, out, 0o644)
+}
@@ -0,0 +1,210 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/tracedconfigtest"
+)
+
+func testKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func parse(t *testing.T, s string) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.NewParser(s).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSignVerify(t *testing.T) {
+	key := testKey(t, 1)
+	keys := Keyring{"ci": key.Public().(ed25519.PublicKey)}
+	sig, err := Sign(parse(t, `{"port": 80, "hosts": ["a", "b"]}`), "ci", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		doc       string
+		signer    string
+		wantError string
+	}{
+		{"same document", `{"port": 80, "hosts": ["a", "b"]}`, "ci", ""},
+		{"formatting and order", "{\n  // web\n  \"hosts\": [\"a\",\"b\"],\n  \"port\": 80\n}", "ci", ""},
+		{"changed value", `{"port": 8080, "hosts": ["a", "b"]}`, "ci", `signature by "ci" does not match`},
+		{"added key", `{"port": 80, "hosts": ["a", "b"], "debug": true}`, "ci", "does not match"},
+		{"reordered array", `{"port": 80, "hosts": ["b", "a"]}`, "ci", "does not match"},
+		{"other signer", `{"port": 80, "hosts": ["a", "b"]}`, "dev", `unknown signer "dev"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sig
+			s.Signer = tt.signer
+			err := keys.Verify(parse(t, tt.doc), s)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+
+	wrong := Keyring{"ci": testKey(t, 2).Public().(ed25519.PublicKey)}
+	if err := wrong.Verify(parse(t, `{"port": 80, "hosts": ["a", "b"]}`), sig); err == nil {
+		t.Error("Verify() with another key should fail")
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	key := testKey(t, 1)
+	sig, err := Sign(parse(t, `{}`), "ci", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", `{}`, `{"$signature": ` + sig.String() + `}`},
+		{"indented", "{\n  \"a\": 1 // one\n}\n", "{\n  \"a\": 1,\n  \"$signature\": " + sig.String() + " // one\n}\n"},
+		{"single line", `{"a": 1}`, `{"a": 1, "$signature": ` + sig.String() + `}`},
+		{"replace", `{"a": 1, "$signature": {"signer": "old", "signature": "AA=="}}`, `{"a": 1, "$signature": ` + sig.String() + `}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Embed([]byte(tt.in), sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Embed() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+	if _, err := Embed([]byte(`[1]`), sig); err == nil {
+		t.Error("Embed() into an array should fail")
+	}
+}
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t, 1)
+	keys := Keyring{"ci": key.Public().(ed25519.PublicKey)}
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	embedded := write("embedded.json", "{\n  \"port\": 80\n}\n")
+	if err := SignFile(embedded, "", "ci", key); err != nil {
+		t.Fatal(err)
+	}
+	detached := write("detached.json", `{"host": "example.com"}`)
+	if err := SignFile(detached, detached+".sig", "ci", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(embedded, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// signing again replaces the embedded signature and keeps the mode
+	if err := SignFile(embedded, "", "ci", key); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(embedded); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("SignFile() mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	c := tracedconfig.NewConfig(
+		Source(tracedconfig.File(embedded), keys),
+		DetachedSource(tracedconfig.File(detached), keys, detached+".sig"),
+	)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Root().Keys(); strings.Join(got, ",") != "port,host" {
+		t.Errorf("keys = %v, the signature should be removed", got)
+	}
+	o, _ := c.Origin("port")
+	if got, want := o.String(), embedded+":2:11 ("+embedded+", signer=ci)"; got != want {
+		t.Errorf("Origin() = %q, want %q", got, want)
+	}
+
+	// tampering with a signed file fails the load and keeps the previous config
+	data, _ := os.ReadFile(embedded)
+	write("embedded.json", strings.Replace(string(data), "80", "81", 1))
+	err := c.Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "the config was modified after signing") {
+		t.Errorf("Load() error = %v", err)
+	}
+	write("embedded.json", `{"port": 80}`)
+	if err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "config is not signed") {
+		t.Errorf("Load() error = %v", err)
+	}
+
+	// a second signature member is not covered by the signature
+	write("embedded.json", strings.Replace(string(data), "\n}", ",\n  \"$signature\": {\"admin\": true}\n}", 1))
+	if err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "duplicate $signature") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestSource_Changed(t *testing.T) {
+	key := testKey(t, 1)
+	keys := Keyring{"ci": key.Public().(ed25519.PublicKey)}
+	remote := tracedconfigtest.NewSource("remote", `{"port": 80}`)
+	src := Source(remote, keys).(tracedconfig.ChangeDetector)
+	if ok, err := src.Changed(context.Background()); ok || err != nil {
+		t.Errorf("Changed() = %v, %v before a change", ok, err)
+	}
+	remote.Set(`{"port": 81}`)
+	if ok, err := src.Changed(context.Background()); !ok || err != nil {
+		t.Errorf("Changed() = %v, %v after a change", ok, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	priv, pub, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(priv + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PublicKey(key) != pub {
+		t.Errorf("PublicKey() = %s, want %s", PublicKey(key), pub)
+	}
+	keys, err := ParseKeyring(parse(t, `{"ci": "`+pub+`"}`))
+	if err != nil || len(keys["ci"]) != ed25519.PublicKeySize {
+		t.Fatalf("ParseKeyring() = %v, %v", keys, err)
+	}
+	if _, err := ParseKeyring(parse(t, `{"ci": "AAAA"}`)); err == nil || !strings.Contains(err.Error(), "want 32 bytes") {
+		t.Errorf("ParseKeyring() error = %v", err)
+	}
+	if _, err := ParsePrivateKey("not base64!"); err == nil {
+		t.Error("ParsePrivateKey() should fail on invalid input")
+	}
+}
@@ -0,0 +1,51 @@
+package slowjson
+
+// Without a fixed block size an Arena starts with small blocks and doubles them up to maxArenaBlock,
+// so a small parse does not pin a large block while a large one still makes few allocations.
+const (
+	minArenaBlock = 16
+	maxArenaBlock = 1024
+)
+
+// Arena allocates nodes in blocks instead of one by one, so parsing a large document makes a few large
+// allocations rather than one per value. This cuts allocation work and GC pressure for services that
+// reload large configs frequently. A block is freed as a unit once no node in it is referenced, i.e. when
+// the tree parsed with the arena is dropped. Use a new Arena per parse, see Parser.Arena.
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	block []Node
+	size  int // fixed block size, 0 to grow blocks
+	next  int // size of the next block when growing
+	count int
+}
+
+// NewArena creates an Arena allocating blockSize nodes at a time. When blockSize is not positive blocks
+// start small and double with each block, up to 1024 nodes.
+// A block is kept alive by any node in it, so size it for the documents parsed with it.
+func NewArena(blockSize int) *Arena {
+	if blockSize <= 0 {
+		return &Arena{next: minArenaBlock}
+	}
+	return &Arena{size: blockSize}
+}
+
+// Len returns how many nodes were allocated from the arena.
+func (a *Arena) Len() int {
+	return a.count
+}
+
+// alloc returns a zeroed node from the current block, starting a new block when it is full.
+func (a *Arena) alloc() *Node {
+	if len(a.block) == 0 {
+		size := a.size
+		if size == 0 {
+			size = a.next
+			a.next = min(a.next*2, maxArenaBlock)
+		}
+		a.block = make([]Node, size)
+	}
+	n := &a.block[0]
+	a.block = a.block[1:]
+	a.count++
+	return n
+}
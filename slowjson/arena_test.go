@@ -0,0 +1,43 @@
+package slowjson
+
+import "testing"
+
+func TestParser_Arena(t *testing.T) {
+	input := `{"a": [1, true, null, "x"], "b": {"c": 1.5}}`
+	want, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 3, 100} {
+		p := NewParser(input)
+		p.Arena = NewArena(size)
+		got, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse() with block %d error = %v", size, err)
+		}
+		if Print(got) != input || !Equal(got, want, EqualOptions{}) {
+			t.Errorf("Parse() with block %d = %s", size, Print(got))
+		}
+		if p.Arena.Len() != 11 {
+			t.Errorf("Arena.Len() = %d, want 11", p.Arena.Len())
+		}
+		c := got.Lookup(Path{{Key: "b"}, {Key: "c"}})
+		if c == nil || c.Location() != "line 1 col 40" || c.Parent.Parent != got.Children[1].Children[0] {
+			t.Errorf("b.c = %+v", c)
+		}
+	}
+}
+
+func TestArena_GrowingBlocks(t *testing.T) {
+	a := NewArena(0)
+	a.alloc()
+	if cap(a.block) != minArenaBlock-1 {
+		t.Errorf("first block holds %d free nodes, want %d", cap(a.block), minArenaBlock-1)
+	}
+	for i := 1; i < 5000; i++ {
+		a.alloc()
+	}
+	if a.next != maxArenaBlock {
+		t.Errorf("next block = %d, want %d", a.next, maxArenaBlock)
+	}
+}
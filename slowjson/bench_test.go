@@ -0,0 +1,171 @@
+package slowjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchInputs are the shapes parser changes are measured on, see internal/benchcmp to compare a run
+// with the committed baseline in testdata/bench.txt.
+var benchInputs = []struct {
+	name  string
+	input string
+}{
+	{"small", smallInput()},
+	{"large", largeInput(2000)},
+	{"deep", deepInput(500)},
+	{"strings", stringsInput(500)},
+}
+
+// smallInput is a typical hand-written service config with comments.
+func smallInput() string {
+	return `// service config
+{
+  "name": "api",
+  "server": {"host": "0.0.0.0", "port": 8080, "tls": false},
+  "database": {"url": "postgres://db.internal/app", "pool": 20, "timeout": "5s"},
+  "features": ["search", "billing"], /* rolled out */
+  "limits": {"rate": 100.5, "burst": 1e3}
+}`
+}
+
+// largeInput is an array of n generated records of mixed types.
+func largeInput(n int) string {
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+		fmt.Fprintf(&sb, `  {"id": %d, "name": "service-%d", "enabled": %t, "weight": %d.25, "tags": ["a", "b"], "owner": null}`, i, i, i%2 == 0, i)
+	}
+	sb.WriteString("\n]")
+	return sb.String()
+}
+
+// deepInput nests n objects and arrays alternately.
+func deepInput(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			sb.WriteString(`{"k": `)
+		} else {
+			sb.WriteString("[")
+		}
+	}
+	sb.WriteString("1")
+	for i := n - 1; i >= 0; i-- {
+		if i%2 == 0 {
+			sb.WriteString("}")
+		} else {
+			sb.WriteString("]")
+		}
+	}
+	return sb.String()
+}
+
+// stringsInput is an object of n long string values with escapes and non-ASCII text.
+func stringsInput(n int) string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, `"key%d": "%s \"quoted\" naïve café %s"`, i, strings.Repeat("lorem ipsum ", 8), strings.Repeat("x", i%50))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, in := range benchInputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(len(in.input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewParser(in.input).Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseArena(b *testing.B) {
+	for _, in := range benchInputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(len(in.input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := NewParser(in.input)
+				p.Arena = NewArena(0)
+				if _, err := p.Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, in := range benchInputs {
+		n, err := NewParser(in.input).Parse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(len(in.input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Marshal(n); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPrint(b *testing.B) {
+	for _, in := range benchInputs {
+		n, err := NewParser(in.input).Parse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(len(in.input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Print(n)
+			}
+		})
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	for _, in := range benchInputs {
+		x, err := NewParser(in.input).Parse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		y, err := NewParser(in.input).Parse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Diff(x, y)
+			}
+		})
+	}
+}
+
+func TestBenchInputs(t *testing.T) {
+	for _, in := range benchInputs {
+		if _, err := NewParser(in.input).Parse(); err != nil {
+			t.Errorf("%s: %v", in.name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package slowjson
+
+import (
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// parseAll runs parse for 0..n-1 with at most concurrency calls at a time, GOMAXPROCS when concurrency
+// is not positive. The nodes are in index order and the errors are joined in index order, so the result
+// does not depend on scheduling.
+func parseAll(n, concurrency int, parse func(i int) (*Node, error)) ([]*Node, error) {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	nodes := make([]*Node, n)
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				nodes[i], errs[i] = parse(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return nodes, Join(errs...)
+}
+
+// ParseFiles parses the files at paths, up to concurrency at a time, see ParseFile. The nodes are in the
+// order of paths, nil for files that failed, and the errors of all failed files are joined in that order.
+// A non-positive concurrency uses GOMAXPROCS. The files share an Interner.
+func ParseFiles(paths []string, concurrency int) ([]*Node, error) {
+	return ParseFilesFunc(paths, concurrency, os.ReadFile)
+}
+
+// ParseFilesFunc is ParseFiles reading the files with read, e.g. to limit their size.
+func ParseFilesFunc(paths []string, concurrency int, read func(path string) ([]byte, error)) ([]*Node, error) {
+	in := NewInterner()
+	return parseAll(len(paths), concurrency, func(i int) (*Node, error) {
+		b, err := read(paths[i])
+		if err != nil {
+			return nil, err
+		}
+		p := NewParser(string(b))
+		p.File = paths[i]
+		p.Interner = in
+		return p.Parse()
+	})
+}
+
+// ParseNDJSON parses newline-delimited JSON, one document per line, up to concurrency lines at a time.
+// Blank lines are skipped. file is recorded on the nodes, and their positions and Source refer to the
+// whole input, so a document on line 3 reports line 3. The documents are in input order, nil for lines
+// that failed, and the errors of all failed lines are joined in that order. The documents share an
+// Interner.
+func ParseNDJSON(r io.Reader, file string, concurrency int) ([]*Node, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	source := string(b)
+	type line struct {
+		text         string
+		number, from int
+	}
+	var lines []line
+	from := 0
+	for i, text := range strings.Split(source, "\n") {
+		if strings.TrimSpace(text) != "" {
+			lines = append(lines, line{text: text, number: i + 1, from: from})
+		}
+		from += len(text) + 1
+	}
+	in := NewInterner()
+	return parseAll(len(lines), concurrency, func(i int) (*Node, error) {
+		l := lines[i]
+		p := NewParser(l.text)
+		p.File = file
+		p.Interner = in
+		n, err := p.Parse()
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.Pos.Line += l.number - 1
+			pe.Pos.Offset += l.from
+		}
+		if err != nil {
+			return nil, err
+		}
+		relocate(n, source, l.number-1, l.from)
+		return n, nil
+	})
+}
+
+// ParseNDJSONFile parses the newline-delimited JSON file at path, see ParseNDJSON.
+func ParseNDJSONFile(path string, concurrency int) ([]*Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseNDJSON(f, path, concurrency)
+}
+
+// relocate moves the positions of n, parsed from a single line, lines and bytes down into source.
+func relocate(n *Node, source string, lines, bytes int) {
+	n.Source = source
+	n.StartLine += lines
+	n.EndLine += lines
+	n.StartOffset += bytes
+	n.EndOffset += bytes
+	for _, trivia := range [][]Trivia{n.Leading, n.Trailing, n.Inner} {
+		for i := range trivia {
+			trivia[i].StartLine += lines
+			trivia[i].StartOffset += bytes
+		}
+	}
+	for _, c := range n.Children {
+		relocate(c, source, lines, bytes)
+	}
+}
@@ -0,0 +1,82 @@
+package slowjson
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseNDJSON(t *testing.T) {
+	input := "{\"id\": 1}\n\n  {\"id\": 2, \"tags\": [\"a\"]} // second\r\n[true]\n"
+	for _, concurrency := range []int{0, 1, 3} {
+		docs, err := ParseNDJSON(strings.NewReader(input), "events.ndjson", concurrency)
+		if err != nil {
+			t.Fatalf("ParseNDJSON(%d) error = %v", concurrency, err)
+		}
+		if len(docs) != 3 {
+			t.Fatalf("ParseNDJSON(%d) = %d documents, want 3", concurrency, len(docs))
+		}
+		tag := docs[1].Lookup(Path{{Key: "tags"}, {Index: 0, IsIndex: true}})
+		if got := tag.Location(); got != "events.ndjson:3:22" {
+			t.Errorf("tag location = %s, want events.ndjson:3:22", got)
+		}
+		if got := input[tag.StartOffset:tag.EndOffset]; got != `"a"` {
+			t.Errorf("tag offsets point at %q", got)
+		}
+		if ctx := tag.DebugContext(0, 0); !strings.HasPrefix(ctx, "3:   {\"id\": 2") {
+			t.Errorf("DebugContext() = %q", ctx)
+		}
+		if docs[2].Type != NodeArray || docs[2].StartLine != 4 {
+			t.Errorf("third document = %+v", docs[2])
+		}
+	}
+}
+
+func TestParseNDJSON_Errors(t *testing.T) {
+	docs, err := ParseNDJSON(strings.NewReader("{\"a\": 1}\n{\"b\": tru}\n{\"c\": 3}\n[1,\n"), "in", 2)
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 2 {
+		t.Fatalf("ParseNDJSON() error = %v, want 2 errors", err)
+	}
+	var pe *ParseError
+	if !errors.As(multi.Errors[0], &pe) || pe.Pos.Line != 2 || pe.Pos.File != "in" {
+		t.Errorf("first error = %v, want line 2", multi.Errors[0])
+	}
+	if !errors.As(multi.Errors[1], &pe) || pe.Pos.Line != 4 {
+		t.Errorf("second error = %v, want line 4", multi.Errors[1])
+	}
+	if docs[0] == nil || docs[1] != nil || docs[2] == nil {
+		t.Errorf("ParseNDJSON() documents = %v, want nil for failed lines", docs)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%02d.json", i))
+		content := fmt.Sprintf(`{"n": %d}`, i)
+		if i == 7 {
+			content = `{"n": `
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	nodes, err := ParseFiles(paths, 4)
+	if err == nil || !strings.Contains(err.Error(), "07.json") {
+		t.Errorf("ParseFiles() error = %v, want the failure of 07.json", err)
+	}
+	for i, n := range nodes {
+		if i == 7 {
+			continue
+		}
+		if n == nil || n.File != paths[i] || n.Children[0].Children[0].Value != fmt.Sprint(i) {
+			t.Errorf("ParseFiles() node %d = %+v, want the content of %s", i, n, paths[i])
+		}
+	}
+}
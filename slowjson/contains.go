@@ -0,0 +1,72 @@
+package slowjson
+
+import "fmt"
+
+// Mismatch is the first place where a subset is not contained in a document, see Contains.
+type Mismatch struct {
+	Path Path
+	// Want is the value in the subset.
+	Want *Node
+	// Got is the value in the document, nil when it is missing.
+	Got *Node
+}
+
+// String formats the mismatch with the positions in both documents, e.g.
+// "server.port: want 443 at want.json:1:21, got 80 at app.json:3:13".
+func (m *Mismatch) String() string {
+	path := m.Path.String()
+	if path == "" {
+		path = "$"
+	}
+	if m.Got == nil {
+		return fmt.Sprintf("%s: want %s at %s, got nothing", path, compact(m.Want), m.Want.Location())
+	}
+	return fmt.Sprintf("%s: want %s at %s, got %s at %s", path, compact(m.Want), m.Want.Location(), compact(m.Got), m.Got.Location())
+}
+
+// Contains reports where subset is not contained in superset, nil when it is. Every key of a subset
+// object must be in the superset object with a contained value, other keys are ignored. Arrays must have
+// the same length with every element contained. Scalars must be equal, numbers by value so 1 matches 1.0.
+// Tests use it to assert a rendered config includes some settings without spelling out the rest.
+func Contains(superset, subset *Node) *Mismatch {
+	return contains(nil, superset, subset)
+}
+
+func contains(path Path, sup, sub *Node) *Mismatch {
+	mismatch := &Mismatch{Path: path, Want: sub, Got: sup}
+	if sup.Type != sub.Type {
+		return mismatch
+	}
+	switch sub.Type {
+	case NodeObject:
+		have, _ := members(sup)
+		want, keys := members(sub)
+		for _, k := range keys {
+			got, ok := have[k]
+			if !ok {
+				return &Mismatch{Path: path.Key(k), Want: want[k]}
+			}
+			if m := contains(path.Key(k), got, want[k]); m != nil {
+				return m
+			}
+		}
+	case NodeArray:
+		if len(sup.Children) != len(sub.Children) {
+			return mismatch
+		}
+		for i := range sub.Children {
+			if m := contains(path.Index(i), sup.Children[i], sub.Children[i]); m != nil {
+				return m
+			}
+		}
+	case NodeNumber:
+		if sup.Value != sub.Value && !sameNumber(sup.Value, sub.Value) {
+			return mismatch
+		}
+	default:
+		if sup.Value != sub.Value {
+			return mismatch
+		}
+	}
+	return nil
+}
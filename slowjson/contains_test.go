@@ -0,0 +1,46 @@
+package slowjson
+
+import "testing"
+
+func TestContains(t *testing.T) {
+	doc := "{\n  \"server\": {\"host\": \"a\", \"port\": 80, \"tags\": [\"x\", \"y\"]},\n  \"debug\": false\n}"
+	tests := []struct {
+		name   string
+		subset string
+		want   string
+	}{
+		{"same", doc, ""},
+		{"subset of keys", `{"server": {"port": 80.0}}`, ""},
+		{"empty object", `{}`, ""},
+		{"different value", `{"server": {"port": 443}}`, "server.port: want 443 at want.json:1:21, got 80 at app.json:2:35"},
+		{"missing key", `{"server": {"tls": true}}`, "server.tls: want true at want.json:1:20, got nothing"},
+		{"different type", `{"debug": "false"}`, `debug: want "false" at want.json:1:11, got false at app.json:3:12`},
+		{"array length", `{"server": {"tags": ["x"]}}`, `server.tags: want ["x"] at want.json:1:21, got ["x","y"] at app.json:2:47`},
+		{"array element", `{"server": {"tags": ["x", "z"]}}`, `server.tags[1]: want "z" at want.json:1:27, got "y" at app.json:2:53`},
+		{"root", `[]`, "$: want [] at want.json:1:1, got " + `{"server":{"host":"a","port":80,"tags":["x","y"]},"debug":false} at app.json:1:1`},
+	}
+	p := NewParser(doc)
+	p.File = "app.json"
+	sup, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(tt.subset)
+			p.File = "want.json"
+			sub, err := p.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := Contains(sup, sub)
+			got := ""
+			if m != nil {
+				got = m.String()
+			}
+			if got != tt.want {
+				t.Errorf("Contains() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package slowjson
+
+import "unicode/utf8"
+
+// cursor walks the input rune by rune and keeps track of line, column and byte offset.
+// It is shared by Parser and Scanner.
+type cursor struct {
+	runes  []rune
+	pos    int
+	line   int
+	col    int
+	offset int // byte offset of pos in source
+	length int
+	// original input
+	source string
+}
+
+func newCursor(input string) cursor {
+	r := []rune(input)
+	return cursor{
+		runes:  r,
+		pos:    0,
+		line:   1, // 1-based indexing for line
+		col:    1, // 1-based indexing for column
+		length: len(r),
+		source: input,
+	}
+}
+
+func (c *cursor) peekChar() rune {
+	if c.pos >= c.length {
+		return 0
+	}
+	return c.runes[c.pos]
+}
+
+// peekCharAt returns the rune i positions ahead without consuming anything.
+func (c *cursor) peekCharAt(i int) rune {
+	if c.pos+i >= c.length {
+		return 0
+	}
+	return c.runes[c.pos+i]
+}
+
+func (c *cursor) consumeChar() {
+	if c.pos >= c.length {
+		return
+	}
+
+	ch := c.runes[c.pos]
+	c.pos++
+	// update line/col
+	if ch == '\n' {
+		c.line++
+		c.col = 1
+	} else {
+		c.col++
+	}
+	// An invalid byte decodes to U+FFFD, so the width comes from the source rather than the rune.
+	_, w := utf8.DecodeRuneInString(c.source[c.offset:])
+	c.offset += w
+}
+
+// remaining returns the unread input, slicing the source so peeking at literals does not copy it.
+func (c *cursor) remaining() string {
+	if c.pos >= c.length {
+		return ""
+	}
+	return c.source[c.offset:]
+}
+
+func (c *cursor) isEOF() bool {
+	return c.pos >= c.length
+}
@@ -0,0 +1,185 @@
+package slowjson
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ChangeKind is the kind of a structural change between two trees.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeModified
+	// ChangeReordered means an object has the same keys in a different order.
+	ChangeReordered
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	case ChangeReordered:
+		return "reordered"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a difference between two trees at Path.
+// Old is nil for added values and New is nil for removed values.
+type Change struct {
+	Kind ChangeKind
+	Path Path
+	Old  *Node
+	New  *Node
+}
+
+// String formats the change on one line, e.g. "~ server.port: 80 -> 8080".
+func (c Change) String() string {
+	path := c.Path.String()
+	if path == "" {
+		path = "$"
+	}
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", path, compact(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", path, compact(c.Old))
+	case ChangeReordered:
+		return fmt.Sprintf("~ %s: keys reordered", path)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, compact(c.Old), compact(c.New))
+	}
+}
+
+func compact(n *Node) string {
+	b, err := Marshal(n)
+	if err != nil {
+		return n.Value
+	}
+	return string(b)
+}
+
+// Diff compares two trees, reporting key reordering as a change. See DiffOptions.
+func Diff(a, b *Node) []Change {
+	return DiffOptions{}.Diff(a, b)
+}
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// IgnoreOrder does not report objects whose keys only differ in order.
+	IgnoreOrder bool
+	// NumericValue compares numbers by value so 1, 1.0 and 1e0 are equal.
+	NumericValue bool
+}
+
+// Diff compares the value trees a and b structurally, ignoring formatting and comments.
+// Objects are compared by key, with the last duplicate winning like in Lookup, arrays by index.
+// Changes are ordered by their position in a, added keys follow the keys of a.
+func (o DiffOptions) Diff(a, b *Node) []Change {
+	var changes []Change
+	o.diff(&changes, nil, a, b)
+	return changes
+}
+
+func (o DiffOptions) diff(changes *[]Change, path Path, a, b *Node) {
+	if a.Type != b.Type {
+		*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+		return
+	}
+	switch a.Type {
+	case NodeObject:
+		am, aKeys := members(a)
+		bm, bKeys := members(b)
+		var common []string
+		for _, k := range aKeys {
+			child := path.Key(k)
+			if bv, ok := bm[k]; ok {
+				common = append(common, k)
+				o.diff(changes, child, am[k], bv)
+			} else {
+				*changes = append(*changes, Change{Kind: ChangeRemoved, Path: child, Old: am[k]})
+			}
+		}
+		var bCommon []string
+		for _, k := range bKeys {
+			if _, ok := am[k]; ok {
+				bCommon = append(bCommon, k)
+			} else {
+				*changes = append(*changes, Change{Kind: ChangeAdded, Path: path.Key(k), New: bm[k]})
+			}
+		}
+		if !o.IgnoreOrder {
+			for i := range common {
+				if common[i] != bCommon[i] {
+					*changes = append(*changes, Change{Kind: ChangeReordered, Path: path, Old: a, New: b})
+					break
+				}
+			}
+		}
+	case NodeArray:
+		for i := 0; i < len(a.Children) || i < len(b.Children); i++ {
+			child := path.Index(i)
+			switch {
+			case i >= len(b.Children):
+				*changes = append(*changes, Change{Kind: ChangeRemoved, Path: child, Old: a.Children[i]})
+			case i >= len(a.Children):
+				*changes = append(*changes, Change{Kind: ChangeAdded, Path: child, New: b.Children[i]})
+			default:
+				o.diff(changes, child, a.Children[i], b.Children[i])
+			}
+		}
+	case NodeNumber:
+		if a.Value != b.Value && !(o.NumericValue && sameNumber(a.Value, b.Value)) {
+			*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+		}
+	default:
+		if a.Value != b.Value {
+			*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+		}
+	}
+}
+
+// sameNumber reports whether two number literals have the same exact value.
+func sameNumber(a, b string) bool {
+	x, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return false
+	}
+	y, ok := new(big.Rat).SetString(b)
+	return ok && x.Cmp(y) == 0
+}
+
+// EqualOptions configures Equal.
+type EqualOptions struct {
+	// NumericValue compares numbers by value so 1, 1.0 and 1e0 are equal, by default they must be written the same.
+	NumericValue bool
+}
+
+// Equal reports whether a and b hold the same values, ignoring formatting, comments and the order of object keys.
+// It is Diff finding no changes with IgnoreOrder set.
+func Equal(a, b *Node, opts EqualOptions) bool {
+	return len(DiffOptions{IgnoreOrder: true, NumericValue: opts.NumericValue}.Diff(a, b)) == 0
+}
+
+// members returns the value of each key, the last duplicate winning, and the keys in order of first appearance.
+func members(n *Node) (map[string]*Node, []string) {
+	m := make(map[string]*Node, len(n.Children))
+	var keys []string
+	for _, key := range n.Children {
+		if len(key.Children) == 0 {
+			continue
+		}
+		if _, ok := m[key.Value]; !ok {
+			keys = append(keys, key.Value)
+		}
+		m[key.Value] = key.Children[0]
+	}
+	return m, keys
+}
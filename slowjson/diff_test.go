@@ -0,0 +1,95 @@
+package slowjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		opts DiffOptions
+		want []string
+	}{
+		{"equal ignoring formatting", `{"a": 1, /* c */ "b": [true]}`, "{\n\"a\":1,\"b\":[ true ]}", DiffOptions{}, nil},
+		{"modified", `{"server": {"port": 80}}`, `{"server": {"port": 8080}}`, DiffOptions{}, []string{
+			"~ server.port: 80 -> 8080",
+		}},
+		{"type change", `{"a": "1"}`, `{"a": 1}`, DiffOptions{}, []string{`~ a: "1" -> 1`}},
+		{"added and removed", `{"a": 1, "b": 2}`, `{"b": 2, "c": {"d": null}}`, DiffOptions{}, []string{
+			"- a: 1",
+			`+ c: {"d":null}`,
+		}},
+		{"arrays", `{"l": [1, 2, 3]}`, `{"l": [1, 5]}`, DiffOptions{}, []string{
+			"~ l[1]: 2 -> 5",
+			"- l[2]: 3",
+		}},
+		{"array grows", `[1]`, `[1, 2]`, DiffOptions{}, []string{"+ [1]: 2"}},
+		{"reordered", `{"a": 1, "b": 2}`, `{"b": 2, "a": 1}`, DiffOptions{}, []string{"~ $: keys reordered"}},
+		{"ignore order", `{"x": {"a": 1, "b": 2}}`, `{"x": {"b": 2, "a": 1}}`, DiffOptions{IgnoreOrder: true}, nil},
+		{"duplicate keys", `{"a": 1, "a": 2}`, `{"a": 2}`, DiffOptions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewParser(tt.a).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := NewParser(tt.b).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range tt.opts.Diff(a, b) {
+				got = append(got, c.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Diff() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiff_Nodes(t *testing.T) {
+	a, _ := NewParser(`{"port": 80}`).Parse()
+	b, _ := NewParser("{\n  \"port\": 81\n}").Parse()
+	changes := Diff(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("Diff() = %v", changes)
+	}
+	c := changes[0]
+	if c.Kind != ChangeModified || c.Kind.String() != "modified" || c.Old.StartLine != 1 || c.New.StartLine != 2 {
+		t.Errorf("change = %+v", c)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		opts EqualOptions
+		want bool
+	}{
+		{`{"a": 1, "b": [true, null]}`, "{\n  // moved\n  \"b\": [ true, null ],\n  \"a\": 1\n}", EqualOptions{}, true},
+		{`{"a": {"x": 1, "y": 2}}`, `{"a": {"y": 2, "x": 1}}`, EqualOptions{}, true},
+		{`{"a": 1}`, `{"a": 1.0}`, EqualOptions{}, false},
+		{`{"a": 1}`, `{"a": 1.0}`, EqualOptions{NumericValue: true}, true},
+		{`[100, 0.5]`, `[1e2, 5E-1]`, EqualOptions{NumericValue: true}, true},
+		{`[1, 2]`, `[2, 1]`, EqualOptions{NumericValue: true}, false},
+		{`{"a": "1"}`, `{"a": 1}`, EqualOptions{NumericValue: true}, false},
+		{`{"a": 1}`, `{"a": 1, "b": null}`, EqualOptions{}, false},
+	}
+	for _, tt := range tests {
+		a, err := NewParser(tt.a).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := NewParser(tt.b).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Equal(a, b, tt.opts); got != tt.want {
+			t.Errorf("Equal(%s, %s, %+v) = %v, want %v", tt.a, tt.b, tt.opts, got, tt.want)
+		}
+	}
+}
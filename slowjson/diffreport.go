@@ -0,0 +1,114 @@
+package slowjson
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// diffSection is the changes below one top-level key.
+type diffSection struct {
+	name    string
+	changes []Change
+}
+
+// sections groups changes by their top-level key in order of appearance, changes of the root are in "$".
+func sections(changes []Change) []diffSection {
+	var out []diffSection
+	index := map[string]int{}
+	for _, c := range changes {
+		name := "$"
+		if len(c.Path) > 0 {
+			name = c.Path[:1].String()
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, diffSection{name: name})
+		}
+		out[i].changes = append(out[i].changes, c)
+	}
+	return out
+}
+
+// reportValue is the value side of a change in a report, empty for a missing side.
+func reportValue(n *Node, kind ChangeKind) (value, location string) {
+	if n == nil {
+		return "", ""
+	}
+	if kind == ChangeReordered {
+		return "keys reordered", n.Location()
+	}
+	value = compact(n)
+	if len(value) > 80 {
+		value = value[:77] + "..."
+	}
+	return value, n.Location()
+}
+
+// MarkdownDiff renders changes as Markdown for pull requests and tickets: a section per top-level key with
+// a table of the changed paths, their old and new values and where each value is written.
+func MarkdownDiff(changes []Change) string {
+	if len(changes) == 0 {
+		return "No changes.\n"
+	}
+	var sb strings.Builder
+	for i, s := range sections(changes) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "### %s\n\n| | Path | Old | New |\n|---|---|---|---|\n", markdownEscape(s.name))
+		for _, c := range s.changes {
+			path := c.Path.String()
+			if path == "" {
+				path = "$"
+			}
+			fmt.Fprintf(&sb, "| %s | `%s` | %s | %s |\n", c.Kind, markdownEscape(path),
+				markdownCell(reportValue(c.Old, c.Kind)), markdownCell(reportValue(c.New, c.Kind)))
+		}
+	}
+	return sb.String()
+}
+
+func markdownCell(value, location string) string {
+	if location == "" {
+		return ""
+	}
+	return fmt.Sprintf("`%s`<br>%s", markdownEscape(value), markdownEscape(location))
+}
+
+// markdownEscape keeps text from breaking the table or the code spans.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "`", "'", "\n", " ").Replace(s)
+}
+
+// HTMLDiff renders changes as an HTML fragment, a section per top-level key with a table of the changed
+// paths, their old and new values and where each value is written. Rows carry the class of their kind,
+// e.g. "modified", for styling.
+func HTMLDiff(changes []Change) string {
+	if len(changes) == 0 {
+		return "<p>No changes.</p>\n"
+	}
+	var sb strings.Builder
+	for _, s := range sections(changes) {
+		fmt.Fprintf(&sb, "<section>\n<h3>%s</h3>\n<table>\n<tr><th></th><th>Path</th><th>Old</th><th>New</th></tr>\n", html.EscapeString(s.name))
+		for _, c := range s.changes {
+			path := c.Path.String()
+			if path == "" {
+				path = "$"
+			}
+			fmt.Fprintf(&sb, "<tr class=%q><td>%s</td><td><code>%s</code></td><td>%s</td><td>%s</td></tr>\n", c.Kind, c.Kind,
+				html.EscapeString(path), htmlCell(reportValue(c.Old, c.Kind)), htmlCell(reportValue(c.New, c.Kind)))
+		}
+		sb.WriteString("</table>\n</section>\n")
+	}
+	return sb.String()
+}
+
+func htmlCell(value, location string) string {
+	if location == "" {
+		return ""
+	}
+	return fmt.Sprintf("<code>%s</code><br><small>%s</small>", html.EscapeString(value), html.EscapeString(location))
+}
@@ -0,0 +1,51 @@
+package slowjson
+
+import (
+	"testing"
+)
+
+func diffFiles(t *testing.T, a, b string) []Change {
+	t.Helper()
+	pa := NewParser(a)
+	pa.File = "old.json"
+	na, err := pa.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb := NewParser(b)
+	pb.File = "new.json"
+	nb, err := pb.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Diff(na, nb)
+}
+
+func TestMarkdownDiff(t *testing.T) {
+	changes := diffFiles(t, `{"server": {"port": 80, "host": "a|b"}, "debug": true}`, `{"server": {"port": 8080}, "debug": true, "tls": {}}`)
+	want := "### server\n\n" +
+		"| | Path | Old | New |\n|---|---|---|---|\n" +
+		"| modified | `server.port` | `80`<br>old.json:1:21 | `8080`<br>new.json:1:21 |\n" +
+		"| removed | `server.host` | `\"a\\|b\"`<br>old.json:1:33 |  |\n" +
+		"\n### tls\n\n" +
+		"| | Path | Old | New |\n|---|---|---|---|\n" +
+		"| added | `tls` |  | `{}`<br>new.json:1:50 |\n"
+	if got := MarkdownDiff(changes); got != want {
+		t.Errorf("MarkdownDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := MarkdownDiff(nil); got != "No changes.\n" {
+		t.Errorf("MarkdownDiff(nil) = %q", got)
+	}
+}
+
+func TestHTMLDiff(t *testing.T) {
+	changes := diffFiles(t, `{"a": "<b>"}`, `{"a": "<i>"}`)
+	want := "<section>\n<h3>a</h3>\n<table>\n<tr><th></th><th>Path</th><th>Old</th><th>New</th></tr>\n" +
+		"<tr class=\"modified\"><td>modified</td><td><code>a</code></td>" +
+		"<td><code>&#34;\\u003cb\\u003e&#34;</code><br><small>old.json:1:7</small></td>" +
+		"<td><code>&#34;\\u003ci\\u003e&#34;</code><br><small>new.json:1:7</small></td></tr>\n" +
+		"</table>\n</section>\n"
+	if got := HTMLDiff(changes); got != want {
+		t.Errorf("HTMLDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package slowjson_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+	"github.com/at15/tracedconfig/tracedconfigtest"
+)
+
+// TestMarshal_Golden encodes every input in testdata/encode with each set of options and compares the
+// output with testdata/encode/<input>.<options>.golden. Golden compares JSON by value, the layout of the
+// output is compared byte for byte here. Run TRACEDCONFIG_UPDATE_GOLDEN=1 go test after changing the
+// encoder and review the golden diffs.
+func TestMarshal_Golden(t *testing.T) {
+	modes := []struct {
+		name string
+		opts slowjson.MarshalOptions
+	}{
+		{"compact", slowjson.MarshalOptions{}},
+		{"sorted", slowjson.MarshalOptions{SortKeys: true}},
+		{"indent", slowjson.MarshalOptions{Indent: "  "}},
+		{"indent-sorted", slowjson.MarshalOptions{Indent: "\t", SortKeys: true}},
+	}
+	inputs, err := filepath.Glob("testdata/encode/*.json*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden") {
+			continue
+		}
+		n, err := slowjson.ParseFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range modes {
+			t.Run(filepath.Base(input)+"/"+m.name, func(t *testing.T) {
+				b, err := m.opts.Marshal(n)
+				if err != nil {
+					t.Fatalf("Marshal() error = %v", err)
+				}
+				golden := input + "." + m.name + ".golden"
+				tracedconfigtest.Golden(t, golden, append(b, '\n'))
+				if want, err := os.ReadFile(golden); err == nil && string(want) != string(b)+"\n" {
+					t.Errorf("Marshal() =\n%s\nwant the layout of %s\n%s", b, golden, want)
+				}
+			})
+		}
+	}
+}
+
+// TestPrint_Golden checks that printing keeps every input in testdata/encode as written.
+func TestPrint_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/encode/*.json*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden") {
+			continue
+		}
+		want, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := tracedconfigtest.MustParse(t, input, string(want))
+		if got := slowjson.Print(n); got != string(want) {
+			t.Errorf("Print(%s) =\n%s\nwant\n%s", input, got, want)
+		}
+	}
+}
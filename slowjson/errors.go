@@ -0,0 +1,124 @@
+package slowjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is a place in the input, lines and columns are 1-based and zero for binary input.
+type Position struct {
+	File   string
+	Line   int
+	Col    int
+	Offset int
+}
+
+// String formats the position like Node.Location.
+func (p Position) String() string {
+	switch {
+	case p.Line == 0 && p.File != "":
+		return fmt.Sprintf("%s offset %d", p.File, p.Offset)
+	case p.Line == 0:
+		return fmt.Sprintf("offset %d", p.Offset)
+	case p.File != "":
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+	default:
+		return fmt.Sprintf("line %d col %d", p.Line, p.Col)
+	}
+}
+
+// Positioned is implemented by errors pointing into the input, use errors.As to find them:
+//
+//	var p slowjson.Positioned
+//	if errors.As(err, &p) { fmt.Println(p.Position().Line) }
+type Positioned interface {
+	error
+	Position() Position
+}
+
+// ParseError is a syntax error.
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+// Error formats the error as "msg at line L col C", with the file when it is known.
+func (e *ParseError) Error() string {
+	return e.Msg + " at " + e.Pos.String()
+}
+
+// Position returns where parsing stopped.
+func (e *ParseError) Position() Position {
+	return e.Pos
+}
+
+// ValidationError is an error about a parsed value, e.g. a type mismatch while decoding or a failed
+// check. Node.Errorf creates it.
+type ValidationError struct {
+	// Path is the key path of the node, e.g. "servers[0].port".
+	Path Path
+	Pos  Position
+	// Node is the value the error is about.
+	Node *Node
+	Err  error
+	// Code is the stable diagnostic code of the error, e.g. "TC2003" for type mismatches, and may be empty.
+	Code string
+}
+
+// Error formats the error as "path: err at location".
+func (e *ValidationError) Error() string {
+	if len(e.Path) > 0 {
+		return fmt.Sprintf("%s: %v at %s", e.Path, e.Err, e.Pos)
+	}
+	return fmt.Sprintf("%v at %s", e.Err, e.Pos)
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns the start of the node.
+func (e *ValidationError) Position() Position {
+	return e.Pos
+}
+
+// MultiError holds several errors, e.g. every field that failed to decode.
+// errors.Is and errors.As look into each of them.
+type MultiError struct {
+	Errors []error
+}
+
+// Error lists the errors one per line.
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// Join returns nil for no errors, the error itself for one and a MultiError otherwise.
+// Nested MultiErrors are flattened.
+func Join(errs ...error) error {
+	var flat []error
+	for _, err := range errs {
+		if m, ok := err.(*MultiError); ok {
+			flat = append(flat, m.Errors...)
+		} else if err != nil {
+			flat = append(flat, err)
+		}
+	}
+	switch len(flat) {
+	case 0:
+		return nil
+	case 1:
+		return flat[0]
+	}
+	return &MultiError{Errors: flat}
+}
@@ -0,0 +1,71 @@
+package slowjson
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	p := NewParser("{\n  \"a\": tru\n}")
+	p.File = "app.json"
+	_, err := p.Parse()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Parse() error = %v, want *ParseError", err)
+	}
+	want := Position{File: "app.json", Line: 2, Col: 8, Offset: 9}
+	if perr.Pos != want {
+		t.Errorf("Pos = %+v, want %+v", perr.Pos, want)
+	}
+	if got := err.Error(); got != "invalid boolean at app.json:2:8" {
+		t.Errorf("Error() = %q", got)
+	}
+	var pos Positioned
+	if !errors.As(err, &pos) || pos.Position() != want {
+		t.Errorf("errors.As(Positioned) failed for %v", err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	root, err := NewParser(`{"servers": [{"port": "x"}]}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := root.Get("servers[0].port")
+	err = port.Errorf("read port: %w", io.ErrUnexpectedEOF)
+	if got, want := err.Error(), "servers[0].port: read port: unexpected EOF at line 1 col 23"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("errors.Is() = false for the wrapped error")
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Node != port || verr.Path.String() != "servers[0].port" {
+		t.Errorf("errors.As(*ValidationError) = %+v", verr)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+	if err := Join(nil, nil); err != nil {
+		t.Errorf("Join(nil, nil) = %v, want nil", err)
+	}
+	if err := Join(nil, a); err != a {
+		t.Errorf("Join(nil, a) = %v, want a", err)
+	}
+	err := Join(a, Join(b, nil, c))
+	var m *MultiError
+	if !errors.As(err, &m) || len(m.Errors) != 3 {
+		t.Fatalf("Join() = %#v, want a flat MultiError of 3", err)
+	}
+	for _, e := range []error{a, b, c} {
+		if !errors.Is(err, e) {
+			t.Errorf("errors.Is(%v) = false", e)
+		}
+	}
+	if got := strings.Split(err.Error(), "\n"); len(got) != 3 {
+		t.Errorf("Error() = %q, want one line per error", err.Error())
+	}
+}
@@ -0,0 +1,215 @@
+package slowjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Flatten returns a map of the scalars below n keyed by their path relative to n, see Path.String, e.g.
+// "server.port" or "hosts[0]". Strings are kept as they are, other scalars as written in the source and
+// null as "null". Empty objects and arrays are "{}" and "[]" so Unflatten can restore them.
+func Flatten(n *Node) map[string]string {
+	out := map[string]string{}
+	var walk func(n *Node, p Path)
+	walk = func(n *Node, p Path) {
+		switch n.Type {
+		case NodeObject:
+			if len(n.Children) == 0 {
+				out[p.String()] = "{}"
+			}
+			for _, key := range n.Children {
+				if len(key.Children) > 0 {
+					walk(key.Children[0], p.Key(key.Value))
+				}
+			}
+		case NodeArray:
+			if len(n.Children) == 0 {
+				out[p.String()] = "[]"
+			}
+			for i, elem := range n.Children {
+				walk(elem, p.Index(i))
+			}
+		case NodeNull:
+			out[p.String()] = "null"
+		default:
+			out[p.String()] = n.Value
+		}
+	}
+	walk(n, nil)
+	return out
+}
+
+// UnflattenOptions configures Unflatten.
+type UnflattenOptions struct {
+	// InferTypes turns values that are JSON numbers, true, false, null, {} or [] into those instead of
+	// strings, which reverses Flatten for trees without such strings.
+	InferTypes bool
+	// File is set on the nodes so errors name where the flat values came from, e.g. "consul:app/".
+	File string
+}
+
+// Unflatten builds a tree from flat paths and values, the inverse of Flatten, with every value a string.
+func Unflatten(m map[string]string) (*Node, error) {
+	return UnflattenOptions{}.Unflatten(m)
+}
+
+// Unflatten builds a tree from flat paths and values, the inverse of Flatten. Paths are parsed with
+// ParsePath, object keys are sorted and the indexes of an array must run from 0 without gaps. A path
+// that is both a value and the parent of another, e.g. "a" and "a.b", is an error.
+func (o UnflattenOptions) Unflatten(m map[string]string) (*Node, error) {
+	type entry struct {
+		path  Path
+		value string
+	}
+	entries := make([]entry, 0, len(m))
+	for k, v := range m {
+		p, err := ParsePath(k)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{p, v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return comparePaths(entries[i].path, entries[j].path) < 0 })
+
+	var root *Node
+	for _, e := range entries {
+		leaf := o.value(e.value)
+		if len(e.path) == 0 {
+			// sorted first, a later path finds it is not a container
+			root = leaf
+			continue
+		}
+		if root == nil {
+			root = o.container(e.path[0])
+		}
+		parent := root
+		for i, seg := range e.path {
+			if err := o.checkContainer(parent, seg, e.path[:i]); err != nil {
+				return nil, err
+			}
+			last := i == len(e.path)-1
+			child := o.child(parent, seg)
+			switch {
+			case child != nil && last:
+				return nil, fmt.Errorf("%s is set twice", e.path[:i+1])
+			case child != nil:
+				parent = child
+				continue
+			case seg.IsIndex && seg.Index != len(parent.Children):
+				return nil, fmt.Errorf("%s is set without %s", e.path[:i+1], e.path[:i].Index(len(parent.Children)))
+			}
+			next := leaf
+			if !last {
+				next = o.container(e.path[i+1])
+			}
+			o.add(parent, seg, next)
+			parent = next
+		}
+	}
+	if root == nil {
+		root = &Node{Type: NodeObject, File: o.File}
+	}
+	return root, nil
+}
+
+// value converts a flat value to a node.
+func (o UnflattenOptions) value(v string) *Node {
+	n := &Node{Type: NodeString, Value: v, File: o.File}
+	if !o.InferTypes {
+		return n
+	}
+	switch v {
+	case "true", "false":
+		n.Type = NodeBoolean
+	case "null":
+		n.Type = NodeNull
+	case "{}":
+		n.Type, n.Value = NodeObject, ""
+	case "[]":
+		n.Type, n.Value = NodeArray, ""
+	default:
+		var num json.Number
+		if json.Unmarshal([]byte(v), &num) == nil && string(num) == v {
+			n.Type = NodeNumber
+		}
+	}
+	return n
+}
+
+// container returns the object or array holding seg.
+func (o UnflattenOptions) container(seg PathSegment) *Node {
+	if seg.IsIndex {
+		return &Node{Type: NodeArray, File: o.File}
+	}
+	return &Node{Type: NodeObject, File: o.File}
+}
+
+// checkContainer reports a parent that cannot hold seg, e.g. a string or an array given a key.
+func (o UnflattenOptions) checkContainer(parent *Node, seg PathSegment, p Path) error {
+	name := p.String()
+	if name == "" {
+		name = "the root"
+	}
+	switch {
+	case parent.Type != NodeObject && parent.Type != NodeArray:
+		return fmt.Errorf("%s is both a value and a container", name)
+	case seg.IsIndex && parent.Type != NodeArray:
+		return fmt.Errorf("%s is both an object and an array", name)
+	case !seg.IsIndex && parent.Type != NodeObject:
+		return fmt.Errorf("%s is both an array and an object", name)
+	}
+	return nil
+}
+
+func (o UnflattenOptions) child(parent *Node, seg PathSegment) *Node {
+	if seg.IsIndex {
+		if seg.Index < len(parent.Children) {
+			return parent.Children[seg.Index]
+		}
+		return nil
+	}
+	for _, key := range parent.Children {
+		if key.Value == seg.Key {
+			return key.Children[0]
+		}
+	}
+	return nil
+}
+
+func (o UnflattenOptions) add(parent *Node, seg PathSegment, n *Node) {
+	if seg.IsIndex {
+		n.Parent = parent
+		parent.Children = append(parent.Children, n)
+		return
+	}
+	key := &Node{Type: NodeString, Value: seg.Key, Parent: parent, File: o.File, Children: []*Node{n}}
+	n.Parent = key
+	parent.Children = append(parent.Children, key)
+}
+
+// comparePaths orders paths segment by segment, indexes numerically and keys lexically.
+func comparePaths(a, b Path) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[i], b[i]
+		switch {
+		case x.IsIndex && y.IsIndex && x.Index != y.Index:
+			if x.Index < y.Index {
+				return -1
+			}
+			return 1
+		case x.IsIndex != y.IsIndex:
+			// keep mixed segments together, checkContainer reports the conflict
+			if x.IsIndex {
+				return -1
+			}
+			return 1
+		case !x.IsIndex && x.Key != y.Key:
+			if x.Key < y.Key {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
@@ -0,0 +1,106 @@
+package slowjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	n, err := NewParser(`{"server": {"port": 8080, "tls": null}, "hosts": ["a", "b"], "labels": {"app.io/name": "x"}, "debug": true, "empty": {}, "none": []}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got := Flatten(n)
+	want := map[string]string{
+		"server.port":           "8080",
+		"server.tls":            "null",
+		"hosts[0]":              "a",
+		"hosts[1]":              "b",
+		`labels["app.io/name"]`: "x",
+		"debug":                 "true",
+		"empty":                 "{}",
+		"none":                  "[]",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Flatten() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Flatten()[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+
+	back, err := UnflattenOptions{InferTypes: true}.Unflatten(got)
+	if err != nil {
+		t.Fatalf("Unflatten() error = %v", err)
+	}
+	if !Equal(n, back, EqualOptions{}) {
+		b, _ := Marshal(back)
+		t.Errorf("Unflatten(Flatten()) = %s", b)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	n, err := Unflatten(map[string]string{
+		"servers[10]":    "k",
+		"servers[2]":     "c",
+		"servers[0]":     "a",
+		"servers[1]":     "b",
+		"servers[3]":     "d",
+		"servers[4]":     "e",
+		"servers[5]":     "f",
+		"servers[6]":     "g",
+		"servers[7]":     "h",
+		"servers[8]":     "i",
+		"servers[9]":     "j",
+		"db.port":        "5432",
+		"db.replicas[0]": "r1",
+		"app":            "true",
+	})
+	if err != nil {
+		t.Fatalf("Unflatten() error = %v", err)
+	}
+	b, _ := Marshal(n)
+	if want := `{"app":"true","db":{"port":"5432","replicas":["r1"]},"servers":["a","b","c","d","e","f","g","h","i","j","k"]}`; string(b) != want {
+		t.Errorf("Unflatten() = %s, want %s", b, want)
+	}
+	if got := n.Lookup(MustParsePath("db.replicas[0]")).Path().String(); got != "db.replicas[0]" {
+		t.Errorf("Path() = %s, parents should be set", got)
+	}
+
+	typed, err := UnflattenOptions{InferTypes: true, File: "consul"}.Unflatten(map[string]string{"a": "1.5", "b": "01", "c": "false", "d": "null"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := Marshal(typed); string(b) != `{"a":1.5,"b":"01","c":false,"d":null}` {
+		t.Errorf("Unflatten() with InferTypes = %s", b)
+	}
+	if typed.Children[0].Children[0].File != "consul" {
+		t.Error("Unflatten() should set File")
+	}
+	if empty, err := Unflatten(nil); err != nil || empty.Type != NodeObject {
+		t.Errorf("Unflatten(nil) = %v, %v", empty, err)
+	}
+}
+
+func TestUnflatten_Errors(t *testing.T) {
+	tests := []struct {
+		m    map[string]string
+		want string
+	}{
+		{map[string]string{"a": "1", "a.b": "2"}, "a is both a value and a container"},
+		{map[string]string{"a[0]": "1", "a.b": "2"}, "a is both an array and an object"},
+		{map[string]string{"a[1]": "1"}, "a[1] is set without a[0]"},
+		{map[string]string{"a.b": "1", `a["b"]`: "2"}, "a.b is set twice"},
+		{map[string]string{"": "1", "a": "2"}, "the root is both a value and a container"},
+		{map[string]string{"a..b": "1"}, "empty key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			_, err := Unflatten(tt.m)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Unflatten() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
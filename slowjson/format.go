@@ -0,0 +1,258 @@
+package slowjson
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Array layouts of Style.
+const (
+	// ArraysAuto writes arrays of scalars on one line when they fit MaxWidth, other arrays one item per line.
+	ArraysAuto = "auto"
+	// ArraysExpand writes every array one item per line.
+	ArraysExpand = "expand"
+	// ArraysCollapse writes arrays of scalars on one line regardless of their width.
+	ArraysCollapse = "collapse"
+)
+
+// Style is a canonical layout of JSON documents, so files written by different people and tools
+// converge. Unlike MarshalOptions it keeps comments: a comment before a member or item stays before
+// it and one on the line of a value stays after it, also when keys are sorted.
+type Style struct {
+	// Indent is written once per nesting level, two spaces when empty.
+	Indent string
+	// SortKeys writes object members in lexical order of their keys.
+	SortKeys bool
+	// NormalizeNumbers writes numbers in their shortest form, e.g. 1.50 as 1.5, 1.0 as 1 and 2E+03 as 2e3.
+	NormalizeNumbers bool
+	// Arrays is ArraysAuto, the default, ArraysExpand or ArraysCollapse. Arrays holding comments,
+	// objects or non-empty arrays are always expanded.
+	Arrays string
+	// MaxWidth is the line width ArraysAuto collapses arrays within, 80 when zero.
+	MaxWidth int
+}
+
+// Format writes n in the style. Objects are written one member per line, strings are re-escaped
+// canonically and the output ends with a newline.
+func (s Style) Format(n *Node) string {
+	if s.Indent == "" {
+		s.Indent = "  "
+	}
+	if s.MaxWidth == 0 {
+		s.MaxWidth = 80
+	}
+	f := &formatter{style: s}
+	for _, c := range comments(n.Leading) {
+		f.buf.WriteString(c.Text)
+		f.buf.WriteByte('\n')
+	}
+	f.value(n, 0, 0)
+	for _, c := range comments(n.Trailing) {
+		if c.StartLine == n.EndLine {
+			f.buf.WriteByte(' ')
+		} else {
+			f.buf.WriteByte('\n')
+		}
+		f.buf.WriteString(c.Text)
+	}
+	f.buf.WriteByte('\n')
+	return f.buf.String()
+}
+
+type formatter struct {
+	style Style
+	buf   bytes.Buffer
+}
+
+func comments(trivia []Trivia) []Trivia {
+	var out []Trivia
+	for _, t := range trivia {
+		if t.IsComment() {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// entry is a member or item with the comments written around it.
+type entry struct {
+	key, value *Node
+	// before are written on their own lines, after on the line of the value.
+	before, after []Trivia
+}
+
+// entries splits the comments of the children of n among them. A comment starting on the line a
+// child ends belongs to that child, other comments to the next child, or to the closing bracket.
+func entries(n *Node) (list []entry, closing []Trivia) {
+	var pending []Trivia
+	for _, child := range n.Children {
+		e := entry{value: child}
+		lead := append([]Trivia(nil), child.Leading...)
+		if n.Type == NodeObject {
+			e.key, e.value = child, child.Children[0]
+			lead = append(lead, child.Trailing...)
+			lead = append(lead, e.value.Leading...)
+		}
+		for _, c := range comments(lead) {
+			if len(list) > 0 && len(pending) == 0 && c.StartLine == list[len(list)-1].value.EndLine {
+				list[len(list)-1].after = append(list[len(list)-1].after, c)
+				continue
+			}
+			pending = append(pending, c)
+		}
+		e.before, pending = pending, nil
+		for _, c := range comments(e.value.Trailing) {
+			if c.StartLine == e.value.EndLine && len(pending) == 0 {
+				e.after = append(e.after, c)
+			} else {
+				pending = append(pending, c)
+			}
+		}
+		list = append(list, e)
+	}
+	return list, append(pending, comments(n.Inner)...)
+}
+
+func (f *formatter) indent(depth int) {
+	f.buf.WriteString(strings.Repeat(f.style.Indent, depth))
+}
+
+// value writes n at the nesting depth, col is the width of the line before it.
+func (f *formatter) value(n *Node, depth, col int) {
+	switch n.Type {
+	case NodeObject, NodeArray:
+		list, closing := entries(n)
+		open, end := "{", "}"
+		if n.Type == NodeArray {
+			open, end = "[", "]"
+		}
+		if len(list) == 0 && len(closing) == 0 {
+			f.buf.WriteString(open + end)
+			return
+		}
+		if n.Type == NodeArray && len(closing) == 0 && f.collapse(list, col) {
+			f.buf.WriteString(open)
+			for i, e := range list {
+				if i > 0 {
+					f.buf.WriteString(", ")
+				}
+				f.buf.WriteString(f.scalar(e.value))
+			}
+			f.buf.WriteString(end)
+			return
+		}
+		if n.Type == NodeObject && f.style.SortKeys {
+			sort.SliceStable(list, func(i, j int) bool { return list[i].key.Value < list[j].key.Value })
+		}
+		f.buf.WriteString(open + "\n")
+		for i, e := range list {
+			for _, c := range e.before {
+				f.indent(depth + 1)
+				f.buf.WriteString(c.Text + "\n")
+			}
+			f.indent(depth + 1)
+			width := len(f.style.Indent) * (depth + 1)
+			if e.key != nil {
+				key := f.scalar(e.key)
+				f.buf.WriteString(key + ": ")
+				width += utf8.RuneCountInString(key) + 2
+			}
+			f.value(e.value, depth+1, width)
+			if i < len(list)-1 {
+				f.buf.WriteByte(',')
+			}
+			for _, c := range e.after {
+				f.buf.WriteString(" " + c.Text)
+			}
+			f.buf.WriteByte('\n')
+		}
+		for _, c := range closing {
+			f.indent(depth + 1)
+			f.buf.WriteString(c.Text + "\n")
+		}
+		f.indent(depth)
+		f.buf.WriteString(end)
+	default:
+		f.buf.WriteString(f.scalar(n))
+	}
+}
+
+// collapse reports whether the items of an array are written on one line starting at col.
+func (f *formatter) collapse(list []entry, col int) bool {
+	width := col + 2
+	for i, e := range list {
+		if len(e.before) > 0 || len(e.after) > 0 {
+			return false
+		}
+		switch v := e.value; {
+		case v.Type == NodeObject && len(v.Children) > 0 || v.Type == NodeArray && len(v.Children) > 0:
+			return false
+		case len(comments(v.Inner)) > 0:
+			return false
+		}
+		if i > 0 {
+			width += 2
+		}
+		width += utf8.RuneCountInString(f.scalar(e.value))
+	}
+	switch f.style.Arrays {
+	case ArraysExpand:
+		return false
+	case ArraysCollapse:
+		return true
+	default:
+		// the comma after the array counts
+		return width+1 <= f.style.MaxWidth
+	}
+}
+
+// scalar returns the text of a scalar, an empty object or an empty array.
+func (f *formatter) scalar(n *Node) string {
+	switch n.Type {
+	case NodeObject:
+		return "{}"
+	case NodeArray:
+		return "[]"
+	case NodeString:
+		var buf bytes.Buffer
+		if err := writeString(&buf, n.Value); err != nil {
+			return n.raw()
+		}
+		return buf.String()
+	case NodeNumber:
+		if f.style.NormalizeNumbers {
+			return normalizeNumber(n.Value)
+		}
+	}
+	return n.Value
+}
+
+var numberPattern = regexp.MustCompile(`^(-?)0*([0-9]+?)(?:\.([0-9]*?)0*)?(?:[eE]([-+]?)0*([0-9]+))?$`)
+
+// normalizeNumber returns the shortest form of a JSON number with the same value, e.g. "1.50" as
+// "1.5" and "2E+03" as "2e3". Numbers it does not recognize are returned unchanged.
+func normalizeNumber(v string) string {
+	m := numberPattern.FindStringSubmatch(v)
+	if m == nil {
+		return v
+	}
+	sign, intPart, frac, expSign, exp := m[1], m[2], m[3], m[4], m[5]
+	out := intPart
+	if frac != "" {
+		out += "." + frac
+	}
+	if exp != "" && exp != "0" {
+		if expSign == "-" {
+			out += "e-" + exp
+		} else {
+			out += "e" + exp
+		}
+	}
+	if sign == "-" && strings.Trim(intPart+frac, "0") != "" {
+		out = "-" + out
+	}
+	return out
+}
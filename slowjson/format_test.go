@@ -0,0 +1,88 @@
+package slowjson
+
+import "testing"
+
+func TestStyle_Format(t *testing.T) {
+	input := `// header
+{"name":"api", // the service
+  "ports":[80,443],
+  // limits
+  "limits":{"rps":1.50E+03,"burst":10},
+  "tags":[
+    "a", // first
+    "b"
+  ],
+  "empty":{},"list":[{"a":1}],
+  "none":[ /* nothing yet */ ]
+}`
+	n, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Style{SortKeys: true, NormalizeNumbers: true}.Format(n)
+	want := `// header
+{
+  "empty": {},
+  // limits
+  "limits": {
+    "burst": 10,
+    "rps": 1.5e3
+  },
+  "list": [
+    {
+      "a": 1
+    }
+  ],
+  "name": "api", // the service
+  "none": [
+    /* nothing yet */
+  ],
+  "ports": [80, 443],
+  "tags": [
+    "a", // first
+    "b"
+  ]
+}
+`
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+	again, err := NewParser(got).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (Style{SortKeys: true, NormalizeNumbers: true}).Format(again) != got {
+		t.Errorf("Format() is not idempotent")
+	}
+}
+
+func TestStyle_Arrays(t *testing.T) {
+	n, err := NewParser(`{"short": [1, 2], "long": ["aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"]}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{Style{MaxWidth: 40}, "{\n  \"short\": [1, 2],\n  \"long\": [\n    \"aaaaaaaaaa\",\n    \"bbbbbbbbbb\",\n    \"cccccccccc\"\n  ]\n}\n"},
+		{Style{Arrays: ArraysCollapse, MaxWidth: 40, Indent: "\t"}, "{\n\t\"short\": [1, 2],\n\t\"long\": [\"aaaaaaaaaa\", \"bbbbbbbbbb\", \"cccccccccc\"]\n}\n"},
+		{Style{Arrays: ArraysExpand}, "{\n  \"short\": [\n    1,\n    2\n  ],\n  \"long\": [\n    \"aaaaaaaaaa\",\n    \"bbbbbbbbbb\",\n    \"cccccccccc\"\n  ]\n}\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.style.Format(n); got != tt.want {
+			t.Errorf("Format(%+v) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	for in, want := range map[string]string{
+		"10": "10", "0": "0", "-0": "0", "-0.0": "0", "1.50": "1.5", "1.0": "1", "2E+03": "2e3",
+		"1e-05": "1e-5", "3.000e0": "3", "-12.340": "-12.34", "0.001": "0.001", "NaN": "NaN",
+	} {
+		if got := normalizeNumber(in); got != want {
+			t.Errorf("normalizeNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package slowjson
+
+import (
+	"strings"
+	"unicode"
+)
+
+// KeyNormalizer maps a key to a canonical form, keys with the same canonical form match.
+type KeyNormalizer func(key string) string
+
+// NormalizeCase matches keys case-insensitively.
+func NormalizeCase(key string) string {
+	return strings.ToLower(key)
+}
+
+// NormalizeSeparators matches keys case-insensitively and ignores '_', '-' and '.',
+// so server_port, serverPort, server-port and SERVER.PORT are the same key.
+func NormalizeSeparators(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, key)
+}
+
+type getOptions struct {
+	normalize KeyNormalizer
+}
+
+// GetOption configures Get.
+type GetOption func(*getOptions)
+
+// WithKeyNormalizer compares keys after applying f.
+// Consecutive path segments are joined with '.' before normalizing, so with NormalizeSeparators
+// Get("server.port") also matches a flat "SERVER_PORT" key, which is what env var layers produce.
+func WithKeyNormalizer(f KeyNormalizer) GetOption {
+	return func(o *getOptions) {
+		o.normalize = f
+	}
+}
+
+// Get returns the value node at the path relative to n, or nil if the path is invalid or missing.
+// The path uses the syntax of ParsePath. Without options it is the same as Lookup.
+func (n *Node) Get(path string, opts ...GetOption) *Node {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil
+	}
+	var o getOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.normalize == nil {
+		return n.Lookup(p)
+	}
+	return getNormalized(n, p, o.normalize)
+}
+
+func getNormalized(cur *Node, path Path, normalize KeyNormalizer) *Node {
+	if len(path) == 0 {
+		return cur
+	}
+	if path[0].IsIndex {
+		if cur.Type != NodeArray || path[0].Index >= len(cur.Children) {
+			return nil
+		}
+		return getNormalized(cur.Children[path[0].Index], path[1:], normalize)
+	}
+	if cur.Type != NodeObject {
+		return nil
+	}
+	// Try to match the shortest run of key segments first, backtracking to longer runs.
+	var keys []string
+	for i := 0; i < len(path) && !path[i].IsIndex; i++ {
+		keys = append(keys, path[i].Key)
+		want := normalize(strings.Join(keys, "."))
+		// later duplicate keys win
+		for j := len(cur.Children) - 1; j >= 0; j-- {
+			key := cur.Children[j]
+			if len(key.Children) == 0 || normalize(key.Value) != want {
+				continue
+			}
+			if found := getNormalized(key.Children[0], path[i+1:], normalize); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
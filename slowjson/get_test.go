@@ -0,0 +1,62 @@
+package slowjson
+
+import "testing"
+
+func TestNode_Get(t *testing.T) {
+	root, err := NewParser(`{
+  "Server": {"Port": 80},
+  "server_port": 8080,
+  "serverHost": "localhost",
+  "DB": {"replicas": [{"Max-Conn": 5}]}
+}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		path      string
+		normalize KeyNormalizer
+		want      string
+	}{
+		{"exact", "Server.Port", nil, "80"},
+		{"exact miss", "server.port", nil, ""},
+		{"case", "server.port", NormalizeCase, "80"},
+		{"case no separators", "serverhost", NormalizeCase, "localhost"},
+		{"separators nested wins", "SERVER.PORT", NormalizeSeparators, "80"},
+		{"separators flat", "server.host", NormalizeSeparators, "localhost"},
+		{"separators snake", "server_port", NormalizeSeparators, "8080"},
+		{"index", "db.replicas[0].max_conn", NormalizeSeparators, "5"},
+		{"missing", "server.timeout", NormalizeSeparators, ""},
+		{"invalid path", "server..port", NormalizeSeparators, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []GetOption
+			if tt.normalize != nil {
+				opts = append(opts, WithKeyNormalizer(tt.normalize))
+			}
+			got := root.Get(tt.path, opts...)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("Get(%q) = %v, want nil", tt.path, got.Value)
+				}
+				return
+			}
+			if got == nil || got.Value != tt.want {
+				t.Errorf("Get(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_Get_Backtrack(t *testing.T) {
+	// "a" matches but has no "b.c", so the flat "a_b" key has to be tried next
+	root, err := NewParser(`{"a": {"x": 1}, "a_b": {"c": 2}}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got := root.Get("a.b.c", WithKeyNormalizer(NormalizeSeparators))
+	if got == nil || got.Value != "2" {
+		t.Errorf("Get() = %v, want 2", got)
+	}
+}
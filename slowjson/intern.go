@@ -0,0 +1,105 @@
+package slowjson
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// maxInternedValue is the longest string or number value that is interned. Keys are interned whatever
+// their length, longer values are rarely repeated and would only grow the table.
+const maxInternedValue = 32
+
+// internShards is how many independently locked tables an Interner spreads its strings over, so
+// parsers sharing it rarely wait on each other.
+const internShards = 32
+
+// Interner deduplicates the strings of parsed documents, so the thousands of repeated keys of a large
+// document or of many similar documents, and short values like "info" or "0", share one copy. Parsers
+// intern keys and short values on their own, share an Interner to also deduplicate across parses.
+// It is safe for concurrent use, e.g. by ParseNDJSON workers.
+type Interner struct {
+	seed   maphash.Seed
+	shards [internShards]internShard
+}
+
+type internShard struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+// NewInterner creates an empty Interner.
+func NewInterner() *Interner {
+	in := &Interner{seed: maphash.MakeSeed()}
+	for i := range in.shards {
+		in.shards[i].strings = map[string]string{}
+	}
+	return in
+}
+
+// Intern returns the copy of s held by the interner, adding s when it is new.
+func (in *Interner) Intern(s string) string {
+	sh := &in.shards[maphash.String(in.seed, s)%internShards]
+	sh.mu.RLock()
+	v, ok := sh.strings[s]
+	sh.mu.RUnlock()
+	if ok {
+		return v
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if v, ok := sh.strings[s]; ok {
+		return v
+	}
+	sh.strings[s] = s
+	return s
+}
+
+// Len returns how many distinct strings the interner holds.
+func (in *Interner) Len() int {
+	n := 0
+	for i := range in.shards {
+		sh := &in.shards[i]
+		sh.mu.RLock()
+		n += len(sh.strings)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// lookup is Intern for bytes, it only allocates a string for new content.
+func (in *Interner) lookup(b []byte) string {
+	sh := &in.shards[maphash.Bytes(in.seed, b)%internShards]
+	sh.mu.RLock()
+	v, ok := sh.strings[string(b)]
+	sh.mu.RUnlock()
+	if ok {
+		return v
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if v, ok := sh.strings[string(b)]; ok {
+		return v
+	}
+	s := string(b)
+	sh.strings[s] = s
+	return s
+}
+
+// intern returns b as a string, shared with equal keys and short values parsed before.
+func (p *Parser) intern(b []byte, key bool) string {
+	if !key && len(b) > maxInternedValue {
+		return string(b)
+	}
+	if p.Interner != nil {
+		return p.Interner.lookup(b)
+	}
+	if v, ok := p.strings[string(b)]; ok {
+		return v
+	}
+	if p.strings == nil {
+		p.strings = map[string]string{}
+	}
+	s := string(b)
+	p.strings[s] = s
+	return s
+}
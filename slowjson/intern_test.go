@@ -0,0 +1,93 @@
+package slowjson
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func same(a, b string) bool {
+	return unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestParser_Intern(t *testing.T) {
+	long := strings.Repeat("v", maxInternedValue+1)
+	input := `[{"name": "a", "level": "info", "n": 10, "long": "` + long + `"},
+	           {"name": "b", "level": "info", "n": 10, "long": "` + long + `"}]`
+	n, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := n.Children[0].Children, n.Children[1].Children
+	for i := range a {
+		if !same(a[i].Value, b[i].Value) {
+			t.Errorf("key %q is not interned", a[i].Value)
+		}
+	}
+	if !same(a[1].Children[0].Value, b[1].Children[0].Value) || !same(a[2].Children[0].Value, b[2].Children[0].Value) {
+		t.Error("short values are not interned")
+	}
+	if same(a[3].Children[0].Value, b[3].Children[0].Value) {
+		t.Error("long values are interned")
+	}
+}
+
+func TestInterner_Shared(t *testing.T) {
+	docs, err := ParseNDJSON(strings.NewReader("{\"level\": \"info\"}\n{\"level\": \"info\"}\n"), "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same(docs[0].Children[0].Value, docs[1].Children[0].Value) {
+		t.Error("keys of NDJSON documents are not shared")
+	}
+
+	in := NewInterner()
+	var values []string
+	for _, input := range []string{`{"service": 1}`, `{"service": 2}`} {
+		p := NewParser(input)
+		p.Interner = in
+		n, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, n.Children[0].Value)
+	}
+	if !same(values[0], values[1]) || in.Len() != 3 {
+		t.Errorf("shared Interner holds %d strings, want service, 1 and 2", in.Len())
+	}
+	if s := "service"; !same(in.Intern(strings.Clone(s)), values[0]) {
+		t.Error("Intern() does not return the held copy")
+	}
+}
+
+func TestInterner_Concurrent(t *testing.T) {
+	in := NewInterner()
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	results := make([][]string, 8)
+	var wg sync.WaitGroup
+	for w := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, k := range keys {
+				results[w] = append(results[w], in.lookup([]byte(k)))
+			}
+		}()
+	}
+	wg.Wait()
+	if in.Len() != len(keys) {
+		t.Errorf("Len() = %d, want %d", in.Len(), len(keys))
+	}
+	for w := 1; w < len(results); w++ {
+		for i := range keys {
+			if !same(results[w][i], results[0][i]) {
+				t.Fatalf("worker %d got another copy of %q", w, keys[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package slowjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Marshal encodes the node as compact JSON, dropping comments and whitespace.
+// Strings are re-escaped from Value so the output is valid JSON even if the input used lax escapes.
+// Object keys keep their source order, see MarshalOptions to sort them.
+func Marshal(n *Node) ([]byte, error) {
+	return MarshalOptions{}.Marshal(n)
+}
+
+// MarshalOptions configures how nodes are encoded as JSON.
+type MarshalOptions struct {
+	// Indent is written once per nesting level, the output is compact when it is empty.
+	Indent string
+	// SortKeys writes object keys in lexical order instead of source order, for deterministic output
+	// regardless of how the input was written. Duplicate keys keep their relative order.
+	SortKeys bool
+}
+
+// Marshal encodes the node as JSON using the options.
+func (o MarshalOptions) Marshal(n *Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := o.writeJSON(&buf, n, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (o MarshalOptions) newline(buf *bytes.Buffer, depth int) {
+	if o.Indent == "" {
+		return
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(strings.Repeat(o.Indent, depth))
+}
+
+func (o MarshalOptions) writeJSON(buf *bytes.Buffer, n *Node, depth int) error {
+	switch n.Type {
+	case NodeObject:
+		keys := n.Children
+		if o.SortKeys {
+			keys = append([]*Node(nil), keys...)
+			sort.SliceStable(keys, func(i, j int) bool { return keys[i].Value < keys[j].Value })
+		}
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			o.newline(buf, depth+1)
+			if err := writeString(buf, key.Value); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if o.Indent != "" {
+				buf.WriteByte(' ')
+			}
+			if len(key.Children) == 0 {
+				return key.Errorf("missing value for key %q", key.Value)
+			}
+			if err := o.writeJSON(buf, key.Children[0], depth+1); err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			o.newline(buf, depth)
+		}
+		buf.WriteByte('}')
+	case NodeArray:
+		buf.WriteByte('[')
+		for i, child := range n.Children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			o.newline(buf, depth+1)
+			if err := o.writeJSON(buf, child, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(n.Children) > 0 {
+			o.newline(buf, depth)
+		}
+		buf.WriteByte(']')
+	case NodeString:
+		return writeString(buf, n.Value)
+	case NodeNumber, NodeBoolean, NodeNull:
+		buf.WriteString(n.Value)
+	default:
+		return n.Errorf("unknown node type %d", n.Type)
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode string: %w", err)
+	}
+	buf.Write(b)
+	return nil
+}
@@ -0,0 +1,89 @@
+package slowjson
+
+// MatrixRow is a path of a Matrix with its value in every document.
+type MatrixRow struct {
+	Path Path
+	// Values holds the value in each document, in the order of the documents, nil where the path is not set.
+	Values []*Node
+}
+
+// Missing reports whether some documents set the path and others do not.
+func (r MatrixRow) Missing() bool {
+	set := 0
+	for _, v := range r.Values {
+		if v != nil {
+			set++
+		}
+	}
+	return set > 0 && set < len(r.Values)
+}
+
+// Differs reports whether the documents setting the path disagree on its value. Numbers are compared
+// by value and objects ignoring the order of their keys.
+func (r MatrixRow) Differs() bool {
+	var first *Node
+	for _, v := range r.Values {
+		switch {
+		case v == nil:
+		case first == nil:
+			first = v
+		case !Equal(first, v, EqualOptions{NumericValue: true}):
+			return true
+		}
+	}
+	return false
+}
+
+// Outliers returns the documents disagreeing with the value more than half of the documents share, e.g.
+// the one environment with debug enabled. It returns none when no value has such a majority, as for a
+// host name set per environment, or when the documents agree.
+func (r MatrixRow) Outliers() []int {
+	for _, v := range r.Values {
+		if v == nil {
+			continue
+		}
+		var agree, disagree []int
+		for i, w := range r.Values {
+			if w != nil && Equal(v, w, EqualOptions{NumericValue: true}) {
+				agree = append(agree, i)
+			} else {
+				disagree = append(disagree, i)
+			}
+		}
+		if 2*len(agree) > len(r.Values) {
+			return disagree
+		}
+	}
+	return nil
+}
+
+// Matrix lines up the values of documents by path, e.g. the configs of every environment, so keys set in
+// some documents only and values that differ stand out. Rows are the scalars, arrays and empty objects
+// below the roots, in order of first appearance across the documents. Arrays are compared as a whole.
+func Matrix(docs ...*Node) []MatrixRow {
+	var rows []MatrixRow
+	index := map[string]int{}
+	for i, doc := range docs {
+		var walk func(n *Node, p Path)
+		walk = func(n *Node, p Path) {
+			if n.Type == NodeObject && len(n.Children) > 0 {
+				for _, key := range n.Children {
+					if len(key.Children) > 0 {
+						walk(key.Children[0], p.Key(key.Value))
+					}
+				}
+				return
+			}
+			s := p.String()
+			j, ok := index[s]
+			if !ok {
+				j = len(rows)
+				index[s] = j
+				rows = append(rows, MatrixRow{Path: p, Values: make([]*Node, len(docs))})
+			}
+			rows[j].Values[i] = n
+		}
+		walk(doc, nil)
+	}
+	return rows
+}
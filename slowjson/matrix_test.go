@@ -0,0 +1,41 @@
+package slowjson
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	var docs []*Node
+	for _, s := range []string{
+		`{"db": {"host": "dev-db", "pool": 5}, "debug": false, "hosts": ["a"], "cache": {"ttl": 30}}`,
+		`{"db": {"host": "stg-db", "pool": 5.0}, "debug": false, "hosts": ["a"]}`,
+		`{"db": {"host": "prod-db", "pool": 5}, "debug": true, "hosts": ["a", "b"], "cache": {"ttl": 30}}`,
+	} {
+		n, err := NewParser(s).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, n)
+	}
+	rows := Matrix(docs...)
+	want := []string{
+		"db.host missing=false differs=true outliers=[]",
+		"db.pool missing=false differs=false outliers=[]",
+		"debug missing=false differs=true outliers=[2]",
+		"hosts missing=false differs=true outliers=[2]",
+		"cache.ttl missing=true differs=false outliers=[1]",
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Matrix() has %d rows, want %d", len(rows), len(want))
+	}
+	for i, r := range rows {
+		got := fmt.Sprintf("%s missing=%v differs=%v outliers=%v", r.Path, r.Missing(), r.Differs(), r.Outliers())
+		if got != want[i] {
+			t.Errorf("row %d = %s, want %s", i, got, want[i])
+		}
+	}
+	if rows[4].Values[1] != nil || rows[4].Values[2].Value != "30" {
+		t.Errorf("cache.ttl values = %v", rows[4].Values)
+	}
+}
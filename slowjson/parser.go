@@ -1,9 +1,12 @@
 package slowjson
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // NodeType represents the kind of JSON node.
@@ -19,23 +22,45 @@ const (
 	NodeNull
 )
 
+// MaxDepth is the deepest nesting of objects and arrays a parser accepts, so a malicious document
+// can't exhaust the stack. The parsers and decoders of the other formats apply it as well.
+const MaxDepth = 1000
+
 // Node is a parsed JSON element, with start/end line/column info.
-// For objects and arrays, Children holds contained items.
+// For objects and arrays, Children holds contained items in source order. The children of an
+// object are its key nodes, each with the value as its only child, duplicates included.
 // For strings, numbers, booleans, and null, Value holds the literal.
 // StartLine, StartCol, EndLine, EndCol indicate where the node begins/ends.
+// StartOffset and EndOffset are the same span in bytes, decoders for binary formats
+// only fill the offsets and leave line/col as zero.
 // The entire JSON source is stored in Source for easy context extraction.
 type Node struct {
 	Type     NodeType
 	Value    string
 	Children []*Node
+	// Parent is the containing node, nil for the root. The parent of an object value is its key.
+	Parent *Node
 
 	StartLine int
 	StartCol  int
 	EndLine   int
 	EndCol    int
 
+	StartOffset int
+	EndOffset   int
+
+	// Leading and Trailing hold whitespace and comments right before and after the node.
+	// For object members trivia between the key and ':' is the key's Trailing, trivia after ':'
+	// is the value's Leading. Inner holds trivia inside an empty object or array.
+	// Together with Print they allow a lossless round-trip of the input.
+	Leading  []Trivia
+	Trailing []Trivia
+	Inner    []Trivia
+
 	// Store entire input for debug context. In practice you may store it externally.
 	Source string
+	// File is the name of the file the node was parsed from, empty if unknown.
+	File string
 }
 
 // DebugContext returns lines around the node to help in debugging.
@@ -78,26 +103,34 @@ func (n *Node) DebugContext(linesBefore, linesAfter int) string {
 
 // Parser implements a simple JSON parser that tracks line/column positions.
 type Parser struct {
-	runes  []rune
-	pos    int
-	line   int
-	col    int
-	length int
-	// original input
-	source string
+	cursor
+	// File is recorded on every node so errors can point at the file, set it before parsing.
+	File string
+	// Arena allocates the nodes when set, see Arena.
+	Arena *Arena
+	// Interner is shared by parses of similar documents so their keys and short values share one copy,
+	// see Interner. Without it each parse interns on its own.
+	Interner *Interner
+
+	buf     []byte
+	strings map[string]string
+	depth   int
 }
 
 // NewParser creates a Parser from the given JSON string.
 func NewParser(input string) *Parser {
-	r := []rune(input)
-	return &Parser{
-		runes:  r,
-		pos:    0,
-		line:   1, // 1-based indexing for line
-		col:    1, // 1-based indexing for column
-		length: len(r),
-		source: input,
+	return &Parser{cursor: newCursor(input)}
+}
+
+// ParseFile reads and parses the file at path, nodes record path as their File.
+func ParseFile(path string) (*Node, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
+	p := NewParser(string(b))
+	p.File = path
+	return p.Parse()
 }
 
 // Parse parses the entire input and returns the root Node.
@@ -107,7 +140,7 @@ func (p *Parser) Parse() (*Node, error) {
 	if err != nil {
 		return n, err
 	}
-	p.skipWhitespace()
+	n.Trailing = p.skipTrivia()
 	// If we haven't consumed all runes, we can ignore or return an error.
 	if !p.isEOF() {
 		// We'll ignore trailing characters, or you can return an error.
@@ -115,64 +148,151 @@ func (p *Parser) Parse() (*Node, error) {
 	return n, nil
 }
 
+// ParseValueOrString parses text holding a single JSON value, e.g. 80, true or {"a": 1}, and
+// returns any other text as a string node spanning it. Sources of untyped strings such as database
+// rows or environment variables use it, file names the origin of the value.
+func ParseValueOrString(text, file string) *Node {
+	if json.Valid([]byte(text)) {
+		p := NewParser(text)
+		p.File = file
+		if n, err := p.Parse(); err == nil {
+			return n
+		}
+	}
+	line := 1 + strings.Count(text, "\n")
+	return &Node{
+		Type:      NodeString,
+		Value:     text,
+		Source:    text,
+		File:      file,
+		StartLine: 1,
+		StartCol:  1,
+		EndLine:   line,
+		EndCol:    1 + utf8.RuneCountInString(text[strings.LastIndexByte(text, '\n')+1:]),
+		EndOffset: len(text),
+	}
+}
+
+// ParseValueAt parses a single value starting at the byte offset in the input.
+// Positions of the returned nodes are relative to the whole input, so editors can
+// re-parse an edited region without parsing the entire document.
+func (p *Parser) ParseValueAt(offset int) (*Node, error) {
+	if offset < 0 || offset > len(p.source) {
+		return nil, &ParseError{Pos: Position{File: p.File, Offset: offset}, Msg: fmt.Sprintf("offset out of range [0, %d]", len(p.source))}
+	}
+	if offset < len(p.source) && !utf8.RuneStart(p.source[offset]) {
+		return nil, &ParseError{Pos: Position{File: p.File, Offset: offset}, Msg: "offset is not at the start of a character"}
+	}
+	prefix := p.source[:offset]
+	p.pos = utf8.RuneCountInString(prefix)
+	p.line = 1 + strings.Count(prefix, "\n")
+	p.col = 1 + utf8.RuneCountInString(prefix[strings.LastIndexByte(prefix, '\n')+1:])
+	p.offset = offset
+	p.depth = 0
+	return p.parseValue()
+}
+
+// errorf returns a *ParseError at the current position.
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return &ParseError{
+		Pos: Position{File: p.File, Line: p.line, Col: p.col, Offset: p.offset},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// Pos returns the current line and column of the parser.
+// After Parse returns an error it points at where parsing stopped.
+func (p *Parser) Pos() (line, col int) {
+	return p.line, p.col
+}
+
 func (p *Parser) parseValue() (*Node, error) {
-	p.skipWhitespace()
+	leading := p.skipTrivia()
 	if p.isEOF() {
 		// Return an error node
-		return nil, fmt.Errorf("unexpected end of input")
+		return nil, p.errorf("unexpected end of input")
 	}
 
-	switch p.peekChar() {
-	case '{':
-		return p.parseObject()
-	case '[':
-		return p.parseArray()
+	var (
+		n   *Node
+		err error
+	)
+	switch ch := p.peekChar(); ch {
+	case '{', '[':
+		p.depth++
+		if p.depth > MaxDepth {
+			return nil, p.errorf("exceeded max depth %d", MaxDepth)
+		}
+		if ch == '{' {
+			n, err = p.parseObject()
+		} else {
+			n, err = p.parseArray()
+		}
+		p.depth--
 	case '"':
-		return p.parseString()
+		n, err = p.parseString(false)
 	case 't', 'f':
-		return p.parseBoolean()
+		n, err = p.parseBoolean()
 	case 'n':
-		return p.parseNull()
+		n, err = p.parseNull()
 	default:
 		// Might be a number?
-		return p.parseNumber()
+		n, err = p.parseNumber()
 	}
+	if n != nil {
+		n.Leading = leading
+	}
+	return n, err
 }
 
-func (p *Parser) parseObject() (*Node, error) {
-	n := &Node{
-		Type:      NodeObject,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
+// newNode starts a node of typ at the current position.
+func (p *Parser) newNode(typ NodeType) *Node {
+	var n *Node
+	if p.Arena != nil {
+		n = p.Arena.alloc()
+	} else {
+		n = &Node{}
 	}
+	n.Type = typ
+	n.Source = p.source
+	n.File = p.File
+	n.StartLine = p.line
+	n.StartCol = p.col
+	n.StartOffset = p.offset
+	return n
+}
+
+func (p *Parser) parseObject() (*Node, error) {
+	n := p.newNode(NodeObject)
 
 	p.consumeChar() // consume '{'
-	p.skipWhitespace()
+	leading := p.skipTrivia()
 
 	n.Children = []*Node{}
 
 	// Check for empty object
 	if p.peekChar() == '}' {
+		n.Inner = leading
 		p.consumeChar()
 		n.EndLine = p.line
 		n.EndCol = p.col
+		n.EndOffset = p.offset
 		return n, nil
 	}
 
 	for {
-		p.skipWhitespace()
 		if p.peekChar() != '"' {
-			return n, fmt.Errorf("expected string key at line %d col %d", p.line, p.col)
+			return n, p.errorf("expected string key")
 		}
-		keyNode, err := p.parseString()
+		keyNode, err := p.parseString(true)
 		if err != nil {
 			return n, err
 		}
+		keyNode.Leading = leading
+		keyNode.Trailing = p.skipTrivia()
 
-		p.skipWhitespace()
 		if p.peekChar() != ':' {
-			return n, fmt.Errorf("expected ':' after object key at line %d col %d", p.line, p.col)
+			return n, p.errorf("expected ':' after object key")
 		}
 		p.consumeChar() // consume ':'
 
@@ -180,45 +300,46 @@ func (p *Parser) parseObject() (*Node, error) {
 		if err != nil {
 			return n, err
 		}
+		valueNode.Trailing = p.skipTrivia()
 
 		// We can store key as a child with a single child representing its value
 		// or we can store them differently. We'll create a node of type NodeString for key
 		// and attach the value as its child.
 		keyNode.Children = []*Node{valueNode}
+		keyNode.Parent = n
+		valueNode.Parent = keyNode
 		n.Children = append(n.Children, keyNode)
 
-		p.skipWhitespace()
 		if p.peekChar() == '}' {
 			p.consumeChar()
 			n.EndLine = p.line
 			n.EndCol = p.col
+			n.EndOffset = p.offset
 			return n, nil
 		}
 		if p.peekChar() != ',' {
-			return n, fmt.Errorf("expected ',' or '}' in object at line %d col %d", p.line, p.col)
+			return n, p.errorf("expected ',' or '}' in object")
 		}
 		p.consumeChar() // consume ','
+		leading = p.skipTrivia()
 	}
 }
 
 func (p *Parser) parseArray() (*Node, error) {
-	n := &Node{
-		Type:      NodeArray,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
-	}
+	n := p.newNode(NodeArray)
 
 	p.consumeChar() // consume '['
-	p.skipWhitespace()
+	leading := p.skipTrivia()
 
 	n.Children = []*Node{}
 
 	// Check for empty array
 	if p.peekChar() == ']' {
+		n.Inner = leading
 		p.consumeChar()
 		n.EndLine = p.line
 		n.EndCol = p.col
+		n.EndOffset = p.offset
 		return n, nil
 	}
 
@@ -227,40 +348,41 @@ func (p *Parser) parseArray() (*Node, error) {
 		if err != nil {
 			return n, err
 		}
+		valueNode.Leading = append(leading, valueNode.Leading...)
+		valueNode.Trailing = p.skipTrivia()
+		valueNode.Parent = n
 		n.Children = append(n.Children, valueNode)
 
-		p.skipWhitespace()
 		if p.peekChar() == ']' {
 			p.consumeChar()
 			n.EndLine = p.line
 			n.EndCol = p.col
+			n.EndOffset = p.offset
 			return n, nil
 		}
 		if p.peekChar() != ',' {
-			return n, fmt.Errorf("expected ',' or ']' in array at line %d col %d", p.line, p.col)
+			return n, p.errorf("expected ',' or ']' in array")
 		}
 		p.consumeChar() // consume ','
-		p.skipWhitespace()
+		// the next value collects its own leading trivia
+		leading = nil
 	}
 }
 
-func (p *Parser) parseString() (*Node, error) {
-	n := &Node{
-		Type:      NodeString,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
-	}
+// parseString parses a string, key is set for object keys which are always interned.
+func (p *Parser) parseString(key bool) (*Node, error) {
+	n := p.newNode(NodeString)
 
 	p.consumeChar() // consume '"'
 
-	var sb strings.Builder
+	p.buf = p.buf[:0]
 	for {
 		if p.isEOF() {
-			n.Value = sb.String()
+			n.Value = p.intern(p.buf, key)
 			n.EndLine = p.line
 			n.EndCol = p.col
-			return n, fmt.Errorf("unexpected end of input in string")
+			n.EndOffset = p.offset
+			return n, p.errorf("unexpected end of input in string")
 		}
 		ch := p.peekChar()
 		if ch == '"' {
@@ -271,62 +393,99 @@ func (p *Parser) parseString() (*Node, error) {
 			// handle escape
 			p.consumeChar() // consume '\'
 			if p.isEOF() {
-				return n, fmt.Errorf("unexpected end of input in string escape")
+				return n, p.errorf("unexpected end of input in string escape")
 			}
 			escaped := p.peekChar()
 			// simplistic approach: just append the character after '\' as-is
-			sb.WriteRune(escaped)
+			p.buf = utf8.AppendRune(p.buf, escaped)
 			p.consumeChar()
 		} else {
-			sb.WriteRune(ch)
+			p.buf = utf8.AppendRune(p.buf, ch)
 			p.consumeChar()
 		}
 	}
 
-	n.Value = sb.String()
+	n.Value = p.intern(p.buf, key)
 	n.EndLine = p.line
 	n.EndCol = p.col
+	n.EndOffset = p.offset
 	return n, nil
 }
 
 func (p *Parser) parseNumber() (*Node, error) {
-	n := &Node{
-		Type:      NodeNumber,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
-	}
+	n := p.newNode(NodeNumber)
 
-	var sb strings.Builder
-
-	// We'll parse until we hit a non-number-related character.
-	// This is naive and does not strictly enforce JSON numeric format.
+	p.buf = p.buf[:0]
 
+	// Collect every number-related character, then check the result against the JSON grammar.
 	for !p.isEOF() {
 		ch := p.peekChar()
-		if ch == '-' || ch == '+' || ch == '.' || unicode.IsDigit(ch) {
-			sb.WriteRune(ch)
+		if ch == '-' || ch == '+' || ch == '.' || ch == 'e' || ch == 'E' || unicode.IsDigit(ch) {
+			p.buf = utf8.AppendRune(p.buf, ch)
 			p.consumeChar()
 		} else {
 			break
 		}
 	}
 
-	n.Value = sb.String()
+	if !validNumber(p.buf) {
+		return n, &ParseError{
+			Pos: Position{File: p.File, Line: n.StartLine, Col: n.StartCol, Offset: n.StartOffset},
+			Msg: fmt.Sprintf("invalid number %q", p.buf),
+		}
+	}
+	n.Value = p.intern(p.buf, false)
 	n.EndLine = p.line
 	n.EndCol = p.col
-
-	// We could validate if it's a valid number.
+	n.EndOffset = p.offset
 	return n, nil
 }
 
-func (p *Parser) parseBoolean() (*Node, error) {
-	n := &Node{
-		Type:      NodeBoolean,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
+// validNumber reports whether b follows the JSON number grammar:
+// an optional minus, an integer without leading zeros, an optional fraction and an optional exponent.
+func validNumber(b []byte) bool {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(b) && b[i] == '0':
+		i++
+	case i < len(b) && b[i] >= '1' && b[i] <= '9':
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+	default:
+		return false
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		start := i
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
 	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		start := i
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(b)
+}
+
+func (p *Parser) parseBoolean() (*Node, error) {
+	n := p.newNode(NodeBoolean)
 
 	if strings.HasPrefix(p.remaining(), "true") {
 		n.Value = "true"
@@ -341,21 +500,17 @@ func (p *Parser) parseBoolean() (*Node, error) {
 			p.consumeChar()
 		}
 	} else {
-		return n, fmt.Errorf("invalid boolean at line %d col %d", p.line, p.col)
+		return n, p.errorf("invalid boolean")
 	}
 
 	n.EndLine = p.line
 	n.EndCol = p.col
+	n.EndOffset = p.offset
 	return n, nil
 }
 
 func (p *Parser) parseNull() (*Node, error) {
-	n := &Node{
-		Type:      NodeNull,
-		Source:    p.source,
-		StartLine: p.line,
-		StartCol:  p.col,
-	}
+	n := p.newNode(NodeNull)
 
 	if strings.HasPrefix(p.remaining(), "null") {
 		n.Value = "null"
@@ -364,59 +519,15 @@ func (p *Parser) parseNull() (*Node, error) {
 			p.consumeChar()
 		}
 	} else {
-		return n, fmt.Errorf("invalid null at line %d col %d", p.line, p.col)
+		return n, p.errorf("invalid null")
 	}
 
 	n.EndLine = p.line
 	n.EndCol = p.col
+	n.EndOffset = p.offset
 	return n, nil
 }
 
-func (p *Parser) skipWhitespace() {
-	for !p.isEOF() {
-		ch := p.peekChar()
-		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
-			p.consumeChar()
-		} else {
-			break
-		}
-	}
-}
-
-func (p *Parser) peekChar() rune {
-	if p.pos >= p.length {
-		return 0
-	}
-	return p.runes[p.pos]
-}
-
-func (p *Parser) consumeChar() {
-	if p.pos >= p.length {
-		return
-	}
-
-	ch := p.runes[p.pos]
-	p.pos++
-	// update line/col
-	if ch == '\n' {
-		p.line++
-		p.col = 1
-	} else {
-		p.col++
-	}
-}
-
-func (p *Parser) remaining() string {
-	if p.pos >= p.length {
-		return ""
-	}
-	return string(p.runes[p.pos:])
-}
-
-func (p *Parser) isEOF() bool {
-	return p.pos >= p.length
-}
-
 // Example usage:
 // func main() {
 // 	input := `{"hello": [1, 2, 3], "world": true, "nested": {"foo": "bar"}}`
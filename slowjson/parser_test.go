@@ -1,6 +1,9 @@
 package slowjson
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -88,6 +91,26 @@ func TestParser_Parse_Errors(t *testing.T) {
 			input:     `{"key": tru}`,
 			wantError: "invalid boolean",
 		},
+		{
+			name:      "too deep",
+			input:     strings.Repeat("[", MaxDepth+1),
+			wantError: "exceeded max depth 1000 at line 1 col 1001",
+		},
+		{
+			name:      "malformed exponent",
+			input:     `[1e+e-]`,
+			wantError: `invalid number "1e+e-" at line 1 col 2`,
+		},
+		{
+			name:      "leading zero",
+			input:     `{"key": 01}`,
+			wantError: `invalid number "01" at line 1 col 9`,
+		},
+		{
+			name:      "missing fraction digits",
+			input:     `-1.`,
+			wantError: `invalid number "-1."`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -212,3 +235,178 @@ func TestParser_Parse_Types(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseValueAt(t *testing.T) {
+	input := "{\n  \"name\": \"héllo\", \"list\": [1, {\"a\": true}]\n}"
+	tests := []struct {
+		name      string
+		offset    int
+		wantType  NodeType
+		wantLine  int
+		wantCol   int
+		wantEnd   int
+		wantValue string
+	}{
+		{
+			name:      "string",
+			offset:    strings.Index(input, `"héllo"`),
+			wantType:  NodeString,
+			wantLine:  2,
+			wantCol:   11,
+			wantEnd:   strings.Index(input, `, "list"`),
+			wantValue: "héllo",
+		},
+		{
+			name:     "object after multi-byte character",
+			offset:   strings.Index(input, `{"a"`),
+			wantType: NodeObject,
+			wantLine: 2,
+			wantCol:  32,
+			wantEnd:  strings.Index(input, `]`),
+		},
+		{
+			name:     "leading whitespace",
+			offset:   strings.Index(input, ` {`),
+			wantType: NodeObject,
+			wantLine: 2,
+			wantCol:  32,
+			wantEnd:  strings.Index(input, `]`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(input).ParseValueAt(tt.offset)
+			if err != nil {
+				t.Fatalf("ParseValueAt() error = %v", err)
+			}
+			if got.Type != tt.wantType || got.Value != tt.wantValue {
+				t.Errorf("ParseValueAt() got %v %q, want %v %q", got.Type, got.Value, tt.wantType, tt.wantValue)
+			}
+			if got.StartLine != tt.wantLine || got.StartCol != tt.wantCol {
+				t.Errorf("ParseValueAt() got start %d:%d, want %d:%d", got.StartLine, got.StartCol, tt.wantLine, tt.wantCol)
+			}
+			if got.EndOffset != tt.wantEnd {
+				t.Errorf("ParseValueAt() got end offset %d, want %d", got.EndOffset, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParser_ParseValueAt_Errors(t *testing.T) {
+	input := `{"a": "é"}`
+	tests := []struct {
+		name      string
+		offset    int
+		wantError string
+	}{
+		{"negative", -1, "out of range"},
+		{"past end", len(input) + 1, "out of range"},
+		{"inside character", strings.Index(input, "é") + 1, "not at the start of a character"},
+		{"at end", len(input), "unexpected end of input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(input).ParseValueAt(tt.offset)
+			if err == nil {
+				t.Fatal("ParseValueAt() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("ParseValueAt() error = %v, want error containing %v", err, tt.wantError)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("ParseValueAt() error = %#v, want *ParseError", err)
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{\n  \"a\": tru\n}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ParseFile(path)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Msg != "invalid boolean" || perr.Pos.File != path || perr.Pos.Line != 2 {
+		t.Errorf("ParseFile() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"a": [1]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	elem := n.Lookup(MustParsePath("a[0]"))
+	if elem.File != path || elem.Location() != path+":1:8" {
+		t.Errorf("ParseFile() got location %q", elem.Location())
+	}
+}
+
+func TestParseValueOrString(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantType NodeType
+		want     string
+	}{
+		{"80", NodeNumber, "80"},
+		{"true", NodeBoolean, "true"},
+		{`"quoted"`, NodeString, "quoted"},
+		{"example.com", NodeString, "example.com"},
+		{"80 apples", NodeString, "80 apples"},
+		{"", NodeString, ""},
+	}
+	for _, tt := range tests {
+		n := ParseValueOrString(tt.text, "env[X]")
+		if n.Type != tt.wantType || n.Value != tt.want || n.Location() != "env[X]:1:1" {
+			t.Errorf("ParseValueOrString(%q) = %v %q at %s", tt.text, n.Type, n.Value, n.Location())
+		}
+	}
+	if n := ParseValueOrString(`{"a": [1]}`, ""); n.Type != NodeObject || n.Lookup(MustParsePath("a[0]")) == nil {
+		t.Errorf("ParseValueOrString() object = %v", n)
+	}
+}
+
+func TestParser_Parse_InvalidUTF8Offsets(t *testing.T) {
+	input := "{\"a\": \"\xff\", \"b\": 2}"
+	n, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	b := n.Lookup(MustParsePath("b"))
+	if b == nil || b.StartOffset != 16 || b.EndOffset != 17 || b.StartCol != 17 {
+		t.Fatalf("Parse() b = %+v", b)
+	}
+	if got := Print(n); got != input {
+		t.Errorf("Print() = %q, want %q", got, input)
+	}
+}
+
+func TestParser_Parse_LiteralsAfterInvalidUTF8(t *testing.T) {
+	for _, input := range []string{"[\"\xff\", true]", "[\"\xff\", false]", "[\"\xff\", null]"} {
+		n, err := NewParser(input).Parse()
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", input, err)
+			continue
+		}
+		if len(n.Children) != 2 || n.Children[1].StartOffset != 6 {
+			t.Errorf("Parse(%q) got %+v", input, n.Children)
+		}
+	}
+}
+
+func TestValidNumber(t *testing.T) {
+	for _, s := range []string{"0", "-0", "12", "1.5", "-0.25e10", "1E+3", "2e-7"} {
+		if !validNumber([]byte(s)) {
+			t.Errorf("validNumber(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "-", "+1", "01", "1.", ".5", "1e", "1e+", "1e+e-", "1-2", "1.2.3"} {
+		if validNumber([]byte(s)) {
+			t.Errorf("validNumber(%q) = true, want false", s)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package slowjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSegment is an object key or an array index.
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Path is a list of segments from the root to a node.
+type Path []PathSegment
+
+// String renders the path as dotted keys and bracketed indexes, e.g. servers[2].tls.cert.
+// Keys that are not plain identifiers are quoted in brackets, e.g. labels["app.kubernetes.io/name"].
+// The output can be parsed back with ParsePath.
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, seg := range p {
+		switch {
+		case seg.IsIndex:
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+		case !isPlainKey(seg.Key):
+			sb.WriteString("[" + strconv.Quote(seg.Key) + "]")
+		default:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(seg.Key)
+		}
+	}
+	return sb.String()
+}
+
+// Key appends an object key segment and returns the new path.
+func (p Path) Key(key string) Path {
+	return append(p[:len(p):len(p)], PathSegment{Key: key})
+}
+
+// Index appends an array index segment and returns the new path.
+func (p Path) Index(i int) Path {
+	return append(p[:len(p):len(p)], PathSegment{Index: i, IsIndex: true})
+}
+
+// Equal reports whether both paths have the same segments.
+func (p Path) Equal(other Path) bool {
+	if len(p) != len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// HasPrefix reports whether prefix is the start of p.
+func (p Path) HasPrefix(prefix Path) bool {
+	return len(prefix) <= len(p) && p[:len(prefix)].Equal(prefix)
+}
+
+// ParsePath parses the output of Path.String back into segments.
+// A leading "$" or "$." is accepted so JSONPath style root references work as well.
+func ParsePath(s string) (Path, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	if strings.HasPrefix(s, "$") && !strings.HasPrefix(s, "$.") && !strings.HasPrefix(s, "$[") && s != "$" {
+		return nil, fmt.Errorf("invalid path %q: expected '.' or '[' after '$'", s)
+	}
+	path := Path{}
+	pos := len(s) - len(rest)
+	first := true
+	for len(rest) > 0 {
+		switch {
+		case rest[0] == '[':
+			end, seg, err := parseBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q at col %d: %w", s, pos+1, err)
+			}
+			path = append(path, seg)
+			rest, pos = rest[end:], pos+end
+		case rest[0] == '.' && !first:
+			rest, pos = rest[1:], pos+1
+			fallthrough
+		default:
+			end := 0
+			for end < len(rest) && rest[end] != '.' && rest[end] != '[' {
+				end++
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q at col %d: empty key", s, pos+1)
+			}
+			path = append(path, PathSegment{Key: rest[:end]})
+			rest, pos = rest[end:], pos+end
+		}
+		first = false
+	}
+	return path, nil
+}
+
+// MustParsePath is like ParsePath but panics on error, for paths known at compile time.
+func MustParsePath(s string) Path {
+	p, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// parseBracket parses [123] or ["key"] and returns the number of bytes consumed.
+func parseBracket(s string) (int, PathSegment, error) {
+	if len(s) > 1 && s[1] == '"' {
+		// find the closing quote, skipping escaped characters
+		for i := 2; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				key, err := strconv.Unquote(s[1 : i+1])
+				if err != nil {
+					return 0, PathSegment{}, err
+				}
+				if i+1 >= len(s) || s[i+1] != ']' {
+					return 0, PathSegment{}, fmt.Errorf("expected ']' after quoted key")
+				}
+				return i + 2, PathSegment{Key: key}, nil
+			}
+		}
+		return 0, PathSegment{}, fmt.Errorf("unterminated quoted key")
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return 0, PathSegment{}, fmt.Errorf("expected ']'")
+	}
+	i, err := strconv.Atoi(s[1:end])
+	if err != nil || i < 0 {
+		return 0, PathSegment{}, fmt.Errorf("invalid index %q", s[1:end])
+	}
+	return end + 1, PathSegment{Index: i, IsIndex: true}, nil
+}
+
+// isPlainKey reports whether the key can be written without quoting.
+func isPlainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, ch := range key {
+		switch {
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+		case i > 0 && (ch == '-' || ch >= '0' && ch <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
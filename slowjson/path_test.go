@@ -0,0 +1,115 @@
+package slowjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPath_String(t *testing.T) {
+	tests := []struct {
+		name string
+		path Path
+		want string
+	}{
+		{"empty", Path{}, ""},
+		{"keys", Path{}.Key("tls").Key("cert"), "tls.cert"},
+		{"index", Path{}.Key("servers").Index(2).Key("port"), "servers[2].port"},
+		{"root index", Path{}.Index(0).Key("a"), "[0].a"},
+		{"quoted", Path{}.Key("labels").Key("app.kubernetes.io/name"), `labels["app.kubernetes.io/name"]`},
+		{"quoted first", Path{}.Key("a b").Key("c"), `["a b"].c`},
+		{"dash and digits", Path{}.Key("max-conn2"), "max-conn2"},
+		{"empty key", Path{}.Key(""), `[""]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.path.String()
+			if got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+			parsed, err := ParsePath(got)
+			if err != nil {
+				t.Fatalf("ParsePath(%q) error = %v", got, err)
+			}
+			if !parsed.Equal(tt.path) {
+				t.Errorf("ParsePath(%q) = %v, want %v", got, parsed, tt.path)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$.a.b", "a.b"},
+		{"$[1]", "[1]"},
+		{"$", ""},
+		{`a["x\"y"][3]`, `a["x\"y"][3]`},
+	}
+	for _, tt := range tests {
+		p, err := ParsePath(tt.input)
+		if err != nil {
+			t.Fatalf("ParsePath(%q) error = %v", tt.input, err)
+		}
+		if p.String() != tt.want {
+			t.Errorf("ParsePath(%q) = %q, want %q", tt.input, p.String(), tt.want)
+		}
+	}
+}
+
+func TestParsePath_Errors(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{"a..b", "at col 3: empty key"},
+		{"a[x]", `at col 2: invalid index "x"`},
+		{"a[1", "at col 2: expected ']'"},
+		{`a["b`, "unterminated quoted key"},
+		{`a["b"`, "expected ']' after quoted key"},
+		{"$a", "expected '.' or '[' after '$'"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePath(tt.input)
+		if err == nil {
+			t.Fatalf("ParsePath(%q) expected error, got nil", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("ParsePath(%q) error = %v, want error containing %v", tt.input, err, tt.wantError)
+		}
+	}
+}
+
+func TestNode_Lookup(t *testing.T) {
+	root, err := NewParser(`{"servers": [{"port": 1}, {"port": 2}], "a.b": {"c": true}}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"servers[1].port", "2"},
+		{`["a.b"].c`, "true"},
+		{"servers[2].port", ""},
+		{"servers.port", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		got := root.Lookup(MustParsePath(tt.path))
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("Lookup(%q) = %v, want nil", tt.path, got.Value)
+			}
+			continue
+		}
+		if got == nil || got.Value != tt.want {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.path, got, tt.want)
+			continue
+		}
+		if got.Path().String() != tt.path {
+			t.Errorf("Lookup(%q).Path() = %q", tt.path, got.Path())
+		}
+	}
+}
@@ -1,3 +1,6 @@
 // Package slowjson is a slow JSON parser that returns json node and allows printing context around a node.
 // The parser is generated by GPT and the tests are generated by cursor.
+//
+// Numbers must follow the JSON grammar in both the Parser and the Scanner, e.g. 01, 1. and 1e+ are errors.
+// Earlier versions accepted any run of digits, signs, dots and exponent markers.
 package slowjson
@@ -0,0 +1,72 @@
+package slowjson
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Print writes the node back as text, including its trivia.
+// For a tree returned by Parse the output is identical to the input.
+// Scalars are printed from the source when available so escapes and number formats are kept.
+func Print(n *Node) string {
+	var sb strings.Builder
+	printNode(&sb, n)
+	return sb.String()
+}
+
+func printNode(sb *strings.Builder, n *Node) {
+	writeTrivia(sb, n.Leading)
+	switch n.Type {
+	case NodeObject:
+		sb.WriteByte('{')
+		if len(n.Children) == 0 {
+			writeTrivia(sb, n.Inner)
+		}
+		for i, key := range n.Children {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeTrivia(sb, key.Leading)
+			sb.WriteString(key.raw())
+			writeTrivia(sb, key.Trailing)
+			sb.WriteByte(':')
+			if len(key.Children) > 0 {
+				printNode(sb, key.Children[0])
+			}
+		}
+		sb.WriteByte('}')
+	case NodeArray:
+		sb.WriteByte('[')
+		if len(n.Children) == 0 {
+			writeTrivia(sb, n.Inner)
+		}
+		for i, child := range n.Children {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			printNode(sb, child)
+		}
+		sb.WriteByte(']')
+	default:
+		sb.WriteString(n.raw())
+	}
+	writeTrivia(sb, n.Trailing)
+}
+
+// raw returns the scalar as it appears in the source, or a canonical form if there is no source.
+func (n *Node) raw() string {
+	if n.Source != "" && n.EndOffset > n.StartOffset {
+		return n.Source[n.StartOffset:n.EndOffset]
+	}
+	if n.Type == NodeString {
+		b, _ := json.Marshal(n.Value)
+		return string(b)
+	}
+	return n.Value
+}
+
+func writeTrivia(sb *strings.Builder, trivia []Trivia) {
+	for _, t := range trivia {
+		sb.WriteString(t.Text)
+	}
+}
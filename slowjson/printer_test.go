@@ -0,0 +1,134 @@
+package slowjson
+
+import "testing"
+
+func TestPrint_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"compact", `{"a":[1,2,{"b":null}],"c":"d"}`},
+		{"whitespace", "  {\n\t\"a\" :  [ 1 , 2 ] ,\r\n  \"b\": { }\n}\n"},
+		{"escapes and numbers", `["a\"bé", -1.50, 0.0]`},
+		{"line comments", "// header\n{\n  // port\n  \"port\": 8080, // trailing\n  \"host\": \"x\" // last\n}\n// footer"},
+		{"block comments", "[/* empty */]"},
+		{"comments everywhere", "{/*a*/\"k\"/*b*/:/*c*/1/*d*/,/*e*/\"l\":[/*f*/true/*g*/]/*h*/}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewParser(tt.input).Parse()
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := Print(n); got != tt.input {
+				t.Errorf("Print() got\n%q\nwant\n%q", got, tt.input)
+			}
+		})
+	}
+}
+
+func TestParser_Parse_Trivia(t *testing.T) {
+	input := "{\n  // the port\n  \"port\": 8080 /* tcp */,\n  \"tags\": [/* none */]\n}"
+	n, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	port := n.Children[0]
+	if len(port.Leading) != 3 || !port.Leading[1].IsComment() || port.Leading[1].Text != "// the port" {
+		t.Errorf("Parse() got key leading %+v", port.Leading)
+	}
+	if port.Leading[1].StartLine != 2 || port.Leading[1].StartCol != 3 {
+		t.Errorf("Parse() got comment at %d:%d", port.Leading[1].StartLine, port.Leading[1].StartCol)
+	}
+	value := port.Children[0]
+	if len(value.Trailing) != 2 || value.Trailing[1].Kind != TriviaBlockComment {
+		t.Errorf("Parse() got value trailing %+v", value.Trailing)
+	}
+	tags := n.Children[1].Children[0]
+	if len(tags.Inner) != 1 || tags.Inner[0].Text != "/* none */" {
+		t.Errorf("Parse() got inner %+v", tags.Inner)
+	}
+}
+
+func TestPrint_WithoutSource(t *testing.T) {
+	n := &Node{Type: NodeObject, Children: []*Node{
+		{Type: NodeString, Value: "a\"b", Children: []*Node{{Type: NodeNumber, Value: "1"}}},
+	}}
+	if got := Print(n); got != `{"a\"b":1}` {
+		t.Errorf("Print() got %s", got)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	n, err := NewParser("{\n  // c\n  \"a\": [1, \"x\\\"y\", null], \"b\": {\"c\": false}\n}").Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	b, err := Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"a":[1,"x\"y",null],"b":{"c":false}}`; string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	n, err := NewParser(`{"b": 1, "a": {}, "b": 2}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := n.Keys(); len(got) != 3 || got[0] != "b" || got[1] != "a" || got[2] != "b" {
+		t.Errorf("Keys() = %v", got)
+	}
+	if got := n.Children[0].Keys(); got != nil {
+		t.Errorf("Keys() of a key = %v, want nil", got)
+	}
+}
+
+func TestParser_Parse_TriviaInvalidUTF8(t *testing.T) {
+	for _, input := range []string{"1 //\xff", "/*\xff*/ 1", "[1 /* \xff"} {
+		n, err := NewParser(input).Parse()
+		if err != nil {
+			continue
+		}
+		if got := Print(n); got != input {
+			t.Errorf("Print() = %q, want %q", got, input)
+		}
+	}
+	n, err := NewParser("1 //\xff").Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(n.Trailing) != 2 || n.Trailing[1].Text != "//\xff" {
+		t.Errorf("Parse() got trailing %+v", n.Trailing)
+	}
+}
+
+func TestAttachComments(t *testing.T) {
+	// a tree as a YAML parser builds it for "# top\na:\n  # inner\n  b: |\n    # text\n  c: 1\n"
+	src := "# top\na:\n  # inner\n  b: |\n    # text\n  c: 1\n"
+	leaf := func(typ NodeType, start, end int) *Node {
+		return &Node{Type: typ, StartOffset: start, EndOffset: end}
+	}
+	b := leaf(NodeString, 21, 22)
+	b.Children = []*Node{leaf(NodeString, 24, 39)}
+	c := leaf(NodeString, 39, 40)
+	c.Children = []*Node{leaf(NodeNumber, 42, 43)}
+	inner := &Node{Type: NodeObject, StartOffset: 21, EndOffset: 43, Children: []*Node{b, c}}
+	a := leaf(NodeString, 6, 7)
+	a.Children = []*Node{inner}
+	root := &Node{Type: NodeObject, StartOffset: 6, EndOffset: 44, Children: []*Node{a}}
+	SetParents(root)
+
+	AttachComments(src, []*Node{root}, "#")
+	if len(root.Leading) != 0 || len(a.Leading) != 1 || a.Leading[0].Text != "# top" || a.Leading[0].StartLine != 1 {
+		t.Errorf("root, a Leading = %+v, %+v, want the top comment on a", root.Leading, a.Leading)
+	}
+	if len(b.Leading) != 1 || b.Leading[0].Text != "# inner" || b.Leading[0].StartLine != 3 || b.Leading[0].StartCol != 3 {
+		t.Errorf("b Leading = %+v", b.Leading)
+	}
+	if len(c.Leading) != 0 {
+		t.Errorf("c Leading = %+v, the line inside the block scalar is not a comment", c.Leading)
+	}
+}
@@ -0,0 +1,206 @@
+package slowjson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match is a node found by Query, with its path from the queried node.
+type Match struct {
+	Path Path
+	Node *Node
+}
+
+// selector is a step of a query.
+type selector struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	// descend applies the selector to the node and all its descendants, for ..
+	descend bool
+}
+
+// Query returns the nodes matching the JSONPath expression expr relative to n, in document order. It
+// supports a leading $, .key, ["key"] and ['key'], [i] with negative indexes counting from the end, * and
+// [*] for every member or element, and .. for any depth, e.g. servers[*].host or $..port. Filters and
+// slices are errors. A plain path in the syntax of ParsePath matches at most the node Lookup returns.
+func (n *Node) Query(expr string) ([]Match, error) {
+	sels, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	matches := []Match{{Path: Path{}, Node: n}}
+	descended := false
+	for _, sel := range sels {
+		var next []Match
+		seen := map[*Node]bool{}
+		add := func(m Match) {
+			if !seen[m.Node] {
+				seen[m.Node] = true
+				next = append(next, m)
+			}
+		}
+		for _, m := range matches {
+			if !sel.descend {
+				sel.apply(m, add)
+				continue
+			}
+			descended = true
+			walkMatches(m, func(d Match) {
+				sel.apply(d, add)
+			})
+		}
+		matches = next
+	}
+	if descended {
+		// .. visits a node before applying the selector to its descendants, so a match can precede the
+		// children of an earlier sibling. Without .. every step keeps the order.
+		rank := map[*Node]int{}
+		walkMatches(Match{Node: n}, func(m Match) {
+			rank[m.Node] = len(rank)
+		})
+		sort.SliceStable(matches, func(i, j int) bool {
+			return rank[matches[i].Node] < rank[matches[j].Node]
+		})
+	}
+	return matches, nil
+}
+
+// walkMatches calls f with m and every node below it, parents first.
+func walkMatches(m Match, f func(Match)) {
+	f(m)
+	switch m.Node.Type {
+	case NodeObject:
+		for _, key := range m.Node.Children {
+			if len(key.Children) > 0 {
+				walkMatches(Match{Path: m.Path.Key(key.Value), Node: key.Children[0]}, f)
+			}
+		}
+	case NodeArray:
+		for i, item := range m.Node.Children {
+			walkMatches(Match{Path: m.Path.Index(i), Node: item}, f)
+		}
+	}
+}
+
+// apply passes the children of m the selector matches to add.
+func (s selector) apply(m Match, add func(Match)) {
+	switch n := m.Node; {
+	case n.Type == NodeObject && !s.isIndex:
+		for _, key := range n.Children {
+			// only the last of duplicate keys takes effect, like in Lookup
+			if (s.wildcard || key.Value == s.key) && n.findLastKey(key.Value) == key {
+				add(Match{Path: m.Path.Key(key.Value), Node: key.Children[0]})
+			}
+		}
+	case n.Type == NodeArray && s.wildcard:
+		for i, item := range n.Children {
+			add(Match{Path: m.Path.Index(i), Node: item})
+		}
+	case n.Type == NodeArray && s.isIndex:
+		i := s.index
+		if i < 0 {
+			i += len(n.Children)
+		}
+		if i >= 0 && i < len(n.Children) {
+			add(Match{Path: m.Path.Index(i), Node: n.Children[i]})
+		}
+	}
+}
+
+func (n *Node) findLastKey(name string) *Node {
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		if key := n.Children[i]; key.Value == name && len(key.Children) > 0 {
+			return key
+		}
+	}
+	return nil
+}
+
+// parseQuery parses a query into its selectors.
+func parseQuery(expr string) ([]selector, error) {
+	rest := strings.TrimPrefix(expr, "$")
+	fail := func(format string, args ...interface{}) ([]selector, error) {
+		return nil, fmt.Errorf("invalid query %q at col %d: %s", expr, len(expr)-len(rest)+1, fmt.Sprintf(format, args...))
+	}
+	var sels []selector
+	first := rest == expr
+	for rest != "" {
+		var sel selector
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			sel.descend = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] != '[' && !first:
+			return fail("expected '.' or '['")
+		}
+		first = false
+		if rest == "" || rest[0] == '.' {
+			return fail("expected a key")
+		}
+		if rest[0] != '[' {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			sel.key, sel.wildcard = rest[:end], rest[:end] == "*"
+			sels, rest = append(sels, sel), rest[end:]
+			continue
+		}
+		end := strings.IndexByte(rest, ']')
+		switch {
+		case rest[1:] == "":
+			return fail("expected ']'")
+		case rest[1] == '?':
+			return fail("filters are not supported")
+		case rest[1] == '"' || rest[1] == '\'':
+			key, n, err := unquoteKey(rest[1:])
+			if err != nil {
+				return fail("%v", err)
+			}
+			if !strings.HasPrefix(rest[1+n:], "]") {
+				return fail("expected ']' after quoted key")
+			}
+			sel.key, rest = key, rest[2+n:]
+		case end < 0:
+			return fail("expected ']'")
+		case rest[1:end] == "*":
+			sel.wildcard, rest = true, rest[end+1:]
+		case strings.ContainsAny(rest[1:end], ":,"):
+			return fail("slices and unions are not supported")
+		default:
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return fail("invalid index %q", rest[1:end])
+			}
+			sel.index, sel.isIndex, rest = i, true, rest[end+1:]
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+// unquoteKey reads the key in double or single quotes at the start of s and returns it with the number
+// of bytes it takes.
+func unquoteKey(s string) (string, int, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case q:
+			if q == '"' {
+				key, err := strconv.Unquote(s[:i+1])
+				return key, i + 1, err
+			}
+			r := strings.NewReplacer(`\'`, `'`, `\\`, `\`)
+			return r.Replace(s[1:i]), i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
@@ -0,0 +1,93 @@
+package slowjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNode_Query(t *testing.T) {
+	root, err := NewParser(`{
+  "servers": [{"host": "a", "port": 80}, {"host": "b", "port": 81, "tls": {"port": 443}}],
+  "db": {"port": 5432, "port": 5433},
+  "labels": {"app.kubernetes.io/name": "web", "it's": "quoted"}
+}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"servers[1].host", `servers[1].host="b"`},
+		{"$.servers[*].host", `servers[0].host="a" servers[1].host="b"`},
+		{"$.servers[-1].port", `servers[1].port=81`},
+		{"$..port", `servers[0].port=80 servers[1].port=81 servers[1].tls.port=443 db.port=5433`},
+		{"$..tls..port", `servers[1].tls.port=443`},
+		{"servers.*.host", `servers[0].host="a" servers[1].host="b"`},
+		{"db.*", `db.port=5433`},
+		{`labels["app.kubernetes.io/name"]`, `labels["app.kubernetes.io/name"]="web"`},
+		{`$['labels']['it\'s']`, `labels["it's"]="quoted"`},
+		{"servers[5].host", ``},
+		{"missing..port", ``},
+		{"$", `{"servers":[{"host":"a","port":80},{"host":"b","port":81,"tls":{"port":443}}],"db":{"port":5432,"port":5433},` +
+			`"labels":{"app.kubernetes.io/name":"web","it's":"quoted"}}`},
+	}
+	for _, tt := range tests {
+		matches, err := root.Query(tt.expr)
+		if err != nil {
+			t.Errorf("Query(%q) error = %v", tt.expr, err)
+			continue
+		}
+		var got []string
+		for _, m := range matches {
+			b, _ := Marshal(m.Node)
+			if len(m.Path) == 0 {
+				got = append(got, string(b))
+				continue
+			}
+			got = append(got, m.Path.String()+"="+string(b))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Query(%q) = %s, want %s", tt.expr, strings.Join(got, " "), tt.want)
+		}
+	}
+	if m, _ := root.Query("servers[1].tls.port"); len(m) != 1 || m[0].Node != root.Get("servers[1].tls.port") {
+		t.Errorf("Query() of a plain path = %v, want the node of Get", m)
+	}
+}
+
+func TestNode_Query_Errors(t *testing.T) {
+	root := &Node{Type: NodeObject}
+	for expr, want := range map[string]string{
+		"$servers":  `invalid query "$servers" at col 2: expected '.' or '['`,
+		"a..":       `invalid query "a.." at col 4: expected a key`,
+		"a[?(@.x)]": `invalid query "a[?(@.x)]" at col 2: filters are not supported`,
+		"a[0:2]":    `invalid query "a[0:2]" at col 2: slices and unions are not supported`,
+		"a[x]":      `invalid query "a[x]" at col 2: invalid index "x"`,
+		"a[0":       `invalid query "a[0" at col 2: expected ']'`,
+		`a["b"`:     `invalid query "a[\"b\"" at col 2: expected ']' after quoted key`,
+		`a['b`:      `invalid query "a['b" at col 2: unterminated quoted key`,
+	} {
+		if _, err := root.Query(expr); err == nil || err.Error() != want {
+			t.Errorf("Query(%q) error = %v, want %s", expr, err, want)
+		}
+	}
+}
+
+func TestNode_Query_DocumentOrder(t *testing.T) {
+	root, err := NewParser(`{"a": {"b": [1, {"c": 2}]}, "d": 3}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := root.Query("$..*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Path.String())
+	}
+	if want := "a a.b a.b[0] a.b[1] a.b[1].c d"; strings.Join(got, " ") != want {
+		t.Errorf("Query($..*) = %v, want %s", got, want)
+	}
+}
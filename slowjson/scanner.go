@@ -0,0 +1,249 @@
+package slowjson
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// TokenType is the kind of a lexical token.
+type TokenType int
+
+const (
+	TokenIllegal TokenType = iota
+	TokenEOF
+	TokenLBrace
+	TokenRBrace
+	TokenLBracket
+	TokenRBracket
+	TokenColon
+	TokenComma
+	TokenString
+	TokenNumber
+	TokenTrue
+	TokenFalse
+	TokenNull
+	TokenComment
+	TokenWhitespace
+)
+
+var tokenNames = [...]string{
+	TokenIllegal:    "illegal",
+	TokenEOF:        "EOF",
+	TokenLBrace:     "'{'",
+	TokenRBrace:     "'}'",
+	TokenLBracket:   "'['",
+	TokenRBracket:   "']'",
+	TokenColon:      "':'",
+	TokenComma:      "','",
+	TokenString:     "string",
+	TokenNumber:     "number",
+	TokenTrue:       "true",
+	TokenFalse:      "false",
+	TokenNull:       "null",
+	TokenComment:    "comment",
+	TokenWhitespace: "whitespace",
+}
+
+func (t TokenType) String() string {
+	if t >= 0 && int(t) < len(tokenNames) {
+		return tokenNames[t]
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// Token is a lexical token with its raw text and span.
+// Text is the token as it appears in the source, e.g. strings keep their quotes and escapes.
+type Token struct {
+	Type TokenType
+	Text string
+
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+
+	StartOffset int
+	EndOffset   int
+}
+
+// Scanner splits JSON input into tokens without building a tree.
+// Besides JSON it accepts // line comments and /* */ block comments.
+// Whitespace is returned as tokens so the input can be reconstructed from the token stream.
+type Scanner struct {
+	cursor
+	err error
+}
+
+// NewScanner creates a Scanner from the given input.
+func NewScanner(input string) *Scanner {
+	return &Scanner{cursor: newCursor(input)}
+}
+
+// Err returns the first error encountered as a *ParseError, the matching token has type TokenIllegal.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Next returns the next token, it returns TokenEOF at the end of input and keeps returning it.
+func (s *Scanner) Next() Token {
+	tok := Token{
+		StartLine:   s.line,
+		StartCol:    s.col,
+		StartOffset: s.offset,
+	}
+	if s.isEOF() {
+		tok.Type = TokenEOF
+		return s.finish(tok)
+	}
+
+	switch ch := s.peekChar(); {
+	case ch == '{':
+		tok.Type = s.single(TokenLBrace)
+	case ch == '}':
+		tok.Type = s.single(TokenRBrace)
+	case ch == '[':
+		tok.Type = s.single(TokenLBracket)
+	case ch == ']':
+		tok.Type = s.single(TokenRBracket)
+	case ch == ':':
+		tok.Type = s.single(TokenColon)
+	case ch == ',':
+		tok.Type = s.single(TokenComma)
+	case ch == '"':
+		tok.Type = s.scanString()
+	case ch == '/':
+		tok.Type = s.scanComment()
+	case isSpace(ch):
+		for !s.isEOF() && isSpace(s.peekChar()) {
+			s.consumeChar()
+		}
+		tok.Type = TokenWhitespace
+	case ch == '-' || unicode.IsDigit(ch):
+		for !s.isEOF() && isNumberChar(s.peekChar()) {
+			s.consumeChar()
+		}
+		tok.Type = TokenNumber
+		if text := s.source[tok.StartOffset:s.offset]; !validNumber([]byte(text)) {
+			tok.Type = s.illegal(tok, "invalid number %q", text)
+		}
+	case unicode.IsLetter(ch):
+		tok.Type = s.scanLiteral()
+	default:
+		s.consumeChar()
+		tok.Type = s.illegal(tok, "unexpected character %q", ch)
+	}
+	return s.finish(tok)
+}
+
+// All scans the remaining input and returns every token, excluding the final TokenEOF.
+func (s *Scanner) All() ([]Token, error) {
+	var tokens []Token
+	for {
+		tok := s.Next()
+		if tok.Type == TokenEOF {
+			return tokens, s.err
+		}
+		tokens = append(tokens, tok)
+		if tok.Type == TokenIllegal {
+			return tokens, s.err
+		}
+	}
+}
+
+func (s *Scanner) single(t TokenType) TokenType {
+	s.consumeChar()
+	return t
+}
+
+func (s *Scanner) scanString() TokenType {
+	at := s.here()
+	s.consumeChar() // consume '"'
+	for !s.isEOF() {
+		switch s.peekChar() {
+		case '"':
+			s.consumeChar()
+			return TokenString
+		case '\\':
+			s.consumeChar()
+		}
+		s.consumeChar()
+	}
+	return s.illegalAt(at, "unexpected end of input in string")
+}
+
+func (s *Scanner) scanComment() TokenType {
+	at := s.here()
+	switch s.peekCharAt(1) {
+	case '/':
+		for !s.isEOF() && s.peekChar() != '\n' {
+			s.consumeChar()
+		}
+		return TokenComment
+	case '*':
+		s.consumeChar()
+		s.consumeChar()
+		for !s.isEOF() {
+			if s.peekChar() == '*' && s.peekCharAt(1) == '/' {
+				s.consumeChar()
+				s.consumeChar()
+				return TokenComment
+			}
+			s.consumeChar()
+		}
+		return s.illegalAt(at, "unexpected end of input in comment")
+	default:
+		s.consumeChar()
+		return s.illegalAt(at, "unexpected character '/'")
+	}
+}
+
+func (s *Scanner) scanLiteral() TokenType {
+	at := s.here()
+	start := s.pos
+	for !s.isEOF() && unicode.IsLetter(s.peekChar()) {
+		s.consumeChar()
+	}
+	switch word := string(s.runes[start:s.pos]); word {
+	case "true":
+		return TokenTrue
+	case "false":
+		return TokenFalse
+	case "null":
+		return TokenNull
+	default:
+		return s.illegalAt(at, "invalid literal %q", word)
+	}
+}
+
+func (s *Scanner) illegal(tok Token, format string, args ...interface{}) TokenType {
+	return s.illegalAt(Position{Line: tok.StartLine, Col: tok.StartCol, Offset: tok.StartOffset}, format, args...)
+}
+
+// illegalAt records a *ParseError at pos unless an earlier error is already recorded.
+func (s *Scanner) illegalAt(pos Position, format string, args ...interface{}) TokenType {
+	if s.err == nil {
+		s.err = &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+	return TokenIllegal
+}
+
+// here returns the current position of the scanner.
+func (s *Scanner) here() Position {
+	return Position{Line: s.line, Col: s.col, Offset: s.offset}
+}
+
+func (s *Scanner) finish(tok Token) Token {
+	tok.EndLine = s.line
+	tok.EndCol = s.col
+	tok.EndOffset = s.offset
+	tok.Text = s.source[tok.StartOffset:tok.EndOffset]
+	return tok
+}
+
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isNumberChar(ch rune) bool {
+	return ch == '-' || ch == '+' || ch == '.' || ch == 'e' || ch == 'E' || unicode.IsDigit(ch)
+}
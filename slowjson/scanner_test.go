@@ -0,0 +1,111 @@
+package slowjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanner_Next(t *testing.T) {
+	input := "{\n  // port\n  \"port\": 8080, /* list */ \"a\": [true, null, -1.5e3]\n}"
+	s := NewScanner(input)
+	tokens, err := s.All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+
+	var types []TokenType
+	var sb strings.Builder
+	for _, tok := range tokens {
+		sb.WriteString(tok.Text)
+		if tok.Type != TokenWhitespace {
+			types = append(types, tok.Type)
+		}
+	}
+	if sb.String() != input {
+		t.Errorf("tokens do not reconstruct input, got %q", sb.String())
+	}
+	want := []TokenType{
+		TokenLBrace, TokenComment, TokenString, TokenColon, TokenNumber, TokenComma, TokenComment,
+		TokenString, TokenColon, TokenLBracket, TokenTrue, TokenComma, TokenNull, TokenComma, TokenNumber,
+		TokenRBracket, TokenRBrace,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("All() got %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("token %d got %v, want %v", i, types[i], want[i])
+		}
+	}
+	if next := s.Next(); next.Type != TokenEOF {
+		t.Errorf("Next() after end got %v, want EOF", next.Type)
+	}
+}
+
+func TestScanner_Spans(t *testing.T) {
+	s := NewScanner("[\n  \"é\", 12]")
+	var str Token
+	for tok := s.Next(); tok.Type != TokenEOF; tok = s.Next() {
+		if tok.Type == TokenString {
+			str = tok
+		}
+	}
+	if str.Text != `"é"` || str.StartLine != 2 || str.StartCol != 3 || str.EndCol != 6 {
+		t.Errorf("Next() got %+v", str)
+	}
+	if str.StartOffset != 4 || str.EndOffset != 8 {
+		t.Errorf("Next() got offsets [%d, %d), want [4, 8)", str.StartOffset, str.EndOffset)
+	}
+}
+
+func TestScanner_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantError  string
+		wantOffset int
+	}{
+		{"unclosed string", `{"key`, "unexpected end of input in string at line 1 col 2", 1},
+		{"unclosed comment", "[1 /* x", "unexpected end of input in comment at line 1 col 4", 3},
+		{"invalid literal", "[nil]", `invalid literal "nil" at line 1 col 2`, 1},
+		{"unexpected character", "{'a': 1}", `unexpected character '\'' at line 1 col 2`, 1},
+		{"invalid number", "[1, 01]", `invalid number "01" at line 1 col 5`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := NewScanner(tt.input).All()
+			if err == nil {
+				t.Fatal("All() expected error, got nil")
+			}
+			if tokens[len(tokens)-1].Type != TokenIllegal {
+				t.Errorf("All() last token = %v, want illegal", tokens[len(tokens)-1].Type)
+			}
+			if !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("All() error = %v, want error containing %v", err, tt.wantError)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) || perr.Pos.Offset != tt.wantOffset {
+				t.Errorf("All() error = %#v, want *ParseError at offset %d", err, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestScanner_InvalidUTF8(t *testing.T) {
+	tokens, err := NewScanner("\xff").All()
+	if err == nil {
+		t.Fatal("All() expected error, got nil")
+	}
+	tok := tokens[len(tokens)-1]
+	if tok.Type != TokenIllegal || tok.Text != "\xff" || tok.StartOffset != 0 || tok.EndOffset != 1 {
+		t.Errorf("All() got %+v", tok)
+	}
+	tokens, err = NewScanner("[\"\xff\", 1]").All()
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if num := tokens[len(tokens)-2]; num.Text != "1" || num.StartOffset != 6 {
+		t.Errorf("All() got number %+v", num)
+	}
+}
@@ -0,0 +1,77 @@
+package slowjson
+
+import "unsafe"
+
+// Stats describes the size of a parsed tree, see Node.Stats.
+type Stats struct {
+	// Nodes counts every node, keys included. The other counts split it by kind.
+	Nodes    int
+	Objects  int
+	Arrays   int
+	Keys     int
+	Strings  int
+	Numbers  int
+	Booleans int
+	Nulls    int
+	// MaxDepth is the deepest nesting of objects and arrays, 0 for a scalar.
+	MaxDepth int
+	// StringBytes is the length of all key and value text, as if every value had its own copy.
+	StringBytes int
+	// SourceBytes is the size of the inputs the nodes keep for context, each input counted once.
+	SourceBytes int
+	// EstimatedBytes estimates the heap held by the tree: the nodes, their child and trivia slices, the
+	// distinct value strings, which is less than StringBytes when values are interned, and the inputs.
+	// It ignores allocator overhead and memory shared with other trees.
+	EstimatedBytes int
+}
+
+// Stats walks the tree below n and reports its size, to understand and tune the memory of large
+// configs, e.g. how much interning saves or how to size an Arena.
+func (n *Node) Stats() Stats {
+	var s Stats
+	strs := map[*byte]bool{}
+	sources := map[*byte]bool{}
+	var walk func(n *Node, depth int)
+	walk = func(n *Node, depth int) {
+		s.Nodes++
+		switch {
+		case n.IsKey():
+			s.Keys++
+		case n.Type == NodeObject:
+			s.Objects++
+		case n.Type == NodeArray:
+			s.Arrays++
+		case n.Type == NodeString:
+			s.Strings++
+		case n.Type == NodeNumber:
+			s.Numbers++
+		case n.Type == NodeBoolean:
+			s.Booleans++
+		default:
+			s.Nulls++
+		}
+		if n.Type == NodeObject || n.Type == NodeArray {
+			depth++
+			s.MaxDepth = max(s.MaxDepth, depth)
+		}
+		s.EstimatedBytes += int(unsafe.Sizeof(Node{})) + cap(n.Children)*int(unsafe.Sizeof(n)) +
+			(cap(n.Leading)+cap(n.Trailing)+cap(n.Inner))*int(unsafe.Sizeof(Trivia{}))
+		if n.Type == NodeString || n.Type == NodeNumber {
+			s.StringBytes += len(n.Value)
+			if p := unsafe.StringData(n.Value); p != nil && !strs[p] {
+				strs[p] = true
+				s.EstimatedBytes += len(n.Value)
+			}
+		}
+		if p := unsafe.StringData(n.Source); p != nil && !sources[p] {
+			sources[p] = true
+			s.SourceBytes += len(n.Source)
+		}
+		for _, c := range n.Children {
+			walk(c, depth)
+		}
+	}
+	walk(n, 0)
+	s.EstimatedBytes += s.SourceBytes
+	return s
+}
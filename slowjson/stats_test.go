@@ -0,0 +1,42 @@
+package slowjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNode_Stats(t *testing.T) {
+	input := `{"a": [1, "xy", true, null], "b": {"c": {"d": []}}, "e": "xy"}`
+	n, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := n.Stats()
+	want := Stats{Nodes: 15, Objects: 3, Arrays: 2, Keys: 5, Strings: 2, Numbers: 1, Booleans: 1, Nulls: 1,
+		MaxDepth: 4, StringBytes: 10, SourceBytes: len(input)}
+	got := s
+	got.EstimatedBytes = 0
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if min := 15*100 + len(input); s.EstimatedBytes < min {
+		t.Errorf("EstimatedBytes = %d, want at least %d", s.EstimatedBytes, min)
+	}
+
+	scalar, _ := NewParser(`"x"`).Parse()
+	if s := scalar.Stats(); s.Nodes != 1 || s.MaxDepth != 0 || s.Strings != 1 {
+		t.Errorf("Stats() of a scalar = %+v", s)
+	}
+
+	interned, _ := NewParser(largeInput(100)).Parse()
+	copied, _ := NewParser(largeInput(100)).Parse()
+	for _, r := range copied.Children {
+		for _, k := range r.Children {
+			k.Value = strings.Clone(k.Value)
+		}
+	}
+	is, cs := interned.Stats(), copied.Stats()
+	if is.EstimatedBytes >= cs.EstimatedBytes || is.StringBytes != cs.StringBytes {
+		t.Errorf("EstimatedBytes interned = %d, copied keys = %d", is.EstimatedBytes, cs.EstimatedBytes)
+	}
+}
@@ -0,0 +1,183 @@
+package slowjson
+
+import "fmt"
+
+// SetParents sets Parent on every node in the tree rooted at n.
+// Parse does this already, it is for trees built by hand or by other decoders.
+func SetParents(n *Node) {
+	for _, child := range n.Children {
+		child.Parent = n
+		SetParents(child)
+	}
+}
+
+// IsKey reports whether the node is the key of an object member.
+func (n *Node) IsKey() bool {
+	return n.Type == NodeString && n.Parent != nil && n.Parent.Type == NodeObject
+}
+
+// Index returns the position of the node in its parent's children, -1 for the root.
+func (n *Node) Index() int {
+	if n.Parent == nil {
+		return -1
+	}
+	for i, sibling := range n.Parent.Children {
+		if sibling == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextSibling returns the next node in the parent's children, e.g. the next key of an object
+// or the next element of an array. It returns nil for the last child and for object values.
+func (n *Node) NextSibling() *Node {
+	i := n.Index()
+	if i < 0 || i+1 >= len(n.Parent.Children) {
+		return nil
+	}
+	return n.Parent.Children[i+1]
+}
+
+// PrevSibling returns the previous node in the parent's children.
+func (n *Node) PrevSibling() *Node {
+	i := n.Index()
+	if i <= 0 {
+		return nil
+	}
+	return n.Parent.Children[i-1]
+}
+
+// Keys returns the keys of an object in source order, including duplicates. It returns nil for other nodes.
+func (n *Node) Keys() []string {
+	if n.Type != NodeObject {
+		return nil
+	}
+	keys := make([]string, len(n.Children))
+	for i, key := range n.Children {
+		keys[i] = key.Value
+	}
+	return keys
+}
+
+// Path returns the key path from the root to the node.
+// A key node has the same path as its value.
+func (n *Node) Path() Path {
+	var path Path
+	for cur := n; cur != nil && cur.Parent != nil; cur = cur.Parent {
+		switch {
+		case cur.IsKey():
+			path = append(path, PathSegment{Key: cur.Value})
+		case cur.Parent.Type == NodeArray:
+			path = append(path, PathSegment{Index: cur.Index(), IsIndex: true})
+		}
+	}
+	// segments were collected from the leaf up
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Lookup returns the value node at path relative to n, or nil if it does not exist.
+// Key segments match object keys exactly, later duplicate keys win like in encoding/json.
+func (n *Node) Lookup(path Path) *Node {
+	cur := n
+	for _, seg := range path {
+		if cur == nil {
+			return nil
+		}
+		next := (*Node)(nil)
+		switch {
+		case seg.IsIndex && cur.Type == NodeArray:
+			if seg.Index < len(cur.Children) {
+				next = cur.Children[seg.Index]
+			}
+		case !seg.IsIndex && cur.Type == NodeObject:
+			for _, key := range cur.Children {
+				if key.Value == seg.Key && len(key.Children) > 0 {
+					next = key.Children[0]
+				}
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+// Location describes where the node is, as line and col for text input or offset for binary input.
+// When the file is known it is file:line:col instead.
+func (n *Node) Location() string {
+	return n.Position().String()
+}
+
+// Position returns the start of the node.
+func (n *Node) Position() Position {
+	return Position{File: n.File, Line: n.StartLine, Col: n.StartCol, Offset: n.StartOffset}
+}
+
+// Errorf returns a *ValidationError prefixed with the node's path and followed by its location,
+// e.g. "servers[0].port: expected number at line 3 col 13". %w in format is supported.
+func (n *Node) Errorf(format string, args ...interface{}) error {
+	return &ValidationError{Path: n.Path(), Pos: n.Position(), Node: n, Err: fmt.Errorf(format, args...)}
+}
+
+// Set puts value at path below the object n, replacing an existing value and creating missing objects
+// on the way. Created objects and keys take the position of value so they point at what caused them.
+// Sources that build trees from flat keys, e.g. database rows, use it. Index segments are not supported.
+// Errors are *ValidationError with the offending path, positioned at the node in the way or at value.
+func (n *Node) Set(path Path, value *Node) error {
+	if len(path) == 0 {
+		return &ValidationError{Pos: value.Position(), Node: value, Err: fmt.Errorf("empty path")}
+	}
+	if n.Type != NodeObject {
+		return &ValidationError{Path: path, Pos: n.Position(), Node: n, Err: fmt.Errorf("cannot set a key, the root is not an object")}
+	}
+	cur := n
+	for i, seg := range path {
+		if seg.IsIndex {
+			return &ValidationError{Path: path[:i+1], Pos: value.Position(), Node: value, Err: fmt.Errorf("cannot set an array element")}
+		}
+		if cur.Type != NodeObject {
+			return &ValidationError{Path: path[:i+1], Pos: cur.Position(), Node: cur, Err: fmt.Errorf("cannot set a key, %s is not an object", path[:i])}
+		}
+		var key *Node
+		for _, k := range cur.Children {
+			if k.Value == seg.Key && len(k.Children) > 0 {
+				key = k
+			}
+		}
+		if key == nil {
+			key = synthetic(NodeString, seg.Key, value)
+			key.Parent = cur
+			cur.Children = append(cur.Children, key)
+		}
+		if i == len(path)-1 {
+			value.Parent = key
+			key.Children = []*Node{value}
+			return nil
+		}
+		if len(key.Children) == 0 {
+			obj := synthetic(NodeObject, "", value)
+			obj.Parent = key
+			key.Children = []*Node{obj}
+		}
+		cur = key.Children[0]
+	}
+	return nil
+}
+
+// synthetic creates a node without source text at the position of at.
+func synthetic(typ NodeType, value string, at *Node) *Node {
+	return &Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   at.StartLine,
+		StartCol:    at.StartCol,
+		EndLine:     at.StartLine,
+		EndCol:      at.StartCol,
+		StartOffset: at.StartOffset,
+		EndOffset:   at.StartOffset,
+		File:        at.File,
+	}
+}
@@ -0,0 +1,117 @@
+package slowjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNode_Path(t *testing.T) {
+	input := `{"servers": [{"name": "a"}, {"name": "b", "tls": {"cert": "c.pem"}}], "debug": true}`
+	root, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	servers := root.Children[0]
+	second := servers.Children[0].Children[1]
+	tlsKey := second.Children[1]
+	cert := tlsKey.Children[0].Children[0].Children[0]
+
+	tests := []struct {
+		name string
+		node *Node
+		want string
+	}{
+		{"root", root, ""},
+		{"key", servers, "servers"},
+		{"array", servers.Children[0], "servers"},
+		{"element", second, "servers[1]"},
+		{"nested key", tlsKey, "servers[1].tls"},
+		{"leaf", cert, "servers[1].tls.cert"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Path().String(); got != tt.want {
+				t.Errorf("Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_Siblings(t *testing.T) {
+	root, err := NewParser(`{"a": 1, "b": [true, false], "c": null}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	a, b, c := root.Children[0], root.Children[1], root.Children[2]
+	if !a.IsKey() || a.Parent != root || a.Children[0].Parent != a {
+		t.Error("Parse() did not set parents of object members")
+	}
+	if a.NextSibling() != b || b.NextSibling() != c || c.NextSibling() != nil {
+		t.Error("NextSibling() got wrong key")
+	}
+	if c.PrevSibling() != b || a.PrevSibling() != nil {
+		t.Error("PrevSibling() got wrong key")
+	}
+	arr := b.Children[0]
+	if arr.Children[0].NextSibling() != arr.Children[1] || arr.Children[1].Parent != arr {
+		t.Error("NextSibling() got wrong element")
+	}
+	if a.Children[0].NextSibling() != nil || root.NextSibling() != nil {
+		t.Error("NextSibling() of object value or root should be nil")
+	}
+}
+
+func TestSetParents(t *testing.T) {
+	leaf := &Node{Type: NodeNumber, Value: "1"}
+	root := &Node{Type: NodeArray, Children: []*Node{leaf}}
+	SetParents(root)
+	if leaf.Parent != root || leaf.Path().String() != "[0]" {
+		t.Errorf("SetParents() got parent %v path %q", leaf.Parent, leaf.Path())
+	}
+}
+
+func TestNode_Set(t *testing.T) {
+	root, err := NewParser(`{"server": {"host": "localhost"}, "debug": true}`).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	port := &Node{Type: NodeNumber, Value: "80", File: "settings", StartLine: 3, StartCol: 1}
+	if err := root.Set(MustParsePath("server.port"), port); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Set(MustParsePath("log.level"), &Node{Type: NodeString, Value: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Set(MustParsePath("debug"), &Node{Type: NodeBoolean, Value: "false"}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"server":{"host":"localhost","port":80},"debug":false,"log":{"level":"info"}}`; string(b) != want {
+		t.Errorf("Set() = %s, want %s", b, want)
+	}
+	if got := root.Lookup(MustParsePath("server.port")); got != port || got.Path().String() != "server.port" {
+		t.Errorf("Lookup() = %v", got)
+	}
+
+	tests := []struct {
+		path      string
+		wantError string
+	}{
+		{"debug.x", "debug.x: cannot set a key, debug is not an object"},
+		{"server[0]", "server[0]: cannot set an array element"},
+	}
+	for _, tt := range tests {
+		err := root.Set(MustParsePath(tt.path), &Node{Type: NodeNull})
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Set(%s) error = %v, want %q", tt.path, err, tt.wantError)
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Path.String() != tt.path {
+			t.Errorf("Set(%s) error = %#v, want *ValidationError at %s", tt.path, err, tt.path)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package slowjson
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TriviaKind is the kind of trivia.
+type TriviaKind int
+
+const (
+	TriviaWhitespace TriviaKind = iota
+	TriviaLineComment
+	TriviaBlockComment
+)
+
+// Trivia is whitespace or a comment that has no meaning for the value but matters for formatting.
+// Text is the raw source, comments include their // or /* */ markers.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+
+	StartLine   int
+	StartCol    int
+	StartOffset int
+}
+
+// IsComment reports whether the trivia is a line or block comment.
+func (t Trivia) IsComment() bool {
+	return t.Kind == TriviaLineComment || t.Kind == TriviaBlockComment
+}
+
+// skipTrivia consumes whitespace and comments and returns them.
+// An unterminated block comment runs until the end of input.
+func (p *Parser) skipTrivia() []Trivia {
+	var trivia []Trivia
+	for !p.isEOF() {
+		t := Trivia{StartLine: p.line, StartCol: p.col, StartOffset: p.offset}
+		ch := p.peekChar()
+		switch {
+		case isSpace(ch):
+			t.Kind = TriviaWhitespace
+			for !p.isEOF() && isSpace(p.peekChar()) {
+				p.consumeChar()
+			}
+		case ch == '/' && p.peekCharAt(1) == '/':
+			t.Kind = TriviaLineComment
+			for !p.isEOF() && p.peekChar() != '\n' {
+				p.consumeChar()
+			}
+		case ch == '/' && p.peekCharAt(1) == '*':
+			t.Kind = TriviaBlockComment
+			p.consumeChar()
+			p.consumeChar()
+			for !p.isEOF() && !(p.peekChar() == '*' && p.peekCharAt(1) == '/') {
+				p.consumeChar()
+			}
+			p.consumeChar()
+			p.consumeChar()
+		default:
+			return trivia
+		}
+		t.Text = p.source[t.StartOffset:p.offset]
+		trivia = append(trivia, t)
+	}
+	return trivia
+}
+
+// AttachComments adds the comments on lines of their own in src, lines starting with one of markers
+// after blanks, to the Leading trivia of the node following them, the innermost one starting there.
+// Parsers of formats with line comments, e.g. "#" in YAML, call it so comments such as suppressions
+// stay with their node. Lines inside a scalar, e.g. of a multi-line string, are not comments. roots
+// are the documents parsed from src, with their parents set.
+func AttachComments(src string, roots []*Node, markers ...string) {
+	var nodes, leaves []*Node
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		nodes = append(nodes, n)
+		if len(n.Children) == 0 && n.Type != NodeObject && n.Type != NodeArray {
+			leaves = append(leaves, n)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	// nodes starting at the same offset stay in document order, outer ones first
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].StartOffset < nodes[j].StartOffset })
+	sort.SliceStable(leaves, func(i, j int) bool { return leaves[i].StartOffset < leaves[j].StartOffset })
+
+	for line, start := 1, 0; start < len(src); line++ {
+		end := strings.IndexByte(src[start:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += start
+		}
+		text := strings.TrimRight(src[start:end], "\r")
+		indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		at := start + len(indent)
+		lineStart := start
+		start = end + 1
+		if !hasPrefix(text[len(indent):], markers) || insideLeaf(leaves, at) {
+			continue
+		}
+		i := sort.Search(len(nodes), func(i int) bool { return nodes[i].StartOffset > at })
+		if i == len(nodes) {
+			continue
+		}
+		target := nodes[i]
+		for _, n := range nodes[i+1:] {
+			if n.StartOffset != target.StartOffset {
+				break
+			}
+			if n.Parent == target {
+				target = n
+			}
+		}
+		target.Leading = append(target.Leading, Trivia{
+			Kind:        TriviaLineComment,
+			Text:        text[len(indent):],
+			StartLine:   line,
+			StartCol:    utf8.RuneCountInString(src[lineStart:at]) + 1,
+			StartOffset: at,
+		})
+	}
+}
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// insideLeaf reports whether off is within one of leaves, which are sorted by start and do not overlap.
+func insideLeaf(leaves []*Node, off int) bool {
+	i := sort.Search(len(leaves), func(i int) bool { return leaves[i].StartOffset > off })
+	return i > 0 && off < leaves[i-1].EndOffset
+}
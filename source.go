@@ -0,0 +1,121 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Source provides one layer of a Config.
+type Source interface {
+	// Name identifies the source in provenance, e.g. the file path.
+	Name() string
+	// Load returns the current content of the source.
+	Load(ctx context.Context) (*slowjson.Node, error)
+}
+
+// MetadataSource is implemented by sources that describe what they loaded, e.g. a version or a signer.
+// Config calls Metadata after each successful Load and records the result in provenance.
+type MetadataSource interface {
+	Source
+	Metadata() map[string]string
+}
+
+// FileSource loads a JSON file, comments are allowed.
+type FileSource struct {
+	Path string
+	// ArenaBlock, when positive, allocates the nodes of each Load in blocks of that many nodes, see
+	// slowjson.Arena. It reduces GC pressure for large files that are reloaded often.
+	ArenaBlock int
+}
+
+// File creates a source reading the JSON file at path.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	p := slowjson.NewParser(string(b))
+	p.File = s.Path
+	if s.ArenaBlock > 0 {
+		p.Arena = slowjson.NewArena(s.ArenaBlock)
+	}
+	return p.Parse()
+}
+
+// WriteFile writes data to path like os.WriteFile, but replaces the file atomically so a reader or a
+// Watch never sees it half written. An existing file keeps its permissions and a symlink keeps pointing
+// at the replaced file, perm only applies to new files.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	target, err := filepath.EvalSymlinks(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		target = path
+	case err != nil:
+		return err
+	}
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), target)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// BytesSource parses JSON held in memory, e.g. embedded defaults.
+type BytesSource struct {
+	name string
+	data []byte
+}
+
+// Bytes creates a source parsing data, name is recorded as the file of its nodes.
+func Bytes(name string, data []byte) *BytesSource {
+	return &BytesSource{name: name, data: data}
+}
+
+// Name returns the name given to Bytes.
+func (s *BytesSource) Name() string {
+	return s.name
+}
+
+// Load parses the data.
+func (s *BytesSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	if err := CheckSize(ctx, s.name, len(s.data)); err != nil {
+		return nil, err
+	}
+	p := slowjson.NewParser(string(s.data))
+	p.File = s.name
+	return p.Parse()
+}
@@ -0,0 +1,8 @@
+// Package sourcemap records how positions in a transformed document map back to the original files.
+// Transformations such as templating or include expansion fill a Map so diagnostics can be
+// reported against the file the user edited instead of the intermediate output.
+//
+// Resolving is up to the transformation: preprocess is the only producer today and it resolves
+// the parse errors of its output with Errorf. Nodes keep their positions in the transformed
+// document, so diag, Config.Origin and decode errors are not resolved through a Map.
+package sourcemap
@@ -0,0 +1,165 @@
+package sourcemap
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Position is a location in an original file, line and column are 1-based.
+// A zero Col means the column is unknown, e.g. for output produced by a template action.
+type Position struct {
+	File string
+	Line int
+	Col  int
+}
+
+// String formats the position as file:line:col, omitting unknown parts.
+func (p Position) String() string {
+	s := p.File
+	if p.Line > 0 {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d", p.Line)
+		if p.Col > 0 {
+			s += fmt.Sprintf(":%d", p.Col)
+		}
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// IsValid reports whether the position has a line.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Map maps positions in a transformed document to positions in the original files.
+// It is a list of segments, each saying text starting at a transformed line/col was copied verbatim
+// from an original position. Text up to the next segment continues from that position.
+type Map struct {
+	// Name is the name of the transformed document, used by Chain to connect maps.
+	Name     string
+	segments []segment
+}
+
+type segment struct {
+	line int
+	col  int
+	orig Position
+}
+
+// New creates an empty map for the transformed document name.
+func New(name string) *Map {
+	return &Map{Name: name}
+}
+
+// Add records that the transformed text starting at line, col comes from orig.
+func (m *Map) Add(line, col int, orig Position) {
+	s := segment{line: line, col: col, orig: orig}
+	i := sort.Search(len(m.segments), func(i int) bool {
+		return !m.segments[i].before(line, col)
+	})
+	if i < len(m.segments) && m.segments[i].line == line && m.segments[i].col == col {
+		m.segments[i] = s
+		return
+	}
+	m.segments = append(m.segments, segment{})
+	copy(m.segments[i+1:], m.segments[i:])
+	m.segments[i] = s
+}
+
+// Len returns the number of recorded segments.
+func (m *Map) Len() int {
+	return len(m.segments)
+}
+
+// Resolve returns the original position of line, col in the transformed document.
+// It returns false when the position is before the first segment.
+func (m *Map) Resolve(line, col int) (Position, bool) {
+	i := sort.Search(len(m.segments), func(i int) bool {
+		return !m.segments[i].before(line, col+1)
+	})
+	if i == 0 {
+		return Position{}, false
+	}
+	s := m.segments[i-1]
+	p := s.orig
+	if line == s.line {
+		if p.Col > 0 {
+			p.Col += col - s.col
+		}
+	} else {
+		p.Line += line - s.line
+		if p.Col > 0 {
+			p.Col = col
+		}
+	}
+	return p, true
+}
+
+// before reports whether the segment starts before line, col.
+func (s segment) before(line, col int) bool {
+	return s.line < line || s.line == line && s.col < col
+}
+
+// Chain resolves positions through a sequence of transformations.
+// Maps are in the order the transformations were applied, the last one describes the final output.
+type Chain []*Map
+
+// Resolve maps a position in the final output back through every transformation.
+// It stops early when a position lands in a file the previous transformation did not produce.
+func (c Chain) Resolve(line, col int) (Position, bool) {
+	if len(c) == 0 {
+		return Position{}, false
+	}
+	p, ok := c[len(c)-1].Resolve(line, col)
+	if !ok {
+		return p, false
+	}
+	for i := len(c) - 2; i >= 0; i-- {
+		if p.File != c[i].Name {
+			break
+		}
+		prev, ok := c[i].Resolve(p.Line, max(p.Col, 1))
+		if !ok {
+			break
+		}
+		if p.Col == 0 {
+			prev.Col = 0
+		}
+		p = prev
+	}
+	return p, true
+}
+
+// Resolver is implemented by Map and Chain.
+type Resolver interface {
+	Resolve(line, col int) (Position, bool)
+}
+
+// Error is an error located in an original file.
+type Error struct {
+	Pos Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf resolves line, col with r and wraps err with the original position.
+// If the position can't be resolved, the transformed position is reported against fallback.
+func Errorf(r Resolver, fallback string, line, col int, err error) error {
+	p, ok := r.Resolve(line, col)
+	if !ok {
+		p = Position{File: fallback, Line: line, Col: col}
+	}
+	return &Error{Pos: p, Err: err}
+}
@@ -0,0 +1,84 @@
+package sourcemap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMap_Resolve(t *testing.T) {
+	// main.json includes inc.json at line 3, the included content is 2 lines long
+	m := New("out.json")
+	m.Add(1, 1, Position{File: "main.json", Line: 1, Col: 1})
+	m.Add(3, 5, Position{File: "inc.json", Line: 1, Col: 1})
+	m.Add(4, 10, Position{File: "main.json", Line: 3, Col: 20})
+
+	tests := []struct {
+		line, col int
+		want      Position
+	}{
+		{1, 1, Position{"main.json", 1, 1}},
+		{2, 7, Position{"main.json", 2, 7}},
+		{3, 4, Position{"main.json", 3, 4}},
+		{3, 5, Position{"inc.json", 1, 1}},
+		{3, 8, Position{"inc.json", 1, 4}},
+		{4, 2, Position{"inc.json", 2, 2}},
+		{4, 10, Position{"main.json", 3, 20}},
+		{6, 3, Position{"main.json", 5, 3}},
+	}
+	for _, tt := range tests {
+		got, ok := m.Resolve(tt.line, tt.col)
+		if !ok || got != tt.want {
+			t.Errorf("Resolve(%d, %d) = %v, %v, want %v", tt.line, tt.col, got, ok, tt.want)
+		}
+	}
+	if _, ok := New("empty").Resolve(1, 1); ok {
+		t.Error("Resolve() on empty map should not resolve")
+	}
+}
+
+func TestMap_Add_Replace(t *testing.T) {
+	m := New("out")
+	m.Add(2, 1, Position{File: "a", Line: 1})
+	m.Add(1, 1, Position{File: "b", Line: 1})
+	m.Add(2, 1, Position{File: "c", Line: 9})
+	if m.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", m.Len())
+	}
+	if got, _ := m.Resolve(2, 4); got.File != "c" || got.Line != 9 || got.Col != 0 {
+		t.Errorf("Resolve() = %v", got)
+	}
+}
+
+func TestChain_Resolve(t *testing.T) {
+	// config.tmpl is rendered to config.rendered, then includes are expanded into config.json
+	render := New("config.rendered")
+	render.Add(1, 1, Position{File: "config.tmpl", Line: 1})
+	render.Add(2, 1, Position{File: "config.tmpl", Line: 5})
+	expand := New("config.json")
+	expand.Add(1, 1, Position{File: "config.rendered", Line: 1, Col: 1})
+	expand.Add(3, 1, Position{File: "extra.json", Line: 1, Col: 1})
+
+	c := Chain{render, expand}
+	if got, _ := c.Resolve(2, 3); got != (Position{File: "config.tmpl", Line: 5}) {
+		t.Errorf("Resolve(2, 3) = %v", got)
+	}
+	if got, _ := c.Resolve(4, 2); got != (Position{File: "extra.json", Line: 2, Col: 2}) {
+		t.Errorf("Resolve(4, 2) = %v", got)
+	}
+}
+
+func TestErrorf(t *testing.T) {
+	m := New("out")
+	m.Add(1, 1, Position{File: "in", Line: 10, Col: 1})
+	base := errors.New("boom")
+	err := Errorf(m, "out", 2, 3, base)
+	if err.Error() != "in:11:3: boom" {
+		t.Errorf("Errorf() = %v", err)
+	}
+	if !errors.Is(err, base) {
+		t.Error("Errorf() should wrap the original error")
+	}
+	if got := Errorf(New("x"), "x", 1, 2, base).Error(); got != "x:1:2: boom" {
+		t.Errorf("Errorf() fallback = %v", got)
+	}
+}
@@ -0,0 +1,3 @@
+// Package sqlsource loads runtime settings kept in a SQL database through database/sql, either as
+// key/value rows or as a JSON column, so they are merged, traced and explained like config files.
+package sqlsource
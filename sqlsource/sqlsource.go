@@ -0,0 +1,180 @@
+package sqlsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Source loads config with a query.
+type Source struct {
+	// VersionQuery returns a single value that changes with the settings, e.g.
+	// "SELECT max(updated_at) FROM settings". Changed runs it instead of the full query when set.
+	VersionQuery string
+
+	db    *sql.DB
+	name  string
+	query string
+	args  []interface{}
+	json  bool
+
+	mu      sync.Mutex
+	hash    string
+	version string
+}
+
+// KeyValue creates a source from a query returning key and value columns, e.g.
+// "SELECT key, value FROM settings". Keys are paths like "server.port". Values holding JSON,
+// e.g. 80, true or {"a": 1}, are parsed, anything else is a string and NULL is null.
+// The nodes of a row are in the file "name[key]", so provenance names the row.
+func KeyValue(db *sql.DB, name, query string, args ...interface{}) *Source {
+	return &Source{db: db, name: name, query: query, args: args}
+}
+
+// JSON creates a source from a query returning one row with a JSON document, e.g.
+// "SELECT doc FROM app_config WHERE app = $1". Comments are allowed in the document.
+func JSON(db *sql.DB, name, query string, args ...interface{}) *Source {
+	return &Source{db: db, name: name, query: query, args: args, json: true}
+}
+
+// Name returns the name given to KeyValue or JSON.
+func (s *Source) Name() string {
+	return s.name
+}
+
+type row struct {
+	key   string
+	value sql.NullString
+}
+
+func (s *Source) rows(ctx context.Context) ([]row, error) {
+	rs, err := s.db.QueryContext(ctx, s.query, s.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	var rows []row
+	for rs.Next() {
+		var r row
+		if s.json {
+			err = rs.Scan(&r.value)
+		} else {
+			err = rs.Scan(&r.key, &r.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, r)
+	}
+	return rows, rs.Err()
+}
+
+// hash identifies the content of rows so Changed can compare it.
+func hash(rows []row) string {
+	h := sha256.New()
+	for _, r := range rows {
+		fmt.Fprintf(h, "%q %v %q\n", r.key, r.value.Valid, r.value.String)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Load runs the query and builds the config.
+func (s *Source) Load(ctx context.Context) (*slowjson.Node, error) {
+	var version string
+	if s.VersionQuery != "" {
+		if err := s.db.QueryRowContext(ctx, s.VersionQuery).Scan(&version); err != nil {
+			return nil, fmt.Errorf("version query: %w", err)
+		}
+	}
+	rows, err := s.rows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var n *slowjson.Node
+	if s.json {
+		n, err = s.document(ctx, rows)
+	} else {
+		n, err = s.keyValues(rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.hash, s.version = hash(rows), version
+	s.mu.Unlock()
+	return n, nil
+}
+
+func (s *Source) document(ctx context.Context, rows []row) (*slowjson.Node, error) {
+	if len(rows) != 1 {
+		return nil, fmt.Errorf("query returned %d rows, want 1", len(rows))
+	}
+	if !rows[0].value.Valid {
+		return nil, fmt.Errorf("query returned NULL")
+	}
+	if err := tracedconfig.CheckSize(ctx, s.name, len(rows[0].value.String)); err != nil {
+		return nil, err
+	}
+	p := slowjson.NewParser(rows[0].value.String)
+	p.File = s.name
+	return p.Parse()
+}
+
+func (s *Source) keyValues(rows []row) (*slowjson.Node, error) {
+	root := &slowjson.Node{Type: slowjson.NodeObject, File: s.name}
+	for _, r := range rows {
+		path, err := slowjson.ParsePath(r.key)
+		if err != nil {
+			return nil, err
+		}
+		v := value(r, fmt.Sprintf("%s[%s]", s.name, r.key))
+		if err := root.Set(path, v); err != nil {
+			return nil, fmt.Errorf("row %q: %w", r.key, err)
+		}
+	}
+	return root, nil
+}
+
+// value parses a row value, NULL is null.
+func value(r row, file string) *slowjson.Node {
+	if !r.value.Valid {
+		return &slowjson.Node{Type: slowjson.NodeNull, Value: "null", File: file, StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1}
+	}
+	return slowjson.ParseValueOrString(r.value.String, file)
+}
+
+// Metadata records the result of VersionQuery at the last load.
+func (s *Source) Metadata() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.version == "" {
+		return nil
+	}
+	return map[string]string{"version": s.version}
+}
+
+// Changed reports whether the settings differ from the last load, by VersionQuery when set and
+// otherwise by running the query again. It lets Config.Watch poll the database.
+func (s *Source) Changed(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	last, lastVersion := s.hash, s.version
+	s.mu.Unlock()
+	if s.VersionQuery != "" {
+		var version string
+		if err := s.db.QueryRowContext(ctx, s.VersionQuery).Scan(&version); err != nil {
+			return false, fmt.Errorf("version query: %w", err)
+		}
+		return last == "" || version != lastVersion, nil
+	}
+	rows, err := s.rows(ctx)
+	if err != nil {
+		return false, err
+	}
+	return hash(rows) != last, nil
+}
@@ -0,0 +1,172 @@
+package sqlsource
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+// fakeDB answers queries with fixed rows, it is registered as the "fakesql" driver.
+var fakeDB = struct {
+	sync.Mutex
+	results map[string][][]driver.Value
+}{results: map[string][][]driver.Value{}}
+
+func setRows(query string, rows ...[]driver.Value) {
+	fakeDB.Lock()
+	defer fakeDB.Unlock()
+	fakeDB.results[query] = rows
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("no transactions") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, fmt.Errorf("no exec") }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	fakeDB.Lock()
+	defer fakeDB.Unlock()
+	rows, ok := fakeDB.results[s.query]
+	if !ok {
+		return nil, fmt.Errorf("no such table")
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct{ rows [][]driver.Value }
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) > 0 && len(r.rows[0]) == 1 {
+		return []string{"doc"}
+	}
+	return []string{"key", "value"}
+}
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("fakesql", fakeDriver{})
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("fakesql", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestKeyValue(t *testing.T) {
+	db := openDB(t)
+	setRows("SELECT key, value FROM settings",
+		[]driver.Value{"server.port", "8080"},
+		[]driver.Value{"server.host", "example.com"},
+		[]driver.Value{"features", `["a", "b"]`},
+		[]driver.Value{"proxy", nil},
+	)
+	c := tracedconfig.NewConfig(KeyValue(db, "settings", "SELECT key, value FROM settings"))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Server struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"server"`
+		Features []string `json:"features"`
+		Proxy    *string  `json:"proxy"`
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.Host != "example.com" || len(cfg.Features) != 2 || cfg.Proxy != nil {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	o, _ := c.Origin("server.port")
+	if got, want := o.String(), "settings[server.port]:1:1 (settings)"; got != want {
+		t.Errorf("Origin() = %q, want %q", got, want)
+	}
+
+	setRows("SELECT key, value FROM bad", []driver.Value{"port", "80"}, []driver.Value{"port.x", "1"})
+	err := tracedconfig.NewConfig(KeyValue(db, "bad", "SELECT key, value FROM bad")).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `row "port.x": port.x: cannot set a key, port is not an object`) {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	db := openDB(t)
+	setRows("SELECT doc FROM app_config", []driver.Value{"{\n  // tuned\n  \"workers\": 4\n}"})
+	c := tracedconfig.NewConfig(JSON(db, "app_config", "SELECT doc FROM app_config"))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("workers"); got == nil || got.Location() != "app_config:3:14" {
+		t.Errorf("workers = %v", got)
+	}
+
+	setRows("SELECT doc FROM empty")
+	err := tracedconfig.NewConfig(JSON(db, "empty", "SELECT doc FROM empty")).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "query returned 0 rows, want 1") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	db := openDB(t)
+	setRows("SELECT key, value FROM flags", []driver.Value{"debug", "false"})
+	setRows("SELECT max(updated_at) FROM flags", []driver.Value{"2024-01-01"})
+	plain := KeyValue(db, "flags", "SELECT key, value FROM flags")
+	versioned := KeyValue(db, "flags", "SELECT key, value FROM flags")
+	versioned.VersionQuery = "SELECT max(updated_at) FROM flags"
+	ctx := context.Background()
+	for _, s := range []*Source{plain, versioned} {
+		if _, err := s.Load(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if changed, err := s.Changed(ctx); err != nil || changed {
+			t.Errorf("Changed() = %v, %v, want false", changed, err)
+		}
+	}
+	if got := versioned.Metadata()["version"]; got != "2024-01-01" {
+		t.Errorf("Metadata() version = %q", got)
+	}
+
+	setRows("SELECT key, value FROM flags", []driver.Value{"debug", "true"})
+	if changed, _ := plain.Changed(ctx); !changed {
+		t.Error("Changed() = false after the rows changed")
+	}
+	if changed, _ := versioned.Changed(ctx); changed {
+		t.Error("Changed() = true while the version is the same")
+	}
+	setRows("SELECT max(updated_at) FROM flags", []driver.Value{"2024-01-02"})
+	if changed, _ := versioned.Changed(ctx); !changed {
+		t.Error("Changed() = false after the version changed")
+	}
+}
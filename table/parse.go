@@ -0,0 +1,230 @@
+package table
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Options configures Parse.
+type Options struct {
+	// Comma separates the cells, ',' when zero and '\t' for TSV.
+	Comma rune
+}
+
+// Parse parses a CSV file into an array of its rows. file is recorded as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	return Options{}.Parse(data, file)
+}
+
+// Parse parses a table into an array of its rows.
+func (o Options) Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{comma: o.Comma, src: string(data), file: file, line: 1, col: 1}
+	if p.comma == 0 {
+		p.comma = ','
+	}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	slowjson.SetParents(root)
+	return root, nil
+}
+
+type parser struct {
+	comma rune
+	src   string
+	file  string
+
+	off, line, col int
+}
+
+type mark struct {
+	off, line, col int
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) span(typ slowjson.NodeType, value string, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		Value:       value,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+// peek returns the rune at the offset, utf8.RuneError at the end.
+func (p *parser) peek() rune {
+	r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+	return r
+}
+
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+// lineEnd consumes a line break, reporting whether there was one.
+func (p *parser) lineEnd() bool {
+	if strings.HasPrefix(p.src[p.off:], "\r\n") {
+		p.next()
+	}
+	if p.peek() != '\n' || p.eof() {
+		return false
+	}
+	p.next()
+	return true
+}
+
+func (p *parser) parse() (*slowjson.Node, error) {
+	root := p.span(slowjson.NodeArray, "", p.mark(), p.mark())
+	var header []*slowjson.Node
+	for {
+		for p.lineEnd() {
+			// blank lines are skipped
+		}
+		if p.eof() {
+			break
+		}
+		start := p.mark()
+		cells, err := p.row()
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			header = cells
+			seen := map[string]*slowjson.Node{}
+			for i, c := range header {
+				if c.Value == "" {
+					return nil, p.errorf(mark{c.StartOffset, c.StartLine, c.StartCol}, "column %d has no name", i+1)
+				}
+				if prev, ok := seen[c.Value]; ok {
+					return nil, p.errorf(mark{c.StartOffset, c.StartLine, c.StartCol}, "duplicate column %s, first at %s", c.Value, prev.Location())
+				}
+				seen[c.Value] = c
+			}
+			continue
+		}
+		if len(cells) != len(header) {
+			return nil, p.errorf(start, "the row has %d cells, the header %d", len(cells), len(header))
+		}
+		obj := p.span(slowjson.NodeObject, "", start, p.mark())
+		for i, c := range cells {
+			key := *header[i]
+			key.Children = []*slowjson.Node{c}
+			obj.Children = append(obj.Children, &key)
+		}
+		root.Children = append(root.Children, obj)
+	}
+	end := p.mark()
+	root.EndLine, root.EndCol, root.EndOffset = end.line, end.col, end.off
+	return root, nil
+}
+
+// row reads the cells of a row up to its line break.
+func (p *parser) row() ([]*slowjson.Node, error) {
+	var cells []*slowjson.Node
+	for {
+		cell, err := p.cell()
+		if err != nil {
+			return nil, err
+		}
+		cells = append(cells, cell)
+		if p.peek() != p.comma || p.eof() {
+			return cells, nil
+		}
+		p.next()
+	}
+}
+
+// cell reads a cell, leaving the offset at the separator or line break after it.
+func (p *parser) cell() (*slowjson.Node, error) {
+	start := p.mark()
+	if p.peek() != '"' || p.eof() {
+		i := strings.IndexAny(p.src[p.off:], string(p.comma)+"\r\n")
+		if i < 0 {
+			i = len(p.src) - p.off
+		}
+		text := strings.TrimSuffix(p.src[p.off:p.off+i], "\r")
+		if j := strings.IndexByte(text, '"'); j >= 0 {
+			for range text[:j] {
+				p.next()
+			}
+			return nil, p.errorf(p.mark(), "unexpected \" in an unquoted cell, quote the whole cell")
+		}
+		for range text {
+			p.next()
+		}
+		return p.span(slowjson.NodeString, text, start, p.mark()), nil
+	}
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated quoted cell")
+		}
+		r := p.peek()
+		p.next()
+		if r == '\r' && p.peek() == '\n' {
+			// line breaks in cells are read as \n, like encoding/csv does
+			continue
+		}
+		if r != '"' {
+			b.WriteRune(r)
+			continue
+		}
+		if p.peek() == '"' && !p.eof() {
+			b.WriteByte('"')
+			p.next()
+			continue
+		}
+		break
+	}
+	if !p.eof() && p.peek() != p.comma && p.peek() != '\n' && !strings.HasPrefix(p.src[p.off:], "\r\n") {
+		return nil, p.errorf(p.mark(), "expected %q or a line break after the quoted cell", p.comma)
+	}
+	return p.span(slowjson.NodeString, b.String(), start, p.mark()), nil
+}
+
+// Quote returns s as a cell of a table separated by comma, in double quotes when it holds the comma, a
+// quote or a line break.
+func Quote(s string, comma rune) string {
+	if !strings.ContainsAny(s, string(comma)+"\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
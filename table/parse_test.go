@@ -0,0 +1,91 @@
+package table
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := "region,zone,note\r\n" +
+		"eu,eu-west-1a,\"primary, \"\"hot\"\"\"\r\n" +
+		"\r\n" +
+		"us,,\"two\r\nlines\"\r\n" +
+		"ap,ap-south-1a,ünï\n"
+	n, err := Parse([]byte(input), "zones.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"region":"eu","zone":"eu-west-1a","note":"primary, \"hot\""},` +
+		`{"region":"us","zone":"","note":"two\nlines"},` +
+		`{"region":"ap","zone":"ap-south-1a","note":"ünï"}]`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	note := n.Lookup(slowjson.Path{}.Index(0).Key("note"))
+	if note.Location() != "zones.csv:2:15" || note.EndCol != 33 || note.Parent.Location() != "zones.csv:1:13" {
+		t.Errorf("[0].note at %s to col %d, key at %s", note.Location(), note.EndCol, note.Parent.Location())
+	}
+	if zone := n.Lookup(slowjson.Path{}.Index(1).Key("zone")); zone.Location() != "zones.csv:4:4" || zone.EndCol != 4 {
+		t.Errorf("[1].zone at %s to col %d", zone.Location(), zone.EndCol)
+	}
+	if row := n.Children[1]; row.Location() != "zones.csv:4:1" || row.EndLine != 5 || row.EndCol != 7 {
+		t.Errorf("[1] at %s to %d:%d", row.Location(), row.EndLine, row.EndCol)
+	}
+	if note := n.Lookup(slowjson.Path{}.Index(2).Key("note")); note.Location() != "zones.csv:6:16" || note.EndCol != 19 {
+		t.Errorf("[2].note at %s to col %d", note.Location(), note.EndCol)
+	}
+}
+
+func TestParse_TSV(t *testing.T) {
+	n, err := Options{Comma: '\t'}.Parse([]byte("name\tvalue\nsize\t1,024\nempty\t\n"), "limits.tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, n); got != `[{"name":"size","value":"1,024"},{"name":"empty","value":""}]` {
+		t.Errorf("Parse() = %s", got)
+	}
+	if v := n.Lookup(slowjson.Path{}.Index(0).Key("value")); v.Location() != "limits.tsv:2:6" {
+		t.Errorf("[0].value at %s", v.Location())
+	}
+	if n, err := Parse([]byte("a,b\n"), ""); err != nil || marshal(t, n) != "[]" {
+		t.Errorf("Parse() of a header = %v, %v", n, err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"a,b\n1,2,3\n":    `the row has 3 cells, the header 2 at f:2:1`,
+		"a,b\n1\n":        `the row has 1 cells, the header 2 at f:2:1`,
+		"a,,c\n":          `column 2 has no name at f:1:3`,
+		"a,b,a\n":         `duplicate column a, first at f:1:1 at f:1:5`,
+		"a\nx\"y\n":       `unexpected " in an unquoted cell, quote the whole cell at f:2:2`,
+		"a\n\"x\n":        `unterminated quoted cell at f:2:1`,
+		"a,b\n\"x\"y,1\n": `expected ',' or a line break after the quoted cell at f:2:4`,
+	} {
+		_, err := Parse([]byte(input), "f")
+		if err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v, want %s", input, err, want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for s, want := range map[string]string{"plain": "plain", "a,b": `"a,b"`, `say "hi"`: `"say ""hi"""`, "a\tb": "a\tb"} {
+		if got := Quote(s, ','); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", s, got, want)
+		}
+	}
+	if got := Quote("a\tb", '\t'); got != "\"a\tb\"" {
+		t.Errorf("Quote(%q, tab) = %s", "a\tb", got)
+	}
+}
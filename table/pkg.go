@@ -0,0 +1,14 @@
+// Package table reads CSV and TSV files, such as the lookup tables kept next to configs, into slowjson
+// nodes with a position for every cell, so they go through the same validation, provenance and
+// diagnostics as JSON.
+//
+// The first row names the columns and every other row becomes an object of its cells by column, so
+//
+//	region,zone,replicas
+//	eu,eu-west-1a,3
+//
+// is [{"region": "eu", "zone": "eu-west-1a", "replicas": "3"}]. Cells are strings, quoted like RFC 4180:
+// in double quotes with "" for a quote, spanning lines when needed. The keys of a row are positioned at
+// the header cells and its values at its own cells. Wrap a source in tracedconfig.Mount to load a table
+// under a key of the config.
+package table
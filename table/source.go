@@ -0,0 +1,40 @@
+package table
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FileSource is a config source reading a CSV or TSV file.
+type FileSource struct {
+	Path    string
+	Options Options
+}
+
+// File creates a source reading the table at path, separated by tabs when the path ends in .tsv or .tab
+// and by commas otherwise.
+func File(path string) *FileSource {
+	s := &FileSource{Path: path}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".tsv" || ext == ".tab" {
+		s.Options.Comma = '\t'
+	}
+	return s
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Options.Parse(b, s.Path)
+}
@@ -0,0 +1,37 @@
+package table
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "zones.tsv")
+	if err := os.WriteFile(path, []byte("region\tzone\neu\teu-west-1a\nus\tus-east-1b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := tracedconfig.NewConfig(tracedconfig.Mount(File(path), "zones"))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg struct {
+		Zones []struct {
+			Region string
+			Zone   string
+		}
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Zones) != 2 || cfg.Zones[1].Zone != "us-east-1b" {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if n := c.Get("zones[1].zone"); n.File != path || n.StartLine != 3 || n.StartCol != 4 {
+		t.Errorf("zones[1].zone at %s", n.Location())
+	}
+}
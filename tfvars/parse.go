@@ -0,0 +1,548 @@
+package tfvars
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Parse parses a .tfvars file in HCL native syntax into an object of its variables. file is recorded
+// as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{src: string(data), file: file, line: 1, col: 1}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root, err := p.body()
+	if err != nil {
+		return nil, err
+	}
+	slowjson.SetParents(root)
+	return root, nil
+}
+
+type parser struct {
+	src  string
+	file string
+
+	off, line, col int
+	depth          int
+}
+
+type mark struct {
+	off, line, col int
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) span(typ slowjson.NodeType, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *parser) end(n *slowjson.Node) {
+	m := p.mark()
+	n.EndLine, n.EndCol, n.EndOffset = m.line, m.col, m.off
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+func (p *parser) advance(n int) {
+	for end := p.off + n; p.off < end; {
+		p.next()
+	}
+}
+
+// skip consumes spaces and comments, and line breaks as well when newlines is set.
+func (p *parser) skip(newlines bool) error {
+	for !p.eof() {
+		rest := p.src[p.off:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r':
+			p.next()
+		case rest[0] == '\n' && newlines:
+			p.next()
+		case rest[0] == '#' || strings.HasPrefix(rest, "//"):
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case strings.HasPrefix(rest, "/*"):
+			start := p.mark()
+			i := strings.Index(rest[2:], "*/")
+			if i < 0 {
+				return p.errorf(start, "unterminated comment")
+			}
+			p.advance(i + 4)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// body parses the attributes of the file, one per line.
+func (p *parser) body() (*slowjson.Node, error) {
+	root := p.span(slowjson.NodeObject, p.mark(), p.mark())
+	seen := map[string]*slowjson.Node{}
+	for {
+		if err := p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			break
+		}
+		start := p.mark()
+		name := p.identifier()
+		if name == "" {
+			return nil, p.errorf(start, "expected a variable name")
+		}
+		key := p.span(slowjson.NodeString, start, p.mark())
+		key.Value = name
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case '=':
+		case '{':
+			return nil, p.errorf(start, "blocks are not allowed in a variable file, write %s = {...}", name)
+		default:
+			return nil, p.errorf(p.mark(), "expected '=' after the variable name")
+		}
+		p.next()
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[name]; ok {
+			return nil, p.errorf(start, "variable %s is already set at %s", name, prev.Location())
+		}
+		seen[name] = key
+		key.Children = []*slowjson.Node{val}
+		root.Children = append(root.Children, key)
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf(p.mark(), "expected the end of the line")
+		}
+	}
+	p.end(root)
+	return root, nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// identifier consumes an HCL identifier, empty when there is none.
+func (p *parser) identifier() string {
+	start := p.off
+	for i, r := range p.src[p.off:] {
+		if i == 0 && !isIdentStart(r) || !isIdent(r) {
+			break
+		}
+		p.next()
+	}
+	return p.src[start:p.off]
+}
+
+func (p *parser) value() (*slowjson.Node, error) {
+	start := p.mark()
+	switch c := p.peek(); {
+	case c == '"':
+		return p.str()
+	case strings.HasPrefix(p.src[p.off:], "<<"):
+		return p.heredoc()
+	case c == '[':
+		return p.list()
+	case c == '{':
+		return p.object()
+	case c == '-' || c >= '0' && c <= '9':
+		return p.number()
+	case c == 0 || c == '\n':
+		return nil, p.errorf(start, "expected a value")
+	}
+	name := p.identifier()
+	switch name {
+	case "true", "false":
+		n := p.span(slowjson.NodeBoolean, start, p.mark())
+		n.Value = name
+		return n, nil
+	case "null":
+		n := p.span(slowjson.NodeNull, start, p.mark())
+		n.Value = name
+		return n, nil
+	case "":
+		return nil, p.errorf(start, "expected a value")
+	}
+	// name the whole reference, e.g. var.region or local.tags.env
+	for p.peek() == '.' && p.off+1 < len(p.src) && isIdentStart(rune(p.src[p.off+1])) {
+		p.next()
+		name += "." + p.identifier()
+	}
+	return nil, p.errorf(start, "%s is not a literal value, variable files cannot use references or functions", name)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > slowjson.MaxDepth {
+		return p.errorf(p.mark(), "exceeded max depth %d", slowjson.MaxDepth)
+	}
+	return nil
+}
+
+func (p *parser) list() (*slowjson.Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	start := p.mark()
+	arr := p.span(slowjson.NodeArray, start, start)
+	p.next()
+	for {
+		if err := p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.peek() == ']' {
+			break
+		}
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated list, expected ']'")
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr.Children = append(arr.Children, v)
+		if err := p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.peek() == ',' {
+			p.next()
+			continue
+		}
+		if p.peek() != ']' {
+			if p.eof() {
+				return nil, p.errorf(start, "unterminated list, expected ']'")
+			}
+			return nil, p.errorf(p.mark(), "expected ',' or ']'")
+		}
+	}
+	p.next()
+	p.end(arr)
+	return arr, nil
+}
+
+// object parses a map, whose members are separated by commas or line breaks and written key = value
+// or key: value.
+func (p *parser) object() (*slowjson.Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	start := p.mark()
+	obj := p.span(slowjson.NodeObject, start, start)
+	seen := map[string]*slowjson.Node{}
+	p.next()
+	for {
+		if err := p.skip(true); err != nil {
+			return nil, err
+		}
+		if p.peek() == '}' {
+			break
+		}
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated map, expected '}'")
+		}
+		keyStart := p.mark()
+		var key *slowjson.Node
+		if p.peek() == '"' {
+			k, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = k
+		} else {
+			name := p.identifier()
+			if name == "" {
+				return nil, p.errorf(keyStart, "expected a key")
+			}
+			key = p.span(slowjson.NodeString, keyStart, p.mark())
+			key.Value = name
+		}
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		if c := p.peek(); c != '=' && c != ':' {
+			return nil, p.errorf(p.mark(), "expected '=' or ':' after the key")
+		}
+		p.next()
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[key.Value]; ok {
+			return nil, p.errorf(keyStart, "duplicate key %q (first set at %s)", key.Value, prev.Location())
+		}
+		seen[key.Value] = key
+		key.Children = []*slowjson.Node{val}
+		obj.Children = append(obj.Children, key)
+		if err := p.skip(false); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',', '\n':
+			p.next()
+		case '}':
+		default:
+			if p.eof() {
+				return nil, p.errorf(start, "unterminated map, expected '}'")
+			}
+			return nil, p.errorf(p.mark(), "expected ',', a line break or '}'")
+		}
+	}
+	p.next()
+	p.end(obj)
+	return obj, nil
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`)
+
+func (p *parser) number() (*slowjson.Node, error) {
+	start := p.mark()
+	m := numberPattern.FindString(p.src[p.off:])
+	if m == "" {
+		return nil, p.errorf(start, "invalid number")
+	}
+	p.advance(len(m))
+	if isIdent(rune(p.peek())) || p.peek() == '.' {
+		return nil, p.errorf(start, "invalid number, quote strings")
+	}
+	n := p.span(slowjson.NodeNumber, start, p.mark())
+	// JSON numbers have no leading zeros
+	sign, digits := "", m
+	if strings.HasPrefix(m, "-") {
+		sign, digits = "-", m[1:]
+	}
+	if t := strings.TrimLeft(digits, "0"); t == "" || t[0] < '0' || t[0] > '9' {
+		digits = "0" + t
+	} else {
+		digits = t
+	}
+	n.Value = sign + digits
+	return n, nil
+}
+
+// str parses a quoted string. Template sequences are only allowed escaped, as $${ and %%{.
+func (p *parser) str() (*slowjson.Node, error) {
+	start := p.mark()
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return nil, p.errorf(start, "unterminated string")
+		}
+		rest := p.src[p.off:]
+		switch {
+		case rest[0] == '"':
+			p.next()
+			n := p.span(slowjson.NodeString, start, p.mark())
+			n.Value = b.String()
+			return n, nil
+		case rest[0] == '\\':
+			if err := p.escape(&b); err != nil {
+				return nil, err
+			}
+		default:
+			if err := p.template(&b); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// template copies the next character to b, unescaping $${ and %%{ and rejecting template sequences.
+func (p *parser) template(b *strings.Builder) error {
+	rest := p.src[p.off:]
+	switch {
+	case strings.HasPrefix(rest, "$${"), strings.HasPrefix(rest, "%%{"):
+		b.WriteString(rest[1:3])
+		p.advance(3)
+	case strings.HasPrefix(rest, "${"), strings.HasPrefix(rest, "%{"):
+		return p.errorf(p.mark(), "templates are not allowed in a variable file, write $${ for a literal ${")
+	default:
+		r, size := utf8.DecodeRuneInString(rest)
+		b.WriteRune(r)
+		p.advance(size)
+	}
+	return nil
+}
+
+func (p *parser) escape(b *strings.Builder) error {
+	at := p.mark()
+	p.next()
+	c := p.peek()
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.off+1+size > len(p.src) {
+			return p.errorf(at, "invalid escape sequence")
+		}
+		v, err := strconv.ParseUint(p.src[p.off+1:p.off+1+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(v)) {
+			return p.errorf(at, "invalid escape sequence")
+		}
+		b.WriteRune(rune(v))
+		p.advance(size)
+	default:
+		return p.errorf(at, "invalid escape sequence")
+	}
+	p.next()
+	return nil
+}
+
+// heredoc parses <<EOT or, removing the common indentation, <<-EOT strings up to the line holding
+// only EOT. The line break before EOT is part of the string, like in Terraform.
+func (p *parser) heredoc() (*slowjson.Node, error) {
+	start := p.mark()
+	p.advance(2)
+	indented := p.peek() == '-'
+	if indented {
+		p.next()
+	}
+	marker := p.identifier()
+	if marker == "" {
+		return nil, p.errorf(start, "expected a heredoc marker after <<")
+	}
+	if p.peek() == '\r' {
+		p.next()
+	}
+	if p.peek() != '\n' {
+		return nil, p.errorf(p.mark(), "expected a line break after the heredoc marker")
+	}
+	p.next()
+	var lines []string
+	for {
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated heredoc, expected %s", marker)
+		}
+		lineStart := p.off
+		for !p.eof() && p.peek() != '\n' {
+			p.next()
+		}
+		line := strings.TrimSuffix(p.src[lineStart:p.off], "\r")
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+		lines = append(lines, line)
+		p.next()
+	}
+	if indented {
+		lines = dedent(lines)
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		// the content is a template like quoted strings
+		sub := &parser{src: line, file: p.file, line: 1, col: 1}
+		for !sub.eof() {
+			if err := sub.template(&b); err != nil {
+				return nil, p.errorf(start, "templates are not allowed in a variable file, write $${ for a literal ${")
+			}
+		}
+		b.WriteByte('\n')
+	}
+	n := p.span(slowjson.NodeString, start, p.mark())
+	n.Value = b.String()
+	return n, nil
+}
+
+// dedent removes the indentation the non-blank lines share.
+func dedent(lines []string) []string {
+	common := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if common < 0 || indent < common {
+			common = indent
+		}
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= common && common > 0 {
+			l = l[common:]
+		} else if common > 0 {
+			l = strings.TrimLeft(l, " \t")
+		}
+		out[i] = l
+	}
+	return out
+}
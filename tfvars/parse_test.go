@@ -0,0 +1,106 @@
+package tfvars
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := `# region of the deployment
+region         = "eu-west-1" // inline
+instance_count = 3
+ratio          = -0.50
+enabled        = true
+owner          = null
+zones = [
+  "a", "b",
+  "c", # trailing comma
+]
+tags = {
+  env        = "prod"
+  "cost/center": "42", team = "infra"
+  nested = { a = [1, 2] }
+}
+/* a block
+   comment */
+policy = <<-EOT
+    {
+      "Version": "$${var}"
+    }
+    EOT
+escaped = "tab\tquote\" é $${x} %%{y}"
+`
+	n, err := Parse([]byte(input), "prod.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"region":"eu-west-1","instance_count":3,"ratio":-0.50,"enabled":true,"owner":null,"zones":["a","b","c"],` +
+		`"tags":{"env":"prod","cost/center":"42","team":"infra","nested":{"a":[1,2]}},` +
+		`"policy":"{\n  \"Version\": \"${var}\"\n}\n","escaped":"tab\tquote\" é ${x} %{y}"}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() =\n%s\nwant\n%s", got, want)
+	}
+	team := n.Get("tags.team")
+	if team.Location() != "prod.tfvars:13:31" || team.Parent.Parent.Parent.Value != "tags" {
+		t.Errorf("tags.team at %s", team.Location())
+	}
+	if p := n.Get("policy"); p.StartLine != 18 || p.EndLine != 22 {
+		t.Errorf("policy spans lines %d-%d", p.StartLine, p.EndLine)
+	}
+}
+
+func TestParse_Numbers(t *testing.T) {
+	n, err := Parse([]byte("a = 007\nb = 0.5\nc = 1e3\nd = -0\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := marshal(t, n); got != `{"a":7,"b":0.5,"c":1e3,"d":-0}` {
+		t.Errorf("Parse() = %s", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a = 1\na = 2", `variable a is already set at f.tfvars:1:1 at f.tfvars:2:1`},
+		{"a = var.b", `var.b is not a literal value, variable files cannot use references or functions at f.tfvars:1:5`},
+		{"a = upper(\"x\")", `upper is not a literal value, variable files cannot use references or functions at f.tfvars:1:5`},
+		{`a = "${b}"`, `templates are not allowed in a variable file, write $${ for a literal ${ at f.tfvars:1:6`},
+		{"a = \"x", `unterminated string at f.tfvars:1:5`},
+		{"a = [1, 2", `unterminated list, expected ']' at f.tfvars:1:5`},
+		{"a = {b = 1, b = 2}", `duplicate key "b" (first set at f.tfvars:1:6) at f.tfvars:1:13`},
+		{"a = {b = 1 c = 2}", `expected ',', a line break or '}' at f.tfvars:1:12`},
+		{"a = 1 b = 2", `expected the end of the line at f.tfvars:1:7`},
+		{"a 1", `expected '=' after the variable name at f.tfvars:1:3`},
+		{"a {\n}", `blocks are not allowed in a variable file, write a = {...} at f.tfvars:1:1`},
+		{"a = 10s", `invalid number, quote strings at f.tfvars:1:5`},
+		{"a = <<EOT\nx\n", `unterminated heredoc, expected EOT at f.tfvars:1:5`},
+		{`a = "\q"`, `invalid escape sequence at f.tfvars:1:6`},
+		{"a = /* x", `unterminated comment at f.tfvars:1:5`},
+		{"= 1", `expected a variable name at f.tfvars:1:1`},
+		{"a =", `expected a value at f.tfvars:1:4`},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.input), "f.tfvars")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %s", tt.input, err, tt.want)
+		}
+	}
+	deep := "a = " + strings.Repeat("[", slowjson.MaxDepth+1)
+	if _, err := Parse([]byte(deep), ""); err == nil || !strings.Contains(err.Error(), "exceeded max depth") {
+		t.Errorf("Parse(deep) error = %v", err)
+	}
+}
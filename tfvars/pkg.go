@@ -0,0 +1,8 @@
+// Package tfvars reads and writes Terraform variable files, .tfvars in HCL native syntax and
+// .tfvars.json, as slowjson nodes, so infrastructure values go through the same merging, validation
+// and provenance as application config.
+//
+// Variable files only hold literal values: strings, numbers, booleans, null, lists and maps. Strings
+// may be written as heredocs. References, function calls and ${...} templates, which Terraform rejects
+// in variable files, are errors.
+package tfvars
@@ -0,0 +1,46 @@
+package tfvars
+
+import (
+	"context"
+	"strings"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// FileSource is a config source reading a Terraform variable file.
+type FileSource struct {
+	Path string
+}
+
+// File creates a source reading the variable file at path, JSON when the path ends in .json, e.g.
+// terraform.tfvars.json, and HCL otherwise.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(s.Path, ".json") {
+		return Parse(b, s.Path)
+	}
+	p := slowjson.NewParser(string(b))
+	p.File = s.Path
+	n, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if n.Type != slowjson.NodeObject {
+		return nil, n.Errorf("a variable file holds an object, got %s", typeName(n))
+	}
+	return n, nil
+}
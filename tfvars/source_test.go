@@ -0,0 +1,56 @@
+package tfvars
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "terraform.tfvars", "region = \"eu-west-1\"\ninstance_count = 2\ntags = {\n  env = \"dev\"\n}\n")
+	prod := writeFile(t, dir, "prod.auto.tfvars.json", "{\n  \"instance_count\": 6,\n  \"tags\": {\"env\": \"prod\"}\n}\n")
+	c := tracedconfig.NewConfig(File(base), File(prod))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg struct {
+		Region        string
+		InstanceCount int `json:"instance_count"`
+		Tags          map[string]string
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Region != "eu-west-1" || cfg.InstanceCount != 6 || cfg.Tags["env"] != "prod" {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if n := c.Get("region"); n.File != base || n.StartLine != 1 {
+		t.Errorf("region set at %s:%d", n.File, n.StartLine)
+	}
+	if n := c.Get("instance_count"); n.File != prod || n.StartLine != 2 {
+		t.Errorf("instance_count set at %s:%d", n.File, n.StartLine)
+	}
+
+	list := File(writeFile(t, dir, "list.tfvars.json", "[1]"))
+	if _, err := list.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "a variable file holds an object, got a list") {
+		t.Errorf("Load() error = %v", err)
+	}
+	broken := File(writeFile(t, dir, "broken.tfvars", "a = var.b\n"))
+	if _, err := broken.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "broken.tfvars:1:5") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package tfvars
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Marshal writes the object n as a .tfvars file, one variable per line with the equals signs of
+// consecutive variables aligned like terraform fmt does. Maps are written one member per line, lists of
+// scalars on one line. Variable names must be HCL identifiers, map keys that are not are quoted.
+func Marshal(n *slowjson.Node) ([]byte, error) {
+	if n.Type != slowjson.NodeObject {
+		return nil, fmt.Errorf("a variable file holds an object, got %s", typeName(n))
+	}
+	for _, key := range n.Children {
+		if !IsIdentifier(key.Value) {
+			return nil, key.Errorf("variable name %q is not an identifier", key.Value)
+		}
+	}
+	var b strings.Builder
+	writeMembers(&b, n, 0)
+	return []byte(b.String()), nil
+}
+
+// Write writes the object n to path, as JSON when the path ends in .json, e.g. terraform.tfvars.json,
+// and as a .tfvars file otherwise. An existing file is replaced atomically and keeps its permissions.
+func Write(path string, n *slowjson.Node) error {
+	var out []byte
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		if n.Type != slowjson.NodeObject {
+			return fmt.Errorf("a variable file holds an object, got %s", typeName(n))
+		}
+		out, err = slowjson.MarshalOptions{Indent: "  "}.Marshal(n)
+		out = append(out, '\n')
+	} else {
+		out, err = Marshal(n)
+	}
+	if err != nil {
+		return err
+	}
+	return tracedconfig.WriteFile(path, out, 0o644)
+}
+
+func typeName(n *slowjson.Node) string {
+	switch n.Type {
+	case slowjson.NodeObject:
+		return "an object"
+	case slowjson.NodeArray:
+		return "a list"
+	case slowjson.NodeString:
+		return "a string"
+	case slowjson.NodeNumber:
+		return "a number"
+	case slowjson.NodeBoolean:
+		return "a boolean"
+	}
+	return "null"
+}
+
+// IsIdentifier reports whether s can be written unquoted as a variable name or map key.
+func IsIdentifier(s string) bool {
+	for i, r := range s {
+		if i == 0 && !isIdentStart(r) || !isIdent(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// writeMembers writes the members of obj on lines of their own at the nesting depth, aligning the
+// equals signs of members on consecutive lines.
+func writeMembers(b *strings.Builder, obj *slowjson.Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	names := make([]string, len(obj.Children))
+	width := 0
+	for i, key := range obj.Children {
+		names[i] = key.Value
+		if !IsIdentifier(key.Value) {
+			names[i] = Quote(key.Value)
+		}
+		if w := utf8.RuneCountInString(names[i]); w > width {
+			width = w
+		}
+	}
+	for i, key := range obj.Children {
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(names[i]))
+		b.WriteString(indent + names[i] + pad + " = ")
+		writeValue(b, key.Children[0], depth)
+		b.WriteByte('\n')
+	}
+}
+
+func writeValue(b *strings.Builder, n *slowjson.Node, depth int) {
+	switch n.Type {
+	case slowjson.NodeObject:
+		if len(n.Children) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		writeMembers(b, n, depth+1)
+		b.WriteString(strings.Repeat("  ", depth) + "}")
+	case slowjson.NodeArray:
+		flat := true
+		for _, item := range n.Children {
+			if item.Type == slowjson.NodeObject && len(item.Children) > 0 || item.Type == slowjson.NodeArray && len(item.Children) > 0 {
+				flat = false
+			}
+		}
+		if flat {
+			b.WriteByte('[')
+			for i, item := range n.Children {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				writeValue(b, item, depth)
+			}
+			b.WriteByte(']')
+			return
+		}
+		indent := strings.Repeat("  ", depth+1)
+		b.WriteString("[\n")
+		for _, item := range n.Children {
+			b.WriteString(indent)
+			writeValue(b, item, depth+1)
+			b.WriteString(",\n")
+		}
+		b.WriteString(strings.Repeat("  ", depth) + "]")
+	case slowjson.NodeString:
+		b.WriteString(Quote(n.Value))
+	case slowjson.NodeNull:
+		b.WriteString("null")
+	default:
+		b.WriteString(n.Value)
+	}
+}
+
+// Quote returns s as an HCL quoted string, escaping template sequences such as ${ as $${.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			b.WriteRune(r)
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package tfvars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestMarshal(t *testing.T) {
+	n, err := slowjson.NewParser(`{"region": "eu-west-1", "instance_count": 3, "owner": null, "zones": ["a", "b"],
+		"tags": {"env": "prod", "cost/center": "42"}, "rules": [{"port": 443}], "note": "", "empty": {}}`).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Get("note").Value = "line\nwith ${var}"
+	got, err := Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `region         = "eu-west-1"
+instance_count = 3
+owner          = null
+zones          = ["a", "b"]
+tags           = {
+  env           = "prod"
+  "cost/center" = "42"
+}
+rules          = [
+  {
+    port = 443
+  },
+]
+note           = "line\nwith $${var}"
+empty          = {}
+`
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+	back, err := Parse(got, "")
+	if err != nil {
+		t.Fatalf("Parse(Marshal()) error = %v", err)
+	}
+	if !slowjson.Equal(back, n, slowjson.EqualOptions{}) {
+		t.Errorf("Parse(Marshal()) = %s", marshal(t, back))
+	}
+
+	bad, _ := slowjson.NewParser(`{"not a name": 1}`).Parse()
+	if _, err := Marshal(bad); err == nil || err.Error() != `["not a name"]: variable name "not a name" is not an identifier at line 1 col 2` {
+		t.Errorf("Marshal() error = %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	n, _ := slowjson.NewParser(`{"a": {"b": [1]}}`).Parse()
+	dir := t.TempDir()
+	for name, want := range map[string]string{
+		"terraform.tfvars":      "a = {\n  b = [1]\n}\n",
+		"terraform.tfvars.json": "{\n  \"a\": {\n    \"b\": [\n      1\n    ]\n  }\n}\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := Write(path, n); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(path); string(b) != want {
+			t.Errorf("Write(%s) wrote\n%s", name, b)
+		}
+		if err := os.Chmod(path, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := Write(path, n); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if info.Mode().Perm() != 0o600 {
+			t.Errorf("Write(%s) mode = %v, want 0600", name, info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,647 @@
+package toml
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Parse parses a TOML document into an object. file is recorded as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	p := &parser{
+		src:     string(data),
+		file:    file,
+		line:    1,
+		col:     1,
+		defined: map[*slowjson.Node]bool{},
+		dotted:  map[*slowjson.Node]bool{},
+		frozen:  map[*slowjson.Node]bool{},
+		tables:  map[*slowjson.Node]bool{},
+	}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	root, err := p.document()
+	if err != nil {
+		return nil, err
+	}
+	slowjson.SetParents(root)
+	slowjson.AttachComments(p.src, []*slowjson.Node{root}, "#")
+	return root, nil
+}
+
+type parser struct {
+	src  string
+	file string
+
+	off, line, col int
+	depth          int
+
+	// defined are the tables of a [header], dotted the tables created by dotted keys, frozen the inline
+	// tables and arrays, which cannot be extended, and tables the arrays of [[header]] tables.
+	defined, dotted, frozen, tables map[*slowjson.Node]bool
+}
+
+type mark struct {
+	off, line, col int
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) nodeAt(n *slowjson.Node) mark {
+	return mark{n.StartOffset, n.StartLine, n.StartCol}
+}
+
+func (p *parser) span(typ slowjson.NodeType, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+// extend moves the end of n to the end of child when that is later.
+func extend(n, child *slowjson.Node) {
+	if child.EndOffset > n.EndOffset {
+		n.EndLine, n.EndCol, n.EndOffset = child.EndLine, child.EndCol, child.EndOffset
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+func (p *parser) advance(n int) {
+	for end := p.off + n; p.off < end; {
+		p.next()
+	}
+}
+
+func (p *parser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.next()
+	}
+}
+
+func (p *parser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.next()
+		}
+	}
+}
+
+// skipBlank consumes whitespace, comments and line breaks, as allowed between array elements.
+func (p *parser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		switch p.peek() {
+		case '\n', '\r':
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine consumes the rest of a statement, which may only be a comment.
+func (p *parser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.peek() == '\r' {
+		p.next()
+	}
+	switch p.peek() {
+	case 0:
+		return nil
+	case '\n':
+		p.next()
+		return nil
+	}
+	return p.errorf(p.mark(), "expected the end of the line")
+}
+
+func (p *parser) document() (*slowjson.Node, error) {
+	root := p.span(slowjson.NodeObject, p.mark(), p.mark())
+	table := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+		var err error
+		if p.peek() == '[' {
+			table, err = p.header(root)
+		} else {
+			err = p.keyValue(table)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+	end := p.mark()
+	root.EndLine, root.EndCol, root.EndOffset = end.line, end.col, end.off
+	return root, nil
+}
+
+// member returns the key node of obj named name.
+func member(obj *slowjson.Node, name string) *slowjson.Node {
+	for _, k := range obj.Children {
+		if k.Value == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// add appends the member key with the value val to obj.
+func add(obj, key, val *slowjson.Node) {
+	key.Children = []*slowjson.Node{val}
+	obj.Children = append(obj.Children, key)
+	extend(obj, val)
+}
+
+// header parses a [table] or [[array of tables]] header and returns the table it opens.
+func (p *parser) header(root *slowjson.Node) (*slowjson.Node, error) {
+	start := p.mark()
+	p.next()
+	array := p.peek() == '['
+	if array {
+		p.next()
+	}
+	p.skipSpace()
+	keys, err := p.keys()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.off:], closing) {
+		return nil, p.errorf(p.mark(), "expected %q to close the table header", closing)
+	}
+	p.advance(len(closing))
+	end := p.mark()
+
+	obj := root
+	for _, key := range keys[:len(keys)-1] {
+		k := member(obj, key.Value)
+		if k == nil {
+			t := p.span(slowjson.NodeObject, start, end)
+			add(obj, key, t)
+			obj = t
+			continue
+		}
+		if obj, err = p.descend(k, key); err != nil {
+			return nil, err
+		}
+	}
+	last := keys[len(keys)-1]
+	k := member(obj, last.Value)
+	table := p.span(slowjson.NodeObject, start, end)
+	switch {
+	case array && k == nil:
+		arr := p.span(slowjson.NodeArray, start, end)
+		p.tables[arr] = true
+		arr.Children = []*slowjson.Node{table}
+		add(obj, last, arr)
+	case array && p.tables[k.Children[0]]:
+		arr := k.Children[0]
+		arr.Children = append(arr.Children, table)
+		extend(arr, table)
+	case array:
+		return nil, p.errorf(p.nodeAt(last), "%s is not an array of tables (set at %s)", p.path(keys), k.Location())
+	case k == nil:
+		add(obj, last, table)
+	default:
+		t := k.Children[0]
+		if t.Type != slowjson.NodeObject || p.defined[t] || p.dotted[t] || p.frozen[t] {
+			return nil, p.errorf(p.nodeAt(last), "table %s is defined twice (first at %s)", p.path(keys), k.Location())
+		}
+		// an implicit table of an earlier header is defined now
+		table = t
+	}
+	p.defined[table] = true
+	return table, nil
+}
+
+// descend returns the table of the existing member k that key continues into.
+func (p *parser) descend(k, key *slowjson.Node) (*slowjson.Node, error) {
+	v := k.Children[0]
+	if p.tables[v] {
+		// a header below an array of tables continues its last table
+		v = v.Children[len(v.Children)-1]
+	}
+	if v.Type != slowjson.NodeObject {
+		return nil, p.errorf(p.nodeAt(key), "%s is not a table (set at %s)", key.Value, k.Location())
+	}
+	if p.frozen[v] {
+		return nil, p.errorf(p.nodeAt(key), "%s is an inline table and cannot be extended (set at %s)", key.Value, k.Location())
+	}
+	return v, nil
+}
+
+func (p *parser) path(keys []*slowjson.Node) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Value
+	}
+	return strings.Join(parts, ".")
+}
+
+// keyValue parses a key = value line into table.
+func (p *parser) keyValue(table *slowjson.Node) error {
+	keys, err := p.keys()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() != '=' {
+		return p.errorf(p.mark(), "expected '=' after the key")
+	}
+	p.next()
+	p.skipSpace()
+	val, err := p.value()
+	if err != nil {
+		return err
+	}
+	// the tables of the dotted key end where their last value ends
+	walked := []*slowjson.Node{table}
+	obj := table
+	for _, key := range keys[:len(keys)-1] {
+		k := member(obj, key.Value)
+		if k == nil {
+			t := p.span(slowjson.NodeObject, p.nodeAt(key), p.nodeAt(key))
+			p.dotted[t] = true
+			add(obj, key, t)
+			obj = t
+		} else if t := k.Children[0]; t.Type == slowjson.NodeObject && p.dotted[t] {
+			obj = t
+		} else if t.Type != slowjson.NodeObject {
+			return p.errorf(p.nodeAt(key), "%s is not a table (set at %s)", key.Value, k.Location())
+		} else {
+			return p.errorf(p.nodeAt(key), "table %s is already defined (first at %s)", key.Value, k.Location())
+		}
+		walked = append(walked, obj)
+	}
+	last := keys[len(keys)-1]
+	if k := member(obj, last.Value); k != nil {
+		return p.errorf(p.nodeAt(last), "duplicate key %s (first set at %s)", p.path(keys), k.Location())
+	}
+	add(obj, last, val)
+	for _, t := range walked {
+		extend(t, val)
+	}
+	return nil
+}
+
+// keys parses a possibly dotted key such as a."b.c".d into one key node per part.
+func (p *parser) keys() ([]*slowjson.Node, error) {
+	var keys []*slowjson.Node
+	for {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+		p.skipSpace()
+	}
+}
+
+func isBare(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) key() (*slowjson.Node, error) {
+	start := p.mark()
+	switch p.peek() {
+	case '"', '\'':
+		if strings.HasPrefix(p.src[p.off:], `"""`) || strings.HasPrefix(p.src[p.off:], `'''`) {
+			return nil, p.errorf(start, "a key cannot be a multi-line string")
+		}
+		return p.str()
+	}
+	for isBare(p.peek()) {
+		p.next()
+	}
+	if p.off == start.off {
+		return nil, p.errorf(start, "expected a key")
+	}
+	n := p.span(slowjson.NodeString, start, p.mark())
+	n.Value = p.src[start.off:p.off]
+	return n, nil
+}
+
+func (p *parser) value() (*slowjson.Node, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case c == 0 || c == '\n' || c == '\r' || c == '#':
+		return nil, p.errorf(p.mark(), "expected a value")
+	}
+	return p.scalar()
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > slowjson.MaxDepth {
+		return p.errorf(p.mark(), "exceeded max depth %d", slowjson.MaxDepth)
+	}
+	return nil
+}
+
+func (p *parser) array() (*slowjson.Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	start := p.mark()
+	arr := p.span(slowjson.NodeArray, start, start)
+	p.next()
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			break
+		}
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated array, expected ']'")
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr.Children = append(arr.Children, v)
+		p.skipBlank()
+		if p.peek() == ',' {
+			p.next()
+			continue
+		}
+		if p.peek() != ']' {
+			if p.eof() {
+				return nil, p.errorf(start, "unterminated array, expected ']'")
+			}
+			return nil, p.errorf(p.mark(), "expected ',' or ']'")
+		}
+	}
+	p.next()
+	end := p.mark()
+	arr.EndLine, arr.EndCol, arr.EndOffset = end.line, end.col, end.off
+	p.frozen[arr] = true
+	return arr, nil
+}
+
+func (p *parser) inlineTable() (*slowjson.Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	start := p.mark()
+	obj := p.span(slowjson.NodeObject, start, start)
+	p.next()
+	p.skipSpace()
+	if p.peek() != '}' {
+		for {
+			p.skipSpace()
+			if err := p.keyValue(obj); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.peek() == ',' {
+				p.next()
+				continue
+			}
+			if p.peek() != '}' {
+				return nil, p.errorf(p.mark(), "expected ',' or '}' in the inline table")
+			}
+			break
+		}
+	}
+	p.next()
+	end := p.mark()
+	obj.EndLine, obj.EndCol, obj.EndOffset = end.line, end.col, end.off
+	// dotted keys inside an inline table cannot be extended either
+	var freeze func(n *slowjson.Node)
+	freeze = func(n *slowjson.Node) {
+		p.frozen[n] = true
+		for _, k := range n.Children {
+			if p.dotted[k.Children[0]] {
+				freeze(k.Children[0])
+			}
+		}
+	}
+	freeze(obj)
+	return obj, nil
+}
+
+// str parses any of the four kinds of strings.
+func (p *parser) str() (*slowjson.Node, error) {
+	start := p.mark()
+	q := p.peek()
+	multi := strings.HasPrefix(p.src[p.off:], strings.Repeat(string(q), 3))
+	if multi {
+		p.advance(3)
+		// a line break right after the delimiter is trimmed
+		if strings.HasPrefix(p.src[p.off:], "\r\n") {
+			p.advance(2)
+		} else if p.peek() == '\n' {
+			p.next()
+		}
+	} else {
+		p.next()
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated string")
+		}
+		c := p.peek()
+		switch {
+		case c == q && multi && strings.HasPrefix(p.src[p.off:], strings.Repeat(string(q), 3)):
+			// up to two quotes may precede the closing delimiter
+			run := 3
+			for run < 5 && p.off+run < len(p.src) && p.src[p.off+run] == q {
+				run++
+			}
+			b.WriteString(strings.Repeat(string(q), run-3))
+			p.advance(run)
+			n := p.span(slowjson.NodeString, start, p.mark())
+			n.Value = b.String()
+			return n, nil
+		case c == q && !multi:
+			p.next()
+			n := p.span(slowjson.NodeString, start, p.mark())
+			n.Value = b.String()
+			return n, nil
+		case (c == '\n' || c == '\r') && !multi:
+			return nil, p.errorf(start, "unterminated string")
+		case c == '\\' && q == '"':
+			esc := p.mark()
+			p.next()
+			if multi && p.lineEndingBackslash() {
+				continue
+			}
+			if err := p.escape(&b, esc); err != nil {
+				return nil, err
+			}
+		default:
+			r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+			b.WriteRune(r)
+			p.next()
+		}
+	}
+}
+
+// lineEndingBackslash consumes the whitespace after a backslash ending a line of a multi-line basic
+// string, which joins the lines.
+func (p *parser) lineEndingBackslash() bool {
+	i := p.off
+	for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i++
+	}
+	if i < len(p.src) && p.src[i] == '\r' {
+		i++
+	}
+	if i >= len(p.src) || p.src[i] != '\n' {
+		return false
+	}
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+var escapes = map[byte]string{
+	'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", 'e': "\x1b", '"': "\"", '\\': "\\",
+}
+
+func (p *parser) escape(b *strings.Builder, at mark) error {
+	e := p.peek()
+	if s, ok := escapes[e]; ok {
+		b.WriteString(s)
+		p.next()
+		return nil
+	}
+	digits := map[byte]int{'u': 4, 'U': 8}[e]
+	if digits == 0 || p.off+1+digits > len(p.src) {
+		return p.errorf(at, "invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(p.src[p.off+1:p.off+1+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return p.errorf(at, "invalid escape sequence")
+	}
+	b.WriteRune(rune(code))
+	p.advance(1 + digits)
+	return nil
+}
+
+var (
+	decPattern   = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	hexPattern   = regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)
+	octPattern   = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	binPattern   = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+	datePattern  = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?([Zz]|[-+]\d{2}:\d{2})?)?|\d{2}:\d{2}(:\d{2}(\.\d+)?)?)$`)
+	timeAfter    = regexp.MustCompile(`^ \d{2}:`)
+)
+
+// scalar parses a boolean, number or date-time.
+func (p *parser) scalar() (*slowjson.Node, error) {
+	start := p.mark()
+	for c := p.peek(); isBare(c) || c == '+' || c == '.' || c == ':'; c = p.peek() {
+		p.next()
+	}
+	// a date and time may be separated by a space
+	if datePattern.MatchString(p.src[start.off:p.off]) && timeAfter.MatchString(p.src[p.off:]) {
+		p.next()
+		for c := p.peek(); isBare(c) || c == '+' || c == '.' || c == ':'; c = p.peek() {
+			p.next()
+		}
+	}
+	v := p.src[start.off:p.off]
+	n := p.span(slowjson.NodeString, start, p.mark())
+	unsigned := strings.TrimLeft(v, "+-")
+	switch {
+	case v == "true" || v == "false":
+		n.Type, n.Value = slowjson.NodeBoolean, v
+	case unsigned == "inf" || unsigned == "nan":
+		// JSON has no infinity or NaN
+		n.Value = v
+	case decPattern.MatchString(v):
+		n.Type, n.Value = slowjson.NodeNumber, strings.TrimPrefix(strings.ReplaceAll(v, "_", ""), "+")
+	case hexPattern.MatchString(v), octPattern.MatchString(v), binPattern.MatchString(v):
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[v[1]]
+		i, _ := new(big.Int).SetString(strings.ReplaceAll(v[2:], "_", ""), base)
+		n.Type, n.Value = slowjson.NodeNumber, i.String()
+	case floatPattern.MatchString(v):
+		n.Type, n.Value = slowjson.NodeNumber, strings.TrimPrefix(strings.ReplaceAll(v, "_", ""), "+")
+	case datePattern.MatchString(v):
+		n.Value = v
+	default:
+		if v == "" {
+			return nil, p.errorf(start, "expected a value")
+		}
+		return nil, p.errorf(start, "invalid value %q, quote strings", v)
+	}
+	return n, nil
+}
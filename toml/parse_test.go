@@ -0,0 +1,124 @@
+package toml
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := `# service
+title = "api" # name
+[server]
+host = "localhost"
+port = 8080
+timeouts = { read = "5s", write = "10s" }
+
+[server.tls]
+enabled = true
+
+[[backends]]
+url = 'http://a'
+[[backends]]
+url = 'http://b'
+weights = [
+  1, # first
+  2,
+]
+`
+	n, err := Parse([]byte(input), "app.toml")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := `{"title":"api","server":{"host":"localhost","port":8080,"timeouts":{"read":"5s","write":"10s"},"tls":{"enabled":true}},"backends":[{"url":"http://a"},{"url":"http://b","weights":[1,2]}]}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() = %s, want %s", got, want)
+	}
+	port := n.Get("server.port")
+	if port.StartLine != 5 || port.StartCol != 8 || port.File != "app.toml" || input[port.StartOffset:port.EndOffset] != "8080" {
+		t.Errorf("Parse() port at %d:%d in %q", port.StartLine, port.StartCol, port.File)
+	}
+	if got := n.Get("backends[1].url").Path().String(); got != "backends[1].url" {
+		t.Errorf("Parse() parents give path %q", got)
+	}
+	if tls := n.Get("server.tls"); tls.StartLine != 8 {
+		t.Errorf("Parse() server.tls starts at line %d", tls.StartLine)
+	}
+}
+
+func TestParse_Values(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`a = "tab\tand \u00e9"`, `{"a":"tab\tand é"}`},
+		{`a = 'C:\path'`, `{"a":"C:\\path"}`},
+		{"a = \"\"\"\nRoses \\\n   are red\"\"\"\"", `{"a":"Roses are red\""}`},
+		{"a = '''\nline one\nline ''two'''''", `{"a":"line one\nline ''two''"}`},
+		{"a = +1_000", `{"a":1000}`},
+		{"a = 0xDEAD_beef", `{"a":3735928559}`},
+		{"a = 0o17", `{"a":15}`},
+		{"a = 0b101", `{"a":5}`},
+		{"a = -3.14e+2", `{"a":-3.14e+2}`},
+		{"a = -inf", `{"a":"-inf"}`},
+		{"a = 1979-05-27T07:32:00Z", `{"a":"1979-05-27T07:32:00Z"}`},
+		{"a = 1979-05-27 07:32:00-08:00", `{"a":"1979-05-27 07:32:00-08:00"}`},
+		{"a = 07:32:00", `{"a":"07:32:00"}`},
+		{`"quoted key".b = 1`, `{"quoted key":{"b":1}}`},
+		{"a.b = 1\na.c = 2", `{"a":{"b":1,"c":2}}`},
+		{"[fruit]\napple.color = 'red'\n[fruit.apple.texture]\nsmooth = true", `{"fruit":{"apple":{"color":"red","texture":{"smooth":true}}}}`},
+		{"[a.b]\nc = 1\n[a]\nd = 2", `{"a":{"b":{"c":1},"d":2}}`},
+		{"[[a.b]]\nc = 1\n[a.b.d]\ne = 2\n[[a.b]]\nc = 3", `{"a":{"b":[{"c":1,"d":{"e":2}},{"c":3}]}}`},
+		{"a = []\nb = {}", `{"a":[],"b":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := Parse([]byte(tt.input), "")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := marshal(t, n); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a = 1\na = 2", "duplicate key a (first set at f.toml:1:1) at f.toml:2:1"},
+		{"[a]\n[a]", "table a is defined twice (first at f.toml:1:2) at f.toml:2:2"},
+		{"[fruit]\napple.color = 'red'\n[fruit.apple]", "table fruit.apple is defined twice (first at f.toml:2:1) at f.toml:3:8"},
+		{"a = 1\n[a.b]", "a is not a table (set at f.toml:1:1) at f.toml:2:2"},
+		{"t = {a = 1}\n[t.b]", "t is an inline table and cannot be extended (set at f.toml:1:1) at f.toml:2:2"},
+		{"a = {}\n[[a]]", "a is not an array of tables (set at f.toml:1:1) at f.toml:2:3"},
+		{"a = hello", `invalid value "hello", quote strings at f.toml:1:5`},
+		{"a = 1 b = 2", "expected the end of the line at f.toml:1:7"},
+		{"a = [1, 2", "unterminated array, expected ']' at f.toml:1:5"},
+		{`a = "open`, "unterminated string at f.toml:1:5"},
+		{`a = "\q"`, "invalid escape sequence at f.toml:1:6"},
+		{"a", "expected '=' after the key at f.toml:1:2"},
+		{"[a", `expected "]" to close the table header at f.toml:1:3`},
+		{"a =", "expected a value at f.toml:1:4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input), "f.toml")
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package toml parses TOML config files into slowjson nodes with line and column positions, so TOML
+// goes through the same decoding, merging, provenance and diagnostics as JSON.
+//
+// Tables become objects and arrays of tables arrays of objects. Integers in any base become decimal
+// numbers and floats JSON numbers, while inf, nan and date-times, which JSON lacks, are strings.
+package toml
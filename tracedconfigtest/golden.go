@@ -0,0 +1,71 @@
+package tracedconfigtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Update makes Golden write golden files instead of comparing with them. It is set when the environment
+// variable TRACEDCONFIG_UPDATE_GOLDEN is, run e.g. TRACEDCONFIG_UPDATE_GOLDEN=1 go test ./slowjson and
+// review the changes to the golden files like any other diff.
+var Update = os.Getenv("TRACEDCONFIG_UPDATE_GOLDEN") != ""
+
+// Golden compares got with the golden file at path. When both are JSON they are compared by value, so
+// formatting does not matter, and a failure lists the changed paths with their positions in the golden
+// file and in got. Other content is compared byte for byte and a failure shows the first differing line.
+// With Update set the file is written with got instead, creating its directory if needed.
+func Golden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v, run the test with TRACEDCONFIG_UPDATE_GOLDEN=1 to create it", err)
+	}
+	if msg := compareGolden(path, want, got); msg != "" {
+		t.Error(msg + "\nrun the test with TRACEDCONFIG_UPDATE_GOLDEN=1 to accept the new output")
+	}
+}
+
+// compareGolden returns why got differs from the golden content want, empty when it does not.
+func compareGolden(path string, want, got []byte) string {
+	if json.Valid(want) && json.Valid(got) {
+		w, errW := parse(path, want)
+		g, errG := parse("got", got)
+		if errW == nil && errG == nil {
+			changes := diffOptions.Diff(w, g)
+			if len(changes) == 0 {
+				return ""
+			}
+			return "got differs from " + path + ", golden -> got:\n" + describe(changes)
+		}
+	}
+	if string(want) == string(got) {
+		return ""
+	}
+	wl, gl := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	for i := 0; ; i++ {
+		if i >= len(wl) || i >= len(gl) || wl[i] != gl[i] {
+			return fmt.Sprintf("got differs from %s at line %d:\n  golden: %s\n  got:    %s", path, i+1, line(wl, i), line(gl, i))
+		}
+	}
+}
+
+// line quotes line i of lines, or says there is none.
+func line(lines []string, i int) string {
+	if i >= len(lines) {
+		return "(end of file)"
+	}
+	return fmt.Sprintf("%q", lines[i])
+}
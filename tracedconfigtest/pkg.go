@@ -0,0 +1,4 @@
+// Package tracedconfigtest helps testing code that uses tracedconfig: parsing without error checks,
+// golden files compared value by value, a scripted Source for reload logic, and assertions that
+// show the source lines of a failing value.
+package tracedconfigtest
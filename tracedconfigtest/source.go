@@ -0,0 +1,72 @@
+package tracedconfigtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Source is a tracedconfig.Source whose content is set by the test, to drive reload logic without
+// files or timing. It is a tracedconfig.ChangeDetector reporting a change after every Set or SetError
+// until the next Load. It is safe for concurrent use.
+type Source struct {
+	name string
+
+	mu      sync.Mutex
+	doc     string
+	err     error
+	changed bool
+	loads   int
+}
+
+// NewSource creates a source named name serving doc, which is parsed on every Load.
+func NewSource(name, doc string) *Source {
+	return &Source{name: name, doc: doc}
+}
+
+// Name returns the name given to NewSource, it is also the file of the loaded nodes.
+func (s *Source) Name() string {
+	return s.name
+}
+
+// Load parses the current document or returns the error set by SetError.
+func (s *Source) Load(ctx context.Context) (*slowjson.Node, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	s.changed = false
+	if s.err != nil {
+		return nil, s.err
+	}
+	return parse(s.name, []byte(s.doc))
+}
+
+// Changed reports whether Set or SetError was called since the last Load.
+func (s *Source) Changed(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed, nil
+}
+
+// Set replaces the document served by Load and clears the error.
+func (s *Source) Set(doc string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc, s.err, s.changed = doc, nil, true
+}
+
+// SetError makes Load fail with err until the next Set, e.g. to test that a failed reload keeps the
+// previous config.
+func (s *Source) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err, s.changed = err, true
+}
+
+// Loads returns how many times Load was called.
+func (s *Source) Loads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
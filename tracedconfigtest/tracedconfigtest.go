@@ -0,0 +1,117 @@
+package tracedconfigtest
+
+import (
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// MustParse parses input, failing the test on a syntax error. file names the input in positions.
+func MustParse(t testing.TB, file, input string) *slowjson.Node {
+	t.Helper()
+	p := slowjson.NewParser(input)
+	p.File = file
+	n, err := p.Parse()
+	if err != nil {
+		t.Fatalf("parse %s: %v", file, err)
+	}
+	return n
+}
+
+// MustParseFile parses the file at path, failing the test on any error.
+func MustParseFile(t testing.TB, path string) *slowjson.Node {
+	t.Helper()
+	n, err := slowjson.ParseFile(path)
+	if err != nil {
+		t.Fatalf("parse %v", err)
+	}
+	return n
+}
+
+// AssertValue checks that the value at path in root is want, a JSON literal such as `8080`, `"a"` or
+// `{"x": 1}` compared with slowjson.Equal. A failure shows the source lines of the value.
+func AssertValue(t testing.TB, root *slowjson.Node, path, want string) {
+	t.Helper()
+	p, err := slowjson.ParsePath(path)
+	if err != nil {
+		t.Fatalf("invalid path %q: %v", path, err)
+	}
+	got := root.Lookup(p)
+	if got == nil {
+		t.Errorf("%s is not set, want %s", path, want)
+		return
+	}
+	w := MustParse(t, "want", want)
+	if !slowjson.Equal(got, w, slowjson.EqualOptions{NumericValue: true}) {
+		t.Errorf("%s = %s at %s, want %s\n%s", path, marshal(got), got.Location(), want, sourceLines(got))
+	}
+}
+
+// AssertContains checks that every value of subset is in got, see slowjson.Contains. A failure names the
+// first mismatching path and shows the source lines of both values.
+func AssertContains(t testing.TB, got, subset *slowjson.Node) {
+	t.Helper()
+	m := slowjson.Contains(got, subset)
+	if m == nil {
+		return
+	}
+	msg := m.String() + "\n" + sourceLines(m.Want)
+	if m.Got != nil {
+		msg += sourceLines(m.Got)
+	}
+	t.Error(msg)
+}
+
+// AssertEqual checks that got and want hold the same values regardless of formatting and key order.
+// A failure lists every change with the positions in both trees.
+func AssertEqual(t testing.TB, got, want *slowjson.Node) {
+	t.Helper()
+	changes := diffOptions.Diff(want, got)
+	if len(changes) > 0 {
+		t.Errorf("trees differ, want -> got:\n%s", describe(changes))
+	}
+}
+
+// diffOptions compares trees by value, formatting, key order and the spelling of numbers do not matter.
+var diffOptions = slowjson.DiffOptions{IgnoreOrder: true, NumericValue: true}
+
+// parse parses data named file in positions.
+func parse(file string, data []byte) (*slowjson.Node, error) {
+	p := slowjson.NewParser(string(data))
+	p.File = file
+	return p.Parse()
+}
+
+func marshal(n *slowjson.Node) string {
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		return n.Value
+	}
+	return string(b)
+}
+
+// sourceLines returns the source lines of n, empty for nodes without source.
+func sourceLines(n *slowjson.Node) string {
+	if n.Source == "" {
+		return ""
+	}
+	return n.DebugContext(1, 1)
+}
+
+// describe lists changes with the position of each side.
+func describe(changes []slowjson.Change) string {
+	var s string
+	for _, c := range changes {
+		s += "  " + c.String()
+		switch {
+		case c.Old != nil && c.New != nil:
+			s += " (" + c.Old.Location() + " -> " + c.New.Location() + ")"
+		case c.Old != nil:
+			s += " (" + c.Old.Location() + ")"
+		case c.New != nil:
+			s += " (" + c.New.Location() + ")"
+		}
+		s += "\n"
+	}
+	return s
+}
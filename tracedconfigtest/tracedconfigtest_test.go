@@ -0,0 +1,157 @@
+package tracedconfigtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+)
+
+// recorder is a testing.TB keeping the failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) failure() string {
+	return strings.Join(r.errors, "\n")
+}
+
+func TestAssertValue(t *testing.T) {
+	root := MustParse(t, "app.json", "{\n  \"server\": {\n    \"port\": 8080\n  }\n}")
+	cases := []struct {
+		path, want string
+		failure    []string
+	}{
+		{path: "server.port", want: "8080"},
+		{path: "server.port", want: "8.08e3"},
+		{path: "server", want: `{"port": 8080}`},
+		{path: "server.port", want: "80", failure: []string{"server.port = 8080 at app.json:3:13, want 80", `"port": 8080`}},
+		{path: "server.host", want: `"a"`, failure: []string{"server.host is not set"}},
+	}
+	for _, c := range cases {
+		r := &recorder{TB: t}
+		AssertValue(r, root, c.path, c.want)
+		if len(c.failure) == 0 && len(r.errors) > 0 {
+			t.Errorf("AssertValue(%s, %s) failed: %s", c.path, c.want, r.failure())
+		}
+		for _, f := range c.failure {
+			if !strings.Contains(r.failure(), f) {
+				t.Errorf("AssertValue(%s, %s) failure %q does not contain %q", c.path, c.want, r.failure(), f)
+			}
+		}
+	}
+}
+
+func TestAssertContains(t *testing.T) {
+	got := MustParse(t, "got.json", `{"a": 1, "b": {"c": true}}`)
+	r := &recorder{TB: t}
+	AssertContains(r, got, MustParse(t, "want.json", `{"b": {"c": true}}`))
+	if len(r.errors) > 0 {
+		t.Errorf("unexpected failure: %s", r.failure())
+	}
+	AssertContains(r, got, MustParse(t, "want.json", `{"b": {"c": false}}`))
+	if f := r.failure(); !strings.Contains(f, "b.c") || !strings.Contains(f, "want.json:1:13") {
+		t.Errorf("failure %q does not name the mismatch", f)
+	}
+}
+
+func TestAssertEqual(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertEqual(r, MustParse(t, "a", `{"x": 1, "y": 2}`), MustParse(t, "b", `{"y": 2, "x": 1.0}`))
+	if len(r.errors) > 0 {
+		t.Errorf("unexpected failure: %s", r.failure())
+	}
+	AssertEqual(r, MustParse(t, "got", `{"x": 1}`), MustParse(t, "want", `{"x": 2}`))
+	if f := r.failure(); !strings.Contains(f, "want:1:7 -> got:1:7") {
+		t.Errorf("failure %q does not show the positions", f)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir := t.TempDir()
+	golden := filepath.Join(dir, "out.golden")
+	if err := os.WriteFile(golden, []byte("{\n  \"a\": 1,\n  \"b\": [1, 2]\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(text, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path, got string
+		failure   []string
+	}{
+		{path: golden, got: `{"b": [1, 2], "a": 1}`},
+		{path: golden, got: `{"a": 1, "b": [1, 3]}`, failure: []string{"b[1]: 2 -> 3", "out.golden:3:12 -> got:1:19"}},
+		{path: text, got: "one\ntwo\n"},
+		{path: text, got: "one\nthree\n", failure: []string{"at line 2", `golden: "two"`, `got:    "three"`}},
+		{path: text, got: "one", failure: []string{"at line 2", "(end of file)"}},
+	}
+	for _, c := range cases {
+		r := &recorder{TB: t}
+		Golden(r, c.path, []byte(c.got))
+		if len(c.failure) == 0 && len(r.errors) > 0 {
+			t.Errorf("Golden(%q) failed: %s", c.got, r.failure())
+		}
+		for _, f := range c.failure {
+			if !strings.Contains(r.failure(), f) {
+				t.Errorf("Golden(%q) failure %q does not contain %q", c.got, r.failure(), f)
+			}
+		}
+	}
+}
+
+func TestGolden_Update(t *testing.T) {
+	Update = true
+	defer func() { Update = false }()
+	path := filepath.Join(t.TempDir(), "testdata", "new.golden")
+	Golden(t, path, []byte("{}\n"))
+	Update = false
+	Golden(t, path, []byte("{}\n"))
+}
+
+func TestSource(t *testing.T) {
+	ctx := context.Background()
+	src := NewSource("remote", `{"level": "info"}`)
+	c := tracedconfig.NewConfig(src)
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := src.Changed(ctx); changed {
+		t.Error("changed right after Load")
+	}
+
+	src.Set(`{"level": "debug"}`)
+	if changed, _ := src.Changed(ctx); !changed {
+		t.Error("not changed after Set")
+	}
+	if err := c.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	AssertValue(t, c.Root(), "level", `"debug"`)
+
+	src.SetError(errors.New("unavailable"))
+	if err := c.Load(ctx); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("Load error = %v, want unavailable", err)
+	}
+	AssertValue(t, c.Root(), "level", `"debug"`)
+	if src.Loads() != 3 {
+		t.Errorf("Loads() = %d, want 3", src.Loads())
+	}
+}
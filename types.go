@@ -0,0 +1,184 @@
+package tracedconfig
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// ByteSize is a size in bytes written as a number or with a unit like "512MiB" or "1.5GB".
+type ByteSize int64
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// ParseByteSize parses a size with an optional decimal (kB, MB, ...) or binary (KiB, MiB, ...) unit.
+// Units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.')
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.TrimSpace(s[i:])
+	mult, ok := byteUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", unit)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	size := f * mult
+	// math.MaxInt64 rounds up to 2^63 as a float64, which no longer fits
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows int64", s)
+	}
+	return ByteSize(size), nil
+}
+
+// String formats the size with the largest binary unit that divides it.
+func (b ByteSize) String() string {
+	units := []string{"PiB", "TiB", "GiB", "MiB", "KiB"}
+	for i, unit := range units {
+		m := ByteSize(1) << (10 * (len(units) - i))
+		if b != 0 && b%m == 0 {
+			return strconv.FormatInt(int64(b/m), 10) + unit
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+func init() {
+	RegisterDecoder(reflect.TypeOf(time.Duration(0)), decodeDuration)
+	RegisterDecoder(reflect.TypeOf(ByteSize(0)), decodeByteSize)
+	RegisterDecoder(reflect.TypeOf(time.Time{}), decodeTime)
+	RegisterDecoder(reflect.TypeOf(url.URL{}), decodeURL)
+	RegisterDecoder(reflect.TypeOf(net.IP{}), decodeIP)
+	RegisterDecoder(reflect.TypeOf(net.IPNet{}), decodeIPNet)
+	RegisterDecoder(reflect.TypeOf(&regexp.Regexp{}), decodeRegexp)
+}
+
+func decodeDuration(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "duration")
+	if err != nil {
+		return nil, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q, expected a value like \"30s\" or \"1h30m\"", s)
+	}
+	return d, nil
+}
+
+// decodeByteSize accepts plain numbers as bytes as well as strings with units.
+func decodeByteSize(n *slowjson.Node) (interface{}, error) {
+	if n.Type == slowjson.NodeNumber {
+		return ParseByteSize(n.Value)
+	}
+	s, err := knownString(n, "byte size")
+	if err != nil {
+		return nil, err
+	}
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid byte size: %w", err)
+	}
+	return size, nil
+}
+
+func decodeTime(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q, expected RFC 3339 like \"2006-01-02T15:04:05Z\"", s)
+	}
+	return t, nil
+}
+
+func decodeURL(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "URL")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", s, unwrapURLError(err))
+	}
+	return *u, nil
+}
+
+func decodeIP(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "IP address")
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	return ip, nil
+}
+
+func decodeIPNet(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "CIDR")
+	if err != nil {
+		return nil, err
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q, expected a value like \"10.0.0.0/8\"", s)
+	}
+	return *ipNet, nil
+}
+
+func decodeRegexp(n *slowjson.Node) (interface{}, error) {
+	s, err := knownString(n, "regular expression")
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+func knownString(n *slowjson.Node, what string) (string, error) {
+	if n.Type != slowjson.NodeString {
+		return "", fmt.Errorf("expected string for %s, got %s", what, typeName(n.Type))
+	}
+	return n.Value, nil
+}
+
+func unwrapURLError(err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		return ue.Err
+	}
+	return err
+}
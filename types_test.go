@@ -0,0 +1,116 @@
+package tracedconfig
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type typesConfig struct {
+	Timeout   time.Duration  `json:"timeout"`
+	MaxBody   ByteSize       `json:"max_body"`
+	CacheSize ByteSize       `json:"cache_size"`
+	Since     time.Time      `json:"since"`
+	Endpoint  *url.URL       `json:"endpoint"`
+	Bind      net.IP         `json:"bind"`
+	Allow     []net.IPNet    `json:"allow"`
+	Match     *regexp.Regexp `json:"match"`
+}
+
+func TestDecode_Types(t *testing.T) {
+	input := `{
+  "timeout": "1m30s",
+  "max_body": "512MiB",
+  "cache_size": 1024,
+  "since": "2025-02-21T10:00:00Z",
+  "endpoint": "https://example.com:8443/api",
+  "bind": "::1",
+  "allow": ["10.0.0.0/8", "192.168.1.0/24"],
+  "match": "^api-[0-9]+$"
+}`
+	var c typesConfig
+	if err := Decode(parse(t, input), &c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Timeout != 90*time.Second {
+		t.Errorf("Decode() got timeout %v", c.Timeout)
+	}
+	if c.MaxBody != 512<<20 || c.CacheSize != 1024 {
+		t.Errorf("Decode() got sizes %v %v", c.MaxBody, c.CacheSize)
+	}
+	if !c.Since.Equal(time.Date(2025, 2, 21, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Decode() got since %v", c.Since)
+	}
+	if c.Endpoint == nil || c.Endpoint.Port() != "8443" {
+		t.Errorf("Decode() got endpoint %v", c.Endpoint)
+	}
+	if !c.Bind.Equal(net.IPv6loopback) || len(c.Allow) != 2 || c.Allow[1].String() != "192.168.1.0/24" {
+		t.Errorf("Decode() got bind %v allow %v", c.Bind, c.Allow)
+	}
+	if c.Match == nil || !c.Match.MatchString("api-12") {
+		t.Errorf("Decode() got match %v", c.Match)
+	}
+}
+
+func TestDecode_Types_Errors(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{`{"timeout": "30x"}`, `timeout: invalid duration "30x"`},
+		{`{"timeout": 30}`, "timeout: expected string for duration, got number at line 1 col 13"},
+		{`{"max_body": "12 parsecs"}`, `max_body: invalid byte size: unknown size unit "parsecs"`},
+		{`{"since": "yesterday"}`, `since: invalid timestamp "yesterday"`},
+		{`{"endpoint": "http://a b.com:x"}`, `endpoint: invalid URL`},
+		{`{"bind": "1.2.3"}`, `bind: invalid IP address "1.2.3"`},
+		{"{\n  \"allow\": [\"10.0.0.0\"]\n}", `allow[0]: invalid CIDR "10.0.0.0", expected a value like "10.0.0.0/8" at line 2 col 13`},
+		{`{"match": "(a"}`, "match: invalid regular expression"},
+	}
+	for _, tt := range tests {
+		var c typesConfig
+		err := Decode(parse(t, tt.input), &c)
+		if err == nil {
+			t.Fatalf("Decode(%s) expected error, got nil", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Decode(%s) error = %v, want error containing %v", tt.input, err, tt.wantError)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ByteSize
+	}{
+		{"0", 0},
+		{"10", 10},
+		{"10B", 10},
+		{"1kb", 1000},
+		{"1KiB", 1024},
+		{"1.5GiB", 3 << 29},
+		{"2 TB", 2e12},
+		{"8191PiB", 8191 << 50},
+		{"9223372036854774784", 9223372036854774784},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "MiB", "1.2.3MB", "-1KB", "99999999PiB", "8192PiB", "9223372036854775807"} {
+		if _, err := ParseByteSize(input); err == nil {
+			t.Errorf("ParseByteSize(%q) expected error", input)
+		}
+	}
+	if s := ByteSize(512 << 20).String(); s != "512MiB" {
+		t.Errorf("String() = %v", s)
+	}
+	if s := ByteSize(1000).String(); s != "1000B" {
+		t.Errorf("String() = %v", s)
+	}
+}
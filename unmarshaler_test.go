@@ -0,0 +1,97 @@
+package tracedconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// endpoint accepts either "host:port" or {"host": ..., "port": ...}.
+type endpoint struct {
+	Host string
+	Port int
+}
+
+func (e *endpoint) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		host, port, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("endpoint %q is missing a port", s)
+		}
+		e.Host = host
+		_, err := fmt.Sscanf(port, "%d", &e.Port)
+		return err
+	}
+	type plain endpoint
+	return json.Unmarshal(b, (*plain)(e))
+}
+
+type color string
+
+func (c *color) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "red", "green":
+		*c = color(b)
+		return nil
+	}
+	return fmt.Errorf("unknown color %q", b)
+}
+
+type unmarshalerConfig struct {
+	Primary   endpoint             `json:"primary"`
+	Fallbacks []*endpoint          `json:"fallbacks"`
+	Addr      netip.Addr           `json:"addr"`
+	Colors    map[color]netip.Addr `json:"colors"`
+}
+
+func TestDecode_Unmarshalers(t *testing.T) {
+	input := `{
+  "primary": "db:5432",
+  "fallbacks": [{"Host": "db2", "Port": 5433}],
+  "addr": "10.0.0.1",
+  "colors": {"red": "::1"}
+}`
+	var c unmarshalerConfig
+	if err := Decode(parse(t, input), &c); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Primary.Host != "db" || c.Primary.Port != 5432 {
+		t.Errorf("Decode() got primary %+v", c.Primary)
+	}
+	if len(c.Fallbacks) != 1 || c.Fallbacks[0].Port != 5433 {
+		t.Errorf("Decode() got fallbacks %+v", c.Fallbacks)
+	}
+	if c.Addr.String() != "10.0.0.1" || c.Colors["red"].String() != "::1" {
+		t.Errorf("Decode() got addr %v colors %v", c.Addr, c.Colors)
+	}
+}
+
+func TestDecode_Unmarshalers_Errors(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantError string
+	}{
+		{"{\n  \"primary\": \"db\"\n}", `primary: endpoint "db" is missing a port at config.json:2:14`},
+		{`{"addr": "10.0.0"}`, `addr: ParseAddr("10.0.0"): IPv4 address too short at config.json:1:10`},
+		{`{"addr": 1}`, "addr: expected string for netip.Addr, got number at config.json:1:10"},
+		{`{"colors": {"blue": "::1"}}`, `colors.blue: invalid key: unknown color "blue" at config.json:1:13`},
+	}
+	for _, tt := range tests {
+		p := slowjson.NewParser(tt.input)
+		p.File = "config.json"
+		n, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		var c unmarshalerConfig
+		err = Decode(n, &c)
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Decode(%s) error = %v, want error containing %v", tt.input, err, tt.wantError)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tracedconfig
+
+import (
+	"fmt"
+
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// UnusedKey is a loaded value that was never read.
+type UnusedKey struct {
+	// Path is the path of the value in the merged tree, e.g. "servers[0].weight".
+	Path string
+	// Origin is the source that set the value.
+	Origin merge.Origin
+}
+
+func (k UnusedKey) String() string {
+	return fmt.Sprintf("%s: %s is never read", k.Origin, k.Path)
+}
+
+// markUsed records that the value n of the merged tree was read with everything below it.
+func (c *Config) markUsed(n *slowjson.Node) {
+	if !c.TrackUsage || n == nil {
+		return
+	}
+	path := n.Path().String()
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	if c.used == nil {
+		c.used = map[string]bool{}
+	}
+	c.used[path] = true
+}
+
+// UnusedKeys returns the values of the merged tree that Get and Decode never read, in document order,
+// to find config nobody uses anymore. Call it on demand or at shutdown, after the code paths reading
+// config ran. Scalars, nulls and empty objects and arrays are listed, a value read by Get counts with
+// everything below it, and Decode reads the values it stores in the target, not ignored keys. Reads
+// are tracked by path so they survive reloads. It returns nil unless TrackUsage is set.
+func (c *Config) UnusedKeys() []UnusedKey {
+	if !c.TrackUsage {
+		return nil
+	}
+	res := c.result()
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	var unused []UnusedKey
+	var walk func(n *slowjson.Node, p slowjson.Path)
+	walk = func(n *slowjson.Node, p slowjson.Path) {
+		path := p.String()
+		if c.used[path] {
+			return
+		}
+		switch {
+		case n.Type == slowjson.NodeObject && len(n.Children) > 0:
+			for _, key := range n.Children {
+				if len(key.Children) > 0 {
+					walk(key.Children[0], p.Key(key.Value))
+				}
+			}
+		case n.Type == slowjson.NodeArray && len(n.Children) > 0:
+			for i, elem := range n.Children {
+				walk(elem, p.Index(i))
+			}
+		default:
+			origin, ok := res.Origin(path)
+			if !ok {
+				origin = merge.Origin{Node: n}
+			}
+			unused = append(unused, UnusedKey{Path: path, Origin: origin})
+		}
+	}
+	walk(res.Root, nil)
+	return unused
+}
+
+// ResetUsage forgets which values were read, e.g. to only track the reads after startup.
+func (c *Config) ResetUsage() {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.used = nil
+}
@@ -0,0 +1,63 @@
+package tracedconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_UnusedKeys(t *testing.T) {
+	c := NewConfig(
+		Bytes("defaults.json", []byte(`{"server": {"host": "localhost", "port": 80, "old": true}, "extra": {"a": 1}}`)),
+		Bytes("prod.json", []byte(`{"server": {"port": 443, "timeout": "5s"}, "tags": [], "feature": {"x": 1, "y": 2}}`)),
+	)
+	c.TrackUsage = true
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Server struct {
+			Host    string
+			Port    int
+			Timeout time.Duration
+		}
+		Extra interface{}
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("feature.x")
+
+	var got []string
+	for _, k := range c.UnusedKeys() {
+		got = append(got, k.String())
+	}
+	want := []string{
+		"defaults.json:1:53 (defaults.json): server.old is never read",
+		"prod.json:1:52 (prod.json): tags is never read",
+		"prod.json:1:81 (prod.json): feature.y is never read",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("UnusedKeys() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	c.Get("feature")
+	if got := c.UnusedKeys(); len(got) != 2 {
+		t.Errorf("after Get(feature) UnusedKeys() = %v, want server.old and tags", got)
+	}
+	c.ResetUsage()
+	if got := c.UnusedKeys(); len(got) != 8 {
+		t.Errorf("after ResetUsage UnusedKeys() = %v, want every value", got)
+	}
+}
+
+func TestConfig_UnusedKeys_Disabled(t *testing.T) {
+	c := NewConfig(Bytes("a.json", []byte(`{"a": 1}`)))
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.UnusedKeys(); got != nil {
+		t.Errorf("UnusedKeys() = %v, want nil without TrackUsage", got)
+	}
+}
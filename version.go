@@ -0,0 +1,59 @@
+package tracedconfig
+
+import (
+	"strconv"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// VersionKey is the root key giving the version of the config format a document is written for,
+// e.g. {"configVersion": 2}.
+const VersionKey = "configVersion"
+
+// VersionRange is the range of VersionKey values a program reads, both ends included, so configs
+// written for a newer program are not consumed by an older one and the other way round. Zero fields
+// are not checked, documents without the key are accepted.
+type VersionRange struct {
+	Min int
+	Max int
+}
+
+// Check returns a *slowjson.ValidationError with code diag.CodeVersion pointing at the VersionKey of
+// n when it is not an integer within the range.
+func (r VersionRange) Check(n *slowjson.Node) error {
+	if r == (VersionRange{}) || n == nil {
+		return nil
+	}
+	v := n.Lookup(slowjson.Path{{Key: VersionKey}})
+	if v == nil {
+		return nil
+	}
+	var err error
+	version, perr := strconv.Atoi(v.Value)
+	switch {
+	case v.Type != slowjson.NodeNumber || perr != nil:
+		err = v.Errorf("%s must be an integer, got %s", VersionKey, v.Value)
+	case r.Min != 0 && version < r.Min:
+		err = v.Errorf("%s %d is older than this program reads (%s), migrate the config", VersionKey, version, r)
+	case r.Max != 0 && version > r.Max:
+		err = v.Errorf("%s %d is newer than this program reads (%s), upgrade the program", VersionKey, version, r)
+	default:
+		return nil
+	}
+	err.(*slowjson.ValidationError).Code = diag.CodeVersion
+	return err
+}
+
+func (r VersionRange) String() string {
+	switch {
+	case r.Min == 0:
+		return "up to " + strconv.Itoa(r.Max)
+	case r.Max == 0:
+		return strconv.Itoa(r.Min) + " or later"
+	case r.Min == r.Max:
+		return "only " + strconv.Itoa(r.Min)
+	default:
+		return strconv.Itoa(r.Min) + " to " + strconv.Itoa(r.Max)
+	}
+}
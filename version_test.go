@@ -0,0 +1,56 @@
+package tracedconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/at15/tracedconfig/diag"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func TestVersionRange_Check(t *testing.T) {
+	tests := []struct {
+		r    VersionRange
+		doc  string
+		want string
+	}{
+		{VersionRange{}, `{"configVersion": "x"}`, ""},
+		{VersionRange{Min: 1, Max: 2}, `{"port": 80}`, ""},
+		{VersionRange{Min: 1, Max: 2}, `{"configVersion": 2}`, ""},
+		{VersionRange{Min: 2, Max: 3}, `{"configVersion": 1}`, "configVersion: configVersion 1 is older than this program reads (2 to 3), migrate the config at app.json:1:19"},
+		{VersionRange{Min: 1, Max: 2}, `{"configVersion": 3}`, "configVersion: configVersion 3 is newer than this program reads (1 to 2), upgrade the program at app.json:1:19"},
+		{VersionRange{Max: 2}, `{"configVersion": 3}`, "configVersion: configVersion 3 is newer than this program reads (up to 2), upgrade the program at app.json:1:19"},
+		{VersionRange{Min: 2}, `{"configVersion": 1}`, "configVersion: configVersion 1 is older than this program reads (2 or later), migrate the config at app.json:1:19"},
+		{VersionRange{Min: 2, Max: 2}, `{"configVersion": 1}`, "configVersion: configVersion 1 is older than this program reads (only 2), migrate the config at app.json:1:19"},
+		{VersionRange{Min: 1}, `{"configVersion": "2"}`, "configVersion: configVersion must be an integer, got 2 at app.json:1:19"},
+		{VersionRange{Min: 1}, `{"configVersion": 1.5}`, "configVersion: configVersion must be an integer, got 1.5 at app.json:1:19"},
+	}
+	for _, tt := range tests {
+		n, err := Bytes("app.json", []byte(tt.doc)).Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tt.r.Check(n)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Check(%s) error = %v", tt.doc, err)
+			}
+			continue
+		}
+		var ve *slowjson.ValidationError
+		if !errors.As(err, &ve) || ve.Code != diag.CodeVersion || err.Error() != tt.want {
+			t.Errorf("Check(%s) error = %v, want %q with code %s", tt.doc, err, tt.want, diag.CodeVersion)
+		}
+	}
+}
+
+func TestConfig_Versions(t *testing.T) {
+	c := NewConfig(Bytes("defaults.json", []byte(`{"configVersion": 2, "port": 80}`)), Bytes("old.json", []byte(`{"configVersion": 1}`)))
+	c.Versions = VersionRange{Min: 2, Max: 2}
+	err := c.Load(context.Background())
+	var ve *slowjson.ValidationError
+	if !errors.As(err, &ve) || ve.Pos.File != "old.json" {
+		t.Errorf("Load() error = %v, want one pointing at old.json", err)
+	}
+}
@@ -0,0 +1,86 @@
+package tracedconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ChangeDetector is implemented by sources that can tell whether they changed since the last Load,
+// e.g. by comparing an object version, so Watch only reloads when needed.
+type ChangeDetector interface {
+	Source
+	Changed(ctx context.Context) (bool, error)
+}
+
+// SourceChanged asks src whether it changed, sources that are not a ChangeDetector count as changed.
+// Wrapping sources use it to forward Changed to the source they wrap.
+func SourceChanged(ctx context.Context, src Source) (bool, error) {
+	if cd, ok := src.(ChangeDetector); ok {
+		return cd.Changed(ctx)
+	}
+	return true, nil
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Interval between checks, polling is off when zero.
+	Interval time.Duration
+	// Notify triggers a check, e.g. on a storage event notification or a pub/sub message.
+	// Closing it ends the notifications but not Watch.
+	Notify <-chan struct{}
+	// OnReload is called after every reload, and for failed checks, with the error or nil.
+	OnReload func(error)
+	// Sources limits the checks to these sources, all sources of the config are checked when empty.
+	Sources []ChangeDetector
+}
+
+// Watch reloads c on every interval and notification when any source has changed. Sources that are
+// not a ChangeDetector count as changed, so with such a source every check reloads, undoing a Rollback.
+// It blocks until ctx is done. A failed reload keeps the previous config, see Load.
+func (c *Config) Watch(ctx context.Context, opts WatchOptions) error {
+	var tick <-chan time.Time
+	if opts.Interval > 0 {
+		t := time.NewTicker(opts.Interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	report := func(err error) {
+		if opts.OnReload != nil {
+			opts.OnReload(err)
+		}
+	}
+	sources := c.sources
+	if len(opts.Sources) > 0 {
+		sources = make([]Source, len(opts.Sources))
+		for i, s := range opts.Sources {
+			sources[i] = s
+		}
+	}
+	notify := opts.Notify
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+		case _, ok := <-notify:
+			if !ok {
+				// a closed channel stops notifications, polling goes on
+				notify = nil
+				continue
+			}
+		}
+		reload := false
+		for _, s := range sources {
+			ok, err := SourceChanged(ctx, s)
+			if err != nil {
+				report(fmt.Errorf("check %s: %w", s.Name(), err))
+				continue
+			}
+			reload = reload || ok
+		}
+		if reload {
+			report(c.Load(ctx))
+		}
+	}
+}
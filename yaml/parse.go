@@ -0,0 +1,1253 @@
+package yaml
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/at15/tracedconfig/internal/suggest"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// maxAliasNodes limits the nodes copied by aliases, so a small document of nested aliases cannot
+// expand to billions of nodes.
+const maxAliasNodes = 1 << 20
+
+// Parse parses a YAML document. An empty document is null, a stream of several documents is an
+// error, see ParseStream. file is recorded as the file of the nodes.
+func Parse(data []byte, file string) (*slowjson.Node, error) {
+	docs, err := ParseStream(data, file)
+	if err != nil {
+		return nil, err
+	}
+	switch len(docs) {
+	case 0:
+		return &slowjson.Node{Type: slowjson.NodeNull, Value: "null", StartLine: 1, StartCol: 1, EndLine: 1, EndCol: 1, Source: string(data), File: file}, nil
+	case 1:
+		return docs[0], nil
+	default:
+		return nil, docs[1].Errorf("expected a single document, the stream has %d", len(docs))
+	}
+}
+
+// ParseStream parses every document of a stream, separated by "---" lines and optionally ended by
+// "..." lines. Positions are those in the whole stream, so they point into the file. Anchors are
+// local to their document.
+func ParseStream(data []byte, file string) ([]*slowjson.Node, error) {
+	p := &parser{src: string(data), file: file, line: 1, col: 1}
+	if strings.HasPrefix(p.src, "\uFEFF") {
+		p.off = len("\uFEFF")
+	}
+	docs, err := p.stream()
+	if err != nil {
+		return nil, err
+	}
+	slowjson.AttachComments(p.src, docs, "#")
+	return docs, nil
+}
+
+type parser struct {
+	src  string
+	file string
+
+	off, line, col int
+
+	anchors map[string]*slowjson.Node
+	// mergeKeys are the plain "<<" keys of the current document.
+	mergeKeys map[*slowjson.Node]bool
+	copied    int
+	depth     int
+}
+
+// mark is a position in the input.
+type mark struct {
+	off, line, col int
+}
+
+func (p *parser) mark() mark {
+	return mark{p.off, p.line, p.col}
+}
+
+func (p *parser) reset(m mark) {
+	p.off, p.line, p.col = m.off, m.line, m.col
+}
+
+func (p *parser) errorf(m mark, format string, args ...interface{}) error {
+	return &slowjson.ParseError{
+		Pos: slowjson.Position{File: p.file, Line: m.line, Col: m.col, Offset: m.off},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// node returns a node of typ starting at start and ending at the current position.
+func (p *parser) node(typ slowjson.NodeType, start mark) *slowjson.Node {
+	return p.span(typ, start, p.mark())
+}
+
+func (p *parser) span(typ slowjson.NodeType, start, end mark) *slowjson.Node {
+	return &slowjson.Node{
+		Type:        typ,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		StartOffset: start.off,
+		EndLine:     end.line,
+		EndCol:      end.col,
+		EndOffset:   end.off,
+		Source:      p.src,
+		File:        p.file,
+	}
+}
+
+func (p *parser) null(at mark) *slowjson.Node {
+	n := p.span(slowjson.NodeNull, at, at)
+	n.Value = "null"
+	return n
+}
+
+func (p *parser) setEnd(n *slowjson.Node, end mark) {
+	n.EndLine, n.EndCol, n.EndOffset = end.line, end.col, end.off
+}
+
+func (p *parser) endOf(n *slowjson.Node) mark {
+	return mark{n.EndOffset, n.EndLine, n.EndCol}
+}
+
+func (p *parser) eof() bool {
+	return p.off >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *parser) peekAt(off int) byte {
+	if off >= len(p.src) {
+		return 0
+	}
+	return p.src[off]
+}
+
+// next consumes a rune.
+func (p *parser) next() {
+	if p.eof() {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(p.src[p.off:])
+	p.off += size
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+}
+
+// advanceTo consumes the input up to off.
+func (p *parser) advanceTo(off int) {
+	for p.off < off {
+		p.next()
+	}
+}
+
+// blankAt reports whether off is a space, tab, line break or the end of input.
+func (p *parser) blankAt(off int) bool {
+	switch p.peekAt(off) {
+	case 0, ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
+
+// skipSpace consumes spaces and tabs.
+func (p *parser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.next()
+	}
+}
+
+// atLineEnd reports whether only a comment or nothing is left on the line.
+func (p *parser) atLineEnd() bool {
+	switch p.peek() {
+	case 0, '\n', '\r', '#':
+		return true
+	}
+	return false
+}
+
+// skipToContent consumes spaces, comments and line breaks up to the next content. Tabs may not
+// indent content.
+func (p *parser) skipToContent() error {
+	lineStart := p.col == 1
+	tab := false
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\r':
+			p.next()
+		case '\t':
+			tab = tab || lineStart
+			p.next()
+		case '\n':
+			p.next()
+			lineStart, tab = true, false
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			if tab {
+				return p.errorf(p.mark(), "tabs are not allowed for indentation")
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// atMarker reports whether a document marker, "---" or "...", starts the line at the current position.
+func (p *parser) atMarker(marker string) bool {
+	return p.col == 1 && strings.HasPrefix(p.src[p.off:], marker) && p.blankAt(p.off+3)
+}
+
+// atBoundary reports whether the current document ends here.
+func (p *parser) atBoundary() bool {
+	return p.eof() || p.atMarker("---") || p.atMarker("...")
+}
+
+func (p *parser) atSeqEntry() bool {
+	return p.peek() == '-' && p.blankAt(p.off+1)
+}
+
+func (p *parser) stream() ([]*slowjson.Node, error) {
+	var docs []*slowjson.Node
+	for {
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		for p.col == 1 && p.peek() == '%' {
+			// directives such as %YAML 1.2 do not change how config is read
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+		}
+		if p.eof() {
+			return docs, nil
+		}
+		if p.atMarker("...") {
+			p.advanceTo(p.off + 3)
+			continue
+		}
+		start := p.mark()
+		explicit := p.atMarker("---")
+		if explicit {
+			p.advanceTo(p.off + 3)
+		}
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		p.anchors = map[string]*slowjson.Node{}
+		p.mergeKeys = map[*slowjson.Node]bool{}
+		var root *slowjson.Node
+		if p.atBoundary() {
+			root = p.null(start)
+		} else {
+			var err error
+			if root, err = p.value(-1, false); err != nil {
+				return nil, err
+			}
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+			if !p.atBoundary() {
+				return nil, p.errorf(p.mark(), "unexpected content, check the indentation")
+			}
+		}
+		slowjson.SetParents(root)
+		docs = append(docs, root)
+	}
+}
+
+// value parses the node starting at the current position. parent is the indentation of the enclosing
+// block collection, -1 at the top of a document. inline is set for a value on the line of its key,
+// which cannot start a block collection there.
+func (p *parser) value(parent int, inline bool) (*slowjson.Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > slowjson.MaxDepth {
+		return nil, p.errorf(p.mark(), "exceeded max depth %d", slowjson.MaxDepth)
+	}
+	start := p.mark()
+	indent := p.col - 1
+	anchor, tag, err := p.properties()
+	if err != nil {
+		return nil, err
+	}
+	if (anchor != "" || tag != "") && p.atLineEnd() {
+		// the properties are for a node on the next lines
+		after := p.mark()
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		var n *slowjson.Node
+		if p.atBoundary() || p.col-1 < parent || p.col-1 == parent && !(inline && p.atSeqEntry()) {
+			p.reset(after)
+			n = p.null(after)
+		} else if n, err = p.value(parent, false); err != nil {
+			return nil, err
+		}
+		return p.finish(n, anchor, tag, start)
+	}
+
+	var n *slowjson.Node
+	style := byte(0)
+	switch c := p.peek(); {
+	case c == '*':
+		if anchor != "" || tag != "" {
+			return nil, p.errorf(start, "an alias cannot have an anchor or tag")
+		}
+		if n, err = p.alias(false); err != nil {
+			return nil, err
+		}
+	case c == '[' || c == '{':
+		if n, err = p.flow(); err != nil {
+			return nil, err
+		}
+	case c == '|' || c == '>':
+		if n, err = p.blockScalar(parent); err != nil {
+			return nil, err
+		}
+		return p.finish(n, anchor, tag, start)
+	case p.atSeqEntry():
+		if inline {
+			return nil, p.errorf(p.mark(), "a block sequence cannot start on the line of its key")
+		}
+		if n, err = p.sequence(indent); err != nil {
+			return nil, err
+		}
+		return p.finish(n, anchor, tag, start)
+	case c == '?' && p.blankAt(p.off+1):
+		return nil, p.errorf(p.mark(), "complex mapping keys are not supported")
+	case c == '"' || c == '\'':
+		style = c
+		if n, err = p.quoted(); err != nil {
+			return nil, err
+		}
+	case c == '@' || c == '`':
+		return nil, p.errorf(p.mark(), "%q is reserved and cannot start a plain scalar", c)
+	case c == ':' && p.blankAt(p.off+1):
+		return nil, p.errorf(p.mark(), "missing key before ':'")
+	default:
+		if n, err = p.plain(parent, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// a scalar followed by ':' is the first key of a mapping
+	after := p.mark()
+	p.skipSpace()
+	if p.peek() == ':' && (p.blankAt(p.off+1) || style != 0 || n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray) {
+		if inline {
+			return nil, p.errorf(p.mark(), "mapping values are not allowed here, quote the value if it contains ': '")
+		}
+		key, err := p.key(n, style, tag)
+		if err != nil {
+			return nil, err
+		}
+		if anchor != "" {
+			p.anchors[anchor] = key
+		}
+		return p.mapping(indent, key)
+	}
+	p.reset(after)
+	if style != 0 && tag == "" {
+		tag = "!!str"
+	}
+	return p.finish(n, anchor, tag, start)
+}
+
+// finish applies the tag and registers the anchor of n.
+func (p *parser) finish(n *slowjson.Node, anchor, tag string, start mark) (*slowjson.Node, error) {
+	if err := p.applyTag(n, tag, start); err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = n
+	}
+	return n, nil
+}
+
+// properties reads the anchor and tag in front of a node, e.g. "&base" or "!!str".
+func (p *parser) properties() (anchor, tag string, err error) {
+	for {
+		switch p.peek() {
+		case '&':
+			if anchor != "" {
+				return "", "", p.errorf(p.mark(), "a node can only have one anchor")
+			}
+			p.next()
+			if anchor = p.name(); anchor == "" {
+				return "", "", p.errorf(p.mark(), "missing anchor name after '&'")
+			}
+		case '!':
+			if tag != "" {
+				return "", "", p.errorf(p.mark(), "a node can only have one tag")
+			}
+			start := p.off
+			for !p.blankAt(p.off) && !isFlowIndicator(p.peek()) || p.off == start {
+				p.next()
+			}
+			tag = p.src[start:p.off]
+		default:
+			return anchor, tag, nil
+		}
+		p.skipSpace()
+	}
+}
+
+// name reads an anchor or alias name.
+func (p *parser) name() string {
+	start := p.off
+	for !p.blankAt(p.off) && !isFlowIndicator(p.peek()) {
+		p.next()
+	}
+	return p.src[start:p.off]
+}
+
+// alias returns a copy of the node anchored under the name at the current '*'.
+func (p *parser) alias(inFlow bool) (*slowjson.Node, error) {
+	start := p.mark()
+	p.next()
+	name := p.name()
+	if name == "" {
+		return nil, p.errorf(start, "missing alias name after '*'")
+	}
+	target, ok := p.anchors[name]
+	if !ok {
+		return nil, p.errorf(start, "unknown anchor %q, %s", name, p.anchorsBefore(name))
+	}
+	return p.copyNode(target, start)
+}
+
+// anchorsBefore describes the anchors an alias to name could refer to: those defined before it in the
+// document, the closest first.
+func (p *parser) anchorsBefore(name string) string {
+	if len(p.anchors) == 0 {
+		return "no anchor is defined before it"
+	}
+	names := make([]string, 0, len(p.anchors))
+	for a := range p.anchors {
+		names = append(names, a)
+	}
+	sort.Strings(names)
+	if closest, ok := suggest.Closest(name, names); ok {
+		return fmt.Sprintf("did you mean *%s?", closest)
+	}
+	for i, a := range names {
+		names[i] = "&" + a
+	}
+	return "defined before it are " + strings.Join(names, ", ")
+}
+
+// copyNode deep-copies n for an alias. The copy keeps the positions of n, where its values are written.
+func (p *parser) copyNode(n *slowjson.Node, at mark) (*slowjson.Node, error) {
+	p.copied++
+	if p.copied > maxAliasNodes {
+		return nil, p.errorf(at, "aliases expand to more than %d nodes", maxAliasNodes)
+	}
+	c := *n
+	c.Parent = nil
+	c.Children = make([]*slowjson.Node, len(n.Children))
+	for i, child := range n.Children {
+		cc, err := p.copyNode(child, at)
+		if err != nil {
+			return nil, err
+		}
+		c.Children[i] = cc
+	}
+	if n.Children == nil {
+		c.Children = nil
+	}
+	if p.mergeKeys[n] {
+		p.mergeKeys[&c] = true
+	}
+	return &c, nil
+}
+
+// key converts the scalar n to the key of a mapping member.
+func (p *parser) key(n *slowjson.Node, style byte, tag string) (*slowjson.Node, error) {
+	if n.Type == slowjson.NodeObject || n.Type == slowjson.NodeArray {
+		return nil, p.errorf(mark{n.StartOffset, n.StartLine, n.StartCol}, "only scalar keys are supported")
+	}
+	if n.StartLine != n.EndLine && style == 0 {
+		return nil, p.errorf(mark{n.StartOffset, n.StartLine, n.StartCol}, "a key must be on a single line")
+	}
+	if style == 0 && tag == "" && n.Value == "<<" {
+		p.mergeKeys[n] = true
+	}
+	n.Type = slowjson.NodeString
+	return n, nil
+}
+
+// mapping parses a block mapping whose first key was read, the current position is before its ':'.
+func (p *parser) mapping(indent int, key *slowjson.Node) (*slowjson.Node, error) {
+	obj := p.span(slowjson.NodeObject, mark{key.StartOffset, key.StartLine, key.StartCol}, p.mark())
+	for {
+		p.skipSpace()
+		p.next() // ':'
+		colon := p.mark()
+		p.skipSpace()
+		var val *slowjson.Node
+		if p.atLineEnd() {
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+			if p.atBoundary() || p.col-1 < indent || p.col-1 == indent && !p.atSeqEntry() {
+				val = p.null(colon)
+			} else {
+				var err error
+				if val, err = p.value(indent, false); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			var err error
+			if val, err = p.value(indent, true); err != nil {
+				return nil, err
+			}
+		}
+		key.Children = []*slowjson.Node{val}
+		obj.Children = append(obj.Children, key)
+		p.setEnd(obj, p.endOf(val))
+
+		end := p.mark()
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.atBoundary() || p.col-1 < indent {
+			p.reset(end)
+			break
+		}
+		if p.col-1 > indent {
+			return nil, p.errorf(p.mark(), "unexpected indentation")
+		}
+		var err error
+		if key, err = p.nextKey(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, p.merge(obj)
+}
+
+// nextKey reads the key of the next member of a block mapping, up to its ':'.
+func (p *parser) nextKey() (*slowjson.Node, error) {
+	start := p.mark()
+	anchor, tag, err := p.properties()
+	if err != nil {
+		return nil, err
+	}
+	var n *slowjson.Node
+	style := byte(0)
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		style = c
+		n, err = p.quoted()
+	case c == '*':
+		n, err = p.alias(false)
+	case p.atSeqEntry():
+		return nil, p.errorf(start, "expected a key, a sequence entry cannot follow mapping members at the same indentation")
+	case c == '[' || c == '{' || c == '?' && p.blankAt(p.off+1):
+		return nil, p.errorf(start, "only scalar keys are supported")
+	default:
+		n, err = p.plain(-1, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.peek() != ':' || !(p.blankAt(p.off+1) || style != 0) {
+		return nil, p.errorf(start, "expected a key followed by ':'")
+	}
+	key, err := p.key(n, style, tag)
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = key
+	}
+	return key, nil
+}
+
+// merge replaces the "<<" members of obj by the members of the mappings they name, skipping keys obj
+// sets itself and keys an earlier merged mapping has.
+func (p *parser) merge(obj *slowjson.Node) error {
+	hasMerge := false
+	explicit := map[string]bool{}
+	for _, key := range obj.Children {
+		if p.mergeKeys[key] {
+			hasMerge = true
+		} else {
+			explicit[key.Value] = true
+		}
+	}
+	if !hasMerge {
+		return nil
+	}
+	added := map[string]bool{}
+	var members []*slowjson.Node
+	for _, key := range obj.Children {
+		if !p.mergeKeys[key] {
+			members = append(members, key)
+			continue
+		}
+		val := key.Children[0]
+		sources := []*slowjson.Node{val}
+		if val.Type == slowjson.NodeArray {
+			sources = val.Children
+		}
+		for _, src := range sources {
+			if src.Type != slowjson.NodeObject {
+				return p.errorf(mark{src.StartOffset, src.StartLine, src.StartCol}, "the << merge key needs a mapping or a sequence of mappings")
+			}
+			for _, k := range src.Children {
+				if explicit[k.Value] || added[k.Value] {
+					continue
+				}
+				added[k.Value] = true
+				members = append(members, k)
+			}
+		}
+	}
+	obj.Children = members
+	return nil
+}
+
+// sequence parses a block sequence, the current position is at its first '-'.
+func (p *parser) sequence(indent int) (*slowjson.Node, error) {
+	arr := p.node(slowjson.NodeArray, p.mark())
+	for {
+		p.next() // '-'
+		dash := p.mark()
+		p.skipSpace()
+		var item *slowjson.Node
+		if p.atLineEnd() {
+			if err := p.skipToContent(); err != nil {
+				return nil, err
+			}
+			if p.atBoundary() || p.col-1 <= indent {
+				item = p.null(dash)
+			} else {
+				var err error
+				if item, err = p.value(indent, false); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			var err error
+			if item, err = p.value(indent, false); err != nil {
+				return nil, err
+			}
+		}
+		arr.Children = append(arr.Children, item)
+		p.setEnd(arr, p.endOf(item))
+
+		end := p.mark()
+		if err := p.skipToContent(); err != nil {
+			return nil, err
+		}
+		if p.atBoundary() || p.col-1 < indent || p.col-1 == indent && !p.atSeqEntry() {
+			p.reset(end)
+			return arr, nil
+		}
+		if p.col-1 > indent {
+			return nil, p.errorf(p.mark(), "unexpected indentation")
+		}
+	}
+}
+
+// plain reads a plain scalar. In block context it continues on more indented lines than parent, the
+// lines folded into one with spaces.
+func (p *parser) plain(parent int, inFlow bool) (*slowjson.Node, error) {
+	start := p.mark()
+	end := start
+	var b strings.Builder
+	for {
+		// one line of the scalar
+		var line strings.Builder
+		stop := false
+		for !p.eof() {
+			c := p.peek()
+			if c == '\n' || c == '\r' {
+				break
+			}
+			if c == ':' && (p.blankAt(p.off+1) || inFlow && isFlowIndicator(p.peekAt(p.off+1))) ||
+				c == '#' && p.off > 0 && (p.src[p.off-1] == ' ' || p.src[p.off-1] == '\t') ||
+				inFlow && isFlowIndicator(c) {
+				stop = true
+				break
+			}
+			r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+			line.WriteRune(r)
+			p.next()
+			if c != ' ' && c != '\t' {
+				end = p.mark()
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " \t"))
+		if stop || p.eof() {
+			break
+		}
+		// a continuation line must be more indented than the parent and not a comment
+		p.reset(end)
+		for !p.eof() && p.peek() != '\n' {
+			p.next()
+		}
+		breaks := 0
+		for !p.eof() {
+			p.skipSpace()
+			if c := p.peek(); c == '\n' || c == '\r' {
+				if c == '\r' {
+					p.next()
+					continue
+				}
+				p.next()
+				breaks++
+				continue
+			}
+			break
+		}
+		if p.eof() || p.peek() == '#' || p.atBoundary() || !inFlow && p.col-1 <= parent ||
+			inFlow && (isFlowIndicator(p.peek()) || p.peek() == ':') || !inFlow && p.atSeqEntry() && p.col-1 <= parent+1 {
+			break
+		}
+		if breaks == 1 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(strings.Repeat("\n", breaks-1))
+		}
+	}
+	p.reset(end)
+	if end == start {
+		return nil, p.errorf(start, "expected a value")
+	}
+	n := p.node(slowjson.NodeString, start)
+	n.Value = b.String()
+	p.resolve(n)
+	return n, nil
+}
+
+// quoted reads a single or double quoted scalar.
+func (p *parser) quoted() (*slowjson.Node, error) {
+	start := p.mark()
+	q := p.peek()
+	p.next()
+	var b strings.Builder
+	// spaces are held back so those before a line break can be dropped
+	var spaces strings.Builder
+	flush := func() {
+		b.WriteString(spaces.String())
+		spaces.Reset()
+	}
+	for {
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated quoted string")
+		}
+		c := p.peek()
+		switch {
+		case c == q && q == '\'' && p.peekAt(p.off+1) == '\'':
+			flush()
+			b.WriteByte('\'')
+			p.next()
+			p.next()
+		case c == q:
+			flush()
+			p.next()
+			n := p.node(slowjson.NodeString, start)
+			n.Value = b.String()
+			return n, nil
+		case c == ' ' || c == '\t':
+			spaces.WriteByte(c)
+			p.next()
+		case c == '\n' || c == '\r':
+			spaces.Reset()
+			breaks := 0
+			for !p.eof() {
+				// a lone \r is a line break too, \r\n counts once at its \n
+				if c := p.peek(); c == '\n' || c == '\r' && p.peekAt(p.off+1) != '\n' {
+					breaks++
+				} else if c != ' ' && c != '\t' && c != '\r' {
+					break
+				}
+				p.next()
+			}
+			if p.atMarker("---") || p.atMarker("...") {
+				return nil, p.errorf(start, "unterminated quoted string")
+			}
+			if breaks == 1 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(strings.Repeat("\n", breaks-1))
+			}
+		case c == '\\' && q == '"':
+			flush()
+			esc := p.mark()
+			p.next()
+			if e := p.peek(); e == '\n' || e == '\r' {
+				// an escaped line break joins the lines without a space
+				p.next()
+				if e == '\r' && p.peek() == '\n' {
+					p.next()
+				}
+				p.skipSpace()
+				continue
+			}
+			if err := p.escape(&b, esc); err != nil {
+				return nil, err
+			}
+		default:
+			flush()
+			r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+			b.WriteRune(r)
+			p.next()
+		}
+	}
+}
+
+var escapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f", 'r': "\r",
+	'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085", '_': " ", 'L': " ",
+	'P': " ",
+}
+
+// escape decodes the escape sequence after a backslash of a double quoted scalar.
+func (p *parser) escape(b *strings.Builder, at mark) error {
+	e := p.peek()
+	if s, ok := escapes[e]; ok {
+		b.WriteString(s)
+		p.next()
+		return nil
+	}
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+	if digits == 0 || p.off+1+digits > len(p.src) {
+		return p.errorf(at, "invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(p.src[p.off+1:p.off+1+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return p.errorf(at, "invalid escape sequence")
+	}
+	b.WriteRune(rune(code))
+	p.advanceTo(p.off + 1 + digits)
+	return nil
+}
+
+// blockScalar reads a literal "|" or folded ">" scalar, parent is the indentation of its parent.
+func (p *parser) blockScalar(parent int) (*slowjson.Node, error) {
+	start := p.mark()
+	literal := p.peek() == '|'
+	p.next()
+	chomp := byte(0)
+	explicit := 0
+	for i := 0; i < 2; i++ {
+		switch c := p.peek(); {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+			p.next()
+		case c >= '1' && c <= '9' && explicit == 0:
+			explicit = int(c - '0')
+			p.next()
+		}
+	}
+	p.skipSpace()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.next()
+		}
+	}
+	if c := p.peek(); c == '\r' {
+		p.next()
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return nil, p.errorf(p.mark(), "invalid block scalar header")
+	}
+	end := p.mark()
+	p.next()
+
+	base := parent
+	if base < 0 {
+		base = 0
+	}
+	indent := base + explicit
+	if explicit == 0 {
+		// the first non-empty line sets the indentation
+		indent = -1
+		for off := p.off; off < len(p.src); {
+			lineEnd := strings.IndexByte(p.src[off:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(p.src) - off
+			}
+			line := strings.TrimRight(p.src[off:off+lineEnd], "\r")
+			if strings.TrimLeft(line, " ") != "" {
+				indent = len(line) - len(strings.TrimLeft(line, " "))
+				break
+			}
+			off += lineEnd + 1
+		}
+		if indent <= parent {
+			indent = -1
+		}
+	}
+
+	var lines []string
+	for indent >= 0 && !p.eof() {
+		if p.atMarker("---") || p.atMarker("...") {
+			break
+		}
+		lineEnd := strings.IndexByte(p.src[p.off:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(p.src) - p.off
+		}
+		line := strings.TrimRight(p.src[p.off:p.off+lineEnd], "\r")
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.TrimLeft(line, " ") == "" {
+			lines = append(lines, "")
+		} else if spaces < indent {
+			break
+		} else {
+			lines = append(lines, line[indent:])
+			p.advanceTo(p.off + len(line))
+			end = p.mark()
+		}
+		p.advanceTo(p.lineStartAfter())
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var b strings.Builder
+	empty := 0
+	prevMore := false
+	for i, line := range lines {
+		if line == "" {
+			empty++
+			continue
+		}
+		more := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case i == empty:
+			// leading empty lines
+			b.WriteString(strings.Repeat("\n", empty))
+		case literal:
+			b.WriteString(strings.Repeat("\n", empty+1))
+		case empty > 0:
+			b.WriteString(strings.Repeat("\n", empty))
+			if prevMore || more {
+				b.WriteByte('\n')
+			}
+		case prevMore || more:
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(line)
+		empty = 0
+		prevMore = more
+	}
+	switch {
+	case chomp == '-':
+	case chomp == '+':
+		if len(lines) > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat("\n", trailing))
+	case len(lines) > 0:
+		b.WriteByte('\n')
+	}
+	n := p.span(slowjson.NodeString, start, end)
+	n.Value = b.String()
+	return n, nil
+}
+
+// lineStartAfter returns the offset after the line break ending the current line.
+func (p *parser) lineStartAfter() int {
+	i := strings.IndexByte(p.src[p.off:], '\n')
+	if i < 0 {
+		return len(p.src)
+	}
+	return p.off + i + 1
+}
+
+// flow parses a flow mapping or sequence, the current position is at its '{' or '['.
+func (p *parser) flow() (*slowjson.Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > slowjson.MaxDepth {
+		return nil, p.errorf(p.mark(), "exceeded max depth %d", slowjson.MaxDepth)
+	}
+	start := p.mark()
+	isObj := p.peek() == '{'
+	closing := byte(']')
+	n := p.node(slowjson.NodeArray, start)
+	if isObj {
+		closing = '}'
+		n.Type = slowjson.NodeObject
+	}
+	p.next()
+	for {
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf(start, "unterminated flow collection, expected '%c'", closing)
+		}
+		if p.peek() == closing {
+			p.next()
+			break
+		}
+		entry, style, tag, err := p.flowValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		if p.peek() == ':' {
+			key, err := p.key(entry, style, tag)
+			if err != nil {
+				return nil, err
+			}
+			p.next()
+			colon := p.mark()
+			if err := p.skipFlowSpace(); err != nil {
+				return nil, err
+			}
+			val := p.null(colon)
+			if c := p.peek(); c != ',' && c != closing {
+				if val, _, _, err = p.flowValue(); err != nil {
+					return nil, err
+				}
+			}
+			key.Children = []*slowjson.Node{val}
+			entry = key
+			if !isObj {
+				// a single pair mapping in a sequence, e.g. [a: 1]
+				pair := p.span(slowjson.NodeObject, mark{key.StartOffset, key.StartLine, key.StartCol}, p.endOf(val))
+				pair.Children = []*slowjson.Node{key}
+				entry = pair
+			}
+		} else if isObj {
+			// a key without value, e.g. {a, b}
+			key, err := p.key(entry, style, tag)
+			if err != nil {
+				return nil, err
+			}
+			key.Children = []*slowjson.Node{p.null(p.endOf(key))}
+			entry = key
+		}
+		n.Children = append(n.Children, entry)
+		if err := p.skipFlowSpace(); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.next()
+		case closing:
+		case 0:
+			return nil, p.errorf(start, "unterminated flow collection, expected '%c'", closing)
+		default:
+			return nil, p.errorf(p.mark(), "expected ',' or '%c'", closing)
+		}
+	}
+	p.setEnd(n, p.mark())
+	if isObj {
+		if err := p.merge(n); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// flowValue parses a node inside a flow collection.
+func (p *parser) flowValue() (*slowjson.Node, byte, string, error) {
+	start := p.mark()
+	anchor, tag, err := p.properties()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if err := p.skipFlowSpace(); err != nil {
+		return nil, 0, "", err
+	}
+	var n *slowjson.Node
+	style := byte(0)
+	switch c := p.peek(); {
+	case c == '[' || c == '{':
+		n, err = p.flow()
+	case c == '"' || c == '\'':
+		style = c
+		n, err = p.quoted()
+	case c == '*':
+		n, err = p.alias(true)
+	case (c == ',' || c == ']' || c == '}') && (anchor != "" || tag != ""):
+		n = p.null(p.mark())
+	case c == '?' && p.blankAt(p.off+1):
+		return nil, 0, "", p.errorf(p.mark(), "complex mapping keys are not supported")
+	default:
+		n, err = p.plain(-1, true)
+	}
+	if err != nil {
+		return nil, 0, "", err
+	}
+	scalarTag := tag
+	if style != 0 && scalarTag == "" {
+		scalarTag = "!!str"
+	}
+	if err := p.applyTag(n, scalarTag, start); err != nil {
+		return nil, 0, "", err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = n
+	}
+	return n, style, tag, nil
+}
+
+// skipFlowSpace consumes whitespace, line breaks and comments inside a flow collection.
+func (p *parser) skipFlowSpace() error {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			if p.atMarker("---") || p.atMarker("...") {
+				return p.errorf(p.mark(), "document marker inside a flow collection")
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolve types the plain scalar n by the core schema.
+func (p *parser) resolve(n *slowjson.Node) {
+	v := n.Value
+	switch v {
+	case "", "~", "null", "Null", "NULL":
+		n.Type, n.Value = slowjson.NodeNull, "null"
+	case "true", "True", "TRUE":
+		n.Type, n.Value = slowjson.NodeBoolean, "true"
+	case "false", "False", "FALSE":
+		n.Type, n.Value = slowjson.NodeBoolean, "false"
+	default:
+		if num, ok := number(v); ok {
+			n.Type, n.Value = slowjson.NodeNumber, num
+		}
+	}
+}
+
+// number converts a core schema integer or float to JSON syntax, e.g. "0x1F" to "31" and "+.5" to "0.5".
+// Infinity and NaN have no JSON form and are not numbers here.
+func number(v string) (string, bool) {
+	switch {
+	case strings.HasPrefix(v, "0x"), strings.HasPrefix(v, "0o"):
+		i, ok := new(big.Int).SetString(v[2:], map[byte]int{'x': 16, 'o': 8}[v[1]])
+		if !ok {
+			return "", false
+		}
+		return i.String(), true
+	case intPattern.MatchString(v):
+		i, _ := new(big.Int).SetString(strings.TrimPrefix(v, "+"), 10)
+		return i.String(), true
+	case floatPattern.MatchString(v):
+		sign := ""
+		if v[0] == '-' || v[0] == '+' {
+			if v[0] == '-' {
+				sign = "-"
+			}
+			v = v[1:]
+		}
+		mant, exp := v, ""
+		if i := strings.IndexAny(v, "eE"); i >= 0 {
+			mant, exp = v[:i], v[i:]
+		}
+		intPart, frac, hasDot := strings.Cut(mant, ".")
+		intPart = strings.TrimLeft(intPart, "0")
+		if intPart == "" {
+			intPart = "0"
+		}
+		out := sign + intPart
+		if hasDot {
+			if frac == "" {
+				frac = "0"
+			}
+			out += "." + frac
+		}
+		return out + exp, true
+	}
+	return "", false
+}
+
+// applyTag retypes n by a core schema tag.
+func (p *parser) applyTag(n *slowjson.Node, tag string, at mark) error {
+	scalar := n.Type != slowjson.NodeObject && n.Type != slowjson.NodeArray
+	raw := func() string {
+		if n.Type == slowjson.NodeString {
+			return n.Value
+		}
+		return p.src[n.StartOffset:n.EndOffset]
+	}
+	switch tag {
+	case "!!str":
+		if scalar {
+			n.Value = raw()
+			n.Type = slowjson.NodeString
+		}
+	case "!!int", "!!float":
+		num, ok := number(raw())
+		if !scalar || !ok || tag == "!!int" && strings.ContainsAny(num, ".eE") {
+			return p.errorf(at, "invalid %s value", tag)
+		}
+		n.Type, n.Value = slowjson.NodeNumber, num
+	case "!!bool":
+		switch strings.ToLower(raw()) {
+		case "true", "false":
+			n.Type, n.Value = slowjson.NodeBoolean, strings.ToLower(raw())
+		default:
+			return p.errorf(at, "invalid !!bool value")
+		}
+	case "!!null":
+		if !scalar {
+			return p.errorf(at, "invalid !!null value")
+		}
+		n.Type, n.Value = slowjson.NodeNull, "null"
+	case "!!map":
+		if n.Type != slowjson.NodeObject {
+			return p.errorf(at, "!!map needs a mapping")
+		}
+	case "!!seq":
+		if n.Type != slowjson.NodeArray {
+			return p.errorf(at, "!!seq needs a sequence")
+		}
+	}
+	return nil
+}
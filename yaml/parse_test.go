@@ -0,0 +1,222 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func marshal(t *testing.T, n *slowjson.Node) string {
+	t.Helper()
+	b, err := slowjson.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestParse(t *testing.T) {
+	input := "# server\nserver:\n  host: example.com\n  port: 8080\n  tags: [a, b]\nlist:\n- x\n- name: y\n  on: true\n"
+	n, err := Parse([]byte(input), "app.yaml")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := `{"server":{"host":"example.com","port":8080,"tags":["a","b"]},"list":["x",{"name":"y","on":true}]}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() = %s, want %s", got, want)
+	}
+	port := n.Get("server.port")
+	if port.StartLine != 4 || port.StartCol != 9 || port.EndCol != 13 || port.File != "app.yaml" {
+		t.Errorf("Parse() port at %d:%d-%d in %q", port.StartLine, port.StartCol, port.EndCol, port.File)
+	}
+	if got := input[port.StartOffset:port.EndOffset]; got != "8080" {
+		t.Errorf("Parse() port offsets cover %q", got)
+	}
+	if got := n.Get("list[1].name").Path().String(); got != "list[1].name" {
+		t.Errorf("Parse() parents give path %q", got)
+	}
+}
+
+func TestParse_Scalars(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a: ~", `{"a":null}`},
+		{"a:", `{"a":null}`},
+		{"a: True", `{"a":true}`},
+		{"a: yes", `{"a":"yes"}`},
+		{"a: 0x1F", `{"a":31}`},
+		{"a: 0o17", `{"a":15}`},
+		{"a: +12", `{"a":12}`},
+		{"a: -.5", `{"a":-0.5}`},
+		{"a: 1.", `{"a":1.0}`},
+		{"a: .inf", `{"a":".inf"}`},
+		{"a: 1.2.3", `{"a":"1.2.3"}`},
+		{"a: 10s", `{"a":"10s"}`},
+		{"a: http://x:80/#frag # note", `{"a":"http://x:80/#frag"}`},
+		{"a: '80'", `{"a":"80"}`},
+		{"a: 'it''s'", `{"a":"it's"}`},
+		{`a: "tab\tand \u00e9\x21"`, `{"a":"tab\tand é!"}`},
+		{"a: \"one\n  two\n\n  three\"", `{"a":"one two\nthree"}`},
+		{"a: \"joined\\\n  line\"", `{"a":"joinedline"}`},
+		{"a: \"x\ry\"\r", `{"a":"x y"}`},
+		{"a: \"x\r\n\r\ny\"", `{"a":"x\ny"}`},
+		{"a: \"joined\\\r  line\"", `{"a":"joinedline"}`},
+		{"a: plain\n  folded\n  text", `{"a":"plain folded text"}`},
+		{"a: !!str 123", `{"a":"123"}`},
+		{"a: !!int '42'", `{"a":42}`},
+		{"a: !!bool 'true'", `{"a":true}`},
+		{"a: !custom value", `{"a":"value"}`},
+		{`"quoted key": 1`, `{"quoted key":1}`},
+		{`{"json": [1, true, null]}`, `{"json":[1,true,null]}`},
+		{"a: {b: 1, c, d: [x, y: 2]}", `{"a":{"b":1,"c":null,"d":["x",{"y":2}]}}`},
+		{"a: [\n  1, # one\n  2,\n]", `{"a":[1,2]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := Parse([]byte(tt.input), "")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := marshal(t, n); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_BlockScalars(t *testing.T) {
+	input := "literal: |\n  line one\n    indented\n\n  line three\nfolded: >\n  a\n  b\n\n  c\nstrip: |-\n  x\n\nkeep: |+\n  x\n\nindent: |2\n    y\nlast: 1\n"
+	n, err := Parse([]byte(input), "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string]string{
+		"literal": "line one\n  indented\n\nline three\n",
+		"folded":  "a b\nc\n",
+		"strip":   "x",
+		"keep":    "x\n\n",
+		"indent":  "  y\n",
+	}
+	for key, v := range want {
+		if got := n.Get(key).Value; got != v {
+			t.Errorf("Parse() %s = %q, want %q", key, got, v)
+		}
+	}
+	if got := n.Get("literal"); got.StartLine != 1 || got.EndLine != 5 {
+		t.Errorf("Parse() literal spans lines %d-%d", got.StartLine, got.EndLine)
+	}
+	if got := n.Get("last").Value; got != "1" {
+		t.Errorf("Parse() last = %q", got)
+	}
+}
+
+func TestParse_Anchors(t *testing.T) {
+	input := "defaults: &defaults\n  host: localhost\n  port: 80\nextra: &extra\n  debug: true\n  port: 81\nprod:\n  <<: [*defaults, *extra]\n  host: prod\nlist: [*defaults]\n"
+	n, err := Parse([]byte(input), "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := `{"defaults":{"host":"localhost","port":80},"extra":{"debug":true,"port":81},"prod":{"port":80,"debug":true,"host":"prod"},"list":[{"host":"localhost","port":80}]}`
+	if got := marshal(t, n); got != want {
+		t.Errorf("Parse() = %s, want %s", got, want)
+	}
+	// merged keys take the place of "<<" and point where they are written
+	if port := n.Get("prod.port"); port.StartLine != 3 {
+		t.Errorf("Parse() prod.port at line %d, want 3", port.StartLine)
+	}
+	if got := n.Get("prod.port").Path().String(); got != "prod.port" {
+		t.Errorf("Parse() merged path = %q", got)
+	}
+}
+
+func TestParse_AliasBomb(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i < 10; i++ {
+		prev := strings.Repeat(fmt.Sprintf("*a%d, ", i-1), 10)
+		fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.TrimSuffix(prev, ", "))
+	}
+	_, err := Parse([]byte(b.String()), "")
+	if err == nil || !strings.Contains(err.Error(), "aliases expand to more than") {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	input := "%YAML 1.2\n---\nenv: dev\nport: 1\n---\nenv: prod\nport: 2\n...\n---\n--- # empty\n"
+	docs, err := ParseStream([]byte(input), "app.yaml")
+	if err != nil {
+		t.Fatalf("ParseStream() error = %v", err)
+	}
+	if len(docs) != 4 {
+		t.Fatalf("ParseStream() got %d documents", len(docs))
+	}
+	port := docs[1].Get("port")
+	if port.Value != "2" || port.StartLine != 7 || port.StartCol != 7 || input[port.StartOffset:port.EndOffset] != "2" {
+		t.Errorf("ParseStream() second port %q at %d:%d", port.Value, port.StartLine, port.StartCol)
+	}
+	if docs[2].Type != slowjson.NodeNull || docs[3].Type != slowjson.NodeNull {
+		t.Errorf("ParseStream() empty documents are %v, %v", docs[2].Type, docs[3].Type)
+	}
+	if docs[0].Parent != nil || docs[1].Get("env").Parent.Parent != docs[1] {
+		t.Errorf("ParseStream() documents must be separate roots")
+	}
+
+	if _, err := Parse([]byte(input), "app.yaml"); err == nil || !strings.Contains(err.Error(), "app.yaml:6:1") {
+		t.Errorf("Parse() of a stream error = %v", err)
+	}
+	if docs, err := ParseStream([]byte("# nothing\n"), ""); err != nil || len(docs) != 0 {
+		t.Errorf("ParseStream() of comments = %d documents, %v", len(docs), err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a: b: c", "mapping values are not allowed here, quote the value if it contains ': ' at f.yaml:1:5"},
+		{"a:\n  b:\n    c: 1\n   d: 2", "unexpected indentation at f.yaml:4:4"},
+		{"a:\n\tb: 1", "tabs are not allowed for indentation at f.yaml:2:2"},
+		{"a: *missing", `unknown anchor "missing", no anchor is defined before it at f.yaml:1:4`},
+		{"a: &base 1\nb: &other 2\nc: *bsae", `unknown anchor "bsae", did you mean *base? at f.yaml:3:4`},
+		{"a: &base 1\nb: *zzz", `unknown anchor "zzz", defined before it are &base at f.yaml:2:4`},
+		{"a: [1, 2", "unterminated flow collection, expected ']' at f.yaml:1:4"},
+		{"a: \"open", "unterminated quoted string at f.yaml:1:4"},
+		{"a: - b", "a block sequence cannot start on the line of its key at f.yaml:1:4"},
+		{"? complex\n: key", "complex mapping keys are not supported at f.yaml:1:1"},
+		{"a: !!int x", "invalid !!int value at f.yaml:1:4"},
+		{"a: \"\\q\"", "invalid escape sequence at f.yaml:1:5"},
+		{"a: 1\n- b", "expected a key, a sequence entry cannot follow mapping members at the same indentation at f.yaml:2:1"},
+		{"a: <<\nb: [1]\nc:\n  <<: *x", `unknown anchor "x", no anchor is defined before it at f.yaml:4:7`},
+		{"a: &x 1\nb:\n  <<: *x", "the << merge key needs a mapping or a sequence of mappings at f.yaml:1:7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input), "f.yaml")
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"a: \"x\ry\"\r",
+		"a: 'x\r\n\r\ny'",
+		"a: \"x\\\r\ny\"",
+		"a: |\r\n  x\r\n",
+		"- [1, {b: &x c}]\n- *x\n",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// only the absence of panics is checked, errors are fine
+		Parse(data, "fuzz.yaml")
+	})
+}
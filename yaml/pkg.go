@@ -0,0 +1,10 @@
+// Package yaml parses YAML config files into slowjson nodes with line and column positions, so YAML
+// goes through the same decoding, merging, provenance and diagnostics as JSON.
+//
+// It covers the YAML written for configuration: block and flow mappings and sequences, plain, quoted
+// and block scalars, comments, anchors, aliases and "<<" merge keys, and streams of several documents
+// separated by "---". Plain scalars are typed by the YAML 1.2 core schema, so "80" is a number and
+// "true" a boolean while "yes" and "on" stay strings. The core tags !!str, !!int, !!float, !!bool and
+// !!null retype a scalar, other tags are accepted and ignored. Complex "?" keys and collections as keys
+// are not supported.
+package yaml
@@ -0,0 +1,82 @@
+package yaml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/merge"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+// Documents is the policy turning the documents of a stream into one config.
+type Documents struct {
+	// Select keeps the documents it returns true for, e.g. those whose "env" member is "prod".
+	// Every document is kept when it is nil.
+	Select func(i int, doc *slowjson.Node) bool
+	// Merge merges the kept documents in order, later documents overriding earlier ones. Without it
+	// a stream may keep at most one document.
+	Merge bool
+}
+
+// Apply returns the config of the documents of the stream name by the policy. It is an empty object
+// when no document is kept. Merged values keep the positions of the documents that set them.
+func (d Documents) Apply(docs []*slowjson.Node, name string) (*slowjson.Node, error) {
+	var kept []merge.Layer
+	for i, doc := range docs {
+		if d.Select == nil || d.Select(i, doc) {
+			kept = append(kept, merge.Layer{Name: fmt.Sprintf("%s#%d", name, i), Root: doc})
+		}
+	}
+	switch {
+	case len(kept) == 0:
+		return &slowjson.Node{Type: slowjson.NodeObject}, nil
+	case len(kept) == 1:
+		return kept[0].Root, nil
+	case !d.Merge:
+		return nil, kept[1].Root.Errorf("%s keeps %d documents, select one or merge them", name, len(kept))
+	}
+	res, err := merge.Merge(kept...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Root, nil
+}
+
+// FileSource is a config source reading a YAML file.
+type FileSource struct {
+	Path string
+	// Documents selects or merges the documents of a multi-document file.
+	Documents Documents
+}
+
+// File creates a source reading the YAML file at path, which must have a single document unless
+// Documents says otherwise.
+func File(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load parses the file. An empty file is an empty object.
+func (s *FileSource) Load(ctx context.Context) (*slowjson.Node, error) {
+	b, err := tracedconfig.ReadFile(ctx, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := ParseStream(b, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.Documents.Apply(docs, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if n.Type == slowjson.NodeNull {
+		return &slowjson.Node{Type: slowjson.NodeObject}, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+package yaml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/at15/tracedconfig"
+	"github.com/at15/tracedconfig/slowjson"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const stream = "env: base\nport: 80\n---\nenv: dev\ndebug: true\n---\nenv: prod\nport: 443\n"
+
+func TestDocuments_Apply(t *testing.T) {
+	docs, err := ParseStream([]byte(stream), "app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := func(name string) func(int, *slowjson.Node) bool {
+		return func(i int, doc *slowjson.Node) bool {
+			return i == 0 || doc.Get("env").Value == name
+		}
+	}
+	tests := []struct {
+		name    string
+		policy  Documents
+		want    string
+		wantErr string
+	}{
+		{"merge all", Documents{Merge: true}, `{"env":"prod","port":443,"debug":true}`, ""},
+		{"merge selected", Documents{Select: env("prod"), Merge: true}, `{"env":"prod","port":443}`, ""},
+		{"select one", Documents{Select: func(i int, _ *slowjson.Node) bool { return i == 1 }}, `{"env":"dev","debug":true}`, ""},
+		{"select none", Documents{Select: func(int, *slowjson.Node) bool { return false }}, `{}`, ""},
+		{"several without merge", Documents{}, "", "app.yaml keeps 3 documents, select one or merge them at app.yaml:4:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.policy.Apply(docs, "app.yaml")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Apply() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got := marshal(t, n); got != tt.want {
+				t.Errorf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := writeFile(t, "app.yaml", stream)
+	s := File(path)
+	s.Documents.Merge = true
+	c := tracedconfig.NewConfig(s)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var cfg struct {
+		Env   string
+		Port  int
+		Debug bool
+	}
+	if err := c.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != "prod" || cfg.Port != 443 || !cfg.Debug {
+		t.Errorf("Decode() = %+v", cfg)
+	}
+	if n := c.Get("port"); n.File != path || n.StartLine != 8 {
+		t.Errorf("port set at %s:%d", n.File, n.StartLine)
+	}
+
+	empty := File(writeFile(t, "empty.yaml", "# nothing yet\n"))
+	if n, err := empty.Load(context.Background()); err != nil || n.Type != slowjson.NodeObject {
+		t.Errorf("Load() of an empty file = %v, %v", n, err)
+	}
+	broken := File(writeFile(t, "broken.yaml", "a: [1\n"))
+	if _, err := broken.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "broken.yaml:1:4") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestFile_Cached(t *testing.T) {
+	ctx := context.Background()
+	path := writeFile(t, "app.yaml", "name: hello\nport: 80\n")
+	src := tracedconfig.Cached(File(path), tracedconfig.CacheOptions{Dir: t.TempDir()})
+	if _, err := src.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// offline the cached payload is served
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	c := tracedconfig.NewConfig(src)
+	if err := c.Load(ctx); err != nil {
+		t.Fatalf("Load() error = %v, want the cached config", err)
+	}
+	if name, port := c.Get("name"), c.Get("port"); name.Value != "hello" || port.Value != "80" {
+		t.Errorf("name, port = %s, %s", name.Value, port.Value)
+	}
+}